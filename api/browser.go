@@ -29,7 +29,8 @@ type Browser interface {
 	IsConnected() bool
 	NewContext(opts goja.Value) BrowserContext
 	NewPage(opts goja.Value) Page
-	On(string) *goja.Promise
+	On(event string, handler goja.Value) *goja.Promise
+	RegisterSelectorEngine(name string, source goja.Value)
 	UserAgent() string
 	Version() string
 }
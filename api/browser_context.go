@@ -32,14 +32,15 @@ type BrowserContext interface {
 	ClearCookies()
 	ClearPermissions()
 	Close()
-	Cookies() []goja.Object
+	Cookies(urls ...string) []*Cookie
 	ExposeBinding(name string, callback goja.Callable, opts goja.Value)
 	ExposeFunction(name string, callback goja.Callable)
 	GrantPermissions(permissions []string, opts goja.Value)
-	NewCDPSession() CDPSession
+	NewCDPSession(page Page) CDPSession
 	NewPage() Page
+	On(event string, handler goja.Callable)
 	Pages() []Page
-	Route(url goja.Value, handler goja.Callable)
+	Route(url goja.Value, handler goja.Value)
 	SetDefaultNavigationTimeout(timeout int64)
 	SetDefaultTimeout(timeout int64)
 	SetExtraHTTPHeaders(headers map[string]string)
@@ -52,7 +53,7 @@ type BrowserContext interface {
 	// - https://github.com/microsoft/playwright/pull/2763
 	SetHTTPCredentials(httpCredentials goja.Value)
 	SetOffline(offline bool)
-	StorageState(opts goja.Value)
-	Unroute(url goja.Value, handler goja.Callable)
+	StorageState(opts goja.Value) *StorageState
+	Unroute(url goja.Value, handler goja.Value)
 	WaitForEvent(event string, optsOrPredicate goja.Value) interface{}
 }
@@ -26,7 +26,7 @@ import (
 
 // BrowserType is the public interface of a CDP browser client.
 type BrowserType interface {
-	Connect(opts goja.Value)
+	Connect(wsEndpoint string, opts goja.Value) Browser
 	ExecutablePath() string
 	Launch(opts goja.Value) Browser
 	LaunchPersistentContext(userDataDir string, opts goja.Value) Browser
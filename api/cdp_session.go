@@ -25,5 +25,6 @@ import "github.com/dop251/goja"
 // CDPSession is the interface of a raw CDP session.
 type CDPSession interface {
 	Detach()
+	On(event string, handler goja.Callable)
 	Send(method string, params goja.Value) goja.Value
 }
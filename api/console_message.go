@@ -0,0 +1,48 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package api
+
+// ConsoleMessage is the interface of a message that a page
+// or a web worker logged with the console API.
+type ConsoleMessage interface {
+	// Args returns handles to the arguments of the console API call.
+	Args() []JSHandle
+	// Location returns the location in the source of the console API call.
+	Location() ConsoleMessageLocation
+	// Page returns the page that logged the message.
+	Page() Page
+	// Text returns the text of the message.
+	Text() string
+	// Type returns the type of the console API call,
+	// such as log, debug, info, error or warning.
+	Type() string
+	// Worker returns the web worker that logged the message,
+	// or nil if the page logged it.
+	Worker() Worker
+}
+
+// ConsoleMessageLocation is the location of a console API call
+// in the source of a page or a web worker.
+type ConsoleMessageLocation struct {
+	URL          string `js:"url"`
+	LineNumber   int64  `js:"lineNumber"`
+	ColumnNumber int64  `js:"columnNumber"`
+}
@@ -0,0 +1,43 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package api
+
+import "github.com/dop251/goja"
+
+// Dialog is the interface of a JavaScript dialog that a page opened,
+// such as an alert, confirm, prompt or beforeunload dialog.
+type Dialog interface {
+	// Accept accepts the dialog. The prompt text is the response
+	// to a prompt dialog.
+	Accept(promptText goja.Value)
+	// DefaultValue returns the default prompt text of a prompt dialog,
+	// or an empty string.
+	DefaultValue() string
+	// Dismiss dismisses the dialog.
+	Dismiss()
+	// Message returns the message of the dialog.
+	Message() string
+	// Page returns the page that opened the dialog.
+	Page() Page
+	// Type returns the type of the dialog, which is one of:
+	// alert, beforeunload, confirm or prompt.
+	Type() string
+}
@@ -0,0 +1,41 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package api
+
+// Download is the interface of a file download that a page started.
+type Download interface {
+	// Failure waits for the download to finish, and returns why it failed,
+	// or an empty string if it succeeded.
+	Failure() string
+	// Page returns the page that started the download.
+	Page() Page
+	// Path waits for the download to finish, and returns the path
+	// of the downloaded file.
+	Path() string
+	// SaveAs waits for the download to finish, and copies the
+	// downloaded file to the path.
+	SaveAs(path string)
+	// SuggestedFilename returns the file name that the browser suggests
+	// for the download, based on its response headers or URL.
+	SuggestedFilename() string
+	// URL returns the URL of the download.
+	URL() string
+}
@@ -0,0 +1,326 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Error is an error of the browser API that scripts can tell apart from the
+// others by its name, such as with e.name === 'TimeoutError', and Go code
+// with errors.Is and errors.As.
+type Error interface {
+	error
+	// Name returns the name of the error class, which doesn't change
+	// across versions, such as TimeoutError.
+	Name() string
+}
+
+// The names of the errors of the browser API.
+const (
+	TimeoutErrorName            = "TimeoutError"
+	TargetClosedErrorName       = "TargetClosedError"
+	SelectorResolutionErrorName = "SelectorResolutionError"
+	NavigationErrorName         = "NavigationError"
+	ProtocolErrorName           = "ProtocolError"
+	EvaluationErrorName         = "EvaluationError"
+)
+
+// ErrorName returns the name of the first of the errors of the browser API
+// that err is, in the order of their precedence: TimeoutError,
+// TargetClosedError, SelectorResolutionError, NavigationError,
+// ProtocolError and EvaluationError. It returns an empty string if err is
+// none of them.
+func ErrorName(err error) string {
+	if e := AsError(err); e != nil {
+		return e.Name()
+	}
+	return ""
+}
+
+// AsError returns the first of the errors of the browser API in the chain
+// of err, in the order of precedence of ErrorName, or nil if there is none.
+func AsError(err error) Error {
+	var (
+		timeoutErr      *TimeoutError
+		targetClosedErr *TargetClosedError
+		selectorErr     *SelectorResolutionError
+		navigationErr   *NavigationError
+		protocolErr     *ProtocolError
+		evaluationErr   *EvaluationError
+	)
+	switch {
+	case errors.As(err, &timeoutErr):
+		return timeoutErr
+	case errors.As(err, &targetClosedErr):
+		return targetClosedErr
+	case errors.As(err, &selectorErr):
+		return selectorErr
+	case errors.As(err, &navigationErr):
+		return navigationErr
+	case errors.As(err, &protocolErr):
+		return protocolErr
+	case errors.As(err, &evaluationErr):
+		return evaluationErr
+	}
+	return nil
+}
+
+// TimeoutError is returned when an operation doesn't finish within its
+// timeout, such as waiting for a selector.
+type TimeoutError struct {
+	Err error `js:"-"`
+}
+
+// Error returns the message of the wrapped error.
+func (e *TimeoutError) Error() string {
+	if e.Err == nil {
+		return "timed out"
+	}
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// Name returns TimeoutError.
+func (*TimeoutError) Name() string {
+	return TimeoutErrorName
+}
+
+// Is reports whether target is a TimeoutError, so that any timeout
+// matches errors.Is(err, &TimeoutError{}).
+func (*TimeoutError) Is(target error) bool {
+	_, ok := target.(*TimeoutError)
+	return ok
+}
+
+// TargetClosedError is returned when the page, the frame or the browser
+// that an operation targets is closed, or closes before it finishes.
+type TargetClosedError struct {
+	Err error `js:"-"`
+}
+
+// Error returns the message of the wrapped error.
+func (e *TargetClosedError) Error() string {
+	if e.Err == nil {
+		return "target closed"
+	}
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *TargetClosedError) Unwrap() error {
+	return e.Err
+}
+
+// Name returns TargetClosedError.
+func (*TargetClosedError) Name() string {
+	return TargetClosedErrorName
+}
+
+// Is reports whether target is a TargetClosedError.
+func (*TargetClosedError) Is(target error) bool {
+	_, ok := target.(*TargetClosedError)
+	return ok
+}
+
+// SelectorResolutionError is returned when a selector is malformed, or
+// when it matches more than one element where a single one is expected.
+type SelectorResolutionError struct {
+	// Selector is the selector that can't be resolved.
+	Selector string `js:"selector"`
+	Err      error  `js:"-"`
+}
+
+// Error returns the message of the wrapped error.
+func (e *SelectorResolutionError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("resolving selector %q", e.Selector)
+	}
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *SelectorResolutionError) Unwrap() error {
+	return e.Err
+}
+
+// Name returns SelectorResolutionError.
+func (*SelectorResolutionError) Name() string {
+	return SelectorResolutionErrorName
+}
+
+// Is reports whether target is a SelectorResolutionError.
+func (*SelectorResolutionError) Is(target error) bool {
+	_, ok := target.(*SelectorResolutionError)
+	return ok
+}
+
+// NavigationError is returned when a navigation fails, such as when the
+// host name of its URL can't be resolved.
+type NavigationError struct {
+	// URL is the URL of the navigation.
+	URL string `js:"url"`
+	// Reason is the reason of the failure that the browser reports,
+	// such as net::ERR_NAME_NOT_RESOLVED.
+	Reason string `js:"reason"`
+	Err    error  `js:"-"`
+}
+
+// Error returns the message of the wrapped error.
+func (e *NavigationError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("navigating to %q: %s", e.URL, e.Reason)
+	}
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *NavigationError) Unwrap() error {
+	return e.Err
+}
+
+// Name returns NavigationError.
+func (*NavigationError) Name() string {
+	return NavigationErrorName
+}
+
+// Is reports whether target is a NavigationError.
+func (*NavigationError) Is(target error) bool {
+	_, ok := target.(*NavigationError)
+	return ok
+}
+
+// ProtocolError is an error that the browser returns in reply to a
+// Chrome DevTools Protocol command.
+type ProtocolError struct {
+	// Method is the method of the command, such as Input.dispatchKeyEvent.
+	Method string `js:"method"`
+	// Code is the code of the error, such as -32000.
+	Code int64 `js:"code"`
+	// Message is the message of the error.
+	Message string `js:"message"`
+	Err     error  `js:"-"`
+}
+
+// Error returns the message and the code of the error, after the method
+// of the command if it is known.
+func (e *ProtocolError) Error() string {
+	msg := fmt.Sprintf("%s (%d)", e.Message, e.Code)
+	if e.Method == "" {
+		return msg
+	}
+	return fmt.Sprintf("sending %q: %s", e.Method, msg)
+}
+
+// Unwrap returns the wrapped error.
+func (e *ProtocolError) Unwrap() error {
+	return e.Err
+}
+
+// Name returns ProtocolError.
+func (*ProtocolError) Name() string {
+	return ProtocolErrorName
+}
+
+// Is reports whether target is a ProtocolError.
+func (*ProtocolError) Is(target error) bool {
+	_, ok := target.(*ProtocolError)
+	return ok
+}
+
+// EvaluationError is returned when a function or an expression that is
+// evaluated in the page throws, or returns a promise that rejects.
+type EvaluationError struct {
+	// ClassName is the class of the thrown error, such as TypeError.
+	// It is empty if the thrown value is not an error.
+	ClassName string `js:"className"`
+	// Message is the message of the error, or the thrown value if it
+	// is not an error.
+	Message string `js:"message"`
+	// Stack is the stack trace of the error as the page formats it.
+	Stack string `js:"stack"`
+	// URL, LineNumber and ColumnNumber are the location where the value
+	// is thrown. The line and column numbers start from 1.
+	URL          string `js:"url"`
+	LineNumber   int64  `js:"lineNumber"`
+	ColumnNumber int64  `js:"columnNumber"`
+	// StackFrames are the frames of the stack trace, innermost first.
+	StackFrames []StackFrame `js:"stackFrames"`
+}
+
+// StackFrame is a frame of the stack trace of an error thrown in the page.
+// The line and column numbers start from 1.
+type StackFrame struct {
+	FunctionName string `js:"functionName"`
+	URL          string `js:"url"`
+	LineNumber   int64  `js:"lineNumber"`
+	ColumnNumber int64  `js:"columnNumber"`
+}
+
+// String returns the frame formatted like a line of the stack of a JS
+// error, without the leading "at".
+func (f StackFrame) String() string {
+	url := f.URL
+	if url == "" {
+		url = "<anonymous>"
+	}
+	if f.FunctionName == "" {
+		return fmt.Sprintf("%s:%d:%d", url, f.LineNumber, f.ColumnNumber)
+	}
+	return fmt.Sprintf("%s (%s:%d:%d)", f.FunctionName, url, f.LineNumber, f.ColumnNumber)
+}
+
+// Error returns the class name and the message of the error followed by
+// its stack frames on a single line, so that it reads well in the logs.
+func (e *EvaluationError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.ClassName)
+	if e.ClassName != "" && e.Message != "" {
+		b.WriteString(": ")
+	}
+	b.WriteString(e.Message)
+	for i, f := range e.StackFrames {
+		if i == 0 {
+			b.WriteString(" at ")
+		} else {
+			b.WriteString(" <- ")
+		}
+		b.WriteString(f.String())
+	}
+	return b.String()
+}
+
+// Name returns EvaluationError.
+func (*EvaluationError) Name() string {
+	return EvaluationErrorName
+}
+
+// Is reports whether target is an EvaluationError.
+func (*EvaluationError) Is(target error) bool {
+	_, ok := target.(*EvaluationError)
+	return ok
+}
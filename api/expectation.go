@@ -0,0 +1,38 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package api
+
+import "github.com/dop251/goja"
+
+// Expectation asserts the state of the elements that a locator matches.
+// The assertions retry until they pass or their timeout expires, and
+// record their result as a k6 check.
+type Expectation interface {
+	// ToBeVisible asserts that the element is visible.
+	ToBeVisible(opts goja.Value) bool
+	// ToHaveAttribute asserts that the element has an attribute
+	// with the given value.
+	ToHaveAttribute(name, value string, opts goja.Value) bool
+	// ToHaveCount asserts that the locator matches n elements.
+	ToHaveCount(n int64, opts goja.Value) bool
+	// ToHaveText asserts that the text content of the element is text.
+	ToHaveText(text string, opts goja.Value) bool
+}
@@ -0,0 +1,36 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package api
+
+import "github.com/dop251/goja"
+
+// FileChooser is the interface of a file chooser that a page opened
+// from a file input element.
+type FileChooser interface {
+	// Element returns the file input element that opened the file chooser.
+	Element() ElementHandle
+	// IsMultiple returns whether the file chooser accepts multiple files.
+	IsMultiple() bool
+	// Page returns the page that opened the file chooser.
+	Page() Page
+	// SetFiles sets the files of the file input element.
+	SetFiles(files goja.Value, opts goja.Value)
+}
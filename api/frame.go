@@ -12,6 +12,7 @@ type Frame interface {
 	Content() string
 	Dblclick(selector string, opts goja.Value)
 	DispatchEvent(selector string, typ string, eventInit goja.Value, opts goja.Value)
+	DragAndDrop(source string, target string, opts goja.Value)
 	Evaluate(pageFunc goja.Value, args ...goja.Value) interface{}
 	EvaluateHandle(pageFunc goja.Value, args ...goja.Value) JSHandle
 	Fill(selector string, value string, opts goja.Value)
@@ -52,6 +53,7 @@ type Frame interface {
 	WaitForFunction(pageFunc, opts goja.Value, args ...goja.Value) *goja.Promise
 	WaitForLoadState(state string, opts goja.Value)
 	WaitForNavigation(opts goja.Value) Response
+	WaitForURL(url goja.Value, opts goja.Value)
 	WaitForSelector(selector string, opts goja.Value) ElementHandle
 	WaitForTimeout(timeout int64)
 }
@@ -27,6 +27,7 @@ import (
 
 // JSHandle is the interface of an in-page JS object.
 type JSHandle interface {
+	AsArray() []JSHandle
 	AsElement() ElementHandle
 	Dispose()
 	Evaluate(pageFunc goja.Value, args ...goja.Value) interface{}
@@ -75,4 +75,7 @@ type Locator interface {
 	// WaitFor waits for the element matching the locator's selector
 	// with strict mode on.
 	WaitFor(opts goja.Value)
+	// Screenshot takes a screenshot of the element matching the locator's
+	// selector with strict mode on.
+	Screenshot(opts goja.Value) goja.ArrayBuffer
 }
@@ -27,7 +27,9 @@ type Mouse interface {
 	Click(x float64, y float64, opts goja.Value)
 	DblClick(x float64, y float64, opts goja.Value)
 	Down(x float64, y float64, opts goja.Value)
+	IsPressed(button string) bool
 	Move(x float64, y float64, opts goja.Value)
+	Position() *Position
 	Up(x float64, y float64, opts goja.Value)
 	// Wheel(opts goja.Value)
 }
@@ -37,6 +37,7 @@ type Page interface {
 	DispatchEvent(selector string, typ string, eventInit goja.Value, opts goja.Value)
 	DragAndDrop(source string, target string, opts goja.Value)
 	EmulateMedia(opts goja.Value)
+	EmulateNetworkConditions(conditions goja.Value)
 	EmulateVisionDeficiency(typ string)
 	Evaluate(pageFunc goja.Value, arg ...goja.Value) interface{}
 	EvaluateHandle(pageFunc goja.Value, arg ...goja.Value) JSHandle
@@ -64,14 +65,18 @@ type Page interface {
 	// Locator creates and returns a new locator for this page (main frame).
 	Locator(selector string, opts goja.Value) Locator
 	MainFrame() Frame
+	On(event string, handler goja.Callable)
 	Opener() Page
+	// PageErrors returns the uncaught exceptions that were thrown in the page
+	// and in its frames, in the order they were thrown.
+	PageErrors() []PageError
 	Pause()
 	Pdf(opts goja.Value) goja.ArrayBuffer
 	Press(selector string, key string, opts goja.Value)
 	Query(selector string) ElementHandle
 	QueryAll(selector string) []ElementHandle
 	Reload(opts goja.Value) Response
-	Route(url goja.Value, handler goja.Callable)
+	Route(url goja.Value, handler goja.Value)
 	Screenshot(opts goja.Value) goja.ArrayBuffer
 	SelectOption(selector string, values goja.Value, opts goja.Value) []string
 	SetContent(html string, opts goja.Value)
@@ -85,16 +90,18 @@ type Page interface {
 	Title() string
 	Type(selector string, text string, opts goja.Value)
 	Uncheck(selector string, opts goja.Value)
-	Unroute(url goja.Value, handler goja.Callable)
+	Unroute(url goja.Value, handler goja.Value)
 	URL() string
 	Video() Video
 	ViewportSize() map[string]float64
-	WaitForEvent(event string, optsOrPredicate goja.Value) interface{}
+	WaitForEvent(event string, optsOrPredicate goja.Value) *goja.Promise
+	WaitForFileChooser(opts goja.Value) *goja.Promise
 	WaitForFunction(fn, opts goja.Value, args ...goja.Value) *goja.Promise
 	WaitForLoadState(state string, opts goja.Value)
 	WaitForNavigation(opts goja.Value) Response
-	WaitForRequest(urlOrPredicate, opts goja.Value) Request
-	WaitForResponse(urlOrPredicate, opts goja.Value) Response
+	WaitForRequest(urlOrPredicate, opts goja.Value) *goja.Promise
+	WaitForResponse(urlOrPredicate, opts goja.Value) *goja.Promise
+	WaitForURL(url goja.Value, opts goja.Value)
 	WaitForSelector(selector string, opts goja.Value) ElementHandle
 	WaitForTimeout(timeout int64)
 	Workers() []Worker
@@ -0,0 +1,34 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package api
+
+// PageError is an uncaught exception that was thrown in a page
+// or in one of its frames.
+type PageError struct {
+	// Name is the name of the error class, such as Error or TypeError.
+	// It is empty if the thrown value is not an error.
+	Name string `js:"name"`
+	// Message is the message of the error, or the thrown value
+	// if it is not an error.
+	Message string `js:"message"`
+	// Stack is the stack trace of the error.
+	Stack string `js:"stack"`
+}
@@ -28,13 +28,14 @@ type Request interface {
 	Failure() goja.Value
 	Frame() Frame
 	HeaderValue(string) goja.Value
+	HeaderValues(string) []string
 	Headers() map[string]string
 	HeadersArray() []HTTPHeader
 	IsNavigationRequest() bool
 	Method() string
 	PostData() string
 	PostDataBuffer() goja.ArrayBuffer
-	PostDataJSON() string
+	PostDataJSON() goja.Value
 	RedirectedFrom() Request
 	RedirectedTo() Request
 	ResourceType() string
@@ -40,5 +40,7 @@ type Response interface {
 	Size() HTTPMessageSize
 	Status() int64
 	StatusText() string
+	Text() string
+	Timing() goja.Value
 	URL() string
 }
@@ -37,9 +37,49 @@ func (s HTTPMessageSize) Total() int64 {
 	return s.Headers + s.Body
 }
 
+// Position is a point in CSS pixels.
+type Position struct {
+	X float64 `js:"x"`
+	Y float64 `js:"y"`
+}
+
 type Rect struct {
 	X      float64 `js:"x"`
 	Y      float64 `js:"y"`
 	Width  float64 `js:"width"`
 	Height float64 `js:"height"`
 }
+
+// Cookie is a browser cookie. When a cookie is added, either its URL, or
+// its domain and path are required. The expires is the expiration date in
+// seconds since the UNIX epoch, or -1 for a session cookie.
+type Cookie struct {
+	Name     string  `js:"name" json:"name"`
+	Value    string  `js:"value" json:"value"`
+	URL      string  `js:"url" json:"url,omitempty"`
+	Domain   string  `js:"domain" json:"domain,omitempty"`
+	Path     string  `js:"path" json:"path,omitempty"`
+	Expires  float64 `js:"expires" json:"expires"`
+	HTTPOnly bool    `js:"httpOnly" json:"httpOnly"`
+	Secure   bool    `js:"secure" json:"secure"`
+	SameSite string  `js:"sameSite" json:"sameSite,omitempty"`
+}
+
+// NameValue is a name and value pair, such as a local storage item.
+type NameValue struct {
+	Name  string `js:"name" json:"name"`
+	Value string `js:"value" json:"value"`
+}
+
+// OriginState is the local storage of an origin.
+type OriginState struct {
+	Origin       string      `js:"origin" json:"origin"`
+	LocalStorage []NameValue `js:"localStorage" json:"localStorage"`
+}
+
+// StorageState is the storage of a browser context
+// that another browser context can start with.
+type StorageState struct {
+	Cookies []*Cookie      `js:"cookies" json:"cookies"`
+	Origins []*OriginState `js:"origins" json:"origins"`
+}
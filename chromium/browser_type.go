@@ -1,11 +1,13 @@
 package chromium
 
 import (
-	"bufio"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -13,6 +15,7 @@ import (
 	"runtime"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/grafana/xk6-browser/api"
 	"github.com/grafana/xk6-browser/common"
@@ -72,10 +75,54 @@ func NewBrowserType(ctx context.Context) api.BrowserType {
 	return &b
 }
 
-// Connect attaches k6 browser to an existing browser instance.
-func (b *BrowserType) Connect(opts goja.Value) {
-	rt := b.vu.Runtime()
-	k6common.Throw(rt, errors.New("BrowserType.connect() has not been implemented yet"))
+// Connect attaches k6 browser to an existing browser instance, and returns
+// a new api.Browser value to control it without launching a browser process.
+// The wsEndpoint is the DevTools WebSocket URL of the browser, or its HTTP
+// endpoint, such as http://localhost:9222, to discover the WebSocket URL.
+// The XK6_BROWSER_WS_URL environment variable is used if it's empty.
+func (b *BrowserType) Connect(wsEndpoint string, opts goja.Value) api.Browser {
+	var (
+		rt          = b.vu.Runtime()
+		connectOpts = common.NewConnectOptions()
+	)
+	if err := connectOpts.Parse(b.Ctx, opts); err != nil {
+		k6common.Throw(rt, fmt.Errorf("parsing connect options: %w", err))
+	}
+	b.Ctx = common.WithLaunchOptions(b.Ctx, &connectOpts.LaunchOptions)
+
+	logger, err := makeLogger(b.Ctx, &connectOpts.LaunchOptions)
+	if err != nil {
+		k6common.Throw(rt, fmt.Errorf("setting up logger: %w", err))
+	}
+
+	if wsEndpoint == "" {
+		wsEndpoint = os.Getenv("XK6_BROWSER_WS_URL")
+	}
+	if wsEndpoint == "" {
+		k6common.Throw(rt, errors.New("connecting to browser: "+
+			"a WebSocket endpoint or the XK6_BROWSER_WS_URL environment variable is required"))
+	}
+
+	ctx, cancel := context.WithCancel(b.Ctx)
+	wsURL, err := discoverWebsocketURL(ctx, wsEndpoint, connectOpts.Timeout)
+	if err != nil {
+		cancel()
+		k6common.Throw(rt, fmt.Errorf("connecting to browser: %w", err))
+	}
+	logger.Debugf("BrowserType:Connect", "wsURL:%q", wsURL)
+
+	// there's no browser process to manage, so no process ID is attached
+	// to the context, and the remote browser is not killed in k6ext.Panic.
+	browserProc := common.NewBrowserProcess(ctx, cancel, nil, wsURL, &storage.Dir{})
+	browserProc.AttachLogger(logger)
+
+	browser, err := common.ConnectBrowser(b.Ctx, b.CancelFn, browserProc, connectOpts, logger)
+	if err != nil {
+		cancel()
+		k6common.Throw(rt, fmt.Errorf("connecting to browser: %w", err))
+	}
+
+	return browser
 }
 
 // ExecutablePath returns the path where the extension expects to find the browser executable.
@@ -142,9 +189,10 @@ func (b *BrowserType) Launch(opts goja.Value) api.Browser {
 	}
 
 	flags := prepareFlags(launchOpts, &state.Options)
+	userDataDir := flags["user-data-dir"]
 
 	dataDir := b.storage
-	if err := dataDir.Make("", flags["user-data-dir"]); err != nil {
+	if err := dataDir.Make("", userDataDir); err != nil {
 		k6common.Throw(rt, err)
 	}
 	flags["user-data-dir"] = dataDir.Dir
@@ -165,7 +213,7 @@ func (b *BrowserType) Launch(opts goja.Value) api.Browser {
 
 	browserProc, err := b.allocate(launchOpts, flags, envs, dataDir, logger)
 	if browserProc == nil {
-		k6ext.Panic(b.Ctx, "launching browser: %s", err)
+		k6ext.Panic(b.Ctx, "launching browser: %w", common.NewFatalError(err))
 	}
 
 	browserProc.AttachLogger(logger)
@@ -174,7 +222,38 @@ func (b *BrowserType) Launch(opts goja.Value) api.Browser {
 	// so that we can kill it afterward if it lingers
 	// see: k6ext.Panic function.
 	b.Ctx = k6ext.WithProcessID(b.Ctx, browserProc.Pid())
-	browser, err := common.NewBrowser(b.Ctx, b.CancelFn, browserProc, launchOpts, logger)
+	// A browser that's reused across iterations isn't killed on fatal
+	// errors, so that an error in one iteration doesn't terminate it for
+	// the next ones, which replace it if it disconnected.
+	if launchOpts.ReuseBrowser {
+		b.Ctx = k6ext.WithSharedBrowser(b.Ctx)
+	}
+	if launchOpts.KillOnError {
+		b.Ctx = k6ext.WithKillOnError(b.Ctx)
+	}
+
+	var relaunch common.BrowserRelauncher
+	if launchOpts.AutoRestart {
+		relaunch = func() (*common.BrowserProcess, error) {
+			// the temporary data directory of the crashed browser is removed.
+			dataDir := &storage.Dir{}
+			if err := dataDir.Make("", userDataDir); err != nil {
+				return nil, fmt.Errorf("%w", err)
+			}
+			flags["user-data-dir"] = dataDir.Dir
+
+			browserProc, err := b.allocate(launchOpts, flags, envs, dataDir, logger)
+			if err != nil {
+				_ = dataDir.Cleanup()
+				return nil, err
+			}
+			browserProc.AttachLogger(logger)
+			k6ext.SetProcessID(b.Ctx, browserProc.Pid())
+
+			return browserProc, nil
+		}
+	}
+	browser, err := common.NewBrowser(b.Ctx, b.CancelFn, browserProc, launchOpts, relaunch, logger)
 	if err != nil {
 		k6common.Throw(rt, err)
 	}
@@ -215,12 +294,20 @@ func (b *BrowserType) allocate(
 		path = b.ExecutablePath()
 	}
 
-	cmd, stdout, err := execute(ctx, path, args, env, dataDir, logger)
+	outputFile, err := openOutputFile(opts.OutputDir, b.vu.State().VUID)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd, output, err := execute(ctx, path, args, env, dataDir, outputFile, logger)
 	if err != nil {
+		if outputFile != nil {
+			_ = outputFile.Close()
+		}
 		return nil, err
 	}
 
-	wsURL, err := parseWebsocketURL(ctx, stdout)
+	wsURL, err := parseWebsocketURL(ctx, output)
 	if err != nil {
 		return nil, fmt.Errorf("getting DevTools URL: %w", err)
 	}
@@ -350,17 +437,34 @@ func setFlagsFromK6Options(flags map[string]interface{}, k6opts *k6lib.Options)
 	}
 }
 
+// openOutputFile opens the file in dir that the browser process output of
+// the VU is appended to. It returns nil if dir is empty.
+func openOutputFile(dir string, vuID uint64) (io.WriteCloser, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating browser output directory: %w", err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("browser-vu%d.log", vuID))
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644) //nolint:gosec
+	if err != nil {
+		return nil, fmt.Errorf("opening browser output file: %w", err)
+	}
+	return f, nil
+}
+
 func execute(
-	ctx context.Context, path string, args, env []string, dataDir *storage.Dir, logger *log.Logger,
-) (*exec.Cmd, io.Reader, error) {
+	ctx context.Context, path string, args, env []string, dataDir *storage.Dir,
+	outputFile io.WriteCloser, logger *log.Logger,
+) (*exec.Cmd, *processOutput, error) {
 	cmd := exec.CommandContext(ctx, path, args...)
 	killAfterParent(cmd)
 
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return nil, nil, fmt.Errorf("%w", err)
-	}
-	cmd.Stderr = cmd.Stdout
+	// the output is copied as it's written, so the process can't block.
+	output := newProcessOutput(outputFile, logger)
+	cmd.Stdout = output
+	cmd.Stderr = output
 
 	// Set up environment variable for process
 	if len(env) > 0 {
@@ -369,7 +473,7 @@ func execute(
 
 	// We must start the cmd before calling cmd.Wait, as otherwise the two
 	// can run into a data race.
-	err = cmd.Start()
+	err := cmd.Start()
 	if os.IsNotExist(err) {
 		return nil, nil, fmt.Errorf("file does not exist: %s", path)
 	}
@@ -386,6 +490,10 @@ func execute(
 				logger.Errorf("BrowserType:execute", "%v", err)
 			}
 		}()
+		defer output.didExit()
+		if outputFile != nil {
+			defer func() { _ = outputFile.Close() }()
+		}
 
 		if err := cmd.Wait(); err != nil {
 			log := logger.Errorf
@@ -395,43 +503,58 @@ func execute(
 				// we can stop it gracefully. See #https://github.com/grafana/xk6-browser/issues/423
 				log = logger.Debugf
 			}
-			log("BrowserType:execute", "browser process with PID %d ended unexpectedly: %v", cmd.Process.Pid, err)
+			log("BrowserType:execute", "%v",
+				output.errorf("browser process with PID %d ended unexpectedly: %w", cmd.Process.Pid, err))
 		}
 	}()
 
-	return cmd, stdout, nil
+	return cmd, output, nil
 }
 
-// parseWebsocketURL grabs the websocket address from chrome's output and returns it.
-func parseWebsocketURL(ctx context.Context, rc io.Reader) (wsURL string, _ error) {
-	type result struct {
-		wsURL string
-		err   error
+// discoverWebsocketURL returns the DevTools WebSocket URL of the browser.
+// It returns the endpoint if it's a WebSocket URL, and otherwise asks the
+// /json/version endpoint of the browser's HTTP endpoint for it.
+func discoverWebsocketURL(ctx context.Context, endpoint string, timeout time.Duration) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("parsing endpoint %q: %w", endpoint, err)
 	}
-	c := make(chan result, 1)
-	go func() {
-		const prefix = "DevTools listening on "
-
-		scanner := bufio.NewScanner(rc)
-		for scanner.Scan() {
-			if s := scanner.Text(); strings.HasPrefix(s, prefix) {
-				c <- result{
-					strings.TrimPrefix(strings.TrimSpace(s), prefix),
-					nil,
-				}
-				return
-			}
-		}
-		if err := scanner.Err(); err != nil {
-			c <- result{"", err}
-		}
-	}()
-	select {
-	case r := <-c:
-		return r.wsURL, r.err
-	case <-ctx.Done():
-		return "", fmt.Errorf("%w", ctx.Err())
+	switch u.Scheme {
+	case "ws", "wss":
+		return endpoint, nil
+	case "http", "https":
+	default:
+		return "", fmt.Errorf("invalid endpoint %q: must be a ws, wss, http, or https URL", endpoint)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/json/version"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("discovering DevTools URL: %w", err)
 	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("discovering DevTools URL: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("discovering DevTools URL: %s responded with %q", u, resp.Status)
+	}
+	var version struct {
+		WebSocketDebuggerURL string `json:"webSocketDebuggerUrl"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&version); err != nil {
+		return "", fmt.Errorf("discovering DevTools URL: parsing %s: %w", u, err)
+	}
+	if version.WebSocketDebuggerURL == "" {
+		return "", fmt.Errorf("discovering DevTools URL: %s has no webSocketDebuggerUrl", u)
+	}
+
+	return version.WebSocketDebuggerURL, nil
 }
 
 // makeLogger makes and returns an extension wide logger.
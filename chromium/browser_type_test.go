@@ -1,9 +1,14 @@
 package chromium
 
 import (
+	"context"
+	"fmt"
 	"net"
+	"net/http"
+	"net/http/httptest"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/grafana/xk6-browser/common"
 
@@ -156,3 +161,46 @@ func TestBrowserTypePrepareFlags(t *testing.T) {
 		})
 	}
 }
+
+func TestBrowserTypeDiscoverWebsocketURL(t *testing.T) {
+	t.Parallel()
+
+	const wsURL = "ws://127.0.0.1:9222/devtools/browser/a7ee4ea3"
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json/version":
+			_, _ = fmt.Fprintf(w, `{"Browser": "HeadlessChrome", "webSocketDebuggerUrl": %q}`, wsURL)
+		case "/empty/json/version":
+			_, _ = fmt.Fprint(w, `{}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	testCases := []struct {
+		name, endpoint, want, wantErr string
+	}{
+		{name: "ws", endpoint: wsURL, want: wsURL},
+		{name: "http", endpoint: srv.URL, want: wsURL},
+		{name: "http_trailing_slash", endpoint: srv.URL + "/", want: wsURL},
+		{name: "not_found", endpoint: srv.URL + "/missing", wantErr: `responded with "404 Not Found"`},
+		{name: "no_ws_url", endpoint: srv.URL + "/empty", wantErr: "has no webSocketDebuggerUrl"},
+		{name: "invalid_scheme", endpoint: "ftp://localhost", wantErr: "must be a ws, wss, http, or https URL"},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := discoverWebsocketURL(context.Background(), tc.endpoint, time.Second)
+			if tc.wantErr != "" {
+				assert.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package chromium
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/grafana/xk6-browser/log"
+)
+
+// processOutputTailSize is the number of the last bytes of the browser
+// process output that are kept to report them in errors.
+const processOutputTailSize = 8 << 10
+
+// processOutput receives the stdout and stderr of the browser process.
+// It logs the output lines, writes the raw output to a file if there's
+// one, and keeps the last bytes of the output to report them in the
+// launch and crash errors.
+type processOutput struct {
+	mu   sync.Mutex
+	tail []byte // the last bytes of the output
+	line []byte // the incomplete last line
+	file io.Writer
+
+	wsURL  chan string   // receives the DevTools URL once
+	exited chan struct{} // closed when the process exits
+
+	logger *log.Logger
+}
+
+func newProcessOutput(file io.Writer, logger *log.Logger) *processOutput {
+	return &processOutput{
+		file:   file,
+		wsURL:  make(chan string, 1),
+		exited: make(chan struct{}),
+		logger: logger,
+	}
+}
+
+// Write implements io.Writer so that the process output is drained as soon
+// as it's written, and the process can't block on a full pipe.
+func (o *processOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.file != nil {
+		if _, err := o.file.Write(p); err != nil {
+			o.logger.Errorf("Browser:output", "writing browser output: %v", err)
+			o.file = nil
+		}
+	}
+
+	o.tail = append(o.tail, p...)
+	if n := len(o.tail) - processOutputTailSize; n > 0 {
+		o.tail = append(o.tail[:0], o.tail[n:]...)
+	}
+
+	o.line = append(o.line, p...)
+	for {
+		i := bytes.IndexByte(o.line, '\n')
+		if i < 0 {
+			break
+		}
+		o.onLine(string(bytes.TrimRight(o.line[:i], "\r")))
+		o.line = o.line[i+1:]
+	}
+	// a line can't grow unbounded without a line break.
+	if len(o.line) > processOutputTailSize {
+		o.onLine(string(o.line))
+		o.line = o.line[:0]
+	}
+
+	return len(p), nil
+}
+
+func (o *processOutput) onLine(s string) {
+	const prefix = "DevTools listening on "
+
+	o.logger.Debugf("Browser:output", "%s", s)
+	if strings.HasPrefix(s, prefix) {
+		select {
+		case o.wsURL <- strings.TrimSpace(strings.TrimPrefix(s, prefix)):
+		default:
+		}
+	}
+}
+
+// didExit should be called when the process exits.
+func (o *processOutput) didExit() {
+	close(o.exited)
+}
+
+// String returns the last bytes of the process output.
+func (o *processOutput) String() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return string(bytes.TrimSpace(o.tail))
+}
+
+// errorf returns a new error that includes the last bytes of the process
+// output, if there's any.
+func (o *processOutput) errorf(format string, a ...interface{}) error {
+	err := fmt.Errorf(format, a...)
+	if out := o.String(); out != "" {
+		return fmt.Errorf("%w\nbrowser output:\n%s", err, out)
+	}
+	return err
+}
+
+// parseWebsocketURL grabs the websocket address from chrome's output and returns it.
+func parseWebsocketURL(ctx context.Context, out *processOutput) (wsURL string, _ error) {
+	select {
+	case wsURL = <-out.wsURL:
+		return wsURL, nil
+	case <-out.exited:
+		return "", out.errorf("browser process exited before listening on a DevTools URL")
+	case <-ctx.Done():
+		return "", out.errorf("%w", ctx.Err())
+	}
+}
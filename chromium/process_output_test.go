@@ -0,0 +1,108 @@
+package chromium
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/xk6-browser/log"
+	"github.com/grafana/xk6-browser/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessOutput(t *testing.T) {
+	t.Parallel()
+
+	t.Run("websocket_url", func(t *testing.T) {
+		t.Parallel()
+
+		var file bytes.Buffer
+		out := newProcessOutput(&file, log.NewNullLogger())
+		_, _ = out.Write([]byte("[0101/000000.000000:ERROR:gpu_init.cc(446)] GPU error\nDevTools liste"))
+		_, _ = out.Write([]byte("ning on ws://127.0.0.1:9222/devtools/browser/a7ee4ea3\r\n"))
+
+		wsURL, err := parseWebsocketURL(context.Background(), out)
+		require.NoError(t, err)
+		assert.Equal(t, "ws://127.0.0.1:9222/devtools/browser/a7ee4ea3", wsURL)
+		assert.Contains(t, file.String(), "GPU error\nDevTools listening on")
+	})
+
+	t.Run("tail", func(t *testing.T) {
+		t.Parallel()
+
+		out := newProcessOutput(nil, log.NewNullLogger())
+		_, _ = out.Write([]byte("first\n"))
+		_, _ = out.Write([]byte(strings.Repeat("x", processOutputTailSize-5)))
+		_, _ = out.Write([]byte("last\n"))
+
+		s := out.String()
+		assert.Len(t, s, processOutputTailSize-1)
+		assert.NotContains(t, s, "first")
+		assert.True(t, strings.HasSuffix(s, "xlast"))
+	})
+
+	t.Run("err_exited", func(t *testing.T) {
+		t.Parallel()
+
+		out := newProcessOutput(nil, log.NewNullLogger())
+		_, _ = out.Write([]byte("Failed to create a ProcessSingleton for your profile directory.\n"))
+		out.didExit()
+
+		_, err := parseWebsocketURL(context.Background(), out)
+		assert.ErrorContains(t, err, "browser process exited before listening on a DevTools URL")
+		assert.ErrorContains(t, err, "ProcessSingleton")
+	})
+
+	t.Run("err_timeout", func(t *testing.T) {
+		t.Parallel()
+
+		out := newProcessOutput(nil, log.NewNullLogger())
+		_, _ = out.Write([]byte("still starting\n"))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := parseWebsocketURL(ctx, out)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.ErrorContains(t, err, "still starting")
+	})
+}
+
+func TestExecuteOutput(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake browser is a shell script")
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "browser")
+	script := "#!/bin/sh\n" +
+		"echo 'starting'\n" +
+		"echo 'error while loading shared libraries: libnss3.so' >&2\n" +
+		"exit 127\n"
+	require.NoError(t, os.WriteFile(path, []byte(script), 0o700)) //nolint:gosec
+
+	outputFile, err := openOutputFile(filepath.Join(dir, "logs"), 3)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, output, err := execute(ctx, path, nil, nil, &storage.Dir{}, outputFile, log.NewNullLogger())
+	require.NoError(t, err)
+
+	_, err = parseWebsocketURL(ctx, output)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "browser process exited before listening on a DevTools URL")
+	assert.Contains(t, err.Error(), "starting\nerror while loading shared libraries: libnss3.so")
+
+	raw, err := os.ReadFile(filepath.Join(dir, "logs", "browser-vu3.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "starting\nerror while loading shared libraries: libnss3.so\n", string(raw))
+}
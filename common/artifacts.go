@@ -0,0 +1,123 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package common
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	k6lib "go.k6.io/k6/lib"
+)
+
+// The placeholders of the paths of the artifacts, such as the screenshots
+// and the videos, so that the VUs don't overwrite each other's files.
+const (
+	artifactPathVU        = "vu"
+	artifactPathIter      = "iter"
+	artifactPathScenario  = "scenario"
+	artifactPathTimestamp = "timestamp"
+	artifactPathURLHost   = "url_host"
+)
+
+// Matches the placeholders in the paths of the artifacts, such as {{vu}}.
+var reArtifactPathPlaceholder = regexp.MustCompile(`{{\s*([^{}]*?)\s*}}`)
+
+// validateArtifactPath returns an error if the path of an artifact
+// has a placeholder that isn't one of the supported placeholders.
+func validateArtifactPath(path string) error {
+	for _, m := range reArtifactPathPlaceholder.FindAllStringSubmatch(path, -1) {
+		switch m[1] {
+		case artifactPathVU, artifactPathIter, artifactPathScenario, artifactPathTimestamp, artifactPathURLHost:
+		default:
+			return fmt.Errorf(
+				"invalid placeholder %q in path %q: must be one of: {{%s}}, {{%s}}, {{%s}}, {{%s}}, {{%s}}",
+				m[0], path, artifactPathVU, artifactPathIter, artifactPathScenario,
+				artifactPathTimestamp, artifactPathURLHost)
+		}
+	}
+	return nil
+}
+
+// expandArtifactPath replaces the placeholders in
+// the path of an artifact with their values.
+func expandArtifactPath(path string, values map[string]string) string {
+	return reArtifactPathPlaceholder.ReplaceAllStringFunc(path, func(s string) string {
+		name := reArtifactPathPlaceholder.FindStringSubmatch(s)[1]
+		return values[name]
+	})
+}
+
+// artifactPathValues returns the values of the placeholders of the
+// paths of the artifacts of the page. The host of a page without
+// one, such as about:blank, is "blank".
+func (p *Page) artifactPathValues() map[string]string {
+	values := map[string]string{
+		artifactPathScenario:  "default",
+		artifactPathTimestamp: strconv.FormatInt(time.Now().UnixMilli(), 10),
+		artifactPathURLHost:   "blank",
+	}
+	if state := p.vu.State(); state != nil {
+		values[artifactPathVU] = strconv.FormatUint(state.VUID, 10)
+		values[artifactPathIter] = strconv.FormatInt(state.Iteration, 10)
+	}
+	if ss := k6lib.GetScenarioState(p.ctx); ss != nil {
+		values[artifactPathScenario] = ss.Name
+	}
+	if p.frameManager != nil {
+		if f := p.frameManager.MainFrame(); f != nil {
+			if u, err := url.Parse(f.URL()); err == nil && u.Host != "" {
+				// the port separator isn't allowed in the Windows paths.
+				values[artifactPathURLHost] = strings.ReplaceAll(u.Host, ":", "_")
+			}
+		}
+	}
+	return values
+}
+
+// artifactPath returns the path of an artifact of the page with its
+// placeholders expanded. A relative path is relative to the artifacts
+// directory of the browser, if it has one.
+func (p *Page) artifactPath(path string) string {
+	if path == "" {
+		return ""
+	}
+	path = expandArtifactPath(path, p.artifactPathValues())
+	if filepath.IsAbs(path) {
+		return path
+	}
+	if dir := p.artifactsDir(); dir != "" {
+		return filepath.Join(dir, path)
+	}
+	return path
+}
+
+// artifactsDir returns the artifacts directory of the browser of the page.
+func (p *Page) artifactsDir() string {
+	if p.browserCtx == nil || p.browserCtx.browser == nil || p.browserCtx.browser.launchOpts == nil {
+		return ""
+	}
+	return p.browserCtx.browser.launchOpts.ArtifactsDir
+}
@@ -0,0 +1,79 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana/xk6-browser/k6ext/k6test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	k6lib "go.k6.io/k6/lib"
+)
+
+func TestValidateArtifactPath(t *testing.T) {
+	t.Parallel()
+
+	for _, path := range []string{
+		"shot.png",
+		"{{vu}}/{{iter}}/{{scenario}}-{{timestamp}}.png",
+		"{{ url_host }}.png",
+	} {
+		assert.NoError(t, validateArtifactPath(path), path)
+	}
+
+	err := validateArtifactPath("{{vu}}/{{user}}.png")
+	assert.EqualError(t, err, `invalid placeholder "{{user}}" in path "{{vu}}/{{user}}.png": `+
+		`must be one of: {{vu}}, {{iter}}, {{scenario}}, {{timestamp}}, {{url_host}}`)
+
+	opts := NewPageScreenshotOptions()
+	vu := k6test.NewVU(t)
+	err = opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{"path": "{{vuid}}.png"}))
+	assert.ErrorContains(t, err, `invalid placeholder "{{vuid}}"`)
+}
+
+func TestPageArtifactPath(t *testing.T) {
+	t.Parallel()
+
+	// newPage returns a page of a simulated VU.
+	newPage := func(t *testing.T, id uint64, dir string) *Page {
+		t.Helper()
+
+		vu := k6test.NewVU(t)
+		vu.State().VUID = id
+		vu.State().Iteration = 3
+		ctx := k6lib.WithScenarioState(vu.Context(), &k6lib.ScenarioState{Name: "checkout"})
+		return &Page{
+			ctx: ctx,
+			vu:  vu,
+			browserCtx: &BrowserContext{
+				browser: &Browser{launchOpts: &LaunchOptions{ArtifactsDir: dir}},
+			},
+		}
+	}
+
+	dir := t.TempDir()
+	p1 := newPage(t, 1, dir)
+	p2 := newPage(t, 2, dir)
+
+	const path = "{{scenario}}/vu-{{vu}}/{{iter}}-{{url_host}}.png"
+	path1, path2 := p1.artifactPath(path), p2.artifactPath(path)
+	assert.Equal(t, filepath.Join(dir, "checkout", "vu-1", "3-blank.png"), path1)
+	assert.Equal(t, filepath.Join(dir, "checkout", "vu-2", "3-blank.png"), path2)
+
+	// the intermediate directories are created.
+	require.NoError(t, saveScreenshot(path1, []byte("1")))
+	require.NoError(t, saveScreenshot(path2, []byte("2")))
+	for path, want := range map[string]string{path1: "1", path2: "2"} {
+		got, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, want, string(got))
+	}
+
+	// absolute paths don't resolve against the artifacts directory.
+	abs := filepath.Join(t.TempDir(), "{{vu}}.png")
+	assert.Equal(t, filepath.Join(filepath.Dir(abs), "1.png"), p1.artifactPath(abs))
+	assert.Empty(t, p1.artifactPath(""))
+	assert.Regexp(t, `^\d+\.png$`, newPage(t, 1, "").artifactPath("{{timestamp}}.png"))
+}
@@ -0,0 +1,253 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package common
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image/jpeg"
+	"os"
+)
+
+// The offsets of the header fields that aviWriter updates
+// when it closes the file. See aviWriter.writeHeader.
+const (
+	aviRIFFSizeOffset    = 4
+	aviTotalFramesOffset = 48
+	aviStreamLenOffset   = 140
+	aviMoviSizeOffset    = 216
+	aviHeaderSize        = 224
+)
+
+// aviIndexEntry is an entry of the index of the frames of an AVI file.
+type aviIndexEntry struct {
+	offset uint32
+	size   uint32
+}
+
+// aviWriter writes Motion JPEG frames to an AVI file at a constant frame rate.
+// The header of the file is written with the first frame, since the width
+// and height of the video are those of the first frame.
+type aviWriter struct {
+	f      *os.File
+	fps    int
+	width  int
+	height int
+	offset int64
+	index  []aviIndexEntry
+}
+
+// newAVIWriter creates an AVI file at the given path. The width and height
+// of the video are used if the video is closed without any frames.
+func newAVIWriter(path string, fps, width, height int) (*aviWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("creating video file: %w", err)
+	}
+
+	return &aviWriter{
+		f:      f,
+		fps:    fps,
+		width:  width,
+		height: height,
+	}, nil
+}
+
+// frames returns the number of frames in the video.
+func (w *aviWriter) frames() int {
+	return len(w.index)
+}
+
+// writeFrame appends a JPEG image to the video as its next frame.
+func (w *aviWriter) writeFrame(frame []byte) error {
+	if w.offset == 0 {
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(frame))
+		if err != nil {
+			return fmt.Errorf("decoding video frame: %w", err)
+		}
+		w.width, w.height = cfg.Width, cfg.Height
+		if err := w.writeHeader(); err != nil {
+			return err
+		}
+	}
+
+	var b bytes.Buffer
+	b.WriteString("00dc")
+	le32(&b, uint32(len(frame)))
+	b.Write(frame)
+	if len(frame)%2 == 1 {
+		b.WriteByte(0)
+	}
+	if _, err := w.f.Write(b.Bytes()); err != nil {
+		return fmt.Errorf("writing video frame: %w", err)
+	}
+	w.index = append(w.index, aviIndexEntry{
+		// the offsets are from the movi list type.
+		offset: uint32(w.offset - aviHeaderSize + 4),
+		size:   uint32(len(frame)),
+	})
+	w.offset += int64(b.Len())
+
+	return nil
+}
+
+// repeatFrame repeats the last frame of the video as its next frame. The
+// repeated frame isn't written again, but is pointed at in the index.
+func (w *aviWriter) repeatFrame() error {
+	if len(w.index) == 0 {
+		return errors.New("repeating video frame: no frame to repeat")
+	}
+	w.index = append(w.index, w.index[len(w.index)-1])
+
+	return nil
+}
+
+// writeHeader writes the header of the file up to the start of the frames.
+// The sizes and the frame counts are updated by close.
+func (w *aviWriter) writeHeader() error {
+	var b bytes.Buffer
+
+	b.WriteString("RIFF")
+	le32(&b, 0) // RIFF size
+	b.WriteString("AVI ")
+
+	b.WriteString("LIST")
+	le32(&b, 192)
+	b.WriteString("hdrl")
+
+	b.WriteString("avih")
+	le32(&b, 56)
+	le32(&b, uint32(1000000/w.fps)) // microseconds per frame
+	le32(&b, 0)                     // max bytes per second
+	le32(&b, 0)                     // padding granularity
+	le32(&b, 0x10)                  // has index
+	le32(&b, 0)                     // total frames
+	le32(&b, 0)                     // initial frames
+	le32(&b, 1)                     // streams
+	le32(&b, 0)                     // suggested buffer size
+	le32(&b, uint32(w.width))
+	le32(&b, uint32(w.height))
+	b.Write(make([]byte, 16)) // reserved
+
+	b.WriteString("LIST")
+	le32(&b, 116)
+	b.WriteString("strl")
+
+	b.WriteString("strh")
+	le32(&b, 56)
+	b.WriteString("vids")
+	b.WriteString("MJPG")
+	le32(&b, 0)             // flags
+	le32(&b, 0)             // priority and language
+	le32(&b, 0)             // initial frames
+	le32(&b, 1)             // scale
+	le32(&b, uint32(w.fps)) // rate
+	le32(&b, 0)             // start
+	le32(&b, 0)             // length
+	le32(&b, 0)             // suggested buffer size
+	le32(&b, 0xffffffff)    // quality
+	le32(&b, 0)             // sample size
+	le16(&b, 0)             // frame left
+	le16(&b, 0)             // frame top
+	le16(&b, uint16(w.width))
+	le16(&b, uint16(w.height))
+
+	b.WriteString("strf")
+	le32(&b, 40)
+	le32(&b, 40) // header size
+	le32(&b, uint32(w.width))
+	le32(&b, uint32(w.height))
+	le16(&b, 1)  // planes
+	le16(&b, 24) // bits per pixel
+	b.WriteString("MJPG")
+	le32(&b, uint32(w.width*w.height*3))
+	b.Write(make([]byte, 16)) // resolution and colors
+
+	b.WriteString("LIST")
+	le32(&b, 0) // movi size
+	b.WriteString("movi")
+
+	if _, err := w.f.Write(b.Bytes()); err != nil {
+		return fmt.Errorf("writing video header: %w", err)
+	}
+	w.offset = int64(b.Len())
+
+	return nil
+}
+
+// close writes the index of the frames, updates the header and closes the file.
+func (w *aviWriter) close() error {
+	if w.offset == 0 {
+		if err := w.writeHeader(); err != nil {
+			_ = w.f.Close()
+			return err
+		}
+	}
+
+	var b bytes.Buffer
+	b.WriteString("idx1")
+	le32(&b, uint32(len(w.index)*16))
+	for _, e := range w.index {
+		b.WriteString("00dc")
+		le32(&b, 0x10) // key frame
+		le32(&b, e.offset)
+		le32(&b, e.size)
+	}
+	if _, err := w.f.Write(b.Bytes()); err != nil {
+		_ = w.f.Close()
+		return fmt.Errorf("writing video index: %w", err)
+	}
+	size := w.offset + int64(b.Len())
+
+	patches := []struct {
+		offset int64
+		value  uint32
+	}{
+		{aviRIFFSizeOffset, uint32(size - 8)},
+		{aviTotalFramesOffset, uint32(len(w.index))},
+		{aviStreamLenOffset, uint32(len(w.index))},
+		{aviMoviSizeOffset, uint32(w.offset - aviMoviSizeOffset - 4)},
+	}
+	for _, p := range patches {
+		var v [4]byte
+		binary.LittleEndian.PutUint32(v[:], p.value)
+		if _, err := w.f.WriteAt(v[:], p.offset); err != nil {
+			_ = w.f.Close()
+			return fmt.Errorf("updating video header: %w", err)
+		}
+	}
+
+	if err := w.f.Close(); err != nil {
+		return fmt.Errorf("closing video file: %w", err)
+	}
+
+	return nil
+}
+
+func le32(b *bytes.Buffer, v uint32) {
+	_ = binary.Write(b, binary.LittleEndian, v)
+}
+
+func le16(b *bytes.Buffer, v uint16) {
+	_ = binary.Write(b, binary.LittleEndian, v)
+}
@@ -23,7 +23,6 @@ package common
 import (
 	"context"
 	"sync/atomic"
-	"time"
 )
 
 type Barrier struct {
@@ -50,7 +49,7 @@ func (b *Barrier) AddFrameNavigation(frame *Frame) {
 		atomic.AddInt64(&b.count, 1)
 		select {
 		case <-frame.ctx.Done():
-		case <-time.After(time.Duration(frame.manager.timeoutSettings.navigationTimeout()) * time.Second):
+		case <-timeoutAfter(frame.manager.timeoutSettings.navigationTimeout()):
 			b.errCh <- ErrTimedOut
 		case <-ch:
 			b.ch <- true
@@ -0,0 +1,258 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/chromedp/cdproto/cdp"
+	cdpruntime "github.com/chromedp/cdproto/runtime"
+	"github.com/dop251/goja"
+)
+
+// bindingName is the name of the CDP binding that the functions
+// exposed with Page.exposeFunction call to reach the extension.
+const bindingName = "__xk6BrowserBinding__"
+
+// bindingSource returns the script that exposes a function with the given
+// name in the page. The function sends its arguments to the binding, and
+// returns a Promise that is settled when the extension delivers the result.
+func bindingSource(name string) string {
+	return fmt.Sprintf(`(() => {
+	const name = %q;
+	const binding = globalThis[%q];
+	if (typeof binding !== "function" || (globalThis[name] && globalThis[name].__deliver)) {
+		return;
+	}
+	const callbacks = new Map();
+	let lastSeq = 0;
+	const fn = (...args) => {
+		const seq = ++lastSeq;
+		const promise = new Promise((resolve, reject) => callbacks.set(seq, { resolve, reject }));
+		binding(JSON.stringify({ name, seq, args }));
+		return promise;
+	};
+	fn.__deliver = (seq, result, error) => {
+		const callback = callbacks.get(seq);
+		if (!callback) {
+			return;
+		}
+		callbacks.delete(seq);
+		if (error !== undefined) {
+			callback.reject(new Error(error));
+		} else {
+			callback.resolve(result);
+		}
+	};
+	globalThis[name] = fn;
+})();`, name, bindingName)
+}
+
+// bindingCall is a call of an exposed function in the page.
+type bindingCall struct {
+	Name string        `json:"name"`
+	Seq  int64         `json:"seq"`
+	Args []interface{} `json:"args"`
+
+	// where to deliver the result of the call.
+	session   session
+	execCtxID cdpruntime.ExecutionContextID
+}
+
+// pageBindings stores the functions exposed in a page.
+type pageBindings struct {
+	mu        sync.Mutex
+	callbacks map[string]goja.Callable
+	sources   []string
+}
+
+func (p *Page) exposeFunction(name string, callback goja.Callable) error {
+	if name == "" {
+		return errors.New("missing function name")
+	}
+	if callback == nil {
+		return errors.New("missing callback")
+	}
+
+	source := bindingSource(name)
+	p.bindings.mu.Lock()
+	if _, ok := p.bindings.callbacks[name]; ok {
+		p.bindings.mu.Unlock()
+		return errors.New("function has already been exposed")
+	}
+	if p.bindings.callbacks == nil {
+		p.bindings.callbacks = make(map[string]goja.Callable)
+	}
+	p.bindings.callbacks[name] = callback
+	p.bindings.sources = append(p.bindings.sources, source)
+	p.bindings.mu.Unlock()
+
+	p.startEventLoopQueue()
+
+	// the new documents get the function from the init script.
+	for _, fs := range p.getFrameSessions() {
+		if err := fs.addBinding(source); err != nil {
+			return err
+		}
+	}
+	// and the current ones get it right away.
+	for _, frame := range p.frameManager.Frames() {
+		f, ok := frame.(*Frame)
+		if !ok {
+			continue
+		}
+		f.executionContextMu.RLock()
+		ec := f.executionContexts[mainWorld]
+		f.executionContextMu.RUnlock()
+		if ec == nil {
+			continue
+		}
+		if _, err := ec.eval(p.ctx, evalOptions{}, source); err != nil {
+			// the frame may have navigated away, and the init script takes care of it.
+			p.logger.Debugf("Page:exposeFunction", "sid:%v fid:%s err:%v", p.sessionID(), f.ID(), err)
+		}
+	}
+
+	return nil
+}
+
+// getFrameSessions returns the frame sessions of the page.
+func (p *Page) getFrameSessions() []*FrameSession {
+	p.frameSessionsMu.RLock()
+	defer p.frameSessionsMu.RUnlock()
+
+	sessions := []*FrameSession{p.mainFrameSession}
+	for _, fs := range p.frameSessions {
+		if fs != p.mainFrameSession {
+			sessions = append(sessions, fs)
+		}
+	}
+	return sessions
+}
+
+// onBindingCalled queues a call of an exposed function to run on the event
+// loop. It doesn't wait for the event loop as it runs in the goroutine that
+// handles the CDP events of the frame session.
+func (p *Page) onBindingCalled(call *bindingCall) {
+	p.bindings.mu.Lock()
+	_, ok := p.bindings.callbacks[call.Name]
+	p.bindings.mu.Unlock()
+	if !ok {
+		return
+	}
+	p.eventLoopQueue.push(func() error {
+		p.runBindingCall(call)
+		return nil
+	})
+}
+
+// runBindingCall runs the callback of an exposed function and delivers
+// its result to the page. It must be called on the event loop.
+func (p *Page) runBindingCall(call *bindingCall) {
+	p.bindings.mu.Lock()
+	callback := p.bindings.callbacks[call.Name]
+	p.bindings.mu.Unlock()
+
+	var (
+		rt     = p.vu.Runtime()
+		args   = make([]goja.Value, 0, len(call.Args))
+		result = "undefined"
+		errMsg = "undefined"
+	)
+	for _, arg := range call.Args {
+		args = append(args, rt.ToValue(arg))
+	}
+	v, err := callback(goja.Undefined(), args...)
+	if err == nil && gojaValueExists(v) {
+		var b []byte
+		if b, err = json.Marshal(v.Export()); err == nil {
+			result = string(b)
+		}
+	}
+	if err != nil {
+		msg := err.Error()
+		var exc *goja.Exception
+		if errors.As(err, &exc) {
+			msg = exc.Value().String()
+		}
+		b, _ := json.Marshal(msg)
+		errMsg = string(b)
+	}
+
+	expr := fmt.Sprintf("globalThis[%q].__deliver(%d, %s, %s)", call.Name, call.Seq, result, errMsg)
+	go func() {
+		action := cdpruntime.Evaluate(expr).WithContextID(call.execCtxID)
+		if _, _, err := action.Do(cdp.WithExecutor(p.ctx, call.session)); err != nil {
+			// the page may have navigated away.
+			p.logger.Debugf("Page:runBindingCall", "sid:%v name:%q err:%v", p.sessionID(), call.Name, err)
+		}
+	}()
+}
+
+// addBinding adds the binding that the exposed functions call, and the script
+// that exposes a function in the new documents of the frame session.
+func (fs *FrameSession) addBinding(source string) error {
+	if err := cdpruntime.AddBinding(bindingName).Do(cdp.WithExecutor(fs.ctx, fs.session)); err != nil {
+		return fmt.Errorf("adding binding: %w", err)
+	}
+	return fs.addScriptToEvaluateOnNewDocument(source)
+}
+
+// initBindings exposes the functions that are already exposed in the page
+// to the frame session.
+func (fs *FrameSession) initBindings() error {
+	fs.page.bindings.mu.Lock()
+	sources := append([]string(nil), fs.page.bindings.sources...)
+	fs.page.bindings.mu.Unlock()
+
+	for _, source := range sources {
+		if err := fs.addBinding(source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (fs *FrameSession) onBindingCalled(event *cdpruntime.EventBindingCalled) {
+	fs.logger.Debugf("FrameSession:onBindingCalled",
+		"sid:%v tid:%v name:%s ectxid:%d",
+		fs.session.ID(), fs.targetID, event.Name, event.ExecutionContextID)
+
+	if event.Name == webVitalsBindingName {
+		fs.page.emitWebVitals(event.Payload, false)
+		return
+	}
+	if event.Name != bindingName {
+		return
+	}
+	var call bindingCall
+	if err := json.Unmarshal([]byte(event.Payload), &call); err != nil {
+		fs.logger.Debugf("FrameSession:onBindingCalled", "sid:%v tid:%v err:%v",
+			fs.session.ID(), fs.targetID, err)
+		return
+	}
+	call.session = fs.session
+	call.execCtxID = event.ExecutionContextID
+	fs.page.onBindingCalled(&call)
+}
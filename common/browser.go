@@ -22,6 +22,7 @@ package common
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -49,8 +50,13 @@ const (
 	BrowserStateOpen int64 = iota
 	BrowserStateClosing
 	BrowserStateClosed
+	BrowserStateCrashed
 )
 
+// BrowserRelauncher launches a new browser process in place of the one
+// that crashed.
+type BrowserRelauncher func() (*BrowserProcess, error)
+
 // Browser stores a Browser context.
 type Browser struct {
 	BaseEventEmitter
@@ -60,42 +66,93 @@ type Browser struct {
 
 	state int64
 
-	browserProc *BrowserProcess
-	launchOpts  *LaunchOptions
+	launchOpts *LaunchOptions
+
+	// Whether closing a connected browser closes the remote browser too,
+	// see: ConnectBrowser.
+	closeOnDisconnect bool
 
+	// relaunch restarts the browser after it crashes, if it's set.
+	// restartMu makes the concurrent calls restart it only once.
+	relaunch  BrowserRelauncher
+	restartMu sync.Mutex
+
+	// The browser process, the connection to it, and the channel that is
+	// closed when it crashes are replaced when the browser restarts, so
+	// they're guarded by procMu.
+	procMu      sync.RWMutex
+	browserProc *BrowserProcess
+	crashed     chan struct{}
 	// Connection to the browser to talk CDP protocol.
 	// A *Connection is saved to this field, see: connect().
 	conn connection
 
+	// The contexts by their IDs, and their IDs in creation order.
 	contextsMu     sync.RWMutex
 	contexts       map[cdp.BrowserContextID]*BrowserContext
+	contextIDs     []cdp.BrowserContextID
 	defaultContext *BrowserContext
 
+	// The browser context and the page that the iterations
+	// reuse with the reusePage launch option.
+	reuse pageReuse
+
 	// Cancel function to stop event listening
 	evCancelFn context.CancelFunc
 
 	// Needed as the targets map will be accessed from multiple Go routines,
 	// the main VU/JS go routine and the Go routine listening for CDP messages.
+	// The target IDs of the pages are in the order the pages were attached.
 	pagesMu sync.RWMutex
 	pages   map[target.ID]*Page
+	pageIDs []target.ID
 
 	sessionIDtoTargetIDMu sync.RWMutex
 	sessionIDtoTargetID   map[target.SessionID]target.ID
 
+	// The downloads in progress by their GUIDs.
+	downloadsMu sync.Mutex
+	downloads   map[string]*Download
+
+	// Custom selector engines registered with RegisterSelectorEngine.
+	selectorEngines selectorEngines
+
+	// Records the browser trace events for the traces of the contexts.
+	tracer *browserTracer
+
 	vu k6modules.VU
 
 	logger *log.Logger
 }
 
 // NewBrowser creates a new browser, connects to it, then returns it.
+// The browser is relaunched with relaunch when it crashes, unless it's nil.
 func NewBrowser(
 	ctx context.Context,
 	cancel context.CancelFunc,
 	browserProc *BrowserProcess,
 	launchOpts *LaunchOptions,
+	relaunch BrowserRelauncher,
 	logger *log.Logger,
 ) (*Browser, error) {
 	b := newBrowser(ctx, cancel, browserProc, launchOpts, logger)
+	b.relaunch = relaunch
+	if err := b.connect(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// ConnectBrowser connects to a running browser, then returns it.
+func ConnectBrowser(
+	ctx context.Context,
+	cancel context.CancelFunc,
+	browserProc *BrowserProcess,
+	connectOpts *ConnectOptions,
+	logger *log.Logger,
+) (*Browser, error) {
+	b := newBrowser(ctx, cancel, browserProc, &connectOpts.LaunchOptions, logger)
+	b.closeOnDisconnect = connectOpts.CloseOnDisconnect
 	if err := b.connect(); err != nil {
 		return nil, err
 	}
@@ -110,7 +167,9 @@ func newBrowser(
 	launchOpts *LaunchOptions,
 	logger *log.Logger,
 ) *Browser {
-	return &Browser{
+	ctx = withRouteQueue(ctx, newRouteQueue())
+
+	b := &Browser{
 		BaseEventEmitter:    NewBaseEventEmitter(ctx),
 		ctx:                 ctx,
 		cancelFn:            cancelFn,
@@ -120,19 +179,26 @@ func newBrowser(
 		contexts:            make(map[cdp.BrowserContextID]*BrowserContext),
 		pages:               make(map[target.ID]*Page),
 		sessionIDtoTargetID: make(map[target.SessionID]target.ID),
+		downloads:           make(map[string]*Download),
+		crashed:             make(chan struct{}),
 		vu:                  k6ext.GetVU(ctx),
 		logger:              logger,
 	}
+	b.tracer = newBrowserTracer(b)
+
+	return b
 }
 
 func (b *Browser) connect() error {
-	b.logger.Debugf("Browser:connect", "wsURL:%q", b.browserProc.WsURL())
-	conn, err := NewConnection(b.ctx, b.browserProc.WsURL(), b.logger)
+	b.logger.Debugf("Browser:connect", "wsURL:%q", b.getBrowserProc().WsURL())
+	conn, err := NewConnection(b.ctx, b.getBrowserProc().WsURL(), b.logger)
 	if err != nil {
 		return fmt.Errorf("connecting to browser DevTools URL: %w", err)
 	}
 
+	b.procMu.Lock()
 	b.conn = conn
+	b.procMu.Unlock()
 
 	// We don't need to lock this because `connect()` is called only in NewBrowser
 	b.defaultContext = NewBrowserContext(b.ctx, b, "", NewBrowserContextOptions(), b.logger)
@@ -144,42 +210,97 @@ func (b *Browser) disposeContext(id cdp.BrowserContextID) error {
 	b.logger.Debugf("Browser:disposeContext", "bctxid:%v", id)
 
 	action := target.DisposeBrowserContext(id)
-	if err := action.Do(cdp.WithExecutor(b.ctx, b.conn)); err != nil {
+	if err := action.Do(cdp.WithExecutor(b.ctx, b.getConn())); err != nil {
 		return fmt.Errorf("disposing browser context ID %s: %w", id, err)
 	}
 
 	b.contextsMu.Lock()
 	defer b.contextsMu.Unlock()
 	delete(b.contexts, id)
+	for i, cid := range b.contextIDs {
+		if cid == id {
+			b.contextIDs = append(b.contextIDs[:i:i], b.contextIDs[i+1:]...)
+			break
+		}
+	}
 
 	return nil
 }
 
+// getConn returns the connection to the browser.
+func (b *Browser) getConn() connection {
+	b.procMu.RLock()
+	defer b.procMu.RUnlock()
+	return b.conn
+}
+
+// getBrowserProc returns the browser process.
+func (b *Browser) getBrowserProc() *BrowserProcess {
+	b.procMu.RLock()
+	defer b.procMu.RUnlock()
+	return b.browserProc
+}
+
+// getPages returns the pages in the order they were attached.
 func (b *Browser) getPages() []*Page {
 	b.pagesMu.RLock()
 	defer b.pagesMu.RUnlock()
-	pages := make([]*Page, 0, len(b.pages))
-	for _, p := range b.pages {
-		pages = append(pages, p)
+	pages := make([]*Page, 0, len(b.pageIDs))
+	for _, id := range b.pageIDs {
+		pages = append(pages, b.pages[id])
 	}
 	return pages
 }
 
+// addPage adds the page of the target. It must be called with pagesMu locked.
+func (b *Browser) addPage(id target.ID, p *Page) {
+	if _, ok := b.pages[id]; !ok {
+		b.pageIDs = append(b.pageIDs, id)
+	}
+	b.pages[id] = p
+}
+
+// deletePage deletes the page of the target. It must be called with pagesMu locked.
+func (b *Browser) deletePage(id target.ID) {
+	delete(b.pages, id)
+	for i, pid := range b.pageIDs {
+		if pid == id {
+			b.pageIDs = append(b.pageIDs[:i:i], b.pageIDs[i+1:]...)
+			break
+		}
+	}
+}
+
 func (b *Browser) initEvents() error {
 	var cancelCtx context.Context
 	cancelCtx, b.evCancelFn = context.WithCancel(b.ctx)
 	chHandler := make(chan Event)
 
-	b.conn.on(cancelCtx, []string{
+	b.getConn().on(cancelCtx, []string{
 		cdproto.EventTargetAttachedToTarget,
 		cdproto.EventTargetDetachedFromTarget,
+		cdproto.EventBrowserDownloadWillBegin,
+		cdproto.EventBrowserDownloadProgress,
 		EventConnectionClose,
 	}, chHandler)
 
+	b.procMu.RLock()
+	browserProc, crashed := b.browserProc, b.crashed
+	b.procMu.RUnlock()
 	go func() {
+		var connClosed bool
 		defer func() {
 			b.logger.Debugf("Browser:initEvents:defer", "ctx err: %v", cancelCtx.Err())
-			b.browserProc.didLoseConnection()
+			browserProc.didLoseConnection()
+			// the browser crashed if it lost the connection while it was open.
+			if connClosed && b.ctx.Err() == nil &&
+				atomic.CompareAndSwapInt64(&b.state, BrowserStateOpen, BrowserStateCrashed) {
+				b.didCrash(crashed)
+				if b.relaunch != nil {
+					// keep the extension running so that the browser can restart.
+					return
+				}
+			}
 			if b.cancelFn != nil {
 				b.cancelFn()
 			}
@@ -195,8 +316,13 @@ func (b *Browser) initEvents() error {
 				} else if ev, ok := event.data.(*target.EventDetachedFromTarget); ok {
 					b.logger.Debugf("Browser:initEvents:onDetachedFromTarget", "sid:%v", ev.SessionID)
 					b.onDetachedFromTarget(ev)
+				} else if ev, ok := event.data.(*cdpbrowser.EventDownloadWillBegin); ok {
+					b.onDownloadWillBegin(ev)
+				} else if ev, ok := event.data.(*cdpbrowser.EventDownloadProgress); ok {
+					b.onDownloadProgress(ev)
 				} else if event.typ == EventConnectionClose {
 					b.logger.Debugf("Browser:initEvents:EventConnectionClose", "")
+					connClosed = true
 					return
 				}
 			}
@@ -204,7 +330,7 @@ func (b *Browser) initEvents() error {
 	}()
 
 	action := target.SetAutoAttach(true, true).WithFlatten(true)
-	if err := action.Do(cdp.WithExecutor(b.ctx, b.conn)); err != nil {
+	if err := action.Do(cdp.WithExecutor(b.ctx, b.getConn())); err != nil {
 		return fmt.Errorf("executing setAutoAttach: %w", err)
 	}
 
@@ -212,7 +338,7 @@ func (b *Browser) initEvents() error {
 	// However making a dummy call afterwards fixes this.
 	// This can be removed after https://chromium-review.googlesource.com/c/chromium/src/+/2885888 lands in stable.
 	action2 := target.GetTargetInfo()
-	if _, err := action2.Do(cdp.WithExecutor(b.ctx, b.conn)); err != nil {
+	if _, err := action2.Do(cdp.WithExecutor(b.ctx, b.getConn())); err != nil {
 		return fmt.Errorf("executing getTargetInfo: %w", err)
 	}
 
@@ -240,14 +366,23 @@ func (b *Browser) onAttachedToTarget(ev *target.EventAttachedToTarget) {
 		return
 	}
 
-	session := b.conn.getSession(ev.SessionID)
+	// The raw CDP sessions attach to the targets of the existing pages.
+	b.pagesMu.RLock()
+	_, attached := b.pages[evti.TargetID]
+	b.pagesMu.RUnlock()
+	if attached {
+		b.logger.Debugf("Browser:onAttachedToTarget:return", "sid:%v tid:%v (attached)", ev.SessionID, evti.TargetID)
+		return
+	}
+
+	session := b.getConn().getSession(ev.SessionID)
 
 	switch evti.Type {
 	case "background_page":
 		p, err := NewPage(b.ctx, session, browserCtx, evti.TargetID, nil, false, b.logger)
 		if err != nil {
 			isRunning := atomic.LoadInt64(&b.state) == BrowserStateOpen && b.IsConnected() // b.conn.isConnected()
-			if _, ok := err.(*websocket.CloseError); !ok && !isRunning {
+			if !errors.As(err, new(*websocket.CloseError)) && !isRunning {
 				// If we're no longer connected to browser, then ignore WebSocket errors
 				b.logger.Debugf("Browser:onAttachedToTarget:background_page:return", "sid:%v tid:%v websocket err:%v",
 					ev.SessionID, evti.TargetID, err)
@@ -266,7 +401,7 @@ func (b *Browser) onAttachedToTarget(ev *target.EventAttachedToTarget) {
 
 		b.pagesMu.Lock()
 		b.logger.Debugf("Browser:onAttachedToTarget:background_page:addTid", "sid:%v tid:%v", ev.SessionID, evti.TargetID)
-		b.pages[evti.TargetID] = p
+		b.addPage(evti.TargetID, p)
 		b.pagesMu.Unlock()
 
 		b.sessionIDtoTargetIDMu.Lock()
@@ -287,7 +422,7 @@ func (b *Browser) onAttachedToTarget(ev *target.EventAttachedToTarget) {
 		p, err := NewPage(b.ctx, session, browserCtx, evti.TargetID, opener, true, b.logger)
 		if err != nil {
 			isRunning := atomic.LoadInt64(&b.state) == BrowserStateOpen && b.IsConnected() // b.conn.isConnected()
-			if _, ok := err.(*websocket.CloseError); !ok && !isRunning {
+			if !errors.As(err, new(*websocket.CloseError)) && !isRunning {
 				// If we're no longer connected to browser, then ignore WebSocket errors
 				b.logger.Debugf("Browser:onAttachedToTarget:page:return", "sid:%v tid:%v websocket err:", ev.SessionID, evti.TargetID)
 				return
@@ -305,7 +440,7 @@ func (b *Browser) onAttachedToTarget(ev *target.EventAttachedToTarget) {
 
 		b.pagesMu.Lock()
 		b.logger.Debugf("Browser:onAttachedToTarget:page:addTarget", "sid:%v tid:%v", ev.SessionID, evti.TargetID)
-		b.pages[evti.TargetID] = p
+		b.addPage(evti.TargetID, p)
 		b.pagesMu.Unlock()
 
 		b.sessionIDtoTargetIDMu.Lock()
@@ -313,7 +448,10 @@ func (b *Browser) onAttachedToTarget(ev *target.EventAttachedToTarget) {
 		b.sessionIDtoTargetID[ev.SessionID] = evti.TargetID
 		b.sessionIDtoTargetIDMu.Unlock()
 
-		browserCtx.emit(EventBrowserContextPage, p)
+		browserCtx.onPage(p)
+		if opener != nil {
+			opener.onPopup(p)
+		}
 	default:
 		b.logger.Warnf(
 			"Browser:onAttachedToTarget", "sid:%v tid:%v bctxid:%v bctx nil:%t, unknown target type: %q",
@@ -342,11 +480,107 @@ func (b *Browser) onDetachedFromTarget(ev *target.EventDetachedFromTarget) {
 	if t, ok := b.pages[targetID]; ok {
 		b.logger.Debugf("Browser:onDetachedFromTarget:deletePage", "sid:%v tid:%v", ev.SessionID, targetID)
 
-		delete(b.pages, targetID)
+		b.deletePage(targetID)
 		t.didClose()
 	}
 }
 
+// didCrash fails the actions of the pages of the crashed browser with
+// ErrPageCrashed, and lets the crashed event listeners know.
+func (b *Browser) didCrash(crashed chan struct{}) {
+	b.logger.Warnf("Browser:didCrash", "browser crashed")
+
+	for _, p := range b.getPages() {
+		if s, ok := p.session.(*Session); ok {
+			s.markAsCrashed()
+		}
+		if !p.isClosedOrCrashed() {
+			p.didCrash()
+		}
+	}
+	close(crashed)
+	b.emit(EventBrowserCrashed, b)
+}
+
+// restartIfCrashed relaunches the browser if it crashed, and recreates its
+// browser contexts in the new browser. The pages of the crashed browser
+// aren't restored. It does nothing if the browser can't be relaunched.
+func (b *Browser) restartIfCrashed() error {
+	if b.relaunch == nil {
+		return nil
+	}
+	b.restartMu.Lock()
+	defer b.restartMu.Unlock()
+
+	if atomic.LoadInt64(&b.state) != BrowserStateCrashed {
+		return nil
+	}
+	b.logger.Warnf("Browser:restartIfCrashed", "relaunching the crashed browser")
+
+	browserProc, err := b.relaunch()
+	if err != nil {
+		return fmt.Errorf("relaunching browser: %w", err)
+	}
+	conn, err := NewConnection(b.ctx, browserProc.WsURL(), b.logger)
+	if err != nil {
+		return fmt.Errorf("connecting to relaunched browser: %w", err)
+	}
+
+	b.evCancelFn()
+	b.procMu.Lock()
+	b.browserProc = browserProc
+	b.conn = conn
+	b.crashed = make(chan struct{})
+	b.procMu.Unlock()
+	b.pagesMu.Lock()
+	b.pages = make(map[target.ID]*Page)
+	b.pageIDs = nil
+	b.pagesMu.Unlock()
+	b.sessionIDtoTargetIDMu.Lock()
+	b.sessionIDtoTargetID = make(map[target.SessionID]target.ID)
+	b.sessionIDtoTargetIDMu.Unlock()
+	atomic.StoreInt64(&b.state, BrowserStateOpen)
+
+	if err := b.initEvents(); err != nil {
+		return fmt.Errorf("relaunching browser: %w", err)
+	}
+	if err := b.defaultContext.restore(); err != nil {
+		return fmt.Errorf("restoring default browser context: %w", err)
+	}
+
+	b.contextsMu.Lock()
+	contexts := make([]*BrowserContext, 0, len(b.contextIDs))
+	for _, id := range b.contextIDs {
+		contexts = append(contexts, b.contexts[id])
+	}
+	b.contexts = make(map[cdp.BrowserContextID]*BrowserContext)
+	b.contextIDs = nil
+	b.contextsMu.Unlock()
+
+	for _, c := range contexts {
+		id, err := b.createBrowserContext(c.opts)
+		if err != nil {
+			return fmt.Errorf("recreating browser context: %w", err)
+		}
+		c.id = id
+		if err := c.setDownloadBehavior(); err != nil {
+			return fmt.Errorf("recreating browser context: %w", err)
+		}
+		if err := c.restore(); err != nil {
+			return fmt.Errorf("recreating browser context: %w", err)
+		}
+		b.contextsMu.Lock()
+		b.contexts[id] = c
+		b.contextIDs = append(b.contextIDs, id)
+		b.contextsMu.Unlock()
+	}
+	if err := b.tracer.resume(b.ctx, b.defaultContext.timeoutSettings.timeout()); err != nil {
+		return fmt.Errorf("resuming tracing: %w", err)
+	}
+
+	return nil
+}
+
 func (b *Browser) newPageInContext(id cdp.BrowserContextID) (*Page, error) {
 	b.contextsMu.RLock()
 	browserCtx, ok := b.contexts[id]
@@ -362,25 +596,30 @@ func (b *Browser) newPageInContext(id cdp.BrowserContextID) (*Page, error) {
 	// exists or not.
 	targetID := make(chan target.ID, 1)
 
+	// the target ID is received once, and the pages that other
+	// calls create meanwhile are skipped.
+	var newTID target.ID
 	waitForPage, removeEventHandler := createWaitForEventHandler(
 		ctx,
 		browserCtx, // browser context will emit the following event:
 		[]string{EventBrowserContextPage},
 		func(e interface{}) bool {
-			tid := <-targetID
+			if newTID == "" {
+				newTID = <-targetID
+			}
 
 			b.logger.Debugf("Browser:newPageInContext:createWaitForEventHandler",
-				"tid:%v ptid:%v bctxid:%v", tid, e.(*Page).targetID, id)
+				"tid:%v ptid:%v bctxid:%v", newTID, e.(*Page).targetID, id)
 
 			// we are only interested in the new page.
-			return e.(*Page).targetID == tid
+			return e.(*Page).targetID == newTID
 		},
 	)
 	defer removeEventHandler()
 
 	// create a new page.
 	action := target.CreateTarget("about:blank").WithBrowserContextID(id)
-	tid, err := action.Do(cdp.WithExecutor(ctx, b.conn))
+	tid, err := action.Do(cdp.WithExecutor(ctx, b.getConn()))
 	if err != nil {
 		return nil, fmt.Errorf("%T: %w", action, err)
 	}
@@ -400,46 +639,115 @@ func (b *Browser) newPageInContext(id cdp.BrowserContextID) (*Page, error) {
 	return page, err
 }
 
-// Close shuts down the browser.
+// Close shuts down the browser. A browser that is reused across the
+// iterations of a VU keeps running, and only the browser contexts that
+// were created with newContext are closed.
 func (b *Browser) Close() {
+	if b.launchOpts != nil && b.launchOpts.ReuseBrowser {
+		b.closeContexts()
+		return
+	}
+	b.close()
+}
+
+// closeContexts closes the browser contexts that were created with
+// newContext, except the one that's reused with the reusePage option.
+// It logs the errors instead of throwing them, since it also runs after
+// the iterations end.
+func (b *Browser) closeContexts() {
+	for _, c := range b.Contexts() {
+		bc := c.(*BrowserContext)
+		if b.isReusedContext(bc) {
+			b.releaseReusedContext()
+			continue
+		}
+		if err := bc.close(); err != nil {
+			b.logger.Errorf("Browser:closeContexts", "%v", err)
+		}
+	}
+}
+
+func (b *Browser) close() {
+	if err := b.shutdown(); err != nil {
+		k6ext.Panic(b.ctx, "%w", err)
+	}
+}
+
+// shutdown closes the browser like close, but returns the error of closing
+// the browser instead of throwing it, since a shared browser is also closed
+// when no iteration is running.
+func (b *Browser) shutdown() error {
 	defer func() {
-		if err := b.browserProc.userDataDir.Cleanup(); err != nil {
+		if err := b.getBrowserProc().userDataDir.Cleanup(); err != nil {
 			b.logger.Errorf("Browser:Close", "%v", err)
 		}
+		for _, c := range b.Contexts() {
+			c.(*BrowserContext).removeDownloads()
+		}
+		for _, p := range b.getPages() {
+			p.finishVideo()
+		}
 	}()
 
 	b.logger.Debugf("Browser:Close", "")
+	if atomic.LoadInt64(&b.state) == BrowserStateOpen {
+		for _, p := range b.getPages() {
+			p.reportWebVitals()
+		}
+	}
 	if !atomic.CompareAndSwapInt64(&b.state, b.state, BrowserStateClosing) {
 		// If we're already in a closing state then no need to continue.
 		b.logger.Debugf("Browser:Close", "already in a closing state")
-		return
+		return nil
 	}
 
 	atomic.CompareAndSwapInt64(&b.state, b.state, BrowserStateClosed)
 
+	if b.getBrowserProc().isRemote() && !b.closeOnDisconnect {
+		b.disconnect()
+		return nil
+	}
+
 	action := cdpbrowser.Close()
-	if err := action.Do(cdp.WithExecutor(b.ctx, b.conn)); err != nil {
-		if _, ok := err.(*websocket.CloseError); !ok {
-			k6ext.Panic(b.ctx, "closing the browser: %v", err)
+	if err := action.Do(cdp.WithExecutor(b.ctx, b.getConn())); err != nil {
+		// the browser closes the connection when it closes, or it was already disconnected.
+		if !errors.As(err, new(*websocket.CloseError)) && !errors.Is(err, ErrBrowserDisconnected) {
+			return fmt.Errorf("closing the browser: %w", err)
 		}
 	}
 
 	// terminate the browser process early on, then tell the CDP
 	// afterwards. this will take a little bit of time, and CDP
 	// will stop emitting events.
-	b.browserProc.GracefulClose()
-	b.browserProc.Terminate()
-	b.conn.Close()
+	browserProc := b.getBrowserProc()
+	browserProc.GracefulClose()
+	browserProc.Terminate()
+	b.getConn().Close()
+
+	return nil
+}
+
+// disconnect closes the browser contexts that were created with newContext
+// and the connection, and leaves the remote browser running.
+func (b *Browser) disconnect() {
+	for _, c := range b.Contexts() {
+		if err := b.disposeContext(c.(*BrowserContext).id); err != nil {
+			b.logger.Errorf("Browser:disconnect", "%v", err)
+		}
+	}
+	b.getBrowserProc().GracefulClose()
+	b.getConn().Close()
 }
 
-// Contexts returns list of browser contexts.
+// Contexts returns the open browser contexts that were created with
+// newContext, in the order they were created.
 func (b *Browser) Contexts() []api.BrowserContext {
 	b.contextsMu.RLock()
 	defer b.contextsMu.RUnlock()
 
-	contexts := make([]api.BrowserContext, 0, len(b.contexts))
-	for _, b := range b.contexts {
-		contexts = append(contexts, b)
+	contexts := make([]api.BrowserContext, 0, len(b.contextIDs))
+	for _, id := range b.contextIDs {
+		contexts = append(contexts, b.contexts[id])
 	}
 
 	return contexts
@@ -448,16 +756,22 @@ func (b *Browser) Contexts() []api.BrowserContext {
 // IsConnected returns whether the WebSocket connection to the browser process
 // is active or not.
 func (b *Browser) IsConnected() bool {
-	return b.browserProc.isConnected()
+	return b.getBrowserProc().isConnected()
 }
 
 // NewContext creates a new incognito-like browser context.
 func (b *Browser) NewContext(opts goja.Value) api.BrowserContext {
-	action := target.CreateBrowserContext().WithDisposeOnDetach(true)
-	browserContextID, err := action.Do(cdp.WithExecutor(b.ctx, b.conn))
-	b.logger.Debugf("Browser:NewContext", "bctxid:%v", browserContextID)
-	if err != nil {
-		k6ext.Panic(b.ctx, "cannot create browser context (%s): %w", browserContextID, err)
+	if err := b.restartIfCrashed(); err != nil {
+		k6ext.Panic(b.ctx, "creating browser context: %w", err)
+	}
+	if !b.IsConnected() {
+		k6ext.Panic(b.ctx, "creating browser context: %w", ErrBrowserDisconnected)
+	}
+
+	if b.reusesPage() {
+		if browserCtx := b.reusedContext(); browserCtx != nil {
+			return browserCtx
+		}
 	}
 
 	browserCtxOpts := NewBrowserContextOptions()
@@ -465,25 +779,75 @@ func (b *Browser) NewContext(opts goja.Value) api.BrowserContext {
 		k6ext.Panic(b.ctx, "parsing newContext options: %w", err)
 	}
 
-	b.contextsMu.Lock()
-	defer b.contextsMu.Unlock()
+	browserContextID, err := b.createBrowserContext(browserCtxOpts)
+	b.logger.Debugf("Browser:NewContext", "bctxid:%v", browserContextID)
+	if err != nil {
+		k6ext.Panic(b.ctx, "cannot create browser context (%s): %w", browserContextID, err)
+	}
+
 	browserCtx := NewBrowserContext(b.ctx, b, browserContextID, browserCtxOpts, b.logger)
+	if err := browserCtx.setDownloadBehavior(); err != nil {
+		k6ext.Panic(b.ctx, "creating browser context: %w", err)
+	}
+
+	b.contextsMu.Lock()
 	b.contexts[browserContextID] = browserCtx
+	b.contextIDs = append(b.contextIDs, browserContextID)
+	b.contextsMu.Unlock()
+
+	if b.reusesPage() {
+		b.keepContext(browserCtx)
+	}
 
 	return browserCtx
 }
 
+// createBrowserContext creates a new browser context in the browser
+// and returns its ID.
+func (b *Browser) createBrowserContext(opts *BrowserContextOptions) (cdp.BrowserContextID, error) {
+	action := target.CreateBrowserContext().WithDisposeOnDetach(true)
+	if proxy := opts.Proxy; proxy != nil {
+		action = action.
+			WithProxyServer(proxy.Server).
+			WithProxyBypassList(proxy.bypassList())
+	}
+	id, err := action.Do(cdp.WithExecutor(b.ctx, b.getConn()))
+	if err != nil {
+		return id, fmt.Errorf("%w", err)
+	}
+	return id, nil
+}
+
 // NewPage creates a new tab in the browser window.
 func (b *Browser) NewPage(opts goja.Value) api.Page {
 	browserCtx := b.NewContext(opts)
 	return browserCtx.NewPage()
 }
 
-// On returns a Promise that is resolved when the browser process is disconnected.
-// The only accepted event value is "disconnected".
-func (b *Browser) On(event string) *goja.Promise {
-	if event != EventBrowserDisconnected {
-		k6ext.Panic(b.ctx, "unknown browser event: %q, must be %q", event, EventBrowserDisconnected)
+// On returns a Promise that is resolved when the event happens. The event
+// is "disconnected" when the browser disconnects, such as when it's closed,
+// or when its process exits or crashes, and "crashed" when the browser
+// crashes. The optional handler is called with the browser before the
+// Promise is resolved.
+func (b *Browser) On(event string, handler goja.Value) *goja.Promise {
+	var happened <-chan struct{}
+	switch event {
+	case EventBrowserDisconnected:
+		happened = b.getBrowserProc().lostConnection
+	case EventBrowserCrashed:
+		b.procMu.RLock()
+		happened = b.crashed
+		b.procMu.RUnlock()
+	default:
+		k6ext.Panic(b.ctx, "unknown browser event: %q, must be %q or %q",
+			event, EventBrowserDisconnected, EventBrowserCrashed)
+	}
+	var fn goja.Callable
+	if gojaValueExists(handler) {
+		var ok bool
+		if fn, ok = goja.AssertFunction(handler); !ok {
+			k6ext.Panic(b.ctx, "handler of browser event %q must be a function", event)
+		}
 	}
 
 	rt := b.vu.Runtime()
@@ -492,8 +856,15 @@ func (b *Browser) On(event string) *goja.Promise {
 
 	go func() {
 		select {
-		case <-b.browserProc.lostConnection:
+		case <-happened:
+			b.logger.Debugf("Browser:On", "event:%q", event)
 			cb(func() error {
+				if fn != nil {
+					if _, err := fn(goja.Undefined(), rt.ToValue(b)); err != nil {
+						reject(fmt.Errorf("calling handler of browser event %q: %w", event, err))
+						return nil
+					}
+				}
 				resolve(true)
 				return nil
 			})
@@ -508,10 +879,26 @@ func (b *Browser) On(event string) *goja.Promise {
 	return p
 }
 
+// RegisterSelectorEngine registers a custom selector engine with the given name,
+// so that selectors prefixed with "name=" are resolved by it in all the frames
+// of the browser, including the ones of the existing pages. The source is either
+// a function that returns the engine, or a string with a JS expression that
+// evaluates to it. The engine is an object with a query(root, selector) and/or
+// a queryAll(root, selector) function.
+func (b *Browser) RegisterSelectorEngine(name string, source goja.Value) {
+	src, err := selectorEngineSource(source)
+	if err == nil {
+		err = b.selectorEngines.register(name, src)
+	}
+	if err != nil {
+		k6ext.Panic(b.ctx, "registering selector engine %q: %w", name, err)
+	}
+}
+
 // UserAgent returns the controlled browser's user agent string.
 func (b *Browser) UserAgent() string {
 	action := cdpbrowser.GetVersion()
-	_, _, _, ua, _, err := action.Do(cdp.WithExecutor(b.ctx, b.conn))
+	_, _, _, ua, _, err := action.Do(cdp.WithExecutor(b.ctx, b.getConn()))
 	if err != nil {
 		k6ext.Panic(b.ctx, "getting browser user agent: %w", err)
 	}
@@ -521,7 +908,7 @@ func (b *Browser) UserAgent() string {
 // Version returns the controlled browser's version.
 func (b *Browser) Version() string {
 	action := cdpbrowser.GetVersion()
-	_, product, _, _, _, err := action.Do(cdp.WithExecutor(b.ctx, b.conn))
+	_, product, _, _, _, err := action.Do(cdp.WithExecutor(b.ctx, b.getConn()))
 	if err != nil {
 		k6ext.Panic(b.ctx, "getting browser version: %w", err)
 	}
@@ -22,8 +22,12 @@ package common
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 	"reflect"
+	"sync"
 	"time"
 
 	"github.com/grafana/xk6-browser/api"
@@ -34,6 +38,7 @@ import (
 
 	cdpbrowser "github.com/chromedp/cdproto/browser"
 	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
 	"github.com/chromedp/cdproto/storage"
 	"github.com/chromedp/cdproto/target"
 	"github.com/dop251/goja"
@@ -50,6 +55,8 @@ var _ api.BrowserContext = &BrowserContext{}
 type BrowserContext struct {
 	BaseEventEmitter
 
+	Tracing *Tracing `js:"tracing"` // Public JS API
+
 	ctx             context.Context
 	browser         *Browser
 	id              cdp.BrowserContextID
@@ -58,7 +65,33 @@ type BrowserContext struct {
 	logger          *log.Logger
 	vu              k6modules.VU
 
+	initScriptsMu                sync.RWMutex
 	evaluateOnNewDocumentSources []string
+
+	// the granted permissions by their origins, where
+	// an empty origin is for all the origins.
+	permissionsMu sync.Mutex
+	permissions   map[string][]string
+
+	// runs the handlers of browser context events and routes.
+	eventLoopQueue  eventLoopQueue
+	eventHandlersMu sync.RWMutex
+	eventHandlers   map[string][]goja.Callable
+
+	// the route handlers of the context, and the routes
+	// of the context and its pages that wait for a handler.
+	routes          routeHandlers
+	pendingRoutesMu sync.Mutex
+	pendingRoutes   map[*Route]*routeHandler
+
+	// the origins that the frames of the pages navigated to,
+	// which are tracked only if the context is reused.
+	visitedOriginsMu sync.Mutex
+	visitedOrigins   map[string]bool
+
+	// The temporary directory of the downloads,
+	// or an empty string if the context doesn't accept downloads.
+	downloadsPath string
 }
 
 // NewBrowserContext creates a new browser context.
@@ -74,55 +107,172 @@ func NewBrowserContext(
 		logger:           logger,
 		vu:               k6ext.GetVU(ctx),
 		timeoutSettings:  NewTimeoutSettings(nil),
+		permissions:      make(map[string][]string),
 	}
+	b.Tracing = NewTracing(ctx, &b, logger)
 
 	if opts != nil && len(opts.Permissions) > 0 {
 		b.GrantPermissions(opts.Permissions, nil)
 	}
+	if opts != nil && opts.Geolocation != nil {
+		b.grantGeolocationPermission()
+	}
+	if opts != nil && opts.StorageState != nil {
+		if err := b.restoreStorageState(opts.StorageState); err != nil {
+			k6ext.Panic(b.ctx, "restoring storage state: %w", err)
+		}
+	}
+	if opts != nil && opts.MetricURLGrouping != nil && opts.MetricURLGrouping.Group != nil {
+		// the function that groups the URLs of the metrics runs on the event loop.
+		b.startEventLoopQueue()
+	}
 
 	return &b
 }
 
+// restoreStorageState adds the cookies of the storage state to the browser
+// context, and restores the local storage of its origins when they load.
+func (b *BrowserContext) restoreStorageState(state *api.StorageState) error {
+	if err := b.restoreCookies(state.Cookies); err != nil {
+		return err
+	}
+	if len(state.Origins) > 0 {
+		source, err := restoreLocalStorageScript(state.Origins)
+		if err != nil {
+			return err
+		}
+		b.initScriptsMu.Lock()
+		b.evaluateOnNewDocumentSources = append(b.evaluateOnNewDocumentSources, source)
+		b.initScriptsMu.Unlock()
+	}
+
+	return nil
+}
+
+// restoreCookies adds the cookies of a storage state to the browser context.
+func (b *BrowserContext) restoreCookies(cookies []*api.Cookie) error {
+	if len(cookies) == 0 {
+		return nil
+	}
+	params := make([]*network.CookieParam, 0, len(cookies))
+	for _, c := range cookies {
+		p, err := newCookieParam(c)
+		if err != nil {
+			return fmt.Errorf("invalid cookie %q: %w", c.Name, err)
+		}
+		params = append(params, p)
+	}
+	action := storage.SetCookies(params).WithBrowserContextID(b.id)
+	if err := action.Do(cdp.WithExecutor(b.ctx, b.browser.getConn())); err != nil {
+		return fmt.Errorf("adding cookies: %w", err)
+	}
+	return nil
+}
+
+// restore grants the granted permissions, and adds the cookies of the
+// storage state option to the browser context again, after the browser
+// restarts. The other changes to the browser context, such as the added
+// cookies, aren't restored.
+func (b *BrowserContext) restore() error {
+	b.permissionsMu.Lock()
+	defer b.permissionsMu.Unlock()
+
+	for origin, permissions := range b.permissions {
+		perms, err := parsePermissions(permissions)
+		if err != nil {
+			return fmt.Errorf("granting permissions: %w", err)
+		}
+		action := cdpbrowser.GrantPermissions(perms).WithOrigin(origin).WithBrowserContextID(b.id)
+		if err := action.Do(cdp.WithExecutor(b.ctx, b.browser.getConn())); err != nil {
+			return fmt.Errorf("granting permissions: %w", err)
+		}
+	}
+	if b.opts != nil && b.opts.StorageState != nil {
+		return b.restoreCookies(b.opts.StorageState.Cookies)
+	}
+
+	return nil
+}
+
+// AddCookies adds the cookies to the browser context, so that all its
+// pages send them, including the pages that are created after the call.
 func (b *BrowserContext) AddCookies(cookies goja.Value) {
-	k6ext.Panic(b.ctx, "BrowserContext.addCookies(cookies) has not been implemented yet")
+	b.logger.Debugf("BrowserContext:AddCookies", "bctxid:%v", b.id)
+
+	params, err := parseCookies(b.vu.Runtime(), cookies)
+	if err != nil {
+		k6ext.Panic(b.ctx, "parsing cookies: %w", err)
+	}
+
+	action := storage.SetCookies(params).WithBrowserContextID(b.id)
+	if err := action.Do(cdp.WithExecutor(b.ctx, b.browser.getConn())); err != nil {
+		k6ext.Panic(b.ctx, "adding cookies: %w", err)
+	}
 }
 
-// AddInitScript adds a script that will be initialized on all new pages.
+// AddInitScript adds a script that runs in all the frames of the current and
+// future pages of the browser context, before any of the page scripts. The
+// current pages run it from their next navigation.
 func (b *BrowserContext) AddInitScript(script goja.Value, arg goja.Value) {
 	b.logger.Debugf("BrowserContext:AddInitScript", "bctxid:%v", b.id)
 
-	rt := b.vu.Runtime()
-
-	source := ""
-	if script != nil && !goja.IsUndefined(script) && !goja.IsNull(script) {
-		switch script.ExportType() {
-		case reflect.TypeOf(string("")):
-			source = script.String()
-		case reflect.TypeOf(goja.Object{}):
-			opts := script.ToObject(rt)
-			for _, k := range opts.Keys() {
-				switch k {
-				case "content":
-					source = opts.Get(k).String()
-				}
-			}
-		default:
-			_, isCallable := goja.AssertFunction(script)
-			if !isCallable {
-				source = fmt.Sprintf("(%s);", script.ToString().String())
-			} else {
-				source = fmt.Sprintf("(%s)(...args);", script.ToString().String())
-			}
-		}
+	source, err := initScriptSource(b.vu.Runtime(), script, arg)
+	if err != nil {
+		k6ext.Panic(b.ctx, "adding init script: %w", err)
 	}
 
+	b.initScriptsMu.Lock()
 	b.evaluateOnNewDocumentSources = append(b.evaluateOnNewDocumentSources, source)
+	b.initScriptsMu.Unlock()
 
 	for _, p := range b.browser.getPages() {
-		p.evaluateOnNewDocument(source)
+		if p.browserCtx != b {
+			continue
+		}
+		if err := p.evaluateOnNewDocument(source); err != nil {
+			k6ext.Panic(b.ctx, "adding init script: %w", err)
+		}
 	}
 }
 
+// initScripts returns the sources of the init scripts of the browser context.
+func (b *BrowserContext) initScripts() []string {
+	b.initScriptsMu.RLock()
+	defer b.initScriptsMu.RUnlock()
+
+	return append([]string(nil), b.evaluateOnNewDocumentSources...)
+}
+
+// initScriptSource returns the source of an init script that can be:
+//   - a string with the source.
+//   - an object with the source in its content property.
+//   - a function that is called with arg.
+func initScriptSource(rt *goja.Runtime, script goja.Value, arg goja.Value) (string, error) {
+	if !gojaValueExists(script) {
+		return "", errors.New("missing script")
+	}
+	if _, isCallable := goja.AssertFunction(script); isCallable {
+		argJSON := "undefined"
+		if gojaValueExists(arg) {
+			b, err := json.Marshal(arg.Export())
+			if err != nil {
+				return "", fmt.Errorf("serializing script argument: %w", err)
+			}
+			argJSON = string(b)
+		}
+		return fmt.Sprintf("(%s)(%s);", script.String(), argJSON), nil
+	}
+	if script.ExportType().Kind() == reflect.String {
+		return script.String(), nil
+	}
+	content := script.ToObject(rt).Get("content")
+	if !gojaValueExists(content) {
+		return "", errors.New("script must be a function, a string or an object with a content property")
+	}
+
+	return content.String(), nil
+}
+
 // Browser returns the browser instance that this browser context belongs to.
 func (b *BrowserContext) Browser() api.Browser {
 	return b.browser
@@ -133,19 +283,24 @@ func (b *BrowserContext) ClearCookies() {
 	b.logger.Debugf("BrowserContext:ClearCookies", "bctxid:%v", b.id)
 
 	action := storage.ClearCookies().WithBrowserContextID(b.id)
-	if err := action.Do(b.ctx); err != nil {
+	if err := action.Do(cdp.WithExecutor(b.ctx, b.browser.getConn())); err != nil {
 		k6ext.Panic(b.ctx, "clearing cookies: %w", err)
 	}
 }
 
-// ClearPermissions clears any permission overrides.
+// ClearPermissions resets the permissions of the browser context
+// to their defaults, so that the pages prompt for them again.
 func (b *BrowserContext) ClearPermissions() {
 	b.logger.Debugf("BrowserContext:ClearPermissions", "bctxid:%v", b.id)
 
+	b.permissionsMu.Lock()
+	defer b.permissionsMu.Unlock()
+
 	action := cdpbrowser.ResetPermissions().WithBrowserContextID(b.id)
-	if err := action.Do(b.ctx); err != nil {
+	if err := action.Do(cdp.WithExecutor(b.ctx, b.browser.getConn())); err != nil {
 		k6ext.Panic(b.ctx, "clearing permissions: %w", err)
 	}
+	b.permissions = make(map[string][]string)
 }
 
 // Close shuts down the browser context.
@@ -155,16 +310,96 @@ func (b *BrowserContext) Close() {
 	if b.id == "" {
 		k6ext.Panic(b.ctx, "default browser context can't be closed")
 	}
+	if b.browser.isReusedContext(b) {
+		// the next iteration resets and reuses the context.
+		b.logger.Debugf("BrowserContext:Close", "bctxid:%v reused", b.id)
+		return
+	}
+	if err := b.close(); err != nil {
+		k6ext.Panic(b.ctx, "%w", err)
+	}
+}
+
+func (b *BrowserContext) close() error {
+	pages := b.Pages()
+	for _, p := range pages {
+		p.(*Page).reportWebVitals()
+	}
+	b.Tracing.discard()
+	// don't let the requests wait for handlers that won't run.
+	b.continuePendingRoutes()
 	if err := b.browser.disposeContext(b.id); err != nil {
-		k6ext.Panic(b.ctx, "disposing browser context: %w", err)
+		return fmt.Errorf("disposing browser context: %w", err)
 	}
+	// the videos are complete when Close returns.
+	for _, p := range pages {
+		p.(*Page).finishVideo()
+	}
+	b.removeDownloads()
+	b.emit(EventBrowserContextClose, b)
+
+	return nil
 }
 
-func (b *BrowserContext) Cookies() []goja.Object {
-	k6ext.Panic(b.ctx, "BrowserContext.cookies() has not been implemented yet")
+// setDownloadBehavior makes the browser save the downloads of the context
+// to a temporary directory if the context accepts downloads, and cancel
+// them otherwise. The browser reports the progress of the downloads in
+// both cases.
+func (b *BrowserContext) setDownloadBehavior() error {
+	action := cdpbrowser.SetDownloadBehavior(cdpbrowser.SetDownloadBehaviorBehaviorDeny)
+	if b.opts.AcceptDownloads {
+		// the directory is kept when the browser restarts.
+		if b.downloadsPath == "" {
+			dir, err := os.MkdirTemp("", "xk6-browser-downloads-*")
+			if err != nil {
+				return fmt.Errorf("creating downloads directory: %w", err)
+			}
+			b.downloadsPath = dir
+		}
+		action = cdpbrowser.SetDownloadBehavior(cdpbrowser.SetDownloadBehaviorBehaviorAllowAndName).
+			WithDownloadPath(b.downloadsPath)
+	}
+	action = action.WithBrowserContextID(b.id).WithEventsEnabled(true)
+	if err := action.Do(cdp.WithExecutor(b.ctx, b.browser.getConn())); err != nil {
+		return fmt.Errorf("setting download behavior: %w", err)
+	}
+
 	return nil
 }
 
+// removeDownloads removes the downloaded files of the context.
+func (b *BrowserContext) removeDownloads() {
+	if b.downloadsPath == "" {
+		return
+	}
+	if err := os.RemoveAll(b.downloadsPath); err != nil {
+		b.logger.Errorf("BrowserContext:removeDownloads", "bctxid:%v err:%v", b.id, err)
+	}
+}
+
+// Cookies returns the cookies of the browser context. If URLs are given,
+// it only returns the cookies that the pages would send to any of them.
+func (b *BrowserContext) Cookies(urls ...string) []*api.Cookie {
+	b.logger.Debugf("BrowserContext:Cookies", "bctxid:%v urls:%v", b.id, urls)
+
+	action := storage.GetCookies().WithBrowserContextID(b.id)
+	cookies, err := action.Do(cdp.WithExecutor(b.ctx, b.browser.getConn()))
+	if err != nil {
+		k6ext.Panic(b.ctx, "getting cookies: %w", err)
+	}
+	cookies, err = filterCookies(cookies, urls)
+	if err != nil {
+		k6ext.Panic(b.ctx, "filtering cookies: %w", err)
+	}
+
+	result := make([]*api.Cookie, 0, len(cookies))
+	for _, c := range cookies {
+		result = append(result, toAPICookie(c))
+	}
+
+	return result
+}
+
 func (b *BrowserContext) ExposeBinding(name string, callback goja.Callable, opts goja.Value) {
 	k6ext.Panic(b.ctx, "BrowserContext.exposeBinding(name, callback, opts) has not been implemented yet")
 }
@@ -173,65 +408,86 @@ func (b *BrowserContext) ExposeFunction(name string, callback goja.Callable) {
 	k6ext.Panic(b.ctx, "BrowserContext.exposeFunction(name, callback) has not been implemented yet")
 }
 
-// GrantPermissions enables the specified permissions, all others will be disabled.
+// GrantPermissions grants the permissions to the pages of the browser
+// context, or only to the pages of the origin option if it's set.
+// The permissions that aren't granted are reset to their defaults.
 func (b *BrowserContext) GrantPermissions(permissions []string, opts goja.Value) {
-	b.logger.Debugf("BrowserContext:GrantPermissions", "bctxid:%v", b.id)
-
-	permsToProtocol := map[string]cdpbrowser.PermissionType{
-		"geolocation":          cdpbrowser.PermissionTypeGeolocation,
-		"midi":                 cdpbrowser.PermissionTypeMidi,
-		"midi-sysex":           cdpbrowser.PermissionTypeMidiSysex,
-		"notifications":        cdpbrowser.PermissionTypeNotifications,
-		"camera":               cdpbrowser.PermissionTypeVideoCapture,
-		"microphone":           cdpbrowser.PermissionTypeAudioCapture,
-		"background-sync":      cdpbrowser.PermissionTypeBackgroundSync,
-		"ambient-light-sensor": cdpbrowser.PermissionTypeSensors,
-		"accelerometer":        cdpbrowser.PermissionTypeSensors,
-		"gyroscope":            cdpbrowser.PermissionTypeSensors,
-		"magnetometer":         cdpbrowser.PermissionTypeSensors,
-		"accessibility-events": cdpbrowser.PermissionTypeAccessibilityEvents,
-		"clipboard-read":       cdpbrowser.PermissionTypeClipboardReadWrite,
-		"clipboard-write":      cdpbrowser.PermissionTypeClipboardSanitizedWrite,
-		"payment-handler":      cdpbrowser.PermissionTypePaymentHandler,
-	}
-	origin := ""
+	b.logger.Debugf("BrowserContext:GrantPermissions", "bctxid:%v permissions:%v", b.id, permissions)
 
-	rt := b.vu.Runtime()
-	if opts != nil && !goja.IsUndefined(opts) && !goja.IsNull(opts) {
-		opts := opts.ToObject(rt)
-		for _, k := range opts.Keys() {
-			if k == "origin" {
-				origin = opts.Get(k).String()
-				break
-			}
-		}
+	parsedOpts := NewGrantPermissionsOptions()
+	if err := parsedOpts.Parse(b.ctx, opts); err != nil {
+		k6ext.Panic(b.ctx, "parsing grant permissions options: %w", err)
+	}
+	perms, err := parsePermissions(permissions)
+	if err != nil {
+		k6ext.Panic(b.ctx, "granting permissions: %w", err)
 	}
 
-	perms := make([]cdpbrowser.PermissionType, 0, len(permissions))
-	for _, p := range permissions {
-		perms = append(perms, permsToProtocol[p])
+	b.permissionsMu.Lock()
+	defer b.permissionsMu.Unlock()
+
+	action := cdpbrowser.GrantPermissions(perms).WithOrigin(parsedOpts.Origin).WithBrowserContextID(b.id)
+	if err := action.Do(cdp.WithExecutor(b.ctx, b.browser.getConn())); err != nil {
+		k6ext.Panic(b.ctx, "granting permissions: %w", err)
 	}
+	b.permissions[parsedOpts.Origin] = append([]string{}, permissions...)
+}
 
-	action := cdpbrowser.GrantPermissions(perms).WithOrigin(origin).WithBrowserContextID(b.id)
-	if err := action.Do(cdp.WithExecutor(b.ctx, b.browser.conn)); err != nil {
-		k6ext.Panic(b.ctx, "override permissions: %w", err)
+// grantGeolocationPermission grants the geolocation permission to all the
+// origins, and keeps the other granted permissions, if it isn't granted yet.
+// Otherwise, the pages would prompt for the permission, and wouldn't get the
+// emulated geolocation.
+func (b *BrowserContext) grantGeolocationPermission() {
+	b.permissionsMu.Lock()
+	permissions := b.permissions[""]
+	b.permissionsMu.Unlock()
+	for _, p := range permissions {
+		if p == "geolocation" {
+			return
+		}
 	}
+
+	b.logger.Warnf("BrowserContext:grantGeolocationPermission",
+		"granting the geolocation permission to emulate the geolocation;"+
+			" grant it with the permissions option or grantPermissions to avoid this warning")
+	b.GrantPermissions(append(permissions, "geolocation"), nil)
 }
 
-// NewCDPSession returns a new CDP session attached to this target.
-func (b *BrowserContext) NewCDPSession() api.CDPSession {
-	k6ext.Panic(b.ctx, "BrowserContext.newCDPSession() has not been implemented yet")
-	return nil
+// NewCDPSession returns a new raw CDP session attached to the target of the
+// page of the browser context. The session is detached when the page closes.
+func (b *BrowserContext) NewCDPSession(page api.Page) api.CDPSession {
+	b.logger.Debugf("BrowserContext:NewCDPSession", "bctxid:%v", b.id)
+
+	p, ok := page.(*Page)
+	if !ok || p == nil {
+		k6ext.Panic(b.ctx, "creating CDP session: a page is required")
+	}
+	if p.browserCtx != b {
+		k6ext.Panic(b.ctx, "creating CDP session: the page belongs to another browser context")
+	}
+	s, err := NewCDPSession(b.ctx, b.browser, p, b.logger)
+	if err != nil {
+		k6ext.Panic(b.ctx, "creating CDP session: %w", err)
+	}
+
+	return s
 }
 
 // NewPage creates a new page inside this browser context.
 func (b *BrowserContext) NewPage() api.Page {
 	b.logger.Debugf("BrowserContext:NewPage", "bctxid:%v", b.id)
 
+	if err := b.browser.restartIfCrashed(); err != nil {
+		k6ext.Panic(b.ctx, "newPageInContext: %w", err)
+	}
+	if p := b.browser.reusedPage(b); p != nil {
+		return p
+	}
 	p, err := b.browser.newPageInContext(b.id)
 	if err != nil {
 		k6ext.Panic(b.ctx, "newPageInContext: %w", err)
 	}
+	b.browser.keepPage(b, p)
 
 	var (
 		bctxid cdp.BrowserContextID
@@ -248,17 +504,171 @@ func (b *BrowserContext) NewPage() api.Page {
 	return p
 }
 
-// Pages returns a list of pages inside this browser context.
+// Pages returns the open pages of the browser context in the order they
+// were opened, including the ones that its pages opened, such as popups.
+// The closed and crashed pages aren't included.
 func (b *BrowserContext) Pages() []api.Page {
-	pages := make([]api.Page, 1)
+	pages := []api.Page{}
 	for _, p := range b.browser.getPages() {
-		pages = append(pages, p)
+		if p.browserCtx == b && !p.isClosedOrCrashed() {
+			pages = append(pages, p)
+		}
 	}
 	return pages
 }
 
-func (b *BrowserContext) Route(url goja.Value, handler goja.Callable) {
-	k6ext.Panic(b.ctx, "BrowserContext.route(url, handler) has not been implemented yet")
+// On registers a handler that is called on the event loop every time the
+// browser context emits the event, until it closes. The supported events are:
+//   - page: the handler is called with a new Page of the browser context,
+//     such as a page that another page opened.
+func (b *BrowserContext) On(event string, handler goja.Callable) {
+	b.logger.Debugf("BrowserContext:On", "bctxid:%v event:%q", b.id, event)
+
+	if event != EventBrowserContextPage {
+		k6ext.Panic(b.ctx, "unknown browser context event: %q", event)
+	}
+	if handler == nil {
+		k6ext.Panic(b.ctx, "missing handler of browser context event %q", event)
+	}
+
+	b.eventHandlersMu.Lock()
+	if b.eventHandlers == nil {
+		b.eventHandlers = make(map[string][]goja.Callable)
+	}
+	b.eventHandlers[event] = append(b.eventHandlers[event], handler)
+	b.eventHandlersMu.Unlock()
+
+	b.startEventLoopQueue()
+}
+
+// onPage emits the new page of the browser context, and calls
+// the page event handlers with it, if there are any.
+func (b *BrowserContext) onPage(p *Page) {
+	if b.Tracing != nil {
+		b.Tracing.onPage(p)
+	}
+	b.emit(EventBrowserContextPage, p)
+
+	b.eventHandlersMu.RLock()
+	handlers := b.eventHandlers[EventBrowserContextPage]
+	b.eventHandlersMu.RUnlock()
+	if len(handlers) == 0 {
+		return
+	}
+	b.eventLoopQueue.push(func() error {
+		v := b.vu.Runtime().ToValue(p)
+		for _, handler := range handlers {
+			if _, err := handler(goja.Undefined(), v); err != nil {
+				return fmt.Errorf("calling handler of browser context event %q: %w", EventBrowserContextPage, err)
+			}
+		}
+		return nil
+	})
+}
+
+// startEventLoopQueue starts running the handlers of browser context events
+// on the event loop until the browser context closes or the iteration ends.
+// It must be called on the event loop.
+func (b *BrowserContext) startEventLoopQueue() {
+	ctx, cancel := context.WithCancel(b.ctx)
+	closed := make(chan Event)
+	b.on(ctx, []string{EventBrowserContextClose}, closed)
+	if !b.eventLoopQueue.start(ctx, b.vu) {
+		cancel()
+		return
+	}
+	// the queue stops when the iteration ends, and so does the watching.
+	iterDone := b.vu.Context().Done()
+	go func() {
+		defer cancel()
+		select {
+		case <-closed:
+		case <-ctx.Done():
+		case <-iterDone:
+		}
+	}()
+}
+
+// Route calls the handler with a Route for every request of the pages of
+// the browser context, including its popups and iframes, to a URL that
+// matches the URL pattern. The routes of a page take precedence over
+// the routes of its browser context. See Page.Route for the details.
+func (b *BrowserContext) Route(url goja.Value, handler goja.Value) {
+	b.logger.Debugf("BrowserContext:Route", "bctxid:%v url:%v", b.id, url)
+
+	h, err := newRouteHandler(b.vu.Runtime(), url, handler)
+	if err != nil {
+		k6ext.Panic(b.ctx, "adding route: %w", err)
+	}
+	b.routes.add(h)
+	b.startEventLoopQueue()
+	if err := b.updateRequestInterception(); err != nil {
+		k6ext.Panic(b.ctx, "adding route: %w", err)
+	}
+}
+
+// updateRequestInterception updates the interception
+// of the requests of the pages of the browser context.
+func (b *BrowserContext) updateRequestInterception() error {
+	for _, p := range b.Pages() {
+		if err := p.(*Page).updateRequestInterception(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *BrowserContext) addPendingRoute(r *Route, h *routeHandler) {
+	b.pendingRoutesMu.Lock()
+	defer b.pendingRoutesMu.Unlock()
+
+	if b.pendingRoutes == nil {
+		b.pendingRoutes = make(map[*Route]*routeHandler)
+	}
+	b.pendingRoutes[r] = h
+}
+
+func (b *BrowserContext) removePendingRoute(r *Route) {
+	b.pendingRoutesMu.Lock()
+	defer b.pendingRoutesMu.Unlock()
+
+	delete(b.pendingRoutes, r)
+}
+
+// continuePendingRoutes continues the requests of the routes
+// that wait for a handler, without calling the handlers.
+func (b *BrowserContext) continuePendingRoutes() {
+	b.pendingRoutesMu.Lock()
+	routes := b.pendingRoutes
+	b.pendingRoutes = nil
+	b.pendingRoutesMu.Unlock()
+
+	for r := range routes {
+		r.continueUnhandled()
+	}
+}
+
+// continueRemovedRoutes continues the requests of the routes that wait
+// for one of the removed handlers, without calling the handlers.
+func (b *BrowserContext) continueRemovedRoutes(removed []*routeHandler) {
+	isRemoved := make(map[*routeHandler]bool, len(removed))
+	for _, h := range removed {
+		isRemoved[h] = true
+	}
+
+	var routes []*Route
+	b.pendingRoutesMu.Lock()
+	for r, h := range b.pendingRoutes {
+		if isRemoved[h] {
+			routes = append(routes, r)
+			delete(b.pendingRoutes, r)
+		}
+	}
+	b.pendingRoutesMu.Unlock()
+
+	for _, r := range routes {
+		r.continueUnhandled()
+	}
 }
 
 // SetDefaultNavigationTimeout sets the default navigation timeout in milliseconds.
@@ -279,19 +689,28 @@ func (b *BrowserContext) SetExtraHTTPHeaders(headers map[string]string) {
 	k6ext.Panic(b.ctx, "BrowserContext.setExtraHTTPHeaders(headers) has not been implemented yet")
 }
 
-// SetGeolocation overrides the geo location of the user.
+// SetGeolocation overrides the geolocation of the pages of the browser
+// context, or clears the override if the geolocation is null. It grants the
+// geolocation permission with a warning if it isn't granted to all origins.
 func (b *BrowserContext) SetGeolocation(geolocation goja.Value) {
 	b.logger.Debugf("BrowserContext:SetGeolocation", "bctxid:%v", b.id)
 
-	g := NewGeolocation()
-	if err := g.Parse(b.ctx, geolocation); err != nil {
-		k6ext.Panic(b.ctx, "parsing geo location: %v", err)
+	var g *Geolocation
+	if gojaValueExists(geolocation) {
+		g = NewGeolocation()
+		if err := g.Parse(b.ctx, geolocation); err != nil {
+			k6ext.Panic(b.ctx, "parsing geolocation: %w", err)
+		}
+		b.grantGeolocationPermission()
 	}
 
 	b.opts.Geolocation = g
 	for _, p := range b.browser.getPages() {
+		if p.browserCtx != b {
+			continue
+		}
 		if err := p.updateGeolocation(); err != nil {
-			k6ext.Panic(b.ctx, "updating geo location in target ID %s: %w", p.targetID, err)
+			k6ext.Panic(b.ctx, "updating geolocation in target ID %s: %w", p.targetID, err)
 		}
 	}
 }
@@ -314,26 +733,113 @@ func (b *BrowserContext) SetHTTPCredentials(httpCredentials goja.Value) {
 
 	b.opts.HttpCredentials = c
 	for _, p := range b.browser.getPages() {
+		if p.browserCtx != b {
+			continue
+		}
 		p.updateHttpCredentials()
 	}
 }
 
-// SetOffline toggles the browser's connectivity on/off.
+// SetOffline toggles the connectivity of the pages of the browser context
+// on/off, including the pages that are created after the call.
 func (b *BrowserContext) SetOffline(offline bool) {
 	b.logger.Debugf("BrowserContext:SetOffline", "bctxid:%v offline:%t", b.id, offline)
 
 	b.opts.Offline = offline
 	for _, p := range b.browser.getPages() {
+		if p.browserCtx != b {
+			continue
+		}
 		p.updateOffline()
 	}
 }
 
-func (b *BrowserContext) StorageState(opts goja.Value) {
-	k6ext.Panic(b.ctx, "BrowserContext.storageState(opts) has not been implemented yet")
+// StorageState returns the cookies and the local storage of the browser
+// context, and writes them to the file at the path option if it's set.
+// A new browser context can start with them with the storageState option.
+func (b *BrowserContext) StorageState(opts goja.Value) *api.StorageState {
+	b.logger.Debugf("BrowserContext:StorageState", "bctxid:%v", b.id)
+
+	parsedOpts := NewStorageStateOptions()
+	if err := parsedOpts.Parse(b.ctx, opts); err != nil {
+		k6ext.Panic(b.ctx, "parsing storage state options: %w", err)
+	}
+
+	state := &api.StorageState{
+		Cookies: b.Cookies(),
+		Origins: b.originStates(),
+	}
+	if parsedOpts.Path != "" {
+		if err := writeStorageState(parsedOpts.Path, state); err != nil {
+			k6ext.Panic(b.ctx, "%w", err)
+		}
+	}
+
+	return state
 }
 
-func (b *BrowserContext) Unroute(url goja.Value, handler goja.Callable) {
-	k6ext.Panic(b.ctx, "BrowserContext.unroute(url, handler) has not been implemented yet")
+// originStates returns the local storage of the origins of the frames of
+// the pages, and of the restored origins that the pages haven't loaded.
+func (b *BrowserContext) originStates() []*api.OriginState {
+	var (
+		origins = []*api.OriginState{}
+		seen    = make(map[string]bool)
+		rt      = b.vu.Runtime()
+	)
+	for _, p := range b.browser.getPages() {
+		if p.browserCtx != b {
+			continue
+		}
+		for _, f := range p.frameManager.Frames() {
+			f, ok := f.(*Frame)
+			if !ok {
+				continue
+			}
+			f.waitForExecutionContext(mainWorld)
+			opts := evalOptions{forceCallable: true, returnByValue: true}
+			result, err := f.evaluate(f.ctx, mainWorld, opts, rt.ToValue(localStorageScript))
+			if err != nil {
+				b.logger.Debugf("BrowserContext:originStates", "fid:%s furl:%q err:%v", f.ID(), f.URL(), err)
+				continue
+			}
+			var o api.OriginState
+			if err := json.Unmarshal([]byte(asGojaValue(b.ctx, result).String()), &o); err != nil {
+				k6ext.Panic(b.ctx, "parsing local storage of frame %q: %w", f.URL(), err)
+			}
+			// the opaque origins, such as about:blank, serialize to "null".
+			if o.Origin == "null" || len(o.LocalStorage) == 0 || seen[o.Origin] {
+				continue
+			}
+			seen[o.Origin] = true
+			origins = append(origins, &o)
+		}
+	}
+	if b.opts.StorageState != nil {
+		for _, o := range b.opts.StorageState.Origins {
+			if !seen[o.Origin] {
+				seen[o.Origin] = true
+				origins = append(origins, o)
+			}
+		}
+	}
+
+	return origins
+}
+
+// Unroute removes the route handlers of the URL pattern that were added with
+// BrowserContext.Route, or only the given handler of it if handler is set.
+// See Page.Unroute for the details.
+func (b *BrowserContext) Unroute(url goja.Value, handler goja.Value) {
+	b.logger.Debugf("BrowserContext:Unroute", "bctxid:%v url:%v", b.id, url)
+
+	removed := b.routes.remove(url, handler)
+	if len(removed) == 0 {
+		return
+	}
+	b.continueRemovedRoutes(removed)
+	if err := b.updateRequestInterception(); err != nil {
+		k6ext.Panic(b.ctx, "removing route: %w", err)
+	}
 }
 
 func (b *BrowserContext) WaitForEvent(event string, optsOrPredicate goja.Value) interface{} {
@@ -344,7 +850,7 @@ func (b *BrowserContext) WaitForEvent(event string, optsOrPredicate goja.Value)
 
 	var isCallable bool
 	var predicateFn goja.Callable = nil
-	timeout := time.Duration(b.browser.launchOpts.Timeout * time.Second)
+	timeout := b.timeoutSettings.timeout()
 
 	if optsOrPredicate != nil && !goja.IsUndefined(optsOrPredicate) && !goja.IsNull(optsOrPredicate) {
 		switch optsOrPredicate.ExportType() {
@@ -416,7 +922,7 @@ func (b *BrowserContext) WaitForEvent(event string, optsOrPredicate goja.Value)
 	select {
 	case <-b.ctx.Done():
 		b.logger.Debugf("BrowserContext:WaitForEvent:ctx.Done", "bctxid:%v event:%q", b.id, event)
-	case <-time.After(timeout):
+	case <-timeoutAfter(timeout):
 		b.logger.Debugf("BrowserContext:WaitForEvent:timeout", "bctxid:%v event:%q", b.id, event)
 	case evData := <-ch:
 		b.logger.Debugf("BrowserContext:WaitForEvent:evData", "bctxid:%v event:%q", b.id, event)
@@ -427,5 +933,5 @@ func (b *BrowserContext) WaitForEvent(event string, optsOrPredicate goja.Value)
 }
 
 func (b *BrowserContext) getSession(id target.SessionID) *Session {
-	return b.browser.conn.getSession(id)
+	return b.browser.getConn().getSession(id)
 }
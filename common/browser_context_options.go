@@ -23,34 +23,60 @@ package common
 import (
 	"context"
 	"fmt"
+	"regexp"
+	"strings"
+	"time"
 
+	// Embeds the time zone database to validate
+	// the timezoneID option on every system.
+	_ "time/tzdata"
+
+	"github.com/grafana/xk6-browser/api"
 	"github.com/grafana/xk6-browser/k6ext"
 
 	"github.com/dop251/goja"
 )
 
+// Matches the attribute names that can be used as test ID attributes.
+var reAttributeName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_-]*$`)
+
+// Matches the BCP 47 language tags, such as "en-US" or "zh-Hant-TW".
+var reLanguageTag = regexp.MustCompile(`^[a-zA-Z]{2,8}(-[a-zA-Z0-9]{1,8})*$`)
+
 // BrowserContextOptions stores browser context options.
 type BrowserContextOptions struct {
-	AcceptDownloads   bool              `js:"acceptDownloads"`
-	BypassCSP         bool              `js:"bypassCSP"`
-	ColorScheme       ColorScheme       `js:"colorScheme"`
-	DeviceScaleFactor float64           `js:"deviceScaleFactor"`
-	ExtraHTTPHeaders  map[string]string `js:"extraHTTPHeaders"`
-	Geolocation       *Geolocation      `js:"geolocation"`
-	HasTouch          bool              `js:"hasTouch"`
-	HttpCredentials   *Credentials      `js:"httpCredentials"`
-	IgnoreHTTPSErrors bool              `js:"ignoreHTTPSErrors"`
-	IsMobile          bool              `js:"isMobile"`
-	JavaScriptEnabled bool              `js:"javaScriptEnabled"`
-	Locale            string            `js:"locale"`
-	Offline           bool              `js:"offline"`
-	Permissions       []string          `js:"permissions"`
-	ReducedMotion     ReducedMotion     `js:"reducedMotion"`
-	Screen            *Screen           `js:"screen"`
-	TimezoneID        string            `js:"timezoneID"`
-	UserAgent         string            `js:"userAgent"`
-	VideosPath        string            `js:"videosPath"`
-	Viewport          *Viewport         `js:"viewport"`
+	AcceptDownloads      bool                   `js:"acceptDownloads"`
+	BackgroundThrottling bool                   `js:"backgroundThrottling"`
+	BlockResourceTypes   []string               `js:"blockResourceTypes"`
+	BypassCSP            bool                   `js:"bypassCSP"`
+	ColorScheme          ColorScheme            `js:"colorScheme"`
+	DeviceScaleFactor    float64                `js:"deviceScaleFactor"`
+	ExtraHTTPHeaders     map[string]string      `js:"extraHTTPHeaders"`
+	Geolocation          *Geolocation           `js:"geolocation"`
+	HasTouch             bool                   `js:"hasTouch"`
+	HttpCredentials      *Credentials           `js:"httpCredentials"`
+	IgnoreHTTPSErrors    bool                   `js:"ignoreHTTPSErrors"`
+	IsMobile             bool                   `js:"isMobile"`
+	JavaScriptEnabled    bool                   `js:"javaScriptEnabled"`
+	Locale               string                 `js:"locale"`
+	MetricURLGrouping    *MetricURLGrouping     `js:"metricURLGrouping"`
+	NetworkIdle          *NetworkIdleOptions    `js:"networkIdle"`
+	Offline              bool                   `js:"offline"`
+	Permissions          []string               `js:"permissions"`
+	Platform             string                 `js:"platform"`
+	Proxy                *ProxyOptions          `js:"proxy"`
+	RecordVideo          *VideoOptions          `js:"recordVideo"`
+	ReducedMotion        ReducedMotion          `js:"reducedMotion"`
+	RequestMetrics       *RequestMetricsOptions `js:"requestMetrics"`
+	Screen               *Screen                `js:"screen"`
+	ScreenOrientation    ScreenOrientation      `js:"screenOrientation"`
+	StorageState         *api.StorageState      `js:"storageState"`
+	StrictSelectors      bool                   `js:"strictSelectors"`
+	TestIDAttribute      string                 `js:"testIdAttribute"`
+	TimezoneID           string                 `js:"timezoneID"`
+	UserAgent            string                 `js:"userAgent"`
+	VideosPath           string                 `js:"videosPath"`
+	Viewport             *Viewport              `js:"viewport"`
 }
 
 // NewBrowserContextOptions creates a default set of browser context options.
@@ -61,9 +87,12 @@ func NewBrowserContextOptions() *BrowserContextOptions {
 		ExtraHTTPHeaders:  make(map[string]string),
 		JavaScriptEnabled: true,
 		Locale:            DefaultLocale,
+		NetworkIdle:       NewNetworkIdleOptions(),
 		Permissions:       []string{},
 		ReducedMotion:     ReducedMotionNoPreference,
+		RequestMetrics:    NewRequestMetricsOptions(),
 		Screen:            &Screen{Width: DefaultScreenWidth, Height: DefaultScreenHeight},
+		TestIDAttribute:   DefaultTestIDAttribute,
 		Viewport:          &Viewport{Width: DefaultScreenWidth, Height: DefaultScreenHeight},
 	}
 }
@@ -76,6 +105,21 @@ func (b *BrowserContextOptions) Parse(ctx context.Context, opts goja.Value) erro
 			switch k {
 			case "acceptDownloads":
 				b.AcceptDownloads = opts.Get(k).ToBoolean()
+			case "backgroundThrottling":
+				b.BackgroundThrottling = opts.Get(k).ToBoolean()
+			case "blockResourceTypes":
+				ts, ok := opts.Get(k).Export().([]interface{})
+				if !ok {
+					return fmt.Errorf("blockResourceTypes must be an array, got %T", opts.Get(k).Export())
+				}
+				b.BlockResourceTypes = make([]string, 0, len(ts))
+				for _, t := range ts {
+					typ, err := parseResourceType(fmt.Sprintf("%v", t))
+					if err != nil {
+						return fmt.Errorf("parsing blockResourceTypes: %w", err)
+					}
+					b.BlockResourceTypes = append(b.BlockResourceTypes, strings.ToLower(typ.String()))
+				}
 			case "bypassCSP":
 				b.BypassCSP = opts.Get(k).ToBoolean()
 			case "colorScheme":
@@ -95,9 +139,13 @@ func (b *BrowserContextOptions) Parse(ctx context.Context, opts goja.Value) erro
 					b.ExtraHTTPHeaders[k] = headers.Get(k).String()
 				}
 			case "geolocation":
+				if !gojaValueExists(opts.Get(k)) {
+					b.Geolocation = nil
+					break
+				}
 				geolocation := NewGeolocation()
-				if err := geolocation.Parse(ctx, opts.Get(k).ToObject(rt)); err != nil {
-					return err
+				if err := geolocation.Parse(ctx, opts.Get(k)); err != nil {
+					return fmt.Errorf("parsing geolocation: %w", err)
 				}
 				b.Geolocation = geolocation
 			case "hasTouch":
@@ -115,7 +163,23 @@ func (b *BrowserContextOptions) Parse(ctx context.Context, opts goja.Value) erro
 			case "javaScriptEnabled":
 				b.JavaScriptEnabled = opts.Get(k).ToBoolean()
 			case "locale":
-				b.Locale = opts.Get(k).String()
+				locale := opts.Get(k).String()
+				if locale != "" && !reLanguageTag.MatchString(locale) {
+					return fmt.Errorf("invalid locale %q: must be a BCP 47 language tag, such as \"en-US\"", locale)
+				}
+				b.Locale = locale
+			case "metricURLGrouping":
+				var g MetricURLGrouping
+				if err := g.Parse(opts.Get(k)); err != nil {
+					return fmt.Errorf("parsing metricURLGrouping: %w", err)
+				}
+				b.MetricURLGrouping = &g
+			case "networkIdle":
+				networkIdle := NewNetworkIdleOptions()
+				if err := networkIdle.Parse(ctx, opts.Get(k)); err != nil {
+					return fmt.Errorf("parsing networkIdle: %w", err)
+				}
+				b.NetworkIdle = networkIdle
 			case "offline":
 				b.Offline = opts.Get(k).ToBoolean()
 			case "permissions":
@@ -124,6 +188,23 @@ func (b *BrowserContextOptions) Parse(ctx context.Context, opts goja.Value) erro
 						b.Permissions = append(b.Permissions, fmt.Sprintf("%v", p))
 					}
 				}
+				if _, err := parsePermissions(b.Permissions); err != nil {
+					return fmt.Errorf("parsing permissions: %w", err)
+				}
+			case "platform":
+				b.Platform = opts.Get(k).String()
+			case "proxy":
+				proxy := &ProxyOptions{}
+				if err := proxy.Parse(ctx, opts.Get(k)); err != nil {
+					return fmt.Errorf("parsing proxy: %w", err)
+				}
+				b.Proxy = proxy
+			case "recordVideo":
+				recordVideo := NewVideoOptions()
+				if err := recordVideo.Parse(ctx, opts.Get(k)); err != nil {
+					return fmt.Errorf("parsing recordVideo: %w", err)
+				}
+				b.RecordVideo = recordVideo
 			case "reducedMotion":
 				switch ReducedMotion(opts.Get(k).String()) {
 				case "reduce":
@@ -131,14 +212,44 @@ func (b *BrowserContextOptions) Parse(ctx context.Context, opts goja.Value) erro
 				default:
 					b.ReducedMotion = ReducedMotionNoPreference
 				}
+			case "requestMetrics":
+				requestMetrics := NewRequestMetricsOptions()
+				if err := requestMetrics.Parse(ctx, opts.Get(k)); err != nil {
+					return fmt.Errorf("parsing requestMetrics: %w", err)
+				}
+				b.RequestMetrics = requestMetrics
 			case "screen":
 				screen := &Screen{}
 				if err := screen.Parse(ctx, opts.Get(k).ToObject(rt)); err != nil {
 					return err
 				}
 				b.Screen = screen
+			case "screenOrientation":
+				o, err := parseScreenOrientation(opts.Get(k).String())
+				if err != nil {
+					return err
+				}
+				b.ScreenOrientation = o
+			case "storageState":
+				state, err := parseStorageState(opts.Get(k))
+				if err != nil {
+					return fmt.Errorf("parsing storageState: %w", err)
+				}
+				b.StorageState = state
+			case "strictSelectors":
+				b.StrictSelectors = opts.Get(k).ToBoolean()
+			case "testIdAttribute":
+				attr := opts.Get(k).String()
+				if !reAttributeName.MatchString(attr) {
+					return fmt.Errorf("invalid testIdAttribute %q: must be an attribute name", attr)
+				}
+				b.TestIDAttribute = attr
 			case "timezoneID":
-				b.TimezoneID = opts.Get(k).String()
+				id, err := parseTimezoneID(opts.Get(k).String())
+				if err != nil {
+					return err
+				}
+				b.TimezoneID = id
 			case "userAgent":
 				b.UserAgent = opts.Get(k).String()
 			case "viewport":
@@ -152,3 +263,51 @@ func (b *BrowserContextOptions) Parse(ctx context.Context, opts goja.Value) erro
 	}
 	return nil
 }
+
+// hasUserAgentOverride returns true if the pages and the
+// workers of the browser context override the user agent.
+func (b *BrowserContextOptions) hasUserAgentOverride() bool {
+	return b.UserAgent != "" || b.Locale != "" || b.Platform != ""
+}
+
+// parseTimezoneID validates the IANA time zone ID, such as "Europe/Rome",
+// where an empty ID doesn't override the time zone. Chrome also rejects
+// the invalid IDs, but only when a page is created.
+func parseTimezoneID(id string) (string, error) {
+	if id == "" {
+		return "", nil
+	}
+	// time.LoadLocation accepts "Local" for the local time zone.
+	if id == "Local" {
+		return "", fmt.Errorf("invalid timezoneID %q: must be an IANA time zone ID", id)
+	}
+	if _, err := time.LoadLocation(id); err != nil {
+		return "", fmt.Errorf("invalid timezoneID %q: must be an IANA time zone ID: %w", id, err)
+	}
+	return id, nil
+}
+
+// GrantPermissionsOptions are the options of BrowserContext.grantPermissions.
+type GrantPermissionsOptions struct {
+	// Origin limits the permissions to the pages of the origin, if it's set.
+	Origin string `js:"origin"`
+}
+
+// NewGrantPermissionsOptions returns the default grant permissions options.
+func NewGrantPermissionsOptions() *GrantPermissionsOptions {
+	return &GrantPermissionsOptions{}
+}
+
+// Parse parses the grant permissions options.
+func (g *GrantPermissionsOptions) Parse(ctx context.Context, opts goja.Value) error {
+	if !gojaValueExists(opts) {
+		return nil
+	}
+	obj := opts.ToObject(k6ext.Runtime(ctx))
+	for _, k := range obj.Keys() {
+		if k == "origin" {
+			g.Origin = obj.Get(k).String()
+		}
+	}
+	return nil
+}
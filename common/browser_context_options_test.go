@@ -1,6 +1,7 @@
 package common
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/grafana/xk6-browser/k6ext/k6test"
@@ -20,4 +21,201 @@ func TestBrowserContextOptionsPermissions(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Len(t, opts.Permissions, 2)
 	assert.Equal(t, opts.Permissions, []string{"camera", "microphone"})
+
+	opts = BrowserContextOptions{}
+	err = opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{
+		"permissions": []interface{}{"camera", "webcam"},
+	}))
+	assert.ErrorContains(t, err, `parsing permissions: invalid permission "webcam": must be one of: `)
+}
+
+func TestGrantPermissionsOptionsParse(t *testing.T) {
+	vu := k6test.NewVU(t)
+
+	opts := NewGrantPermissionsOptions()
+	assert.NoError(t, opts.Parse(vu.Context(), nil))
+	assert.Empty(t, opts.Origin)
+
+	err := opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{
+		"origin": "https://example.com",
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com", opts.Origin)
+}
+
+func TestBrowserContextOptionsTestIDAttribute(t *testing.T) {
+	vu := k6test.NewVU(t)
+
+	opts := NewBrowserContextOptions()
+	assert.Equal(t, "data-testid", opts.TestIDAttribute)
+
+	err := opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{
+		"testIdAttribute": "data-qa",
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, "data-qa", opts.TestIDAttribute)
+
+	err = opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{
+		"testIdAttribute": "data qa",
+	}))
+	assert.EqualError(t, err, `invalid testIdAttribute "data qa": must be an attribute name`)
+}
+
+func TestBrowserContextOptionsScreenOrientation(t *testing.T) {
+	vu := k6test.NewVU(t)
+
+	opts := NewBrowserContextOptions()
+	err := opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{
+		"screenOrientation": "landscape-secondary",
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, ScreenOrientationLandscapeSecondary, opts.ScreenOrientation)
+
+	err = opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{
+		"screenOrientation": "sideways",
+	}))
+	assert.EqualError(t, err, `invalid screenOrientation "sideways": must be one of: `+
+		`portrait-primary, portrait-secondary, landscape-primary, landscape-secondary`)
+}
+
+func TestBrowserContextOptionsBlockResourceTypes(t *testing.T) {
+	vu := k6test.NewVU(t)
+
+	opts := NewBrowserContextOptions()
+	err := opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{
+		"blockResourceTypes": []interface{}{"image", "Font", "media"},
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"image", "font", "media"}, opts.BlockResourceTypes)
+
+	err = opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{
+		"blockResourceTypes": []interface{}{"image", "video"},
+	}))
+	assert.ErrorContains(t, err, `parsing blockResourceTypes: invalid resource type "video"`)
+
+	err = opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{
+		"blockResourceTypes": "image",
+	}))
+	assert.EqualError(t, err, "blockResourceTypes must be an array, got string")
+}
+
+func TestBrowserContextOptionsProxy(t *testing.T) {
+	vu := k6test.NewVU(t)
+
+	opts := NewBrowserContextOptions()
+	assert.Nil(t, opts.Proxy)
+	err := opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{
+		"proxy": map[string]interface{}{
+			"server":   "localhost:8080",
+			"bypass":   ".example.com, localhost",
+			"username": "user",
+			"password": "pass",
+		},
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, &ProxyOptions{
+		Server:   "http://localhost:8080",
+		Bypass:   ".example.com, localhost",
+		Username: "user",
+		Password: "pass",
+	}, opts.Proxy)
+	assert.Equal(t, "*.example.com;localhost;<-loopback>", opts.Proxy.bypassList())
+	assert.Equal(t, &Credentials{Username: "user", Password: "pass"}, opts.Proxy.credentials())
+
+	opts = NewBrowserContextOptions()
+	err = opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{
+		"proxy": map[string]interface{}{"server": "socks5://proxy:1080"},
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, "socks5://proxy:1080", opts.Proxy.Server)
+	assert.Equal(t, "<-loopback>", opts.Proxy.bypassList())
+	assert.Nil(t, opts.Proxy.credentials())
+
+	err = opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{
+		"proxy": map[string]interface{}{"bypass": "localhost"},
+	}))
+	assert.EqualError(t, err, "parsing proxy: server is required")
+}
+
+func TestBrowserContextOptionsTimezoneID(t *testing.T) {
+	vu := k6test.NewVU(t)
+
+	opts := NewBrowserContextOptions()
+	for _, id := range []string{"America/New_York", "Asia/Tokyo", "UTC"} {
+		err := opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{
+			"timezoneID": id,
+		}))
+		assert.NoError(t, err)
+		assert.Equal(t, id, opts.TimezoneID)
+	}
+
+	err := opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{
+		"timezoneID": "",
+	}))
+	assert.NoError(t, err)
+	assert.Empty(t, opts.TimezoneID)
+
+	for _, id := range []string{"Local", "Mars/Olympus_Mons"} {
+		err := opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{
+			"timezoneID": id,
+		}))
+		assert.ErrorContains(t, err, fmt.Sprintf("invalid timezoneID %q: must be an IANA time zone ID", id))
+	}
+}
+
+func TestBrowserContextOptionsLocale(t *testing.T) {
+	vu := k6test.NewVU(t)
+
+	opts := NewBrowserContextOptions()
+	assert.Equal(t, DefaultLocale, opts.Locale)
+	for _, locale := range []string{"de-DE", "fr", "zh-Hant-TW", ""} {
+		err := opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{
+			"locale": locale,
+		}))
+		assert.NoError(t, err)
+		assert.Equal(t, locale, opts.Locale)
+	}
+
+	for _, locale := range []string{"de_DE", "en US", "-US"} {
+		err := opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{
+			"locale": locale,
+		}))
+		assert.EqualError(t, err, fmt.Sprintf(`invalid locale %q: must be a BCP 47 language tag, such as "en-US"`, locale))
+	}
+}
+
+func TestBrowserContextOptionsUserAgent(t *testing.T) {
+	vu := k6test.NewVU(t)
+
+	opts := NewBrowserContextOptions()
+	opts.Locale = ""
+	assert.False(t, opts.hasUserAgentOverride())
+
+	err := opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{
+		"userAgent": "k6-bot/1.0",
+		"platform":  "k6OS",
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, "k6-bot/1.0", opts.UserAgent)
+	assert.Equal(t, "k6OS", opts.Platform)
+	assert.True(t, opts.hasUserAgentOverride())
+}
+
+func TestBrowserContextOptionsRequestMetrics(t *testing.T) {
+	vu := k6test.NewVU(t)
+
+	opts := NewBrowserContextOptions()
+	assert.Equal(t, &RequestMetricsOptions{Enabled: true}, opts.RequestMetrics)
+
+	err := opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{
+		"requestMetrics": map[string]interface{}{"errorTag": true},
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, &RequestMetricsOptions{Enabled: true, ErrorTag: true}, opts.RequestMetrics)
+
+	err = opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{
+		"requestMetrics": map[string]interface{}{"enabled": false},
+	}))
+	assert.NoError(t, err)
+	assert.False(t, opts.RequestMetrics.Enabled)
 }
@@ -104,11 +104,21 @@ func (p *BrowserProcess) WsURL() string {
 	return p.wsURL
 }
 
-// Pid returns the browser process ID.
+// Pid returns the browser process ID, or zero if the browser is not
+// running locally.
 func (p *BrowserProcess) Pid() int {
+	if p.process == nil {
+		return 0
+	}
 	return p.process.Pid
 }
 
+// isRemote returns true if the browser is not running locally, such as
+// when it's connected with browser.connect().
+func (p *BrowserProcess) isRemote() bool {
+	return p.process == nil
+}
+
 // AttachLogger attaches a logger to the browser process.
 func (p *BrowserProcess) AttachLogger(logger *log.Logger) {
 	p.logger = logger
@@ -6,11 +6,14 @@ import (
 	"testing"
 	"time"
 
+	cdpbrowser "github.com/chromedp/cdproto/browser"
 	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/storage"
 	"github.com/chromedp/cdproto/target"
 	"github.com/mailru/easyjson"
 	"github.com/stretchr/testify/require"
 
+	"github.com/grafana/xk6-browser/api"
 	"github.com/grafana/xk6-browser/log"
 )
 
@@ -175,6 +178,93 @@ func TestBrowserNewPageInContext(t *testing.T) {
 	})
 }
 
+func TestBrowserContextsAndPages(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	b := newBrowser(ctx, nil, nil, NewLaunchOptions(), log.NewNullLogger())
+	b.conn = fakeConn{
+		execute: func(context.Context, string, easyjson.Marshaler, easyjson.Unmarshaler) error {
+			return nil
+		},
+	}
+	for _, id := range []cdp.BrowserContextID{"3", "1", "2"} {
+		b.contexts[id] = NewBrowserContext(ctx, b, id, nil, log.NewNullLogger())
+		b.contextIDs = append(b.contextIDs, id)
+	}
+	contextIDs := func() (ids []cdp.BrowserContextID) {
+		for _, c := range b.Contexts() {
+			ids = append(ids, c.(*BrowserContext).id)
+		}
+		return ids
+	}
+	require.Equal(t, []cdp.BrowserContextID{"3", "1", "2"}, contextIDs())
+	require.NoError(t, b.disposeContext("1"))
+	require.Equal(t, []cdp.BrowserContextID{"3", "2"}, contextIDs())
+
+	bc, other := b.contexts["3"], b.contexts["2"]
+	b.pagesMu.Lock()
+	for _, p := range []*Page{
+		{targetID: "c", browserCtx: bc},
+		{targetID: "a", browserCtx: other},
+		{targetID: "b", browserCtx: bc},
+		{targetID: "closed", browserCtx: bc, closed: true},
+		{targetID: "crashed", browserCtx: bc, crashed: true},
+		{targetID: "d", browserCtx: bc},
+	} {
+		b.addPage(p.targetID, p)
+	}
+	b.pagesMu.Unlock()
+	targetIDs := func(c *BrowserContext) (ids []target.ID) {
+		for _, p := range c.Pages() {
+			ids = append(ids, p.(*Page).targetID)
+		}
+		return ids
+	}
+	require.Equal(t, []target.ID{"c", "b", "d"}, targetIDs(bc))
+	require.Equal(t, []target.ID{"a"}, targetIDs(other))
+
+	b.pagesMu.Lock()
+	b.deletePage("b")
+	b.pagesMu.Unlock()
+	require.Equal(t, []target.ID{"c", "d"}, targetIDs(bc))
+	require.Len(t, b.getPages(), 5)
+}
+
+func TestBrowserContextRestore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	b := newBrowser(ctx, nil, nil, NewLaunchOptions(), log.NewNullLogger())
+	var methods []string
+	b.conn = fakeConn{
+		execute: func(_ context.Context, method string, params easyjson.Marshaler, _ easyjson.Unmarshaler) error {
+			switch p := params.(type) {
+			case *cdpbrowser.GrantPermissionsParams:
+				require.Equal(t, cdp.BrowserContextID("new"), p.BrowserContextID)
+				require.Equal(t, "https://example.com", p.Origin)
+				require.Equal(t, []cdpbrowser.PermissionType{cdpbrowser.PermissionTypeGeolocation}, p.Permissions)
+			case *storage.SetCookiesParams:
+				require.Equal(t, cdp.BrowserContextID("new"), p.BrowserContextID)
+				require.Len(t, p.Cookies, 1)
+				require.Equal(t, "session", p.Cookies[0].Name)
+			}
+			methods = append(methods, method)
+			return nil
+		},
+	}
+	opts := NewBrowserContextOptions()
+	opts.StorageState = &api.StorageState{
+		Cookies: []*api.Cookie{{Name: "session", Value: "1", Domain: "example.com", Path: "/", Expires: -1}},
+	}
+	bc := NewBrowserContext(ctx, b, "new", opts, log.NewNullLogger())
+	bc.permissions["https://example.com"] = []string{"geolocation"}
+	methods = nil
+
+	require.NoError(t, bc.restore())
+	require.Equal(t, []string{cdpbrowser.CommandGrantPermissions, storage.CommandSetCookies}, methods)
+}
+
 type fakeConn struct {
 	connection
 	execute func(context.Context, string, easyjson.Marshaler, easyjson.Unmarshaler) error
@@ -0,0 +1,238 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/grafana/xk6-browser/api"
+	"github.com/grafana/xk6-browser/k6ext"
+	"github.com/grafana/xk6-browser/log"
+
+	k6modules "go.k6.io/k6/js/modules"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/target"
+	"github.com/dop251/goja"
+	"github.com/mailru/easyjson"
+)
+
+// Ensure CDPSession implements the api.CDPSession interface.
+var _ api.CDPSession = &CDPSession{}
+
+// CDPSession is a raw CDP session to the target of a page, for the
+// CDP domains that the API doesn't cover. It's detached when the page
+// closes, or when it's detached explicitly.
+type CDPSession struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	browser *Browser
+	session *Session
+	vu      k6modules.VU
+	logger  *log.Logger
+
+	// runs the handlers of the protocol events.
+	eventLoopQueue  eventLoopQueue
+	eventHandlersMu sync.RWMutex
+	eventHandlers   map[string][]goja.Callable
+}
+
+// NewCDPSession attaches a new CDP session to the target of the page.
+func NewCDPSession(ctx context.Context, browser *Browser, p *Page, logger *log.Logger) (*CDPSession, error) {
+	action := target.AttachToTarget(p.targetID).WithFlatten(true)
+	sid, err := action.Do(cdp.WithExecutor(ctx, browser.getConn()))
+	if err != nil {
+		return nil, fmt.Errorf("attaching to target ID %v: %w", p.targetID, err)
+	}
+	// the connection creates the session when the browser
+	// reports the attachment, before it returns the session ID.
+	session := browser.getConn().getSession(sid)
+	if session == nil {
+		return nil, fmt.Errorf("attaching to target ID %v: missing session ID %v", p.targetID, sid)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s := CDPSession{
+		ctx:     ctx,
+		cancel:  cancel,
+		browser: browser,
+		session: session,
+		vu:      k6ext.GetVU(ctx),
+		logger:  logger,
+	}
+	go func() {
+		defer cancel()
+		select {
+		case <-session.Done():
+		case <-ctx.Done():
+		}
+	}()
+
+	return &s, nil
+}
+
+// Detach detaches the session from its target. The session can't send
+// commands or receive events afterwards.
+func (s *CDPSession) Detach() {
+	s.logger.Debugf("CDPSession:Detach", "sid:%v tid:%v", s.session.ID(), s.session.TargetID())
+
+	if s.detached() {
+		k6ext.Panic(s.ctx, "detaching CDP session: session is detached")
+	}
+	defer s.cancel()
+
+	action := target.DetachFromTarget().WithSessionID(s.session.ID())
+	if err := action.Do(cdp.WithExecutor(s.ctx, s.browser.getConn())); err != nil {
+		k6ext.Panic(s.ctx, "detaching CDP session: %w", err)
+	}
+}
+
+// On calls the handler with the parameters of every protocol event of
+// the method, such as "Network.requestWillBeSent". The events of most
+// domains are only sent after their enable method is sent.
+func (s *CDPSession) On(event string, handler goja.Callable) {
+	s.logger.Debugf("CDPSession:On", "sid:%v tid:%v event:%q", s.session.ID(), s.session.TargetID(), event)
+
+	if handler == nil {
+		k6ext.Panic(s.ctx, "missing handler of CDP event %q", event)
+	}
+	if s.detached() {
+		k6ext.Panic(s.ctx, "listening to CDP event %q: session is detached", event)
+	}
+
+	s.eventHandlersMu.Lock()
+	if s.eventHandlers == nil {
+		s.eventHandlers = make(map[string][]goja.Callable)
+	}
+	first := len(s.eventHandlers[event]) == 0
+	s.eventHandlers[event] = append(s.eventHandlers[event], handler)
+	s.eventHandlersMu.Unlock()
+
+	s.eventLoopQueue.start(s.ctx, s.vu)
+	if !first {
+		return
+	}
+
+	events := make(chan Event)
+	s.session.on(s.ctx, []string{event}, events)
+	go func() {
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case ev := <-events:
+				s.onEvent(ev)
+			}
+		}
+	}()
+}
+
+// onEvent calls the handlers of the protocol event with its parameters.
+func (s *CDPSession) onEvent(ev Event) {
+	params, err := json.Marshal(ev.data)
+	if err != nil {
+		s.logger.Debugf("CDPSession:onEvent", "sid:%v event:%q err:%v", s.session.ID(), ev.typ, err)
+		return
+	}
+
+	s.eventHandlersMu.RLock()
+	handlers := s.eventHandlers[ev.typ]
+	s.eventHandlersMu.RUnlock()
+
+	s.eventLoopQueue.push(func() error {
+		v, err := s.toGojaValue(params)
+		if err != nil {
+			return fmt.Errorf("parsing params of CDP event %q: %w", ev.typ, err)
+		}
+		for _, handler := range handlers {
+			if _, err := handler(goja.Undefined(), v); err != nil {
+				return fmt.Errorf("calling handler of CDP event %q: %w", ev.typ, err)
+			}
+		}
+		return nil
+	})
+}
+
+// Send sends the protocol command with the method and the parameters, and
+// returns its result. It throws the protocol error if the command fails.
+func (s *CDPSession) Send(method string, params goja.Value) goja.Value {
+	s.logger.Debugf("CDPSession:Send", "sid:%v tid:%v method:%q", s.session.ID(), s.session.TargetID(), method)
+
+	if s.detached() {
+		k6ext.Panic(s.ctx, "sending %q: session is detached", method)
+	}
+
+	var p easyjson.Marshaler
+	if gojaValueExists(params) {
+		buf, err := json.Marshal(params.Export())
+		if err != nil {
+			k6ext.Panic(s.ctx, "serializing params of %q: %w", method, err)
+		}
+		raw := easyjson.RawMessage(buf)
+		p = &raw
+	}
+	var res easyjson.RawMessage
+	if err := s.session.Execute(s.ctx, method, p, &res); err != nil {
+		if s.detached() {
+			k6ext.Panic(s.ctx, "sending %q: session is detached", method)
+		}
+		var perr *api.ProtocolError
+		if errors.As(err, &perr) && perr.Method == method {
+			// the protocol error already names the method.
+			k6ext.Panic(s.ctx, "%w", err)
+		}
+		k6ext.Panic(s.ctx, "sending %q: %w", method, err)
+	}
+
+	v, err := s.toGojaValue(res)
+	if err != nil {
+		k6ext.Panic(s.ctx, "parsing result of %q: %w", method, err)
+	}
+	return v
+}
+
+// detached returns true if the session was detached, or if its target closed.
+func (s *CDPSession) detached() bool {
+	select {
+	case <-s.session.Done():
+		return true
+	case <-s.ctx.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+// toGojaValue returns the JSON as a goja value, or an empty
+// object if there's no JSON, as the protocol does.
+func (s *CDPSession) toGojaValue(data []byte) (goja.Value, error) {
+	var v interface{} = map[string]interface{}{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, fmt.Errorf("%w", err)
+		}
+	}
+	return s.vu.Runtime().ToValue(v), nil
+}
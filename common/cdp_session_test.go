@@ -0,0 +1,121 @@
+package common
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/grafana/xk6-browser/k6ext/k6test"
+	"github.com/grafana/xk6-browser/log"
+	"github.com/grafana/xk6-browser/tests/ws"
+
+	"github.com/chromedp/cdproto"
+	"github.com/gorilla/websocket"
+	"github.com/mailru/easyjson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCDPSession(t *testing.T) {
+	const (
+		sessionID = "session_id_0123456789"
+
+		targetAttachedToTargetEvent = `
+		{
+			"sessionId": "session_id_0123456789",
+			"targetInfo": {
+				"targetId": "target_id_0123456789",
+				"type": "page",
+				"title": "",
+				"url": "about:blank",
+				"attached": true,
+				"browserContextId": "browser_context_id_0123456789"
+			},
+			"waitingForDebugger": false
+		}`
+	)
+
+	var params []string
+	handler := func(conn *websocket.Conn, msg *cdproto.Message, writeCh chan cdproto.Message, done chan struct{}) {
+		switch msg.Method {
+		case cdproto.CommandTargetAttachToTarget:
+			writeCh <- cdproto.Message{
+				Method: cdproto.EventTargetAttachedToTarget,
+				Params: easyjson.RawMessage(targetAttachedToTargetEvent),
+			}
+			writeCh <- cdproto.Message{
+				ID:     msg.ID,
+				Result: easyjson.RawMessage(`{"sessionId":"` + sessionID + `"}`),
+			}
+		case cdproto.CommandTargetDetachFromTarget:
+			writeCh <- cdproto.Message{ID: msg.ID, Result: easyjson.RawMessage("{}")}
+		case cdproto.CommandBrowserGetVersion:
+			params = append(params, string(msg.Params))
+			writeCh <- cdproto.Message{
+				ID:        msg.ID,
+				SessionID: msg.SessionID,
+				Result:    easyjson.RawMessage(`{"product":"HeadlessChrome/100.0","protocolVersion":"1.3"}`),
+			}
+		case cdproto.CommandPageEnable:
+			params = append(params, string(msg.Params))
+			writeCh <- cdproto.Message{ID: msg.ID, SessionID: msg.SessionID, Result: easyjson.RawMessage("{}")}
+			writeCh <- cdproto.Message{
+				SessionID: msg.SessionID,
+				Method:    cdproto.EventPageFrameStoppedLoading,
+				Params:    easyjson.RawMessage(`{"frameId":"frame_id_0123456789"}`),
+			}
+		case "Nope.nope":
+			writeCh <- cdproto.Message{
+				ID:        msg.ID,
+				SessionID: msg.SessionID,
+				Error:     &cdproto.Error{Code: -32601, Message: "'Nope.nope' wasn't found"},
+			}
+		}
+	}
+	server := ws.NewServer(t, ws.WithCDPHandler("/cdp", handler, nil))
+
+	vu := k6test.NewVU(t)
+	u, err := url.Parse(server.ServerHTTP.URL)
+	require.NoError(t, err)
+	conn, err := NewConnection(vu.Context(), fmt.Sprintf("ws://%s/cdp", u.Host), log.NewNullLogger())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	s, err := NewCDPSession(vu.Context(), &Browser{conn: conn}, &Page{targetID: "target_id_0123456789"}, log.NewNullLogger())
+	require.NoError(t, err)
+
+	rt := vu.Runtime()
+	require.NoError(t, rt.Set("session", s))
+	var got []string
+	require.NoError(t, rt.Set("log", func(s string) { got = append(got, s) }))
+
+	err = vu.Loop.Start(func() error {
+		_, err := rt.RunString(`
+			log(session.send('Browser.getVersion').product);
+			session.on('Page.frameStoppedLoading', e => {
+				log(e.frameId);
+				session.detach();
+			});
+			session.send('Page.enable', { custom: [1, 'a'] });
+			try {
+				session.send('Nope.nope');
+			} catch (e) {
+				log(e.toString());
+			}
+		`)
+		if err != nil {
+			return fmt.Errorf("%w", err)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"HeadlessChrome/100.0",
+		`ProtocolError: sending "Nope.nope": 'Nope.nope' wasn't found (-32601)`,
+		"frame_id_0123456789",
+	}, got)
+	assert.Equal(t, []string{"", `{"custom":[1,"a"]}`}, params)
+
+	_, err = rt.RunString(`session.send('Browser.getVersion')`)
+	assert.ErrorContains(t, err, `sending "Browser.getVersion": session is detached`)
+}
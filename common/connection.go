@@ -39,6 +39,7 @@ import (
 	"github.com/mailru/easyjson/jlexer"
 	"github.com/mailru/easyjson/jwriter"
 
+	"github.com/grafana/xk6-browser/api"
 	"github.com/grafana/xk6-browser/log"
 )
 
@@ -129,6 +130,10 @@ type Connection struct {
 	shutdownOnce sync.Once
 	msgID        int64
 
+	// closeErr is the unexpected closure error that closed the connection.
+	closeErrMu sync.Mutex
+	closeErr   error
+
 	sessionsMu sync.RWMutex
 	sessions   map[target.SessionID]*Session
 
@@ -143,7 +148,6 @@ func NewConnection(ctx context.Context, wsURL string, logger *log.Logger) (*Conn
 	var tlsConfig *tls.Config
 	wsd := websocket.Dialer{
 		HandshakeTimeout: time.Second * 60,
-		Proxy:            "gateway.zsdevel.net:9443", // TODO(fix): use proxy settings from launch options
 		TLSClientConfig:  tlsConfig,
 		WriteBufferSize:  wsWriteBufferSize,
 	}
@@ -186,6 +190,9 @@ func (c *Connection) closeConnection(code int) error {
 
 			// Stop the main control loop
 			close(c.done)
+
+			// the listeners see a closed connection.
+			c.emit(EventConnectionClose, nil)
 		}()
 
 		err = c.conn.WriteControl(websocket.CloseMessage,
@@ -199,8 +206,6 @@ func (c *Connection) closeConnection(code int) error {
 			delete(c.sessions, s.id)
 		}
 		c.sessionsMu.Unlock()
-
-		c.emit(EventConnectionClose, nil)
 	})
 
 	return err
@@ -237,11 +242,20 @@ func (c *Connection) handleIOError(err error) {
 	c.logger.Errorf("Connection:handleIOError", "err:%v", err)
 
 	if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
-		// Report an unexpected closure
+		// the browser can't be used without its connection.
+		err := NewFatalError(err)
+		c.closeErrMu.Lock()
+		c.closeErr = err
+		c.closeErrMu.Unlock()
+
+		// Report an unexpected closure to a message that's being sent, if
+		// there's one. Otherwise, the connection must still close, such as
+		// when the browser process exits between the messages.
 		select {
 		case c.errorCh <- err:
 		case <-c.done:
 			return
+		default:
 		}
 	}
 	code := websocket.CloseGoingAway
@@ -347,10 +361,10 @@ func (c *Connection) recvLoop() {
 			select {
 			case session.readCh <- &msg:
 			case code := <-c.closeCh:
-				c.logger.Debugf("Connection:recvLoop:<-c.closeCh", "sid:%v tid:%v wsURL:%v crashed:%t", session.id, session.targetID, c.wsURL, session.crashed)
+				c.logger.Debugf("Connection:recvLoop:<-c.closeCh", "sid:%v tid:%v wsURL:%v crashed:%t", session.id, session.targetID, c.wsURL, session.isCrashed())
 				_ = c.closeConnection(code)
 			case <-c.done:
-				c.logger.Debugf("Connection:recvLoop:<-c.done", "sid:%v tid:%v wsURL:%v crashed:%t", session.id, session.targetID, c.wsURL, session.crashed)
+				c.logger.Debugf("Connection:recvLoop:<-c.done", "sid:%v tid:%v wsURL:%v crashed:%t", session.id, session.targetID, c.wsURL, session.isCrashed())
 				return
 			}
 
@@ -373,7 +387,39 @@ func (c *Connection) recvLoop() {
 	}
 }
 
+// disconnectedErr returns the unexpected closure error that closed the
+// connection, if there's one. Otherwise, it returns ErrBrowserDisconnected.
+func (c *Connection) disconnectedErr() error {
+	c.closeErrMu.Lock()
+	defer c.closeErrMu.Unlock()
+
+	if c.closeErr != nil {
+		return c.closeErr
+	}
+	return ErrBrowserDisconnected
+}
+
+// closedErr returns the unexpected closure error that closed the connection,
+// if there's one. Otherwise, it returns a close error with the given code.
+// Both are fatal errors.
+func (c *Connection) closedErr(code int) error {
+	c.closeErrMu.Lock()
+	defer c.closeErrMu.Unlock()
+
+	if c.closeErr != nil {
+		return c.closeErr
+	}
+	return NewFatalError(&websocket.CloseError{Code: code})
+}
+
 func (c *Connection) send(ctx context.Context, msg *cdproto.Message, recvCh chan *cdproto.Message, res easyjson.Unmarshaler) error {
+	// fail fast instead of waiting for a closed connection.
+	select {
+	case <-c.done:
+		return c.disconnectedErr()
+	default:
+	}
+
 	select {
 	case c.sendCh <- msg:
 	case err := <-c.errorCh:
@@ -382,10 +428,10 @@ func (c *Connection) send(ctx context.Context, msg *cdproto.Message, recvCh chan
 	case code := <-c.closeCh:
 		c.logger.Debugf("Connection:send:<-c.closeCh", "wsURL:%q sid:%v, websocket code:%v", c.wsURL, msg.SessionID, code)
 		_ = c.closeConnection(code)
-		return &websocket.CloseError{Code: code}
+		return c.closedErr(code)
 	case <-c.done:
 		c.logger.Debugf("Connection:send:<-c.done", "wsURL:%q sid:%v", c.wsURL, msg.SessionID)
-		return nil
+		return c.disconnectedErr()
 	case <-ctx.Done():
 		c.logger.Errorf("Connection:send:<-ctx.Done()", "wsURL:%q sid:%v err:%v", c.wsURL, msg.SessionID, c.ctx.Err())
 		return ctx.Err()
@@ -399,6 +445,7 @@ func (c *Connection) send(ctx context.Context, msg *cdproto.Message, recvCh chan
 		return nil
 	}
 	tid := c.findTargetIDForLog(msg.SessionID)
+	method := string(msg.Method)
 	select {
 	case msg := <-recvCh:
 		var sid target.SessionID
@@ -413,7 +460,12 @@ func (c *Connection) send(ctx context.Context, msg *cdproto.Message, recvCh chan
 			return ErrChannelClosed
 		case msg.Error != nil:
 			c.logger.Debugf("Connection:send", "sid:%v tid:%v wsURL:%q, msg err:%v", sid, tid, c.wsURL, msg.Error)
-			return msg.Error
+			return &api.ProtocolError{
+				Method:  method,
+				Code:    msg.Error.Code,
+				Message: msg.Error.Message,
+				Err:     msg.Error,
+			}
 		case res != nil:
 			return easyjson.Unmarshal(msg.Result, res)
 		}
@@ -423,9 +475,10 @@ func (c *Connection) send(ctx context.Context, msg *cdproto.Message, recvCh chan
 	case code := <-c.closeCh:
 		c.logger.Debugf("Connection:send:<-c.closeCh #2", "sid:%v tid:%v wsURL:%q, websocket code:%v", msg.SessionID, tid, c.wsURL, code)
 		_ = c.closeConnection(code)
-		return &websocket.CloseError{Code: code}
+		return c.closedErr(code)
 	case <-c.done:
 		c.logger.Debugf("Connection:send:<-c.done #2", "sid:%v tid:%v wsURL:%q", msg.SessionID, tid, c.wsURL)
+		return c.disconnectedErr()
 	case <-ctx.Done():
 		c.logger.Debugf("Connection:send:<-ctx.Done()", "sid:%v tid:%v wsURL:%q err:%v", msg.SessionID, tid, c.wsURL, c.ctx.Err())
 		return ctx.Err()
@@ -25,6 +25,7 @@ import (
 	"fmt"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/grafana/xk6-browser/log"
 	"github.com/grafana/xk6-browser/tests/ws"
@@ -69,6 +70,30 @@ func TestConnectionClosureAbnormal(t *testing.T) {
 	})
 }
 
+func TestConnectionSendAfterClose(t *testing.T) {
+	server := ws.NewServer(t, ws.WithCDPHandler("/cdp", ws.CDPDefaultHandler, nil))
+
+	ctx := context.Background()
+	url, _ := url.Parse(server.ServerHTTP.URL)
+	wsURL := fmt.Sprintf("ws://%s/cdp", url.Host)
+	conn, err := NewConnection(ctx, wsURL, log.NewNullLogger())
+	require.NoError(t, err)
+
+	closeCh := make(chan Event, 1)
+	conn.on(ctx, []string{EventConnectionClose}, closeCh)
+	conn.Close()
+
+	select {
+	case <-closeCh:
+	case <-time.After(time.Second):
+		t.Fatal("connection close event is not emitted")
+	}
+
+	action := target.SetDiscoverTargets(true)
+	err = action.Do(cdp.WithExecutor(ctx, conn))
+	require.ErrorIs(t, err, ErrBrowserDisconnected)
+}
+
 func TestConnectionSendRecv(t *testing.T) {
 	server := ws.NewServer(t, ws.WithCDPHandler("/cdp", ws.CDPDefaultHandler, nil))
 
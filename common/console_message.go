@@ -0,0 +1,157 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/grafana/xk6-browser/api"
+
+	cdpruntime "github.com/chromedp/cdproto/runtime"
+)
+
+// Ensure ConsoleMessage implements the api.ConsoleMessage interface.
+var _ api.ConsoleMessage = &ConsoleMessage{}
+
+// ConsoleMessage is a message that a page or a web worker
+// logged with the console API.
+type ConsoleMessage struct {
+	typ      string
+	text     string
+	args     []api.JSHandle
+	location api.ConsoleMessageLocation
+	page     *Page
+	worker   *Worker
+}
+
+// Args returns handles to the arguments of the console API call.
+func (m *ConsoleMessage) Args() []api.JSHandle {
+	return m.args
+}
+
+// Location returns the location in the source of the console API call.
+// The line and column numbers are zero-based.
+func (m *ConsoleMessage) Location() api.ConsoleMessageLocation {
+	return m.location
+}
+
+// Page returns the page that logged the message.
+func (m *ConsoleMessage) Page() api.Page {
+	return m.page
+}
+
+// Worker returns the web worker that logged the message,
+// or nil if the page logged it.
+func (m *ConsoleMessage) Worker() api.Worker {
+	if m.worker == nil {
+		return nil
+	}
+	return m.worker
+}
+
+// Text returns the text of the message, which is the arguments
+// of the console API call separated by spaces.
+func (m *ConsoleMessage) Text() string {
+	return m.text
+}
+
+// Type returns the type of the console API call,
+// such as log, debug, info, error or warning.
+func (m *ConsoleMessage) Type() string {
+	return m.typ
+}
+
+// onConsoleAPICalled calls the console event handlers with a message of the
+// console API call, if there are any. The handles to the arguments of the call
+// are created in the execution context of the call. The worker is the web
+// worker of the page that called the console API, or nil.
+func (p *Page) onConsoleAPICalled(ec *ExecutionContext, w *Worker, event *cdpruntime.EventConsoleAPICalled) {
+	if !p.hasEventHandlers(EventPageConsole) {
+		return
+	}
+
+	msg := &ConsoleMessage{
+		typ:    event.Type.String(),
+		text:   consoleMessageText(event.Args),
+		args:   make([]api.JSHandle, 0, len(event.Args)),
+		page:   p,
+		worker: w,
+	}
+	if st := event.StackTrace; st != nil && len(st.CallFrames) > 0 {
+		msg.location = api.ConsoleMessageLocation{
+			URL:          st.CallFrames[0].URL,
+			LineNumber:   st.CallFrames[0].LineNumber,
+			ColumnNumber: st.CallFrames[0].ColumnNumber,
+		}
+	}
+	if msg.location.URL == "" && w != nil {
+		msg.location.URL = w.url
+	}
+
+	p.eventHandlersMu.Lock()
+	for _, arg := range event.Args {
+		h := NewJSHandle(p.ctx, ec.session, ec, ec.Frame(), arg, p.logger)
+		msg.args = append(msg.args, h)
+		// the handles live until the page closes.
+		p.consoleHandles = append(p.consoleHandles, h)
+	}
+	p.eventHandlersMu.Unlock()
+
+	p.callEventHandlers(EventPageConsole, msg)
+}
+
+// disposeConsoleHandles marks the handles to the arguments of the console
+// messages as disposed. The remote objects are already gone with the page.
+func (p *Page) disposeConsoleHandles() {
+	p.eventHandlersMu.Lock()
+	defer p.eventHandlersMu.Unlock()
+
+	for _, h := range p.consoleHandles {
+		h.markDisposed()
+	}
+	p.consoleHandles = nil
+}
+
+// consoleMessageText returns the text of a console message
+// with the arguments of the console API call.
+func consoleMessageText(args []*cdpruntime.RemoteObject) string {
+	texts := make([]string, 0, len(args))
+	for _, arg := range args {
+		v, err := parseRemoteObject(arg)
+		switch s, ok := v.(string); {
+		case err != nil:
+			texts = append(texts, arg.Description)
+		case ok:
+			texts = append(texts, s)
+		default:
+			b, err := json.Marshal(v)
+			if err != nil {
+				texts = append(texts, fmt.Sprint(v))
+				continue
+			}
+			texts = append(texts, string(b))
+		}
+	}
+
+	return strings.Join(texts, " ")
+}
@@ -0,0 +1,30 @@
+package common
+
+import (
+	"testing"
+
+	cdpruntime "github.com/chromedp/cdproto/runtime"
+	"github.com/mailru/easyjson"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsoleMessageText(t *testing.T) {
+	t.Parallel()
+
+	args := []*cdpruntime.RemoteObject{
+		{Type: cdpruntime.TypeString, Value: easyjson.RawMessage(`"hello"`)},
+		{Type: cdpruntime.TypeNumber, Value: easyjson.RawMessage(`42`)},
+		{Type: cdpruntime.TypeBoolean, Value: easyjson.RawMessage(`true`)},
+		{Type: cdpruntime.TypeUndefined},
+		{
+			Type: cdpruntime.TypeObject,
+			Preview: &cdpruntime.ObjectPreview{
+				Properties: []*cdpruntime.PropertyPreview{
+					{Name: "a", Type: cdpruntime.TypeNumber, Value: "1"},
+				},
+			},
+		},
+		{Type: cdpruntime.TypeNumber, UnserializableValue: "NaN"},
+	}
+	assert.Equal(t, `hello 42 true undefined {"a":1} NaN`, consoleMessageText(args))
+}
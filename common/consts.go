@@ -25,10 +25,14 @@ import "time"
 const (
 	// Defaults
 
-	DefaultLocale       string        = "en-US"
-	DefaultScreenWidth  int64         = 1280
-	DefaultScreenHeight int64         = 720
-	DefaultTimeout      time.Duration = 30 * time.Second
+	DefaultLocale                string        = "en-US"
+	DefaultScreenWidth           int64         = 1280
+	DefaultScreenHeight          int64         = 720
+	DefaultTimeout               time.Duration = 30 * time.Second
+	DefaultTestIDAttribute       string        = "data-testid"
+	DefaultEvaluateMaxDepth      int64         = 64
+	DefaultEvaluateMaxBinarySize int64         = 10 << 20
+	DefaultExpectTimeout         time.Duration = 5 * time.Second
 
 	// Life-cycle consts
 
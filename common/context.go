@@ -29,6 +29,7 @@ type ctxKey int
 const (
 	ctxKeyLaunchOptions ctxKey = iota
 	ctxKeyHooks
+	ctxKeyRouteQueue
 )
 
 func WithHooks(ctx context.Context, hooks *Hooks) context.Context {
@@ -55,6 +56,18 @@ func GetLaunchOptions(ctx context.Context) *LaunchOptions {
 	return v.(*LaunchOptions)
 }
 
+// withRouteQueue returns a new context with the queue that runs the route
+// handlers while synchronous calls block the event loop.
+func withRouteQueue(ctx context.Context, q *routeQueue) context.Context {
+	return context.WithValue(ctx, ctxKeyRouteQueue, q)
+}
+
+// getRouteQueue returns the route queue of the context, or nil if there is none.
+func getRouteQueue(ctx context.Context) *routeQueue {
+	v, _ := ctx.Value(ctxKeyRouteQueue).(*routeQueue)
+	return v
+}
+
 // contextWithDoneChan returns a new context that is canceled either
 // when the done channel is closed or ctx is canceled.
 func contextWithDoneChan(ctx context.Context, done chan struct{}) context.Context {
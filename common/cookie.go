@@ -0,0 +1,245 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package common
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/xk6-browser/api"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/dop251/goja"
+)
+
+// cookieSameSite are the valid sameSite values of the cookies.
+var cookieSameSite = map[string]network.CookieSameSite{
+	"Strict": network.CookieSameSiteStrict,
+	"Lax":    network.CookieSameSiteLax,
+	"None":   network.CookieSameSiteNone,
+}
+
+// parseCookies parses an array of cookies to add to a browser context.
+func parseCookies(rt *goja.Runtime, cookies goja.Value) ([]*network.CookieParam, error) {
+	if cookies == nil || goja.IsUndefined(cookies) || goja.IsNull(cookies) {
+		return nil, errors.New("cookies must be an array")
+	}
+	obj := cookies.ToObject(rt)
+	if obj.ClassName() != "Array" {
+		return nil, fmt.Errorf("cookies must be an array, got %s", obj.ClassName())
+	}
+
+	n := obj.Get("length").ToInteger()
+	params := make([]*network.CookieParam, 0, n)
+	for i := int64(0); i < n; i++ {
+		c := parseCookie(rt, obj.Get(strconv.FormatInt(i, 10)))
+		p, err := newCookieParam(c)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cookie %q: %w", c.Name, err)
+		}
+		params = append(params, p)
+	}
+
+	return params, nil
+}
+
+// parseCookie parses a cookie object. The cookie is a session
+// cookie unless the object has an expiration date.
+func parseCookie(rt *goja.Runtime, cookie goja.Value) *api.Cookie {
+	c := &api.Cookie{Expires: -1}
+	if cookie == nil || goja.IsUndefined(cookie) || goja.IsNull(cookie) {
+		return c
+	}
+	obj := cookie.ToObject(rt)
+	for _, k := range obj.Keys() {
+		v := obj.Get(k)
+		switch k {
+		case "name":
+			c.Name = v.String()
+		case "value":
+			c.Value = v.String()
+		case "url":
+			c.URL = v.String()
+		case "domain":
+			c.Domain = v.String()
+		case "path":
+			c.Path = v.String()
+		case "expires":
+			c.Expires = v.ToFloat()
+		case "httpOnly":
+			c.HTTPOnly = v.ToBoolean()
+		case "secure":
+			c.Secure = v.ToBoolean()
+		case "sameSite":
+			c.SameSite = v.String()
+		}
+	}
+
+	return c
+}
+
+// newCookieParam validates the cookie and returns it as a CDP cookie.
+func newCookieParam(c *api.Cookie) (*network.CookieParam, error) {
+	if c.Name == "" {
+		return nil, errors.New("name is required")
+	}
+	switch {
+	case c.URL != "" && (c.Domain != "" || c.Path != ""):
+		return nil, errors.New("either url, or domain and path must be set, not both")
+	case c.URL == "" && (c.Domain == "" || c.Path == ""):
+		return nil, errors.New("either url, or domain and path must be set")
+	}
+	if c.URL != "" {
+		u, err := url.Parse(c.URL)
+		if err != nil {
+			return nil, fmt.Errorf("parsing url: %w", err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, fmt.Errorf("url %q can't have cookies: must be an http or https URL", c.URL)
+		}
+	}
+
+	p := &network.CookieParam{
+		Name:     c.Name,
+		Value:    c.Value,
+		URL:      c.URL,
+		Domain:   c.Domain,
+		Path:     c.Path,
+		Secure:   c.Secure,
+		HTTPOnly: c.HTTPOnly,
+	}
+	if c.SameSite != "" {
+		sameSite, ok := cookieSameSite[c.SameSite]
+		if !ok {
+			return nil, fmt.Errorf("invalid sameSite %q: must be one of: Strict, Lax, None", c.SameSite)
+		}
+		// the browsers reject the cookies without secure.
+		if sameSite == network.CookieSameSiteNone && !c.Secure {
+			return nil, errors.New(`sameSite "None" requires secure`)
+		}
+		p.SameSite = sameSite
+	}
+	switch {
+	case c.Expires == -1:
+	case c.Expires > 0:
+		expires := cdp.TimeSinceEpoch(time.Unix(0, int64(c.Expires*float64(time.Second))))
+		p.Expires = &expires
+	default:
+		return nil, fmt.Errorf("invalid expires %v: must be -1 for a session cookie, "+
+			"or seconds since the UNIX epoch", c.Expires)
+	}
+
+	return p, nil
+}
+
+// toAPICookie returns the CDP cookie as a cookie, where the session cookies
+// expire at -1 and the cookies without sameSite have the default Lax.
+func toAPICookie(c *network.Cookie) *api.Cookie {
+	cookie := &api.Cookie{
+		Name:     c.Name,
+		Value:    c.Value,
+		Domain:   c.Domain,
+		Path:     c.Path,
+		Expires:  c.Expires,
+		HTTPOnly: c.HTTPOnly,
+		Secure:   c.Secure,
+		SameSite: c.SameSite.String(),
+	}
+	if c.Session || c.Expires <= 0 {
+		cookie.Expires = -1
+	}
+	if cookie.SameSite == "" {
+		cookie.SameSite = network.CookieSameSiteLax.String()
+	}
+
+	return cookie
+}
+
+// filterCookies returns the cookies that the browser would send to any of
+// the URLs, or all the cookies if there aren't any URLs.
+func filterCookies(cookies []*network.Cookie, urls []string) ([]*network.Cookie, error) {
+	if len(urls) == 0 {
+		return cookies, nil
+	}
+	parsed := make([]*url.URL, 0, len(urls))
+	for _, s := range urls {
+		u, err := url.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("parsing url: %w", err)
+		}
+		parsed = append(parsed, u)
+	}
+
+	var filtered []*network.Cookie
+	for _, c := range cookies {
+		for _, u := range parsed {
+			if cookieMatchesURL(c, u) {
+				filtered = append(filtered, c)
+				break
+			}
+		}
+	}
+
+	return filtered, nil
+}
+
+// cookieMatchesURL returns true if the browser would send the cookie to the URL.
+func cookieMatchesURL(c *network.Cookie, u *url.URL) bool {
+	host := u.Hostname()
+	if strings.HasPrefix(c.Domain, ".") {
+		if !strings.HasSuffix("."+host, c.Domain) {
+			return false
+		}
+	} else if host != c.Domain {
+		return false
+	}
+	if !cookiePathMatches(c.Path, u.Path) {
+		return false
+	}
+	// the browsers consider the local hosts secure.
+	local := host == "localhost"
+	if ip := net.ParseIP(host); ip != nil {
+		local = ip.IsLoopback()
+	}
+
+	return !c.Secure || u.Scheme == "https" || local
+}
+
+// cookiePathMatches returns true if the request path
+// is the cookie path, or a subdirectory of it.
+func cookiePathMatches(cookiePath, reqPath string) bool {
+	if reqPath == "" {
+		reqPath = "/"
+	}
+	if !strings.HasPrefix(reqPath, cookiePath) {
+		return false
+	}
+
+	return len(reqPath) == len(cookiePath) ||
+		strings.HasSuffix(cookiePath, "/") ||
+		reqPath[len(cookiePath)] == '/'
+}
@@ -0,0 +1,132 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/xk6-browser/api"
+	"github.com/grafana/xk6-browser/k6ext/k6test"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCookies(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+	rt := vu.Runtime()
+
+	cookies, err := rt.RunString(`([
+		{ name: 'session', value: '1', url: 'https://example.com/app' },
+		{
+			name: 'tracking', value: '2', domain: '.example.com', path: '/',
+			expires: 1735689600, httpOnly: true, secure: true, sameSite: 'None',
+		},
+	])`)
+	require.NoError(t, err)
+	params, err := parseCookies(rt, cookies)
+	require.NoError(t, err)
+
+	expires := cdp.TimeSinceEpoch(time.Unix(1735689600, 0))
+	assert.Equal(t, []*network.CookieParam{
+		{Name: "session", Value: "1", URL: "https://example.com/app"},
+		{
+			Name: "tracking", Value: "2", Domain: ".example.com", Path: "/",
+			Expires: &expires, HTTPOnly: true, Secure: true, SameSite: network.CookieSameSiteNone,
+		},
+	}, params)
+
+	testCases := []struct {
+		cookie, wantErr string
+	}{
+		{`{ value: '1', url: 'https://example.com' }`, `invalid cookie "": name is required`},
+		{`{ name: 'a', domain: 'example.com' }`, `invalid cookie "a": either url, or domain and path must be set`},
+		{
+			`{ name: 'a', url: 'https://example.com', path: '/' }`,
+			`invalid cookie "a": either url, or domain and path must be set, not both`,
+		},
+		{
+			`{ name: 'a', url: 'about:blank' }`,
+			`invalid cookie "a": url "about:blank" can't have cookies: must be an http or https URL`,
+		},
+		{
+			`{ name: 'a', url: 'https://example.com', sameSite: 'lax' }`,
+			`invalid cookie "a": invalid sameSite "lax": must be one of: Strict, Lax, None`,
+		},
+		{
+			`{ name: 'a', url: 'https://example.com', sameSite: 'None' }`,
+			`invalid cookie "a": sameSite "None" requires secure`,
+		},
+		{
+			`{ name: 'a', url: 'https://example.com', expires: 0 }`,
+			`invalid cookie "a": invalid expires 0: must be -1 for a session cookie, or seconds since the UNIX epoch`,
+		},
+	}
+	for _, tc := range testCases {
+		cookies, err := rt.RunString("([" + tc.cookie + "])")
+		require.NoError(t, err)
+		_, err = parseCookies(rt, cookies)
+		assert.EqualError(t, err, tc.wantErr)
+	}
+
+	_, err = parseCookies(rt, rt.ToValue("session=1"))
+	assert.EqualError(t, err, "cookies must be an array, got String")
+}
+
+func TestToAPICookie(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, &api.Cookie{
+		Name: "session", Value: "1", Domain: "example.com", Path: "/",
+		Expires: -1, SameSite: "Lax",
+	}, toAPICookie(&network.Cookie{
+		Name: "session", Value: "1", Domain: "example.com", Path: "/",
+		Expires: -1, Session: true,
+	}))
+	assert.Equal(t, &api.Cookie{
+		Name: "tracking", Value: "2", Domain: ".example.com", Path: "/",
+		Expires: 1735689600, HTTPOnly: true, Secure: true, SameSite: "None",
+	}, toAPICookie(&network.Cookie{
+		Name: "tracking", Value: "2", Domain: ".example.com", Path: "/",
+		Expires: 1735689600, HTTPOnly: true, Secure: true, SameSite: network.CookieSameSiteNone,
+	}))
+}
+
+func TestFilterCookies(t *testing.T) {
+	t.Parallel()
+
+	var (
+		host   = &network.Cookie{Name: "host", Domain: "example.com", Path: "/"}
+		domain = &network.Cookie{Name: "domain", Domain: ".example.com", Path: "/"}
+		path   = &network.Cookie{Name: "path", Domain: "example.com", Path: "/app"}
+		secure = &network.Cookie{Name: "secure", Domain: "example.com", Path: "/", Secure: true}
+		local  = &network.Cookie{Name: "local", Domain: "127.0.0.1", Path: "/", Secure: true}
+
+		cookies = []*network.Cookie{host, domain, path, secure, local}
+	)
+
+	testCases := []struct {
+		urls []string
+		want []*network.Cookie
+	}{
+		{nil, cookies},
+		{[]string{"https://example.com/"}, []*network.Cookie{host, domain, secure}},
+		{[]string{"http://example.com/app/page"}, []*network.Cookie{host, domain, path}},
+		{[]string{"http://example.com/application"}, []*network.Cookie{host, domain}},
+		{[]string{"http://www.example.com/app"}, []*network.Cookie{domain}},
+		{[]string{"http://127.0.0.1:8080/"}, []*network.Cookie{local}},
+		{[]string{"http://other.com/", "https://example.com"}, []*network.Cookie{host, domain, secure}},
+		{[]string{"http://other.com/"}, nil},
+	}
+	for _, tc := range testCases {
+		got, err := filterCookies(cookies, tc.urls)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, got, "urls: %v", tc.urls)
+	}
+
+	_, err := filterCookies(cookies, []string{":::"})
+	assert.ErrorContains(t, err, "parsing url")
+}
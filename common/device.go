@@ -20,6 +20,13 @@
 
 package common
 
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
 // Device represents an end-user device (computer, tablet, phone etc.)
 type Device struct {
 	Name              string   `js:"name"`
@@ -30,9 +37,100 @@ type Device struct {
 	HasTouch          bool     `js:"hasTouch"`
 }
 
+// Devices maps device names to their emulation settings. A device can be
+// spread into the browser context options to emulate it:
+//
+//	browser.newContext({ ...devices['iPhone 12'] })
+type Devices map[string]Device
+
+// Get returns the device with the given name. Unlike indexing the devices,
+// it returns an error that suggests similar names if the device is unknown.
+func (d Devices) Get(name string) (Device, error) {
+	if device, ok := d[name]; ok {
+		return device, nil
+	}
+	if suggestions := d.similarNames(name); len(suggestions) > 0 {
+		return Device{}, fmt.Errorf("unknown device %q, did you mean %s?", name, strings.Join(suggestions, ", "))
+	}
+	return Device{}, fmt.Errorf("unknown device %q", name)
+}
+
+// similarNames returns up to three quoted device names that are similar
+// to name. The names that contain name come first, then the closest ones.
+func (d Devices) similarNames(name string) []string {
+	const max = 3
+
+	lname := strings.ToLower(strings.TrimSpace(name))
+	if lname == "" {
+		return nil
+	}
+	type candidate struct {
+		name     string
+		distance int
+	}
+	candidates := make([]candidate, 0, len(d))
+	for n := range d {
+		ln := strings.ToLower(n)
+		dist := levenshtein(lname, ln)
+		if strings.Contains(ln, lname) {
+			dist = 0
+		}
+		// too different to be a typo.
+		if dist > len(lname)/2 {
+			continue
+		}
+		candidates = append(candidates, candidate{n, dist})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].name < candidates[j].name
+	})
+
+	var names []string
+	for i := 0; i < len(candidates) && i < max; i++ {
+		names = append(names, strconv.Quote(candidates[i].name))
+	}
+	return names
+}
+
+// levenshtein returns the number of single character edits
+// that turn a into b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min3(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
 // GetDevices returns predefined emulation settings for many end-user devices.
-func GetDevices() map[string]Device {
-	return map[string]Device{
+func GetDevices() Devices {
+	return Devices{
 		"Blackberry PlayBook": {
 			Name:      "Blackberry PlayBook",
 			UserAgent: "Mozilla/5.0 (PlayBook; U; RIM Tablet OS 2.1.0; en-US) AppleWebKit/536.2+ (KHTML like Gecko) Version/7.2.1.0 Safari/536.2+",
@@ -165,6 +263,50 @@ func GetDevices() map[string]Device {
 			IsMobile:          true,
 			HasTouch:          true,
 		},
+		"Galaxy S9+": {
+			Name:      "Galaxy S9+",
+			UserAgent: "Mozilla/5.0 (Linux; Android 8.0.0; SM-G965U Build/R16NW) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/90.0.4430.0 Mobile Safari/537.36",
+			Viewport: Viewport{
+				Width:  320,
+				Height: 658,
+			},
+			DeviceScaleFactor: 4.5,
+			IsMobile:          true,
+			HasTouch:          true,
+		},
+		"Galaxy S9+ landscape": {
+			Name:      "Galaxy S9+ landscape",
+			UserAgent: "Mozilla/5.0 (Linux; Android 8.0.0; SM-G965U Build/R16NW) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/90.0.4430.0 Mobile Safari/537.36",
+			Viewport: Viewport{
+				Width:  658,
+				Height: 320,
+			},
+			DeviceScaleFactor: 4.5,
+			IsMobile:          true,
+			HasTouch:          true,
+		},
+		"Galaxy Tab S4": {
+			Name:      "Galaxy Tab S4",
+			UserAgent: "Mozilla/5.0 (Linux; Android 8.1.0; SM-T837A) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/90.0.4430.0 Safari/537.36",
+			Viewport: Viewport{
+				Width:  712,
+				Height: 1138,
+			},
+			DeviceScaleFactor: 2.25,
+			IsMobile:          true,
+			HasTouch:          true,
+		},
+		"Galaxy Tab S4 landscape": {
+			Name:      "Galaxy Tab S4 landscape",
+			UserAgent: "Mozilla/5.0 (Linux; Android 8.1.0; SM-T837A) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/90.0.4430.0 Safari/537.36",
+			Viewport: Viewport{
+				Width:  1138,
+				Height: 712,
+			},
+			DeviceScaleFactor: 2.25,
+			IsMobile:          true,
+			HasTouch:          true,
+		},
 		"iPad": {
 			Name:      "iPad",
 			UserAgent: "Mozilla/5.0 (iPad; CPU OS 11_0 like Mac OS X) AppleWebKit/604.1.34 (KHTML, like Gecko) Version/11.0 Mobile/15A5341f Safari/604.1",
@@ -187,6 +329,28 @@ func GetDevices() map[string]Device {
 			IsMobile:          true,
 			HasTouch:          true,
 		},
+		"iPad (gen 7)": {
+			Name:      "iPad (gen 7)",
+			UserAgent: "Mozilla/5.0 (iPad; CPU OS 12_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/13.0.3 Mobile/15E148 Safari/604.1",
+			Viewport: Viewport{
+				Width:  810,
+				Height: 1080,
+			},
+			DeviceScaleFactor: 2,
+			IsMobile:          true,
+			HasTouch:          true,
+		},
+		"iPad (gen 7) landscape": {
+			Name:      "iPad (gen 7) landscape",
+			UserAgent: "Mozilla/5.0 (iPad; CPU OS 12_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/13.0.3 Mobile/15E148 Safari/604.1",
+			Viewport: Viewport{
+				Width:  1080,
+				Height: 810,
+			},
+			DeviceScaleFactor: 2,
+			IsMobile:          true,
+			HasTouch:          true,
+		},
 		"iPad Mini": {
 			Name:      "iPad Mini",
 			UserAgent: "Mozilla/5.0 (iPad; CPU OS 11_0 like Mac OS X) AppleWebKit/604.1.34 (KHTML, like Gecko) Version/11.0 Mobile/15A5341f Safari/604.1",
@@ -473,6 +637,116 @@ func GetDevices() map[string]Device {
 			IsMobile:          true,
 			HasTouch:          true,
 		},
+		"iPhone 11": {
+			Name:      "iPhone 11",
+			UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 13_7 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/13.1 Mobile/15E148 Safari/604.1",
+			Viewport: Viewport{
+				Width:  414,
+				Height: 715,
+			},
+			DeviceScaleFactor: 2,
+			IsMobile:          true,
+			HasTouch:          true,
+		},
+		"iPhone 11 landscape": {
+			Name:      "iPhone 11 landscape",
+			UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 13_7 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/13.1 Mobile/15E148 Safari/604.1",
+			Viewport: Viewport{
+				Width:  800,
+				Height: 364,
+			},
+			DeviceScaleFactor: 2,
+			IsMobile:          true,
+			HasTouch:          true,
+		},
+		"iPhone 12": {
+			Name:      "iPhone 12",
+			UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 14_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.0.3 Mobile/15E148 Safari/604.1",
+			Viewport: Viewport{
+				Width:  390,
+				Height: 664,
+			},
+			DeviceScaleFactor: 3,
+			IsMobile:          true,
+			HasTouch:          true,
+		},
+		"iPhone 12 landscape": {
+			Name:      "iPhone 12 landscape",
+			UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 14_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.0.3 Mobile/15E148 Safari/604.1",
+			Viewport: Viewport{
+				Width:  750,
+				Height: 340,
+			},
+			DeviceScaleFactor: 3,
+			IsMobile:          true,
+			HasTouch:          true,
+		},
+		"iPhone 12 Pro": {
+			Name:      "iPhone 12 Pro",
+			UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 14_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.0.3 Mobile/15E148 Safari/604.1",
+			Viewport: Viewport{
+				Width:  390,
+				Height: 664,
+			},
+			DeviceScaleFactor: 3,
+			IsMobile:          true,
+			HasTouch:          true,
+		},
+		"iPhone 12 Pro landscape": {
+			Name:      "iPhone 12 Pro landscape",
+			UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 14_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.0.3 Mobile/15E148 Safari/604.1",
+			Viewport: Viewport{
+				Width:  750,
+				Height: 340,
+			},
+			DeviceScaleFactor: 3,
+			IsMobile:          true,
+			HasTouch:          true,
+		},
+		"iPhone 13": {
+			Name:      "iPhone 13",
+			UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 15_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/15.0 Mobile/15E148 Safari/604.1",
+			Viewport: Viewport{
+				Width:  390,
+				Height: 664,
+			},
+			DeviceScaleFactor: 3,
+			IsMobile:          true,
+			HasTouch:          true,
+		},
+		"iPhone 13 landscape": {
+			Name:      "iPhone 13 landscape",
+			UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 15_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/15.0 Mobile/15E148 Safari/604.1",
+			Viewport: Viewport{
+				Width:  750,
+				Height: 342,
+			},
+			DeviceScaleFactor: 3,
+			IsMobile:          true,
+			HasTouch:          true,
+		},
+		"iPhone 13 Pro Max": {
+			Name:      "iPhone 13 Pro Max",
+			UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 15_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/15.0 Mobile/15E148 Safari/604.1",
+			Viewport: Viewport{
+				Width:  428,
+				Height: 746,
+			},
+			DeviceScaleFactor: 3,
+			IsMobile:          true,
+			HasTouch:          true,
+		},
+		"iPhone 13 Pro Max landscape": {
+			Name:      "iPhone 13 Pro Max landscape",
+			UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 15_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/15.0 Mobile/15E148 Safari/604.1",
+			Viewport: Viewport{
+				Width:  832,
+				Height: 380,
+			},
+			DeviceScaleFactor: 3,
+			IsMobile:          true,
+			HasTouch:          true,
+		},
 		"JioPhone 2": {
 			Name:      "JioPhone 2",
 			UserAgent: "Mozilla/5.0 (Mobile; LYF/F300B/LYF-F300B-001-01-15-130718-i;Android; rv:48.0) Gecko/48.0 Firefox/48.0 KAIOS/2.5",
@@ -814,5 +1088,27 @@ func GetDevices() map[string]Device {
 			IsMobile:          true,
 			HasTouch:          true,
 		},
+		"Pixel 5": {
+			Name:      "Pixel 5",
+			UserAgent: "Mozilla/5.0 (Linux; Android 11; Pixel 5) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/90.0.4430.0 Mobile Safari/537.36",
+			Viewport: Viewport{
+				Width:  393,
+				Height: 727,
+			},
+			DeviceScaleFactor: 2.75,
+			IsMobile:          true,
+			HasTouch:          true,
+		},
+		"Pixel 5 landscape": {
+			Name:      "Pixel 5 landscape",
+			UserAgent: "Mozilla/5.0 (Linux; Android 11; Pixel 5) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/90.0.4430.0 Mobile Safari/537.36",
+			Viewport: Viewport{
+				Width:  802,
+				Height: 293,
+			},
+			DeviceScaleFactor: 2.75,
+			IsMobile:          true,
+			HasTouch:          true,
+		},
 	}
 }
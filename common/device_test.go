@@ -0,0 +1,49 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDevicesGet(t *testing.T) {
+	t.Parallel()
+
+	devices := GetDevices()
+
+	d, err := devices.Get("iPhone 12")
+	require.NoError(t, err)
+	assert.Equal(t, "iPhone 12", d.Name)
+	assert.Equal(t, Viewport{Width: 390, Height: 664}, d.Viewport)
+	assert.Equal(t, 3.0, d.DeviceScaleFactor)
+	assert.True(t, d.IsMobile)
+	assert.True(t, d.HasTouch)
+
+	_, err = devices.Get("iphone 12 pro")
+	assert.EqualError(t, err, `unknown device "iphone 12 pro", `+
+		`did you mean "iPhone 12 Pro", "iPhone 12 Pro landscape", "iPhone 12"?`)
+	_, err = devices.Get("Pixel 4")
+	assert.EqualError(t, err, `unknown device "Pixel 4", did you mean "Pixel 2", "Pixel 5"?`)
+	_, err = devices.Get("Commodore 64")
+	assert.EqualError(t, err, `unknown device "Commodore 64"`)
+}
+
+func TestDevicesNames(t *testing.T) {
+	t.Parallel()
+
+	for name, d := range GetDevices() {
+		assert.Equal(t, name, d.Name)
+		assert.NotEmpty(t, d.UserAgent, name)
+		assert.Positive(t, d.DeviceScaleFactor, name)
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, 0, levenshtein("pixel", "pixel"))
+	assert.Equal(t, 1, levenshtein("pixel 4", "pixel 5"))
+	assert.Equal(t, 3, levenshtein("kitten", "sitting"))
+	assert.Equal(t, 5, levenshtein("", "ipad "))
+}
@@ -0,0 +1,145 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/grafana/xk6-browser/api"
+	"github.com/grafana/xk6-browser/k6ext"
+
+	"github.com/chromedp/cdproto/cdp"
+	cdppage "github.com/chromedp/cdproto/page"
+	"github.com/dop251/goja"
+)
+
+// Ensure Dialog implements the api.Dialog interface.
+var _ api.Dialog = &Dialog{}
+
+// Dialog is a JavaScript dialog that a page opened.
+type Dialog struct {
+	ctx     context.Context
+	session session
+	page    *Page
+
+	typ          cdppage.DialogType
+	message      string
+	defaultValue string
+
+	handledMu sync.Mutex
+	handled   bool
+}
+
+// Accept accepts the dialog. The prompt text is the response
+// to a prompt dialog.
+func (d *Dialog) Accept(promptText goja.Value) {
+	var text string
+	if gojaValueExists(promptText) {
+		text = promptText.String()
+	}
+	if err := d.handle(true, text); err != nil {
+		k6ext.Panic(d.ctx, "accepting dialog: %w", err)
+	}
+}
+
+// DefaultValue returns the default prompt text of a prompt dialog,
+// or an empty string.
+func (d *Dialog) DefaultValue() string {
+	return d.defaultValue
+}
+
+// Dismiss dismisses the dialog.
+func (d *Dialog) Dismiss() {
+	if err := d.handle(false, ""); err != nil {
+		k6ext.Panic(d.ctx, "dismissing dialog: %w", err)
+	}
+}
+
+// Message returns the message of the dialog.
+func (d *Dialog) Message() string {
+	return d.message
+}
+
+// Page returns the page that opened the dialog.
+func (d *Dialog) Page() api.Page {
+	return d.page
+}
+
+// Type returns the type of the dialog, which is one of:
+// alert, beforeunload, confirm or prompt.
+func (d *Dialog) Type() string {
+	return d.typ.String()
+}
+
+// handleDefault answers the dialog the way it is answered without
+// dialog handlers: it accepts beforeunload dialogs so that pages
+// can close, and dismisses the others.
+func (d *Dialog) handleDefault() error {
+	return d.handle(d.typ == cdppage.DialogTypeBeforeunload, "")
+}
+
+func (d *Dialog) handle(accept bool, promptText string) error {
+	d.handledMu.Lock()
+	defer d.handledMu.Unlock()
+
+	if d.handled {
+		return errors.New("dialog has already been handled")
+	}
+	d.handled = true
+
+	action := cdppage.HandleJavaScriptDialog(accept).WithPromptText(promptText)
+
+	if err := action.Do(cdp.WithExecutor(d.ctx, d.session)); err != nil {
+		return fmt.Errorf("handling javascript dialog: %w", err)
+	}
+
+	return nil
+}
+
+// onJavascriptDialogOpening calls the dialog event handlers with the dialog
+// that the page opened in the session. Without handlers, it answers the
+// dialog so that the page doesn't wait for an answer forever.
+func (p *Page) onJavascriptDialogOpening(s session, event *cdppage.EventJavascriptDialogOpening) {
+	d := &Dialog{
+		ctx:          p.ctx,
+		session:      s,
+		page:         p,
+		typ:          event.Type,
+		message:      event.Message,
+		defaultValue: event.DefaultPrompt,
+	}
+	if p.hasEventHandlers(EventPageDialog) {
+		p.callEventHandlers(EventPageDialog, d)
+		return
+	}
+
+	p.logger.Debugf("Page:onJavascriptDialogOpening",
+		"sid:%v type:%s: answering dialog without handlers", p.sessionID(), d.typ)
+	// don't block the events of the session while answering.
+	go func() {
+		if err := d.handleDefault(); err != nil {
+			p.logger.Debugf("Page:onJavascriptDialogOpening", "sid:%v err:%v", p.sessionID(), err)
+		}
+	}()
+}
@@ -0,0 +1,245 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/grafana/xk6-browser/api"
+	"github.com/grafana/xk6-browser/k6ext"
+
+	cdpbrowser "github.com/chromedp/cdproto/browser"
+)
+
+// Ensure Download implements the api.Download interface.
+var _ api.Download = &Download{}
+
+// downloadsNotAcceptedFailure is the failure of the downloads of
+// the browser contexts that don't accept downloads.
+const downloadsNotAcceptedFailure = "canceled: set the acceptDownloads option " +
+	"of the browser context to accept downloads"
+
+// Download is a file download that a page started.
+type Download struct {
+	ctx               context.Context
+	page              *Page
+	url               string
+	suggestedFilename string
+	// path is the path of the downloaded file, or an empty
+	// string if the browser context doesn't accept downloads.
+	path    string
+	timeout time.Duration
+
+	finishOnce sync.Once
+	done       chan struct{}
+	failure    string
+}
+
+// newDownload returns a download in progress of the page.
+func newDownload(p *Page, guid, url, suggestedFilename string) *Download {
+	d := &Download{
+		ctx:               p.ctx,
+		page:              p,
+		url:               url,
+		suggestedFilename: suggestedFilename,
+		timeout:           p.timeoutSettings.timeout(),
+		done:              make(chan struct{}),
+	}
+	if dir := p.browserCtx.downloadsPath; dir != "" {
+		d.path = filepath.Join(dir, guid)
+	}
+
+	return d
+}
+
+// Failure waits for the download to finish, and returns why it failed,
+// or an empty string if it succeeded.
+func (d *Download) Failure() string {
+	if err := d.wait(); err != nil {
+		k6ext.Panic(d.ctx, "getting download failure: %w", err)
+	}
+
+	return d.failure
+}
+
+// Page returns the page that started the download.
+func (d *Download) Page() api.Page {
+	return d.page
+}
+
+// Path waits for the download to finish, and returns the path
+// of the downloaded file. The file is removed when the browser
+// context closes.
+func (d *Download) Path() string {
+	if err := d.waitForSuccess(); err != nil {
+		k6ext.Panic(d.ctx, "getting download path: %w", err)
+	}
+
+	return d.path
+}
+
+// SaveAs waits for the download to finish, and copies the
+// downloaded file to the path.
+func (d *Download) SaveAs(path string) {
+	if err := d.waitForSuccess(); err != nil {
+		k6ext.Panic(d.ctx, "saving download as %q: %w", path, err)
+	}
+	if err := copyFile(d.path, path); err != nil {
+		k6ext.Panic(d.ctx, "saving download as %q: %w", path, err)
+	}
+}
+
+// SuggestedFilename returns the file name that the browser suggests
+// for the download, based on its response headers or URL.
+func (d *Download) SuggestedFilename() string {
+	return d.suggestedFilename
+}
+
+// URL returns the URL of the download.
+func (d *Download) URL() string {
+	return d.url
+}
+
+// finish marks the download as finished with the failure,
+// or as successful if the failure is empty.
+func (d *Download) finish(failure string) {
+	d.finishOnce.Do(func() {
+		d.failure = failure
+		close(d.done)
+	})
+}
+
+func (d *Download) wait() error {
+	select {
+	case <-d.done:
+		return nil
+	case <-d.ctx.Done():
+		return fmt.Errorf("waiting for download of %q: %w", d.url, d.ctx.Err())
+	case <-timeoutAfter(d.timeout):
+		return fmt.Errorf("waiting for download of %q: %w after %s", d.url, ErrTimedOut, d.timeout)
+	}
+}
+
+func (d *Download) waitForSuccess() error {
+	if err := d.wait(); err != nil {
+		return err
+	}
+	if d.failure != "" {
+		return fmt.Errorf("download of %q failed: %s", d.url, d.failure)
+	}
+
+	return nil
+}
+
+// copyFile copies the src file to dst, and creates the
+// directories of dst if they don't exist.
+func copyFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+	in, err := os.Open(filepath.Clean(src))
+	if err != nil {
+		return fmt.Errorf("opening downloaded file: %w", err)
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := os.Create(filepath.Clean(dst))
+	if err != nil {
+		return fmt.Errorf("creating file: %w", err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return fmt.Errorf("copying downloaded file: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("closing file: %w", err)
+	}
+
+	return nil
+}
+
+// onDownloadWillBegin starts tracking the download until it finishes, and
+// reports it to the page that started it. The download aborts the navigation
+// of the frame that started it, if any.
+func (b *Browser) onDownloadWillBegin(ev *cdpbrowser.EventDownloadWillBegin) {
+	var p *Page
+	for _, page := range b.getPages() {
+		if page.frameManager.getFrameByID(ev.FrameID) != nil {
+			p = page
+			break
+		}
+	}
+	if p == nil {
+		b.logger.Debugf("Browser:onDownloadWillBegin",
+			"fid:%v guid:%v url:%q: missing page of the frame", ev.FrameID, ev.GUID, ev.URL)
+		return
+	}
+	b.logger.Debugf("Browser:onDownloadWillBegin",
+		"sid:%v fid:%v guid:%v url:%q", p.sessionID(), ev.FrameID, ev.GUID, ev.URL)
+
+	d := newDownload(p, ev.GUID, ev.URL, ev.SuggestedFilename)
+	b.downloadsMu.Lock()
+	b.downloads[ev.GUID] = d
+	b.downloadsMu.Unlock()
+
+	p.frameManager.frameAbortedNavigation(ev.FrameID, "navigation aborted: download is starting", "")
+	p.onDownload(d)
+}
+
+// onDownloadProgress finishes the download when it completes or fails.
+func (b *Browser) onDownloadProgress(ev *cdpbrowser.EventDownloadProgress) {
+	if ev.State == cdpbrowser.DownloadProgressStateInProgress {
+		return
+	}
+
+	b.downloadsMu.Lock()
+	d, ok := b.downloads[ev.GUID]
+	delete(b.downloads, ev.GUID)
+	b.downloadsMu.Unlock()
+	if !ok {
+		return
+	}
+	b.logger.Debugf("Browser:onDownloadProgress", "guid:%v state:%s", ev.GUID, ev.State)
+
+	switch {
+	case ev.State == cdpbrowser.DownloadProgressStateCompleted:
+		d.finish("")
+	case d.path == "":
+		d.finish(downloadsNotAcceptedFailure)
+	default:
+		d.finish(ev.State.String())
+	}
+}
+
+// onDownload emits the download, and calls the download event handlers
+// with it, if there are any.
+func (p *Page) onDownload(d *Download) {
+	p.emit(EventPageDownload, d)
+	if p.hasEventHandlers(EventPageDownload) {
+		p.callEventHandlers(EventPageDownload, d)
+	}
+}
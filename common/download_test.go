@@ -0,0 +1,54 @@
+package common
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadWaitForSuccess(t *testing.T) {
+	t.Parallel()
+
+	newTestDownload := func() *Download {
+		return &Download{
+			ctx:     context.Background(),
+			url:     "http://test/report.csv",
+			timeout: time.Second,
+			done:    make(chan struct{}),
+		}
+	}
+
+	d := newTestDownload()
+	go d.finish("")
+	require.NoError(t, d.waitForSuccess())
+
+	d = newTestDownload()
+	d.finish("canceled")
+	d.finish("") // only the first finish counts.
+	assert.EqualError(t, d.waitForSuccess(), `download of "http://test/report.csv" failed: canceled`)
+
+	d = newTestDownload()
+	d.timeout = 10 * time.Millisecond
+	assert.ErrorIs(t, d.waitForSuccess(), ErrTimedOut)
+}
+
+func TestCopyFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "guid")
+	require.NoError(t, os.WriteFile(src, []byte("a,b\n"), 0o600))
+
+	dst := filepath.Join(dir, "sub", "dir", "report.csv")
+	require.NoError(t, copyFile(src, dst))
+	b, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	assert.Equal(t, "a,b\n", string(b))
+
+	assert.Error(t, copyFile(filepath.Join(dir, "missing"), dst))
+}
@@ -244,7 +244,7 @@ func (h *ElementHandle) dblClick(p *Position, opts *MouseClickOptions) error {
 }
 
 func (h *ElementHandle) defaultTimeout() time.Duration {
-	return time.Duration(h.frame.manager.timeoutSettings.timeout()) * time.Second
+	return h.frame.manager.timeoutSettings.timeout()
 }
 
 func (h *ElementHandle) dispatchEvent(_ context.Context, typ string, eventInit goja.Value) (interface{}, error) {
@@ -324,8 +324,17 @@ func (h *ElementHandle) getAttribute(apiCtx context.Context, name string) (inter
 	return h.eval(apiCtx, opts, js)
 }
 
-func (h *ElementHandle) hover(apiCtx context.Context, p *Position) error {
-	return h.frame.page.Mouse.move(p.X, p.Y, NewMouseMoveOptions())
+func (h *ElementHandle) hover(apiCtx context.Context, p *Position, modifiers []string) error {
+	restore, err := h.frame.page.Keyboard.ensureModifiers(modifiers)
+	if err != nil {
+		return err
+	}
+	if err := h.frame.page.Mouse.move(apiCtx, p.X, p.Y, NewMouseMoveOptions()); err != nil {
+		_ = restore()
+		return err
+	}
+
+	return restore()
 }
 
 func (h *ElementHandle) innerHTML(apiCtx context.Context) (interface{}, error) {
@@ -604,8 +613,56 @@ func (h *ElementHandle) selectText(apiCtx context.Context) error {
 	return nil
 }
 
-func (h *ElementHandle) tap(apiCtx context.Context, p *Position) error {
-	return h.frame.page.Touchscreen.tap(p.X, p.Y)
+func (h *ElementHandle) setInputFiles(apiCtx context.Context, files *InputFiles) error {
+	// in-memory files, and clearing the selection, are handled in the page.
+	// files on disk are set with CDP, which also dispatches the change event.
+	var payloads []*InputFilePayload
+	if len(files.Paths) == 0 {
+		payloads = append([]*InputFilePayload{}, files.Payloads...)
+	}
+	fn := `
+		(node, injected, count, payloads) => {
+			return injected.setInputFiles(node, count, payloads);
+		}
+	`
+	opts := evalOptions{
+		forceCallable: true,
+		returnByValue: true,
+	}
+	result, err := h.evalWithScript(apiCtx, opts, fn, len(files.Paths)+len(files.Payloads), payloads)
+	if err != nil {
+		return err
+	}
+	v, ok := result.(goja.Value)
+	if !ok {
+		return fmt.Errorf("unexpected type %T", result)
+	}
+	if s := v.String(); s != resultDone {
+		// Either we're done or an error happened (returned as "error:..." from JS)
+		return errorFromDOMError(s)
+	}
+	if len(files.Paths) == 0 {
+		return nil
+	}
+	action := dom.SetFileInputFiles(files.Paths).WithObjectID(h.remoteObject.ObjectID)
+	if err := action.Do(cdp.WithExecutor(apiCtx, h.session)); err != nil {
+		return fmt.Errorf("setting input files: %w", err)
+	}
+
+	return nil
+}
+
+func (h *ElementHandle) tap(apiCtx context.Context, p *Position, modifiers []string) error {
+	restore, err := h.frame.page.Keyboard.ensureModifiers(modifiers)
+	if err != nil {
+		return err
+	}
+	if err := h.frame.page.Touchscreen.tap(p.X, p.Y); err != nil {
+		_ = restore()
+		return err
+	}
+
+	return restore()
 }
 
 func (h *ElementHandle) textContent(apiCtx context.Context) (interface{}, error) {
@@ -669,7 +726,7 @@ func (h *ElementHandle) waitForElementState(
 	}
 	result, err := h.evalWithScript(apiCtx, opts, fn, states, timeout.Milliseconds())
 	if err != nil {
-		return false, errorFromDOMError(err.Error())
+		return false, errorFromDOMErr(err)
 	}
 	v, ok := result.(goja.Value)
 	if !ok {
@@ -720,6 +777,71 @@ func (h *ElementHandle) waitForSelector(apiCtx context.Context, selector string,
 	}
 }
 
+// closedShadowRootHost returns a preview of the host of the first closed
+// shadow root under this element that contains elements matching the
+// selector, or an empty string if there is none. The injected script
+// cannot reach into closed shadow roots, but the DOM domain can.
+func (h *ElementHandle) closedShadowRootHost(apiCtx context.Context, selector string) (string, error) {
+	parsedSelector, err := NewSelector(selector)
+	if err != nil {
+		return "", err
+	}
+	action := dom.DescribeNode().
+		WithObjectID(h.remoteObject.ObjectID).
+		WithDepth(-1).
+		WithPierce(true)
+	node, err := action.Do(cdp.WithExecutor(apiCtx, h.session))
+	if err != nil {
+		return "", fmt.Errorf("describing node: %w", err)
+	}
+
+	fn := `
+		(root, injected, selector) => {
+			if (injected.querySelectorAll(selector, root).length === 0) {
+				return "";
+			}
+			return injected.previewNode(root.host);
+		}
+	`
+	opts := evalOptions{
+		forceCallable: true,
+		returnByValue: true,
+	}
+	for _, root := range closedShadowRoots(node) {
+		rootHandle, err := h.execCtx.adoptBackendNodeID(root.BackendNodeID)
+		if err != nil {
+			return "", fmt.Errorf("resolving closed shadow root: %w", err)
+		}
+		result, err := rootHandle.evalWithScript(apiCtx, opts, fn, parsedSelector)
+		rootHandle.Dispose()
+		if err != nil {
+			return "", err
+		}
+		if v, ok := result.(goja.Value); ok && v.String() != "" {
+			return v.String(), nil
+		}
+	}
+
+	return "", nil
+}
+
+// closedShadowRoots returns the closed shadow roots under the node,
+// excluding the ones of other frames.
+func closedShadowRoots(node *cdp.Node) []*cdp.Node {
+	var roots []*cdp.Node
+	for _, root := range node.ShadowRoots {
+		if root.ShadowRootType == cdp.ShadowRootTypeClosed {
+			roots = append(roots, root)
+		}
+		roots = append(roots, closedShadowRoots(root)...)
+	}
+	for _, child := range node.Children {
+		roots = append(roots, closedShadowRoots(child)...)
+	}
+
+	return roots
+}
+
 // AsElement returns this element handle.
 func (h *ElementHandle) AsElement() api.ElementHandle {
 	return h
@@ -747,9 +869,8 @@ func (h *ElementHandle) Click(opts goja.Value) {
 	pointerFn := h.newPointerAction(fn, &actionOpts.ElementHandleBasePointerOptions)
 	_, err := callApiWithTimeout(h.ctx, pointerFn, actionOpts.Timeout)
 	if err != nil {
-		k6ext.Panic(h.ctx, "clicking on element: %v", err)
+		k6ext.Panic(h.ctx, "clicking on element: %w", err)
 	}
-	applySlowMo(h.ctx)
 }
 
 func (h *ElementHandle) ContentFrame() api.Frame {
@@ -781,7 +902,6 @@ func (h *ElementHandle) Dblclick(opts goja.Value) {
 	if err != nil {
 		k6ext.Panic(h.ctx, "double clicking on element: %w", err)
 	}
-	applySlowMo(h.ctx)
 }
 
 func (h *ElementHandle) DispatchEvent(typ string, eventInit goja.Value) {
@@ -844,21 +964,21 @@ func (h *ElementHandle) GetAttribute(name string) goja.Value {
 	return asGojaValue(h.ctx, v)
 }
 
-// Hover scrolls element into view and hovers over its center point.
+// Hover scrolls element into view and hovers over its center point,
+// or over the position option relative to its top-left corner.
 func (h *ElementHandle) Hover(opts goja.Value) {
 	actionOpts := NewElementHandleHoverOptions(h.defaultTimeout())
 	if err := actionOpts.Parse(h.ctx, opts); err != nil {
 		k6ext.Panic(h.ctx, "parsing element hover options: %w", err)
 	}
 	fn := func(apiCtx context.Context, handle *ElementHandle, p *Position) (interface{}, error) {
-		return nil, handle.hover(apiCtx, p)
+		return nil, handle.hover(apiCtx, p, actionOpts.Modifiers)
 	}
 	pointerFn := h.newPointerAction(fn, &actionOpts.ElementHandleBasePointerOptions)
 	_, err := callApiWithTimeout(h.ctx, pointerFn, actionOpts.Timeout)
 	if err != nil {
 		k6ext.Panic(h.ctx, "hovering on element: %w", err)
 	}
-	applySlowMo(h.ctx)
 }
 
 // InnerHTML returns the inner HTML of the element.
@@ -1013,9 +1133,8 @@ func (h *ElementHandle) Press(key string, opts goja.Value) {
 	actFn := h.newAction([]string{}, fn, false, parsedOpts.NoWaitAfter, parsedOpts.Timeout)
 	_, err := callApiWithTimeout(h.ctx, actFn, parsedOpts.Timeout)
 	if err != nil {
-		k6ext.Panic(h.ctx, "pressing %q: %v", key, err)
+		k6ext.Panic(h.ctx, "pressing %q: %w", key, err)
 	}
-	applySlowMo(h.ctx)
 }
 
 // Query runs "element.querySelector" within the page. If no element matches the selector,
@@ -1106,7 +1225,8 @@ func (h *ElementHandle) queryAll(selector string, eval evalFunc) ([]api.ElementH
 	return els, nil
 }
 
-// SetChecked checks or unchecks an element.
+// SetChecked checks or unchecks a checkbox or radio button element.
+// It clicks on the element only if it's not in the requested state already.
 func (h *ElementHandle) SetChecked(checked bool, opts goja.Value) {
 	parsedOpts := NewElementHandleSetCheckedOptions(h.defaultTimeout())
 	err := parsedOpts.Parse(h.ctx, opts)
@@ -1114,15 +1234,15 @@ func (h *ElementHandle) SetChecked(checked bool, opts goja.Value) {
 		k6ext.Panic(h.ctx, "parsing setChecked options: %w", err)
 	}
 
-	fn := func(apiCtx context.Context, handle *ElementHandle, p *Position) (interface{}, error) {
-		return nil, handle.setChecked(apiCtx, checked, p)
+	fn := func(apiCtx context.Context, handle *ElementHandle) (interface{}, error) {
+		return nil, handle.setChecked(apiCtx, checked, &parsedOpts.ElementHandleBasePointerOptions)
 	}
-	pointerFn := h.newPointerAction(fn, &parsedOpts.ElementHandleBasePointerOptions)
-	_, err = callApiWithTimeout(h.ctx, pointerFn, parsedOpts.Timeout)
+	// the click waits for the actionability checks and navigations when needed.
+	actFn := h.newAction([]string{}, fn, true, true, parsedOpts.Timeout)
+	_, err = callApiWithTimeout(h.ctx, actFn, parsedOpts.Timeout)
 	if err != nil {
 		k6ext.Panic(h.ctx, "checking element: %w", err)
 	}
-	applySlowMo(h.ctx)
 }
 
 // Uncheck scrolls element into view, and if it's an input element of type
@@ -1132,12 +1252,15 @@ func (h *ElementHandle) Uncheck(opts goja.Value) {
 }
 
 // Check scrolls element into view, and if it's an input element of type
-// checkbox that is unchecked, clicks on it to mark it as checked.
+// checkbox or radio that is unchecked, clicks on it to mark it as checked.
 func (h *ElementHandle) Check(opts goja.Value) {
 	h.SetChecked(true, opts)
 }
 
-func (h *ElementHandle) setChecked(apiCtx context.Context, checked bool, p *Position) error {
+// setChecked reads the checked state of the element, and clicks on it if
+// it's not in the wanted state. Then, it verifies that the click changed the
+// state, which may not happen, for example, if a click handler prevents it.
+func (h *ElementHandle) setChecked(apiCtx context.Context, checked bool, opts *ElementHandleBasePointerOptions) error {
 	state, err := h.checkElementState(apiCtx, "checked")
 	if err != nil {
 		return err
@@ -1145,11 +1268,25 @@ func (h *ElementHandle) setChecked(apiCtx context.Context, checked bool, p *Posi
 	if checked == *state {
 		return nil
 	}
+	if !checked {
+		radio, err := h.isRadio(apiCtx)
+		if err != nil {
+			return err
+		}
+		if radio {
+			return errors.New("cannot uncheck a radio button")
+		}
+	}
 
-	err = h.click(p, NewMouseClickOptions())
-	if err != nil {
+	click := func(apiCtx context.Context, handle *ElementHandle, p *Position) (interface{}, error) {
+		return nil, handle.click(p, NewMouseClickOptions())
+	}
+	if _, err := callApiWithTimeout(apiCtx, h.newPointerAction(click, opts), opts.Timeout); err != nil {
 		return err
 	}
+	if opts.Trial {
+		return nil
+	}
 
 	state, err = h.checkElementState(apiCtx, "checked")
 	if err != nil {
@@ -1162,12 +1299,38 @@ func (h *ElementHandle) setChecked(apiCtx context.Context, checked bool, p *Posi
 	return nil
 }
 
+// isRadio returns true if the element, or the control of the label element,
+// is an input element of type radio.
+func (h *ElementHandle) isRadio(apiCtx context.Context) (bool, error) {
+	fn := `
+		(node, injected) => {
+			const element = injected._retarget(node, "follow-label");
+			return !!element && element.nodeName === "INPUT" && element.type.toLowerCase() === "radio";
+		}
+	`
+	opts := evalOptions{
+		forceCallable: true,
+		returnByValue: true,
+	}
+	result, err := h.evalWithScript(apiCtx, opts, fn)
+	if err != nil {
+		return false, err
+	}
+	v, ok := result.(goja.Value)
+	if !ok {
+		return false, fmt.Errorf("unexpected type %T", result)
+	}
+
+	return v.ToBoolean(), nil
+}
+
 func (h *ElementHandle) Screenshot(opts goja.Value) goja.ArrayBuffer {
 	rt := h.execCtx.vu.Runtime()
 	parsedOpts := NewElementHandleScreenshotOptions(h.defaultTimeout())
 	if err := parsedOpts.Parse(h.ctx, opts); err != nil {
 		k6ext.Panic(h.ctx, "parsing screenshot options: %w", err)
 	}
+	parsedOpts.Path = h.frame.page.artifactPath(parsedOpts.Path)
 
 	s := newScreenshotter(h.ctx)
 	buf, err := s.screenshotElement(h, parsedOpts)
@@ -1229,27 +1392,46 @@ func (h *ElementHandle) SelectText(opts goja.Value) {
 	applySlowMo(h.ctx)
 }
 
+// SetInputFiles sets the files of a file input element.
 func (h *ElementHandle) SetInputFiles(files goja.Value, opts goja.Value) {
-	// TODO: implement
-	k6ext.Panic(h.ctx, "ElementHandle.setInputFiles() has not been implemented yet")
+	actionOpts := NewElementHandleBaseOptions(h.defaultTimeout())
+	if err := actionOpts.Parse(h.ctx, opts); err != nil {
+		k6ext.Panic(h.ctx, "parsing setInputFiles options: %w", err)
+	}
+	var parsedFiles InputFiles
+	if err := parsedFiles.Parse(h.ctx, files); err != nil {
+		k6ext.Panic(h.ctx, "parsing setInputFiles files: %w", err)
+	}
+	fn := func(apiCtx context.Context, handle *ElementHandle) (interface{}, error) {
+		return nil, handle.setInputFiles(apiCtx, &parsedFiles)
+	}
+	actFn := h.newAction([]string{}, fn, actionOpts.Force, actionOpts.NoWaitAfter, actionOpts.Timeout)
+	if _, err := callApiWithTimeout(h.ctx, actFn, actionOpts.Timeout); err != nil {
+		k6ext.Panic(h.ctx, "setting input files: %w", err)
+	}
+	applySlowMo(h.ctx)
 }
 
+// Tap scrolls element into view and taps its center point,
+// or the position option relative to its top-left corner.
 func (h *ElementHandle) Tap(opts goja.Value) {
 	parsedOpts := NewElementHandleTapOptions(h.defaultTimeout())
 	err := parsedOpts.Parse(h.ctx, opts)
 	if err != nil {
 		k6ext.Panic(h.ctx, "parsing tap options: %w", err)
 	}
+	if !h.frame.page.Touchscreen.hasTouch {
+		k6ext.Panic(h.ctx, "tapping element: %w", ErrTouchNotSupported)
+	}
 
 	fn := func(apiCtx context.Context, handle *ElementHandle, p *Position) (interface{}, error) {
-		return nil, handle.tap(apiCtx, p)
+		return nil, handle.tap(apiCtx, p, parsedOpts.Modifiers)
 	}
 	pointerFn := h.newPointerAction(fn, &parsedOpts.ElementHandleBasePointerOptions)
 	_, err = callApiWithTimeout(h.ctx, pointerFn, parsedOpts.Timeout)
 	if err != nil {
 		k6ext.Panic(h.ctx, "tapping element: %w", err)
 	}
-	applySlowMo(h.ctx)
 }
 
 func (h *ElementHandle) TextContent() string {
@@ -1281,7 +1463,6 @@ func (h *ElementHandle) Type(text string, opts goja.Value) {
 	if err != nil {
 		k6ext.Panic(h.ctx, "typing text %q: %w", text, err)
 	}
-	applySlowMo(h.ctx)
 }
 
 func (h *ElementHandle) WaitForElementState(state string, opts goja.Value) {
@@ -1304,7 +1485,10 @@ func (h *ElementHandle) WaitForSelector(selector string, opts goja.Value) api.El
 
 	handle, err := h.waitForSelector(h.ctx, selector, parsedOpts)
 	if err != nil {
-		k6ext.Panic(h.ctx, "waiting for selector %q: %w", selector, err)
+		k6ext.Panic(h.ctx, "waiting for selector %q to be %s: %w", selector, parsedOpts.State, err)
+	}
+	if handle == nil {
+		return nil
 	}
 
 	return handle
@@ -1492,11 +1676,30 @@ func retryPointerAction(
 	return res, err
 }
 
+// errorFromDOMErr is like errorFromDOMError, but returns err as is if it
+// is an error of the browser API, such as a closed target, so that its
+// type survives.
+func errorFromDOMErr(err error) error {
+	if api.ErrorName(err) != "" {
+		return err
+	}
+	return errorFromDOMError(err.Error())
+}
+
 func errorFromDOMError(derr string) error {
+	// checked first as the element previews of the error may contain anything.
+	if s := "error:strictmodeviolation:"; strings.HasPrefix(derr, s) {
+		return &api.SelectorResolutionError{
+			Err: fmt.Errorf("strict mode violation: %s", strings.TrimPrefix(derr, s)),
+		}
+	}
 	// return the same sentinel error value for the timed out err
 	if strings.Contains(derr, "timed out") {
 		return ErrTimedOut
 	}
+	if s := ErrInterrupted.Error(); strings.HasPrefix(derr, s) {
+		return fmt.Errorf("%w%s", ErrInterrupted, strings.TrimPrefix(derr, s))
+	}
 	if s := "error:expectednode:"; strings.HasPrefix(derr, s) {
 		return fmt.Errorf("expected node but got %s", strings.TrimPrefix(derr, s))
 	}
@@ -1512,6 +1715,7 @@ func errorFromDOMError(derr string) error {
 		"error:hasnovalue":             "node is not an HTMLInputElement or HTMLTextAreaElement or HTMLSelectElement",
 		"error:notselect":              "element is not a <select> element",
 		"error:notcheckbox":            "not a checkbox or radio button",
+		"error:notfileinput":           "node is not an <input type=file> element",
 		"error:notmultiplefileinput":   "non-multiple file input can only accept single file",
 		"error:strictmodeviolation":    "strict mode violation, multiple elements returned for selector query",
 		"error:notqueryablenode":       "node is not queryable",
@@ -1519,6 +1723,9 @@ func errorFromDOMError(derr string) error {
 		"error:intercept":              "another element is intercepting with pointer action",
 	}
 	if err, ok := errs[derr]; ok {
+		if derr == "error:strictmodeviolation" {
+			return &api.SelectorResolutionError{Err: errors.New(err)}
+		}
 		return errors.New(err)
 	}
 
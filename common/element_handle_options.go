@@ -22,7 +22,7 @@ package common
 
 import (
 	"context"
-	"strings"
+	"fmt"
 	"time"
 
 	"github.com/dop251/goja"
@@ -103,6 +103,8 @@ type ElementHandlePressOptions struct {
 type ElementHandleScreenshotOptions struct {
 	Path           string        `json:"path"`
 	Format         ImageFormat   `json:"format"`
+	Mask           []string      `json:"mask"`
+	MaskColor      string        `json:"maskColor"`
 	OmitBackground bool          `json:"omitBackground"`
 	Quality        int64         `json:"quality"`
 	Timeout        time.Duration `json:"timeout"`
@@ -174,11 +176,10 @@ func (o *ElementHandleBasePointerOptions) Parse(ctx context.Context, opts goja.V
 			switch k {
 			case "position":
 				var p map[string]float64
-				o.Position = &Position{}
-				if rt.ExportTo(opts.Get(k), &p) != nil {
-					o.Position.X = p["x"]
-					o.Position.Y = p["y"]
+				if err := rt.ExportTo(opts.Get(k), &p); err != nil {
+					return fmt.Errorf("parsing position: %w", err)
 				}
+				o.Position = &Position{X: p["x"], Y: p["y"]}
 			case "trial":
 				o.Trial = opts.Get(k).ToBoolean()
 			}
@@ -231,7 +232,7 @@ func (o *ElementHandleClickOptions) Parse(ctx context.Context, opts goja.Value)
 			}
 		}
 	}
-	return nil
+	return validateClickCount(o.ClickCount)
 }
 
 func (o *ElementHandleClickOptions) ToMouseClickOptions() *MouseClickOptions {
@@ -350,6 +351,7 @@ func NewElementHandleScreenshotOptions(defaultTimeout time.Duration) *ElementHan
 	return &ElementHandleScreenshotOptions{
 		Path:           "",
 		Format:         ImageFormatPNG,
+		MaskColor:      defaultScreenshotMaskColor,
 		OmitBackground: false,
 		Quality:        100,
 		Timeout:        defaultTimeout,
@@ -359,30 +361,47 @@ func NewElementHandleScreenshotOptions(defaultTimeout time.Duration) *ElementHan
 func (o *ElementHandleScreenshotOptions) Parse(ctx context.Context, opts goja.Value) error {
 	rt := k6ext.Runtime(ctx)
 	if opts != nil && !goja.IsUndefined(opts) && !goja.IsNull(opts) {
-		formatSpecified := false
+		var (
+			typ        string
+			qualitySet bool
+		)
 		opts := opts.ToObject(rt)
 		for _, k := range opts.Keys() {
 			switch k {
+			case "mask":
+				mask, err := parseScreenshotMask(rt, opts.Get(k))
+				if err != nil {
+					return err
+				}
+				o.Mask = mask
+			case "maskColor":
+				o.MaskColor = opts.Get(k).String()
 			case "omitBackground":
 				o.OmitBackground = opts.Get(k).ToBoolean()
 			case "path":
 				o.Path = opts.Get(k).String()
+				if err := validateArtifactPath(o.Path); err != nil {
+					return err
+				}
 			case "quality":
 				o.Quality = opts.Get(k).ToInteger()
+				qualitySet = true
 			case "type":
-				if f, ok := imageFormatToID[opts.Get(k).String()]; ok {
-					o.Format = f
-					formatSpecified = true
-				}
+				typ = opts.Get(k).String()
 			case "timeout":
 				o.Timeout = time.Duration(opts.Get(k).ToInteger()) * time.Millisecond
 			}
 		}
 
 		// Infer file format by path if format not explicitly specified (default is PNG)
-		if o.Path != "" && !formatSpecified {
-			if strings.HasSuffix(o.Path, ".jpg") || strings.HasSuffix(o.Path, ".jpeg") {
-				o.Format = ImageFormatJPEG
+		format, path, err := parseScreenshotFormat(typ, o.Path, o.Format)
+		if err != nil {
+			return err
+		}
+		o.Format, o.Path = format, path
+		if qualitySet {
+			if err := validateScreenshotQuality(o.Format, o.Quality); err != nil {
+				return err
 			}
 		}
 	}
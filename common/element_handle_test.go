@@ -21,6 +21,10 @@ func TestErrorFromDOMError(t *testing.T) {
 		{in: "timed out", want: ErrTimedOut, sentinel: true},
 		{in: "error:notconnected", want: errors.New("element is not attached to the DOM")},
 		{in: "error:expectednode:anything", want: errors.New("expected node but got anything")},
+		{
+			in:   "error:strictmodeviolation:selector \"a\" resolved to 2 elements:\n    1) <a>timed out</a>",
+			want: errors.New("strict mode violation: selector \"a\" resolved to 2 elements:\n    1) <a>timed out</a>"),
+		},
 		{in: "nonexistent error", want: errors.New("nonexistent error")},
 	} {
 		got := errorFromDOMError(tc.in)
@@ -33,6 +37,16 @@ func TestErrorFromDOMError(t *testing.T) {
 	}
 }
 
+func TestErrorFromDOMErr(t *testing.T) {
+	t.Parallel()
+
+	got := errorFromDOMErr(errors.New("error:notconnected"))
+	assert.EqualError(t, got, "element is not attached to the DOM")
+
+	got = errorFromDOMErr(ErrTargetClosed)
+	assert.ErrorIs(t, got, ErrTargetClosed)
+}
+
 //nolint:funlen
 func TestQueryAll(t *testing.T) {
 	t.Parallel()
@@ -22,6 +22,9 @@ package common
 
 import (
 	"fmt"
+	"reflect"
+
+	"github.com/grafana/xk6-browser/api"
 
 	"github.com/chromedp/cdproto/runtime"
 )
@@ -37,15 +40,93 @@ func (e Error) Error() string {
 // Error types.
 const (
 	ErrUnexpectedRemoteObjectWithID Error = "cannot extract value when remote object ID is given"
+	ErrBinaryTooLarge               Error = "binary data is too large"
+	ErrBrowserDisconnected          Error = "browser has been closed or disconnected"
 	ErrChannelClosed                Error = "channel closed"
 	ErrFrameDetached                Error = "frame detached"
+	ErrInterrupted                  Error = "interrupted"
 	ErrJSHandleDisposed             Error = "JS handle is disposed"
 	ErrJSHandleInvalid              Error = "JS handle is invalid"
-	ErrTargetCrashed                Error = "Target has crashed"
+	ErrPageCrashed                  Error = "page crashed"
+	ErrTargetClosed                 Error = "target closed"
 	ErrTimedOut                     Error = "timed out"
+	ErrTouchNotSupported            Error = "the browser context does not support touch; create it with the hasTouch option"
 	ErrWrongExecutionContext        Error = "JS handles can be evaluated only in the context they were created"
 )
 
+// Fatal reports whether the browser can't be used anymore after the error,
+// so that k6ext.Panic kills its process.
+func (e Error) Fatal() bool {
+	switch e {
+	case ErrBrowserDisconnected, ErrChannelClosed:
+		return true
+	}
+	return false
+}
+
+// apiError returns the error of the browser API that the error is an
+// instance of, such as api.TimeoutError for ErrTimedOut, or nil.
+func (e Error) apiError() api.Error {
+	switch e {
+	case ErrTimedOut:
+		return &api.TimeoutError{Err: e}
+	case ErrBrowserDisconnected, ErrChannelClosed, ErrFrameDetached, ErrTargetClosed:
+		return &api.TargetClosedError{Err: e}
+	}
+	return nil
+}
+
+// Is reports whether target is the error of the browser API that the
+// error is an instance of, so that errors.Is(err, &api.TimeoutError{})
+// matches the errors that wrap ErrTimedOut.
+func (e Error) Is(target error) bool {
+	a := e.apiError()
+	return a != nil && reflect.TypeOf(a) == reflect.TypeOf(target)
+}
+
+// As sets target to the error of the browser API that the error is an
+// instance of, if target is a pointer to its type, or to api.Error.
+func (e Error) As(target interface{}) bool {
+	a := e.apiError()
+	if a == nil {
+		return false
+	}
+	v := reflect.ValueOf(target).Elem()
+	if !reflect.TypeOf(a).AssignableTo(v.Type()) {
+		return false
+	}
+	v.Set(reflect.ValueOf(a))
+	return true
+}
+
+// FatalError is an error after which the browser can't be used anymore,
+// such as a lost connection or a failed launch. k6ext.Panic kills the
+// browser process only for the fatal errors, and leaves it running for
+// the others, such as a timeout.
+type FatalError struct {
+	err error
+}
+
+// NewFatalError returns the error err classified as fatal.
+func NewFatalError(err error) *FatalError {
+	return &FatalError{err: err}
+}
+
+// Error returns the message of the wrapped error.
+func (e *FatalError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *FatalError) Unwrap() error {
+	return e.err
+}
+
+// Fatal reports that the browser can't be used anymore.
+func (*FatalError) Fatal() bool {
+	return true
+}
+
 type BigIntParseError struct {
 	err error
 }
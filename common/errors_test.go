@@ -0,0 +1,89 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/grafana/xk6-browser/api"
+	"github.com/grafana/xk6-browser/k6ext"
+
+	"github.com/chromedp/cdproto"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFatalError(t *testing.T) {
+	t.Parallel()
+
+	closeErr := &websocket.CloseError{Code: websocket.CloseAbnormalClosure}
+	err := fmt.Errorf("sending message: %w", NewFatalError(closeErr))
+	assert.True(t, k6ext.IsFatal(err))
+	assert.True(t, errors.As(err, new(*websocket.CloseError)), "should wrap the error")
+	assert.Equal(t, "sending message: "+closeErr.Error(), err.Error())
+
+	assert.True(t, k6ext.IsFatal(fmt.Errorf("creating page: %w", ErrBrowserDisconnected)))
+	assert.True(t, k6ext.IsFatal(ErrChannelClosed))
+	assert.False(t, k6ext.IsFatal(fmt.Errorf("waiting for selector: %w", ErrTimedOut)))
+	assert.False(t, k6ext.IsFatal(ErrPageCrashed))
+}
+
+func TestAPIErrors(t *testing.T) {
+	t.Parallel()
+
+	t.Run("timeout", func(t *testing.T) {
+		t.Parallel()
+
+		err := fmt.Errorf("waiting for selector: %w", ErrTimedOut)
+		assert.ErrorIs(t, err, &api.TimeoutError{})
+		assert.ErrorIs(t, err, ErrTimedOut)
+		assert.Equal(t, api.TimeoutErrorName, api.ErrorName(err))
+
+		var terr *api.TimeoutError
+		require.True(t, errors.As(err, &terr))
+		assert.ErrorIs(t, terr, ErrTimedOut)
+	})
+	t.Run("target_closed", func(t *testing.T) {
+		t.Parallel()
+
+		for _, err := range []error{ErrFrameDetached, ErrTargetClosed, ErrBrowserDisconnected} {
+			err = fmt.Errorf("clicking: %w", err)
+			assert.Equal(t, api.TargetClosedErrorName, api.ErrorName(err))
+
+			var aerr api.Error
+			require.True(t, errors.As(err, &aerr))
+			assert.Equal(t, api.TargetClosedErrorName, aerr.Name())
+		}
+	})
+	t.Run("selector_resolution", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewSelector("*css=div >> *css=span")
+		require.Error(t, err)
+		var serr *api.SelectorResolutionError
+		require.True(t, errors.As(err, &serr))
+		assert.Equal(t, "*css=div >> *css=span", serr.Selector)
+
+		err = errorFromDOMError("error:strictmodeviolation:<div></div>")
+		assert.Equal(t, api.SelectorResolutionErrorName, api.ErrorName(err))
+		assert.EqualError(t, err, "strict mode violation: <div></div>")
+	})
+	t.Run("protocol", func(t *testing.T) {
+		t.Parallel()
+
+		cdpErr := &cdproto.Error{Code: -32000, Message: "Cannot find context"}
+		err := fmt.Errorf("evaluating: %w", &api.ProtocolError{
+			Code: cdpErr.Code, Message: cdpErr.Message, Err: cdpErr,
+		})
+		assert.Equal(t, api.ProtocolErrorName, api.ErrorName(err))
+		assert.True(t, errors.As(err, new(*cdproto.Error)), "should wrap the CDP error")
+	})
+	t.Run("other", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Empty(t, api.ErrorName(ErrPageCrashed))
+		assert.Empty(t, api.ErrorName(errors.New("timed out")))
+		assert.False(t, errors.As(ErrPageCrashed, new(api.Error)))
+	})
+}
@@ -30,6 +30,7 @@ var _ EventEmitter = &BaseEventEmitter{}
 const (
 	// Browser
 
+	EventBrowserCrashed      string = "crashed"
 	EventBrowserDisconnected string = "disconnected"
 
 	// BrowserContext
@@ -51,7 +52,7 @@ const (
 
 	EventPageClose            string = "close"
 	EventPageConsole          string = "console"
-	EventPageCrash            string = "crash"
+	EventPageCrash            string = "crashed"
 	EventPageDialog           string = "dialog"
 	EventPageDOMContentLoaded string = "domcontentloaded"
 	EventPageDownload         string = "download"
@@ -68,6 +69,7 @@ const (
 	EventPageResponse         string = "response"
 	EventPageWebSocket        string = "websocket"
 	EventPageWorker           string = "worker"
+	EventPageWorkerDestroyed  string = "workerdestroyed"
 
 	// Session
 
@@ -204,6 +206,7 @@ func (e *BaseEventEmitter) on(ctx context.Context, events []string, ch chan Even
 			e.handlers[event] = append(e.handlers[event], eh)
 		}
 	})
+	e.removeOnDone(ctx)
 }
 
 // OnAll registers a handler for all events.
@@ -211,4 +214,37 @@ func (e *BaseEventEmitter) onAll(ctx context.Context, ch chan Event) {
 	e.sync(func() {
 		e.handlersAll = append(e.handlersAll, eventHandler{ctx, ch})
 	})
+	e.removeOnDone(ctx)
+}
+
+// removeOnDone removes the handlers of ctx when ctx is done, so that
+// they don't linger until an event that they handle is emitted.
+func (e *BaseEventEmitter) removeOnDone(ctx context.Context) {
+	if ctx.Done() == nil {
+		// the handlers are never removed.
+		return
+	}
+	go func() {
+		select {
+		case <-e.ctx.Done():
+		case <-ctx.Done():
+			e.sync(func() {
+				for event, handlers := range e.handlers {
+					e.handlers[event] = removeDoneHandlers(handlers)
+				}
+				e.handlersAll = removeDoneHandlers(e.handlersAll)
+			})
+		}
+	}()
+}
+
+// removeDoneHandlers returns the handlers whose contexts aren't done.
+func removeDoneHandlers(handlers []eventHandler) []eventHandler {
+	kept := handlers[:0]
+	for _, h := range handlers {
+		if h.ctx.Err() == nil {
+			kept = append(kept, h)
+		}
+	}
+	return kept
 }
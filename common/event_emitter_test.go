@@ -23,6 +23,7 @@ package common
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/chromedp/cdproto"
 	"github.com/stretchr/testify/require"
@@ -62,6 +63,25 @@ func TestEventEmitterSpecificEvent(t *testing.T) {
 		})
 	})
 
+	t.Run("remove event handler without emission", func(t *testing.T) {
+		ctx := context.Background()
+		emitter := NewBaseEventEmitter(ctx)
+		cancelCtx, cancelFn := context.WithCancel(ctx)
+		ch := make(chan Event)
+
+		emitter.on(cancelCtx, []string{cdproto.EventTargetTargetCreated}, ch)
+		emitter.onAll(cancelCtx, ch)
+		cancelFn()
+
+		require.Eventually(t, func() bool {
+			var n int
+			emitter.sync(func() {
+				n = len(emitter.handlers[cdproto.EventTargetTargetCreated]) + len(emitter.handlersAll)
+			})
+			return n == 0
+		}, time.Second, 10*time.Millisecond)
+	})
+
 	t.Run("emit event", func(t *testing.T) {
 		ctx := context.Background()
 		emitter := NewBaseEventEmitter(ctx)
@@ -0,0 +1,186 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package common
+
+import (
+	"context"
+	"sync"
+
+	"github.com/grafana/xk6-browser/k6ext"
+
+	k6modules "go.k6.io/k6/js/modules"
+)
+
+// eventLoopQueue runs the tasks that are queued from other goroutines,
+// such as the ones that handle CDP events, on the event loop of a VU.
+type eventLoopQueue struct {
+	mu      sync.Mutex
+	tasks   []func() error
+	ready   chan struct{}
+	started bool
+	cancel  context.CancelFunc
+	// iterDone is closed when the iteration
+	// that started the queue ends.
+	iterDone <-chan struct{}
+}
+
+// start starts running the queued tasks on the event loop of the VU, in the
+// order they are queued, until ctx is done or the current iteration ends.
+// It keeps the event loop alive until then, but without keeping the
+// iteration running while there are no tasks, and stops the event loop
+// with the error of a task if one fails. It must be called on the event
+// loop, and reports whether the queue was started by this call.
+func (q *eventLoopQueue) start(ctx context.Context, vu k6modules.VU) bool {
+	q.mu.Lock()
+	if q.started {
+		select {
+		case <-q.iterDone:
+			// the queue of the previous iteration may not have noticed
+			// that the iteration ended yet, and it's replaced.
+			q.cancel()
+		default:
+			q.mu.Unlock()
+			return false
+		}
+	}
+	q.started = true
+	q.ready = make(chan struct{}, 1)
+	if len(q.tasks) > 0 {
+		q.ready <- struct{}{}
+	}
+	ready := q.ready
+	ctx, q.cancel = context.WithCancel(ctx)
+	iterDone := vu.Context().Done()
+	q.iterDone = iterDone
+	q.mu.Unlock()
+
+	cb := k6ext.RegisterListener(vu)
+	go func() {
+		defer q.release(ready)
+
+		for {
+			select {
+			case <-ctx.Done():
+				cb(func() error { return nil })
+				return
+			case <-iterDone:
+				cb(func() error { return nil })
+				return
+			case <-ready:
+			}
+
+			q.mu.Lock()
+			tasks := q.tasks
+			q.tasks = nil
+			q.mu.Unlock()
+
+			var (
+				next      = make(chan func(func() error), 1)
+				nextMu    sync.Mutex
+				abandoned bool
+			)
+			cb(func() error {
+				var err error
+				for _, task := range tasks {
+					if err = task(); err != nil {
+						break
+					}
+				}
+				nextMu.Lock()
+				defer nextMu.Unlock()
+				// keep waiting for tasks on the event loop, unless a task
+				// failed or the queue was stopped while running the tasks.
+				if err != nil || ctx.Err() != nil || abandoned {
+					next <- nil
+					return err
+				}
+				next <- k6ext.RegisterListener(vu)
+				return nil
+			})
+			select {
+			case cb = <-next:
+				if cb == nil {
+					return
+				}
+			case <-iterDone:
+				// the event loop may be gone, and so may the tasks. If they
+				// already ran, release the callback that they registered.
+				nextMu.Lock()
+				abandoned = true
+				nextMu.Unlock()
+				select {
+				case cb = <-next:
+					if cb != nil {
+						cb(func() error { return nil })
+					}
+				default:
+				}
+				return
+			}
+		}
+	}()
+
+	return true
+}
+
+// release marks the queue that was started with ready as stopped, so that
+// it can start again, such as in the next iteration. It does nothing if the
+// queue has been stopped or started again since.
+func (q *eventLoopQueue) release(ready chan struct{}) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !q.started || q.ready != ready {
+		return
+	}
+	// the tasks of an ended iteration don't run in the next one.
+	q.tasks = nil
+	q.cancel()
+	q.started = false
+}
+
+// stop stops running the tasks on the event loop, so that it no longer keeps
+// the event loop alive, and drops the queued tasks. The queue can be started
+// again, such as when a page is reused in the next iteration.
+func (q *eventLoopQueue) stop() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.tasks = nil
+	if !q.started {
+		return
+	}
+	q.cancel()
+	q.started = false
+}
+
+// push queues a task to run on the event loop.
+// It doesn't wait for the task to run.
+func (q *eventLoopQueue) push(task func() error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.tasks = append(q.tasks, task)
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,169 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/grafana/xk6-browser/k6ext"
+	"github.com/grafana/xk6-browser/k6ext/k6test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventLoopQueue(t *testing.T) {
+	t.Parallel()
+
+	t.Run("runs_tasks_in_order", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			vu          = k6test.NewVU(t)
+			q           eventLoopQueue
+			ctx, cancel = context.WithCancel(context.Background())
+			got         []int
+		)
+		// tasks queued before the queue starts run too.
+		q.push(func() error { got = append(got, 1); return nil })
+		err := vu.Loop.Start(func() error {
+			require.True(t, q.start(ctx, vu))
+			require.False(t, q.start(ctx, vu), "should start once")
+			go func() {
+				q.push(func() error { got = append(got, 2); return nil })
+				q.push(func() error {
+					got = append(got, 3)
+					cancel()
+					return nil
+				})
+			}()
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []int{1, 2, 3}, got)
+	})
+
+	t.Run("restarts_after_stop", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			vu  = k6test.NewVU(t)
+			q   eventLoopQueue
+			got []int
+		)
+		err := vu.Loop.Start(func() error {
+			require.True(t, q.start(context.Background(), vu))
+			// the stopped queue no longer keeps the event loop alive.
+			q.stop()
+			return nil
+		})
+		require.NoError(t, err)
+
+		// the tasks of the stopped queue are dropped.
+		q.push(func() error { got = append(got, 1); return nil })
+		q.stop()
+		ctx, cancel := context.WithCancel(context.Background())
+		err = vu.Loop.Start(func() error {
+			require.True(t, q.start(ctx, vu), "should start again")
+			q.push(func() error {
+				got = append(got, 2)
+				cancel()
+				return nil
+			})
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []int{2}, got)
+	})
+
+	t.Run("stops_when_iteration_ends", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			vu                    = k6test.NewVU(t)
+			q                     eventLoopQueue
+			iterCtx, endIteration = context.WithCancel(vu.CtxField)
+		)
+		vu.CtxField = iterCtx
+		err := vu.Loop.Start(func() error {
+			require.True(t, q.start(context.Background(), vu))
+			time.AfterFunc(50*time.Millisecond, endIteration)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("restarts_after_iteration_ends", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			vu                    = k6test.NewVU(t)
+			q                     eventLoopQueue
+			iterCtx, endIteration = context.WithCancel(context.Background())
+			got                   []int
+		)
+		vu.CtxField = iterCtx
+		err := vu.Loop.Start(func() error {
+			require.True(t, q.start(context.Background(), vu))
+			q.push(func() error {
+				got = append(got, 1)
+				endIteration()
+				return nil
+			})
+			return nil
+		})
+		require.NoError(t, err)
+
+		// the next iteration starts the queue again.
+		vu.CtxField = context.Background()
+		ctx, cancel := context.WithCancel(context.Background())
+		err = vu.Loop.Start(func() error {
+			require.True(t, q.start(ctx, vu), "should start again")
+			q.push(func() error {
+				got = append(got, 2)
+				cancel()
+				return nil
+			})
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []int{1, 2}, got)
+	})
+
+	t.Run("doesnt_keep_iteration_running", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			vu = k6test.NewVU(t)
+			td = k6ext.NewIterationTeardown(vu)
+			q  eventLoopQueue
+		)
+		// like a page that the iteration leaves open,
+		// the queue stops once the browser is closed.
+		var browserCtx context.Context
+		err := vu.Loop.Start(func() error {
+			browserCtx = td.Context()
+			require.True(t, q.start(browserCtx, k6ext.GetVU(browserCtx)))
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Error(t, browserCtx.Err(), "iteration should end")
+	})
+
+	t.Run("stops_on_error", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			vu   = k6test.NewVU(t)
+			q    eventLoopQueue
+			fail = errors.New("fail")
+		)
+		err := vu.Loop.Start(func() error {
+			q.start(context.Background(), vu)
+			q.push(func() error { return fail })
+			return nil
+		})
+		assert.ErrorIs(t, err, fail)
+	})
+}
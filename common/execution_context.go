@@ -23,9 +23,12 @@ package common
 import (
 	"context"
 	_ "embed"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"regexp"
+	"strconv"
+	"sync"
 
 	"github.com/grafana/xk6-browser/api"
 	"github.com/grafana/xk6-browser/k6ext"
@@ -39,6 +42,7 @@ import (
 	"github.com/chromedp/cdproto/runtime"
 	"github.com/chromedp/cdproto/target"
 	"github.com/dop251/goja"
+	"github.com/mailru/easyjson"
 )
 
 const evaluationScriptURL = "__xk6_browser_evaluation_script__"
@@ -74,6 +78,13 @@ type ExecutionContext struct {
 	injectedScript api.JSHandle
 	vu             k6modules.VU
 
+	// The number of custom selector engines of the browser
+	// that are registered in the injected script. The mutex
+	// keeps the concurrent calls of the execution context from
+	// registering the same engines twice.
+	selectorEnginesMu sync.Mutex
+	selectorEngines   int
+
 	// Used for logging
 	sid  target.SessionID // Session ID
 	stid cdp.FrameID      // Session TargetID
@@ -192,26 +203,56 @@ func (e *ExecutionContext) eval(
 
 		action = runtime.Evaluate(js).
 			WithContextID(e.id).
-			WithReturnByValue(opts.returnByValue).
+			WithReturnByValue(false).
 			WithAwaitPromise(true).
 			WithUserGesture(true)
 	} else {
-		var arguments []*runtime.CallArgument
+		var (
+			arguments []*runtime.CallArgument
+			special   = specialArgs{
+				maxDepth:      DefaultEvaluateMaxDepth,
+				maxBinarySize: DefaultEvaluateMaxBinarySize,
+			}
+		)
+		if lopts := GetLaunchOptions(e.ctx); lopts != nil {
+			if lopts.EvaluateMaxDepth > 0 {
+				special.maxDepth = lopts.EvaluateMaxDepth
+			}
+			special.maxBinarySize = lopts.EvaluateMaxBinarySize
+		}
 		for _, arg := range args {
-			result, err := convertArgument(apiCtx, e, arg)
+			extracted, err := special.extract(arg)
+			if err != nil {
+				return nil, fmt.Errorf("converting argument %q "+
+					"in execution context ID %d and frame ID %v: %w",
+					arg, e.id, e.fid, err)
+			}
+			result, err := convertArgument(apiCtx, e, extracted)
 			if err != nil {
 				return nil, fmt.Errorf("converting argument %q "+
 					"in execution context ID %d and frame ID %v: %w",
-					arg, e.id, e.Frame().ID(), err)
+					arg, e.id, e.fid, err)
 			}
 			arguments = append(arguments, result)
 		}
+		// the handles nested in the arguments are passed as arguments
+		// of their own, since they can't be serialized.
+		for i, h := range special.handles {
+			result, err := convertBaseJSHandleTypes(apiCtx, e, h)
+			if err != nil {
+				return nil, fmt.Errorf("converting JS handle #%d nested in the arguments "+
+					"in execution context ID %d and frame ID %v: %w",
+					i, e.id, e.fid, err)
+			}
+			arguments = append(arguments, result)
+		}
+		js = special.wrap(js, len(args))
 
 		js += "\n" + suffix + "\n"
 		action = runtime.CallFunctionOn(js).
 			WithArguments(arguments).
 			WithExecutionContextID(e.id).
-			WithReturnByValue(opts.returnByValue).
+			WithReturnByValue(false).
 			WithAwaitPromise(true).
 			WithUserGesture(true)
 	}
@@ -229,7 +270,7 @@ func (e *ExecutionContext) eval(
 		return nil, err
 	}
 	if exceptionDetails != nil {
-		return nil, fmt.Errorf("%s", parseExceptionDetails(exceptionDetails))
+		return nil, newEvaluationError(exceptionDetails)
 	}
 	var res interface{}
 	if remoteObject == nil {
@@ -241,7 +282,7 @@ func (e *ExecutionContext) eval(
 	}
 
 	if opts.returnByValue {
-		res, err = valueFromRemoteObject(apiCtx, remoteObject)
+		res, err = e.valueFromRemoteObject(apiCtx, remoteObject)
 		if err != nil {
 			return nil, fmt.Errorf(
 				"extracting value from remote object with ID %s: %w",
@@ -255,6 +296,82 @@ func (e *ExecutionContext) eval(
 	return res, nil
 }
 
+// evalValue evaluates the expression within this execution context, waits
+// for it if it's a promise, and unmarshals the JSON of its value into res.
+// Unlike eval, it doesn't use the goja runtime, so it can be called outside
+// of the event loop.
+func (e *ExecutionContext) evalValue(apiCtx context.Context, expr string, res interface{}) error {
+	action := runtime.Evaluate(expr).
+		WithContextID(e.id).
+		WithReturnByValue(true).
+		WithAwaitPromise(true)
+	remoteObject, exceptionDetails, err := action.Do(cdp.WithExecutor(apiCtx, e.session))
+	if err != nil {
+		return fmt.Errorf("evaluating in execution context ID %d: %w", e.id, err)
+	}
+	if exceptionDetails != nil {
+		return newEvaluationError(exceptionDetails)
+	}
+	if remoteObject == nil || len(remoteObject.Value) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(remoteObject.Value, res); err != nil {
+		return fmt.Errorf("unmarshaling value: %w", err)
+	}
+
+	return nil
+}
+
+// valueFromRemoteObject converts a remote object to a goja value. Objects are
+// serialized in the browser first, so that types such as Date and Map are
+// kept, and they are released afterwards.
+func (e *ExecutionContext) valueFromRemoteObject(
+	apiCtx context.Context, remoteObject *runtime.RemoteObject,
+) (goja.Value, error) {
+	if remoteObject.ObjectID == "" {
+		return valueFromRemoteObject(apiCtx, remoteObject)
+	}
+	defer func() {
+		// Note: we don't use the passed in apiCtx here as it could be tied to a timeout
+		action := runtime.ReleaseObject(remoteObject.ObjectID)
+		if err := action.Do(cdp.WithExecutor(e.ctx, e.session)); err != nil {
+			e.logger.Debugf(
+				"ExecutionContext:valueFromRemoteObject",
+				"sid:%s stid:%s fid:%s ectxid:%d furl:%q releasing object: %v",
+				e.sid, e.stid, e.fid, e.id, e.furl, err)
+		}
+	}()
+
+	maxDepth, maxBinarySize := DefaultEvaluateMaxDepth, DefaultEvaluateMaxBinarySize
+	if lopts := GetLaunchOptions(e.ctx); lopts != nil {
+		if lopts.EvaluateMaxDepth > 0 {
+			maxDepth = lopts.EvaluateMaxDepth
+		}
+		maxBinarySize = lopts.EvaluateMaxBinarySize
+	}
+	action := runtime.CallFunctionOn(serializeValueFn).
+		WithObjectID(remoteObject.ObjectID).
+		WithArguments([]*runtime.CallArgument{
+			{ObjectID: remoteObject.ObjectID},
+			{Value: easyjson.RawMessage(strconv.FormatInt(maxDepth, 10))},
+			{Value: easyjson.RawMessage(strconv.FormatInt(maxBinarySize, 10))},
+		}).
+		WithReturnByValue(true)
+	serialized, exceptionDetails, err := action.Do(cdp.WithExecutor(apiCtx, e.session))
+	if err != nil {
+		return nil, fmt.Errorf("serializing value: %w", err)
+	}
+	if exceptionDetails != nil {
+		return nil, fmt.Errorf("serializing value: %w", newEvaluationError(exceptionDetails))
+	}
+	var v serializedValue
+	if err := json.Unmarshal(serialized.Value, &v); err != nil {
+		return nil, fmt.Errorf("parsing serialized value: %w", err)
+	}
+
+	return v.toGojaValue(k6ext.Runtime(apiCtx))
+}
+
 // Based on: https://github.com/microsoft/playwright/blob/master/src/server/injected/injectedScript.ts
 //go:embed js/injected_script.js
 var injectedScriptSource string
@@ -266,13 +383,24 @@ func (e *ExecutionContext) getInjectedScript(apiCtx context.Context) (api.JSHand
 		"sid:%s stid:%s fid:%s ectxid:%d efurl:%s",
 		e.sid, e.stid, e.fid, e.id, e.furl)
 
-	if e.injectedScript != nil {
-		return e.injectedScript, nil
+	if e.injectedScript == nil {
+		if err := e.injectScript(apiCtx); err != nil {
+			return nil, err
+		}
 	}
+	e.registerSelectorEngines(apiCtx)
 
+	return e.injectedScript, nil
+}
+
+// injectScript evaluates the injected script and saves its handle.
+func (e *ExecutionContext) injectScript(apiCtx context.Context) error {
 	var (
-		suffix                  = `//# sourceURL=` + evaluationScriptURL
-		source                  = fmt.Sprintf(`(() => {%s; return new InjectedScript();})()`, injectedScriptSource)
+		suffix = `//# sourceURL=` + evaluationScriptURL
+		source = fmt.Sprintf(
+			`(() => {%s; return new InjectedScript(%q);})()`,
+			injectedScriptSource, e.testIDAttribute(),
+		)
 		expression              = source
 		expressionWithSourceURL = expression
 	)
@@ -285,18 +413,72 @@ func (e *ExecutionContext) getInjectedScript(apiCtx context.Context) (api.JSHand
 		expressionWithSourceURL,
 	)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	if handle == nil {
-		return nil, errors.New("handle is nil")
+		return errors.New("handle is nil")
 	}
 	injectedScript, ok := handle.(api.JSHandle)
 	if !ok {
-		return nil, ErrJSHandleInvalid
+		return ErrJSHandleInvalid
 	}
 	e.injectedScript = injectedScript
 
-	return e.injectedScript, nil
+	return nil
+}
+
+// registerSelectorEngines registers the custom selector engines of the browser
+// that are not registered in the injected script yet, so that the engines that
+// are registered after the execution context is created can be used as well.
+//
+// An engine that cannot be registered, for example because of a syntax error
+// in its source, is replaced by one that fails with the registration error,
+// so that it fails the selectors that use it and not the others.
+func (e *ExecutionContext) registerSelectorEngines(apiCtx context.Context) {
+	b := e.browser()
+	if b == nil {
+		return
+	}
+	e.selectorEnginesMu.Lock()
+	defer e.selectorEnginesMu.Unlock()
+
+	for _, se := range b.selectorEngines.from(e.selectorEngines) {
+		var (
+			js   = fmt.Sprintf(`(injected, name) => injected.registerEngine(name, (%s))`, se.source)
+			opts = evalOptions{forceCallable: true, returnByValue: true}
+		)
+		if _, err := e.eval(apiCtx, opts, js, e.injectedScript, se.name); err != nil {
+			e.logger.Debugf(
+				"ExecutionContext:registerSelectorEngines",
+				"sid:%s stid:%s fid:%s ectxid:%d name:%q err:%v",
+				e.sid, e.stid, e.fid, e.id, se.name, err)
+
+			js = `(injected, name, err) => injected.registerEngine(name, { queryAll() { throw err; } })`
+			if _, err := e.eval(apiCtx, opts, js, e.injectedScript, se.name, err.Error()); err != nil {
+				// the execution context is likely gone, try again next time.
+				return
+			}
+		}
+		e.selectorEngines++
+	}
+}
+
+// testIDAttribute returns the attribute that the testid selector engine
+// of the injected script matches.
+func (e *ExecutionContext) testIDAttribute() string {
+	if e.frame == nil || e.frame.page == nil || e.frame.page.browserCtx == nil ||
+		e.frame.page.browserCtx.opts == nil {
+		return DefaultTestIDAttribute
+	}
+	return e.frame.page.browserCtx.opts.TestIDAttribute
+}
+
+// browser returns the browser of the execution context, if any.
+func (e *ExecutionContext) browser() *Browser {
+	if e.frame == nil || e.frame.page == nil || e.frame.page.browserCtx == nil {
+		return nil
+	}
+	return e.frame.page.browserCtx.browser
 }
 
 // Eval evaluates the provided JavaScript within this execution context and
@@ -0,0 +1,204 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/grafana/xk6-browser/api"
+	"github.com/grafana/xk6-browser/k6ext"
+	"github.com/grafana/xk6-browser/log"
+
+	k6metrics "go.k6.io/k6/metrics"
+
+	"github.com/dop251/goja"
+)
+
+// expectPollInterval is how long an assertion waits
+// before it checks the elements again.
+const expectPollInterval = 100 * time.Millisecond
+
+// Ensure Expectation implements the api.Expectation interface.
+var _ api.Expectation = &Expectation{}
+
+// Expectation asserts the state of the elements that a locator matches.
+type Expectation struct {
+	ctx     context.Context
+	locator *Locator
+	log     *log.Logger
+}
+
+// NewExpectation returns a new expectation of the elements of a locator.
+func NewExpectation(ctx context.Context, l *Locator, logger *log.Logger) *Expectation {
+	return &Expectation{
+		ctx:     ctx,
+		locator: l,
+		log:     logger,
+	}
+}
+
+// ToBeVisible asserts that the element is visible.
+func (e *Expectation) ToBeVisible(opts goja.Value) bool {
+	return e.assert("toBeVisible", "", "visible", opts, func(timeout time.Duration) (bool, string, error) {
+		visible, err := e.locator.isVisible(&FrameIsVisibleOptions{
+			FrameBaseOptions: FrameBaseOptions{Timeout: timeout},
+		})
+		if !visible {
+			return false, "hidden", err
+		}
+		return true, "visible", err
+	})
+}
+
+// ToHaveAttribute asserts that the element has an attribute
+// with the given value.
+func (e *Expectation) ToHaveAttribute(name, value string, opts goja.Value) bool {
+	args := strconv.Quote(name) + ", " + strconv.Quote(value)
+	return e.assert("toHaveAttribute", args, strconv.Quote(value), opts, func(timeout time.Duration) (bool, string, error) {
+		v, err := e.locator.getAttribute(name, &FrameBaseOptions{Timeout: timeout})
+		if err != nil || v == nil || goja.IsNull(v) || goja.IsUndefined(v) {
+			return false, "no attribute", err
+		}
+		return v.String() == value, strconv.Quote(v.String()), nil
+	})
+}
+
+// ToHaveCount asserts that the locator matches n elements.
+func (e *Expectation) ToHaveCount(n int64, opts goja.Value) bool {
+	want := strconv.FormatInt(n, 10)
+	return e.assert("toHaveCount", want, want, opts, func(time.Duration) (bool, string, error) {
+		count, err := e.locator.count()
+		return int64(count) == n, strconv.Itoa(count), err
+	})
+}
+
+// ToHaveText asserts that the text content of the element is text.
+func (e *Expectation) ToHaveText(text string, opts goja.Value) bool {
+	want := strconv.Quote(text)
+	return e.assert("toHaveText", want, want, opts, func(timeout time.Duration) (bool, string, error) {
+		s, err := e.locator.textContent(&FrameTextContentOptions{
+			FrameBaseOptions: FrameBaseOptions{Timeout: timeout},
+		})
+		return s == text, strconv.Quote(s), err
+	})
+}
+
+// assert retries the condition until it holds or the timeout of the
+// options expires, and records the result as a check. The condition
+// returns whether it holds and the actual value of the elements, and it
+// may wait until the given timeout for the elements to be attached.
+// A failed assertion logs the expected and actual values, or throws
+// them with the hard option.
+func (e *Expectation) assert(
+	method, args, expected string, opts goja.Value,
+	cond func(timeout time.Duration) (ok bool, actual string, err error),
+) bool {
+	e.log.Debugf("Expectation:"+method, "sel:%q args:%s", e.locator.selector, args)
+
+	aopts := NewExpectationOptions(DefaultExpectTimeout)
+	if err := aopts.Parse(e.ctx, opts); err != nil {
+		k6ext.Panic(e.ctx, "parsing %s options: %w", method, err)
+	}
+
+	var (
+		ok       bool
+		actual   string
+		err      error
+		deadline = time.Now().Add(aopts.Timeout)
+	)
+poll:
+	for {
+		// a zero timeout would make the condition wait without a limit.
+		timeout := time.Until(deadline)
+		if timeout < expectPollInterval {
+			timeout = expectPollInterval
+		}
+		if ok, actual, err = cond(timeout); ok {
+			break
+		}
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			break
+		}
+		if wait > expectPollInterval {
+			wait = expectPollInterval
+		}
+		select {
+		case <-e.ctx.Done():
+			err = e.ctx.Err()
+			break poll
+		case <-time.After(wait):
+		}
+	}
+
+	name := fmt.Sprintf("expect(%q).%s(%s)", e.locator.selector, method, args)
+	e.check(name, ok)
+	if ok {
+		return true
+	}
+
+	if err != nil {
+		actual = fmt.Sprintf("%s (%v)", actual, err)
+	}
+	if aopts.Hard {
+		k6ext.Panic(e.ctx, "%s: expected %s, got %s after %s", name, expected, actual, aopts.Timeout)
+	}
+	e.log.Warnf("Expectation:"+method, "%s: expected %s, got %s after %s", name, expected, actual, aopts.Timeout)
+
+	return false
+}
+
+// check records the result of an assertion as a k6 check,
+// like the check function of k6 does.
+func (e *Expectation) check(name string, pass bool) {
+	vu := k6ext.GetVU(e.ctx)
+	state := vu.State()
+	if state == nil {
+		return
+	}
+	check, err := state.Group.Check(name)
+	if err != nil {
+		k6ext.Panic(e.ctx, "recording check %q: %w", name, err)
+	}
+
+	ctx := vu.Context()
+	tags := vuMetricTags(ctx, state)
+	if state.Options.SystemTags.Has(k6metrics.TagCheck) {
+		tags["check"] = check.Name
+	}
+	var value float64
+	if pass {
+		atomic.AddInt64(&check.Passes, 1)
+		value = 1
+	} else {
+		atomic.AddInt64(&check.Fails, 1)
+	}
+	k6metrics.PushIfNotDone(ctx, state.Samples, k6metrics.Sample{
+		Metric: state.BuiltinMetrics.Checks,
+		Tags:   k6metrics.IntoSampleTags(&tags),
+		Value:  value,
+		Time:   time.Now(),
+	})
+}
@@ -0,0 +1,63 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package common
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/xk6-browser/k6ext"
+
+	"github.com/dop251/goja"
+)
+
+// ExpectationOptions are the options of the assertions of an Expectation.
+type ExpectationOptions struct {
+	// Timeout is how long the assertion retries until it fails.
+	Timeout time.Duration `json:"timeout"`
+	// Hard makes a failed assertion throw an error,
+	// which aborts the iteration.
+	Hard bool `json:"hard"`
+}
+
+// NewExpectationOptions returns the default options of an assertion.
+func NewExpectationOptions(defaultTimeout time.Duration) *ExpectationOptions {
+	return &ExpectationOptions{
+		Timeout: defaultTimeout,
+	}
+}
+
+// Parse parses the assertion options from opts.
+func (o *ExpectationOptions) Parse(ctx context.Context, opts goja.Value) error {
+	rt := k6ext.Runtime(ctx)
+	if opts != nil && !goja.IsUndefined(opts) && !goja.IsNull(opts) {
+		opts := opts.ToObject(rt)
+		for _, k := range opts.Keys() {
+			switch k {
+			case "hard":
+				o.Hard = opts.Get(k).ToBoolean()
+			case "timeout":
+				o.Timeout = time.Duration(opts.Get(k).ToInteger()) * time.Millisecond
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/xk6-browser/k6ext/k6test"
+	"github.com/grafana/xk6-browser/log"
+
+	k6metrics "go.k6.io/k6/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpectationOptionsParse(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+	opts := NewExpectationOptions(DefaultExpectTimeout)
+	require.NoError(t, opts.Parse(vu.Context(), nil))
+	assert.Equal(t, DefaultExpectTimeout, opts.Timeout)
+	assert.False(t, opts.Hard)
+
+	v, err := vu.Runtime().RunString(`({ timeout: 500, hard: true })`)
+	require.NoError(t, err)
+	require.NoError(t, opts.Parse(vu.Context(), v))
+	assert.Equal(t, 500*time.Millisecond, opts.Timeout)
+	assert.True(t, opts.Hard)
+}
+
+func TestExpectationCheck(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+	samples := make(chan k6metrics.SampleContainer, 10)
+	vu.StateField.Samples = samples
+
+	e := NewExpectation(vu.Context(), &Locator{selector: "#msg"}, log.NewNullLogger())
+	e.check(`expect("#msg").toHaveText("a")`, true)
+	e.check(`expect("#msg").toHaveText("a")`, false)
+
+	check, err := vu.StateField.Group.Check(`expect("#msg").toHaveText("a")`)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, check.Passes)
+	assert.EqualValues(t, 1, check.Fails)
+
+	require.Len(t, samples, 2)
+	for _, want := range []float64{1, 0} {
+		s := (<-samples).GetSamples()[0]
+		assert.Equal(t, k6metrics.ChecksName, s.Metric.Name)
+		assert.Equal(t, want, s.Value)
+		name, _ := s.Tags.Get("check")
+		assert.Equal(t, `expect("#msg").toHaveText("a")`, name)
+	}
+}
@@ -0,0 +1,140 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package common
+
+import (
+	"fmt"
+
+	"github.com/grafana/xk6-browser/api"
+
+	"github.com/chromedp/cdproto/cdp"
+	cdppage "github.com/chromedp/cdproto/page"
+	"github.com/dop251/goja"
+)
+
+// Ensure FileChooser implements the api.FileChooser interface.
+var _ api.FileChooser = &FileChooser{}
+
+// FileChooser is a file chooser that a page opened from a file input element.
+type FileChooser struct {
+	page       *Page
+	element    *ElementHandle
+	isMultiple bool
+}
+
+// Element returns the file input element that opened the file chooser.
+func (c *FileChooser) Element() api.ElementHandle {
+	return c.element
+}
+
+// IsMultiple returns whether the file chooser accepts multiple files.
+func (c *FileChooser) IsMultiple() bool {
+	return c.isMultiple
+}
+
+// Page returns the page that opened the file chooser.
+func (c *FileChooser) Page() api.Page {
+	return c.page
+}
+
+// SetFiles sets the files of the file input element.
+// It accepts the same files and options as ElementHandle.setInputFiles.
+func (c *FileChooser) SetFiles(files goja.Value, opts goja.Value) {
+	c.element.SetInputFiles(files, opts)
+}
+
+// onFileChooserOpened emits the file chooser that the page opened, and calls
+// the filechooser event handlers with it, if there are any. The browser only
+// reports the file choosers that it intercepts.
+func (p *Page) onFileChooserOpened(event *cdppage.EventFileChooserOpened) {
+	frame := p.frameManager.getFrameByID(event.FrameID)
+	if frame == nil {
+		p.logger.Debugf("Page:onFileChooserOpened", "sid:%v fid:%v: missing frame", p.sessionID(), event.FrameID)
+		return
+	}
+
+	// adopting the element waits for the session, so don't block its events.
+	go func() {
+		h, err := frame.adoptBackendNodeID(mainWorld, event.BackendNodeID)
+		if err != nil {
+			p.logger.Debugf("Page:onFileChooserOpened", "sid:%v fid:%v err:%v", p.sessionID(), event.FrameID, err)
+			return
+		}
+		c := &FileChooser{
+			page:       p,
+			element:    h,
+			isMultiple: event.Mode == cdppage.FileChooserOpenedModeSelectMultiple,
+		}
+		p.emit(EventPageFilechooser, c)
+		if p.hasEventHandlers(EventPageFilechooser) {
+			p.callEventHandlers(EventPageFilechooser, c)
+		}
+	}()
+}
+
+// addFileChooserInterceptor makes the browser intercept the file choosers of
+// the page, instead of opening them, until the interceptor is removed. The
+// file choosers are intercepted while there are interceptors.
+func (p *Page) addFileChooserInterceptor() error {
+	p.fileChooserMu.Lock()
+	defer p.fileChooserMu.Unlock()
+
+	p.fileChooserInterceptors++
+	if p.fileChooserInterceptors > 1 {
+		return nil
+	}
+
+	return p.setInterceptFileChooserDialog(true)
+}
+
+// removeFileChooserInterceptor removes an interceptor added with
+// addFileChooserInterceptor.
+func (p *Page) removeFileChooserInterceptor() error {
+	p.fileChooserMu.Lock()
+	defer p.fileChooserMu.Unlock()
+
+	p.fileChooserInterceptors--
+	if p.fileChooserInterceptors > 0 {
+		return nil
+	}
+
+	return p.setInterceptFileChooserDialog(false)
+}
+
+// isInterceptingFileChooser reports whether the browser should
+// intercept the file choosers of the page.
+func (p *Page) isInterceptingFileChooser() bool {
+	p.fileChooserMu.Lock()
+	defer p.fileChooserMu.Unlock()
+
+	return p.fileChooserInterceptors > 0
+}
+
+func (p *Page) setInterceptFileChooserDialog(enabled bool) error {
+	for _, fs := range p.getFrameSessions() {
+		action := cdppage.SetInterceptFileChooserDialog(enabled)
+		if err := action.Do(cdp.WithExecutor(p.ctx, fs.session)); err != nil {
+			return fmt.Errorf("setting file chooser interception: %w", err)
+		}
+	}
+
+	return nil
+}
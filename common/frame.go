@@ -5,12 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/grafana/xk6-browser/api"
 	"github.com/grafana/xk6-browser/k6ext"
 	"github.com/grafana/xk6-browser/log"
 
+	k6common "go.k6.io/k6/js/common"
 	k6modules "go.k6.io/k6/js/modules"
 	k6metrics "go.k6.io/k6/metrics"
 
@@ -60,6 +62,11 @@ type Frame struct {
 
 	documentHandle *ElementHandle
 
+	// handlesMu protects the handles that are disposed with the frame, by
+	// the IDs of the execution contexts that they belong to.
+	handlesMu sync.Mutex
+	handles   map[runtime.ExecutionContextID][]jsHandle
+
 	executionContextMu sync.RWMutex
 	executionContexts  map[executionWorld]frameExecutionContext
 
@@ -73,6 +80,8 @@ type Frame struct {
 	currentDocument *DocumentInfo
 	pendingDocument *DocumentInfo
 
+	setContentCounter int64
+
 	log *log.Logger
 }
 
@@ -147,6 +156,18 @@ func (f *Frame) inflightRequestsLen() int {
 	return len(f.inflightRequests)
 }
 
+// inflightRequestIDs returns the IDs of the in-flight requests of the frame.
+func (f *Frame) inflightRequestIDs() []network.RequestID {
+	f.inflightRequestsMu.RLock()
+	defer f.inflightRequestsMu.RUnlock()
+
+	ids := make([]network.RequestID, 0, len(f.inflightRequests))
+	for id := range f.inflightRequests {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
 func (f *Frame) clearLifecycle() {
 	f.log.Debugf("Frame:clearLifecycle", "fid:%s furl:%q", f.ID(), f.URL())
 
@@ -181,7 +202,7 @@ func (f *Frame) clearLifecycle() {
 	f.inflightRequestsMu.Unlock()
 
 	f.stopNetworkIdleTimer()
-	if f.inflightRequestsLen() == 0 {
+	if f.isNetworkIdle() {
 		f.startNetworkIdleTimer()
 	}
 }
@@ -276,11 +297,12 @@ func (f *Frame) startNetworkIdleTimer() {
 
 	f.stopNetworkIdleTimer()
 
+	idleTime := f.networkIdleOptions().IdleTime
 	go func() {
 		select {
 		case <-f.ctx.Done():
 		case <-f.networkIdleCh:
-		case <-time.After(LifeCycleNetworkIdleTimeout):
+		case <-time.After(idleTime):
 			f.manager.frameLifecycleEvent(cdp.FrameID(f.ID()), LifecycleEventNetworkIdle)
 		}
 	}()
@@ -301,10 +323,11 @@ func (f *Frame) detach() {
 	if f.documentHandle != nil {
 		f.documentHandle.Dispose()
 	}
+	f.disposeHandles(nil)
 }
 
 func (f *Frame) defaultTimeout() time.Duration {
-	return time.Duration(f.manager.timeoutSettings.timeout()) * time.Second
+	return f.manager.timeoutSettings.timeout()
 }
 
 func (f *Frame) document() (*ElementHandle, error) {
@@ -354,7 +377,7 @@ func (f *Frame) emitMetric(m *k6metrics.Metric, t time.Time) {
 	}
 
 	state := f.vu.State()
-	tags := state.CloneTags()
+	tags := f.metricTags()
 	if state.Options.SystemTags.Has(k6metrics.TagURL) {
 		tags["url"] = f.URL()
 	}
@@ -430,6 +453,8 @@ func (f *Frame) navigated(name string, url string, loaderID string) {
 func (f *Frame) nullContext(execCtxID runtime.ExecutionContextID) {
 	f.log.Debugf("Frame:nullContext", "fid:%s furl:%q ectxid:%d ", f.ID(), f.URL(), execCtxID)
 
+	f.disposeHandles(&execCtxID)
+
 	f.executionContextMu.Lock()
 	defer f.executionContextMu.Unlock()
 
@@ -443,6 +468,36 @@ func (f *Frame) nullContext(execCtxID runtime.ExecutionContextID) {
 	}
 }
 
+// trackHandle adds a handle of the given execution context to the handles
+// that are disposed with the frame.
+func (f *Frame) trackHandle(execCtxID runtime.ExecutionContextID, h jsHandle) {
+	f.handlesMu.Lock()
+	defer f.handlesMu.Unlock()
+
+	if f.handles == nil {
+		f.handles = make(map[runtime.ExecutionContextID][]jsHandle)
+	}
+	f.handles[execCtxID] = append(f.handles[execCtxID], h)
+}
+
+// disposeHandles marks the tracked handles of the given execution context,
+// or of all the execution contexts if it's nil, as disposed. The remote
+// objects are already gone with their execution context.
+func (f *Frame) disposeHandles(execCtxID *runtime.ExecutionContextID) {
+	f.handlesMu.Lock()
+	defer f.handlesMu.Unlock()
+
+	for id, handles := range f.handles {
+		if execCtxID != nil && id != *execCtxID {
+			continue
+		}
+		for _, h := range handles {
+			h.markDisposed()
+		}
+		delete(f.handles, id)
+	}
+}
+
 func (f *Frame) onLifecycleEvent(event LifecycleEvent) {
 	f.log.Debugf("Frame:onLifecycleEvent", "fid:%s furl:%q event:%s", f.ID(), f.URL(), event)
 
@@ -537,14 +592,15 @@ func (f *Frame) waitForExecutionContext(world executionWorld) {
 	f.log.Debugf("Frame:waitForExecutionContext", "fid:%s furl:%q world:%s",
 		f.ID(), f.URL(), world)
 
-	t := time.NewTimer(50 * time.Millisecond)
+	t := time.NewTicker(50 * time.Millisecond)
 	defer t.Stop()
 	for {
+		// a detached frame never gets a new execution context.
+		if f.hasContext(world) || f.IsDetached() {
+			return
+		}
 		select {
 		case <-t.C:
-			if f.hasContext(world) {
-				return
-			}
 		case <-f.ctx.Done():
 			return
 		}
@@ -644,9 +700,23 @@ func (f *Frame) waitForSelector(selector string, opts *FrameWaitForSelectorOptio
 
 	handle, err := document.waitForSelector(f.ctx, selector, opts)
 	if err != nil {
-		return nil, err
+		waitsForElement := opts.State == DOMElementStateAttached || opts.State == DOMElementStateVisible
+		if !errors.Is(errorFromDOMErr(err), ErrTimedOut) || !waitsForElement {
+			return nil, err
+		}
+		// tell why the element could not be found, if it is in a closed shadow root.
+		host, herr := document.closedShadowRootHost(f.ctx, selector)
+		if herr != nil || host == "" {
+			return nil, err
+		}
+		return nil, fmt.Errorf("cannot pierce closed shadow root of %s: selector %q matches elements inside it: %w",
+			host, selector, errorFromDOMErr(err))
 	}
 	if handle == nil {
+		// there is no element to return when waiting for it to disappear.
+		if opts.State == DOMElementStateDetached || opts.State == DOMElementStateHidden {
+			return nil, nil
+		}
 		return nil, fmt.Errorf("wait for selector %q did not result in any nodes", selector)
 	}
 
@@ -670,6 +740,27 @@ func (f *Frame) waitForSelector(selector string, opts *FrameWaitForSelectorOptio
 	return handle, nil
 }
 
+// strictSelectors reports whether the selectors of the frame must resolve
+// to a single element by default, as set by the strictSelectors option of
+// its browser context. The strict option of an action overrides it, so it
+// is the default of the options before they're parsed.
+func (f *Frame) strictSelectors() bool {
+	if f.page == nil || f.page.browserCtx == nil || f.page.browserCtx.opts == nil {
+		return false
+	}
+	return f.page.browserCtx.opts.StrictSelectors
+}
+
+// traceAction records the start of an action in the trace of the browser
+// context of the frame, if it's being traced, and returns a function that
+// records the end of the action.
+func (f *Frame) traceAction(name, selector, url string) func() {
+	if f.page == nil || f.page.browserCtx == nil || f.page.browserCtx.Tracing == nil {
+		return func() {}
+	}
+	return f.page.browserCtx.Tracing.action(name, selector, url)
+}
+
 func (f *Frame) AddScriptTag(opts goja.Value) {
 	k6ext.Panic(f.ctx, "Frame.AddScriptTag() has not been implemented yet")
 	applySlowMo(f.ctx)
@@ -697,16 +788,19 @@ func (f *Frame) Click(selector string, opts goja.Value) {
 	f.log.Debugf("Frame:Click", "fid:%s furl:%q sel:%q", f.ID(), f.URL(), selector)
 
 	popts := NewFrameClickOptions(f.defaultTimeout())
+	popts.Strict = f.strictSelectors()
 	if err := popts.Parse(f.ctx, opts); err != nil {
 		k6ext.Panic(f.ctx, "%w", err)
 	}
 	if err := f.click(selector, popts); err != nil {
 		k6ext.Panic(f.ctx, "click %q: %w", selector, err)
 	}
-	applySlowMo(f.ctx)
 }
 
 func (f *Frame) click(selector string, opts *FrameClickOptions) error {
+	f.startNavigation()
+	defer f.traceAction("click", selector, f.URL())()
+
 	click := func(apiCtx context.Context, handle *ElementHandle, p *Position) (interface{}, error) {
 		return nil, handle.click(p, opts.ToMouseClickOptions())
 	}
@@ -714,7 +808,7 @@ func (f *Frame) click(selector string, opts *FrameClickOptions) error {
 		selector, DOMElementStateAttached, opts.Strict, click, &opts.ElementHandleBasePointerOptions,
 	)
 	if _, err := callApiWithTimeout(f.ctx, act, opts.Timeout); err != nil {
-		return errorFromDOMError(err.Error())
+		return errorFromDOMErr(err)
 	}
 
 	return nil
@@ -725,24 +819,27 @@ func (f *Frame) Check(selector string, opts goja.Value) {
 	f.log.Debugf("Frame:Check", "fid:%s furl:%q sel:%q", f.ID(), f.URL(), selector)
 
 	popts := NewFrameCheckOptions(f.defaultTimeout())
+	popts.Strict = f.strictSelectors()
 	if err := popts.Parse(f.ctx, opts); err != nil {
 		k6ext.Panic(f.ctx, "%w", err)
 	}
 	if err := f.check(selector, popts); err != nil {
 		k6ext.Panic(f.ctx, "check %q: %w", selector, err)
 	}
-	applySlowMo(f.ctx)
 }
 
 func (f *Frame) check(selector string, opts *FrameCheckOptions) error {
-	check := func(apiCtx context.Context, handle *ElementHandle, p *Position) (interface{}, error) {
-		return nil, handle.setChecked(apiCtx, true, p)
+	defer f.traceAction("check", selector, f.URL())()
+
+	check := func(apiCtx context.Context, handle *ElementHandle) (interface{}, error) {
+		return nil, handle.setChecked(apiCtx, true, &opts.ElementHandleBasePointerOptions)
 	}
-	act := f.newPointerAction(
-		selector, DOMElementStateAttached, opts.Strict, check, &opts.ElementHandleBasePointerOptions,
+	// the click waits for the actionability checks and navigations when needed.
+	act := f.newAction(
+		selector, DOMElementStateAttached, opts.Strict, check, []string{}, true, true, opts.Timeout,
 	)
 	if _, err := callApiWithTimeout(f.ctx, act, opts.Timeout); err != nil {
-		return errorFromDOMError(err.Error())
+		return errorFromDOMErr(err)
 	}
 
 	return nil
@@ -753,24 +850,27 @@ func (f *Frame) Uncheck(selector string, opts goja.Value) {
 	f.log.Debugf("Frame:Uncheck", "fid:%s furl:%q sel:%q", f.ID(), f.URL(), selector)
 
 	popts := NewFrameUncheckOptions(f.defaultTimeout())
+	popts.Strict = f.strictSelectors()
 	if err := popts.Parse(f.ctx, opts); err != nil {
 		k6ext.Panic(f.ctx, "%w", err)
 	}
 	if err := f.uncheck(selector, popts); err != nil {
 		k6ext.Panic(f.ctx, "uncheck %q: %w", selector, err)
 	}
-	applySlowMo(f.ctx)
 }
 
 func (f *Frame) uncheck(selector string, opts *FrameUncheckOptions) error {
-	uncheck := func(apiCtx context.Context, handle *ElementHandle, p *Position) (interface{}, error) {
-		return nil, handle.setChecked(apiCtx, false, p)
+	defer f.traceAction("uncheck", selector, f.URL())()
+
+	uncheck := func(apiCtx context.Context, handle *ElementHandle) (interface{}, error) {
+		return nil, handle.setChecked(apiCtx, false, &opts.ElementHandleBasePointerOptions)
 	}
-	act := f.newPointerAction(
-		selector, DOMElementStateAttached, opts.Strict, uncheck, &opts.ElementHandleBasePointerOptions,
+	// the click waits for the actionability checks and navigations when needed.
+	act := f.newAction(
+		selector, DOMElementStateAttached, opts.Strict, uncheck, []string{}, true, true, opts.Timeout,
 	)
 	if _, err := callApiWithTimeout(f.ctx, act, opts.Timeout); err != nil {
-		return errorFromDOMError(err.Error())
+		return errorFromDOMErr(err)
 	}
 
 	return nil
@@ -782,6 +882,7 @@ func (f *Frame) IsChecked(selector string, opts goja.Value) bool {
 	f.log.Debugf("Frame:IsChecked", "fid:%s furl:%q sel:%q", f.ID(), f.URL(), selector)
 
 	popts := NewFrameIsCheckedOptions(f.defaultTimeout())
+	popts.Strict = f.strictSelectors()
 	if err := popts.Parse(f.ctx, opts); err != nil {
 		k6ext.Panic(f.ctx, "%w", err)
 	}
@@ -806,7 +907,7 @@ func (f *Frame) isChecked(selector string, opts *FrameIsCheckedOptions) (bool, e
 	)
 	v, err := callApiWithTimeout(f.ctx, act, opts.Timeout)
 	if err != nil {
-		return false, errorFromDOMError(err.Error())
+		return false, errorFromDOMErr(err)
 	}
 
 	bv, ok := v.(bool)
@@ -841,18 +942,21 @@ func (f *Frame) Dblclick(selector string, opts goja.Value) {
 	f.log.Debugf("Frame:DblClick", "fid:%s furl:%q sel:%q", f.ID(), f.URL(), selector)
 
 	popts := NewFrameDblClickOptions(f.defaultTimeout())
+	popts.Strict = f.strictSelectors()
 	if err := popts.Parse(f.ctx, opts); err != nil {
 		k6ext.Panic(f.ctx, "%w", err)
 	}
 	if err := f.dblclick(selector, popts); err != nil {
 		k6ext.Panic(f.ctx, "dblclick %q: %w", selector, err)
 	}
-	applySlowMo(f.ctx)
 }
 
 // dblclick is like Dblclick but takes parsed options and neither throws
 // an error, or applies slow motion.
 func (f *Frame) dblclick(selector string, opts *FrameDblclickOptions) error {
+	f.startNavigation()
+	defer f.traceAction("dblclick", selector, f.URL())()
+
 	dblclick := func(apiCtx context.Context, eh *ElementHandle, p *Position) (interface{}, error) {
 		return nil, eh.dblClick(p, opts.ToMouseClickOptions())
 	}
@@ -860,17 +964,131 @@ func (f *Frame) dblclick(selector string, opts *FrameDblclickOptions) error {
 		selector, DOMElementStateAttached, opts.Strict, dblclick, &opts.ElementHandleBasePointerOptions,
 	)
 	if _, err := callApiWithTimeout(f.ctx, act, opts.Timeout); err != nil {
-		return errorFromDOMError(err.Error())
+		return errorFromDOMErr(err)
 	}
 
 	return nil
 }
 
+// DragAndDrop drags the first element that matches the source selector and
+// drops it onto the first element that matches the target selector.
+func (f *Frame) DragAndDrop(source string, target string, opts goja.Value) {
+	f.log.Debugf("Frame:DragAndDrop", "fid:%s furl:%q source:%q target:%q", f.ID(), f.URL(), source, target)
+
+	popts := NewFrameDragAndDropOptions(f.defaultTimeout())
+	popts.Strict = f.strictSelectors()
+	if err := popts.Parse(f.ctx, opts); err != nil {
+		k6ext.Panic(f.ctx, "parsing drag and drop options: %w", err)
+	}
+	if err := f.dragAndDrop(source, target, popts); err != nil {
+		k6ext.Panic(f.ctx, "dragging %q to %q: %w", source, target, err)
+	}
+}
+
+// dragAndDrop presses the left mouse button over the source element and
+// releases it over the target element.
+//
+// Synthesized mouse events don't start native HTML5 drags in headless
+// browsers. So, unless the native option is false, the drag the mouse
+// move starts is intercepted and replayed with drag events instead.
+func (f *Frame) dragAndDrop(source, target string, opts *FrameDragAndDropOptions) error {
+	defer f.traceAction("dragAndDrop", source, f.URL())()
+
+	mouse := f.page.Mouse
+
+	var sourceHandle *ElementHandle
+	grab := func(apiCtx context.Context, handle *ElementHandle, p *Position) (interface{}, error) {
+		if err := mouse.move(apiCtx, p.X, p.Y, NewMouseMoveOptions()); err != nil {
+			return nil, err
+		}
+		if opts.Native {
+			if err := listenDragStart(apiCtx, handle); err != nil {
+				return nil, fmt.Errorf("listening for drag start: %w", err)
+			}
+		}
+		sourceHandle = handle
+		return nil, mouse.down(p.X, p.Y, NewMouseDownUpOptions())
+	}
+	act := f.newPointerAction(
+		source, DOMElementStateAttached, opts.Strict, grab, opts.sourcePointerOptions(),
+	)
+	if _, err := callApiWithTimeout(f.ctx, act, opts.Timeout); err != nil {
+		return errorFromDOMErr(err)
+	}
+
+	moveOpts := NewMouseMoveOptions()
+	moveOpts.Steps = opts.Steps
+	drop := func(apiCtx context.Context, handle *ElementHandle, p *Position) (interface{}, error) {
+		if !opts.Native {
+			if err := mouse.move(apiCtx, p.X, p.Y, moveOpts); err != nil {
+				return nil, err
+			}
+			return nil, mouse.up(p.X, p.Y, NewMouseDownUpOptions())
+		}
+		started := func(ctx context.Context) (bool, error) { return dragStarted(ctx, sourceHandle) }
+		return nil, mouse.dragAndDrop(apiCtx, p.X, p.Y, moveOpts, started)
+	}
+	act = f.newPointerAction(
+		target, DOMElementStateAttached, opts.Strict, drop, opts.targetPointerOptions(),
+	)
+	if _, err := callApiWithTimeout(f.ctx, act, opts.Timeout); err != nil {
+		// don't leave the mouse button pressed if the target cannot be found.
+		if mouse.IsPressed("left") {
+			_ = mouse.up(mouse.x, mouse.y, NewMouseDownUpOptions())
+		}
+		return errorFromDOMErr(err)
+	}
+
+	return nil
+}
+
+// listenDragStart starts listening for the dragstart event that the next
+// mouse move over the element may cause. See dragStarted.
+func listenDragStart(apiCtx context.Context, h *ElementHandle) error {
+	fn := `
+		(element) => {
+			const w = element.ownerDocument.defaultView;
+			let dragEvent = null;
+			const onDragStart = (e) => { dragEvent = e; };
+			w.addEventListener('dragstart', onDragStart, { once: true, capture: true });
+			w.__xk6DragStarted = () => new Promise((resolve) => setTimeout(() => {
+				w.removeEventListener('dragstart', onDragStart, { capture: true });
+				delete w.__xk6DragStarted;
+				resolve(dragEvent !== null && !dragEvent.defaultPrevented);
+			}, 0));
+		}
+	`
+	_, err := h.eval(apiCtx, evalOptions{forceCallable: true, returnByValue: true}, fn)
+	return err
+}
+
+// dragStarted returns true if a drag operation has started since
+// listenDragStart was called with the element.
+func dragStarted(apiCtx context.Context, h *ElementHandle) (bool, error) {
+	fn := `
+		(element) => {
+			const w = element.ownerDocument.defaultView;
+			return w.__xk6DragStarted ? w.__xk6DragStarted() : false;
+		}
+	`
+	result, err := h.eval(apiCtx, evalOptions{forceCallable: true, returnByValue: true}, fn)
+	if err != nil {
+		return false, err
+	}
+	v, ok := result.(goja.Value)
+	if !ok {
+		return false, fmt.Errorf("unexpected type %T", result)
+	}
+
+	return v.ToBoolean(), nil
+}
+
 // DispatchEvent dispatches an event for the first element matching the selector.
 func (f *Frame) DispatchEvent(selector, typ string, eventInit, opts goja.Value) {
 	f.log.Debugf("Frame:DispatchEvent", "fid:%s furl:%q sel:%q typ:%q", f.ID(), f.URL(), selector, typ)
 
 	popts := NewFrameDispatchEventOptions(f.defaultTimeout())
+	popts.Strict = f.strictSelectors()
 	if err := popts.Parse(f.ctx, opts); err != nil {
 		k6ext.Panic(f.ctx, "Frame.dispatchEvent options: %w", err)
 	}
@@ -895,7 +1113,7 @@ func (f *Frame) dispatchEvent(selector, typ string, eventInit goja.Value, opts *
 		force, noWaitAfter, opts.Timeout,
 	)
 	if _, err := callApiWithTimeout(f.ctx, act, opts.Timeout); err != nil {
-		return errorFromDOMError(err.Error())
+		return errorFromDOMErr(err)
 	}
 
 	return nil
@@ -913,7 +1131,7 @@ func (f *Frame) Evaluate(pageFunc goja.Value, args ...goja.Value) interface{} {
 	}
 	result, err := f.evaluate(f.ctx, mainWorld, opts, pageFunc, args...)
 	if err != nil {
-		k6ext.Panic(f.ctx, "evaluating JS: %v", err)
+		k6ext.Panic(f.ctx, "evaluating JS: %w", err)
 	}
 
 	applySlowMo(f.ctx)
@@ -950,6 +1168,7 @@ func (f *Frame) Fill(selector, value string, opts goja.Value) {
 	f.log.Debugf("Frame:Fill", "fid:%s furl:%q sel:%q val:%q", f.ID(), f.URL(), selector, value)
 
 	popts := NewFrameFillOptions(f.defaultTimeout())
+	popts.Strict = f.strictSelectors()
 	if err := popts.Parse(f.ctx, opts); err != nil {
 		k6ext.Panic(f.ctx, "%w", err)
 	}
@@ -960,6 +1179,8 @@ func (f *Frame) Fill(selector, value string, opts goja.Value) {
 }
 
 func (f *Frame) fill(selector, value string, opts *FrameFillOptions) error {
+	defer f.traceAction("fill", selector, f.URL())()
+
 	fill := func(apiCtx context.Context, handle *ElementHandle) (interface{}, error) {
 		return nil, handle.fill(apiCtx, value)
 	}
@@ -969,7 +1190,7 @@ func (f *Frame) fill(selector, value string, opts *FrameFillOptions) error {
 		opts.Force, opts.NoWaitAfter, opts.Timeout,
 	)
 	if _, err := callApiWithTimeout(f.ctx, act, opts.Timeout); err != nil {
-		return errorFromDOMError(err.Error())
+		return errorFromDOMErr(err)
 	}
 
 	return nil
@@ -980,6 +1201,7 @@ func (f *Frame) Focus(selector string, opts goja.Value) {
 	f.log.Debugf("Frame:Focus", "fid:%s furl:%q sel:%q", f.ID(), f.URL(), selector)
 
 	popts := NewFrameBaseOptions(f.defaultTimeout())
+	popts.Strict = f.strictSelectors()
 	if err := popts.Parse(f.ctx, opts); err != nil {
 		k6ext.Panic(f.ctx, "%w", err)
 	}
@@ -998,12 +1220,14 @@ func (f *Frame) focus(selector string, opts *FrameBaseOptions) error {
 		[]string{}, false, true, opts.Timeout,
 	)
 	if _, err := callApiWithTimeout(f.ctx, act, opts.Timeout); err != nil {
-		return errorFromDOMError(err.Error())
+		return errorFromDOMErr(err)
 	}
 
 	return nil
 }
 
+// FrameElement returns the iframe element of a child frame.
+// It panics for the main frame and for detached frames.
 func (f *Frame) FrameElement() api.ElementHandle {
 	f.log.Debugf("Frame:FrameElement", "fid:%s furl:%q", f.ID(), f.URL())
 
@@ -1019,6 +1243,7 @@ func (f *Frame) GetAttribute(selector, name string, opts goja.Value) goja.Value
 	f.log.Debugf("Frame:GetAttribute", "fid:%s furl:%q sel:%q name:%s", f.ID(), f.URL(), selector, name)
 
 	popts := NewFrameBaseOptions(f.defaultTimeout())
+	popts.Strict = f.strictSelectors()
 	if err := popts.Parse(f.ctx, opts); err != nil {
 		k6ext.Panic(f.ctx, "parse: %w", err)
 	}
@@ -1042,7 +1267,7 @@ func (f *Frame) getAttribute(selector, name string, opts *FrameBaseOptions) (goj
 	)
 	v, err := callApiWithTimeout(f.ctx, act, opts.Timeout)
 	if err != nil {
-		return nil, errorFromDOMError(err.Error())
+		return nil, errorFromDOMErr(err)
 	}
 	gv, ok := v.(goja.Value)
 	if !ok {
@@ -1054,9 +1279,10 @@ func (f *Frame) getAttribute(selector, name string, opts *FrameBaseOptions) (goj
 
 // Goto will navigate the frame to the specified URL and return a HTTP response object.
 func (f *Frame) Goto(url string, opts goja.Value) api.Response {
-	resp := f.manager.NavigateFrame(f, url, opts)
-	applySlowMo(f.ctx)
-	return resp
+	f.startNavigation()
+	defer f.traceAction("goto", "", url)()
+
+	return f.manager.NavigateFrame(f, url, opts)
 }
 
 // Hover moves the pointer over the first element that matches the selector.
@@ -1064,25 +1290,26 @@ func (f *Frame) Hover(selector string, opts goja.Value) {
 	f.log.Debugf("Frame:Hover", "fid:%s furl:%q sel:%q", f.ID(), f.URL(), selector)
 
 	popts := NewFrameHoverOptions(f.defaultTimeout())
+	popts.Strict = f.strictSelectors()
 	if err := popts.Parse(f.ctx, opts); err != nil {
 		k6ext.Panic(f.ctx, "parse: %w", err)
 	}
 	if err := f.hover(selector, popts); err != nil {
 		k6ext.Panic(f.ctx, "hover %q: %w", selector, err)
 	}
-
-	applySlowMo(f.ctx)
 }
 
 func (f *Frame) hover(selector string, opts *FrameHoverOptions) error {
+	defer f.traceAction("hover", selector, f.URL())()
+
 	hover := func(apiCtx context.Context, handle *ElementHandle, p *Position) (interface{}, error) {
-		return nil, handle.hover(apiCtx, p)
+		return nil, handle.hover(apiCtx, p, opts.Modifiers)
 	}
 	act := f.newPointerAction(
 		selector, DOMElementStateAttached, opts.Strict, hover, &opts.ElementHandleBasePointerOptions,
 	)
 	if _, err := callApiWithTimeout(f.ctx, act, opts.Timeout); err != nil {
-		return errorFromDOMError(err.Error())
+		return errorFromDOMErr(err)
 	}
 
 	return nil
@@ -1094,6 +1321,7 @@ func (f *Frame) InnerHTML(selector string, opts goja.Value) string {
 	f.log.Debugf("Frame:InnerHTML", "fid:%s furl:%q sel:%q", f.ID(), f.URL(), selector)
 
 	popts := NewFrameInnerHTMLOptions(f.defaultTimeout())
+	popts.Strict = f.strictSelectors()
 	if err := popts.Parse(f.ctx, opts); err != nil {
 		k6ext.Panic(f.ctx, "parse: %w", err)
 	}
@@ -1117,7 +1345,7 @@ func (f *Frame) innerHTML(selector string, opts *FrameInnerHTMLOptions) (string,
 	)
 	v, err := callApiWithTimeout(f.ctx, act, opts.Timeout)
 	if err != nil {
-		return "", errorFromDOMError(err.Error())
+		return "", errorFromDOMErr(err)
 	}
 	if v == nil {
 		return "", nil
@@ -1136,6 +1364,7 @@ func (f *Frame) InnerText(selector string, opts goja.Value) string {
 	f.log.Debugf("Frame:InnerText", "fid:%s furl:%q sel:%q", f.ID(), f.URL(), selector)
 
 	popts := NewFrameInnerTextOptions(f.defaultTimeout())
+	popts.Strict = f.strictSelectors()
 	if err := popts.Parse(f.ctx, opts); err != nil {
 		k6ext.Panic(f.ctx, "parse: %w", err)
 	}
@@ -1159,7 +1388,7 @@ func (f *Frame) innerText(selector string, opts *FrameInnerTextOptions) (string,
 	)
 	v, err := callApiWithTimeout(f.ctx, act, opts.Timeout)
 	if err != nil {
-		return "", errorFromDOMError(err.Error())
+		return "", errorFromDOMErr(err)
 	}
 	if v == nil {
 		return "", nil
@@ -1178,6 +1407,7 @@ func (f *Frame) InputValue(selector string, opts goja.Value) string {
 	f.log.Debugf("Frame:InputValue", "fid:%s furl:%q sel:%q", f.ID(), f.URL(), selector)
 
 	popts := NewFrameInputValueOptions(f.defaultTimeout())
+	popts.Strict = f.strictSelectors()
 	if err := popts.Parse(f.ctx, opts); err != nil {
 		k6ext.Panic(f.ctx, "parse: %w", err)
 	}
@@ -1199,7 +1429,7 @@ func (f *Frame) inputValue(selector string, opts *FrameInputValueOptions) (strin
 	)
 	v, err := callApiWithTimeout(f.ctx, act, opts.Timeout)
 	if err != nil {
-		return "", errorFromDOMError(err.Error())
+		return "", errorFromDOMErr(err)
 	}
 	gv, ok := v.(goja.Value)
 	if !ok {
@@ -1231,6 +1461,7 @@ func (f *Frame) IsEditable(selector string, opts goja.Value) bool {
 	f.log.Debugf("Frame:IsEditable", "fid:%s furl:%q sel:%q", f.ID(), f.URL(), selector)
 
 	popts := NewFrameIsEditableOptions(f.defaultTimeout())
+	popts.Strict = f.strictSelectors()
 	if err := popts.Parse(f.ctx, opts); err != nil {
 		k6ext.Panic(f.ctx, "%w", err)
 	}
@@ -1255,7 +1486,7 @@ func (f *Frame) isEditable(selector string, opts *FrameIsEditableOptions) (bool,
 	)
 	v, err := callApiWithTimeout(f.ctx, act, opts.Timeout)
 	if err != nil {
-		return false, errorFromDOMError(err.Error())
+		return false, errorFromDOMErr(err)
 	}
 
 	bv, ok := v.(bool)
@@ -1272,6 +1503,7 @@ func (f *Frame) IsEnabled(selector string, opts goja.Value) bool {
 	f.log.Debugf("Frame:IsEnabled", "fid:%s furl:%q sel:%q", f.ID(), f.URL(), selector)
 
 	popts := NewFrameIsEnabledOptions(f.defaultTimeout())
+	popts.Strict = f.strictSelectors()
 	if err := popts.Parse(f.ctx, opts); err != nil {
 		k6ext.Panic(f.ctx, "%w", err)
 	}
@@ -1296,7 +1528,7 @@ func (f *Frame) isEnabled(selector string, opts *FrameIsEnabledOptions) (bool, e
 	)
 	v, err := callApiWithTimeout(f.ctx, act, opts.Timeout)
 	if err != nil {
-		return false, errorFromDOMError(err.Error())
+		return false, errorFromDOMErr(err)
 	}
 
 	bv, ok := v.(bool)
@@ -1313,6 +1545,7 @@ func (f *Frame) IsDisabled(selector string, opts goja.Value) bool {
 	f.log.Debugf("Frame:IsDisabled", "fid:%s furl:%q sel:%q", f.ID(), f.URL(), selector)
 
 	popts := NewFrameIsDisabledOptions(f.defaultTimeout())
+	popts.Strict = f.strictSelectors()
 	if err := popts.Parse(f.ctx, opts); err != nil {
 		k6ext.Panic(f.ctx, "%w", err)
 	}
@@ -1337,7 +1570,7 @@ func (f *Frame) isDisabled(selector string, opts *FrameIsDisabledOptions) (bool,
 	)
 	v, err := callApiWithTimeout(f.ctx, act, opts.Timeout)
 	if err != nil {
-		return false, errorFromDOMError(err.Error())
+		return false, errorFromDOMErr(err)
 	}
 
 	bv, ok := v.(bool)
@@ -1354,6 +1587,7 @@ func (f *Frame) IsHidden(selector string, opts goja.Value) bool {
 	f.log.Debugf("Frame:IsHidden", "fid:%s furl:%q sel:%q", f.ID(), f.URL(), selector)
 
 	popts := NewFrameIsHiddenOptions(f.defaultTimeout())
+	popts.Strict = f.strictSelectors()
 	if err := popts.Parse(f.ctx, opts); err != nil {
 		k6ext.Panic(f.ctx, "%w", err)
 	}
@@ -1378,7 +1612,7 @@ func (f *Frame) isHidden(selector string, opts *FrameIsHiddenOptions) (bool, err
 	)
 	v, err := callApiWithTimeout(f.ctx, act, opts.Timeout)
 	if err != nil {
-		return false, errorFromDOMError(err.Error())
+		return false, errorFromDOMErr(err)
 	}
 
 	bv, ok := v.(bool)
@@ -1395,6 +1629,7 @@ func (f *Frame) IsVisible(selector string, opts goja.Value) bool {
 	f.log.Debugf("Frame:IsVisible", "fid:%s furl:%q sel:%q", f.ID(), f.URL(), selector)
 
 	popts := NewFrameIsVisibleOptions(f.defaultTimeout())
+	popts.Strict = f.strictSelectors()
 	if err := popts.Parse(f.ctx, opts); err != nil {
 		k6ext.Panic(f.ctx, "%w", err)
 	}
@@ -1419,7 +1654,7 @@ func (f *Frame) isVisible(selector string, opts *FrameIsVisibleOptions) (bool, e
 	)
 	v, err := callApiWithTimeout(f.ctx, act, opts.Timeout)
 	if err != nil {
-		return false, errorFromDOMError(err.Error())
+		return false, errorFromDOMErr(err)
 	}
 
 	bv, ok := v.(bool)
@@ -1506,17 +1741,19 @@ func (f *Frame) Press(selector, key string, opts goja.Value) {
 	f.log.Debugf("Frame:Press", "fid:%s furl:%q sel:%q key:%q", f.ID(), f.URL(), selector, key)
 
 	popts := NewFramePressOptions(f.defaultTimeout())
+	popts.Strict = f.strictSelectors()
 	if err := popts.Parse(f.ctx, opts); err != nil {
 		k6ext.Panic(f.ctx, "parse: %w", err)
 	}
 	if err := f.press(selector, key, popts); err != nil {
 		k6ext.Panic(f.ctx, "press %q on %q: %w", key, selector, err)
 	}
-
-	applySlowMo(f.ctx)
 }
 
 func (f *Frame) press(selector, key string, opts *FramePressOptions) error {
+	f.startNavigation()
+	defer f.traceAction("press", selector, f.URL())()
+
 	press := func(apiCtx context.Context, handle *ElementHandle) (interface{}, error) {
 		return nil, handle.press(apiCtx, key, opts.ToKeyboardOptions())
 	}
@@ -1525,7 +1762,7 @@ func (f *Frame) press(selector, key string, opts *FramePressOptions) error {
 		[]string{}, false, opts.NoWaitAfter, opts.Timeout,
 	)
 	if _, err := callApiWithTimeout(f.ctx, act, opts.Timeout); err != nil {
-		return errorFromDOMError(err.Error())
+		return errorFromDOMErr(err)
 	}
 
 	return nil
@@ -1537,6 +1774,7 @@ func (f *Frame) SelectOption(selector string, values goja.Value, opts goja.Value
 	f.log.Debugf("Frame:SelectOption", "fid:%s furl:%q sel:%q", f.ID(), f.URL(), selector)
 
 	popts := NewFrameSelectOptionOptions(f.defaultTimeout())
+	popts.Strict = f.strictSelectors()
 	if err := popts.Parse(f.ctx, opts); err != nil {
 		k6ext.Panic(f.ctx, "parse: %w", err)
 	}
@@ -1551,6 +1789,8 @@ func (f *Frame) SelectOption(selector string, values goja.Value, opts goja.Value
 }
 
 func (f *Frame) selectOption(selector string, values goja.Value, opts *FrameSelectOptionOptions) ([]string, error) {
+	defer f.traceAction("selectOption", selector, f.URL())()
+
 	selectOption := func(apiCtx context.Context, handle *ElementHandle) (interface{}, error) {
 		return handle.selectOption(apiCtx, values)
 	}
@@ -1560,7 +1800,7 @@ func (f *Frame) selectOption(selector string, values goja.Value, opts *FrameSele
 	)
 	v, err := callApiWithTimeout(f.ctx, act, opts.Timeout)
 	if err != nil {
-		return nil, errorFromDOMError(err.Error())
+		return nil, errorFromDOMErr(err)
 	}
 	selectHandle, ok := v.(jsHandle)
 	if !ok {
@@ -1594,10 +1834,50 @@ func (f *Frame) SetContent(html string, opts goja.Value) {
 	if err := parsedOpts.Parse(f.ctx, opts); err != nil {
 		k6ext.Panic(f.ctx, "parsing setContent options: %w", err)
 	}
+	if err := f.setContent(html, parsedOpts); err != nil {
+		k6ext.Panic(f.ctx, "setting content: %w", err)
+	}
+
+	applySlowMo(f.ctx)
+}
+
+// setContent writes html to a new document, and waits for the document to
+// reach the waitUntil lifecycle event. It stops any navigation in flight.
+//
+// The new document doesn't fire navigation events, so the script logs a tag
+// to the console right after opening it, which clears the lifecycle of the
+// frame at the point where the old document is gone.
+func (f *Frame) setContent(html string, opts *FrameSetContentOptions) error {
+	f.startNavigation()
+	defer f.traceAction("setContent", "", f.URL())()
 
-	js := `(html) => {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+	if opts.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(f.ctx, opts.Timeout)
+	} else {
+		ctx, cancel = context.WithCancel(f.ctx)
+	}
+	defer cancel()
+
+	lifecycleCh := make(chan Event)
+	f.on(ctx, []string{EventFrameAddLifecycle}, lifecycleCh)
+
+	tag := fmt.Sprintf("--xk6-browser--set--content--%s--%d--", f.ID(), atomic.AddInt64(&f.setContentCounter, 1))
+	opened := make(chan struct{})
+	f.manager.addConsoleMessageTag(tag, func() {
+		f.manager.frameAbortedNavigation(cdp.FrameID(f.ID()), "navigation interrupted by setContent", "")
+		f.clearLifecycle()
+		close(opened)
+	})
+	defer f.manager.removeConsoleMessageTag(tag)
+
+	js := `(html, tag) => {
 		window.stop();
 		document.open();
+		console.debug(tag);
 		document.write(html);
 		document.close();
 	}`
@@ -1609,16 +1889,63 @@ func (f *Frame) SetContent(html string, opts goja.Value) {
 		returnByValue: true,
 	}
 	rt := f.vu.Runtime()
-	if _, err := f.evaluate(f.ctx, utilityWorld, eopts, rt.ToValue(js), rt.ToValue(html)); err != nil {
-		k6ext.Panic(f.ctx, "setting content: %w", err)
+	if _, err := f.evaluate(ctx, utilityWorld, eopts, rt.ToValue(js), rt.ToValue(html), rt.ToValue(tag)); err != nil {
+		return err
 	}
 
-	applySlowMo(f.ctx)
+	timedOut := func() error {
+		return fmt.Errorf("waiting for %q: %w after %s", opts.WaitUntil, ErrTimedOut, opts.Timeout)
+	}
+	select {
+	case <-opened:
+	case <-ctx.Done():
+		return timedOut()
+	}
+	for !f.hasSubtreeLifecycleEventFired(opts.WaitUntil) {
+		select {
+		case <-lifecycleCh:
+		case <-ctx.Done():
+			return timedOut()
+		}
+	}
+
+	return nil
 }
 
+// SetInputFiles sets the files of the first file input element that matches the selector.
 func (f *Frame) SetInputFiles(selector string, files goja.Value, opts goja.Value) {
-	k6ext.Panic(f.ctx, "Frame.setInputFiles(selector, files, opts) has not been implemented yet")
-	// TODO: needs slowMo
+	f.log.Debugf("Frame:SetInputFiles", "fid:%s furl:%q sel:%q", f.ID(), f.URL(), selector)
+
+	popts := NewFrameSetInputFilesOptions(f.defaultTimeout())
+	popts.Strict = f.strictSelectors()
+	if err := popts.Parse(f.ctx, opts); err != nil {
+		k6ext.Panic(f.ctx, "parsing setInputFiles options: %w", err)
+	}
+	var parsedFiles InputFiles
+	if err := parsedFiles.Parse(f.ctx, files); err != nil {
+		k6ext.Panic(f.ctx, "parsing setInputFiles files: %w", err)
+	}
+	if err := f.setInputFiles(selector, &parsedFiles, popts); err != nil {
+		k6ext.Panic(f.ctx, "setInputFiles %q: %w", selector, err)
+	}
+	applySlowMo(f.ctx)
+}
+
+func (f *Frame) setInputFiles(selector string, files *InputFiles, opts *FrameSetInputFilesOptions) error {
+	defer f.traceAction("setInputFiles", selector, f.URL())()
+
+	setInputFiles := func(apiCtx context.Context, handle *ElementHandle) (interface{}, error) {
+		return nil, handle.setInputFiles(apiCtx, files)
+	}
+	act := f.newAction(
+		selector, DOMElementStateAttached, opts.Strict,
+		setInputFiles, []string{}, opts.Force, opts.NoWaitAfter, opts.Timeout,
+	)
+	if _, err := callApiWithTimeout(f.ctx, act, opts.Timeout); err != nil {
+		return errorFromDOMErr(err)
+	}
+
+	return nil
 }
 
 // Tap the first element that matches the selector.
@@ -1626,25 +1953,31 @@ func (f *Frame) Tap(selector string, opts goja.Value) {
 	f.log.Debugf("Frame:Tap", "fid:%s furl:%q sel:%q", f.ID(), f.URL(), selector)
 
 	popts := NewFrameTapOptions(f.defaultTimeout())
+	popts.Strict = f.strictSelectors()
 	if err := popts.Parse(f.ctx, opts); err != nil {
 		k6ext.Panic(f.ctx, "parse: %w", err)
 	}
 	if err := f.tap(selector, popts); err != nil {
 		k6ext.Panic(f.ctx, "tap %q: %w", selector, err)
 	}
-
-	applySlowMo(f.ctx)
 }
 
 func (f *Frame) tap(selector string, opts *FrameTapOptions) error {
+	f.startNavigation()
+	defer f.traceAction("tap", selector, f.URL())()
+
+	// fail early instead of waiting for the element to be actionable.
+	if !f.page.Touchscreen.hasTouch {
+		return ErrTouchNotSupported
+	}
 	tap := func(apiCtx context.Context, handle *ElementHandle, p *Position) (interface{}, error) {
-		return nil, handle.tap(apiCtx, p)
+		return nil, handle.tap(apiCtx, p, opts.Modifiers)
 	}
 	act := f.newPointerAction(
 		selector, DOMElementStateAttached, opts.Strict, tap, &opts.ElementHandleBasePointerOptions,
 	)
 	if _, err := callApiWithTimeout(f.ctx, act, opts.Timeout); err != nil {
-		return errorFromDOMError(err.Error())
+		return errorFromDOMErr(err)
 	}
 
 	return nil
@@ -1656,6 +1989,7 @@ func (f *Frame) TextContent(selector string, opts goja.Value) string {
 	f.log.Debugf("Frame:TextContent", "fid:%s furl:%q sel:%q", f.ID(), f.URL(), selector)
 
 	popts := NewFrameTextContentOptions(f.defaultTimeout())
+	popts.Strict = f.strictSelectors()
 	if err := popts.Parse(f.ctx, opts); err != nil {
 		k6ext.Panic(f.ctx, "parse: %w", err)
 	}
@@ -1679,7 +2013,7 @@ func (f *Frame) textContent(selector string, opts *FrameTextContentOptions) (str
 	)
 	v, err := callApiWithTimeout(f.ctx, act, opts.Timeout)
 	if err != nil {
-		return "", errorFromDOMError(err.Error())
+		return "", errorFromDOMErr(err)
 	}
 	if v == nil {
 		return "", nil
@@ -1704,17 +2038,18 @@ func (f *Frame) Type(selector, text string, opts goja.Value) {
 	f.log.Debugf("Frame:Type", "fid:%s furl:%q sel:%q text:%q", f.ID(), f.URL(), selector, text)
 
 	popts := NewFrameTypeOptions(f.defaultTimeout())
+	popts.Strict = f.strictSelectors()
 	if err := popts.Parse(f.ctx, opts); err != nil {
 		k6ext.Panic(f.ctx, "parse: %w", err)
 	}
 	if err := f.typ(selector, text, popts); err != nil {
 		k6ext.Panic(f.ctx, "type %q in %q: %w", text, selector, err)
 	}
-
-	applySlowMo(f.ctx)
 }
 
 func (f *Frame) typ(selector, text string, opts *FrameTypeOptions) error {
+	defer f.traceAction("type", selector, f.URL())()
+
 	typeText := func(apiCtx context.Context, handle *ElementHandle) (interface{}, error) {
 		return nil, handle.typ(apiCtx, text, opts.ToKeyboardOptions())
 	}
@@ -1723,7 +2058,7 @@ func (f *Frame) typ(selector, text string, opts *FrameTypeOptions) error {
 		[]string{}, false, opts.NoWaitAfter, opts.Timeout,
 	)
 	if _, err := callApiWithTimeout(f.ctx, act, opts.Timeout); err != nil {
-		return errorFromDOMError(err.Error())
+		return errorFromDOMErr(err)
 	}
 
 	return nil
@@ -1768,9 +2103,10 @@ func (f *Frame) WaitForFunction(fn goja.Value, opts goja.Value, jsArgs ...goja.V
 		js = fmt.Sprintf("() => (%s)", js)
 	}
 
+	// the arguments are converted by convertArgument when they're passed to the page.
 	args := make([]interface{}, 0, len(jsArgs))
 	for _, a := range jsArgs {
-		args = append(args, a.Export())
+		args = append(args, a)
 	}
 
 	var polling interface{} = parsedOpts.Polling
@@ -1801,10 +2137,18 @@ func (f *Frame) WaitForLoadState(state string, opts goja.Value) {
 	waitUntil := LifecycleEventLoad
 	if state != "" {
 		if err = waitUntil.UnmarshalText([]byte(state)); err != nil {
-			k6ext.Panic(f.ctx, "waitForLoadState: %v", err)
+			k6ext.Panic(f.ctx, "waitForLoadState: %w", err)
 		}
 	}
 
+	if waitUntil == LifecycleEventNetworkIdle && parsedOpts.NetworkIdle != nil {
+		ctx, cancel := withTimeout(f.ctx, parsedOpts.Timeout)
+		defer cancel()
+		if err := f.waitForNetworkIdle(ctx, parsedOpts.NetworkIdle); err != nil {
+			k6ext.Panic(f.ctx, "waitForLoadState %q: %v after %s", state, err, parsedOpts.Timeout)
+		}
+		return
+	}
 	if f.hasLifecycleEventFired(waitUntil) {
 		return
 	}
@@ -1813,7 +2157,7 @@ func (f *Frame) WaitForLoadState(state string, opts goja.Value) {
 		return data.(LifecycleEvent) == waitUntil
 	}, parsedOpts.Timeout)
 	if err != nil {
-		k6ext.Panic(f.ctx, "waitForLoadState %q: %v", state, err)
+		k6ext.Panic(f.ctx, "waitForLoadState %q: %w", state, err)
 	}
 }
 
@@ -1822,15 +2166,84 @@ func (f *Frame) WaitForNavigation(opts goja.Value) api.Response {
 	return f.manager.WaitForFrameNavigation(f, opts)
 }
 
+// WaitForURL waits for the frame to navigate to a URL that matches the given
+// string, glob pattern, or regular expression. It returns right away if the
+// current URL already matches, after waiting for the waitUntil lifecycle event.
+func (f *Frame) WaitForURL(url goja.Value, opts goja.Value) {
+	f.log.Debugf("Frame:WaitForURL", "fid:%s furl:%q url:%v", f.ID(), f.URL(), url)
+	defer f.log.Debugf("Frame:WaitForURL:return", "fid:%s furl:%q url:%v", f.ID(), f.URL(), url)
+
+	parsedOpts := NewFrameWaitForURLOptions(f.defaultTimeout())
+	if err := parsedOpts.Parse(f.ctx, opts); err != nil {
+		k6ext.Panic(f.ctx, "parsing waitForURL options: %w", err)
+	}
+	matches, err := newURLMatcher(k6ext.Runtime(f.ctx), url)
+	if err != nil {
+		k6ext.Panic(f.ctx, "parsing waitForURL pattern: %w", err)
+	}
+	if err := f.waitForURL(matches, parsedOpts); err != nil {
+		k6ext.Panic(f.ctx, "waitForURL %v: %w", url, err)
+	}
+}
+
+// waitForURL is like WaitForURL but takes a parsed pattern and options,
+// and neither throws an error, or applies slow motion.
+func (f *Frame) waitForURL(matches urlMatcher, opts *FrameWaitForURLOptions) error {
+	timeoutCtx, timeoutCancel := withTimeout(f.ctx, opts.Timeout)
+	defer timeoutCancel()
+
+	// start listening before checking the current URL so that
+	// we don't miss a navigation in between. Navigations to URLs
+	// that don't match, like intermediate redirects, are skipped.
+	evCtx, evCancelFn := context.WithCancel(timeoutCtx)
+	defer evCancelFn()
+	ch := make(chan Event)
+	f.on(evCtx, []string{EventFrameNavigation}, ch)
+
+	matched := matches(f.URL())
+	for !matched {
+		select {
+		case <-timeoutCtx.Done():
+			if errors.Is(timeoutCtx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("%w after %s", ErrTimedOut, opts.Timeout)
+			}
+			return timeoutCtx.Err()
+		case ev := <-ch:
+			ne, ok := ev.data.(*NavigationEvent)
+			if !ok || ne.err != nil || !matches(ne.url) {
+				continue
+			}
+			if ne.newDocument == nil {
+				// same document navigations don't fire lifecycle events.
+				return nil
+			}
+			matched = true
+		}
+	}
+	if f.hasLifecycleEventFired(opts.WaitUntil) {
+		return nil
+	}
+	_, err := waitForEvent(timeoutCtx, f, []string{EventFrameAddLifecycle}, func(data interface{}) bool {
+		return data.(LifecycleEvent) == opts.WaitUntil
+	}, opts.Timeout)
+
+	return err
+}
+
 // WaitForSelector waits for the given selector to match the waiting criteria.
+// It returns null when waiting for the element to be hidden or detached.
 func (f *Frame) WaitForSelector(selector string, opts goja.Value) api.ElementHandle {
 	parsedOpts := NewFrameWaitForSelectorOptions(f.defaultTimeout())
+	parsedOpts.Strict = f.strictSelectors()
 	if err := parsedOpts.Parse(f.ctx, opts); err != nil {
 		k6ext.Panic(f.ctx, "parsing waitForSelector %q options: %w", selector, err)
 	}
 	handle, err := f.waitForSelectorRetry(selector, parsedOpts, maxRetry)
 	if err != nil {
-		k6ext.Panic(f.ctx, "waitForSelector %q: %w", selector, err)
+		k6ext.Panic(f.ctx, "waiting for selector %q to be %s: %w", selector, parsedOpts.State, errorFromDOMErr(err))
+	}
+	if handle == nil {
+		return nil
 	}
 	return handle
 }
@@ -1842,9 +2255,9 @@ func (f *Frame) WaitForTimeout(timeout int64) {
 	f.log.Debugf("Frame:WaitForTimeout", "fid:%s furl:%q timeout:%s", f.ID(), f.URL(), to)
 	defer f.log.Debugf("Frame:WaitForTimeout:return", "fid:%s furl:%q timeout:%s", f.ID(), f.URL(), to)
 
-	select {
-	case <-f.ctx.Done():
-	case <-time.After(to):
+	if err := sleep(f.ctx, to); err != nil {
+		// an interrupted wait throws without closing the browser.
+		k6common.Throw(f.vu.Runtime(), fmt.Errorf("waiting for timeout: %w", err))
 	}
 }
 
@@ -1868,6 +2281,10 @@ func (f *Frame) evaluate(
 ) (interface{}, error) {
 	f.log.Debugf("Frame:evaluate", "fid:%s furl:%q world:%s opts:%s", f.ID(), f.URL(), world, opts)
 
+	if f.IsDetached() {
+		return nil, ErrFrameDetached
+	}
+
 	f.executionContextMu.RLock()
 	defer f.executionContextMu.RUnlock()
 
@@ -27,13 +27,11 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
-	"time"
 
 	"github.com/grafana/xk6-browser/api"
 	"github.com/grafana/xk6-browser/k6ext"
 	"github.com/grafana/xk6-browser/log"
 
-	k6common "go.k6.io/k6/js/common"
 	k6modules "go.k6.io/k6/js/modules"
 
 	"github.com/chromedp/cdproto/cdp"
@@ -56,6 +54,8 @@ type FrameManager struct {
 
 	// Needed as the frames map will be accessed from multiple Go routines,
 	// the main VU/JS go routine and the Go routine listening for CDP messages.
+	// It also protects the in-flight requests, and the pending documents
+	// of the frames.
 	framesMu sync.RWMutex
 	frames   map[cdp.FrameID]*Frame
 
@@ -64,6 +64,11 @@ type FrameManager struct {
 	barriersMu sync.RWMutex
 	barriers   []*Barrier
 
+	// consoleMessageTags maps the tags that setContent logs to the
+	// page console to the functions that handle them.
+	consoleMessageTagsMu sync.Mutex
+	consoleMessageTags   map[string]func()
+
 	vu k6modules.VU
 
 	logger *log.Logger
@@ -82,16 +87,17 @@ func NewFrameManager(
 	l *log.Logger,
 ) *FrameManager {
 	m := &FrameManager{
-		ctx:              ctx,
-		session:          s,
-		page:             p,
-		timeoutSettings:  ts,
-		frames:           make(map[cdp.FrameID]*Frame),
-		inflightRequests: make(map[network.RequestID]bool),
-		barriers:         make([]*Barrier, 0),
-		vu:               k6ext.GetVU(ctx),
-		logger:           l,
-		id:               atomic.AddInt64(&frameManagerID, 1),
+		ctx:                ctx,
+		session:            s,
+		page:               p,
+		timeoutSettings:    ts,
+		frames:             make(map[cdp.FrameID]*Frame),
+		inflightRequests:   make(map[network.RequestID]bool),
+		barriers:           make([]*Barrier, 0),
+		consoleMessageTags: make(map[string]func()),
+		vu:                 k6ext.GetVU(ctx),
+		logger:             l,
+		id:                 atomic.AddInt64(&frameManagerID, 1),
 	}
 
 	m.logger.Debugf("FrameManager:New", "fmid:%d", m.ID())
@@ -99,6 +105,35 @@ func NewFrameManager(
 	return m
 }
 
+// addConsoleMessageTag registers fn to run when tag is logged to the page console.
+func (m *FrameManager) addConsoleMessageTag(tag string, fn func()) {
+	m.consoleMessageTagsMu.Lock()
+	defer m.consoleMessageTagsMu.Unlock()
+
+	m.consoleMessageTags[tag] = fn
+}
+
+func (m *FrameManager) removeConsoleMessageTag(tag string) {
+	m.consoleMessageTagsMu.Lock()
+	defer m.consoleMessageTagsMu.Unlock()
+
+	delete(m.consoleMessageTags, tag)
+}
+
+// interceptConsoleMessage runs the function registered for a console message
+// that is a tag, and reports whether it was one.
+func (m *FrameManager) interceptConsoleMessage(msg string) bool {
+	m.consoleMessageTagsMu.Lock()
+	fn, ok := m.consoleMessageTags[msg]
+	delete(m.consoleMessageTags, msg)
+	m.consoleMessageTagsMu.Unlock()
+
+	if ok {
+		fn()
+	}
+	return ok
+}
+
 func (m *FrameManager) addBarrier(b *Barrier) {
 	m.logger.Debugf("FrameManager:addBarrier", "fmid:%d", m.ID())
 
@@ -132,6 +167,20 @@ func (m *FrameManager) dispose() {
 	}
 }
 
+// abortNavigations fails the pending navigations of the frames with err.
+func (m *FrameManager) abortNavigations(err error) {
+	m.framesMu.RLock()
+	frames := make([]*Frame, 0, len(m.frames))
+	for _, f := range m.frames {
+		frames = append(frames, f)
+	}
+	m.framesMu.RUnlock()
+
+	for _, f := range frames {
+		m.frameAbortedNavigation(cdp.FrameID(f.ID()), err.Error(), "")
+	}
+}
+
 func (m *FrameManager) frameAbortedNavigation(frameID cdp.FrameID, errorText, documentID string) {
 	m.logger.Debugf("FrameManager:frameAbortedNavigation",
 		"fmid:%d fid:%v err:%s docid:%s",
@@ -329,10 +378,10 @@ func (m *FrameManager) frameNavigated(frameID cdp.FrameID, parentFrameID cdp.Fra
 	frame.clearLifecycle()
 	frame.emit(EventFrameNavigation, &NavigationEvent{url: url, name: name, newDocument: frame.currentDocument})
 
-	// TODO: when we add API support for storage we need to track origins
-	// if !initial {
-	// 	//f.page.frameNavigatedToNewDocument(f)
-	// }
+	// the storage of the origins is cleared when the page is reused.
+	if m.page != nil && m.page.browserCtx != nil {
+		m.page.browserCtx.addVisitedOrigin(url)
+	}
 
 	// Restore pending if any (see comments above about keepPending).
 	frame.pendingDocument = keepPending
@@ -447,8 +496,13 @@ func (m *FrameManager) removeFramesRecursively(frame *Frame) {
 func (m *FrameManager) requestFailed(req *Request, canceled bool) {
 	m.logger.Debugf("FrameManager:requestFailed", "fmid:%d rurl:%s", m.ID(), req.URL())
 
+	m.framesMu.Lock()
 	delete(m.inflightRequests, req.getID())
+	m.framesMu.Unlock()
 	defer m.page.emit(EventPageRequestFailed, req)
+	if m.page.hasEventHandlers(EventPageRequestFailed) {
+		m.page.callEventHandlers(EventPageRequestFailed, req)
+	}
 
 	frame := req.getFrame()
 	if frame == nil {
@@ -458,11 +512,14 @@ func (m *FrameManager) requestFailed(req *Request, canceled bool) {
 	frame.deleteRequest(req.getID())
 
 	switch rc := frame.inflightRequestsLen(); {
-	case rc == 0:
+	case frame.isNetworkIdle():
 		frame.startNetworkIdleTimer()
 	case rc <= 10:
-		for reqID := range frame.inflightRequests {
+		for _, reqID := range frame.inflightRequestIDs() {
 			req := frame.requestByID(reqID)
+			if req == nil {
+				continue
+			}
 
 			m.logger.Debugf("FrameManager:requestFailed:rc<=10",
 				"reqID:%s inflightURL:%s frameID:%s",
@@ -470,7 +527,10 @@ func (m *FrameManager) requestFailed(req *Request, canceled bool) {
 		}
 	}
 
-	if frame.pendingDocument == nil || frame.pendingDocument.request != req {
+	m.framesMu.RLock()
+	pendingDocument := frame.pendingDocument
+	m.framesMu.RUnlock()
+	if pendingDocument == nil || pendingDocument.request != req {
 		m.logger.Debugf("FrameManager:requestFailed:return", "fmid:%d pdoc:nil", m.ID())
 		return
 	}
@@ -479,14 +539,16 @@ func (m *FrameManager) requestFailed(req *Request, canceled bool) {
 		errorText += "; maybe frame was detached?"
 	}
 	m.frameAbortedNavigation(cdp.FrameID(frame.ID()), errorText,
-		frame.pendingDocument.documentID)
+		pendingDocument.documentID)
 }
 
 func (m *FrameManager) requestFinished(req *Request) {
 	m.logger.Debugf("FrameManager:requestFinished", "fmid:%d rurl:%s",
 		m.ID(), req.URL())
 
+	m.framesMu.Lock()
 	delete(m.inflightRequests, req.getID())
+	m.framesMu.Unlock()
 	defer m.page.emit(EventPageRequestFinished, req)
 
 	frame := req.getFrame()
@@ -496,7 +558,7 @@ func (m *FrameManager) requestFinished(req *Request) {
 		return
 	}
 	frame.deleteRequest(req.getID())
-	if frame.inflightRequestsLen() == 0 {
+	if frame.isNetworkIdle() {
 		frame.startNetworkIdleTimer()
 	}
 	/*
@@ -529,9 +591,13 @@ func (m *FrameManager) requestStarted(req *Request) {
 		return
 	}
 
-	frame.addRequest(req.getID())
-	if frame.inflightRequestsLen() == 1 {
-		frame.stopNetworkIdleTimer()
+	// long-lived requests, such as EventSource connections,
+	// don't keep the network of the frame busy.
+	if !isLongLivedRequest(req) {
+		frame.addRequest(req.getID())
+		if !frame.isNetworkIdle() {
+			frame.stopNetworkIdleTimer()
+		}
 	}
 	if req.documentID != "" {
 		frame.pendingDocument = &DocumentInfo{documentID: req.documentID, request: req}
@@ -572,6 +638,17 @@ func (m *FrameManager) setMainFrame(f *Frame) {
 
 // NavigateFrame will navigate specified frame to specified URL.
 func (m *FrameManager) NavigateFrame(frame *Frame, url string, opts goja.Value) api.Response {
+	resp, err := m.navigateFrame(frame, url, opts)
+	if err != nil {
+		k6ext.Panic(m.ctx, "%w", err)
+	}
+	return resp
+}
+
+// navigateFrame navigates the frame to the URL, and returns the response
+// of the navigation, which is nil for the navigations within the document.
+// It returns the navigation errors instead of throwing them.
+func (m *FrameManager) navigateFrame(frame *Frame, url string, opts goja.Value) (*Response, error) {
 	var (
 		fmid = m.ID()
 		fid  = frame.ID()
@@ -585,12 +662,12 @@ func (m *FrameManager) NavigateFrame(frame *Frame, url string, opts goja.Value)
 	rt := m.vu.Runtime()
 	netMgr := m.page.mainFrameSession.getNetworkManager()
 	defaultReferer := netMgr.extraHTTPHeaders["referer"]
-	parsedOpts := NewFrameGotoOptions(defaultReferer, time.Duration(m.timeoutSettings.navigationTimeout())*time.Second)
+	parsedOpts := NewFrameGotoOptions(defaultReferer, m.timeoutSettings.navigationTimeout())
 	if err := parsedOpts.Parse(m.ctx, opts); err != nil {
-		k6ext.Panic(m.ctx, "parsing frame navigation options to %q: %v", url, err)
+		return nil, fmt.Errorf("parsing frame navigation options to %q: %v", url, err)
 	}
 
-	timeoutCtx, timeoutCancelFn := context.WithTimeout(m.ctx, parsedOpts.Timeout)
+	timeoutCtx, timeoutCancelFn := withTimeout(m.ctx, parsedOpts.Timeout)
 	defer timeoutCancelFn()
 
 	chSameDoc, evCancelFn := createWaitForEventHandler(timeoutCtx, frame, []string{EventFrameNavigation}, func(data interface{}) bool {
@@ -614,9 +691,16 @@ func (m *FrameManager) NavigateFrame(frame *Frame, url string, opts goja.Value)
 		// main frame's session.
 		fs = frame.page.mainFrameSession
 	}
-	newDocumentID, err := fs.navigateFrame(frame, url, parsedOpts.Referer)
+	// the navigation request may be paused until a route handler resumes it.
+	var (
+		newDocumentID string
+		err           error
+	)
+	runRouting(m.ctx, func() {
+		newDocumentID, err = fs.navigateFrame(frame, url, parsedOpts.Referer)
+	})
 	if err != nil {
-		k6ext.Panic(m.ctx, "navigating to %q: %v", url, err)
+		return nil, fmt.Errorf("navigating to %q: %w", url, err)
 	}
 
 	var event *NavigationEvent
@@ -636,7 +720,7 @@ func (m *FrameManager) NavigateFrame(frame *Frame, url string, opts goja.Value)
 			return false
 		}, parsedOpts.Timeout)
 		if err != nil {
-			k6ext.Panic(m.ctx, "navigating to %q: %v", url, err)
+			return nil, fmt.Errorf("navigating to %q: %w", url, err)
 		}
 
 		event = data.(*NavigationEvent)
@@ -648,34 +732,58 @@ func (m *FrameManager) NavigateFrame(frame *Frame, url string, opts goja.Value)
 			// TODO: A more graceful way of avoiding Throw()?
 			!(netMgr.userReqInterceptionEnabled &&
 				strings.Contains(event.err.Error(), "ERR_BLOCKED_BY_CLIENT")) {
-			k6common.Throw(rt, event.err)
+			return nil, fmt.Errorf("navigating to %q: %w", url, &api.NavigationError{
+				URL:    url,
+				Reason: event.err.Error(),
+				Err:    event.err,
+			})
 		}
 	} else {
 		m.logger.Debugf("FrameManager:NavigateFrame",
 			"fmid:%d fid:%v furl:%s url:%s newDocID:0",
 			fmid, fid, furl, url)
 
-		select {
-		case <-timeoutCtx.Done():
-			if timeoutCtx.Err() == context.DeadlineExceeded {
-				k6ext.Panic(m.ctx, "navigating to %q: %s after %s", url, ErrTimedOut, parsedOpts.Timeout)
+		routes := getRouteQueue(m.ctx)
+	waitSameDoc:
+		for {
+			select {
+			case <-timeoutCtx.Done():
+				if timeoutCtx.Err() == context.DeadlineExceeded {
+					return nil, fmt.Errorf("navigating to %q: %w after %s", url, ErrTimedOut, parsedOpts.Timeout)
+				}
+				return nil, fmt.Errorf("navigating to %q: %w", url, timeoutCtx.Err())
+			case data := <-chSameDoc:
+				event = data.(*NavigationEvent)
+				break waitSameDoc
+			case <-routes.pending():
+				routes.run(rt)
 			}
-		case data := <-chSameDoc:
-			event = data.(*NavigationEvent)
 		}
 	}
 
-	if !frame.hasSubtreeLifecycleEventFired(parsedOpts.WaitUntil) {
+	if parsedOpts.NetworkIdle != nil {
+		if err := frame.waitForNetworkIdle(timeoutCtx, parsedOpts.NetworkIdle); err != nil {
+			return nil, fmt.Errorf("navigating to %q: %w after %s", url, err, parsedOpts.Timeout)
+		}
+	} else if !frame.hasSubtreeLifecycleEventFired(parsedOpts.WaitUntil) {
 		m.logger.Debugf("FrameManager:NavigateFrame",
 			"fmid:%d fid:%v furl:%s url:%s hasSubtreeLifecycleEventFired:false",
 			fmid, fid, furl, url)
 
-		select {
-		case <-timeoutCtx.Done():
-			if timeoutCtx.Err() == context.DeadlineExceeded {
-				k6ext.Panic(m.ctx, "navigating to %q: %s after %s", url, ErrTimedOut, parsedOpts.Timeout)
+		routes := getRouteQueue(m.ctx)
+	waitUntil:
+		for {
+			select {
+			case <-timeoutCtx.Done():
+				if timeoutCtx.Err() == context.DeadlineExceeded {
+					return nil, fmt.Errorf("navigating to %q: %w after %s", url, ErrTimedOut, parsedOpts.Timeout)
+				}
+				return nil, fmt.Errorf("navigating to %q: %w", url, timeoutCtx.Err())
+			case <-chWaitUntilCh:
+				break waitUntil
+			case <-routes.pending():
+				routes.run(rt)
 			}
-		case <-chWaitUntilCh:
 		}
 	}
 
@@ -686,7 +794,7 @@ func (m *FrameManager) NavigateFrame(frame *Frame, url string, opts goja.Value)
 			resp = req.response
 		}
 	}
-	return resp
+	return resp, nil
 }
 
 // Page returns the page that this frame manager belongs to.
@@ -706,7 +814,7 @@ func (m *FrameManager) WaitForFrameNavigation(frame *Frame, opts goja.Value) api
 		"fmid:%d fid:%s furl:%s",
 		m.ID(), frame.ID(), frame.URL())
 
-	parsedOpts := NewFrameWaitForNavigationOptions(time.Duration(m.timeoutSettings.timeout()) * time.Second)
+	parsedOpts := NewFrameWaitForNavigationOptions(m.timeoutSettings.navigationTimeout())
 	if err := parsedOpts.Parse(m.ctx, opts); err != nil {
 		k6ext.Panic(m.ctx, "cannot parse waitForNavigation options: %v", err)
 	}
@@ -717,18 +825,25 @@ func (m *FrameManager) WaitForFrameNavigation(frame *Frame, opts goja.Value) api
 		})
 	defer evCancelFn() // Remove event handler
 
-	var event *NavigationEvent
-	select {
-	case <-m.ctx.Done():
-		// ignore: the extension is shutting down
-		m.logger.Warnf("FrameManager:WaitForFrameNavigation:<-ctx.Done",
-			"fmid:%d furl:%s err:%v",
-			m.ID(), frame.URL(), m.ctx.Err())
-		return nil
-	case <-time.After(parsedOpts.Timeout):
-		k6ext.Panic(m.ctx, "waitForFrameNavigation timed out after %s", parsedOpts.Timeout)
-	case data := <-ch:
-		event = data.(*NavigationEvent)
+	var (
+		event    *NavigationEvent
+		routes   = getRouteQueue(m.ctx)
+		timedOut = timeoutAfter(parsedOpts.Timeout)
+	)
+	for event == nil {
+		select {
+		case <-m.ctx.Done():
+			k6ext.Panic(m.ctx, "waiting for navigation: %w", m.ctx.Err())
+		case <-timedOut:
+			k6ext.Panic(m.ctx, "waitForFrameNavigation %w after %s", ErrTimedOut, parsedOpts.Timeout)
+		case data := <-ch:
+			event = data.(*NavigationEvent)
+		case <-routes.pending():
+			routes.run(k6ext.Runtime(m.ctx))
+		}
+	}
+	if event.err != nil {
+		k6ext.Panic(m.ctx, "waiting for navigation: %w", event.err)
 	}
 
 	if event.newDocument == nil {
@@ -738,6 +853,15 @@ func (m *FrameManager) WaitForFrameNavigation(frame *Frame, opts goja.Value) api
 		return nil
 	}
 
+	if parsedOpts.NetworkIdle != nil {
+		ctx, cancel := withTimeout(m.ctx, parsedOpts.Timeout)
+		defer cancel()
+		if err := frame.waitForNetworkIdle(ctx, parsedOpts.NetworkIdle); err != nil {
+			k6ext.Panic(m.ctx, "waiting for navigation: %v after %s", err, parsedOpts.Timeout)
+		}
+		return event.newDocument.request.response
+	}
+
 	if frame.hasSubtreeLifecycleEventFired(parsedOpts.WaitUntil) {
 		m.logger.Debugf("FrameManager:WaitForFrameNavigation",
 			"fmid:%d furl:%s hasSubtreeLifecycleEventFired:true",
@@ -747,7 +871,7 @@ func (m *FrameManager) WaitForFrameNavigation(frame *Frame, opts goja.Value) api
 			return data.(LifecycleEvent) == parsedOpts.WaitUntil
 		}, parsedOpts.Timeout)
 		if err != nil {
-			k6ext.Panic(m.ctx, "waitForFrameNavigation cannot wait for event (EventFrameAddLifecycle): %v", err)
+			k6ext.Panic(m.ctx, "waitForFrameNavigation cannot wait for event (EventFrameAddLifecycle): %w", err)
 		}
 	}
 
@@ -51,6 +51,16 @@ type FrameDblclickOptions struct {
 	Strict bool `json:"strict"`
 }
 
+type FrameDragAndDropOptions struct {
+	ElementHandleBaseOptions
+	SourcePosition *Position `json:"sourcePosition"`
+	TargetPosition *Position `json:"targetPosition"`
+	Native         bool      `json:"native"`
+	Steps          int64     `json:"steps"`
+	Strict         bool      `json:"strict"`
+	Trial          bool      `json:"trial"`
+}
+
 type FrameFillOptions struct {
 	ElementHandleBaseOptions
 	Strict bool `json:"strict"`
@@ -60,6 +70,9 @@ type FrameGotoOptions struct {
 	Referer   string         `json:"referer"`
 	Timeout   time.Duration  `json:"timeout"`
 	WaitUntil LifecycleEvent `json:"waitUntil"`
+	// NetworkIdle is set when waitUntil overrides the network
+	// idle options of the browser context.
+	NetworkIdle *NetworkIdleOptions `json:"networkIdle"`
 }
 
 type FrameHoverOptions struct {
@@ -118,6 +131,11 @@ type FrameSetContentOptions struct {
 	WaitUntil LifecycleEvent `json:"waitUntil"`
 }
 
+type FrameSetInputFilesOptions struct {
+	ElementHandleBaseOptions
+	Strict bool `json:"strict"`
+}
+
 type FrameTapOptions struct {
 	ElementHandleBasePointerOptions
 	Modifiers []string `json:"modifiers"`
@@ -145,13 +163,24 @@ type FrameWaitForFunctionOptions struct {
 }
 
 type FrameWaitForLoadStateOptions struct {
-	Timeout time.Duration `json:"timeout"`
+	// NetworkIdle overrides the network idle options of
+	// the browser context when waiting for networkidle.
+	NetworkIdle *NetworkIdleOptions `json:"networkIdle"`
+	Timeout     time.Duration       `json:"timeout"`
 }
 
 type FrameWaitForNavigationOptions struct {
 	URL       string         `json:"url"`
 	WaitUntil LifecycleEvent `json:"waitUntil"`
+	// NetworkIdle is set when waitUntil overrides the network
+	// idle options of the browser context.
+	NetworkIdle *NetworkIdleOptions `json:"networkIdle"`
+	Timeout     time.Duration       `json:"timeout"`
+}
+
+type FrameWaitForURLOptions struct {
 	Timeout   time.Duration  `json:"timeout"`
+	WaitUntil LifecycleEvent `json:"waitUntil"`
 }
 
 type FrameWaitForSelectorOptions struct {
@@ -255,6 +284,77 @@ func (o *FrameDblclickOptions) Parse(ctx context.Context, opts goja.Value) error
 	return nil
 }
 
+func NewFrameDragAndDropOptions(defaultTimeout time.Duration) *FrameDragAndDropOptions {
+	return &FrameDragAndDropOptions{
+		ElementHandleBaseOptions: *NewElementHandleBaseOptions(defaultTimeout),
+		SourcePosition:           nil,
+		TargetPosition:           nil,
+		Native:                   true,
+		Steps:                    1,
+		Strict:                   false,
+		Trial:                    false,
+	}
+}
+
+func (o *FrameDragAndDropOptions) Parse(ctx context.Context, opts goja.Value) error {
+	rt := k6ext.Runtime(ctx)
+	if err := o.ElementHandleBaseOptions.Parse(ctx, opts); err != nil {
+		return err
+	}
+	if opts != nil && !goja.IsUndefined(opts) && !goja.IsNull(opts) {
+		opts := opts.ToObject(rt)
+		for _, k := range opts.Keys() {
+			switch k {
+			case "sourcePosition", "targetPosition":
+				var p map[string]float64
+				if err := rt.ExportTo(opts.Get(k), &p); err != nil {
+					return fmt.Errorf("parsing %s: %w", k, err)
+				}
+				if k == "sourcePosition" {
+					o.SourcePosition = &Position{X: p["x"], Y: p["y"]}
+				} else {
+					o.TargetPosition = &Position{X: p["x"], Y: p["y"]}
+				}
+			case "native":
+				o.Native = opts.Get(k).ToBoolean()
+			case "steps":
+				o.Steps = opts.Get(k).ToInteger()
+				if o.Steps < 1 {
+					return fmt.Errorf("steps must be at least 1, got %d", o.Steps)
+				}
+			case "strict":
+				o.Strict = opts.Get(k).ToBoolean()
+			case "trial":
+				o.Trial = opts.Get(k).ToBoolean()
+			}
+		}
+	}
+	return nil
+}
+
+// sourcePointerOptions returns the pointer action options for grabbing
+// the dragged element.
+func (o *FrameDragAndDropOptions) sourcePointerOptions() *ElementHandleBasePointerOptions {
+	popts := NewElementHandleBasePointerOptions(o.Timeout)
+	popts.Force = o.Force
+	// grabbing the element cannot cause a navigation.
+	popts.NoWaitAfter = true
+	popts.Position = o.SourcePosition
+	popts.Trial = o.Trial
+	return popts
+}
+
+// targetPointerOptions returns the pointer action options for dropping
+// the dragged element onto the target element.
+func (o *FrameDragAndDropOptions) targetPointerOptions() *ElementHandleBasePointerOptions {
+	popts := NewElementHandleBasePointerOptions(o.Timeout)
+	popts.Force = o.Force
+	popts.NoWaitAfter = o.NoWaitAfter
+	popts.Position = o.TargetPosition
+	popts.Trial = o.Trial
+	return popts
+}
+
 func NewFrameFillOptions(defaultTimeout time.Duration) *FrameFillOptions {
 	return &FrameFillOptions{
 		ElementHandleBaseOptions: *NewElementHandleBaseOptions(defaultTimeout),
@@ -298,8 +398,8 @@ func (o *FrameGotoOptions) Parse(ctx context.Context, opts goja.Value) error {
 			case "timeout":
 				o.Timeout = time.Duration(opts.Get(k).ToInteger()) * time.Millisecond
 			case "waitUntil":
-				lifeCycle := opts.Get(k).String()
-				if err := o.WaitUntil.UnmarshalText([]byte(lifeCycle)); err != nil {
+				var err error
+				if o.WaitUntil, o.NetworkIdle, err = parseWaitUntil(ctx, opts.Get(k)); err != nil {
 					return fmt.Errorf("parsing goto options: %w", err)
 				}
 			}
@@ -456,6 +556,23 @@ func NewFramePressOptions(defaultTimeout time.Duration) *FramePressOptions {
 	}
 }
 
+func (o *FramePressOptions) Parse(ctx context.Context, opts goja.Value) error {
+	rt := k6ext.Runtime(ctx)
+	if err := o.ElementHandlePressOptions.Parse(ctx, opts); err != nil {
+		return err
+	}
+	if opts != nil && !goja.IsUndefined(opts) && !goja.IsNull(opts) {
+		opts := opts.ToObject(rt)
+		for _, k := range opts.Keys() {
+			switch k {
+			case "strict":
+				o.Strict = opts.Get(k).ToBoolean()
+			}
+		}
+	}
+	return nil
+}
+
 func (o *FramePressOptions) ToKeyboardOptions() *KeyboardOptions {
 	o2 := NewKeyboardOptions()
 	o2.Delay = o.Delay
@@ -514,6 +631,30 @@ func (o *FrameSetContentOptions) Parse(ctx context.Context, opts goja.Value) err
 	return nil
 }
 
+func NewFrameSetInputFilesOptions(defaultTimeout time.Duration) *FrameSetInputFilesOptions {
+	return &FrameSetInputFilesOptions{
+		ElementHandleBaseOptions: *NewElementHandleBaseOptions(defaultTimeout),
+		Strict:                   false,
+	}
+}
+
+func (o *FrameSetInputFilesOptions) Parse(ctx context.Context, opts goja.Value) error {
+	rt := k6ext.Runtime(ctx)
+	if err := o.ElementHandleBaseOptions.Parse(ctx, opts); err != nil {
+		return err
+	}
+	if opts != nil && !goja.IsUndefined(opts) && !goja.IsNull(opts) {
+		opts := opts.ToObject(rt)
+		for _, k := range opts.Keys() {
+			switch k {
+			case "strict":
+				o.Strict = opts.Get(k).ToBoolean()
+			}
+		}
+	}
+	return nil
+}
+
 func NewFrameTapOptions(defaultTimeout time.Duration) *FrameTapOptions {
 	return &FrameTapOptions{
 		ElementHandleBasePointerOptions: *NewElementHandleBasePointerOptions(defaultTimeout),
@@ -565,6 +706,23 @@ func NewFrameTypeOptions(defaultTimeout time.Duration) *FrameTypeOptions {
 	}
 }
 
+func (o *FrameTypeOptions) Parse(ctx context.Context, opts goja.Value) error {
+	rt := k6ext.Runtime(ctx)
+	if err := o.ElementHandleTypeOptions.Parse(ctx, opts); err != nil {
+		return err
+	}
+	if opts != nil && !goja.IsUndefined(opts) && !goja.IsNull(opts) {
+		opts := opts.ToObject(rt)
+		for _, k := range opts.Keys() {
+			switch k {
+			case "strict":
+				o.Strict = opts.Get(k).ToBoolean()
+			}
+		}
+	}
+	return nil
+}
+
 func (o *FrameTypeOptions) ToKeyboardOptions() *KeyboardOptions {
 	o2 := NewKeyboardOptions()
 	o2.Delay = o.Delay
@@ -616,7 +774,10 @@ func (o *FrameWaitForFunctionOptions) Parse(ctx context.Context, opts goja.Value
 				o.Timeout = time.Duration(v.ToInteger()) * time.Millisecond
 			case "polling":
 				switch v.ExportType().Kind() { //nolint: exhaustive
-				case reflect.Int64:
+				case reflect.Int64, reflect.Float64:
+					if v.ToInteger() <= 0 {
+						return fmt.Errorf("polling interval must be a positive number of milliseconds, got %v", v)
+					}
 					o.Polling = PollingInterval
 					o.Interval = v.ToInteger()
 				case reflect.String:
@@ -648,6 +809,12 @@ func (o *FrameWaitForLoadStateOptions) Parse(ctx context.Context, opts goja.Valu
 		opts := opts.ToObject(rt)
 		for _, k := range opts.Keys() {
 			switch k {
+			case "networkIdle":
+				networkIdle := NewNetworkIdleOptions()
+				if err := networkIdle.Parse(ctx, opts.Get(k)); err != nil {
+					return fmt.Errorf("parsing networkIdle: %w", err)
+				}
+				o.NetworkIdle = networkIdle
 			case "timeout":
 				o.Timeout = time.Duration(opts.Get(k).ToInteger()) * time.Millisecond
 			}
@@ -672,12 +839,38 @@ func (o *FrameWaitForNavigationOptions) Parse(ctx context.Context, opts goja.Val
 			switch k {
 			case "url":
 				o.URL = opts.Get(k).String()
+			case "timeout":
+				o.Timeout = time.Duration(opts.Get(k).ToInteger()) * time.Millisecond
+			case "waitUntil":
+				var err error
+				if o.WaitUntil, o.NetworkIdle, err = parseWaitUntil(ctx, opts.Get(k)); err != nil {
+					return fmt.Errorf("parsing waitForNavigation options: %w", err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func NewFrameWaitForURLOptions(defaultTimeout time.Duration) *FrameWaitForURLOptions {
+	return &FrameWaitForURLOptions{
+		Timeout:   defaultTimeout,
+		WaitUntil: LifecycleEventLoad,
+	}
+}
+
+func (o *FrameWaitForURLOptions) Parse(ctx context.Context, opts goja.Value) error {
+	rt := k6ext.Runtime(ctx)
+	if opts != nil && !goja.IsUndefined(opts) && !goja.IsNull(opts) {
+		opts := opts.ToObject(rt)
+		for _, k := range opts.Keys() {
+			switch k {
 			case "timeout":
 				o.Timeout = time.Duration(opts.Get(k).ToInteger()) * time.Millisecond
 			case "waitUntil":
 				lifeCycle := opts.Get(k).String()
 				if err := o.WaitUntil.UnmarshalText([]byte(lifeCycle)); err != nil {
-					return fmt.Errorf("parsing waitForNavigation options: %w", err)
+					return fmt.Errorf("parsing waitForURL options: %w", err)
 				}
 			}
 		}
@@ -119,3 +119,89 @@ func TestFrameWaitForNavigationOptionsParse(t *testing.T) {
 				`load, domcontentloaded, networkidle`)
 	})
 }
+
+func TestFrameHoverOptionsParse(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+	opts := vu.ToGojaValue(map[string]interface{}{
+		"position":  map[string]float64{"x": 10, "y": 5.5},
+		"modifiers": []string{"Shift", "Alt"},
+		"timeout":   500,
+	})
+	hoverOpts := NewFrameHoverOptions(30 * time.Second)
+	err := hoverOpts.Parse(vu.Context(), opts)
+	require.NoError(t, err)
+
+	assert.Equal(t, &Position{X: 10, Y: 5.5}, hoverOpts.Position)
+	assert.Equal(t, []string{"Shift", "Alt"}, hoverOpts.Modifiers)
+	assert.Equal(t, 500*time.Millisecond, hoverOpts.Timeout)
+}
+
+func TestFrameDragAndDropOptionsParse(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+	opts := vu.ToGojaValue(map[string]interface{}{
+		"sourcePosition": map[string]float64{"x": 1, "y": 2},
+		"targetPosition": map[string]float64{"x": 3, "y": 4},
+		"native":         false,
+		"steps":          5,
+		"trial":          true,
+	})
+	dndOpts := NewFrameDragAndDropOptions(30 * time.Second)
+	require.True(t, dndOpts.Native, "native drag should be the default")
+	require.Equal(t, int64(1), dndOpts.Steps)
+	err := dndOpts.Parse(vu.Context(), opts)
+	require.NoError(t, err)
+
+	assert.False(t, dndOpts.Native)
+	assert.Equal(t, int64(5), dndOpts.Steps)
+	assert.Equal(t, &Position{X: 1, Y: 2}, dndOpts.sourcePointerOptions().Position)
+	assert.Equal(t, &Position{X: 3, Y: 4}, dndOpts.targetPointerOptions().Position)
+	assert.True(t, dndOpts.sourcePointerOptions().NoWaitAfter)
+	assert.True(t, dndOpts.targetPointerOptions().Trial)
+
+	err = dndOpts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{"steps": 0}))
+	assert.EqualError(t, err, "steps must be at least 1, got 0")
+}
+
+func TestFrameWaitForFunctionOptionsParse(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		polling      interface{}
+		wantPolling  PollingType
+		wantInterval int64
+		wantErr      string
+	}{
+		{name: "raf", polling: "raf", wantPolling: PollingRaf},
+		{name: "mutation", polling: "mutation", wantPolling: PollingMutation},
+		{name: "interval", polling: 100, wantPolling: PollingInterval, wantInterval: 100},
+		{name: "interval_float", polling: 100.5, wantPolling: PollingInterval, wantInterval: 100},
+		{name: "err/unknown", polling: "busy", wantErr: "wrong polling option value"},
+		{name: "err/negative", polling: -1, wantErr: "polling interval must be a positive number"},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			vu := k6test.NewVU(t)
+			opts := NewFrameWaitForFunctionOptions(30 * time.Second)
+			err := opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{
+				"polling": tc.polling,
+				"timeout": 500,
+			}))
+			if tc.wantErr != "" {
+				assert.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantPolling, opts.Polling)
+			assert.Equal(t, tc.wantInterval, opts.Interval)
+			assert.Equal(t, 500*time.Millisecond, opts.Timeout)
+		})
+	}
+}
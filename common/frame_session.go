@@ -43,7 +43,6 @@ import (
 	"github.com/chromedp/cdproto/emulation"
 	"github.com/chromedp/cdproto/inspector"
 	cdplog "github.com/chromedp/cdproto/log"
-	"github.com/chromedp/cdproto/network"
 	cdppage "github.com/chromedp/cdproto/page"
 	cdpruntime "github.com/chromedp/cdproto/runtime"
 	"github.com/chromedp/cdproto/security"
@@ -260,10 +259,18 @@ func (fs *FrameSession) initEvents() {
 					fs.onFrameStartedLoading(ev.FrameID)
 				case *cdppage.EventFrameStoppedLoading:
 					fs.onFrameStoppedLoading(ev.FrameID)
+				case *cdppage.EventFileChooserOpened:
+					fs.page.onFileChooserOpened(ev)
+				case *cdppage.EventJavascriptDialogOpening:
+					fs.page.onJavascriptDialogOpening(fs.session, ev)
 				case *cdppage.EventLifecycleEvent:
 					fs.onPageLifecycle(ev)
 				case *cdppage.EventNavigatedWithinDocument:
 					fs.onPageNavigatedWithinDocument(ev)
+				case *cdppage.EventScreencastFrame:
+					fs.onScreencastFrame(ev)
+				case *cdpruntime.EventBindingCalled:
+					fs.onBindingCalled(ev)
 				case *cdpruntime.EventConsoleAPICalled:
 					fs.onConsoleAPICalled(ev)
 				case *cdpruntime.EventExceptionThrown:
@@ -362,6 +369,16 @@ func (fs *FrameSession) initIsolatedWorld(name string) error {
 	return nil
 }
 
+// addScriptToEvaluateOnNewDocument adds a script that runs in
+// the main world of the new documents of the frame session.
+func (fs *FrameSession) addScriptToEvaluateOnNewDocument(source string) error {
+	action := cdppage.AddScriptToEvaluateOnNewDocument(source)
+	if _, err := action.Do(cdp.WithExecutor(fs.ctx, fs.session)); err != nil {
+		return fmt.Errorf("adding script to evaluate on new document: %w", err)
+	}
+	return nil
+}
+
 func (fs *FrameSession) initOptions() error {
 	fs.logger.Debugf("NewFrameSession:initOptions",
 		"sid:%v tid:%v", fs.session.ID(), fs.targetID)
@@ -369,11 +386,14 @@ func (fs *FrameSession) initOptions() error {
 	var (
 		opts       = fs.manager.page.browserCtx.opts
 		optActions = []Action{}
-		state      = fs.vu.State()
 	)
 
 	if fs.isMainFrame() {
-		optActions = append(optActions, emulation.SetFocusEmulationEnabled(true))
+		// Emulating focus keeps Chrome from throttling the timers and
+		// rendering of the page when it isn't in front.
+		if !opts.BackgroundThrottling {
+			optActions = append(optActions, emulation.SetFocusEmulationEnabled(true))
+		}
 		if err := fs.updateViewport(); err != nil {
 			fs.logger.Debugf("NewFrameSession:initOptions:updateViewport",
 				"sid:%v tid:%v, err:%v",
@@ -387,14 +407,19 @@ func (fs *FrameSession) initOptions() error {
 	if opts.IgnoreHTTPSErrors {
 		optActions = append(optActions, security.SetIgnoreCertificateErrors(true))
 	}
-	if opts.HasTouch {
+	if fs.page.deviceMetrics.HasTouch {
 		optActions = append(optActions, emulation.SetTouchEmulationEnabled(true))
 	}
+	if fs.page.isInterceptingFileChooser() {
+		optActions = append(optActions, cdppage.SetInterceptFileChooserDialog(true))
+	}
 	if !opts.JavaScriptEnabled {
 		optActions = append(optActions, emulation.SetScriptExecutionDisabled(true))
 	}
-	if opts.UserAgent != "" || opts.Locale != "" {
-		optActions = append(optActions, emulation.SetUserAgentOverride(opts.UserAgent).WithAcceptLanguage(opts.Locale))
+	if opts.hasUserAgentOverride() {
+		optActions = append(optActions, emulation.SetUserAgentOverride(opts.UserAgent).
+			WithAcceptLanguage(opts.Locale).
+			WithPlatform(opts.Platform))
 	}
 	if opts.Locale != "" {
 		if err := fs.emulateLocale(); err != nil {
@@ -410,29 +435,41 @@ func (fs *FrameSession) initOptions() error {
 		return err
 	}
 	fs.updateExtraHTTPHeaders(true)
+	fs.networkManager.SetBlockedResourceTypes(opts.BlockResourceTypes)
+	fs.networkManager.SetRequestMetrics(opts.RequestMetrics)
 
-	var reqIntercept bool
-	if state.Options.BlockedHostnames.Trie != nil ||
-		len(state.Options.BlacklistIPs) > 0 {
-		reqIntercept = true
-	}
-	if err := fs.updateRequestInterception(reqIntercept); err != nil {
+	if err := fs.updateRequestInterception(); err != nil {
 		return err
 	}
 
 	fs.updateOffline(true)
+	if err := fs.updateNetworkConditions(true); err != nil {
+		return err
+	}
 	fs.updateHTTPCredentials(true)
 	if err := fs.updateEmulateMedia(true); err != nil {
 		return err
 	}
 
-	// if (screencastOptions)
-	//   promises.push(this._startVideoRecording(screencastOptions));
+	if err := fs.initBindings(); err != nil {
+		return err
+	}
+
+	if fs.isMainFrame() {
+		if err := fs.initWebVitals(); err != nil {
+			return err
+		}
+		if err := fs.startVideoRecording(); err != nil {
+			return err
+		}
+	}
 
-	/*for (const source of this._crPage._browserContext._evaluateOnNewDocumentSources)
-	      promises.push(this._evaluateOnNewDocument(source, 'main'));
-	  for (const source of this._crPage._page._evaluateOnNewDocumentSources)
-	      promises.push(this._evaluateOnNewDocument(source, 'main'));*/
+	sources := append(fs.page.browserCtx.initScripts(), fs.page.initScripts()...)
+	for _, source := range sources {
+		if err := fs.addScriptToEvaluateOnNewDocument(source); err != nil {
+			return err
+		}
+	}
 
 	optActions = append(optActions, cdpruntime.RunIfWaitingForDebugger())
 
@@ -461,6 +498,8 @@ func (fs *FrameSession) initRendererEvents() {
 		cdproto.EventPageJavascriptDialogOpening,
 		cdproto.EventPageLifecycleEvent,
 		cdproto.EventPageNavigatedWithinDocument,
+		cdproto.EventPageScreencastFrame,
+		cdproto.EventRuntimeBindingCalled,
 		cdproto.EventRuntimeConsoleAPICalled,
 		cdproto.EventRuntimeExceptionThrown,
 		cdproto.EventRuntimeExecutionContextCreated,
@@ -498,14 +537,27 @@ func (fs *FrameSession) navigateFrame(frame *Frame, url, referrer string) (strin
 		fs.session.ID(), fs.targetID, url, referrer)
 
 	action := cdppage.Navigate(url).WithReferrer(referrer).WithFrameID(cdp.FrameID(frame.ID()))
-	_, documentID, errorText, err := action.Do(cdp.WithExecutor(fs.ctx, fs.session))
+	_, documentID, errorText, err := action.Do(cdp.WithExecutor(fs.ctx, newSlowMoSession(fs.session)))
 	if err != nil {
-		err = fmt.Errorf("%s at %q: %w", errorText, url, err)
+		err = &api.NavigationError{
+			URL:    url,
+			Reason: errorText,
+			Err:    fmt.Errorf("%s at %q: %w", errorText, url, err),
+		}
 	}
 	return documentID.String(), err
 }
 
 func (fs *FrameSession) onConsoleAPICalled(event *cdpruntime.EventConsoleAPICalled) {
+	if event.Type == cdpruntime.APITypeDebug && len(event.Args) == 1 &&
+		event.Args[0].Type == cdpruntime.TypeString {
+		var msg string
+		if err := json.Unmarshal(event.Args[0].Value, &msg); err == nil &&
+			fs.manager.interceptConsoleMessage(msg) {
+			return
+		}
+	}
+
 	l := fs.serializer.
 		WithTime(event.Timestamp.Time()).
 		WithField("source", "browser-console-api")
@@ -535,9 +587,18 @@ func (fs *FrameSession) onConsoleAPICalled(event *cdpruntime.EventConsoleAPICall
 	default:
 		l.Debug()
 	}
+
+	fs.contextIDToContextMu.Lock()
+	ec, ok := fs.contextIDToContext[event.ExecutionContextID]
+	fs.contextIDToContextMu.Unlock()
+	if !ok {
+		ec = NewExecutionContext(fs.ctx, fs.session, nil, event.ExecutionContextID, fs.logger)
+	}
+	fs.page.onConsoleAPICalled(ec, nil, event)
 }
 
 func (fs *FrameSession) onExceptionThrown(event *cdpruntime.EventExceptionThrown) {
+	fs.page.onExceptionThrown(event.ExceptionDetails)
 	fs.page.emit(EventPageError, event.ExceptionDetails)
 }
 
@@ -714,6 +775,9 @@ func (fs *FrameSession) onPageLifecycle(event *cdppage.EventLifecycleEvent) {
 		return
 	case "load":
 		fs.manager.frameLifecycleEvent(event.FrameID, LifecycleEventLoad)
+		if fs.isMainFrame() && frame.parentFrame == nil {
+			fs.page.emitNavigationTiming(frame)
+		}
 	case "DOMContentLoaded":
 		fs.manager.frameLifecycleEvent(event.FrameID, LifecycleEventDOMContentLoad)
 	}
@@ -726,7 +790,7 @@ func (fs *FrameSession) onPageLifecycle(event *cdppage.EventLifecycleEvent) {
 		"firstMeaningfulPaint": fs.k6Metrics.BrowserFirstMeaningfulPaint,
 	}
 
-	if m, ok := eventToMetric[event.Name]; ok {
+	if m, ok := eventToMetric[event.Name]; ok && m != nil {
 		frame.emitMetric(m, event.Timestamp.Time())
 	}
 }
@@ -847,12 +911,12 @@ func (fs *FrameSession) attachIFrameToTarget(ti *target.Info, sid target.Session
 
 // attachWorkerToTarget attaches a Worker target to a given session.
 func (fs *FrameSession) attachWorkerToTarget(ti *target.Info, sid target.SessionID) error {
-	w, err := NewWorker(fs.ctx, fs.page.browserCtx.getSession(sid), ti.TargetID, ti.URL)
+	w, err := NewWorker(fs.ctx, fs.page.browserCtx.getSession(sid), fs.page, ti.TargetID, ti.URL)
 	if err != nil {
 		return fmt.Errorf("attaching worker target ID %v to session ID %v: %w",
 			ti.TargetID, sid, err)
 	}
-	fs.page.workers[sid] = w
+	fs.page.addWorker(sid, w)
 
 	return nil
 }
@@ -907,17 +971,21 @@ func (fs *FrameSession) updateEmulateMedia(initial bool) error {
 	return nil
 }
 
+func (fs *FrameSession) updateTouchEmulation() error {
+	fs.logger.Debugf("NewFrameSession:updateTouchEmulation", "sid:%v tid:%v", fs.session.ID(), fs.targetID)
+
+	action := emulation.SetTouchEmulationEnabled(fs.page.deviceMetrics.HasTouch)
+	if err := action.Do(cdp.WithExecutor(fs.ctx, fs.session)); err != nil {
+		return fmt.Errorf("executing %T: %w", action, err)
+	}
+	return nil
+}
+
 func (fs *FrameSession) updateExtraHTTPHeaders(initial bool) {
 	fs.logger.Debugf("NewFrameSession:updateExtraHTTPHeaders", "sid:%v tid:%v", fs.session.ID(), fs.targetID)
 
-	// Merge extra headers from browser context and page, where page specific headers ake precedence.
-	mergedHeaders := make(network.Headers)
-	for k, v := range fs.page.browserCtx.opts.ExtraHTTPHeaders {
-		mergedHeaders[k] = v
-	}
-	for k, v := range fs.page.extraHTTPHeaders {
-		mergedHeaders[k] = v
-	}
+	// Merge extra headers from browser context and page, where page specific headers take precedence.
+	mergedHeaders := mergeExtraHTTPHeaders(fs.page.browserCtx.opts.ExtraHTTPHeaders, fs.page.getExtraHTTPHeaders())
 	if !initial || len(mergedHeaders) > 0 {
 		fs.networkManager.SetExtraHTTPHeaders(mergedHeaders)
 	}
@@ -927,14 +995,20 @@ func (fs *FrameSession) updateGeolocation(initial bool) error {
 	fs.logger.Debugf("NewFrameSession:updateGeolocation", "sid:%v tid:%v", fs.session.ID(), fs.targetID)
 
 	geolocation := fs.page.browserCtx.opts.Geolocation
-	if !initial || geolocation != nil {
+	switch {
+	case geolocation != nil:
 		action := emulation.SetGeolocationOverride().
 			WithLatitude(geolocation.Latitude).
 			WithLongitude(geolocation.Longitude).
-			WithAccuracy(geolocation.Accurracy)
+			WithAccuracy(geolocation.Accuracy)
 		if err := action.Do(cdp.WithExecutor(fs.ctx, fs.session)); err != nil {
 			return fmt.Errorf("overriding geolocation: %w", err)
 		}
+	case !initial:
+		action := emulation.ClearGeolocationOverride()
+		if err := action.Do(cdp.WithExecutor(fs.ctx, fs.session)); err != nil {
+			return fmt.Errorf("clearing geolocation override: %w", err)
+		}
 	}
 	return nil
 }
@@ -946,6 +1020,10 @@ func (fs *FrameSession) updateHTTPCredentials(initial bool) {
 	if !initial || credentials != nil {
 		fs.networkManager.Authenticate(credentials)
 	}
+	// the proxy of the browser context can't change.
+	if proxy := fs.page.browserCtx.opts.Proxy.credentials(); initial && proxy != nil {
+		fs.networkManager.AuthenticateProxy(proxy)
+	}
 }
 
 func (fs *FrameSession) updateOffline(initial bool) {
@@ -957,13 +1035,31 @@ func (fs *FrameSession) updateOffline(initial bool) {
 	}
 }
 
-func (fs *FrameSession) updateRequestInterception(enable bool) error {
+func (fs *FrameSession) updateNetworkConditions(initial bool) error {
+	fs.logger.Debugf("NewFrameSession:updateNetworkConditions", "sid:%v tid:%v", fs.session.ID(), fs.targetID)
+
+	conditions := fs.page.getNetworkConditions()
+	if !initial || conditions != nil {
+		return fs.networkManager.SetNetworkConditions(conditions)
+	}
+	return nil
+}
+
+// updateRequestInterception enables the interception of the requests if
+// the k6 options block hosts or IP addresses, or the page has routes.
+func (fs *FrameSession) updateRequestInterception() error {
+	state := fs.vu.State()
+	enable := state.Options.BlockedHostnames.Trie != nil ||
+		len(state.Options.BlacklistIPs) > 0 ||
+		len(fs.page.browserCtx.opts.BlockResourceTypes) > 0 ||
+		fs.page.hasRoutes()
+
 	fs.logger.Debugf("NewFrameSession:updateRequestInterception",
 		"sid:%v tid:%v on:%v",
 		fs.session.ID(),
 		fs.targetID, enable)
 
-	return fs.networkManager.setRequestInterception(enable || fs.page.hasRoutes())
+	return fs.networkManager.setRequestInterception(enable)
 }
 
 func (fs *FrameSession) updateViewport() error {
@@ -978,7 +1074,6 @@ func (fs *FrameSession) updateViewport() error {
 		panic(err)
 	}
 
-	opts := fs.page.browserCtx.opts
 	emulatedSize := fs.page.emulatedSize
 	if emulatedSize == nil {
 		return nil
@@ -986,16 +1081,9 @@ func (fs *FrameSession) updateViewport() error {
 	viewport := emulatedSize.Viewport
 	screen := emulatedSize.Screen
 
-	orientation := emulation.ScreenOrientation{
-		Angle: 0.0,
-		Type:  emulation.OrientationTypePortraitPrimary,
-	}
-	if viewport.Width > viewport.Height {
-		orientation.Angle = 90.0
-		orientation.Type = emulation.OrientationTypeLandscapePrimary
-	}
-	action := emulation.SetDeviceMetricsOverride(viewport.Width, viewport.Height, opts.DeviceScaleFactor, opts.IsMobile).
-		WithScreenOrientation(&orientation).
+	metrics := fs.page.deviceMetrics
+	action := emulation.SetDeviceMetricsOverride(viewport.Width, viewport.Height, metrics.DeviceScaleFactor, metrics.IsMobile).
+		WithScreenOrientation(metrics.ScreenOrientation.toCDP(viewport)).
 		WithScreenWidth(screen.Width).
 		WithScreenHeight(screen.Height)
 	if err := action.Do(cdp.WithExecutor(fs.ctx, fs.session)); err != nil {
@@ -2,6 +2,9 @@ package common
 
 import (
 	"context"
+	"fmt"
+	"net/url"
+	"sync"
 	"testing"
 	"time"
 
@@ -9,6 +12,8 @@ import (
 	"github.com/grafana/xk6-browser/log"
 
 	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -98,6 +103,55 @@ func TestFrameManagerFrameAbortedNavigationShouldEmitANonNilPendingDocument(t *t
 	require.Nil(t, frame.pendingDocument)
 }
 
+// The requests of a page are tracked by the goroutines that handle the
+// CDP events of its frame sessions, while the actions read the frames.
+func TestFrameManagerConcurrentRequests(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	log := log.NewNullLogger()
+
+	p := &Page{BaseEventEmitter: NewBaseEventEmitter(ctx)}
+	fm := NewFrameManager(ctx, nil, p, NewTimeoutSettings(nil), log)
+	main := NewFrame(ctx, fm, nil, cdp.FrameID("main"), log)
+	fm.frames[main.id] = main
+	fm.setMainFrame(main)
+
+	const n = 50
+	var wg sync.WaitGroup
+	for g := 0; g < 2; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				req := &Request{
+					requestID: network.RequestID(fmt.Sprintf("%d.%d", g, i)),
+					frame:     main,
+					url:       &url.URL{Scheme: "about", Opaque: "blank"},
+				}
+				fm.requestStarted(req)
+				_ = fm.frameRequestedNavigation(main.id, "about:blank", string(req.requestID))
+				fm.requestFinished(req)
+			}
+		}(g)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < n; i++ {
+			_ = fm.Frames()
+			_ = main.inflightRequestIDs()
+		}
+	}()
+	wg.Wait()
+
+	assert.Zero(t, main.inflightRequestsLen())
+	fm.framesMu.RLock()
+	defer fm.framesMu.RUnlock()
+	assert.Empty(t, fm.inflightRequests)
+}
+
 type executionContextTestStub struct {
 	ExecutionContext
 	evalFn func(
@@ -105,8 +159,33 @@ type executionContextTestStub struct {
 	) (res interface{}, err error)
 }
 
-func (e executionContextTestStub) eval(
+func (e *executionContextTestStub) eval(
 	apiCtx context.Context, opts evalOptions, js string, args ...interface{},
 ) (res interface{}, err error) {
 	return e.evalFn(apiCtx, opts, js, args...)
 }
+
+func TestFrameDisposeHandles(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+	log := log.NewNullLogger()
+	fm := NewFrameManager(vu.Context(), nil, nil, nil, log)
+	frame := NewFrame(vu.Context(), fm, nil, cdp.FrameID("42"), log)
+
+	h1, h2, h3 := &BaseJSHandle{}, &BaseJSHandle{}, &BaseJSHandle{}
+	frame.trackHandle(1, h1)
+	frame.trackHandle(1, h2)
+	frame.trackHandle(2, h3)
+
+	// the handles of a destroyed execution context are disposed.
+	frame.nullContext(1)
+	assert.True(t, h1.disposed)
+	assert.True(t, h2.disposed)
+	assert.False(t, h3.disposed)
+
+	// all the handles are disposed with the frame.
+	frame.detach()
+	assert.True(t, h3.disposed)
+	assert.Empty(t, frame.handles)
+}
@@ -0,0 +1,158 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grafana/xk6-browser/api"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+// extraInfoTimeout is how long the requests and responses wait for their
+// raw headers from the extra info events, before they fall back to the
+// headers of the request and response events.
+const extraInfoTimeout = 2 * time.Second
+
+// headersToArray converts CDP headers to an array of headers that are
+// sorted by name. The values of the headers that are sent more than once,
+// like Set-Cookie, are joined with new lines by the browser, so they are
+// split into a header per value.
+func headersToArray(headers network.Headers) []api.HTTPHeader {
+	names := make([]string, 0, len(headers))
+	for n := range headers {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	arr := make([]api.HTTPHeader, 0, len(headers))
+	for _, n := range names {
+		v, ok := headers[n].(string)
+		if !ok {
+			continue
+		}
+		for _, v := range strings.Split(v, "\n") {
+			arr = append(arr, api.HTTPHeader{Name: n, Value: v})
+		}
+	}
+	return arr
+}
+
+// parseHeadersText parses the raw text of HTTP/1 response headers, which
+// keeps the order of the headers. It returns nil if the text doesn't
+// have any headers.
+func parseHeadersText(text string) []api.HTTPHeader {
+	lines := strings.Split(strings.TrimSpace(text), "\r\n")
+	if len(lines) < 2 {
+		return nil
+	}
+
+	// the first line is the status line.
+	arr := make([]api.HTTPHeader, 0, len(lines)-1)
+	for _, line := range lines[1:] {
+		i := strings.Index(line, ":")
+		if i < 0 {
+			continue
+		}
+		arr = append(arr, api.HTTPHeader{Name: line[:i], Value: strings.TrimSpace(line[i+1:])})
+	}
+	return arr
+}
+
+// headersArrayToMap converts an array of headers to a map of the headers
+// with lowercase names. The values of the headers with the same name
+// are joined with commas, except for Set-Cookie which uses new lines.
+func headersArrayToMap(headers []api.HTTPHeader) map[string]string {
+	m := make(map[string]string, len(headers))
+	for _, h := range headers {
+		n := strings.ToLower(h.Name)
+		prev, ok := m[n]
+		switch {
+		case !ok:
+			m[n] = h.Value
+		case n == "set-cookie":
+			m[n] = prev + "\n" + h.Value
+		default:
+			m[n] = prev + ", " + h.Value
+		}
+	}
+	return m
+}
+
+// headerValues returns the values of the headers with the name,
+// which is case-insensitive.
+func headerValues(headers []api.HTTPHeader, name string) []string {
+	var values []string
+	for _, h := range headers {
+		if strings.EqualFold(h.Name, name) {
+			values = append(values, h.Value)
+		}
+	}
+	return values
+}
+
+// validateExtraHTTPHeaders returns an error if a name or a value of the
+// extra HTTP headers would break the header lines of the requests.
+func validateExtraHTTPHeaders(headers map[string]string) error {
+	for k, v := range headers {
+		if k == "" || strings.ContainsAny(k, "\r\n:") {
+			return fmt.Errorf("invalid header name %q: must not be empty or contain CR, LF or a colon", k)
+		}
+		if strings.ContainsAny(v, "\r\n") {
+			return fmt.Errorf("invalid value of header %q: must not contain CR or LF", k)
+		}
+	}
+	return nil
+}
+
+// mergeExtraHTTPHeaders merges the extra HTTP headers, where the headers of
+// the latter maps take precedence regardless of the case of their names.
+func mergeExtraHTTPHeaders(headers ...map[string]string) network.Headers {
+	merged := make(network.Headers)
+	for _, hs := range headers {
+		for k, v := range hs {
+			for mk := range merged {
+				if strings.EqualFold(mk, k) {
+					delete(merged, mk)
+				}
+			}
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// waitForExtraInfo waits until ready is closed, or the extra info timeout
+// passes. It reports whether ready was closed.
+func waitForExtraInfo(ctx context.Context, ready <-chan struct{}) bool {
+	select {
+	case <-ready:
+		return true
+	case <-ctx.Done():
+	case <-time.After(extraInfoTimeout):
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/grafana/xk6-browser/api"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeadersToArray(t *testing.T) {
+	t.Parallel()
+
+	arr := headersToArray(network.Headers{
+		"Set-Cookie":   "a=1\nb=2",
+		"Content-Type": "text/html",
+	})
+	assert.Equal(t, []api.HTTPHeader{
+		{Name: "Content-Type", Value: "text/html"},
+		{Name: "Set-Cookie", Value: "a=1"},
+		{Name: "Set-Cookie", Value: "b=2"},
+	}, arr)
+
+	assert.Equal(t, map[string]string{
+		"content-type": "text/html",
+		"set-cookie":   "a=1\nb=2",
+	}, headersArrayToMap(arr))
+	assert.Equal(t, []string{"a=1", "b=2"}, headerValues(arr, "set-cookie"))
+	assert.Nil(t, headerValues(arr, "cookie"))
+}
+
+func TestHeadersArrayToMap(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, map[string]string{
+		"accept": "text/html, application/json",
+	}, headersArrayToMap([]api.HTTPHeader{
+		{Name: "Accept", Value: "text/html"},
+		{Name: "accept", Value: "application/json"},
+	}))
+}
+
+func TestParseHeadersText(t *testing.T) {
+	t.Parallel()
+
+	text := "HTTP/1.1 200 OK\r\n" +
+		"Set-Cookie: b=2\r\n" +
+		"Date: Wed, 21 Oct 2015 07:28:00 GMT\r\n" +
+		"Set-Cookie: a=1\r\n" +
+		"\r\n"
+	assert.Equal(t, []api.HTTPHeader{
+		{Name: "Set-Cookie", Value: "b=2"},
+		{Name: "Date", Value: "Wed, 21 Oct 2015 07:28:00 GMT"},
+		{Name: "Set-Cookie", Value: "a=1"},
+	}, parseHeadersText(text))
+
+	assert.Nil(t, parseHeadersText(""))
+}
+
+func TestValidateExtraHTTPHeaders(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, validateExtraHTTPHeaders(map[string]string{"X-Tenant": "a b"}))
+	assert.NoError(t, validateExtraHTTPHeaders(nil))
+	assert.EqualError(t, validateExtraHTTPHeaders(map[string]string{"X-Tenant\r\nX-Evil": "1"}),
+		`invalid header name "X-Tenant\r\nX-Evil": must not be empty or contain CR, LF or a colon`)
+	assert.EqualError(t, validateExtraHTTPHeaders(map[string]string{"": "1"}),
+		`invalid header name "": must not be empty or contain CR, LF or a colon`)
+	assert.EqualError(t, validateExtraHTTPHeaders(map[string]string{"X-Tenant": "a\nX-Evil: 1"}),
+		`invalid value of header "X-Tenant": must not contain CR or LF`)
+}
+
+func TestMergeExtraHTTPHeaders(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, network.Headers{
+		"X-Context": "context",
+		"x-tenant":  "page",
+	}, mergeExtraHTTPHeaders(
+		map[string]string{"X-Context": "context", "X-Tenant": "context"},
+		map[string]string{"x-tenant": "page"},
+	))
+	assert.Equal(t, network.Headers{}, mergeExtraHTTPHeaders(nil, map[string]string{}))
+}
@@ -22,10 +22,15 @@ package common
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
 	"time"
 
 	cdpruntime "github.com/chromedp/cdproto/runtime"
@@ -61,7 +66,7 @@ func convertArgument(
 	}
 	switch a := arg.(type) {
 	case int64:
-		if a > math.MaxInt32 {
+		if a > math.MaxInt32 || a < math.MinInt32 {
 			return &cdpruntime.CallArgument{
 				UnserializableValue: cdpruntime.UnserializableValue(fmt.Sprintf("%dn", a)),
 			}, nil
@@ -95,6 +100,10 @@ func convertArgument(
 		}
 
 		return &cdpruntime.CallArgument{Value: b}, err
+	case *big.Int:
+		return &cdpruntime.CallArgument{
+			UnserializableValue: cdpruntime.UnserializableValue(a.String() + "n"),
+		}, nil
 	case *ElementHandle:
 		return convertBaseJSHandleTypes(ctx, execCtx, &a.BaseJSHandle)
 	case *BaseJSHandle:
@@ -105,12 +114,357 @@ func convertArgument(
 	}
 }
 
+// The keys of the placeholders of the values in the arguments of a
+// function that JSON can't represent.
+const (
+	nestedHandleKey = "__xk6BrowserHandle"
+	dateArgKey      = "__xk6BrowserDate"
+	bigIntArgKey    = "__xk6BrowserBigInt"
+	binaryArgKey    = "__xk6BrowserBinary"
+	mapArgKey       = "__xk6BrowserMap"
+	setArgKey       = "__xk6BrowserSet"
+)
+
+// specialArgs are the values in the arguments of a function that JSON
+// can't represent: the JS handles nested in arrays and objects, which are
+// passed as arguments of their own, the dates, the Maps and the Sets, the
+// ArrayBuffers and the typed arrays, and the BigInts nested in arrays and
+// objects. They are replaced with placeholders that the function that wrap
+// returns revives in the page.
+type specialArgs struct {
+	handles []*BaseJSHandle
+	// values reports whether there are placeholders of dates, Maps,
+	// Sets, binary data or BigInts.
+	values bool
+	// maxDepth is the maximum nesting depth of the arguments.
+	// Zero means no limit.
+	maxDepth int64
+	// maxBinarySize is the maximum total size in bytes of the binary data
+	// in the arguments. Zero means no limit.
+	maxBinarySize int64
+	binarySize    int64
+}
+
+// binaryArg is the binary data of an ArrayBuffer, a typed array or a
+// DataView, with the name of its type.
+type binaryArg struct {
+	typ  string
+	data []byte
+}
+
+// mapArg is the entries of a Map, as pairs of a key and a value.
+type mapArg [][2]interface{}
+
+// setArg is the values of a Set.
+type setArg []interface{}
+
+// extract returns a copy of the argument in which the dates, the Maps and
+// the Sets, the binary data, and the JS handles and the BigInts nested in
+// its arrays and objects at any depth, are replaced with placeholders. The
+// argument itself is returned if it has none of them. It returns an error
+// if the argument is nested too deep, or if the binary data exceeds the
+// maximum size.
+func (s *specialArgs) extract(arg interface{}) (interface{}, error) {
+	if gojaVal, ok := arg.(goja.Value); ok {
+		var err error
+		if arg, err = s.export(gojaVal, 0); err != nil {
+			return nil, err
+		}
+	}
+	switch arg.(type) {
+	case []interface{}, map[string]interface{}, time.Time, goja.ArrayBuffer, binaryArg, mapArg, setArg:
+		return s.replace(arg)
+	default:
+		return arg, nil
+	}
+}
+
+// export is like the Export method of goja values, but it keeps the binary
+// data of the typed arrays and the DataViews, and the entries of the Maps
+// and the Sets, nested in arrays and objects, which Export turns into empty
+// objects and arrays.
+func (s *specialArgs) export(v goja.Value, depth int64) (interface{}, error) {
+	obj, ok := v.(*goja.Object)
+	if !ok {
+		return v.Export(), nil
+	}
+	if s.maxDepth > 0 && depth > s.maxDepth {
+		return nil, fmt.Errorf("argument is nested deeper than the maximum depth of %d", s.maxDepth)
+	}
+	if b, ok := binaryView(obj); ok {
+		return b, nil
+	}
+	// export exports the values of an array, a Map or a Set.
+	export := func(values []goja.Value) ([]interface{}, error) {
+		a := make([]interface{}, len(values))
+		for i, e := range values {
+			if e == nil {
+				continue
+			}
+			x, err := s.export(e, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			a[i] = x
+		}
+		return a, nil
+	}
+	switch {
+	case obj.ClassName() == "Array":
+		values := make([]goja.Value, obj.Get("length").ToInteger())
+		for i := range values {
+			values[i] = obj.Get(strconv.Itoa(i))
+		}
+		return export(values)
+	case obj.ClassName() == "Map":
+		entries, err := iterate(obj, "entries")
+		if err != nil {
+			return nil, err
+		}
+		m := make(mapArg, len(entries))
+		for i, e := range entries {
+			pair, ok := e.(*goja.Object)
+			if !ok {
+				return nil, fmt.Errorf("unexpected Map entry %v", e)
+			}
+			kv, err := export([]goja.Value{pair.Get("0"), pair.Get("1")})
+			if err != nil {
+				return nil, err
+			}
+			m[i] = [2]interface{}{kv[0], kv[1]}
+		}
+		return m, nil
+	case obj.ClassName() == "Set":
+		values, err := iterate(obj, "values")
+		if err != nil {
+			return nil, err
+		}
+		a, err := export(values)
+		return setArg(a), err
+	case obj.ExportType() == reflect.TypeOf(map[string]interface{}{}):
+		m := make(map[string]interface{})
+		for _, k := range obj.Keys() {
+			x, err := s.export(obj.Get(k), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = x
+		}
+		return m, nil
+	default:
+		return obj.Export(), nil
+	}
+}
+
+// iterate returns the values of the iterator that the method of the
+// object returns, such as the entries method of a Map.
+func iterate(obj *goja.Object, method string) ([]goja.Value, error) {
+	fn, ok := goja.AssertFunction(obj.Get(method))
+	if !ok {
+		return nil, fmt.Errorf("%s is not a function", method)
+	}
+	it, err := fn(obj)
+	if err != nil {
+		return nil, fmt.Errorf("calling %s: %w", method, err)
+	}
+	itObj, ok := it.(*goja.Object)
+	if !ok {
+		return nil, fmt.Errorf("%s returned %v instead of an iterator", method, it)
+	}
+	next, ok := goja.AssertFunction(itObj.Get("next"))
+	if !ok {
+		return nil, fmt.Errorf("%s returned an iterator without a next function", method)
+	}
+	var values []goja.Value
+	for {
+		r, err := next(itObj)
+		if err != nil {
+			return nil, fmt.Errorf("iterating %s: %w", method, err)
+		}
+		res, ok := r.(*goja.Object)
+		if !ok {
+			return nil, fmt.Errorf("iterating %s: unexpected result %v", method, r)
+		}
+		if res.Get("done").ToBoolean() {
+			return values, nil
+		}
+		values = append(values, res.Get("value"))
+	}
+}
+
+// binaryView returns the binary data that the typed array or the DataView
+// views in its ArrayBuffer.
+func binaryView(obj *goja.Object) (binaryArg, bool) {
+	buf := obj.Get("buffer")
+	tag := obj.GetSymbol(goja.SymToStringTag)
+	if buf == nil || tag == nil {
+		return binaryArg{}, false
+	}
+	ab, ok := buf.Export().(goja.ArrayBuffer)
+	if !ok {
+		return binaryArg{}, false
+	}
+	data := ab.Bytes()
+	off, n := obj.Get("byteOffset").ToInteger(), obj.Get("byteLength").ToInteger()
+	if off < 0 || n < 0 || off+n > int64(len(data)) {
+		return binaryArg{}, false
+	}
+
+	return binaryArg{typ: tag.String(), data: data[off : off+n]}, true
+}
+
+func (s *specialArgs) replace(v interface{}) (interface{}, error) {
+	handle := func(h *BaseJSHandle) interface{} {
+		s.handles = append(s.handles, h)
+		return map[string]interface{}{nestedHandleKey: len(s.handles) - 1}
+	}
+	switch v := v.(type) {
+	case *ElementHandle:
+		return handle(&v.BaseJSHandle), nil
+	case *BaseJSHandle:
+		return handle(v), nil
+	case time.Time:
+		s.values = true
+		return map[string]interface{}{dateArgKey: float64(v.UnixNano()) / float64(time.Millisecond)}, nil
+	case *big.Int:
+		s.values = true
+		return map[string]interface{}{bigIntArgKey: v.String()}, nil
+	case goja.ArrayBuffer:
+		return s.binary(binaryArg{typ: "ArrayBuffer", data: v.Bytes()})
+	case binaryArg:
+		return s.binary(v)
+	case mapArg:
+		c := make([]interface{}, len(v))
+		for i, e := range v {
+			kv, err := s.replace([]interface{}{e[0], e[1]})
+			if err != nil {
+				return nil, err
+			}
+			c[i] = kv
+		}
+		s.values = true
+		return map[string]interface{}{mapArgKey: c}, nil
+	case setArg:
+		c, err := s.replace([]interface{}(v))
+		if err != nil {
+			return nil, err
+		}
+		s.values = true
+		return map[string]interface{}{setArgKey: c}, nil
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, e := range v {
+			r, err := s.replace(e)
+			if err != nil {
+				return nil, err
+			}
+			c[i] = r
+		}
+		return c, nil
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			r, err := s.replace(e)
+			if err != nil {
+				return nil, err
+			}
+			c[k] = r
+		}
+		return c, nil
+	default:
+		return v, nil
+	}
+}
+
+// binary returns the placeholder of the binary data, which is sent to
+// the page in base64.
+func (s *specialArgs) binary(b binaryArg) (interface{}, error) {
+	s.binarySize += int64(len(b.data))
+	if s.maxBinarySize > 0 && s.binarySize > s.maxBinarySize {
+		return nil, fmt.Errorf("%w: the arguments have %d bytes of binary data or more, over the "+
+			"maximum of %d bytes that the evaluateMaxBinarySize launch option sets",
+			ErrBinaryTooLarge, s.binarySize, s.maxBinarySize)
+	}
+	s.values = true
+
+	return map[string]interface{}{binaryArgKey: map[string]interface{}{
+		"type": b.typ,
+		"data": base64.StdEncoding.EncodeToString(b.data),
+	}}, nil
+}
+
+// wrap returns a function that calls the function fn with its first argc
+// arguments, after it replaces the placeholders in them with the dates, the
+// Maps and the Sets, the binary data, the BigInts, and the JS handles that
+// follow the arguments. It returns fn itself
+// if the arguments have no placeholders.
+func (s *specialArgs) wrap(fn string, argc int) string {
+	if len(s.handles) == 0 && !s.values {
+		return fn
+	}
+	return `function(...args) {
+	const handles = args.splice(` + strconv.Itoa(argc) + `);
+	const revive = v => {
+		if (Array.isArray(v)) {
+			return v.map(revive);
+		}
+		if (v === null || typeof v !== 'object') {
+			return v;
+		}
+		const keys = Object.keys(v);
+		if (keys.length === 1 && keys[0] === '` + nestedHandleKey + `') {
+			return handles[v['` + nestedHandleKey + `']];
+		}
+		if (keys.length === 1 && keys[0] === '` + dateArgKey + `') {
+			return new Date(v['` + dateArgKey + `']);
+		}
+		if (keys.length === 1 && keys[0] === '` + bigIntArgKey + `') {
+			return BigInt(v['` + bigIntArgKey + `']);
+		}
+		if (keys.length === 1 && keys[0] === '` + mapArgKey + `') {
+			return new Map(v['` + mapArgKey + `'].map(([k, x]) => [revive(k), revive(x)]));
+		}
+		if (keys.length === 1 && keys[0] === '` + setArgKey + `') {
+			return new Set(v['` + setArgKey + `'].map(revive));
+		}
+		if (keys.length === 1 && keys[0] === '` + binaryArgKey + `') {
+			const { type, data } = v['` + binaryArgKey + `'];
+			const s = atob(data);
+			const bytes = new Uint8Array(s.length);
+			for (let i = 0; i < s.length; i++) {
+				bytes[i] = s.charCodeAt(i);
+			}
+			if (type === 'ArrayBuffer') {
+				return bytes.buffer;
+			}
+			return new (globalThis[type] || Uint8Array)(bytes.buffer);
+		}
+		for (const k of keys) {
+			v[k] = revive(v[k]);
+		}
+		return v;
+	};
+	return (
+` + fn + `
+	).apply(this, args.map(revive));
+}`
+}
+
+// callApiWithTimeout runs fn in a goroutine and returns the result or the
+// error that fn sends. If the timeout expires first, it returns an error that
+// wraps ErrTimedOut, and if ctx is done first, such as when the iteration is
+// interrupted or the scenario ends, an error that wraps ErrInterrupted. So do
+// the errors of fn that wrap the errors of a context. A zero
+// timeout means no timeout. fn must send a single result or error, and it
+// should return as soon as apiCtx is done, since its late result is discarded.
 func callApiWithTimeout(ctx context.Context, fn func(context.Context, chan interface{}, chan error), timeout time.Duration) (interface{}, error) {
 	var result interface{}
 	var err error
 	var cancelFn context.CancelFunc
-	resultCh := make(chan interface{})
-	errCh := make(chan error)
+	// the channels are buffered so that fn doesn't block on sending
+	// its result after the timeout, and its goroutine can exit.
+	resultCh := make(chan interface{}, 1)
+	errCh := make(chan error, 1)
 
 	apiCtx := ctx
 	if timeout > 0 {
@@ -122,13 +476,25 @@ func callApiWithTimeout(ctx context.Context, fn func(context.Context, chan inter
 
 	select {
 	case <-apiCtx.Done():
-		err = apiCtx.Err()
-		if errors.Is(err, context.DeadlineExceeded) {
-			err = ErrTimedOut
+		// fn may have sent its result just before the deadline.
+		select {
+		case result = <-resultCh:
+		case err = <-errCh:
+		default:
+			err = apiCtx.Err()
 		}
 	case result = <-resultCh:
 	case err = <-errCh:
 	}
+	// fn may return the error of apiCtx as its own, which is a timeout
+	// only if the deadline of apiCtx is the timeout, and not that of ctx.
+	isCtxErr := errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled)
+	switch {
+	case isCtxErr && ctx.Err() != nil:
+		err = fmt.Errorf("%w: %v", ErrInterrupted, err)
+	case errors.Is(err, context.DeadlineExceeded) && errors.Is(apiCtx.Err(), context.DeadlineExceeded):
+		err = fmt.Errorf("%w: %v", ErrTimedOut, err)
+	}
 
 	return result, err
 }
@@ -142,6 +508,10 @@ func stringSliceContains(s []string, e string) bool {
 	return false
 }
 
+// createWaitForEventHandler returns a channel that receives the data of the
+// first of the events that the emitter emits for which predicateFn returns
+// true, or nil data if predicateFn is nil. The returned function stops
+// waiting and removes the event handler, and it must be called in any case.
 func createWaitForEventHandler(
 	ctx context.Context,
 	emitter EventEmitter, events []string,
@@ -160,12 +530,19 @@ func createWaitForEventHandler(
 				return
 			case ev := <-chEvHandler:
 				if stringSliceContains(events, ev.typ) {
+					var data interface{}
 					if predicateFn != nil {
-						if predicateFn(ev.data) {
-							ch <- ev.data
+						// keep waiting for an event that matches.
+						if !safePredicate(predicateFn, ev.data) {
+							continue
 						}
-					} else {
-						ch <- nil
+						data = ev.data
+					}
+					// the waiter may have given up meanwhile.
+					select {
+					case ch <- data:
+					case <-evCancelCtx.Done():
+						return
 					}
 					close(ch)
 
@@ -182,19 +559,92 @@ func createWaitForEventHandler(
 	return ch, evCancelFn
 }
 
+// safePredicate calls predicateFn with data, and reports a panic of
+// predicateFn as a mismatch, since it runs in a goroutine of its own
+// where a panic would crash the process.
+func safePredicate(predicateFn func(data interface{}) bool, data interface{}) (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+
+	return predicateFn(data)
+}
+
+// withTimeout is like context.WithTimeout, but a zero timeout means
+// no timeout.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// timeoutAfter is like time.After, but a zero timeout means no timeout,
+// in which case the returned channel blocks forever.
+func timeoutAfter(timeout time.Duration) <-chan time.Time {
+	if timeout > 0 {
+		return time.After(timeout)
+	}
+	return nil
+}
+
+// waitForEvent waits for the first of the events that the emitter emits
+// with data for which predicateFn returns true, and returns its data.
+// It returns an error that wraps ErrTimedOut if the timeout expires, and
+// an error that wraps the error of ctx if ctx is done before that.
 func waitForEvent(ctx context.Context, emitter EventEmitter, events []string, predicateFn func(data interface{}) bool, timeout time.Duration) (interface{}, error) {
 	ch, evCancelFn := createWaitForEventHandler(ctx, emitter, events, predicateFn)
 	defer evCancelFn() // Remove event handler
 
+	// the event may depend on paused requests.
+	routes := getRouteQueue(ctx)
+	timedOut := timeoutAfter(timeout)
+	for {
+		select {
+		case <-ctx.Done():
+			err := ctx.Err()
+			if errors.Is(err, context.DeadlineExceeded) {
+				return nil, fmt.Errorf("%w after %s", ErrTimedOut, timeout)
+			}
+			return nil, fmt.Errorf("waiting for %s: %w", strings.Join(events, ", "), err)
+		case <-timedOut:
+			return nil, fmt.Errorf("%w after %s", ErrTimedOut, timeout)
+		case evData := <-ch:
+			return evData, nil
+		case <-routes.pending():
+			routes.run(k6ext.Runtime(ctx))
+		}
+	}
+}
+
+// sleep waits for the duration d. It returns an error that wraps
+// ErrInterrupted as soon as ctx, or the current iteration of the VU
+// in ctx, is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	var iterDone <-chan struct{}
+	if vu := k6ext.GetVU(ctx); vu != nil && vu.Context() != nil {
+		iterDone = vu.Context().Done()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+
 	select {
 	case <-ctx.Done():
-	case <-time.After(timeout):
-		return nil, fmt.Errorf("%w after %s", ErrTimedOut, timeout)
-	case evData := <-ch:
-		return evData, nil
+		return fmt.Errorf("%w: %v", ErrInterrupted, ctx.Err())
+	case <-iterDone:
+		return fmt.Errorf("%w: iteration is done", ErrInterrupted)
+	case <-t.C:
+		return nil
 	}
+}
 
-	return nil, nil
+// panicOnError panics if err is not nil.
+func panicOnError(ctx context.Context, err error) {
+	if err != nil {
+		k6ext.Panic(ctx, "%w", err)
+	}
 }
 
 // panicOrSlowMo panics if err is not nil, otherwise applies slow motion.
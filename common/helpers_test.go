@@ -22,10 +22,16 @@ package common
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
+	"math/big"
+	goruntime "runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/chromedp/cdproto/cdp"
 	"github.com/chromedp/cdproto/runtime"
@@ -33,6 +39,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
 
+	"github.com/grafana/xk6-browser/k6ext/k6test"
 	"github.com/grafana/xk6-browser/log"
 )
 
@@ -71,6 +78,32 @@ func TestConvertArgument(t *testing.T) {
 		require.Empty(t, arg.ObjectID)
 	})
 
+	t.Run("int64 minint", func(t *testing.T) {
+		execCtx, ctx, rt := newExecCtx()
+
+		var value int64 = math.MinInt32 - 1
+		arg, _ := convertArgument(ctx, execCtx, rt.ToValue(value))
+
+		require.NotNil(t, arg)
+		require.Equal(t, fmt.Sprintf("%dn", value), string(arg.UnserializableValue))
+		require.Empty(t, arg.Value)
+		require.Empty(t, arg.ObjectID)
+	})
+
+	t.Run("big int", func(t *testing.T) {
+		execCtx, ctx, rt := newExecCtx()
+
+		digits := "-1" + strings.Repeat("0", 99)
+		value, ok := new(big.Int).SetString(digits, 10)
+		require.True(t, ok)
+		arg, err := convertArgument(ctx, execCtx, rt.ToValue(value))
+
+		require.NoError(t, err)
+		require.Equal(t, digits+"n", string(arg.UnserializableValue))
+		require.Empty(t, arg.Value)
+		require.Empty(t, arg.ObjectID)
+	})
+
 	t.Run("float64", func(t *testing.T) {
 		execCtx, ctx, rt := newExecCtx()
 
@@ -233,3 +266,492 @@ func TestConvertArgument(t *testing.T) {
 		require.Empty(t, arg.UnserializableValue)
 	})
 }
+
+func TestSleep(t *testing.T) {
+	t.Parallel()
+
+	t.Run("ok", func(t *testing.T) {
+		t.Parallel()
+
+		vu := k6test.NewVU(t)
+		require.NoError(t, sleep(vu.Context(), time.Millisecond))
+	})
+
+	t.Run("ctx_done", func(t *testing.T) {
+		t.Parallel()
+
+		vu := k6test.NewVU(t)
+		ctx, cancel := context.WithCancel(vu.Context())
+		time.AfterFunc(50*time.Millisecond, cancel)
+
+		start := time.Now()
+		err := sleep(ctx, time.Hour)
+		require.ErrorIs(t, err, ErrInterrupted)
+		require.Less(t, time.Since(start), 5*time.Second)
+	})
+
+	t.Run("iteration_done", func(t *testing.T) {
+		t.Parallel()
+
+		vu := k6test.NewVU(t)
+		ctx := vu.Context()
+		// the iteration ends while the page context is still alive.
+		iterCtx, cancel := context.WithCancel(context.Background())
+		vu.CtxField = iterCtx
+		time.AfterFunc(50*time.Millisecond, cancel)
+
+		start := time.Now()
+		err := sleep(ctx, time.Hour)
+		require.ErrorIs(t, err, ErrInterrupted)
+		require.Less(t, time.Since(start), 5*time.Second)
+	})
+}
+
+func TestWaitForEvent(t *testing.T) {
+	t.Parallel()
+
+	const event = "event"
+	isTwo := func(data interface{}) bool { return data == 2 }
+
+	t.Run("event", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		emitter := NewBaseEventEmitter(ctx)
+		time.AfterFunc(50*time.Millisecond, func() {
+			emitter.emit(event, 1)
+			emitter.emit(event, 2)
+		})
+
+		data, err := waitForEvent(ctx, &emitter, []string{event}, isTwo, time.Hour)
+		require.NoError(t, err)
+		require.Equal(t, 2, data)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		emitter := NewBaseEventEmitter(ctx)
+
+		data, err := waitForEvent(ctx, &emitter, []string{event}, isTwo, 50*time.Millisecond)
+		require.ErrorIs(t, err, ErrTimedOut)
+		require.Nil(t, data)
+	})
+
+	t.Run("ctx_done", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		emitter := NewBaseEventEmitter(ctx)
+		time.AfterFunc(50*time.Millisecond, cancel)
+
+		data, err := waitForEvent(ctx, &emitter, []string{event}, isTwo, time.Hour)
+		require.ErrorIs(t, err, context.Canceled)
+		require.NotErrorIs(t, err, ErrTimedOut)
+		require.Nil(t, data)
+	})
+
+	t.Run("predicate_panics", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		emitter := NewBaseEventEmitter(ctx)
+		time.AfterFunc(50*time.Millisecond, func() {
+			emitter.emit(event, "not an int")
+			emitter.emit(event, 2)
+		})
+
+		data, err := waitForEvent(ctx, &emitter, []string{event}, func(data interface{}) bool {
+			return data.(int) == 2
+		}, time.Hour)
+		require.NoError(t, err)
+		require.Equal(t, 2, data)
+	})
+}
+
+// The handler of an event that matches after the waiter timed out
+// must neither block, nor remain registered.
+func TestWaitForEventTimeoutReleasesHandler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	emitter := NewBaseEventEmitter(ctx)
+	goroutines := goruntime.NumGoroutine()
+
+	const event = "event"
+	matching, release := make(chan struct{}), make(chan struct{})
+	go emitter.emit(event, nil)
+
+	_, err := waitForEvent(ctx, &emitter, []string{event}, func(interface{}) bool {
+		close(matching)
+		<-release
+		return true
+	}, 50*time.Millisecond)
+	require.ErrorIs(t, err, ErrTimedOut)
+	<-matching
+	close(release)
+
+	// require.Eventually would run the condition in a goroutine of its own.
+	var handlers int
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		emitter.sync(func() { handlers = len(emitter.handlers[event]) })
+		if handlers == 0 && goruntime.NumGoroutine() <= goroutines {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("the event handler must be removed: %d handlers, %d goroutines, want %d",
+		handlers, goruntime.NumGoroutine(), goroutines)
+}
+
+// The goroutines of the calls that time out must exit
+// once their late results are sent.
+func TestCallAPIWithTimeoutLateResult(t *testing.T) {
+	ctx := context.Background()
+	goroutines := goruntime.NumGoroutine()
+
+	const calls = 100
+	var (
+		release = make(chan struct{})
+		sent    = make(chan struct{}, calls)
+	)
+	for i := 0; i < calls; i++ {
+		_, err := callApiWithTimeout(ctx, func(_ context.Context, resultCh chan interface{}, _ chan error) {
+			<-release
+			resultCh <- "late"
+			sent <- struct{}{}
+		}, time.Millisecond)
+		require.ErrorIs(t, err, ErrTimedOut)
+	}
+	close(release)
+
+	timeout := time.After(5 * time.Second)
+	for i := 0; i < calls; i++ {
+		select {
+		case <-sent:
+		case <-timeout:
+			t.Fatalf("%d of %d late results blocked", calls-i, calls)
+		}
+	}
+	for deadline := time.Now().Add(5 * time.Second); goruntime.NumGoroutine() > goroutines; {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d goroutines; want %d", goruntime.NumGoroutine(), goroutines)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCallAPIWithTimeout(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	res, err := callApiWithTimeout(ctx, func(_ context.Context, resultCh chan interface{}, _ chan error) {
+		resultCh <- "ok"
+	}, time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "ok", res)
+
+	_, err = callApiWithTimeout(ctx, func(_ context.Context, _ chan interface{}, errCh chan error) {
+		errCh <- ErrInterrupted
+	}, time.Second)
+	require.ErrorIs(t, err, ErrInterrupted)
+
+	_, err = callApiWithTimeout(ctx, func(apiCtx context.Context, _ chan interface{}, errCh chan error) {
+		<-apiCtx.Done()
+		errCh <- apiCtx.Err()
+	}, 10*time.Millisecond)
+	require.ErrorIs(t, err, ErrTimedOut)
+	require.NotErrorIs(t, err, ErrInterrupted)
+
+	_, err = callApiWithTimeout(ctx, func(apiCtx context.Context, _ chan interface{}, errCh chan error) {
+		<-apiCtx.Done()
+		errCh <- fmt.Errorf("waiting for the response: %w", apiCtx.Err())
+	}, 10*time.Millisecond)
+	require.ErrorIs(t, err, ErrTimedOut)
+	require.Contains(t, err.Error(), "context deadline exceeded", "the original error should be kept")
+
+	// an unrelated deadline isn't the timeout of the call.
+	errDeadline := fmt.Errorf("reading the body: %w", context.DeadlineExceeded)
+	_, err = callApiWithTimeout(ctx, func(_ context.Context, _ chan interface{}, errCh chan error) {
+		errCh <- errDeadline
+	}, time.Second)
+	require.ErrorIs(t, err, errDeadline)
+	require.NotErrorIs(t, err, ErrTimedOut)
+}
+
+func TestCallAPIWithTimeoutParentDeadline(t *testing.T) {
+	t.Parallel()
+
+	// the deadline of ctx, such as the end of the scenario,
+	// interrupts the call instead of timing it out.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := callApiWithTimeout(ctx, func(apiCtx context.Context, _ chan interface{}, errCh chan error) {
+		<-apiCtx.Done()
+		errCh <- apiCtx.Err()
+	}, time.Hour)
+	require.ErrorIs(t, err, ErrInterrupted)
+	require.NotErrorIs(t, err, ErrTimedOut)
+}
+
+func TestCallAPIWithTimeoutUnrelatedCancel(t *testing.T) {
+	t.Parallel()
+
+	// a canceled context of fn's own isn't an interruption of the call.
+	_, err := callApiWithTimeout(context.Background(), func(_ context.Context, _ chan interface{}, errCh chan error) {
+		errCh <- fmt.Errorf("sending the request: %w", context.Canceled)
+	}, time.Second)
+	require.ErrorIs(t, err, context.Canceled)
+	require.NotErrorIs(t, err, ErrInterrupted)
+}
+
+func TestCallAPIWithTimeoutCanceled(t *testing.T) {
+	t.Parallel()
+
+	for _, timeout := range []time.Duration{0, time.Hour} {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(10*time.Millisecond, cancel)
+
+		_, err := callApiWithTimeout(ctx, func(apiCtx context.Context, _ chan interface{}, errCh chan error) {
+			<-apiCtx.Done()
+			errCh <- apiCtx.Err()
+		}, timeout)
+		require.ErrorIs(t, err, ErrInterrupted, "timeout: %s", timeout)
+		require.NotErrorIs(t, err, ErrTimedOut, "timeout: %s", timeout)
+		require.ErrorIs(t, errorFromDOMError(err.Error()), ErrInterrupted,
+			"the error must survive the conversion of the DOM errors")
+	}
+}
+
+func TestCallAPIWithTimeoutErrorBeforeDeadline(t *testing.T) {
+	t.Parallel()
+
+	errAction := errors.New("action failed")
+	_, err := callApiWithTimeout(context.Background(), func(_ context.Context, _ chan interface{}, errCh chan error) {
+		time.Sleep(80 * time.Millisecond)
+		errCh <- errAction
+	}, 100*time.Millisecond)
+	require.ErrorIs(t, err, errAction, "the error of fn must not be reported as a timeout")
+}
+
+func TestSpecialArgsExtract(t *testing.T) {
+	t.Parallel()
+
+	h1, h2 := &ElementHandle{}, &BaseJSHandle{}
+	placeholder := func(i int) map[string]interface{} {
+		return map[string]interface{}{nestedHandleKey: i}
+	}
+
+	var special specialArgs
+	extract := func(arg interface{}) interface{} {
+		t.Helper()
+		v, err := special.extract(arg)
+		require.NoError(t, err)
+		return v
+	}
+	require.Same(t, h1, extract(h1), "top-level handles must be kept")
+	require.Equal(t, 42, extract(42))
+	require.Empty(t, special.handles)
+	require.Equal(t, "fn", special.wrap("fn", 1), "a function without placeholders must be kept")
+
+	got := extract([]interface{}{
+		h1,
+		42,
+		map[string]interface{}{"a": []interface{}{map[string]interface{}{"b": h2}}},
+	})
+	require.Equal(t, []interface{}{
+		placeholder(0),
+		42,
+		map[string]interface{}{"a": []interface{}{map[string]interface{}{"b": placeholder(1)}}},
+	}, got)
+	require.Equal(t, []*BaseJSHandle{&h1.BaseJSHandle, h2}, special.handles)
+	require.False(t, special.values)
+
+	n, ok := new(big.Int).SetString(strings.Repeat("9", 100), 10)
+	require.True(t, ok)
+	require.Same(t, n, extract(n), "top-level BigInts must be kept")
+	require.Equal(t, []interface{}{map[string]interface{}{bigIntArgKey: n.String()}}, extract([]interface{}{n}))
+	require.True(t, special.values)
+
+	special = specialArgs{}
+	when := time.Unix(1, 500*int64(time.Millisecond))
+	require.Equal(t, map[string]interface{}{dateArgKey: 1500.0}, extract(when))
+	require.Equal(t, []interface{}{map[string]interface{}{dateArgKey: 1500.0}}, extract([]interface{}{when}))
+	require.True(t, special.values)
+}
+
+func TestSpecialArgsExtractBinary(t *testing.T) {
+	t.Parallel()
+
+	rt := goja.New()
+	placeholder := func(typ string, data ...byte) map[string]interface{} {
+		return map[string]interface{}{binaryArgKey: map[string]interface{}{
+			"type": typ,
+			"data": base64.StdEncoding.EncodeToString(data),
+		}}
+	}
+	extract := func(special *specialArgs, js string) (interface{}, error) {
+		t.Helper()
+		v, err := rt.RunString(js)
+		require.NoError(t, err)
+		return special.extract(v)
+	}
+
+	var special specialArgs
+	got, err := extract(&special, `new Uint8Array([1, 2, 255]).buffer`)
+	require.NoError(t, err)
+	require.Equal(t, placeholder("ArrayBuffer", 1, 2, 255), got)
+	require.True(t, special.values)
+
+	got, err = extract(&special, `new ArrayBuffer(0)`)
+	require.NoError(t, err)
+	require.Equal(t, placeholder("ArrayBuffer"), got)
+
+	got, err = extract(&special, `({
+		list: [new Uint8Array([1, 2, 3, 4]).subarray(1, 3), 42],
+		view: new DataView(new Uint8Array([7]).buffer),
+	})`)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"list": []interface{}{placeholder("Uint8Array", 2, 3), int64(42)},
+		"view": placeholder("DataView", 7),
+	}, got)
+
+	special = specialArgs{maxBinarySize: 4}
+	_, err = extract(&special, `[new ArrayBuffer(3), new Uint8Array(1)]`)
+	require.NoError(t, err)
+	_, err = extract(&special, `new Uint8Array(1)`)
+	require.ErrorIs(t, err, ErrBinaryTooLarge)
+	require.Contains(t, err.Error(), "5 bytes of binary data or more, over the maximum of 4 bytes")
+}
+
+func TestSpecialArgsExtractMapSet(t *testing.T) {
+	t.Parallel()
+
+	rt := goja.New()
+	v, err := rt.RunString(`({
+		m: new Map([['a', 1], [2, new Set(['x', new Date(1500)])]]),
+		s: new Set([new Map([[new Uint8Array([7]), null]])]),
+	})`)
+	require.NoError(t, err)
+
+	var special specialArgs
+	got, err := special.extract(v)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"m": map[string]interface{}{mapArgKey: []interface{}{
+			[]interface{}{"a", int64(1)},
+			[]interface{}{int64(2), map[string]interface{}{setArgKey: []interface{}{
+				"x", map[string]interface{}{dateArgKey: 1500.0},
+			}}},
+		}},
+		"s": map[string]interface{}{setArgKey: []interface{}{
+			map[string]interface{}{mapArgKey: []interface{}{
+				[]interface{}{
+					map[string]interface{}{binaryArgKey: map[string]interface{}{"type": "Uint8Array", "data": "Bw=="}},
+					nil,
+				},
+			}},
+		}},
+	}, got)
+	require.True(t, special.values)
+
+	t.Run("err_max_depth", func(t *testing.T) {
+		t.Parallel()
+
+		rt := goja.New()
+		v, err := rt.RunString(`const o = { m: new Map() }; o.m.set('o', o); o`)
+		require.NoError(t, err)
+
+		special := specialArgs{maxDepth: 8}
+		_, err = special.extract(v)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "argument is nested deeper than the maximum depth of 8")
+	})
+}
+
+func TestSpecialArgsWrap(t *testing.T) {
+	t.Parallel()
+
+	special := specialArgs{handles: []*BaseJSHandle{{}, {}}, values: true}
+	fn := special.wrap(`function(list, obj, when) {
+		return [
+			list[0].id, list[1], obj.a.b[0].id, obj.c, this.id,
+			when instanceof Date && when.getTime(), obj.a.at instanceof Date && obj.a.at.getTime(),
+		];
+	}`, 3)
+
+	rt := goja.New()
+	require.NoError(t, rt.Set("fn", fn))
+	v, err := rt.RunString(`
+		const f = eval('(' + fn + ')');
+		JSON.stringify(f.call(
+			{ id: 'this' },
+			[{ ` + nestedHandleKey + `: 0 }, 42],
+			{ a: { b: [{ ` + nestedHandleKey + `: 1 }], at: { ` + dateArgKey + `: 1000 } }, c: null },
+			{ ` + dateArgKey + `: 1500 },
+			{ id: 'h0' },
+			{ id: 'h1' },
+		));
+	`)
+	require.NoError(t, err)
+	require.JSONEq(t, `["h0", 42, "h1", null, "this", 1500, 1000]`, v.String())
+
+	t.Run("binary", func(t *testing.T) {
+		t.Parallel()
+
+		special := specialArgs{values: true}
+		fn := special.wrap(`function(ab, list) {
+			return [
+				ab instanceof ArrayBuffer && new Uint8Array(ab).join(),
+				list[0] instanceof Uint16Array && list[0].join(),
+				list[1] instanceof ArrayBuffer && list[1].byteLength,
+			];
+		}`, 2)
+
+		rt := goja.New()
+		setBase64Globals(t, rt)
+		require.NoError(t, rt.Set("fn", fn))
+		v, err := rt.RunString(`
+			const f = eval('(' + fn + ')');
+			JSON.stringify(f(
+				{ ` + binaryArgKey + `: { type: 'ArrayBuffer', data: 'AQL/' } },
+				[
+					{ ` + binaryArgKey + `: { type: 'Uint16Array', data: 'AgEDAA==' } },
+					{ ` + binaryArgKey + `: { type: 'ArrayBuffer', data: '' } },
+				],
+			));
+		`)
+		require.NoError(t, err)
+		require.JSONEq(t, `["1,2,255", "258,3", 0]`, v.String())
+	})
+
+	t.Run("map_set", func(t *testing.T) {
+		t.Parallel()
+
+		special := specialArgs{values: true}
+		fn := special.wrap(`function(m, obj) {
+			return [
+				m instanceof Map && m.get('a'), m.get(2) instanceof Set && [...m.get(2)],
+				obj.s instanceof Set && obj.s.size, [...obj.s][0] instanceof Date,
+			];
+		}`, 2)
+
+		rt := goja.New()
+		require.NoError(t, rt.Set("fn", fn))
+		v, err := rt.RunString(`
+			const f = eval('(' + fn + ')');
+			JSON.stringify(f(
+				{ ` + mapArgKey + `: [['a', 1], [2, { ` + setArgKey + `: ['x', 'y'] }]] },
+				{ s: { ` + setArgKey + `: [{ ` + dateArgKey + `: 1500 }] } },
+			));
+		`)
+		require.NoError(t, err)
+		require.JSONEq(t, `[1, ["x", "y"], 1, true]`, v.String())
+	})
+}
@@ -50,13 +50,15 @@ func applySlowMo(ctx context.Context) {
 }
 
 func defaultSlowMo(ctx context.Context) {
-	sm := GetLaunchOptions(ctx).SlowMo
-	if sm <= 0 {
+	opts := GetLaunchOptions(ctx)
+	if opts == nil || opts.SlowMo <= 0 {
 		return
 	}
+	t := time.NewTimer(opts.SlowMo)
+	defer t.Stop()
 	select {
 	case <-ctx.Done():
-	case <-time.After(sm):
+	case <-t.C:
 	}
 }
 
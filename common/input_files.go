@@ -0,0 +1,136 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+
+	"github.com/dop251/goja"
+
+	"github.com/grafana/xk6-browser/k6ext"
+)
+
+// InputFilePayload is an in-memory file to set on a file input element.
+type InputFilePayload struct {
+	Name     string `json:"name"`
+	MimeType string `json:"mimeType"`
+	Buffer   []byte `json:"buffer"`
+}
+
+// InputFiles are the files to set on a file input element.
+// They are either paths of files on disk, or in-memory files, but not both.
+// No files clear the selected files of the element.
+type InputFiles struct {
+	Paths    []string
+	Payloads []*InputFilePayload
+}
+
+// Parse parses the files argument of setInputFiles. It can be a file path,
+// an in-memory file object with the name, mimeType and buffer properties,
+// or an array of either.
+func (f *InputFiles) Parse(ctx context.Context, files goja.Value) error {
+	rt := k6ext.Runtime(ctx)
+	if !gojaValueExists(files) {
+		return errors.New("missing files")
+	}
+
+	items := []goja.Value{files}
+	if obj, ok := files.(*goja.Object); ok && obj.ClassName() == "Array" {
+		items = items[:0]
+		for i := int64(0); i < obj.Get("length").ToInteger(); i++ {
+			items = append(items, obj.Get(fmt.Sprint(i)))
+		}
+	}
+	for _, item := range items {
+		if _, ok := item.Export().(string); ok {
+			if err := f.addPath(item.String()); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := f.addPayload(rt, item); err != nil {
+			return err
+		}
+	}
+	if len(f.Paths) > 0 && len(f.Payloads) > 0 {
+		return errors.New("cannot set both file paths and in-memory files")
+	}
+
+	return nil
+}
+
+func (f *InputFiles) addPath(path string) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("resolving file path: %w", err)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("reading file: %w", err)
+	}
+	if fi.IsDir() {
+		return fmt.Errorf("%q is a directory", path)
+	}
+	f.Paths = append(f.Paths, path)
+
+	return nil
+}
+
+func (f *InputFiles) addPayload(rt *goja.Runtime, v goja.Value) error {
+	if !gojaValueExists(v) {
+		return errors.New("files must be file paths or objects with name, mimeType and buffer properties")
+	}
+	p := &InputFilePayload{}
+	obj := v.ToObject(rt)
+	for _, k := range obj.Keys() {
+		switch k {
+		case "name":
+			p.Name = obj.Get(k).String()
+		case "mimeType":
+			p.MimeType = obj.Get(k).String()
+		case "buffer":
+			switch b := obj.Get(k).Export().(type) {
+			case goja.ArrayBuffer:
+				p.Buffer = b.Bytes()
+			case []byte:
+				p.Buffer = b
+			case string:
+				p.Buffer = []byte(b)
+			default:
+				return fmt.Errorf("buffer of file %q must be an ArrayBuffer or a string, got %T", p.Name, b)
+			}
+		}
+	}
+	if p.Name == "" {
+		return errors.New("in-memory files must have a name")
+	}
+	if p.MimeType == "" {
+		p.MimeType = mime.TypeByExtension(filepath.Ext(p.Name))
+	}
+	f.Payloads = append(f.Payloads, p)
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana/xk6-browser/k6ext/k6test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInputFilesParse(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	require.NoError(t, os.WriteFile(path, []byte("hello"), 0o600))
+
+	t.Run("paths", func(t *testing.T) {
+		t.Parallel()
+
+		vu := k6test.NewVU(t)
+		var files InputFiles
+		require.NoError(t, files.Parse(vu.Context(), vu.ToGojaValue([]string{path, path})))
+		assert.Equal(t, []string{path, path}, files.Paths)
+		assert.Empty(t, files.Payloads)
+	})
+
+	t.Run("payload", func(t *testing.T) {
+		t.Parallel()
+
+		vu := k6test.NewVU(t)
+		v, err := vu.Runtime().RunString(`({
+			name: 'data.json',
+			buffer: new Uint8Array([104, 105]).buffer,
+		})`)
+		require.NoError(t, err)
+
+		var files InputFiles
+		require.NoError(t, files.Parse(vu.Context(), v))
+		assert.Empty(t, files.Paths)
+		assert.Equal(t, []*InputFilePayload{
+			{Name: "data.json", MimeType: "application/json", Buffer: []byte("hi")},
+		}, files.Payloads)
+	})
+
+	t.Run("clear", func(t *testing.T) {
+		t.Parallel()
+
+		vu := k6test.NewVU(t)
+		var files InputFiles
+		require.NoError(t, files.Parse(vu.Context(), vu.ToGojaValue([]string{})))
+		assert.Empty(t, files.Paths)
+		assert.Empty(t, files.Payloads)
+	})
+
+	t.Run("err", func(t *testing.T) {
+		t.Parallel()
+
+		testCases := []struct {
+			name    string
+			files   string // JS expression
+			wantErr string
+		}{
+			{name: "missing", files: `undefined`, wantErr: "missing files"},
+			{name: "not_found", files: `'nonexistent.txt'`, wantErr: "reading file"},
+			{name: "directory", files: `'.'`, wantErr: "is a directory"},
+			{name: "no_name", files: `({ buffer: 'hi' })`, wantErr: "in-memory files must have a name"},
+			{name: "bad_buffer", files: `({ name: 'a', buffer: 42 })`, wantErr: "must be an ArrayBuffer or a string"},
+			{
+				name:    "mixed",
+				files:   `['` + filepath.ToSlash(path) + `', { name: 'b', buffer: '' }]`,
+				wantErr: "cannot set both file paths and in-memory files",
+			},
+		}
+		for _, tc := range testCases {
+			tc := tc
+			t.Run(tc.name, func(t *testing.T) {
+				t.Parallel()
+
+				vu := k6test.NewVU(t)
+				v, err := vu.Runtime().RunString(tc.files)
+				require.NoError(t, err)
+
+				var files InputFiles
+				assert.ErrorContains(t, files.Parse(vu.Context(), v), tc.wantErr)
+			})
+		}
+	})
+}
@@ -95,7 +95,12 @@ function isVisible(element) {
     return true;
   }
   const style = element.ownerDocument.defaultView.getComputedStyle(element);
-  if (!style || style.visibility === "hidden") {
+  if (
+    !style ||
+    style.display === "none" ||
+    style.visibility === "hidden" ||
+    style.visibility === "collapse"
+  ) {
     return false;
   }
   const rect = element.getBoundingClientRect();
@@ -106,15 +111,184 @@ function oneLine(s) {
   return s.replace(/\n/g, "↵").replace(/\t/g, "⇆");
 }
 
+// AttributeQueryEngine finds the elements whose attribute equals the
+// selector, which can be quoted.
+class AttributeQueryEngine {
+  constructor(attribute, pierce) {
+    this._attribute = attribute;
+    this._pierce = pierce;
+  }
+
+  queryAll(root, selector) {
+    let value = selector.trim();
+    if (
+      value.length > 1 &&
+      (value[0] === '"' || value[0] === "'") &&
+      value[value.length - 1] === value[0]
+    ) {
+      value = value.substring(1, value.length - 1).replace(/\\(.)/g, "$1");
+    }
+    const query = (root) => root.querySelectorAll(`[${this._attribute}]`);
+    const elements = this._pierce ? queryAllDeep(root, query) : query(root);
+    const result = [];
+    for (const element of elements) {
+      if (element.getAttribute(this._attribute) === value) {
+        result.push(element);
+      }
+    }
+    return result;
+  }
+}
+
 class CSSQueryEngine {
+  constructor(pierce) {
+    this._pierce = pierce;
+  }
+
   queryAll(root, selector) {
-    return root.querySelectorAll(selector);
+    const query = (root) => root.querySelectorAll(selector);
+    return this._pierce ? queryAllDeep(root, query) : query(root);
+  }
+}
+
+// queryAllDeep returns the elements that query returns for the root, and for
+// the open shadow roots under it and of the root itself, recursively.
+function queryAllDeep(root, query) {
+  const result = Array.from(query(root));
+  const hosts = root.shadowRoot ? [root] : [];
+  for (const element of root.querySelectorAll("*")) {
+    if (element.shadowRoot) {
+      hosts.push(element);
+    }
+  }
+  for (const host of hosts) {
+    result.push(...queryAllDeep(host.shadowRoot, query));
   }
+  return result;
+}
+
+// Elements whose content is never rendered as text.
+const nonTextTags = new Set(["HEAD", "NOSCRIPT", "SCRIPT", "STYLE", "TEMPLATE"]);
+
+function normalizeWhiteSpace(text) {
+  return text.trim().replace(/\s+/g, " ");
+}
+
+// createTextMatcher returns a function that reports whether a normalized
+// text matches the body of a text selector:
+//   - "foo" or 'foo' matches the text "foo" exactly, case-sensitively.
+//   - /foo/i matches the texts that the regular expression matches.
+//   - foo matches the texts that contain "foo", case-insensitively.
+function createTextMatcher(selector) {
+  if (
+    selector.length > 1 &&
+    (selector[0] === '"' || selector[0] === "'") &&
+    selector[selector.length - 1] === selector[0]
+  ) {
+    const text = normalizeWhiteSpace(
+      selector.substring(1, selector.length - 1).replace(/\\(.)/g, "$1")
+    );
+    return (s) => s === text;
+  }
+  if (selector[0] === "/" && selector.lastIndexOf("/") > 0) {
+    const lastSlash = selector.lastIndexOf("/");
+    const re = new RegExp(
+      selector.substring(1, lastSlash),
+      selector.substring(lastSlash + 1)
+    );
+    return (s) => re.test(s);
+  }
+  const text = normalizeWhiteSpace(selector).toLowerCase();
+  return (s) => s.toLowerCase().includes(text);
 }
 
 class TextQueryEngine {
+  constructor(pierce) {
+    this._pierce = pierce;
+  }
+
   queryAll(root, selector) {
-    return root.queryAll(selector);
+    const matches = createTextMatcher(selector);
+    const cache = new Map();
+    const matched = new Set();
+    const result = [];
+    const query = (root) => root.querySelectorAll("*");
+    const elements = this._pierce ? queryAllDeep(root, query) : query(root);
+    for (const element of elements) {
+      if (matches(this._elementText(element, cache))) {
+        matched.add(element);
+      }
+    }
+    // The deepest elements win: skip the ones that only match
+    // because one of their descendants does.
+    for (const element of matched) {
+      const children = [...element.children];
+      if (this._pierce && element.shadowRoot) {
+        children.push(...element.shadowRoot.children);
+      }
+      if (!children.some((child) => matched.has(child))) {
+        result.push(element);
+      }
+    }
+    return result;
+  }
+
+  // _elementText returns the normalized text of the element, without the
+  // text of its hidden descendants.
+  _elementText(element, cache) {
+    let text = cache.get(element);
+    if (text !== undefined) {
+      return text;
+    }
+    text = "";
+    if (!nonTextTags.has(element.nodeName) && !this._isHidden(element)) {
+      if (
+        element.nodeName === "INPUT" &&
+        (element.type === "button" || element.type === "submit")
+      ) {
+        text = element.value;
+      } else if (this._pierce && element.shadowRoot) {
+        // the light DOM children are only rendered in the slots.
+        text = this._nodesText(element.shadowRoot.childNodes, cache);
+      } else {
+        text = this._nodesText(element.childNodes, cache);
+      }
+    }
+    text = normalizeWhiteSpace(text);
+    cache.set(element, text);
+    return text;
+  }
+
+  _nodesText(nodes, cache) {
+    let text = "";
+    for (const node of nodes) {
+      if (node.nodeName === "SLOT" && this._pierce) {
+        const assigned = node.assignedNodes();
+        text += this._nodesText(
+          assigned.length ? assigned : node.childNodes,
+          cache
+        );
+      } else if (node.nodeType === 1 /*Node.ELEMENT_NODE*/) {
+        text += this._elementText(node, cache);
+      } else if (node.nodeType === 3 /*Node.TEXT_NODE*/) {
+        text += node.nodeValue;
+      }
+    }
+    return text;
+  }
+
+  _isHidden(element) {
+    const view = element.ownerDocument && element.ownerDocument.defaultView;
+    if (!view) {
+      return false;
+    }
+    const style = view.getComputedStyle(element);
+    return (
+      !style ||
+      style.display === "none" ||
+      style.visibility === "hidden" ||
+      style.visibility === "collapse"
+    );
   }
 }
 
@@ -144,18 +318,66 @@ class XPathQueryEngine {
 }
 
 class InjectedScript {
-  constructor() {
+  constructor(testIdAttribute) {
     this._replaceRafWithTimeout = false;
     this._stableRafCount = 10;
+    testIdAttribute = testIdAttribute || "data-testid";
+    // The engines pierce open shadow roots, except for their :light variants.
     this._queryEngines = {
-      css: new CSSQueryEngine(),
-      text: new TextQueryEngine(),
+      css: new CSSQueryEngine(true),
+      "css:light": new CSSQueryEngine(false),
+      "data-testid": new AttributeQueryEngine("data-testid", true),
+      "data-testid:light": new AttributeQueryEngine("data-testid", false),
+      testid: new AttributeQueryEngine(testIdAttribute, true),
+      "testid:light": new AttributeQueryEngine(testIdAttribute, false),
+      text: new TextQueryEngine(true),
+      "text:light": new TextQueryEngine(false),
       xpath: new XPathQueryEngine(),
     };
   }
 
+  // registerEngine adds a custom selector engine that queries elements with
+  // its query(root, selector) or queryAll(root, selector) functions.
+  registerEngine(name, engine) {
+    if (this._queryEngines[name]) {
+      throw new Error(`selector engine "${name}" is already registered`);
+    }
+    if (
+      !engine ||
+      (typeof engine.query !== "function" &&
+        typeof engine.queryAll !== "function")
+    ) {
+      throw new Error(
+        `selector engine "${name}" must have a query or queryAll function`
+      );
+    }
+    this._queryEngines[name] = {
+      custom: true,
+      queryAll(root, selector) {
+        if (typeof engine.queryAll === "function") {
+          return Array.from(engine.queryAll(root, selector) || []);
+        }
+        const element = engine.query(root, selector);
+        return element ? [element] : [];
+      },
+    };
+  }
+
   _queryEngineAll(part, root) {
-    return this._queryEngines[part.name].queryAll(root, part.body);
+    const engine = this._queryEngines[part.name];
+    if (!engine) {
+      throw new Error(`unknown selector engine "${part.name}"`);
+    }
+    if (!engine.custom) {
+      return engine.queryAll(root, part.body);
+    }
+    try {
+      return engine.queryAll(root, part.body);
+    } catch (e) {
+      throw new Error(
+        `selector engine "${part.name}" failed: ${(e && e.message) || e}`
+      );
+    }
   }
 
   _querySelectorRecursively(roots, selector, index, queryCache) {
@@ -524,6 +746,22 @@ class InjectedScript {
     }
   }
 
+  // strictModeViolationError returns the error of a strict selector that
+  // resolves to multiple elements, which describes the first few of them.
+  strictModeViolationError(selector, elements) {
+    const maxPreviews = 10;
+    const lines = elements
+      .slice(0, maxPreviews)
+      .map((element, i) => `    ${i + 1}) ${this.previewNode(element)}`);
+    if (elements.length > maxPreviews) {
+      lines.push(`    ...and ${elements.length - maxPreviews} more`);
+    }
+    return (
+      `error:strictmodeviolation:selector "${selector.selector}" ` +
+      `resolved to ${elements.length} elements:\n${lines.join("\n")}`
+    );
+  }
+
   previewNode(node) {
     if (node.nodeType === 3 /*Node.TEXT_NODE*/) {
       return oneLine(`#text=${node.nodeValue || ""}`);
@@ -586,7 +824,10 @@ class InjectedScript {
       new Map()
     );
     if (strict && result.length > 1) {
-      throw "error:strictmodeviolation";
+      throw this.strictModeViolationError(
+        selector,
+        result.map((r) => r.capture || r.element)
+      );
     }
     if (result.length == 0) {
       return null;
@@ -673,6 +914,42 @@ class InjectedScript {
     return selectedOptions.map((option) => option.value);
   }
 
+  setInputFiles(node, count, payloads) {
+    if (node.nodeType !== Node.ELEMENT_NODE) {
+      return "error:notelement";
+    }
+    if (!node.isConnected) {
+      return "error:notconnected";
+    }
+    const input = node;
+    if (
+      input.nodeName.toLowerCase() !== "input" ||
+      input.type.toLowerCase() !== "file"
+    ) {
+      return "error:notfileinput";
+    }
+    if (count > 1 && !input.multiple) {
+      return "error:notmultiplefileinput";
+    }
+    if (!payloads) {
+      // the files are set by the caller.
+      return "done";
+    }
+    const dt = new DataTransfer();
+    for (const payload of payloads) {
+      const bytes = Uint8Array.from(atob(payload.buffer || ""), (c) =>
+        c.charCodeAt(0)
+      );
+      dt.items.add(
+        new File([bytes], payload.name, { type: payload.mimeType })
+      );
+    }
+    input.files = dt.files;
+    input.dispatchEvent(new Event("input", { bubbles: true, composed: true }));
+    input.dispatchEvent(new Event("change", { bubbles: true }));
+    return "done";
+  }
+
   selectText(node) {
     const element = this._retarget(node, "follow-label");
     if (!element) {
@@ -708,7 +985,8 @@ class InjectedScript {
     const predicate = () => {
       return predicateFn(...args) || continuePolling;
     };
-    if (timeout !== undefined || timeout !== null) {
+    // a zero timeout waits forever.
+    if (timeout) {
       setTimeout(() => {
         timedOut = true;
         if (timeoutPoll) timeoutPoll();
@@ -718,6 +996,7 @@ class InjectedScript {
     if (polling === "mutation") return await pollMutation();
     if (typeof polling === "number") return await pollInterval(polling);
 
+    // pollMutation only evaluates the predicate again when the DOM changes.
     async function pollMutation() {
       const success = predicate();
       if (success !== continuePolling) return Promise.resolve(success);
@@ -731,8 +1010,16 @@ class InjectedScript {
         if (timedOut) {
           observer.disconnect();
           reject(`timed out after ${timeout}ms`);
+          return;
+        }
+        let success;
+        try {
+          success = predicate();
+        } catch (e) {
+          observer.disconnect();
+          reject(e);
+          return;
         }
-        const success = predicate();
         if (success !== continuePolling) {
           observer.disconnect();
           resolve(success);
@@ -746,6 +1033,7 @@ class InjectedScript {
         childList: true,
         subtree: true,
         attributes: true,
+        characterData: true,
       });
       return result;
     }
@@ -764,7 +1052,13 @@ class InjectedScript {
           reject(`timed out after ${timeout}ms`);
           return;
         }
-        const success = predicate();
+        let success;
+        try {
+          success = predicate();
+        } catch (e) {
+          reject(e);
+          return;
+        }
         if (success !== continuePolling) resolve(success);
         else requestAnimationFrame(onRaf);
       }
@@ -784,7 +1078,13 @@ class InjectedScript {
           reject(`timed out after ${timeout}ms`);
           return;
         }
-        const success = predicate();
+        let success;
+        try {
+          success = predicate();
+        } catch (e) {
+          reject(e);
+          return;
+        }
         if (success !== continuePolling) resolve(success);
         else setTimeout(onTimeout, pollInterval);
       }
@@ -866,22 +1166,18 @@ class InjectedScript {
 
   waitForSelector(selector, root, strict, state, polling, timeout, ...args) {
     let lastElement;
-    let previewNode = this.previewNode;
     const predicate = () => {
       const elements = this.querySelectorAll(selector, root || document);
       const element = elements[0];
       const visible = element ? isVisible(element) : false;
 
+      if (strict && elements.length > 1) {
+        throw this.strictModeViolationError(selector, elements);
+      }
       if (lastElement !== element) {
         lastElement = element;
         if (!element) {
           console.log(`  selector did not resolve to any element`);
-        } else {
-          if (elements.length > 1) {
-            if (strict) {
-              throw "error:strictmodeviolation";
-            }
-          }
         }
       }
 
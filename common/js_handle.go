@@ -22,7 +22,10 @@ package common
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strconv"
 
 	"github.com/grafana/xk6-browser/api"
 	"github.com/grafana/xk6-browser/k6ext"
@@ -37,6 +40,7 @@ type jsHandle interface {
 	api.JSHandle
 	dispose() error
 	getProperties() (map[string]jsHandle, error)
+	markDisposed()
 }
 
 var _ jsHandle = &BaseJSHandle{}
@@ -79,6 +83,70 @@ func NewJSHandle(
 	return eh
 }
 
+// AsArray returns handles to the items of an array-like object, such as an
+// Array or a NodeList, in the order of their indexes. The items that are
+// DOM nodes are returned as element handles.
+func (h *BaseJSHandle) AsArray() []api.JSHandle {
+	handles, err := h.arrayItems()
+	if err != nil {
+		k6ext.Panic(h.ctx, "getting array items: %w", err)
+	}
+
+	items := make([]api.JSHandle, len(handles))
+	for i, item := range handles {
+		items[i] = item
+	}
+
+	return items
+}
+
+// arrayItems is like AsArray, but does not panic. The item handles are
+// disposed with the frame of the handle.
+func (h *BaseJSHandle) arrayItems() ([]jsHandle, error) {
+	if h.disposed {
+		return nil, errors.New("handle is disposed")
+	}
+	if h.remoteObject.ObjectID == "" {
+		return nil, errors.New("handle is not an object")
+	}
+	act := runtime.GetProperties(h.remoteObject.ObjectID).WithOwnProperties(true)
+	result, _, _, _, err := act.Do(cdp.WithExecutor(h.ctx, h.session)) //nolint:dogsled
+	if err != nil {
+		return nil, fmt.Errorf("getting properties for element with ID %s: %w",
+			h.remoteObject.ObjectID, err)
+	}
+
+	type indexed struct {
+		index int
+		value *runtime.RemoteObject
+	}
+	values := make([]indexed, 0, len(result))
+	for _, r := range result {
+		if !r.Enumerable || r.Value == nil {
+			continue
+		}
+		// only the array indexes, and not the other properties.
+		i, err := strconv.Atoi(r.Name)
+		if err != nil || i < 0 || strconv.Itoa(i) != r.Name {
+			continue
+		}
+		values = append(values, indexed{i, r.Value})
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].index < values[j].index })
+
+	frame := h.execCtx.Frame()
+	items := make([]jsHandle, 0, len(values))
+	for _, v := range values {
+		item := NewJSHandle(h.ctx, h.session, h.execCtx, frame, v.value, h.logger)
+		if frame != nil {
+			frame.trackHandle(h.execCtx.ID(), item)
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
 // AsElement returns an element handle if this JSHandle is a reference to a JS HTML element.
 func (h *BaseJSHandle) AsElement() api.ElementHandle {
 	return nil
@@ -91,6 +159,12 @@ func (h *BaseJSHandle) Dispose() {
 	}
 }
 
+// markDisposed marks the handle as disposed without releasing the remote
+// object, for when the remote object is already gone.
+func (h *BaseJSHandle) markDisposed() {
+	h.disposed = true
+}
+
 // dispose is like Dispose, but does not panic.
 func (h *BaseJSHandle) dispose() error {
 	if h.disposed {
@@ -23,6 +23,7 @@ package common
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/grafana/xk6-browser/api"
@@ -49,6 +50,9 @@ type Keyboard struct {
 	ctx     context.Context
 	session session
 
+	// protects the key state from the actions that run concurrently,
+	// such as the ones of a promise and the event loop.
+	mu          sync.Mutex
 	modifiers   int64          // like shift, alt, ctrl, ...
 	pressedKeys map[int64]bool // tracks keys through down() and up()
 	layoutName  string         // us by default
@@ -121,11 +125,14 @@ func (k *Keyboard) down(key string) error {
 		return fmt.Errorf("%q is not a valid key for layout %q", key, k.layoutName)
 	}
 
+	k.mu.Lock()
 	keyDef := k.keyDefinitionFromKey(keyInput)
 	k.modifiers |= k.modifierBitFromKeyName(keyDef.Key)
+	modifiers := k.modifiers
 	text := keyDef.Text
 	_, autoRepeat := k.pressedKeys[keyDef.KeyCode]
 	k.pressedKeys[keyDef.KeyCode] = true
+	k.mu.Unlock()
 
 	keyType := input.KeyDown
 	if text == "" {
@@ -133,7 +140,7 @@ func (k *Keyboard) down(key string) error {
 	}
 
 	action := input.DispatchKeyEvent(keyType).
-		WithModifiers(input.Modifier(k.modifiers)).
+		WithModifiers(input.Modifier(modifiers)).
 		WithKey(keyDef.Key).
 		WithWindowsVirtualKeyCode(keyDef.KeyCode).
 		WithCode(keyDef.Code).
@@ -155,12 +162,15 @@ func (k *Keyboard) up(key string) error {
 		return fmt.Errorf("'%s' is not a valid key for layout '%s'", key, k.layoutName)
 	}
 
+	k.mu.Lock()
 	keyDef := k.keyDefinitionFromKey(keyInput)
 	k.modifiers &= ^k.modifierBitFromKeyName(keyDef.Key)
+	modifiers := k.modifiers
 	delete(k.pressedKeys, keyDef.KeyCode)
+	k.mu.Unlock()
 
 	action := input.DispatchKeyEvent(input.KeyUp).
-		WithModifiers(input.Modifier(k.modifiers)).
+		WithModifiers(input.Modifier(modifiers)).
 		WithKey(keyDef.Key).
 		WithWindowsVirtualKeyCode(keyDef.KeyCode).
 		WithCode(keyDef.Code).
@@ -180,6 +190,16 @@ func (k *Keyboard) insertText(text string) error {
 	return nil
 }
 
+// getModifiers returns the bits of the pressed modifier keys.
+func (k *Keyboard) getModifiers() int64 {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return k.modifiers
+}
+
+// keyDefinitionFromKey returns the definition of the key with the
+// pressed modifiers. It must be called with the keyboard locked.
 func (k *Keyboard) keyDefinitionFromKey(key keyboardlayout.KeyInput) keyboardlayout.KeyDefinition {
 	shift := k.modifiers & ModifierKeyShift
 
@@ -242,13 +262,50 @@ func (k *Keyboard) modifierBitFromKeyName(key string) int64 {
 	return 0
 }
 
+// ensureModifiers presses or releases the modifier keys so that only the
+// given modifiers are pressed. It returns a function that restores the
+// modifier keys to their previous state.
+func (k *Keyboard) ensureModifiers(modifiers []string) (restore func() error, err error) {
+	var want int64
+	for _, m := range modifiers {
+		bit := k.modifierBitFromKeyName(m)
+		if bit == 0 {
+			return nil, fmt.Errorf("unknown modifier key %q", m)
+		}
+		want |= bit
+	}
+	prev := k.getModifiers()
+	if err := k.setModifiers(want); err != nil {
+		return nil, err
+	}
+
+	return func() error { return k.setModifiers(prev) }, nil
+}
+
+// setModifiers presses and releases the modifier keys whose state
+// differ from the given modifier bits.
+func (k *Keyboard) setModifiers(modifiers int64) error {
+	for _, key := range []string{"Alt", "Control", "Meta", "Shift"} {
+		bit := k.modifierBitFromKeyName(key)
+		switch pressed, want := k.getModifiers()&bit != 0, modifiers&bit != 0; {
+		case want && !pressed:
+			if err := k.down(key); err != nil {
+				return fmt.Errorf("pressing modifier key %q: %w", key, err)
+			}
+		case !want && pressed:
+			if err := k.up(key); err != nil {
+				return fmt.Errorf("releasing modifier key %q: %w", key, err)
+			}
+		}
+	}
+
+	return nil
+}
+
 func (k *Keyboard) press(key string, opts *KeyboardOptions) error {
 	if opts.Delay != 0 {
-		t := time.NewTimer(time.Duration(opts.Delay) * time.Millisecond)
-		select {
-		case <-k.ctx.Done():
-			t.Stop()
-		case <-t.C:
+		if err := sleep(k.ctx, time.Duration(opts.Delay)*time.Millisecond); err != nil {
+			return err
 		}
 	}
 	if err := k.down(key); err != nil {
@@ -261,11 +318,8 @@ func (k *Keyboard) typ(text string, opts *KeyboardOptions) error {
 	layout := keyboardlayout.GetKeyboardLayout(k.layoutName)
 	for _, c := range text {
 		if opts.Delay != 0 {
-			t := time.NewTimer(time.Duration(opts.Delay) * time.Millisecond)
-			select {
-			case <-k.ctx.Done():
-				t.Stop()
-			case <-t.C:
+			if err := sleep(k.ctx, time.Duration(opts.Delay)*time.Millisecond); err != nil {
+				return err
 			}
 		}
 		keyInput := keyboardlayout.KeyInput(c)
@@ -0,0 +1,62 @@
+package common
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/xk6-browser/k6ext/k6test"
+
+	"github.com/mailru/easyjson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingSession counts the commands executed with it.
+type countingSession struct {
+	session
+	calls int
+}
+
+func (s *countingSession) Execute(context.Context, string, easyjson.Marshaler, easyjson.Unmarshaler) error {
+	s.calls++
+	return nil
+}
+
+func TestKeyboardDelayInterrupted(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		fn   func(*Keyboard, *KeyboardOptions) error
+	}{
+		{
+			name: "press",
+			fn:   func(k *Keyboard, opts *KeyboardOptions) error { return k.press("a", opts) },
+		},
+		{
+			name: "type",
+			fn:   func(k *Keyboard, opts *KeyboardOptions) error { return k.typ("abc", opts) },
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			vu := k6test.NewVU(t)
+			ctx, cancel := context.WithCancel(vu.Context())
+			s := &countingSession{}
+			k := NewKeyboard(ctx, s)
+			opts := NewKeyboardOptions()
+			opts.Delay = time.Hour.Milliseconds()
+
+			time.AfterFunc(50*time.Millisecond, cancel)
+			start := time.Now()
+			err := tc.fn(k, opts)
+			require.ErrorIs(t, err, ErrInterrupted)
+			assert.Less(t, time.Since(start), 5*time.Second)
+			assert.Zero(t, s.calls, "no key should be pressed after the interruption")
+		})
+	}
+}
@@ -22,8 +22,10 @@ package common
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 	"time"
 
 	"github.com/dop251/goja"
@@ -31,26 +33,91 @@ import (
 	"github.com/grafana/xk6-browser/k6ext"
 )
 
+// ProxyOptions are the options of the proxy that the browser sends the
+// requests through. The bypass is a comma-separated list of the hosts
+// that are requested directly, such as ".example.com, localhost".
 type ProxyOptions struct {
-	Server   string
-	Bypass   string
-	Username string
-	Password string
+	Server   string `js:"server"`
+	Bypass   string `js:"bypass"`
+	Username string `js:"username"`
+	Password string `js:"password"`
+}
+
+// Parse parses the proxy options.
+func (p *ProxyOptions) Parse(ctx context.Context, opts goja.Value) error {
+	if opts == nil || goja.IsUndefined(opts) || goja.IsNull(opts) {
+		return nil
+	}
+	rt := k6ext.Runtime(ctx)
+	obj := opts.ToObject(rt)
+	for _, k := range obj.Keys() {
+		switch k {
+		case "server":
+			p.Server = obj.Get(k).String()
+		case "bypass":
+			p.Bypass = obj.Get(k).String()
+		case "username":
+			p.Username = obj.Get(k).String()
+		case "password":
+			p.Password = obj.Get(k).String()
+		}
+	}
+	if p.Server == "" {
+		return errors.New("server is required")
+	}
+	// the proxy server defaults to HTTP, as in the command line flag.
+	if !strings.Contains(p.Server, "://") {
+		p.Server = "http://" + p.Server
+	}
+	return nil
+}
+
+// bypassList returns the proxy bypass list in the format of the browser.
+// The loopback addresses are proxied too unless the bypass includes them.
+func (p *ProxyOptions) bypassList() string {
+	var rules []string
+	for _, r := range strings.Split(p.Bypass, ",") {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			continue
+		}
+		if strings.HasPrefix(r, ".") {
+			r = "*" + r
+		}
+		rules = append(rules, r)
+	}
+	return strings.Join(append(rules, "<-loopback>"), ";")
+}
+
+// credentials returns the credentials of the proxy, or nil.
+func (p *ProxyOptions) credentials() *Credentials {
+	if p == nil || (p.Username == "" && p.Password == "") {
+		return nil
+	}
+	return &Credentials{Username: p.Username, Password: p.Password}
 }
 
 // LaunchOptions stores browser launch options.
 type LaunchOptions struct {
-	Args              []string
-	Debug             bool
-	Devtools          bool
-	Env               map[string]string
-	ExecutablePath    string
-	Headless          bool
-	IgnoreDefaultArgs []string
-	LogCategoryFilter string
-	Proxy             ProxyOptions
-	SlowMo            time.Duration
-	Timeout           time.Duration
+	Args                  []string
+	ArtifactsDir          string
+	AutoRestart           bool
+	Debug                 bool
+	Devtools              bool
+	Env                   map[string]string
+	EvaluateMaxBinarySize int64
+	EvaluateMaxDepth      int64
+	ExecutablePath        string
+	Headless              bool
+	IgnoreDefaultArgs     []string
+	KillOnError           bool
+	LogCategoryFilter     string
+	OutputDir             string
+	Proxy                 ProxyOptions
+	ReuseBrowser          bool
+	ReusePage             bool
+	SlowMo                time.Duration
+	Timeout               time.Duration
 }
 
 // LaunchPersistentContextOptions stores browser launch options for persistent context.
@@ -61,10 +128,12 @@ type LaunchPersistentContextOptions struct {
 
 func NewLaunchOptions() *LaunchOptions {
 	launchOpts := LaunchOptions{
-		Env:               make(map[string]string),
-		Headless:          true,
-		LogCategoryFilter: ".*",
-		Timeout:           DefaultTimeout,
+		Env:                   make(map[string]string),
+		EvaluateMaxBinarySize: DefaultEvaluateMaxBinarySize,
+		EvaluateMaxDepth:      DefaultEvaluateMaxDepth,
+		Headless:              true,
+		LogCategoryFilter:     ".*",
+		Timeout:               DefaultTimeout,
 	}
 	return &launchOpts
 }
@@ -83,6 +152,10 @@ func (l *LaunchOptions) Parse(ctx context.Context, opts goja.Value) error {
 						l.Args = append(l.Args, fmt.Sprintf("%v", argv))
 					}
 				}
+			case "artifactsDir":
+				l.ArtifactsDir = opts.Get(k).String()
+			case "autoRestart":
+				l.AutoRestart = opts.Get(k).ToBoolean()
 			case "debug":
 				l.Debug = opts.Get(k).ToBoolean()
 			case "devtools":
@@ -96,6 +169,10 @@ func (l *LaunchOptions) Parse(ctx context.Context, opts goja.Value) error {
 						l.Env[k] = env.Get(k).String()
 					}
 				}
+			case "evaluateMaxBinarySize":
+				l.EvaluateMaxBinarySize = opts.Get(k).ToInteger()
+			case "evaluateMaxDepth":
+				l.EvaluateMaxDepth = opts.Get(k).ToInteger()
 			case "executablePath":
 				l.ExecutablePath = opts.Get(k).String()
 			case "headless":
@@ -107,8 +184,12 @@ func (l *LaunchOptions) Parse(ctx context.Context, opts goja.Value) error {
 					args := v.Export().([]string)
 					l.IgnoreDefaultArgs = append(l.IgnoreDefaultArgs, args...)
 				}
+			case "killOnError":
+				l.KillOnError = opts.Get(k).ToBoolean()
 			case "logCategoryFilter":
 				l.LogCategoryFilter = opts.Get(k).String()
+			case "outputDir":
+				l.OutputDir = opts.Get(k).String()
 			case "proxy":
 				v := opts.Get(k)
 				switch v.ExportType() {
@@ -125,6 +206,10 @@ func (l *LaunchOptions) Parse(ctx context.Context, opts goja.Value) error {
 						l.Proxy.Password = env.Get(k).String()
 					}
 				}
+			case "reuseBrowser":
+				l.ReuseBrowser = opts.Get(k).ToBoolean()
+			case "reusePage":
+				l.ReusePage = opts.Get(k).ToBoolean()
 			case "slowMo":
 				l.SlowMo, _ = time.ParseDuration(opts.Get(k).String())
 			case "timeout":
@@ -132,5 +217,54 @@ func (l *LaunchOptions) Parse(ctx context.Context, opts goja.Value) error {
 			}
 		}
 	}
+	// the page can only be reused if its browser is.
+	if l.ReusePage {
+		l.ReuseBrowser = true
+	}
+	return nil
+}
+
+// ConnectOptions stores the options of connecting to a running browser.
+// The launch options that configure the browser process don't apply.
+type ConnectOptions struct {
+	LaunchOptions
+
+	// CloseOnDisconnect closes the running browser when it's disconnected
+	// with browser.close(), instead of leaving it running.
+	CloseOnDisconnect bool
+}
+
+// NewConnectOptions returns the default connect options.
+func NewConnectOptions() *ConnectOptions {
+	return &ConnectOptions{
+		LaunchOptions: *NewLaunchOptions(),
+	}
+}
+
+// Parse parses the connect options from a JS object.
+func (c *ConnectOptions) Parse(ctx context.Context, opts goja.Value) error {
+	if !gojaValueExists(opts) {
+		return nil
+	}
+	rt := k6ext.Runtime(ctx)
+	obj := opts.ToObject(rt)
+	for _, k := range obj.Keys() {
+		switch k {
+		case "artifactsDir":
+			c.ArtifactsDir = obj.Get(k).String()
+		case "closeOnDisconnect":
+			c.CloseOnDisconnect = obj.Get(k).ToBoolean()
+		case "debug":
+			c.Debug = obj.Get(k).ToBoolean()
+		case "headless":
+			c.Headless = obj.Get(k).ToBoolean()
+		case "logCategoryFilter":
+			c.LogCategoryFilter = obj.Get(k).String()
+		case "slowMo":
+			c.SlowMo, _ = time.ParseDuration(obj.Get(k).String())
+		case "timeout":
+			c.Timeout, _ = time.ParseDuration(obj.Get(k).String())
+		}
+	}
 	return nil
 }
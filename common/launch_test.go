@@ -2,6 +2,7 @@ package common
 
 import (
 	"testing"
+	"time"
 
 	"github.com/grafana/xk6-browser/k6ext/k6test"
 
@@ -30,6 +31,71 @@ func TestLaunchOptionsParse(t *testing.T) {
 				assert.Equal(t, "browser-flag", lopts.Args[2])
 			},
 		},
+		{
+			name: "evaluateMaxBinarySize",
+			opts: map[string]interface{}{
+				"evaluateMaxBinarySize": 1024,
+			},
+			assert: func(t *testing.T, lopts *LaunchOptions) {
+				assert.Equal(t, int64(1024), lopts.EvaluateMaxBinarySize)
+			},
+		},
+		{
+			name: "evaluateMaxDepth",
+			opts: map[string]interface{}{
+				"evaluateMaxDepth": 10,
+			},
+			assert: func(t *testing.T, lopts *LaunchOptions) {
+				assert.Equal(t, int64(10), lopts.EvaluateMaxDepth)
+			},
+		},
+		{
+			name: "killOnError",
+			opts: map[string]interface{}{
+				"killOnError": true,
+			},
+			assert: func(t *testing.T, lopts *LaunchOptions) {
+				assert.True(t, lopts.KillOnError)
+			},
+		},
+		{
+			name: "outputDir",
+			opts: map[string]interface{}{
+				"outputDir": "browser-logs",
+			},
+			assert: func(t *testing.T, lopts *LaunchOptions) {
+				assert.Equal(t, "browser-logs", lopts.OutputDir)
+			},
+		},
+		{
+			name: "reuseBrowser",
+			opts: map[string]interface{}{
+				"reuseBrowser": true,
+			},
+			assert: func(t *testing.T, lopts *LaunchOptions) {
+				assert.True(t, lopts.ReuseBrowser)
+			},
+		},
+		{
+			name: "reusePage",
+			opts: map[string]interface{}{
+				"reusePage": true,
+			},
+			assert: func(t *testing.T, lopts *LaunchOptions) {
+				assert.True(t, lopts.ReusePage)
+				assert.True(t, lopts.ReuseBrowser, "reusing the page requires reusing the browser")
+			},
+		},
+		{
+			name: "defaults",
+			opts: map[string]interface{}{},
+			assert: func(t *testing.T, lopts *LaunchOptions) {
+				assert.Equal(t, DefaultEvaluateMaxBinarySize, lopts.EvaluateMaxBinarySize)
+				assert.Equal(t, DefaultEvaluateMaxDepth, lopts.EvaluateMaxDepth)
+				assert.False(t, lopts.ReuseBrowser)
+				assert.False(t, lopts.KillOnError)
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -46,3 +112,25 @@ func TestLaunchOptionsParse(t *testing.T) {
 		})
 	}
 }
+
+func TestConnectOptionsParse(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+	copts := NewConnectOptions()
+	require.NoError(t, copts.Parse(vu.Context(), nil))
+	assert.False(t, copts.CloseOnDisconnect)
+	assert.Equal(t, DefaultTimeout, copts.Timeout)
+
+	opts := vu.ToGojaValue(map[string]interface{}{
+		"closeOnDisconnect": true,
+		"slowMo":            "100ms",
+		"timeout":           "10s",
+		"args":              []interface{}{"ignored"},
+	})
+	require.NoError(t, copts.Parse(vu.Context(), opts))
+	assert.True(t, copts.CloseOnDisconnect)
+	assert.Equal(t, 100*time.Millisecond, copts.SlowMo)
+	assert.Equal(t, 10*time.Second, copts.Timeout)
+	assert.Empty(t, copts.Args)
+}
@@ -35,7 +35,7 @@ func (l *Locator) Click(opts goja.Value) {
 	l.log.Debugf("Locator:Click", "fid:%s furl:%q sel:%q opts:%+v", l.frame.ID(), l.frame.URL(), l.selector, opts)
 
 	var err error
-	defer func() { panicOrSlowMo(l.ctx, err) }()
+	defer func() { panicOnError(l.ctx, err) }()
 
 	copts := NewFrameClickOptions(l.frame.defaultTimeout())
 	if err = copts.Parse(l.ctx, opts); err != nil {
@@ -60,7 +60,7 @@ func (l *Locator) Dblclick(opts goja.Value) {
 	l.log.Debugf("Locator:Dblclick", "fid:%s furl:%q sel:%q opts:%+v", l.frame.ID(), l.frame.URL(), l.selector, opts)
 
 	var err error
-	defer func() { panicOrSlowMo(l.ctx, err) }()
+	defer func() { panicOnError(l.ctx, err) }()
 
 	copts := NewFrameDblClickOptions(l.frame.defaultTimeout())
 	if err = copts.Parse(l.ctx, opts); err != nil {
@@ -85,7 +85,7 @@ func (l *Locator) Check(opts goja.Value) {
 	l.log.Debugf("Locator:Check", "fid:%s furl:%q sel:%q opts:%+v", l.frame.ID(), l.frame.URL(), l.selector, opts)
 
 	var err error
-	defer func() { panicOrSlowMo(l.ctx, err) }()
+	defer func() { panicOnError(l.ctx, err) }()
 
 	copts := NewFrameCheckOptions(l.frame.defaultTimeout())
 	if err = copts.Parse(l.ctx, opts); err != nil {
@@ -110,7 +110,7 @@ func (l *Locator) Uncheck(opts goja.Value) {
 	l.log.Debugf("Locator:Uncheck", "fid:%s furl:%q sel:%q opts:%+v", l.frame.ID(), l.frame.URL(), l.selector, opts)
 
 	var err error
-	defer func() { panicOrSlowMo(l.ctx, err) }()
+	defer func() { panicOnError(l.ctx, err) }()
 
 	copts := NewFrameUncheckOptions(l.frame.defaultTimeout())
 	if err = copts.Parse(l.ctx, opts); err != nil {
@@ -487,7 +487,7 @@ func (l *Locator) Press(key string, opts goja.Value) {
 	)
 
 	var err error
-	defer func() { panicOrSlowMo(l.ctx, err) }()
+	defer func() { panicOnError(l.ctx, err) }()
 
 	copts := NewFramePressOptions(l.frame.defaultTimeout())
 	if err = copts.Parse(l.ctx, opts); err != nil {
@@ -513,7 +513,7 @@ func (l *Locator) Type(text string, opts goja.Value) {
 	)
 
 	var err error
-	defer func() { panicOrSlowMo(l.ctx, err) }()
+	defer func() { panicOnError(l.ctx, err) }()
 
 	copts := NewFrameTypeOptions(l.frame.defaultTimeout())
 	if err = copts.Parse(l.ctx, opts); err != nil {
@@ -536,7 +536,7 @@ func (l *Locator) Hover(opts goja.Value) {
 	l.log.Debugf("Locator:Hover", "fid:%s furl:%q sel:%q opts:%+v", l.frame.ID(), l.frame.URL(), l.selector, opts)
 
 	var err error
-	defer func() { panicOrSlowMo(l.ctx, err) }()
+	defer func() { panicOnError(l.ctx, err) }()
 
 	copts := NewFrameHoverOptions(l.frame.defaultTimeout())
 	if err = copts.Parse(l.ctx, opts); err != nil {
@@ -558,7 +558,7 @@ func (l *Locator) Tap(opts goja.Value) {
 	l.log.Debugf("Locator:Tap", "fid:%s furl:%q sel:%q opts:%+v", l.frame.ID(), l.frame.URL(), l.selector, opts)
 
 	var err error
-	defer func() { panicOrSlowMo(l.ctx, err) }()
+	defer func() { panicOnError(l.ctx, err) }()
 
 	copts := NewFrameTapOptions(l.frame.defaultTimeout())
 	if err = copts.Parse(l.ctx, opts); err != nil {
@@ -619,3 +619,63 @@ func (l *Locator) waitFor(opts *FrameWaitForSelectorOptions) error {
 	_, err := l.frame.waitForSelector(l.selector, opts)
 	return err
 }
+
+// Screenshot takes a screenshot of the element matching the locator's
+// selector with strict mode on.
+func (l *Locator) Screenshot(opts goja.Value) goja.ArrayBuffer {
+	l.log.Debugf("Locator:Screenshot", "fid:%s furl:%q sel:%q opts:%+v", l.frame.ID(), l.frame.URL(), l.selector, opts)
+
+	popts := NewElementHandleScreenshotOptions(l.frame.defaultTimeout())
+	if err := popts.Parse(l.ctx, opts); err != nil {
+		k6ext.Panic(l.ctx, "parse: %w", err)
+	}
+	buf, err := l.screenshot(popts)
+	if err != nil {
+		k6ext.Panic(l.ctx, "screenshot: %w", err)
+	}
+
+	return k6ext.Runtime(l.ctx).NewArrayBuffer(buf)
+}
+
+func (l *Locator) screenshot(opts *ElementHandleScreenshotOptions) ([]byte, error) {
+	h, err := l.frame.waitForSelector(l.selector, &FrameWaitForSelectorOptions{
+		State:   DOMElementStateAttached,
+		Strict:  true,
+		Timeout: opts.Timeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = h.dispose() }()
+
+	opts.Path = l.frame.page.artifactPath(opts.Path)
+	buf, err := newScreenshotter(l.ctx).screenshotElement(h, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return *buf, nil
+}
+
+// count returns the number of the elements that match the locator's selector.
+func (l *Locator) count() (int, error) {
+	document, err := l.frame.document()
+	if err != nil {
+		return 0, fmt.Errorf("getting document: %w", err)
+	}
+	handles, err := document.queryAll(l.selector, document.evalWithScript)
+	if err != nil {
+		return 0, err
+	}
+	for _, h := range handles {
+		h.Dispose()
+	}
+
+	return len(handles), nil
+}
+
+// Expect returns the assertions of the elements that match
+// the locator's selector.
+func (l *Locator) Expect() *Expectation {
+	return NewExpectation(l.ctx, l, l.log)
+}
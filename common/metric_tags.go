@@ -0,0 +1,90 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package common
+
+import (
+	"context"
+
+	k6lib "go.k6.io/k6/lib"
+	k6metrics "go.k6.io/k6/metrics"
+)
+
+// vuMetricTags returns the tags of the metrics of a VU, with the
+// group and the scenario that the VU is running, if the system
+// tags include them.
+func vuMetricTags(ctx context.Context, state *k6lib.State) map[string]string {
+	tags := state.CloneTags()
+	if state.Options.SystemTags.Has(k6metrics.TagGroup) && state.Group != nil {
+		tags["group"] = state.Group.Path
+	}
+	if state.Options.SystemTags.Has(k6metrics.TagScenario) {
+		if ss := k6lib.GetScenarioState(ctx); ss != nil && tags["scenario"] == "" {
+			tags["scenario"] = ss.Name
+		}
+	}
+	return tags
+}
+
+// metricTags returns the tags of the metrics of the page, with the group
+// and the scenario that were active when the last navigation of the page
+// started, so that the metrics of a page load are attributed to the group
+// that loaded it, even if the script moves on to another group meanwhile.
+func (p *Page) metricTags() map[string]string {
+	p.metricTagsMu.RLock()
+	defer p.metricTagsMu.RUnlock()
+
+	if p.navigationTags == nil {
+		return vuMetricTags(p.ctx, p.vu.State())
+	}
+	tags := make(map[string]string, len(p.navigationTags))
+	for k, v := range p.navigationTags {
+		tags[k] = v
+	}
+	return tags
+}
+
+// startNavigation records the tags of the metrics of the navigation that
+// the script starts. It must be called on the event loop, since the group
+// of the VU changes there.
+func (p *Page) startNavigation() {
+	tags := vuMetricTags(p.ctx, p.vu.State())
+
+	p.metricTagsMu.Lock()
+	defer p.metricTagsMu.Unlock()
+	p.navigationTags = tags
+}
+
+// metricTags returns the tags of the metrics of the frame, which are
+// the tags of its page, or the tags of the VU if it has no page.
+func (f *Frame) metricTags() map[string]string {
+	if f.page == nil {
+		return vuMetricTags(f.ctx, f.vu.State())
+	}
+	return f.page.metricTags()
+}
+
+// startNavigation records the tags of the metrics of the navigation
+// that the script starts in the frame.
+func (f *Frame) startNavigation() {
+	if f.page != nil {
+		f.page.startNavigation()
+	}
+}
@@ -0,0 +1,47 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/grafana/xk6-browser/k6ext/k6test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	k6lib "go.k6.io/k6/lib"
+)
+
+func TestPageMetricTags(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+	ctx := k6lib.WithScenarioState(vu.Context(), &k6lib.ScenarioState{Name: "journey"})
+	state := vu.State()
+	p := &Page{ctx: ctx, vu: vu}
+
+	// enter sets the group of the VU, like the group function of k6 does.
+	enter := func(g *k6lib.Group) {
+		state.Group = g
+		state.Tags.Set("group", g.Path)
+	}
+	root := state.Group
+	outer, err := root.Group("outer")
+	require.NoError(t, err)
+	inner, err := outer.Group("inner")
+	require.NoError(t, err)
+
+	// the tags follow the VU until a navigation starts.
+	enter(outer)
+	assert.Equal(t, "::outer", p.metricTags()["group"])
+	assert.Equal(t, "journey", p.metricTags()["scenario"])
+
+	enter(inner)
+	p.startNavigation()
+	enter(root)
+	tags := p.metricTags()
+	assert.Equal(t, "::outer::inner", tags["group"])
+	assert.Equal(t, "journey", tags["scenario"])
+
+	// the tags are copies.
+	tags["url"] = "https://example.com"
+	assert.NotContains(t, p.metricTags(), "url")
+}
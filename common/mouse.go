@@ -22,11 +22,16 @@ package common
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
 	"time"
 
 	"github.com/grafana/xk6-browser/api"
 	"github.com/grafana/xk6-browser/k6ext"
 
+	"github.com/chromedp/cdproto"
 	"github.com/chromedp/cdproto/cdp"
 	"github.com/chromedp/cdproto/input"
 	"github.com/dop251/goja"
@@ -42,9 +47,32 @@ type Mouse struct {
 	frame           *Frame
 	timeoutSettings *TimeoutSettings
 	keyboard        *Keyboard
-	x               float64
-	y               float64
-	button          input.MouseButton
+
+	// protects the pointer state from the actions that run concurrently,
+	// such as the ones of a promise and the event loop.
+	mu      sync.Mutex
+	x       float64
+	y       float64
+	button  input.MouseButton // last pressed button
+	buttons int64             // bitmask of the pressed buttons
+}
+
+// mouseButtonBits are the bits of the mouse buttons in the pressed buttons
+// bitmask, as in the MouseEvent.buttons property of the DOM.
+var mouseButtonBits = map[input.MouseButton]int64{ //nolint:gochecknoglobals
+	input.Left:    1,
+	input.Right:   2,
+	input.Middle:  4,
+	input.Back:    8,
+	input.Forward: 16,
+}
+
+func mouseButtonBit(button string) (int64, error) {
+	bit, ok := mouseButtonBits[input.MouseButton(button)]
+	if !ok {
+		return 0, fmt.Errorf("invalid mouse button %q: must be one of: left, right, middle, back, forward", button)
+	}
+	return bit, nil
 }
 
 // NewMouse creates a new mouse.
@@ -59,43 +87,22 @@ func NewMouse(ctx context.Context, s session, f *Frame, ts *TimeoutSettings, k *
 	}
 }
 
+// click moves the mouse to the given position and clicks it as many times as
+// the click count option. The click count of the dispatched events increments
+// with each click, so that the browser can recognize double and triple clicks.
 func (m *Mouse) click(x float64, y float64, opts *MouseClickOptions) error {
-	mouseDownUpOpts := opts.ToMouseDownUpOptions()
-	if err := m.move(x, y, NewMouseMoveOptions()); err != nil {
-		return err
-	}
-	if err := m.down(x, y, mouseDownUpOpts); err != nil {
-		return err
-	}
-	if opts.Delay != 0 {
-		t := time.NewTimer(time.Duration(opts.Delay) * time.Millisecond)
-		select {
-		case <-m.ctx.Done():
-			t.Stop()
-		case <-t.C:
-		}
-	}
-	if err := m.up(x, y, mouseDownUpOpts); err != nil {
-		return err
-	}
-	return nil
-}
-
-func (m *Mouse) dblClick(x float64, y float64, opts *MouseDblClickOptions) error {
-	mouseDownUpOpts := opts.ToMouseDownUpOptions()
-	if err := m.move(x, y, NewMouseMoveOptions()); err != nil {
+	if err := m.move(m.ctx, x, y, opts.ToMouseMoveOptions()); err != nil {
 		return err
 	}
-	for i := 0; i < 2; i++ {
+	for i := int64(1); i <= opts.ClickCount; i++ {
+		mouseDownUpOpts := opts.ToMouseDownUpOptions()
+		mouseDownUpOpts.ClickCount = i
 		if err := m.down(x, y, mouseDownUpOpts); err != nil {
 			return err
 		}
 		if opts.Delay != 0 {
-			t := time.NewTimer(time.Duration(opts.Delay) * time.Millisecond)
-			select {
-			case <-m.ctx.Done():
-				t.Stop()
-			case <-t.C:
+			if err := sleep(m.ctx, time.Duration(opts.Delay)*time.Millisecond); err != nil {
+				return err
 			}
 		}
 		if err := m.up(x, y, mouseDownUpOpts); err != nil {
@@ -105,11 +112,25 @@ func (m *Mouse) dblClick(x float64, y float64, opts *MouseDblClickOptions) error
 	return nil
 }
 
+func (m *Mouse) dblClick(x float64, y float64, opts *MouseDblClickOptions) error {
+	return m.click(x, y, opts.ToMouseClickOptions())
+}
+
 func (m *Mouse) down(x float64, y float64, opts *MouseDownUpOptions) error {
+	bit, err := mouseButtonBit(opts.Button)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
 	m.button = input.MouseButton(opts.Button)
-	action := input.DispatchMouseEvent(input.MousePressed, m.x, m.y).
+	m.buttons |= bit
+	mx, my, buttons := m.x, m.y, m.buttons
+	m.mu.Unlock()
+
+	action := input.DispatchMouseEvent(input.MousePressed, mx, my).
 		WithButton(input.MouseButton(opts.Button)).
-		WithModifiers(input.Modifier(m.keyboard.modifiers)).
+		WithButtons(buttons).
+		WithModifiers(input.Modifier(m.keyboard.getModifiers())).
 		WithClickCount(opts.ClickCount)
 	if err := action.Do(cdp.WithExecutor(m.ctx, m.session)); err != nil {
 		return err
@@ -117,38 +138,135 @@ func (m *Mouse) down(x float64, y float64, opts *MouseDownUpOptions) error {
 	return nil
 }
 
-func (m *Mouse) move(x float64, y float64, opts *MouseMoveOptions) error {
-	var fromX float64 = m.x
-	var fromY float64 = m.y
-	m.x = x
-	m.y = y
-	for i := int64(1); i <= opts.Steps; i++ {
-		x := fromX + (m.x-fromX)*float64(i/opts.Steps)
-		y := fromY + (m.y-fromY)*float64(i/opts.Steps)
-		action := input.DispatchMouseEvent(input.MouseMoved, x, y).
-			WithButton(m.button).
-			WithModifiers(input.Modifier(m.keyboard.modifiers))
-		if err := action.Do(cdp.WithExecutor(m.ctx, m.session)); err != nil {
+// move moves the mouse to the given position along a path of opts.Steps
+// steps. The step delays and the events follow ctx, which is the context of
+// the action that moves the mouse. The position follows the path, so that
+// it stays where the mouse is if the movement fails midway.
+func (m *Mouse) move(ctx context.Context, x float64, y float64, opts *MouseMoveOptions) error {
+	m.mu.Lock()
+	fromX, fromY, button, buttons := m.x, m.y, m.button, m.buttons
+	m.mu.Unlock()
+
+	var path []mousePathStep
+	if opts.Movement == MouseMovementHuman {
+		seed := time.Now().UnixNano()
+		if opts.Seed != nil {
+			seed = *opts.Seed
+		}
+		path = humanMousePath(rand.New(rand.NewSource(seed)), fromX, fromY, x, y, opts.Steps) //nolint:gosec
+	} else {
+		path = linearMousePath(fromX, fromY, x, y, opts.Steps)
+	}
+	for _, step := range path {
+		if step.delay > 0 {
+			if err := sleep(ctx, step.delay); err != nil {
+				return err
+			}
+		}
+		action := input.DispatchMouseEvent(input.MouseMoved, step.x, step.y).
+			WithButton(button).
+			WithButtons(buttons).
+			WithModifiers(input.Modifier(m.keyboard.getModifiers()))
+		if err := action.Do(cdp.WithExecutor(ctx, m.session)); err != nil {
 			return err
 		}
+		m.mu.Lock()
+		m.x, m.y = step.x, step.y
+		m.mu.Unlock()
 	}
 	return nil
 }
 
 func (m *Mouse) up(x float64, y float64, opts *MouseDownUpOptions) error {
-	var button input.MouseButton = input.Left
-	var clickCount int64 = 1
+	bit, err := mouseButtonBit(opts.Button)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.buttons &= ^bit
 	m.button = input.None
-	action := input.DispatchMouseEvent(input.MouseReleased, m.x, m.y).
-		WithButton(button).
-		WithModifiers(input.Modifier(m.keyboard.modifiers)).
-		WithClickCount(clickCount)
+	mx, my, buttons := m.x, m.y, m.buttons
+	m.mu.Unlock()
+
+	action := input.DispatchMouseEvent(input.MouseReleased, mx, my).
+		WithButton(input.MouseButton(opts.Button)).
+		WithButtons(buttons).
+		WithModifiers(input.Modifier(m.keyboard.getModifiers())).
+		WithClickCount(opts.ClickCount)
 	if err := action.Do(cdp.WithExecutor(m.ctx, m.session)); err != nil {
 		return err
 	}
 	return nil
 }
 
+// dragAndDrop moves the mouse, while its left button is pressed, to the drop
+// position. It intercepts the native drag operation that the move may start
+// and replays it with drag events, as synthesized mouse events cannot drive
+// native drags. The started function reports whether the move started a drag.
+// If it didn't, the mouse button is released as usual.
+func (m *Mouse) dragAndDrop(
+	apiCtx context.Context, x float64, y float64, opts *MouseMoveOptions,
+	started func(context.Context) (bool, error),
+) error {
+	evCtx, evCancel := context.WithCancel(apiCtx)
+	defer evCancel()
+	ch := make(chan Event)
+	m.session.on(evCtx, []string{cdproto.EventInputDragIntercepted}, ch)
+
+	if err := input.SetInterceptDrags(true).Do(cdp.WithExecutor(apiCtx, m.session)); err != nil {
+		return fmt.Errorf("enabling drag interception: %w", err)
+	}
+	defer func() {
+		_ = input.SetInterceptDrags(false).Do(cdp.WithExecutor(m.ctx, m.session))
+	}()
+
+	if err := m.move(apiCtx, x, y, opts); err != nil {
+		return err
+	}
+	ok, err := started(apiCtx)
+	if err != nil {
+		return fmt.Errorf("checking drag start: %w", err)
+	}
+	if !ok {
+		return m.up(x, y, NewMouseDownUpOptions())
+	}
+
+	var data *input.DragData
+	select {
+	case <-apiCtx.Done():
+		return apiCtx.Err()
+	case ev := <-ch:
+		if ev, ok := ev.data.(*input.EventDragIntercepted); ok {
+			data = ev.Data
+		}
+	}
+	if data == nil {
+		return errors.New("intercepted drag has no data")
+	}
+
+	return m.drop(x, y, data)
+}
+
+// drop dispatches the drag events of dropping the dragged data at the
+// given position, which ends the drag and releases the left mouse button.
+func (m *Mouse) drop(x float64, y float64, data *input.DragData) error {
+	for _, typ := range []input.DispatchDragEventType{
+		input.DragEnter, input.DragOver, input.Drop,
+	} {
+		action := input.DispatchDragEvent(typ, x, y, data).
+			WithModifiers(input.Modifier(m.keyboard.getModifiers()))
+		if err := action.Do(cdp.WithExecutor(m.ctx, m.session)); err != nil {
+			return fmt.Errorf("dispatching drag event %q: %w", typ, err)
+		}
+	}
+	m.mu.Lock()
+	m.buttons &= ^mouseButtonBits[input.Left]
+	m.button = input.None
+	m.mu.Unlock()
+
+	return nil
+}
+
 // Click will trigger a series of MouseMove, MouseDown and MouseUp events in the browser.
 func (m *Mouse) Click(x float64, y float64, opts goja.Value) {
 	mouseOpts := NewMouseClickOptions()
@@ -181,17 +299,37 @@ func (m *Mouse) Down(x float64, y float64, opts goja.Value) {
 	}
 }
 
+// IsPressed returns true if the given mouse button is currently pressed.
+func (m *Mouse) IsPressed(button string) bool {
+	bit, err := mouseButtonBit(button)
+	if err != nil {
+		k6ext.Panic(m.ctx, "checking mouse button: %w", err)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.buttons&bit != 0
+}
+
 // Move will trigger a MouseMoved event in the browser.
 func (m *Mouse) Move(x float64, y float64, opts goja.Value) {
-	mouseOpts := NewMouseDownUpOptions()
+	mouseOpts := NewMouseMoveOptions()
 	if err := mouseOpts.Parse(m.ctx, opts); err != nil {
 		k6ext.Panic(m.ctx, "parsing move options: %w", err)
 	}
-	if err := m.down(x, y, mouseOpts); err != nil {
+	if err := m.move(m.ctx, x, y, mouseOpts); err != nil {
 		k6ext.Panic(m.ctx, "mouse move: %w", err)
 	}
 }
 
+// Position returns the current position of the mouse.
+func (m *Mouse) Position() *api.Position {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return &api.Position{X: m.x, Y: m.y}
+}
+
 // Up will trigger a MouseUp event in the browser.
 func (m *Mouse) Up(x float64, y float64, opts goja.Value) {
 	mouseOpts := NewMouseDownUpOptions()
@@ -22,6 +22,7 @@ package common
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/dop251/goja"
 
@@ -29,9 +30,11 @@ import (
 )
 
 type MouseClickOptions struct {
-	Button     string `json:"button"`
-	ClickCount int64  `json:"clickCount"`
-	Delay      int64  `json:"delay"`
+	Button     string        `json:"button"`
+	ClickCount int64         `json:"clickCount"`
+	Delay      int64         `json:"delay"`
+	Movement   MouseMovement `json:"movement"`
+	Seed       *int64        `json:"seed"`
 }
 
 type MouseDblClickOptions struct {
@@ -45,7 +48,29 @@ type MouseDownUpOptions struct {
 }
 
 type MouseMoveOptions struct {
-	Steps int64 `json:"steps"`
+	Steps    int64         `json:"steps"`
+	Movement MouseMovement `json:"movement"`
+	Seed     *int64        `json:"seed"`
+}
+
+// MouseMovement is the style of the path of mouse moves.
+type MouseMovement string
+
+const (
+	// MouseMovementLinear moves the mouse in a straight line,
+	// with evenly spaced steps dispatched right away.
+	MouseMovementLinear MouseMovement = "linear"
+	// MouseMovementHuman moves the mouse along a curved path,
+	// with variable timing between the steps.
+	MouseMovementHuman MouseMovement = "human"
+)
+
+func parseMouseMovement(v string) (MouseMovement, error) {
+	switch m := MouseMovement(v); m {
+	case MouseMovementLinear, MouseMovementHuman:
+		return m, nil
+	}
+	return "", fmt.Errorf("invalid movement %q: must be one of: %s, %s", v, MouseMovementLinear, MouseMovementHuman)
 }
 
 func NewMouseClickOptions() *MouseClickOptions {
@@ -53,6 +78,7 @@ func NewMouseClickOptions() *MouseClickOptions {
 		Button:     "left",
 		ClickCount: 1,
 		Delay:      0,
+		Movement:   MouseMovementLinear,
 	}
 }
 
@@ -68,10 +94,26 @@ func (o *MouseClickOptions) Parse(ctx context.Context, opts goja.Value) error {
 				o.ClickCount = opts.Get(k).ToInteger()
 			case "delay":
 				o.Delay = opts.Get(k).ToInteger()
+			case "movement":
+				m, err := parseMouseMovement(opts.Get(k).String())
+				if err != nil {
+					return err
+				}
+				o.Movement = m
+			case "seed":
+				seed := opts.Get(k).ToInteger()
+				o.Seed = &seed
 			}
 		}
 	}
-	return nil
+	return validateClickCount(o.ClickCount)
+}
+
+func (o *MouseClickOptions) ToMouseMoveOptions() *MouseMoveOptions {
+	o2 := NewMouseMoveOptions()
+	o2.Movement = o.Movement
+	o2.Seed = o.Seed
+	return o2
 }
 
 func (o *MouseClickOptions) ToMouseDownUpOptions() *MouseDownUpOptions {
@@ -81,6 +123,17 @@ func (o *MouseClickOptions) ToMouseDownUpOptions() *MouseDownUpOptions {
 	return o2
 }
 
+// maxClickCount is the maximum number of clicks in a row that browsers
+// recognize as a multi-click, i.e. a triple click.
+const maxClickCount = 3
+
+func validateClickCount(n int64) error {
+	if n < 1 || n > maxClickCount {
+		return fmt.Errorf("clickCount must be between 1 and %d, got %d", maxClickCount, n)
+	}
+	return nil
+}
+
 func NewMouseDblClickOptions() *MouseDblClickOptions {
 	return &MouseDblClickOptions{
 		Button: "left",
@@ -104,9 +157,11 @@ func (o *MouseDblClickOptions) Parse(ctx context.Context, opts goja.Value) error
 	return nil
 }
 
-func (o *MouseDblClickOptions) ToMouseDownUpOptions() *MouseDownUpOptions {
-	o2 := NewMouseDownUpOptions()
+func (o *MouseDblClickOptions) ToMouseClickOptions() *MouseClickOptions {
+	o2 := NewMouseClickOptions()
 	o2.Button = o.Button
+	o2.ClickCount = 2
+	o2.Delay = o.Delay
 	return o2
 }
 
@@ -135,7 +190,8 @@ func (o *MouseDownUpOptions) Parse(ctx context.Context, opts goja.Value) error {
 
 func NewMouseMoveOptions() *MouseMoveOptions {
 	return &MouseMoveOptions{
-		Steps: 1,
+		Steps:    1,
+		Movement: MouseMovementLinear,
 	}
 }
 
@@ -147,6 +203,15 @@ func (o *MouseMoveOptions) Parse(ctx context.Context, opts goja.Value) error {
 			switch k {
 			case "steps":
 				o.Steps = opts.Get(k).ToInteger()
+			case "movement":
+				m, err := parseMouseMovement(opts.Get(k).String())
+				if err != nil {
+					return err
+				}
+				o.Movement = m
+			case "seed":
+				seed := opts.Get(k).ToInteger()
+				o.Seed = &seed
 			}
 		}
 	}
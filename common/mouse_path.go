@@ -0,0 +1,132 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package common
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+const (
+	// humanMouseMinSteps and humanMouseMaxSteps bound the number of steps
+	// of a human-like mouse move, when the steps option is not set.
+	humanMouseMinSteps = 10
+	humanMouseMaxSteps = 50
+	// humanMousePixelsPerStep is the approximate distance between the steps
+	// of a human-like mouse move, when the steps option is not set.
+	humanMousePixelsPerStep = 20
+	// humanMouseMaxCurve is the maximum distance of the path curve
+	// control points from the straight line, relative to its length.
+	humanMouseMaxCurve = 0.3
+	// humanMouseMinStepDelay and humanMouseMaxStepDelay bound the delay
+	// between the steps of a human-like mouse move.
+	humanMouseMinStepDelay = 2 * time.Millisecond
+	humanMouseMaxStepDelay = 12 * time.Millisecond
+)
+
+// mousePathStep is a point on a mouse path, and the delay
+// before the mouse moves to it.
+type mousePathStep struct {
+	x, y  float64
+	delay time.Duration
+}
+
+// humanMousePath returns a human-like mouse path from (fromX, fromY) to
+// (toX, toY). The path is a cubic Bézier curve that bends sideways, with
+// steps that speed up in the middle of the move and slow down at its ends.
+//
+// The path always progresses towards its end, and its last step is exactly
+// at (toX, toY). If steps is less than 2, the number of steps depends on
+// the distance. The path only depends on the arguments, so the same seeded
+// rnd produces the same path.
+func humanMousePath(rnd *rand.Rand, fromX, fromY, toX, toY float64, steps int64) []mousePathStep {
+	dx, dy := toX-fromX, toY-fromY
+	dist := math.Hypot(dx, dy)
+	if steps < 2 {
+		steps = int64(dist / humanMousePixelsPerStep)
+		if steps < humanMouseMinSteps {
+			steps = humanMouseMinSteps
+		}
+		if steps > humanMouseMaxSteps {
+			steps = humanMouseMaxSteps
+		}
+	}
+
+	// The control points are placed along the straight line at a and b,
+	// such that 0 < a < b < 1, which keeps the progress along the line
+	// monotonic, and are offset perpendicular to it to curve the path.
+	a := 0.2 + rnd.Float64()*0.2
+	b := 0.6 + rnd.Float64()*0.2
+	// both offsets bend towards the same side to draw an arc.
+	side := 1.0
+	if rnd.Intn(2) == 0 {
+		side = -1
+	}
+	offA := side * rnd.Float64() * humanMouseMaxCurve * dist
+	offB := side * rnd.Float64() * humanMouseMaxCurve * dist
+	// unit normal of the straight line.
+	var nx, ny float64
+	if dist > 0 {
+		nx, ny = -dy/dist, dx/dist
+	}
+	c1x, c1y := fromX+dx*a+nx*offA, fromY+dy*a+ny*offA
+	c2x, c2y := fromX+dx*b+nx*offB, fromY+dy*b+ny*offB
+
+	path := make([]mousePathStep, 0, steps)
+	for i := int64(1); i <= steps; i++ {
+		t := easeInOut(float64(i) / float64(steps))
+		x := bezier(t, fromX, c1x, c2x, toX)
+		y := bezier(t, fromY, c1y, c2y, toY)
+		jitter := time.Duration(rnd.Int63n(int64(humanMouseMaxStepDelay - humanMouseMinStepDelay)))
+		path = append(path, mousePathStep{x: x, y: y, delay: humanMouseMinStepDelay + jitter})
+	}
+	// avoid floating point errors at the end of the path.
+	path[len(path)-1].x, path[len(path)-1].y = toX, toY
+
+	return path
+}
+
+// linearMousePath returns a straight mouse path from (fromX, fromY) to
+// (toX, toY) with evenly spaced steps and no delays between them.
+func linearMousePath(fromX, fromY, toX, toY float64, steps int64) []mousePathStep {
+	path := make([]mousePathStep, 0, steps)
+	for i := int64(1); i <= steps; i++ {
+		progress := float64(i) / float64(steps)
+		path = append(path, mousePathStep{
+			x: fromX + (toX-fromX)*progress,
+			y: fromY + (toY-fromY)*progress,
+		})
+	}
+	return path
+}
+
+// bezier returns the value of a cubic Bézier curve at t.
+func bezier(t, p0, p1, p2, p3 float64) float64 {
+	u := 1 - t
+	return u*u*u*p0 + 3*u*u*t*p1 + 3*u*t*t*p2 + t*t*t*p3
+}
+
+// easeInOut maps t in [0, 1] to a monotonic progress in [0, 1]
+// that is slower at both ends.
+func easeInOut(t float64) float64 {
+	return t * t * (3 - 2*t)
+}
@@ -0,0 +1,86 @@
+package common
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHumanMousePath(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name                   string
+		fromX, fromY, toX, toY float64
+		steps                  int64
+		wantSteps              int
+	}{
+		{name: "short", fromX: 0, fromY: 0, toX: 10, toY: 5, wantSteps: humanMouseMinSteps},
+		{name: "long", fromX: 10, fromY: 700, toX: 1900, toY: 20, wantSteps: humanMouseMaxSteps},
+		{name: "backwards", fromX: 500, fromY: 400, toX: 100, toY: 300, wantSteps: 20},
+		{name: "steps", fromX: 0, fromY: 0, toX: 300, toY: 300, steps: 7, wantSteps: 7},
+		{name: "same_point", fromX: 50, fromY: 50, toX: 50, toY: 50, wantSteps: humanMouseMinSteps},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			for seed := int64(0); seed < 100; seed++ {
+				rnd := rand.New(rand.NewSource(seed)) //nolint:gosec
+				path := humanMousePath(rnd, tc.fromX, tc.fromY, tc.toX, tc.toY, tc.steps)
+				require.Len(t, path, tc.wantSteps)
+
+				last := path[len(path)-1]
+				assert.Equal(t, tc.toX, last.x, "seed %d", seed)
+				assert.Equal(t, tc.toY, last.y, "seed %d", seed)
+
+				// the progress along the straight line never goes back.
+				dx, dy := tc.toX-tc.fromX, tc.toY-tc.fromY
+				prev := 0.0
+				for i, s := range path {
+					progress := (s.x-tc.fromX)*dx + (s.y-tc.fromY)*dy
+					assert.GreaterOrEqual(t, progress, prev, "seed %d, step %d", seed, i)
+					prev = progress
+
+					assert.GreaterOrEqual(t, s.delay, humanMouseMinStepDelay)
+					assert.Less(t, s.delay, humanMouseMaxStepDelay)
+				}
+			}
+		})
+	}
+}
+
+func TestHumanMousePathSeed(t *testing.T) {
+	t.Parallel()
+
+	newPath := func(seed int64) []mousePathStep {
+		rnd := rand.New(rand.NewSource(seed)) //nolint:gosec
+		return humanMousePath(rnd, 0, 0, 400, 300, 0)
+	}
+	assert.Equal(t, newPath(42), newPath(42), "same seed should produce the same path")
+	assert.NotEqual(t, newPath(42), newPath(43), "different seeds should produce different paths")
+
+	// the path should not be a straight line.
+	curved := false
+	for _, s := range newPath(42) {
+		if s.x*300 != s.y*400 {
+			curved = true
+			break
+		}
+	}
+	assert.True(t, curved, "expected a curved path")
+}
+
+func TestLinearMousePath(t *testing.T) {
+	t.Parallel()
+
+	path := linearMousePath(0, 10, 30, 40, 3)
+	assert.Equal(t, []mousePathStep{
+		{x: 10, y: 20},
+		{x: 20, y: 30},
+		{x: 30, y: 40},
+	}, path)
+}
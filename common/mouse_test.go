@@ -0,0 +1,265 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/grafana/xk6-browser/api"
+	"github.com/grafana/xk6-browser/k6ext/k6test"
+
+	"github.com/chromedp/cdproto/input"
+	"github.com/mailru/easyjson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mouseEventSession records the mouse events dispatched to it.
+// It fails to dispatch the events after failAfter events if it's set.
+type mouseEventSession struct {
+	session
+	mu        sync.Mutex
+	events    []*input.DispatchMouseEventParams
+	failAfter int
+}
+
+func (s *mouseEventSession) Execute(
+	_ context.Context, method string, params easyjson.Marshaler, _ easyjson.Unmarshaler,
+) error {
+	if p, ok := params.(*input.DispatchMouseEventParams); ok && method == input.CommandDispatchMouseEvent {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.failAfter > 0 && len(s.events) >= s.failAfter {
+			return errors.New("dispatch failed")
+		}
+		s.events = append(s.events, p)
+	}
+	return nil
+}
+
+func newTestMouse(t *testing.T) (*Mouse, *mouseEventSession) {
+	t.Helper()
+
+	vu := k6test.NewVU(t)
+	s := &mouseEventSession{}
+	m := NewMouse(vu.Context(), s, nil, nil, &Keyboard{})
+
+	return m, s
+}
+
+func TestMousePosition(t *testing.T) {
+	t.Parallel()
+
+	m, s := newTestMouse(t)
+	h := &ElementHandle{frame: &Frame{page: &Page{Mouse: m}}}
+
+	assert.Equal(t, &api.Position{X: 0, Y: 0}, m.Position())
+
+	require.NoError(t, m.move(m.ctx, 10, 20, NewMouseMoveOptions()))
+	assert.Equal(t, &api.Position{X: 10, Y: 20}, m.Position())
+
+	// element-targeted clicks move the mouse too.
+	require.NoError(t, h.click(&Position{X: 30, Y: 40}, NewMouseClickOptions()))
+	assert.Equal(t, &api.Position{X: 30, Y: 40}, m.Position())
+
+	moveOpts := NewMouseMoveOptions()
+	moveOpts.Steps = 2
+	require.NoError(t, m.move(m.ctx, 50, 60, moveOpts))
+	assert.Equal(t, &api.Position{X: 50, Y: 60}, m.Position())
+
+	var got []api.Position
+	for _, e := range s.events {
+		if e.Type == input.MouseMoved {
+			got = append(got, api.Position{X: e.X, Y: e.Y})
+		}
+	}
+	assert.Equal(t, []api.Position{
+		{X: 10, Y: 20},
+		{X: 30, Y: 40},
+		{X: 40, Y: 50}, // intermediate step
+		{X: 50, Y: 60},
+	}, got)
+}
+
+func TestMouseIsPressed(t *testing.T) {
+	t.Parallel()
+
+	m, s := newTestMouse(t)
+	h := &ElementHandle{frame: &Frame{page: &Page{Mouse: m}}}
+
+	right := NewMouseDownUpOptions()
+	right.Button = "right"
+	require.NoError(t, m.down(0, 0, right))
+	assert.True(t, m.IsPressed("right"))
+	assert.False(t, m.IsPressed("left"))
+
+	require.NoError(t, m.move(m.ctx, 5, 5, NewMouseMoveOptions()))
+	last := s.events[len(s.events)-1]
+	assert.Equal(t, int64(2), last.Buttons, "move should report the pressed buttons")
+
+	// a left click keeps the right button pressed.
+	require.NoError(t, h.click(&Position{X: 10, Y: 10}, NewMouseClickOptions()))
+	assert.True(t, m.IsPressed("right"))
+	assert.False(t, m.IsPressed("left"))
+
+	require.NoError(t, m.up(0, 0, right))
+	assert.False(t, m.IsPressed("right"))
+	last = s.events[len(s.events)-1]
+	assert.Equal(t, input.MouseReleased, last.Type)
+	assert.Equal(t, input.Right, last.Button)
+
+	assert.Error(t, m.down(0, 0, &MouseDownUpOptions{Button: "thumb"}))
+}
+
+func TestMouseClickCount(t *testing.T) {
+	t.Parallel()
+
+	m, s := newTestMouse(t)
+
+	opts := NewMouseClickOptions()
+	opts.ClickCount = 3
+	require.NoError(t, m.click(10, 10, opts))
+
+	var got []int64
+	for _, e := range s.events {
+		if e.Type != input.MouseMoved {
+			got = append(got, e.ClickCount)
+		}
+	}
+	assert.Equal(t, []int64{1, 1, 2, 2, 3, 3}, got)
+}
+
+func TestMouseClickOptionsParseClickCount(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+	opts := NewMouseClickOptions()
+	err := opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{"clickCount": 3}))
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), opts.ClickCount)
+
+	err = opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{"clickCount": 4}))
+	assert.EqualError(t, err, "clickCount must be between 1 and 3, got 4")
+}
+
+func TestMouseMoveOptionsParseMovement(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+	opts := NewMouseMoveOptions()
+	assert.Equal(t, MouseMovementLinear, opts.Movement)
+
+	err := opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{
+		"movement": "human",
+		"seed":     42,
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, MouseMovementHuman, opts.Movement)
+	require.NotNil(t, opts.Seed)
+	assert.Equal(t, int64(42), *opts.Seed)
+
+	err = opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{"movement": "robot"}))
+	assert.EqualError(t, err, `invalid movement "robot": must be one of: linear, human`)
+}
+
+func TestMouseMoveHuman(t *testing.T) {
+	t.Parallel()
+
+	m, s := newTestMouse(t)
+
+	seed := int64(1)
+	opts := NewMouseMoveOptions()
+	opts.Movement = MouseMovementHuman
+	opts.Seed = &seed
+	require.NoError(t, m.move(m.ctx, 100, 50, opts))
+
+	require.Len(t, s.events, humanMouseMinSteps)
+	last := s.events[len(s.events)-1]
+	assert.Equal(t, 100.0, last.X)
+	assert.Equal(t, 50.0, last.Y)
+	assert.Equal(t, &api.Position{X: 100, Y: 50}, m.Position())
+}
+
+func TestMouseMoveError(t *testing.T) {
+	t.Parallel()
+
+	seed := int64(1)
+	for _, movement := range []MouseMovement{MouseMovementLinear, MouseMovementHuman} {
+		movement := movement
+		t.Run(string(movement), func(t *testing.T) {
+			t.Parallel()
+
+			m, s := newTestMouse(t)
+			s.failAfter = 2
+
+			opts := NewMouseMoveOptions()
+			opts.Movement = movement
+			opts.Steps = 5
+			opts.Seed = &seed
+			require.Error(t, m.move(m.ctx, 100, 50, opts))
+
+			// the mouse stays where the last dispatched event moved it.
+			require.Len(t, s.events, 2)
+			last := s.events[len(s.events)-1]
+			assert.Equal(t, &api.Position{X: last.X, Y: last.Y}, m.Position())
+			assert.NotEqual(t, &api.Position{X: 100, Y: 50}, m.Position())
+		})
+	}
+}
+
+func TestMouseMoveCanceled(t *testing.T) {
+	t.Parallel()
+
+	m, s := newTestMouse(t)
+
+	// the steps of a human movement wait
+	// for the context of the action.
+	ctx, cancel := context.WithCancel(m.ctx)
+	cancel()
+	opts := NewMouseMoveOptions()
+	opts.Movement = MouseMovementHuman
+	require.ErrorIs(t, m.move(ctx, 100, 50, opts), ErrInterrupted)
+
+	assert.Empty(t, s.events)
+	assert.Equal(t, &api.Position{X: 0, Y: 0}, m.Position())
+}
+
+// The mouse and the keyboard of a page can be used by the actions that
+// run concurrently, such as the ones of a promise and the event loop.
+func TestMouseConcurrentActions(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+	s := &mouseEventSession{}
+	k := NewKeyboard(vu.Context(), s)
+	m := NewMouse(vu.Context(), s, nil, nil, k)
+
+	const n = 50
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < n; i++ {
+			assert.NoError(t, m.click(float64(i), float64(i), NewMouseClickOptions()))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < n; i++ {
+			assert.NoError(t, k.press("Shift", NewKeyboardOptions()))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < n; i++ {
+			_ = m.Position()
+			_ = m.IsPressed("left")
+		}
+	}()
+	wg.Wait()
+
+	assert.Equal(t, &api.Position{X: n - 1, Y: n - 1}, m.Position())
+	assert.False(t, m.IsPressed("left"))
+	assert.Zero(t, k.getModifiers(), "the modifier keys should be released")
+}
@@ -0,0 +1,132 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package common
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grafana/xk6-browser/k6ext"
+
+	k6metrics "go.k6.io/k6/metrics"
+)
+
+// navigationTimingScript waits for the load event of the document to end,
+// and returns the navigation timing of the document and its first
+// contentful paint. The navigation timing covers the redirects of the
+// navigation, and its name is the final URL.
+const navigationTimingScript = `new Promise((resolve) => {
+	const read = () => {
+		const [nav] = performance.getEntriesByType("navigation");
+		if (!nav) {
+			resolve(null);
+			return;
+		}
+		if (nav.loadEventEnd <= 0) {
+			setTimeout(read, 10);
+			return;
+		}
+		const [fcp] = performance.getEntriesByName("first-contentful-paint");
+		resolve({
+			url: nav.name,
+			ttfb: nav.responseStart,
+			fcp: fcp ? fcp.startTime : null,
+			domContentLoaded: nav.domContentLoadedEventEnd,
+			load: nav.loadEventEnd,
+		});
+	};
+	read();
+})`
+
+// valueEvaluator evaluates an expression in a document, and
+// unmarshals the JSON of its value into res.
+type valueEvaluator interface {
+	evalValue(ctx context.Context, expr string, res interface{}) error
+}
+
+// navigationTiming is the timing of a navigation. The times are in
+// milliseconds since the navigation started.
+type navigationTiming struct {
+	URL              string   `json:"url"`
+	TTFB             *float64 `json:"ttfb"`
+	FCP              *float64 `json:"fcp"`
+	DOMContentLoaded *float64 `json:"domContentLoaded"`
+	Load             *float64 `json:"load"`
+}
+
+// samples returns the values of the timing of the navigation
+// by their metrics, skipping the missing ones.
+func (t *navigationTiming) samples(m *k6ext.CustomMetrics) map[*k6metrics.Metric]float64 {
+	samples := make(map[*k6metrics.Metric]float64)
+	for metric, v := range map[*k6metrics.Metric]*float64{
+		m.BrowserNavigationTTFB:             t.TTFB,
+		m.BrowserNavigationFCP:              t.FCP,
+		m.BrowserNavigationDOMContentLoaded: t.DOMContentLoaded,
+		m.BrowserNavigationLoadTime:         t.Load,
+	} {
+		if v != nil {
+			samples[metric] = *v
+		}
+	}
+	return samples
+}
+
+// readNavigationTiming returns the timing of the navigation of the document,
+// or nil if the document has no navigation timing, such as about:blank.
+func readNavigationTiming(ctx context.Context, ev valueEvaluator) (*navigationTiming, error) {
+	var timing *navigationTiming
+	if err := ev.evalValue(ctx, navigationTimingScript, &timing); err != nil {
+		return nil, fmt.Errorf("reading navigation timing: %w", err)
+	}
+	return timing, nil
+}
+
+// emitNavigationTiming emits the timing of the navigation of the main frame
+// after the document loads. It doesn't block, as the timing is complete
+// after the load event handlers run.
+func (p *Page) emitNavigationTiming(f *Frame) {
+	if p.k6Metrics == nil || !p.k6Metrics.Enabled(k6ext.MetricFamilyNavigation) {
+		return
+	}
+
+	f.executionContextMu.RLock()
+	ev, ok := f.executionContexts[mainWorld].(valueEvaluator)
+	f.executionContextMu.RUnlock()
+	if !ok {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(p.ctx, p.timeoutSettings.navigationTimeout())
+		defer cancel()
+
+		timing, err := readNavigationTiming(ctx, ev)
+		if err != nil {
+			// the page may have navigated away or closed.
+			p.logger.Debugf("Page:emitNavigationTiming", "sid:%v fid:%s err:%v", p.sessionID(), f.ID(), err)
+			return
+		}
+		if timing == nil {
+			return
+		}
+		p.logger.Debugf("Page:emitNavigationTiming", "sid:%v fid:%s url:%q", p.sessionID(), f.ID(), timing.URL)
+		p.browserCtx.pushPageMetrics(p.metricTags(), timing.URL, timing.samples(p.k6Metrics), false)
+	}()
+}
@@ -0,0 +1,83 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/grafana/xk6-browser/k6ext"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	k6metrics "go.k6.io/k6/metrics"
+)
+
+// stubEvaluator is a valueEvaluator that returns
+// a JSON value instead of evaluating the expression.
+type stubEvaluator struct {
+	expr  string
+	value string
+	err   error
+}
+
+func (e *stubEvaluator) evalValue(_ context.Context, expr string, res interface{}) error {
+	e.expr = expr
+	if e.err != nil {
+		return e.err
+	}
+	return json.Unmarshal([]byte(e.value), res)
+}
+
+func TestReadNavigationTiming(t *testing.T) {
+	t.Parallel()
+
+	t.Run("timing", func(t *testing.T) {
+		t.Parallel()
+
+		ev := &stubEvaluator{value: `{
+			"url": "https://example.com/final?q=1",
+			"ttfb": 120.5, "fcp": 300, "domContentLoaded": 450, "load": 600
+		}`}
+		timing, err := readNavigationTiming(context.Background(), ev)
+		require.NoError(t, err)
+		assert.Equal(t, navigationTimingScript, ev.expr)
+		require.NotNil(t, timing)
+		assert.Equal(t, "https://example.com/final?q=1", timing.URL)
+
+		metrics := k6ext.RegisterCustomMetrics(k6metrics.NewRegistry())
+		assert.Equal(t, map[*k6metrics.Metric]float64{
+			metrics.BrowserNavigationTTFB:             120.5,
+			metrics.BrowserNavigationFCP:              300,
+			metrics.BrowserNavigationDOMContentLoaded: 450,
+			metrics.BrowserNavigationLoadTime:         600,
+		}, timing.samples(metrics))
+	})
+	t.Run("no_paint", func(t *testing.T) {
+		t.Parallel()
+
+		ev := &stubEvaluator{value: `{
+			"url": "https://example.com/", "ttfb": 10, "fcp": null, "domContentLoaded": 20, "load": 30
+		}`}
+		timing, err := readNavigationTiming(context.Background(), ev)
+		require.NoError(t, err)
+
+		metrics := k6ext.RegisterCustomMetrics(k6metrics.NewRegistry())
+		samples := timing.samples(metrics)
+		assert.Len(t, samples, 3)
+		assert.NotContains(t, samples, metrics.BrowserNavigationFCP)
+	})
+	t.Run("no_timing", func(t *testing.T) {
+		t.Parallel()
+
+		timing, err := readNavigationTiming(context.Background(), &stubEvaluator{value: `null`})
+		require.NoError(t, err)
+		assert.Nil(t, timing)
+	})
+	t.Run("error", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := readNavigationTiming(context.Background(), &stubEvaluator{err: errors.New("context destroyed")})
+		assert.ErrorContains(t, err, "reading navigation timing: context destroyed")
+	})
+}
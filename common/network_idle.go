@@ -0,0 +1,200 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/dop251/goja"
+
+	"github.com/grafana/xk6-browser/k6ext"
+)
+
+// networkIdlePollInterval is how often waitForNetworkIdle
+// checks the requests in flight.
+const networkIdlePollInterval = 50 * time.Millisecond
+
+// NetworkIdleOptions define when the network of a frame is idle,
+// which fires the networkidle lifecycle event.
+type NetworkIdleOptions struct {
+	// Connections is the number of requests that
+	// can be in flight while the network is idle.
+	Connections int64 `js:"connections"`
+	// IdleTime is how long the number of requests in flight must stay
+	// at or below Connections for the network to be idle.
+	IdleTime time.Duration `js:"idleTime"`
+}
+
+// NewNetworkIdleOptions returns the default network idle options,
+// where the network is idle after no requests are in flight for 500ms.
+func NewNetworkIdleOptions() *NetworkIdleOptions {
+	return &NetworkIdleOptions{
+		Connections: 0,
+		IdleTime:    LifeCycleNetworkIdleTimeout,
+	}
+}
+
+// Parse parses the network idle options from a JS object. The idle time is
+// either a number of milliseconds, or a duration string such as '1s'.
+func (o *NetworkIdleOptions) Parse(ctx context.Context, opts goja.Value) error {
+	if !gojaValueExists(opts) {
+		return nil
+	}
+	rt := k6ext.Runtime(ctx)
+	obj := opts.ToObject(rt)
+	for _, k := range obj.Keys() {
+		switch k {
+		case "connections":
+			n := obj.Get(k).ToInteger()
+			if n < 0 {
+				return fmt.Errorf("connections must be zero or more, got %d", n)
+			}
+			o.Connections = n
+		case "idleTime":
+			d, err := parseDurationOption(obj.Get(k))
+			if err != nil {
+				return fmt.Errorf("parsing idleTime: %w", err)
+			}
+			o.IdleTime = d
+		}
+	}
+
+	return nil
+}
+
+// parseDurationOption parses a duration that is either a number
+// of milliseconds, or a duration string such as '1s'.
+func parseDurationOption(v goja.Value) (time.Duration, error) {
+	var d time.Duration
+	if v.ExportType().Kind() == reflect.String {
+		var err error
+		if d, err = time.ParseDuration(v.String()); err != nil {
+			return 0, fmt.Errorf("%w", err)
+		}
+	} else {
+		d = time.Duration(v.ToInteger()) * time.Millisecond
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("duration must not be negative, got %s", d)
+	}
+
+	return d, nil
+}
+
+// parseWaitUntil parses a waitUntil option, which is either the name of
+// a lifecycle event, or an object with the networkidle key, whose value
+// is the network idle options to wait for the networkidle event with,
+// such as { networkidle: { connections: 2, idleTime: '1s' } }. The
+// network idle options are nil if the option is a lifecycle event name.
+func parseWaitUntil(ctx context.Context, v goja.Value) (LifecycleEvent, *NetworkIdleOptions, error) {
+	var event LifecycleEvent
+	obj, ok := v.(*goja.Object)
+	if !ok || v.ExportType().Kind() == reflect.String {
+		if err := event.UnmarshalText([]byte(v.String())); err != nil {
+			return event, nil, err
+		}
+		return event, nil, nil
+	}
+	keys := obj.Keys()
+	if len(keys) != 1 || keys[0] != LifecycleEventNetworkIdle.String() {
+		return event, nil, errors.New("waitUntil object must only have the networkidle key")
+	}
+	opts := NewNetworkIdleOptions()
+	if err := opts.Parse(ctx, obj.Get(keys[0])); err != nil {
+		return event, nil, err
+	}
+
+	return LifecycleEventNetworkIdle, opts, nil
+}
+
+// isLongLivedRequest reports whether a request is expected to stay in
+// flight as long as the page is open. Such requests don't keep the
+// network of the page busy.
+func isLongLivedRequest(req *Request) bool {
+	switch req.resourceType {
+	case network.ResourceTypeEventSource.String(), network.ResourceTypeWebSocket.String():
+		return true
+	default:
+		return false
+	}
+}
+
+// networkIdleOptions returns the network idle options of the frame,
+// which come from the browser context options.
+func (f *Frame) networkIdleOptions() *NetworkIdleOptions {
+	if f.page == nil || f.page.browserCtx == nil || f.page.browserCtx.opts == nil ||
+		f.page.browserCtx.opts.NetworkIdle == nil {
+		return NewNetworkIdleOptions()
+	}
+	return f.page.browserCtx.opts.NetworkIdle
+}
+
+// isNetworkIdle reports whether the requests in flight in the frame
+// are few enough for its network to be idle.
+func (f *Frame) isNetworkIdle() bool {
+	return int64(f.inflightRequestsLen()) <= f.networkIdleOptions().Connections
+}
+
+// subtreeInflightRequestsLen returns the number of requests
+// in flight in the frame and its child frames.
+func (f *Frame) subtreeInflightRequestsLen() int {
+	n := f.inflightRequestsLen()
+	for _, child := range f.ChildFrames() {
+		n += child.(*Frame).subtreeInflightRequestsLen()
+	}
+	return n
+}
+
+// waitForNetworkIdle waits until the number of requests in flight in the
+// frame and its child frames stays at or below opts.Connections for
+// opts.IdleTime. Unlike the networkidle lifecycle event, it doesn't
+// depend on the network idle options of the browser context.
+func (f *Frame) waitForNetworkIdle(ctx context.Context, opts *NetworkIdleOptions) error {
+	t := time.NewTicker(networkIdlePollInterval)
+	defer t.Stop()
+
+	var idleSince time.Time
+	for {
+		if int64(f.subtreeInflightRequestsLen()) > opts.Connections {
+			idleSince = time.Time{}
+		} else {
+			if idleSince.IsZero() {
+				idleSince = time.Now()
+			}
+			if time.Since(idleSince) >= opts.IdleTime {
+				return nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return ErrTimedOut
+			}
+			return fmt.Errorf("waiting for network idle: %w", ctx.Err())
+		case <-t.C:
+		}
+	}
+}
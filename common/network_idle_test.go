@@ -0,0 +1,70 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/xk6-browser/k6ext/k6test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetworkIdleOptionsParse(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+
+	opts := NewNetworkIdleOptions()
+	assert.Equal(t, &NetworkIdleOptions{Connections: 0, IdleTime: 500 * time.Millisecond}, opts)
+
+	err := opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{
+		"connections": 2,
+		"idleTime":    "1s",
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, &NetworkIdleOptions{Connections: 2, IdleTime: time.Second}, opts)
+
+	err = opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{"idleTime": 250}))
+	require.NoError(t, err)
+	assert.Equal(t, 250*time.Millisecond, opts.IdleTime)
+
+	err = opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{"connections": -1}))
+	assert.EqualError(t, err, "connections must be zero or more, got -1")
+
+	err = opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{"idleTime": "soon"}))
+	assert.ErrorContains(t, err, "parsing idleTime")
+}
+
+func TestParseWaitUntil(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+
+	event, networkIdle, err := parseWaitUntil(vu.Context(), vu.ToGojaValue("networkidle"))
+	require.NoError(t, err)
+	assert.Equal(t, LifecycleEventNetworkIdle, event)
+	assert.Nil(t, networkIdle)
+
+	event, networkIdle, err = parseWaitUntil(vu.Context(), vu.ToGojaValue(map[string]interface{}{
+		"networkidle": map[string]interface{}{"connections": 2},
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, LifecycleEventNetworkIdle, event)
+	assert.Equal(t, &NetworkIdleOptions{Connections: 2, IdleTime: 500 * time.Millisecond}, networkIdle)
+
+	_, _, err = parseWaitUntil(vu.Context(), vu.ToGojaValue(map[string]interface{}{"load": true}))
+	assert.EqualError(t, err, "waitUntil object must only have the networkidle key")
+
+	_, _, err = parseWaitUntil(vu.Context(), vu.ToGojaValue("idle"))
+	assert.ErrorContains(t, err, `invalid lifecycle event: "idle"`)
+}
+
+func TestIsLongLivedRequest(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, isLongLivedRequest(&Request{resourceType: "EventSource"}))
+	assert.True(t, isLongLivedRequest(&Request{resourceType: "WebSocket"}))
+	assert.False(t, isLongLivedRequest(&Request{resourceType: "Fetch"}))
+	assert.False(t, isLongLivedRequest(&Request{resourceType: "Document"}))
+}
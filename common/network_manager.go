@@ -26,6 +26,7 @@ import (
 	"net"
 	"net/url"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -59,19 +60,35 @@ type NetworkManager struct {
 	session      session
 	parent       *NetworkManager
 	frameManager *FrameManager
-	credentials  *Credentials
 	resolver     k6netext.Resolver
 	vu           k6modules.VU
+	k6Metrics    *k6ext.CustomMetrics
 
 	// TODO: manage inflight requests separately (move them between the two maps
 	// as they transition from inflight -> completed)
 	reqIDToRequest map[network.RequestID]*Request
 	reqsMu         sync.RWMutex
 
-	attemptedAuth map[fetch.RequestID]bool
+	// the extra info events that arrived before
+	// their requests or responses, by request ID.
+	reqExtraInfo  map[network.RequestID][]*network.EventRequestWillBeSentExtraInfo
+	respExtraInfo map[network.RequestID][]*network.EventResponseReceivedExtraInfo
+	extraInfoMu   sync.Mutex
+
+	// the credentials are set on the event loop and
+	// used to answer the auth challenges in the events.
+	credentials      *Credentials
+	proxyCredentials *Credentials
+	credentialsMu    sync.RWMutex
+	attemptedAuth    map[authAttempt]bool
 
 	extraHTTPHeaders               map[string]string
+	blockedResourceTypes           map[network.ResourceType]bool
+	blockedResourceTypesMu         sync.RWMutex
+	requestMetrics                 *RequestMetricsOptions
+	requestMetricsMu               sync.RWMutex
 	offline                        bool
+	networkConditions              *NetworkConditions
 	userCacheDisabled              bool
 	userReqInterceptionEnabled     bool
 	protocolReqInterceptionEnabled bool
@@ -100,9 +117,13 @@ func NewNetworkManager(
 		frameManager:     fm,
 		resolver:         resolver,
 		vu:               vu,
+		k6Metrics:        k6ext.GetCustomMetrics(ctx),
 		reqIDToRequest:   make(map[network.RequestID]*Request),
-		attemptedAuth:    make(map[fetch.RequestID]bool),
+		reqExtraInfo:     make(map[network.RequestID][]*network.EventRequestWillBeSentExtraInfo),
+		respExtraInfo:    make(map[network.RequestID][]*network.EventResponseReceivedExtraInfo),
+		attemptedAuth:    make(map[authAttempt]bool),
 		extraHTTPHeaders: make(map[string]string),
+		requestMetrics:   NewRequestMetricsOptions(),
 	}
 	m.initEvents()
 	if err := m.initDomains(); err != nil {
@@ -163,33 +184,65 @@ func (m *NetworkManager) deleteRequestByID(reqID network.RequestID) {
 }
 
 func (m *NetworkManager) emitRequestMetrics(req *Request) {
+	if !m.requestMetricsEnabled() {
+		return
+	}
 	state := m.vu.State()
 
-	tags := state.CloneTags()
-	if state.Options.SystemTags.Has(k6metrics.TagGroup) {
-		tags["group"] = state.Group.Path
-	}
+	tags := m.metricTags()
 	if state.Options.SystemTags.Has(k6metrics.TagMethod) {
 		tags["method"] = req.method
 	}
+	tags["resource_type"] = strings.ToLower(req.resourceType)
+
+	size := float64(req.Size().Total())
+	m.withMetricURL(req.URL(), func(tag string) {
+		if state.Options.SystemTags.Has(k6metrics.TagURL) {
+			tags["url"] = tag
+		}
+		k6metrics.PushIfNotDone(m.ctx, state.Samples, k6metrics.ConnectedSamples{
+			Samples: []k6metrics.Sample{
+				{
+					Metric: state.BuiltinMetrics.DataSent,
+					Tags:   k6metrics.IntoSampleTags(&tags),
+					Value:  size,
+					Time:   req.timestamp,
+				},
+			},
+		})
+	})
+}
+
+func (m *NetworkManager) emitBlockedRequestMetric(req *network.Request) {
+	if m.k6Metrics == nil || !m.k6Metrics.Enabled(k6ext.MetricFamilyRequests) {
+		return
+	}
+	state := m.vu.State()
+
+	tags := m.metricTags()
+	if state.Options.SystemTags.Has(k6metrics.TagMethod) {
+		tags["method"] = req.Method
+	}
 	if state.Options.SystemTags.Has(k6metrics.TagURL) {
-		tags["url"] = req.URL()
+		tags["url"] = req.URL
 	}
 
-	sampleTags := k6metrics.IntoSampleTags(&tags)
 	k6metrics.PushIfNotDone(m.ctx, state.Samples, k6metrics.ConnectedSamples{
 		Samples: []k6metrics.Sample{
 			{
-				Metric: state.BuiltinMetrics.DataSent,
-				Tags:   sampleTags,
-				Value:  float64(req.Size().Total()),
-				Time:   req.timestamp,
+				Metric: m.k6Metrics.BrowserBlockedRequests,
+				Tags:   k6metrics.IntoSampleTags(&tags),
+				Value:  1,
+				Time:   time.Now(),
 			},
 		},
 	})
 }
 
 func (m *NetworkManager) emitResponseMetrics(resp *Response, req *Request) {
+	if !m.requestMetricsEnabled() {
+		return
+	}
 	state := m.vu.State()
 
 	// In some scenarios we might not receive a ResponseReceived CDP event, in
@@ -217,16 +270,10 @@ func (m *NetworkManager) emitResponseMetrics(resp *Response, req *Request) {
 			"response is nil url:%s method:%s", req.url, req.method)
 	}
 
-	tags := state.CloneTags()
-	if state.Options.SystemTags.Has(k6metrics.TagGroup) {
-		tags["group"] = state.Group.Path
-	}
+	tags := m.metricTags()
 	if state.Options.SystemTags.Has(k6metrics.TagMethod) {
 		tags["method"] = req.method
 	}
-	if state.Options.SystemTags.Has(k6metrics.TagURL) {
-		tags["url"] = url
-	}
 	if state.Options.SystemTags.Has(k6metrics.TagIP) {
 		tags["ip"] = ipAddress
 	}
@@ -240,8 +287,20 @@ func (m *NetworkManager) emitResponseMetrics(resp *Response, req *Request) {
 	tags["from_cache"] = strconv.FormatBool(fromCache)
 	tags["from_prefetch_cache"] = strconv.FormatBool(fromPreCache)
 	tags["from_service_worker"] = strconv.FormatBool(fromSvcWrk)
+	tags["resource_type"] = strings.ToLower(req.resourceType)
+
+	m.withMetricURL(url, func(tag string) {
+		if state.Options.SystemTags.Has(k6metrics.TagURL) {
+			tags["url"] = tag
+		}
+		m.pushResponseMetrics(resp, req, k6metrics.IntoSampleTags(&tags), timestamp, bodySize)
+	})
+}
 
-	sampleTags := k6metrics.IntoSampleTags(&tags)
+func (m *NetworkManager) pushResponseMetrics(
+	resp *Response, req *Request, sampleTags *k6metrics.SampleTags, timestamp time.Time, bodySize int64,
+) {
+	state := m.vu.State()
 	k6metrics.PushIfNotDone(m.ctx, state.Samples, k6metrics.ConnectedSamples{
 		Samples: []k6metrics.Sample{
 			{
@@ -303,9 +362,72 @@ func (m *NetworkManager) emitResponseMetrics(resp *Response, req *Request) {
 	}
 }
 
-func (m *NetworkManager) handleRequestRedirect(req *Request, redirectResponse *network.Response, timestamp *cdp.MonotonicTime) {
+// emitFailedRequestMetric counts a request that failed, tagged
+// with its error text if the errorTag option is enabled.
+func (m *NetworkManager) emitFailedRequestMetric(req *Request, errorText string) {
+	opts := m.requestMetricsOptions()
+	if m.k6Metrics == nil || !m.requestMetricsEnabled() {
+		return
+	}
+	state := m.vu.State()
+
+	tags := m.metricTags()
+	if state.Options.SystemTags.Has(k6metrics.TagMethod) {
+		tags["method"] = req.method
+	}
+	if state.Options.SystemTags.Has(k6metrics.TagError) && opts.ErrorTag {
+		tags["error"] = errorText
+	}
+	tags["resource_type"] = strings.ToLower(req.resourceType)
+
+	now := time.Now()
+	m.withMetricURL(req.URL(), func(tag string) {
+		if state.Options.SystemTags.Has(k6metrics.TagURL) {
+			tags["url"] = tag
+		}
+		k6metrics.PushIfNotDone(m.ctx, state.Samples, k6metrics.ConnectedSamples{
+			Samples: []k6metrics.Sample{
+				{
+					Metric: m.k6Metrics.BrowserFailedRequests,
+					Tags:   k6metrics.IntoSampleTags(&tags),
+					Value:  1,
+					Time:   now,
+				},
+			},
+		})
+	})
+}
+
+// metricTags returns the tags of the metrics of the requests, which are
+// the tags of the page, or the tags of the VU if there's no page.
+func (m *NetworkManager) metricTags() map[string]string {
+	if m.frameManager != nil && m.frameManager.page != nil {
+		return m.frameManager.page.metricTags()
+	}
+	return vuMetricTags(m.ctx, m.vu.State())
+}
+
+// withMetricURL calls push with the url tag of a request, which the
+// metricURLGrouping option of the browser context can group.
+func (m *NetworkManager) withMetricURL(url string, push func(tag string)) {
+	var bctx *BrowserContext
+	if m.frameManager != nil && m.frameManager.page != nil {
+		bctx = m.frameManager.page.browserCtx
+	}
+	if bctx == nil {
+		push(url)
+		return
+	}
+	bctx.withMetricURL(url, false, push)
+}
+
+func (m *NetworkManager) handleRequestRedirect(
+	req *Request, redirectResponse *network.Response, timestamp *cdp.MonotonicTime, hasExtraInfo bool,
+) {
 	resp := NewHTTPResponse(m.ctx, req, redirectResponse, timestamp)
+	resp.hasExtraInfo = hasExtraInfo
 	req.response = resp
+	m.applyResponseExtraInfo(req.requestID, resp)
 	req.redirectChain = append(req.redirectChain, req)
 
 	m.emitResponseMetrics(resp, req)
@@ -343,8 +465,10 @@ func (m *NetworkManager) initEvents() {
 		cdproto.EventNetworkLoadingFailed,
 		cdproto.EventNetworkLoadingFinished,
 		cdproto.EventNetworkRequestWillBeSent,
+		cdproto.EventNetworkRequestWillBeSentExtraInfo,
 		cdproto.EventNetworkRequestServedFromCache,
 		cdproto.EventNetworkResponseReceived,
+		cdproto.EventNetworkResponseReceivedExtraInfo,
 		cdproto.EventFetchRequestPaused,
 		cdproto.EventFetchAuthRequired,
 	}, chHandler)
@@ -372,10 +496,14 @@ func (m *NetworkManager) handleEvents(in <-chan Event) bool {
 			m.onLoadingFinished(ev)
 		case *network.EventRequestWillBeSent:
 			m.onRequest(ev, "")
+		case *network.EventRequestWillBeSentExtraInfo:
+			m.onRequestExtraInfo(ev)
 		case *network.EventRequestServedFromCache:
 			m.onRequestServedFromCache(ev)
 		case *network.EventResponseReceived:
 			m.onResponseReceived(ev)
+		case *network.EventResponseReceivedExtraInfo:
+			m.onResponseExtraInfo(ev)
 		case *fetch.EventRequestPaused:
 			m.onRequestPaused(ev)
 		case *fetch.EventAuthRequired:
@@ -387,13 +515,25 @@ func (m *NetworkManager) handleEvents(in <-chan Event) bool {
 
 func (m *NetworkManager) onLoadingFailed(event *network.EventLoadingFailed) {
 	req := m.requestFromID(event.RequestID)
+	if req == nil && m.parent != nil {
+		// Iframe document requests can start in the parent session
+		// and fail in the iframe session.
+		req = m.parent.requestFromID(event.RequestID)
+		if req != nil {
+			m.parent.deleteRequestByID(event.RequestID)
+		}
+	}
 	if req == nil {
-		// TODO: add handling of iframe document requests starting in one session and ending up in another
 		return
 	}
-	req.setErrorText(event.ErrorText)
-	req.responseEndTiming = float64(event.Timestamp.Time().Unix()-req.timestamp.Unix()) * 1000
+	req.setFailure(event.ErrorText, event.Canceled)
+	req.setResponseEnd(event.Timestamp)
+	if !isInternalURL(req.url) {
+		m.emitResponseMetrics(req.response, req)
+		m.emitFailedRequestMetric(req, event.ErrorText)
+	}
 	m.deleteRequestByID(event.RequestID)
+	m.deleteExtraInfo(event.RequestID)
 	m.frameManager.requestFailed(req, event.Canceled)
 }
 
@@ -418,12 +558,13 @@ func (m *NetworkManager) onLoadingFinished(event *network.EventLoadingFinished)
 			return
 		}
 	}
-	req.responseEndTiming = float64(event.Timestamp.Time().Unix()-req.timestamp.Unix()) * 1000
+	req.setResponseEnd(event.Timestamp)
 	// Skip data and blob URLs when emitting metrics, since they're internal to the browser.
 	if !isInternalURL(req.url) {
 		m.emitResponseMetrics(req.response, req)
 	}
 	m.deleteRequestByID(event.RequestID)
+	m.deleteExtraInfo(event.RequestID)
 	m.frameManager.requestFinished(req)
 }
 
@@ -436,7 +577,7 @@ func (m *NetworkManager) onRequest(event *network.EventRequestWillBeSent, interc
 	if event.RedirectResponse != nil {
 		req := m.requestFromID(event.RequestID)
 		if req != nil {
-			m.handleRequestRedirect(req, event.RedirectResponse, event.Timestamp)
+			m.handleRequestRedirect(req, event.RedirectResponse, event.Timestamp, event.RedirectHasExtraInfo)
 			redirectChain = req.redirectChain
 		}
 	} else {
@@ -466,9 +607,13 @@ func (m *NetworkManager) onRequest(event *network.EventRequestWillBeSent, interc
 		m.logger.Debugf("NetworkManager", "skipped request handling of %s URL", req.url.Scheme)
 		return
 	}
+	if from := req.redirectedFrom(); from != nil {
+		from.setRedirectedTo(req)
+	}
 	m.reqsMu.Lock()
 	m.reqIDToRequest[event.RequestID] = req
 	m.reqsMu.Unlock()
+	m.applyRequestExtraInfo(req)
 	m.emitRequestMetrics(req)
 	m.frameManager.requestStarted(req)
 }
@@ -479,32 +624,55 @@ func (m *NetworkManager) onRequestPaused(event *fetch.EventRequestPaused) {
 	defer m.logger.Debugf("NetworkManager:onRequestPaused:return",
 		"sid:%s url:%v", m.session.ID(), event.Request.URL)
 
-	var failErr error
-
-	defer func() {
-		if failErr != nil {
-			action := fetch.FailRequest(event.RequestID, network.ErrorReasonBlockedByClient)
-			if err := action.Do(cdp.WithExecutor(m.ctx, m.session)); err != nil {
-				m.logger.Errorf("NetworkManager:onRequestPaused",
-					"interrupting request: %s", err)
-			} else {
-				m.logger.Warnf("NetworkManager:onRequestPaused",
-					"request %s %s was interrupted: %s", event.Request.Method, event.Request.URL, failErr)
-				return
-			}
+	if m.isBlockedResourceType(event.ResourceType) {
+		action := fetch.FailRequest(event.RequestID, network.ErrorReasonBlockedByClient)
+		if err := action.Do(cdp.WithExecutor(m.ctx, m.session)); err != nil {
+			m.logger.Errorf("NetworkManager:onRequestPaused",
+				"blocking request: %s", err)
+		} else {
+			m.logger.Debugf("NetworkManager:onRequestPaused",
+				"request %s %s was blocked: resource type %s", event.Request.Method, event.Request.URL, event.ResourceType)
+			m.emitBlockedRequestMetric(event.Request)
+			return
 		}
-		action := fetch.ContinueRequest(event.RequestID)
+	} else if failErr := m.checkBlockedRequest(event.Request.URL); failErr != nil {
+		action := fetch.FailRequest(event.RequestID, network.ErrorReasonBlockedByClient)
 		if err := action.Do(cdp.WithExecutor(m.ctx, m.session)); err != nil {
 			m.logger.Errorf("NetworkManager:onRequestPaused",
-				"continuing request: %s", err)
+				"interrupting request: %s", err)
+		} else {
+			m.logger.Warnf("NetworkManager:onRequestPaused",
+				"request %s %s was interrupted: %s", event.Request.Method, event.Request.URL, failErr)
+			return
 		}
-	}()
+	} else if m.routeRequest(event) {
+		return
+	}
 
-	purl, err := url.Parse(event.Request.URL)
+	action := fetch.ContinueRequest(event.RequestID)
+	if err := action.Do(cdp.WithExecutor(m.ctx, m.session)); err != nil {
+		m.logger.Errorf("NetworkManager:onRequestPaused",
+			"continuing request: %s", err)
+	}
+}
+
+// isBlockedResourceType returns true if requests of
+// the given resource type should be blocked.
+func (m *NetworkManager) isBlockedResourceType(typ network.ResourceType) bool {
+	m.blockedResourceTypesMu.RLock()
+	defer m.blockedResourceTypesMu.RUnlock()
+
+	return m.blockedResourceTypes[typ]
+}
+
+// checkBlockedRequest returns an error if the k6 options block
+// the host or the IP address of the request URL.
+func (m *NetworkManager) checkBlockedRequest(rawURL string) error {
+	purl, err := url.Parse(rawURL)
 	if err != nil {
 		m.logger.Errorf("NetworkManager:onRequestPaused",
-			"parsing URL %q: %s", event.Request.URL, err)
-		return
+			"parsing URL %q: %s", rawURL, err)
+		return nil
 	}
 
 	var (
@@ -513,12 +681,13 @@ func (m *NetworkManager) onRequestPaused(event *fetch.EventRequestPaused) {
 		state = m.vu.State()
 	)
 	if ip != nil {
-		failErr = checkBlockedIPs(ip, state.Options.BlacklistIPs)
-		return
+		return checkBlockedIPs(ip, state.Options.BlacklistIPs)
 	}
-	failErr = checkBlockedHosts(host, state.Options.BlockedHostnames.Trie)
-	if failErr != nil {
-		return
+	if err := checkBlockedHosts(host, state.Options.BlockedHostnames.Trie); err != nil {
+		return err
+	}
+	if len(state.Options.BlacklistIPs) == 0 {
+		return nil
 	}
 
 	// Do one last check of the resolved IP
@@ -526,9 +695,54 @@ func (m *NetworkManager) onRequestPaused(event *fetch.EventRequestPaused) {
 	if err != nil {
 		m.logger.Debugf("NetworkManager:onRequestPaused",
 			"resolving %q: %s", host, err)
-		return
+		return nil
+	}
+	return checkBlockedIPs(ip, state.Options.BlacklistIPs)
+}
+
+// routeRequest hands the paused request over to the route handler of
+// its URL, if there is one, and reports whether it did.
+func (m *NetworkManager) routeRequest(event *fetch.EventRequestPaused) bool {
+	if m.frameManager == nil || m.frameManager.page == nil {
+		return false
+	}
+	p := m.frameManager.page
+	h, queue := p.routeHandler(event.Request.URL)
+	if h == nil {
+		return false
+	}
+
+	req := m.requestFromID(network.RequestID(event.NetworkID))
+	if req == nil {
+		// the request may be paused before the network domain reports it.
+		var err error
+		if req, err = m.newPausedRequest(event); err != nil {
+			m.logger.Errorf("NetworkManager:routeRequest", "creating request: %s", err)
+			return false
+		}
+	}
+	p.handleRoute(h, queue, newRoute(m.ctx, m.session, m.logger, req, event.RequestID))
+
+	return true
+}
+
+// newPausedRequest returns a new request for a paused request.
+func (m *NetworkManager) newPausedRequest(event *fetch.EventRequestPaused) (*Request, error) {
+	var (
+		now  = cdp.MonotonicTime(time.Now())
+		wall = cdp.TimeSinceEpoch(time.Now())
+	)
+	ev := &network.EventRequestWillBeSent{
+		RequestID: network.RequestID(event.NetworkID),
+		Request:   event.Request,
+		FrameID:   event.FrameID,
+		Type:      event.ResourceType,
+		Timestamp: &now,
+		WallTime:  &wall,
 	}
-	failErr = checkBlockedIPs(ip, state.Options.BlacklistIPs)
+	frame := m.frameManager.getFrameByID(event.FrameID)
+
+	return NewRequest(m.ctx, ev, frame, nil, string(event.RequestID), true)
 }
 
 func checkBlockedHosts(host string, blockedHosts *k6types.HostnameTrie) error {
@@ -552,28 +766,43 @@ func checkBlockedIPs(ip net.IP, blockedIPs []*k6lib.IPNet) error {
 	return nil
 }
 
+// authAttempt is an authentication of a request to the server or the proxy.
+type authAttempt struct {
+	requestID fetch.RequestID
+	proxy     bool
+}
+
 func (m *NetworkManager) onAuthRequired(event *fetch.EventAuthRequired) {
 	var (
-		res = fetch.AuthChallengeResponseResponseDefault
-		rid = event.RequestID
+		res         = fetch.AuthChallengeResponseResponseDefault
+		rid         = event.RequestID
+		credentials = m.getCredentials()
+		attempt     = authAttempt{requestID: rid}
 
 		username, password string
 	)
+	// a request can be challenged by both the proxy and the server.
+	if event.AuthChallenge != nil && event.AuthChallenge.Source == fetch.AuthChallengeSourceProxy {
+		credentials = m.getProxyCredentials()
+		attempt.proxy = true
+	}
 
 	switch {
-	case m.attemptedAuth[rid]:
-		delete(m.attemptedAuth, rid)
+	case m.attemptedAuth[attempt]:
+		// the credentials were wrong. cancel the authentication so that
+		// the request gets the 401 or 407 response instead of looping.
+		delete(m.attemptedAuth, attempt)
 		res = fetch.AuthChallengeResponseResponseCancelAuth
-	case m.credentials != nil:
+	case credentials != nil:
 		// TODO: remove requests from attemptedAuth when:
 		//       - request is redirected
 		//       - loading finished
-		m.attemptedAuth[rid] = true
+		m.attemptedAuth[attempt] = true
 		res = fetch.AuthChallengeResponseResponseProvideCredentials
 		// The Fetch.AuthChallengeResponse docs mention username and password should only be set
 		// if the response is ProvideCredentials.
 		// See: https://chromedevtools.github.io/devtools-protocol/tot/Fetch/#type-AuthChallengeResponse
-		username, password = m.credentials.Username, m.credentials.Password
+		username, password = credentials.Username, credentials.Password
 	}
 	err := fetch.ContinueWithAuth(
 		rid,
@@ -603,10 +832,88 @@ func (m *NetworkManager) onResponseReceived(event *network.EventResponseReceived
 		return
 	}
 	resp := NewHTTPResponse(m.ctx, req, event.Response, event.Timestamp)
+	resp.hasExtraInfo = event.HasExtraInfo
 	req.response = resp
+	m.applyResponseExtraInfo(req.requestID, resp)
 	m.frameManager.requestReceivedResponse(resp)
 }
 
+// onRequestExtraInfo sets the raw headers of the request of the event.
+// The event can arrive before the request, in which case it's kept
+// until the request arrives.
+func (m *NetworkManager) onRequestExtraInfo(event *network.EventRequestWillBeSentExtraInfo) {
+	m.extraInfoMu.Lock()
+	defer m.extraInfoMu.Unlock()
+
+	if req := m.requestFromID(event.RequestID); req != nil && !req.hasExtraHeaders() {
+		req.setExtraHeaders(event.Headers)
+		return
+	}
+	m.reqExtraInfo[event.RequestID] = append(m.reqExtraInfo[event.RequestID], event)
+}
+
+// onResponseExtraInfo sets the raw headers of the response of the event.
+// The event can arrive before the response, in which case it's kept
+// until the response arrives.
+func (m *NetworkManager) onResponseExtraInfo(event *network.EventResponseReceivedExtraInfo) {
+	m.extraInfoMu.Lock()
+	defer m.extraInfoMu.Unlock()
+
+	if req := m.requestFromID(event.RequestID); req != nil {
+		if resp := req.response; resp != nil && !resp.hasExtraHeaders() {
+			resp.setExtraHeaders(event.Headers, event.HeadersText)
+			return
+		}
+	}
+	m.respExtraInfo[event.RequestID] = append(m.respExtraInfo[event.RequestID], event)
+}
+
+// deleteExtraInfo deletes the extra info events
+// of a finished request that were never applied.
+func (m *NetworkManager) deleteExtraInfo(id network.RequestID) {
+	m.extraInfoMu.Lock()
+	defer m.extraInfoMu.Unlock()
+
+	delete(m.reqExtraInfo, id)
+	delete(m.respExtraInfo, id)
+}
+
+// applyRequestExtraInfo sets the raw headers of the request
+// from the first extra info event that arrived before it.
+func (m *NetworkManager) applyRequestExtraInfo(req *Request) {
+	m.extraInfoMu.Lock()
+	defer m.extraInfoMu.Unlock()
+
+	events := m.reqExtraInfo[req.requestID]
+	if len(events) == 0 {
+		return
+	}
+	req.setExtraHeaders(events[0].Headers)
+	if len(events) == 1 {
+		delete(m.reqExtraInfo, req.requestID)
+	} else {
+		m.reqExtraInfo[req.requestID] = events[1:]
+	}
+}
+
+// applyResponseExtraInfo sets the raw headers of the response
+// from the first extra info event that arrived before it.
+func (m *NetworkManager) applyResponseExtraInfo(id network.RequestID, resp *Response) {
+	m.extraInfoMu.Lock()
+	defer m.extraInfoMu.Unlock()
+
+	events := m.respExtraInfo[id]
+	if len(events) == 0 {
+		return
+	}
+	resp.setExtraHeaders(events[0].Headers, events[0].HeadersText)
+	if len(events) == 1 {
+		delete(m.respExtraInfo, id)
+	} else {
+		m.respExtraInfo[id] = events[1:]
+	}
+}
+
 func (m *NetworkManager) requestFromID(reqID network.RequestID) *Request {
 	m.reqsMu.RLock()
 	defer m.reqsMu.RUnlock()
@@ -614,7 +921,8 @@ func (m *NetworkManager) requestFromID(reqID network.RequestID) *Request {
 }
 
 func (m *NetworkManager) setRequestInterception(value bool) error {
-	m.userReqInterceptionEnabled = value
+	// the credentials need the interception to authenticate.
+	m.userReqInterceptionEnabled = value || m.hasCredentials()
 	return m.updateProtocolRequestInterception()
 }
 
@@ -665,7 +973,10 @@ func (m *NetworkManager) updateProtocolRequestInterception() error {
 
 // Authenticate sets HTTP authentication credentials to use.
 func (m *NetworkManager) Authenticate(credentials *Credentials) {
+	m.credentialsMu.Lock()
 	m.credentials = credentials
+	m.credentialsMu.Unlock()
+
 	if credentials != nil {
 		m.userReqInterceptionEnabled = true
 	}
@@ -674,6 +985,43 @@ func (m *NetworkManager) Authenticate(credentials *Credentials) {
 	}
 }
 
+// AuthenticateProxy sets the credentials to authenticate to the proxy with.
+func (m *NetworkManager) AuthenticateProxy(credentials *Credentials) {
+	m.credentialsMu.Lock()
+	m.proxyCredentials = credentials
+	m.credentialsMu.Unlock()
+
+	if credentials != nil {
+		m.userReqInterceptionEnabled = true
+	}
+	if err := m.updateProtocolRequestInterception(); err != nil {
+		k6ext.Panic(m.ctx, "setting proxy authentication credentials: %w", err)
+	}
+}
+
+func (m *NetworkManager) getCredentials() *Credentials {
+	m.credentialsMu.RLock()
+	defer m.credentialsMu.RUnlock()
+
+	return m.credentials
+}
+
+func (m *NetworkManager) getProxyCredentials() *Credentials {
+	m.credentialsMu.RLock()
+	defer m.credentialsMu.RUnlock()
+
+	return m.proxyCredentials
+}
+
+// hasCredentials returns true if there are credentials
+// to authenticate to the servers or the proxy with.
+func (m *NetworkManager) hasCredentials() bool {
+	m.credentialsMu.RLock()
+	defer m.credentialsMu.RUnlock()
+
+	return m.credentials != nil || m.proxyCredentials != nil
+}
+
 // ExtraHTTPHeaders returns the currently set extra HTTP request headers.
 func (m *NetworkManager) ExtraHTTPHeaders() goja.Value {
 	rt := m.vu.Runtime()
@@ -688,6 +1036,51 @@ func (m *NetworkManager) SetExtraHTTPHeaders(headers network.Headers) {
 	}
 }
 
+// SetBlockedResourceTypes sets the resource types of the requests to block.
+// Requests are only paused, and therefore blocked, while request
+// interception is enabled.
+func (m *NetworkManager) SetBlockedResourceTypes(types []string) {
+	blocked := make(map[network.ResourceType]bool, len(types))
+	for _, t := range types {
+		typ, err := parseResourceType(t)
+		if err != nil {
+			k6ext.Panic(m.ctx, "setting blocked resource types: %w", err)
+		}
+		blocked[typ] = true
+	}
+
+	m.blockedResourceTypesMu.Lock()
+	defer m.blockedResourceTypesMu.Unlock()
+	m.blockedResourceTypes = blocked
+}
+
+// SetRequestMetrics sets the options of the metrics of the requests.
+func (m *NetworkManager) SetRequestMetrics(opts *RequestMetricsOptions) {
+	if opts == nil {
+		opts = NewRequestMetricsOptions()
+	}
+
+	m.requestMetricsMu.Lock()
+	defer m.requestMetricsMu.Unlock()
+	m.requestMetrics = opts
+}
+
+func (m *NetworkManager) requestMetricsOptions() *RequestMetricsOptions {
+	m.requestMetricsMu.RLock()
+	defer m.requestMetricsMu.RUnlock()
+
+	if m.requestMetrics == nil {
+		return NewRequestMetricsOptions()
+	}
+	return m.requestMetrics
+}
+
+// requestMetricsEnabled reports whether the metrics of the requests are
+// emitted, which both the browser context and the extension can disable.
+func (m *NetworkManager) requestMetricsEnabled() bool {
+	return m.requestMetricsOptions().Enabled && m.k6Metrics.Enabled(k6ext.MetricFamilyRequests)
+}
+
 // SetOfflineMode toggles offline mode on/off.
 func (m *NetworkManager) SetOfflineMode(offline bool) {
 	if m.offline == offline {
@@ -695,12 +1088,24 @@ func (m *NetworkManager) SetOfflineMode(offline bool) {
 	}
 	m.offline = offline
 
-	action := network.EmulateNetworkConditions(m.offline, 0, -1, -1)
+	action := m.networkConditions.emulateAction(m.offline)
 	if err := action.Do(cdp.WithExecutor(m.ctx, m.session)); err != nil {
 		k6ext.Panic(m.ctx, "setting offline mode: %w", err)
 	}
 }
 
+// SetNetworkConditions emulates the network conditions, or restores
+// the default conditions if the conditions are nil.
+func (m *NetworkManager) SetNetworkConditions(c *NetworkConditions) error {
+	m.networkConditions = c
+
+	action := m.networkConditions.emulateAction(m.offline)
+	if err := action.Do(cdp.WithExecutor(m.ctx, m.session)); err != nil {
+		return fmt.Errorf("emulating network conditions: %w", err)
+	}
+	return nil
+}
+
 // SetUserAgent overrides the browser user agent string.
 func (m *NetworkManager) SetUserAgent(userAgent string) {
 	action := emulation.SetUserAgentOverride(userAgent)
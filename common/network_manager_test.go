@@ -5,14 +5,18 @@ import (
 	"fmt"
 	"net"
 	"testing"
+	"time"
 
+	"github.com/grafana/xk6-browser/k6ext"
 	"github.com/grafana/xk6-browser/k6ext/k6test"
 	"github.com/grafana/xk6-browser/log"
 
 	k6lib "go.k6.io/k6/lib"
 	k6mockresolver "go.k6.io/k6/lib/testutils/mockresolver"
 	k6types "go.k6.io/k6/lib/types"
+	k6metrics "go.k6.io/k6/metrics"
 
+	"github.com/chromedp/cdproto/cdp"
 	"github.com/chromedp/cdproto/fetch"
 	"github.com/chromedp/cdproto/network"
 	"github.com/mailru/easyjson"
@@ -198,3 +202,338 @@ func TestOnRequestPausedBlockedIPs(t *testing.T) {
 		})
 	}
 }
+
+func TestOnRequestPausedBlockedResourceTypes(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		resourceType network.ResourceType
+		expCDPCalls  []string
+		expBlocked   bool
+	}{
+		{
+			name:         "ok_fail_image",
+			resourceType: network.ResourceTypeImage,
+			expCDPCalls:  []string{"Fetch.failRequest"},
+			expBlocked:   true,
+		},
+		{
+			name:         "ok_continue_document",
+			resourceType: network.ResourceTypeDocument,
+			expCDPCalls:  []string{"Fetch.continueRequest"},
+		},
+		{
+			name:         "ok_continue_xhr",
+			resourceType: network.ResourceTypeXHR,
+			expCDPCalls:  []string{"Fetch.continueRequest"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			nm, session := newTestNetworkManager(t, k6lib.Options{})
+			samples := make(chan k6metrics.SampleContainer, 10)
+			nm.vu.State().Samples = samples
+			nm.k6Metrics = k6ext.RegisterCustomMetrics(k6metrics.NewRegistry())
+			nm.SetBlockedResourceTypes([]string{"image", "font", "media"})
+			ev := &fetch.EventRequestPaused{
+				RequestID: "1234",
+				Request: &network.Request{
+					Method: "GET",
+					URL:    "http://host.com/",
+				},
+				ResourceType: tc.resourceType,
+			}
+
+			nm.onRequestPaused(ev)
+
+			assert.Equal(t, tc.expCDPCalls, session.cdpCalls)
+
+			var blocked float64
+			for len(samples) > 0 {
+				for _, s := range (<-samples).GetSamples() {
+					if s.Metric == nm.k6Metrics.BrowserBlockedRequests {
+						blocked += s.Value
+					}
+				}
+			}
+			if tc.expBlocked {
+				assert.Equal(t, float64(1), blocked)
+			} else {
+				assert.Zero(t, blocked)
+			}
+		})
+	}
+}
+
+// authSession records the responses to the auth challenges.
+type authSession struct {
+	session
+	responses []fetch.AuthChallengeResponseResponse
+}
+
+func (s *authSession) Execute(
+	_ context.Context, method string, params easyjson.Marshaler, _ easyjson.Unmarshaler,
+) error {
+	if p, ok := params.(*fetch.ContinueWithAuthParams); ok && method == fetch.CommandContinueWithAuth {
+		s.responses = append(s.responses, p.AuthChallengeResponse.Response)
+	}
+	return nil
+}
+
+func TestOnAuthRequired(t *testing.T) {
+	t.Parallel()
+
+	challenge := func(id fetch.RequestID) *fetch.EventAuthRequired {
+		return &fetch.EventAuthRequired{
+			RequestID:     id,
+			Request:       &network.Request{URL: "http://host.com/"},
+			AuthChallenge: &fetch.AuthChallenge{Source: fetch.AuthChallengeSourceServer},
+		}
+	}
+
+	nm, _ := newTestNetworkManager(t, k6lib.Options{})
+	nm.attemptedAuth = make(map[authAttempt]bool)
+	session := &authSession{}
+	nm.session = session
+
+	// without credentials, the browser answers the challenge.
+	nm.onAuthRequired(challenge("1"))
+
+	nm.credentials = &Credentials{Username: "u", Password: "p"}
+	nm.onAuthRequired(challenge("2"))
+	// the credentials were wrong for the request.
+	nm.onAuthRequired(challenge("2"))
+	// other requests still try the credentials.
+	nm.onAuthRequired(challenge("3"))
+
+	assert.Equal(t, []fetch.AuthChallengeResponseResponse{
+		fetch.AuthChallengeResponseResponseDefault,
+		fetch.AuthChallengeResponseResponseProvideCredentials,
+		fetch.AuthChallengeResponseResponseCancelAuth,
+		fetch.AuthChallengeResponseResponseProvideCredentials,
+	}, session.responses)
+	assert.Equal(t, map[authAttempt]bool{{requestID: "3"}: true}, nm.attemptedAuth)
+
+	// the proxy is authenticated with its own credentials.
+	session.responses = nil
+	proxyChallenge := challenge("4")
+	proxyChallenge.AuthChallenge.Source = fetch.AuthChallengeSourceProxy
+	nm.onAuthRequired(proxyChallenge)
+	nm.proxyCredentials = &Credentials{Username: "pu", Password: "pp"}
+	nm.onAuthRequired(proxyChallenge)
+	nm.onAuthRequired(challenge("4"))
+	nm.onAuthRequired(proxyChallenge)
+
+	assert.Equal(t, []fetch.AuthChallengeResponseResponse{
+		fetch.AuthChallengeResponseResponseDefault,
+		fetch.AuthChallengeResponseResponseProvideCredentials,
+		fetch.AuthChallengeResponseResponseProvideCredentials,
+		fetch.AuthChallengeResponseResponseCancelAuth,
+	}, session.responses)
+}
+
+func TestSetRequestInterception(t *testing.T) {
+	t.Parallel()
+
+	nm, session := newTestNetworkManager(t, k6lib.Options{})
+
+	require.NoError(t, nm.setRequestInterception(true))
+	require.NoError(t, nm.setRequestInterception(true))
+	assert.Equal(t, []string{"Network.setCacheDisabled", "Fetch.enable"}, session.cdpCalls)
+
+	// the requests aren't paused once the last route is removed.
+	session.cdpCalls = nil
+	require.NoError(t, nm.setRequestInterception(false))
+	assert.Equal(t, []string{"Network.setCacheDisabled", "Fetch.disable"}, session.cdpCalls)
+
+	// unless the credentials need the interception.
+	nm.Authenticate(&Credentials{Username: "u", Password: "p"})
+	session.cdpCalls = nil
+	require.NoError(t, nm.setRequestInterception(false))
+	assert.Empty(t, session.cdpCalls)
+}
+
+func TestNetworkManagerExtraInfo(t *testing.T) {
+	t.Parallel()
+
+	nm, _ := newTestNetworkManager(t, k6lib.Options{})
+	nm.reqIDToRequest = make(map[network.RequestID]*Request)
+	nm.reqExtraInfo = make(map[network.RequestID][]*network.EventRequestWillBeSentExtraInfo)
+	nm.respExtraInfo = make(map[network.RequestID][]*network.EventResponseReceivedExtraInfo)
+
+	ts := cdp.MonotonicTime(time.Now())
+	wt := cdp.TimeSinceEpoch(time.Now())
+	req, err := NewRequest(nm.ctx, &network.EventRequestWillBeSent{
+		RequestID: "1",
+		Request: &network.Request{
+			URL:     "https://test/",
+			Method:  "GET",
+			Headers: network.Headers{"Accept": "text/html"},
+		},
+		Timestamp: &ts,
+		WallTime:  &wt,
+	}, nil, nil, "", false)
+	require.NoError(t, err)
+
+	// the request extra info arrives before the request.
+	nm.onRequestExtraInfo(&network.EventRequestWillBeSentExtraInfo{
+		RequestID: "1",
+		Headers:   network.Headers{"Accept": "text/html", "Cookie": "a=1"},
+	})
+	nm.reqIDToRequest["1"] = req
+	nm.applyRequestExtraInfo(req)
+
+	// the response extra info arrives after the response.
+	resp := NewHTTPResponse(nm.ctx, req, &network.Response{
+		URL:     "https://test/",
+		Status:  200,
+		Headers: network.Headers{"Content-Type": "text/html"},
+	}, &ts)
+	resp.hasExtraInfo = true
+	req.response = resp
+	nm.applyResponseExtraInfo(req.requestID, resp)
+	nm.onResponseExtraInfo(&network.EventResponseReceivedExtraInfo{
+		RequestID: "1",
+		Headers:   network.Headers{"Content-Type": "text/html", "Set-Cookie": "a=1\nb=2"},
+	})
+
+	assert.Equal(t, map[string]string{"accept": "text/html", "cookie": "a=1"}, req.AllHeaders())
+	assert.Equal(t, map[string]string{"Accept": "text/html"}, req.Headers())
+	assert.Equal(t, []string{"a=1", "b=2"}, resp.HeaderValues("Set-Cookie"))
+	assert.Equal(t, "a=1\nb=2", resp.HeaderValue("set-cookie").Export())
+	assert.Empty(t, nm.reqExtraInfo)
+	assert.Empty(t, nm.respExtraInfo)
+}
+
+func TestNetworkManagerRequestMetrics(t *testing.T) {
+	t.Parallel()
+
+	newRequest := func(t *testing.T, nm *NetworkManager) *Request {
+		t.Helper()
+
+		ts := cdp.MonotonicTime(time.Now())
+		wt := cdp.TimeSinceEpoch(time.Now())
+		req, err := NewRequest(nm.ctx, &network.EventRequestWillBeSent{
+			RequestID: "1",
+			Request: &network.Request{
+				URL:    "https://test/style.css",
+				Method: "GET",
+			},
+			Type:      network.ResourceTypeStylesheet,
+			Timestamp: &ts,
+			WallTime:  &wt,
+		}, nil, nil, "", false)
+		require.NoError(t, err)
+		return req
+	}
+	// collect returns the tags of the samples by their metric names.
+	collect := func(samples chan k6metrics.SampleContainer) map[string][]map[string]string {
+		got := make(map[string][]map[string]string)
+		for len(samples) > 0 {
+			for _, s := range (<-samples).GetSamples() {
+				got[s.Metric.Name] = append(got[s.Metric.Name], s.Tags.CloneTags())
+			}
+		}
+		return got
+	}
+	setup := func(t *testing.T, opts *RequestMetricsOptions) (*NetworkManager, chan k6metrics.SampleContainer) {
+		t.Helper()
+
+		nm, _ := newTestNetworkManager(t, k6lib.Options{
+			SystemTags: &k6metrics.DefaultSystemTagSet,
+		})
+		samples := make(chan k6metrics.SampleContainer, 10)
+		nm.vu.State().Samples = samples
+		nm.k6Metrics = k6ext.RegisterCustomMetrics(k6metrics.NewRegistry())
+		nm.SetRequestMetrics(opts)
+		return nm, samples
+	}
+
+	t.Run("finished", func(t *testing.T) {
+		t.Parallel()
+
+		nm, samples := setup(t, nil)
+		req := newRequest(t, nm)
+		ts := cdp.MonotonicTime(time.Now())
+		resp := NewHTTPResponse(nm.ctx, req, &network.Response{
+			URL:    "https://test/style.css",
+			Status: 200,
+		}, &ts)
+		nm.emitRequestMetrics(req)
+		nm.emitResponseMetrics(resp, req)
+
+		got := collect(samples)
+		require.Len(t, got["data_sent"], 1)
+		assert.Equal(t, "stylesheet", got["data_sent"][0]["resource_type"])
+		assert.Equal(t, "GET", got["data_sent"][0]["method"])
+		for _, name := range []string{"http_reqs", "http_req_duration", "data_received"} {
+			require.Len(t, got[name], 1, name)
+			tags := got[name][0]
+			assert.Equal(t, "GET", tags["method"])
+			assert.Equal(t, "200", tags["status"])
+			assert.Equal(t, "stylesheet", tags["resource_type"])
+			assert.Equal(t, "false", tags["from_cache"])
+			assert.Equal(t, "https://test/style.css", tags["url"])
+		}
+		assert.Empty(t, got["browser_failed_requests"])
+	})
+	t.Run("failed", func(t *testing.T) {
+		t.Parallel()
+
+		nm, samples := setup(t, nil)
+		req := newRequest(t, nm)
+		nm.emitResponseMetrics(nil, req)
+		nm.emitFailedRequestMetric(req, "net::ERR_FAILED")
+
+		got := collect(samples)
+		require.Len(t, got["http_req_duration"], 1)
+		assert.Equal(t, "0", got["http_req_duration"][0]["status"])
+		require.Len(t, got["browser_failed_requests"], 1)
+		tags := got["browser_failed_requests"][0]
+		assert.Equal(t, "stylesheet", tags["resource_type"])
+		assert.NotContains(t, tags, "error")
+	})
+	t.Run("failed_error_tag", func(t *testing.T) {
+		t.Parallel()
+
+		nm, samples := setup(t, &RequestMetricsOptions{Enabled: true, ErrorTag: true})
+		nm.emitFailedRequestMetric(newRequest(t, nm), "net::ERR_FAILED")
+
+		got := collect(samples)
+		require.Len(t, got["browser_failed_requests"], 1)
+		assert.Equal(t, "net::ERR_FAILED", got["browser_failed_requests"][0]["error"])
+	})
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+
+		nm, samples := setup(t, &RequestMetricsOptions{Enabled: false})
+		req := newRequest(t, nm)
+		nm.emitRequestMetrics(req)
+		nm.emitResponseMetrics(nil, req)
+		nm.emitFailedRequestMetric(req, "net::ERR_FAILED")
+
+		assert.Empty(t, collect(samples))
+	})
+	t.Run("disabled_family", func(t *testing.T) {
+		t.Parallel()
+
+		nm, samples := setup(t, nil)
+		var err error
+		nm.k6Metrics, err = k6ext.RegisterCustomMetricsWithOptions(k6metrics.NewRegistry(), &k6ext.MetricsOptions{
+			Disabled: map[string]bool{k6ext.MetricFamilyRequests: true},
+		})
+		require.NoError(t, err)
+		req := newRequest(t, nm)
+		nm.emitRequestMetrics(req)
+		nm.emitResponseMetrics(nil, req)
+		nm.emitFailedRequestMetric(req, "net::ERR_FAILED")
+		nm.emitBlockedRequestMetric(&network.Request{Method: "GET", URL: "https://test/"})
+
+		assert.Empty(t, collect(samples))
+	})
+}
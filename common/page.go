@@ -73,22 +73,58 @@ type Page struct {
 	// - FrameSession.initEvents.onFrameDetached->FrameManager.frameDetached.removeFramesRecursively->Page.IsClosed
 	closedMu sync.RWMutex
 	closed   bool
+	crashed  bool
 
 	// TODO: setter change these fields (mutex?)
-	emulatedSize     *EmulatedSize
-	mediaType        MediaType
-	colorScheme      ColorScheme
-	reducedMotion    ReducedMotion
-	extraHTTPHeaders map[string]string
+	emulatedSize  *EmulatedSize
+	deviceMetrics DeviceMetrics
+	mediaType     MediaType
+	colorScheme   ColorScheme
+	reducedMotion ReducedMotion
+
+	extraHTTPHeadersMu sync.RWMutex
+	extraHTTPHeaders   map[string]string
+
+	networkConditionsMu sync.RWMutex
+	networkConditions   *NetworkConditions
 
 	backgroundPage bool
 
 	mainFrameSession *FrameSession
-	// TODO: FrameSession changes by attachFrameSession (mutex?)
-	frameSessions map[cdp.FrameID]*FrameSession
-	workers       map[target.SessionID]*Worker
-	routes        []api.Route
-	vu            k6modules.VU
+
+	// the sessions of the frames are attached by the goroutines
+	// that handle CDP events, while the actions read them.
+	frameSessionsMu sync.RWMutex
+	frameSessions   map[cdp.FrameID]*FrameSession
+
+	workersMu sync.RWMutex
+	videoMu   sync.RWMutex
+	video     *Video
+	workers   map[target.SessionID]*Worker
+	routes    routeHandlers
+	bindings  pageBindings
+	vu        k6modules.VU
+	k6Metrics *k6ext.CustomMetrics
+
+	// the tags of the metrics of the last
+	// navigation that the script started.
+	metricTagsMu   sync.RWMutex
+	navigationTags map[string]string
+
+	// runs the handlers of page events, routes and exposed functions.
+	eventLoopQueue  eventLoopQueue
+	eventHandlersMu sync.RWMutex
+	eventHandlers   map[string][]goja.Callable
+	consoleHandles  []jsHandle
+
+	pageErrorsMu sync.RWMutex
+	pageErrors   []api.PageError
+
+	fileChooserMu           sync.Mutex
+	fileChooserInterceptors int
+
+	initScriptsMu                sync.RWMutex
+	evaluateOnNewDocumentSources []string
 
 	logger *log.Logger
 }
@@ -103,6 +139,8 @@ func NewPage(
 	bp bool,
 	logger *log.Logger,
 ) (*Page, error) {
+	// the input devices slow down their events when slowMo is set.
+	input := newSlowMoSession(s)
 	p := Page{
 		BaseEventEmitter: NewBaseEventEmitter(ctx),
 		ctx:              ctx,
@@ -114,14 +152,20 @@ func NewPage(
 		mediaType:        MediaTypeScreen,
 		colorScheme:      bctx.opts.ColorScheme,
 		reducedMotion:    bctx.opts.ReducedMotion,
+		deviceMetrics: DeviceMetrics{
+			DeviceScaleFactor: bctx.opts.DeviceScaleFactor,
+			IsMobile:          bctx.opts.IsMobile,
+			HasTouch:          bctx.opts.HasTouch,
+			ScreenOrientation: bctx.opts.ScreenOrientation,
+		},
 		extraHTTPHeaders: bctx.opts.ExtraHTTPHeaders,
 		timeoutSettings:  NewTimeoutSettings(bctx.timeoutSettings),
-		Keyboard:         NewKeyboard(ctx, s),
+		Keyboard:         NewKeyboard(ctx, input),
 		jsEnabled:        true,
 		frameSessions:    make(map[cdp.FrameID]*FrameSession),
 		workers:          make(map[target.SessionID]*Worker),
-		routes:           make([]api.Route, 0),
 		vu:               k6ext.GetVU(ctx),
+		k6Metrics:        k6ext.GetCustomMetrics(ctx),
 		logger:           logger,
 	}
 
@@ -135,7 +179,7 @@ func NewPage(
 	}
 
 	var err error
-	p.frameManager = NewFrameManager(ctx, s, &p, bctx.timeoutSettings, p.logger)
+	p.frameManager = NewFrameManager(ctx, s, &p, p.timeoutSettings, p.logger)
 	p.mainFrameSession, err = NewFrameSession(ctx, s, &p, nil, tid, p.logger)
 	if err != nil {
 		p.logger.Debugf("Page:NewPage:NewFrameSession:return", "sid:%v tid:%v err:%v",
@@ -144,8 +188,8 @@ func NewPage(
 		return nil, err
 	}
 	p.frameSessions[cdp.FrameID(tid)] = p.mainFrameSession
-	p.Mouse = NewMouse(ctx, s, p.frameManager.MainFrame(), bctx.timeoutSettings, p.Keyboard)
-	p.Touchscreen = NewTouchscreen(ctx, s, p.Keyboard)
+	p.Mouse = NewMouse(ctx, input, p.frameManager.MainFrame(), p.timeoutSettings, p.Keyboard)
+	p.Touchscreen = NewTouchscreen(ctx, input, p.Keyboard, bctx.opts.HasTouch)
 
 	action := target.SetAutoAttach(true, true).WithFlatten(true)
 	if err := action.Do(cdp.WithExecutor(p.ctx, p.session)); err != nil {
@@ -155,17 +199,42 @@ func NewPage(
 	return &p, nil
 }
 
+// addWorker adds a web worker to the page and calls the worker event handlers.
+func (p *Page) addWorker(sessionID target.SessionID, w *Worker) {
+	p.logger.Debugf("Page:addWorker", "sid:%v wurl:%q", sessionID, w.url)
+
+	p.workersMu.Lock()
+	p.workers[sessionID] = w
+	p.workersMu.Unlock()
+
+	p.emit(EventPageWorker, w)
+	if p.hasEventHandlers(EventPageWorker) {
+		p.callEventHandlers(EventPageWorker, w)
+	}
+}
+
+// closeWorker removes a web worker from the page and
+// calls the workerdestroyed event handlers.
 func (p *Page) closeWorker(sessionID target.SessionID) {
 	p.logger.Debugf("Page:closeWorker", "sid:%v", sessionID)
 
-	if worker, ok := p.workers[sessionID]; ok {
-		worker.didClose()
-		delete(p.workers, sessionID)
+	p.workersMu.Lock()
+	worker, ok := p.workers[sessionID]
+	delete(p.workers, sessionID)
+	p.workersMu.Unlock()
+	if !ok {
+		return
+	}
+
+	worker.didClose()
+	p.emit(EventPageWorkerDestroyed, worker)
+	if p.hasEventHandlers(EventPageWorkerDestroyed) {
+		p.callEventHandlers(EventPageWorkerDestroyed, worker)
 	}
 }
 
 func (p *Page) defaultTimeout() time.Duration {
-	return time.Duration(p.timeoutSettings.timeout()) * time.Second
+	return p.timeoutSettings.timeout()
 }
 
 func (p *Page) didClose() {
@@ -177,48 +246,80 @@ func (p *Page) didClose() {
 	}
 	p.closedMu.Unlock()
 
+	p.disposeConsoleHandles()
+	p.finishVideo()
 	p.emit(EventPageClose, p)
 }
 
 func (p *Page) didCrash() {
 	p.logger.Debugf("Page:didCrash", "sid:%v", p.sessionID())
 
+	p.closedMu.Lock()
+	p.crashed = true
+	p.closedMu.Unlock()
+
 	p.frameManager.dispose()
+	p.frameManager.abortNavigations(ErrPageCrashed)
+	p.finishVideo()
 	p.emit(EventPageCrash, p)
+	if p.hasEventHandlers(EventPageCrash) {
+		p.callEventHandlers(EventPageCrash, p)
+	}
+}
+
+// evaluateOnNewDocument adds a script that runs in all the frames of
+// the new documents of the page.
+func (p *Page) evaluateOnNewDocument(source string) error {
+	p.initScriptsMu.Lock()
+	p.evaluateOnNewDocumentSources = append(p.evaluateOnNewDocumentSources, source)
+	p.initScriptsMu.Unlock()
+
+	for _, fs := range p.getFrameSessions() {
+		if err := fs.addScriptToEvaluateOnNewDocument(source); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (p *Page) evaluateOnNewDocument(source string) {
-	// TODO: implement
+// initScripts returns the sources of the init scripts of the page.
+func (p *Page) initScripts() []string {
+	p.initScriptsMu.RLock()
+	defer p.initScriptsMu.RUnlock()
+
+	return append([]string(nil), p.evaluateOnNewDocumentSources...)
 }
 
+// getFrameElement returns the iframe element that owns the child frame f.
+// The element is resolved in the session of the parent frame, since the
+// child frame can be out of process and have a session of its own.
 func (p *Page) getFrameElement(f *Frame) (handle *ElementHandle, _ error) {
-	if f == nil {
-		p.logger.Debugf("Page:getFrameElement", "sid:%v frame:nil", p.sessionID())
-	} else {
-		p.logger.Debugf("Page:getFrameElement", "sid:%v fid:%s furl:%s",
-			p.sessionID(), f.ID(), f.URL())
-	}
+	p.logger.Debugf("Page:getFrameElement", "sid:%v fid:%s furl:%s",
+		p.sessionID(), f.ID(), f.URL())
 
+	if f.IsDetached() {
+		return nil, ErrFrameDetached
+	}
 	parent := f.parentFrame
 	if parent == nil {
-		return nil, errors.New("frame has been detached 1")
+		return nil, errors.New("main frame has no frame element")
 	}
 
-	parentSession := p.getFrameSession(cdp.FrameID(parent.ID()))
+	parentSession := p.sessionForFrame(parent)
 	action := dom.GetFrameOwner(cdp.FrameID(f.ID()))
-	backendNodeId, _, err := action.Do(cdp.WithExecutor(p.ctx, parentSession.session))
+	backendNodeID, _, err := action.Do(cdp.WithExecutor(p.ctx, parentSession.session))
 	if err != nil {
 		if strings.Contains(err.Error(), "frame with the given id was not found") {
-			return nil, errors.New("frame has been detached")
+			return nil, ErrFrameDetached
 		}
 		return nil, fmt.Errorf("getting frame owner: %w", err)
 	}
 
-	parent = f.parentFrame
-	if parent == nil {
-		return nil, errors.New("frame has been detached 2")
+	parent.waitForExecutionContext(mainWorld)
+	if f.IsDetached() {
+		return nil, ErrFrameDetached
 	}
-	return parent.adoptBackendNodeID(mainWorld, backendNodeId)
+	return parent.adoptBackendNodeID(mainWorld, backendNodeID)
 }
 
 func (p *Page) getOwnerFrame(apiCtx context.Context, h *ElementHandle) cdp.FrameID {
@@ -272,18 +373,91 @@ func (p *Page) getOwnerFrame(apiCtx context.Context, h *ElementHandle) cdp.Frame
 }
 
 func (p *Page) attachFrameSession(fid cdp.FrameID, fs *FrameSession) {
-	p.logger.Debugf("Page:attachFrameSession", "sid:%v fid=%v", p.session.ID(), fid)
-	fs.page.frameSessions[fid] = fs
+	p.logger.Debugf("Page:attachFrameSession", "sid:%v fid=%v", p.sessionID(), fid)
+	p.frameSessionsMu.Lock()
+	defer p.frameSessionsMu.Unlock()
+
+	p.frameSessions[fid] = fs
 }
 
 func (p *Page) getFrameSession(frameID cdp.FrameID) *FrameSession {
 	p.logger.Debugf("Page:getFrameSession", "sid:%v fid:%v", p.sessionID(), frameID)
 
+	p.frameSessionsMu.RLock()
+	defer p.frameSessionsMu.RUnlock()
+
 	return p.frameSessions[frameID]
 }
 
+// sessionForFrame returns the frame session of the closest frame, starting
+// from f and going up its parents, that has a session of its own.
+func (p *Page) sessionForFrame(f *Frame) *FrameSession {
+	for ; f != nil; f = f.parentFrame {
+		if fs := p.getFrameSession(cdp.FrameID(f.ID())); fs != nil {
+			return fs
+		}
+	}
+	return p.mainFrameSession
+}
+
+// hasRoutes reports whether the page or its browser context has routes.
 func (p *Page) hasRoutes() bool {
-	return len(p.routes) > 0
+	return p.routes.len() > 0 || p.browserCtx.routes.len() > 0
+}
+
+// updateRequestInterception enables the interception of the requests
+// of the page if it has routes, and disables it otherwise, unless the
+// page needs it for something else.
+func (p *Page) updateRequestInterception() error {
+	for _, fs := range p.getFrameSessions() {
+		if err := fs.updateRequestInterception(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// routeHandler returns the route handler of the URL, and the queue that runs
+// it on the event loop, or nil if no handler matches the URL. The handlers
+// of the page take precedence over the handlers of its browser context.
+func (p *Page) routeHandler(url string) (*routeHandler, *eventLoopQueue) {
+	if h := p.routes.match(url); h != nil {
+		return h, &p.eventLoopQueue
+	}
+	if h := p.browserCtx.routes.match(url); h != nil {
+		return h, &p.browserCtx.eventLoopQueue
+	}
+	return nil, nil
+}
+
+// handleRoute queues a call of the route handler with the route to run on
+// the event loop. The call also runs while synchronous calls wait for
+// navigations, whichever comes first. It doesn't wait for the call.
+func (p *Page) handleRoute(h *routeHandler, queue *eventLoopQueue, route *Route) {
+	p.browserCtx.addPendingRoute(route, h)
+
+	var once sync.Once
+	task := func() error {
+		var err error
+		once.Do(func() {
+			p.browserCtx.removePendingRoute(route)
+			// the request was continued when its handler was
+			// removed, or when the browser context closed.
+			if route.isHandled() {
+				return
+			}
+			if p.IsClosed() {
+				route.continueUnhandled()
+				return
+			}
+			err = h.handle(p.vu.Runtime(), route)
+		})
+		return err
+	}
+	queue.push(task)
+	if q := getRouteQueue(p.ctx); q != nil {
+		q.push(task)
+	}
 }
 
 func (p *Page) resetViewport() error {
@@ -300,6 +474,25 @@ func (p *Page) setEmulatedSize(emulatedSize *EmulatedSize) error {
 	return p.mainFrameSession.updateViewport()
 }
 
+// setDeviceMetrics sets the device metrics that the page emulates. They
+// apply to the viewport from its next update.
+func (p *Page) setDeviceMetrics(metrics DeviceMetrics) error {
+	p.logger.Debugf("Page:setDeviceMetrics", "sid:%v metrics:%+v", p.sessionID(), metrics)
+
+	hasTouchChanged := metrics.HasTouch != p.deviceMetrics.HasTouch
+	p.deviceMetrics = metrics
+	if !hasTouchChanged {
+		return nil
+	}
+	p.Touchscreen.hasTouch = metrics.HasTouch
+	for _, fs := range p.getFrameSessions() {
+		if err := fs.updateTouchEmulation(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (p *Page) setViewportSize(viewportSize *Size) error {
 	p.logger.Debugf("Page:setViewportSize", "sid:%v vps:%v",
 		p.sessionID(), viewportSize)
@@ -318,7 +511,7 @@ func (p *Page) setViewportSize(viewportSize *Size) error {
 func (p *Page) updateExtraHTTPHeaders() {
 	p.logger.Debugf("Page:updateExtraHTTPHeaders", "sid:%v", p.sessionID())
 
-	for _, fs := range p.frameSessions {
+	for _, fs := range p.getFrameSessions() {
 		fs.updateExtraHTTPHeaders(false)
 	}
 }
@@ -326,7 +519,7 @@ func (p *Page) updateExtraHTTPHeaders() {
 func (p *Page) updateGeolocation() error {
 	p.logger.Debugf("Page:updateGeolocation", "sid:%v", p.sessionID())
 
-	for _, fs := range p.frameSessions {
+	for _, fs := range p.getFrameSessions() {
 		p.logger.Debugf("Page:updateGeolocation:frameSession",
 			"sid:%v tid:%v wid:%v",
 			p.sessionID(), fs.targetID, fs.windowID)
@@ -345,15 +538,40 @@ func (p *Page) updateGeolocation() error {
 func (p *Page) updateOffline() {
 	p.logger.Debugf("Page:updateOffline", "sid:%v", p.sessionID())
 
-	for _, fs := range p.frameSessions {
+	for _, fs := range p.getFrameSessions() {
 		fs.updateOffline(false)
 	}
+	p.updateWorkersNetworkConditions()
+}
+
+// updateWorkersNetworkConditions applies the network conditions and
+// the offline mode of the page to its web workers.
+func (p *Page) updateWorkersNetworkConditions() {
+	p.workersMu.RLock()
+	defer p.workersMu.RUnlock()
+
+	action, _ := p.workerNetworkConditionsAction()
+	for _, w := range p.workers {
+		if err := action.Do(cdp.WithExecutor(p.ctx, w.session)); err != nil {
+			k6ext.Panic(p.ctx, "emulating network conditions of worker %q: %w", w.url, err)
+		}
+	}
+}
+
+// workerNetworkConditionsAction returns the action that emulates the network
+// conditions and the offline mode of the page in a web worker, and whether
+// they differ from the default conditions.
+func (p *Page) workerNetworkConditionsAction() (Action, bool) {
+	c := p.getNetworkConditions()
+	offline := p.browserCtx != nil && p.browserCtx.opts.Offline
+
+	return c.emulateAction(offline), c != nil || offline
 }
 
 func (p *Page) updateHttpCredentials() {
 	p.logger.Debugf("Page:updateHttpCredentials", "sid:%v", p.sessionID())
 
-	for _, fs := range p.frameSessions {
+	for _, fs := range p.getFrameSessions() {
 		fs.updateHTTPCredentials(false)
 	}
 }
@@ -365,9 +583,18 @@ func (p *Page) viewportSize() Size {
 	}
 }
 
-// AddInitScript adds script to run in all new frames.
+// AddInitScript adds a script that runs in all the frames of the page,
+// before any of the page scripts. It runs from the next navigation.
 func (p *Page) AddInitScript(script goja.Value, arg goja.Value) {
-	k6ext.Panic(p.ctx, "Page.addInitScript(script, arg) has not been implemented yet")
+	p.logger.Debugf("Page:AddInitScript", "sid:%v", p.sessionID())
+
+	source, err := initScriptSource(p.vu.Runtime(), script, arg)
+	if err != nil {
+		k6ext.Panic(p.ctx, "adding init script: %w", err)
+	}
+	if err := p.evaluateOnNewDocument(source); err != nil {
+		k6ext.Panic(p.ctx, "adding init script: %w", err)
+	}
 }
 
 func (p *Page) AddScriptTag(opts goja.Value) {
@@ -418,10 +645,26 @@ func (p *Page) Click(selector string, opts goja.Value) {
 }
 
 // Close closes the page.
+// Close closes the page. With the runBeforeUnload option, it runs the
+// beforeunload handlers of the page first, which can open a beforeunload
+// dialog, and doesn't wait for the page to close.
 func (p *Page) Close(opts goja.Value) {
 	p.logger.Debugf("Page:Close", "sid:%v", p.sessionID())
 
-	p.browserCtx.Close()
+	parsedOpts := NewPageCloseOptions()
+	if err := parsedOpts.Parse(p.ctx, opts); err != nil {
+		k6ext.Panic(p.ctx, "parsing page close options: %w", err)
+	}
+	if !parsedOpts.RunBeforeUnload {
+		p.browserCtx.Close()
+		return
+	}
+
+	p.reportWebVitals()
+	action := cdppage.Close()
+	if err := action.Do(cdp.WithExecutor(p.ctx, p.session)); err != nil {
+		k6ext.Panic(p.ctx, "closing page with beforeunload handlers: %w", err)
+	}
 }
 
 // Content returns the HTML content of the page.
@@ -449,8 +692,11 @@ func (p *Page) DispatchEvent(selector string, typ string, eventInit goja.Value,
 	p.MainFrame().DispatchEvent(selector, typ, eventInit, opts)
 }
 
+// DragAndDrop drags the source element onto the target element in the main frame.
 func (p *Page) DragAndDrop(source string, target string, opts goja.Value) {
-	k6ext.Panic(p.ctx, "Page.DragAndDrop(source, target, opts) has not been implemented yet")
+	p.logger.Debugf("Page:DragAndDrop", "sid:%v source:%q target:%q", p.sessionID(), source, target)
+
+	p.MainFrame().DragAndDrop(source, target, opts)
 }
 
 func (p *Page) EmulateMedia(opts goja.Value) {
@@ -465,7 +711,7 @@ func (p *Page) EmulateMedia(opts goja.Value) {
 	p.colorScheme = parsedOpts.ColorScheme
 	p.reducedMotion = parsedOpts.ReducedMotion
 
-	for _, fs := range p.frameSessions {
+	for _, fs := range p.getFrameSessions() {
 		if err := fs.updateEmulateMedia(false); err != nil {
 			k6ext.Panic(p.ctx, "emulating media: %w", err)
 		}
@@ -474,6 +720,40 @@ func (p *Page) EmulateMedia(opts goja.Value) {
 	applySlowMo(p.ctx)
 }
 
+// EmulateNetworkConditions throttles the network of the page, its frames and
+// its web workers with the conditions, or with a preset such as 'Slow 3G'.
+// Null restores the default conditions.
+func (p *Page) EmulateNetworkConditions(conditions goja.Value) {
+	p.logger.Debugf("Page:EmulateNetworkConditions", "sid:%v", p.sessionID())
+
+	var c *NetworkConditions
+	if conditions != nil && !goja.IsUndefined(conditions) && !goja.IsNull(conditions) {
+		c = NewNetworkConditions()
+		if err := c.Parse(p.ctx, conditions); err != nil {
+			k6ext.Panic(p.ctx, "parsing network conditions: %w", err)
+		}
+	}
+
+	p.networkConditionsMu.Lock()
+	p.networkConditions = c
+	p.networkConditionsMu.Unlock()
+
+	for _, fs := range p.getFrameSessions() {
+		if err := fs.updateNetworkConditions(false); err != nil {
+			k6ext.Panic(p.ctx, "%w", err)
+		}
+	}
+
+	p.updateWorkersNetworkConditions()
+}
+
+func (p *Page) getNetworkConditions() *NetworkConditions {
+	p.networkConditionsMu.RLock()
+	defer p.networkConditionsMu.RUnlock()
+
+	return p.networkConditions
+}
+
 // EmulateVisionDeficiency activates/deactivates emulation of a vision deficiency.
 func (p *Page) EmulateVisionDeficiency(typ string) {
 	p.logger.Debugf("Page:EmulateVisionDeficiency", "sid:%v typ:%s", p.sessionID(), typ)
@@ -516,8 +796,21 @@ func (p *Page) ExposeBinding(name string, callback goja.Callable, opts goja.Valu
 	k6ext.Panic(p.ctx, "Page.exposeBinding(name, callback) has not been implemented yet")
 }
 
+// ExposeFunction adds a function with the given name to the global object of
+// every frame of the page, including the frames of later navigations. When the
+// function is called, it runs callback with its arguments and returns a Promise
+// that resolves to the JSON serializable result of callback, or rejects if
+// callback throws.
+//
+// The callback runs on the event loop, so the page cannot wait for its result
+// while the script is blocked in a synchronous call, such as page.evaluate.
+// Until the page is closed, the event loop keeps waiting for the calls.
 func (p *Page) ExposeFunction(name string, callback goja.Callable) {
-	k6ext.Panic(p.ctx, "Page.exposeFunction(name, callback) has not been implemented yet")
+	p.logger.Debugf("Page:ExposeFunction", "sid:%v name:%q", p.sessionID(), name)
+
+	if err := p.exposeFunction(name, callback); err != nil {
+		k6ext.Panic(p.ctx, "exposing function %q: %w", name, err)
+	}
 }
 
 func (p *Page) Fill(selector string, value string, opts goja.Value) {
@@ -532,8 +825,31 @@ func (p *Page) Focus(selector string, opts goja.Value) {
 	p.MainFrame().Focus(selector, opts)
 }
 
+// Frame returns a frame of the page that matches the frame selector, or nil
+// if no frame matches it. The frame selector is either the name of the frame,
+// or an object with the name and a URL pattern of the frame (see
+// PageFrameOptions). Detached frames never match.
 func (p *Page) Frame(frameSelector goja.Value) api.Frame {
-	k6ext.Panic(p.ctx, "Page.frame(frameSelector) has not been implemented yet")
+	p.logger.Debugf("Page:Frame", "sid:%v", p.sessionID())
+
+	parsedOpts := NewPageFrameOptions()
+	if err := parsedOpts.Parse(p.ctx, frameSelector); err != nil {
+		k6ext.Panic(p.ctx, "parsing frame selector: %w", err)
+	}
+	for _, f := range p.frameManager.Frames() {
+		f := f.(*Frame)
+		if f.IsDetached() {
+			continue
+		}
+		if parsedOpts.Name != "" && f.Name() != parsedOpts.Name {
+			continue
+		}
+		if parsedOpts.URL != nil && !parsedOpts.URL(f.URL()) {
+			continue
+		}
+		return f
+	}
+
 	return nil
 }
 
@@ -597,6 +913,14 @@ func (p *Page) IsClosed() bool {
 	return p.closed
 }
 
+// isClosedOrCrashed returns true if the page closed or crashed.
+func (p *Page) isClosedOrCrashed() bool {
+	p.closedMu.RLock()
+	defer p.closedMu.RUnlock()
+
+	return p.closed || p.crashed
+}
+
 func (p *Page) IsDisabled(selector string, opts goja.Value) bool {
 	p.logger.Debugf("Page:IsDisabled", "sid:%v selector:%s", p.sessionID(), selector)
 
@@ -649,11 +973,147 @@ func (p *Page) MainFrame() api.Frame {
 	return mf
 }
 
+// On registers a handler that is called on the event loop every time
+// the page emits the event. The supported events are:
+//   - console: the handler is called with a ConsoleMessage.
+//   - dialog: the handler is called with a Dialog, and must accept or
+//     dismiss it. Without handlers, dialogs are dismissed, and beforeunload
+//     dialogs are accepted.
+//   - download: the handler is called with a Download.
+//   - filechooser: the handler is called with a FileChooser. The page
+//     intercepts its file choosers from then on, instead of opening them.
+//   - pageerror: the handler is called with a PageError.
+//   - popup: the handler is called with the Page that the page opened.
+//   - worker: the handler is called with a Worker that the page started.
+//   - workerdestroyed: the handler is called with a Worker that the page
+//     terminated.
+//
+// The page waits for an answer to its dialogs, and the handlers don't run
+// until the event loop is free. So, when there are dialog handlers, a call
+// that waits for a dialog to be answered, such as a click that opens it,
+// times out.
+func (p *Page) On(event string, handler goja.Callable) {
+	p.logger.Debugf("Page:On", "sid:%v event:%q", p.sessionID(), event)
+
+	if _, ok := pageHandlerEvents[event]; !ok {
+		k6ext.Panic(p.ctx, "unknown page event: %q", event)
+	}
+	if handler == nil {
+		k6ext.Panic(p.ctx, "missing handler of page event %q", event)
+	}
+
+	p.eventHandlersMu.Lock()
+	if p.eventHandlers == nil {
+		p.eventHandlers = make(map[string][]goja.Callable)
+	}
+	first := len(p.eventHandlers[event]) == 0
+	p.eventHandlers[event] = append(p.eventHandlers[event], handler)
+	p.eventHandlersMu.Unlock()
+
+	// the handlers are kept until the page closes,
+	// so they only need a single interceptor.
+	if event == EventPageFilechooser && first {
+		if err := p.addFileChooserInterceptor(); err != nil {
+			k6ext.Panic(p.ctx, "intercepting file choosers: %w", err)
+		}
+	}
+
+	p.startEventLoopQueue()
+}
+
+// pageHandlerEvents are the page events that Page.on can handle.
+var pageHandlerEvents = map[string]struct{}{
+	EventPageConsole:         {},
+	EventPageCrash:           {},
+	EventPageDialog:          {},
+	EventPageDownload:        {},
+	EventPageError:           {},
+	EventPageFilechooser:     {},
+	EventPagePopup:           {},
+	EventPageRequestFailed:   {},
+	EventPageWorker:          {},
+	EventPageWorkerDestroyed: {},
+}
+
+// hasEventHandlers reports whether there are handlers for the event.
+func (p *Page) hasEventHandlers(event string) bool {
+	p.eventHandlersMu.RLock()
+	defer p.eventHandlersMu.RUnlock()
+
+	return len(p.eventHandlers[event]) > 0
+}
+
+// callEventHandlers queues a call of the handlers of the event with the
+// argument to run on the event loop. It doesn't wait for the handlers.
+func (p *Page) callEventHandlers(event string, arg interface{}) {
+	p.eventLoopQueue.push(func() error {
+		p.eventHandlersMu.RLock()
+		handlers := p.eventHandlers[event]
+		p.eventHandlersMu.RUnlock()
+
+		v := p.vu.Runtime().ToValue(arg)
+		for _, handler := range handlers {
+			if _, err := handler(goja.Undefined(), v); err != nil {
+				return fmt.Errorf("calling handler of page event %q: %w", event, err)
+			}
+		}
+		return nil
+	})
+}
+
+// startEventLoopQueue starts running the handlers of page events and exposed
+// functions on the event loop until the page closes or the iteration ends.
+// It must be called on the event loop.
+func (p *Page) startEventLoopQueue() {
+	ctx, cancel := context.WithCancel(p.ctx)
+	closed := make(chan Event)
+	p.on(ctx, []string{EventPageClose}, closed)
+	if !p.eventLoopQueue.start(ctx, p.vu) {
+		cancel()
+		return
+	}
+	if p.IsClosed() {
+		cancel()
+		return
+	}
+	// the queue stops when the iteration ends, and so does the watching.
+	iterDone := p.vu.Context().Done()
+	go func() {
+		defer cancel()
+		select {
+		case <-closed:
+		case <-ctx.Done():
+		case <-iterDone:
+		}
+	}()
+}
+
+// onPopup emits the page that the page opened, and calls the
+// popup event handlers with it, if there are any.
+func (p *Page) onPopup(popup *Page) {
+	p.emit(EventPagePopup, popup)
+	if p.hasEventHandlers(EventPagePopup) {
+		p.callEventHandlers(EventPagePopup, popup)
+	}
+}
+
 // Opener returns the opener of the target.
 func (p *Page) Opener() api.Page {
 	return p.opener
 }
 
+// PageErrors returns the uncaught exceptions that were thrown in the page
+// and in its frames, in the order they were thrown.
+func (p *Page) PageErrors() []api.PageError {
+	p.pageErrorsMu.RLock()
+	defer p.pageErrorsMu.RUnlock()
+
+	errs := make([]api.PageError, len(p.pageErrors))
+	copy(errs, p.pageErrors)
+
+	return errs
+}
+
 func (p *Page) Pause() {
 	k6ext.Panic(p.ctx, "Page.pause() has not been implemented yet")
 }
@@ -685,8 +1145,9 @@ func (p *Page) QueryAll(selector string) []api.ElementHandle {
 // Reload will reload the current page.
 func (p *Page) Reload(opts goja.Value) api.Response {
 	p.logger.Debugf("Page:Reload", "sid:%v", p.sessionID())
+	p.startNavigation()
 
-	parsedOpts := NewPageReloadOptions(LifecycleEventLoad, p.defaultTimeout())
+	parsedOpts := NewPageReloadOptions(LifecycleEventLoad, p.timeoutSettings.navigationTimeout())
 	if err := parsedOpts.Parse(p.ctx, opts); err != nil {
 		k6ext.Panic(p.ctx, "parsing reload options: %w", err)
 	}
@@ -697,23 +1158,37 @@ func (p *Page) Reload(opts goja.Value) api.Response {
 	defer evCancelFn() // Remove event handler
 
 	action := cdppage.Reload()
-	if err := action.Do(cdp.WithExecutor(p.ctx, p.session)); err != nil {
+	if err := action.Do(cdp.WithExecutor(p.ctx, newSlowMoSession(p.session))); err != nil {
 		k6ext.Panic(p.ctx, "reloading page: %w", err)
 	}
 
-	var event *NavigationEvent
-	select {
-	case <-p.ctx.Done():
-	case <-time.After(parsedOpts.Timeout):
-		k6ext.Panic(p.ctx, "%w", ErrTimedOut)
-	case data := <-ch:
-		event = data.(*NavigationEvent)
+	var (
+		event    *NavigationEvent
+		routes   = getRouteQueue(p.ctx)
+		timedOut = timeoutAfter(parsedOpts.Timeout)
+	)
+wait:
+	for {
+		select {
+		case <-p.ctx.Done():
+			k6ext.Panic(p.ctx, "reloading page: %w", p.ctx.Err())
+		case <-timedOut:
+			k6ext.Panic(p.ctx, "%w", ErrTimedOut)
+		case data := <-ch:
+			event = data.(*NavigationEvent)
+			break wait
+		case <-routes.pending():
+			routes.run(p.vu.Runtime())
+		}
 	}
 
 	if p.frameManager.mainFrame.hasSubtreeLifecycleEventFired(parsedOpts.WaitUntil) {
-		_, _ = waitForEvent(p.ctx, p.frameManager.MainFrame(), []string{EventFrameAddLifecycle}, func(data interface{}) bool {
+		_, err := waitForEvent(p.ctx, p.frameManager.MainFrame(), []string{EventFrameAddLifecycle}, func(data interface{}) bool {
 			return data.(LifecycleEvent) == parsedOpts.WaitUntil
 		}, parsedOpts.Timeout)
+		if err != nil {
+			k6ext.Panic(p.ctx, "reloading page: %w", err)
+		}
 	}
 
 	var resp *Response
@@ -723,12 +1198,26 @@ func (p *Page) Reload(opts goja.Value) api.Response {
 			resp = req.response
 		}
 	}
-	applySlowMo(p.ctx)
 	return resp
 }
 
-func (p *Page) Route(url goja.Value, handler goja.Callable) {
-	k6ext.Panic(p.ctx, "Page.route(url, handler) has not been implemented yet")
+// Route calls the handler with a Route for every request of the page to a
+// URL that matches the URL pattern. The request is paused until the handler
+// aborts, continues or fulfills the route, or returns, which continues the
+// request as is. The handlers run on the event loop, and while page.goto,
+// page.reload, and the other calls that wait for navigations wait.
+func (p *Page) Route(url goja.Value, handler goja.Value) {
+	p.logger.Debugf("Page:Route", "sid:%v url:%v", p.sessionID(), url)
+
+	h, err := newRouteHandler(p.vu.Runtime(), url, handler)
+	if err != nil {
+		k6ext.Panic(p.ctx, "adding route: %w", err)
+	}
+	p.routes.add(h)
+	p.startEventLoopQueue()
+	if err := p.updateRequestInterception(); err != nil {
+		k6ext.Panic(p.ctx, "adding route: %w", err)
+	}
 }
 
 // Screenshot will instruct Chrome to save a screenshot of the current page and save it to specified file.
@@ -737,6 +1226,7 @@ func (p *Page) Screenshot(opts goja.Value) goja.ArrayBuffer {
 	if err := parsedOpts.Parse(p.ctx, opts); err != nil {
 		k6ext.Panic(p.ctx, "parsing screenshot options: %w", err)
 	}
+	parsedOpts.Path = p.artifactPath(parsedOpts.Path)
 	s := newScreenshotter(p.ctx)
 	buf, err := s.screenshotPage(p, parsedOpts)
 	if err != nil {
@@ -776,24 +1266,52 @@ func (p *Page) SetDefaultTimeout(timeout int64) {
 func (p *Page) SetExtraHTTPHeaders(headers map[string]string) {
 	p.logger.Debugf("Page:SetExtraHTTPHeaders", "sid:%v", p.sessionID())
 
-	p.extraHTTPHeaders = headers
+	if err := validateExtraHTTPHeaders(headers); err != nil {
+		k6ext.Panic(p.ctx, "setting extra HTTP headers: %w", err)
+	}
+
+	// copy the headers so that later changes to the
+	// headers map doesn't change the page headers.
+	hs := make(map[string]string, len(headers))
+	for k, v := range headers {
+		hs[k] = v
+	}
+	p.extraHTTPHeadersMu.Lock()
+	p.extraHTTPHeaders = hs
+	p.extraHTTPHeadersMu.Unlock()
+
 	p.updateExtraHTTPHeaders()
 }
 
+func (p *Page) getExtraHTTPHeaders() map[string]string {
+	p.extraHTTPHeadersMu.RLock()
+	defer p.extraHTTPHeadersMu.RUnlock()
+
+	return p.extraHTTPHeaders
+}
+
+// SetInputFiles sets the files of the first file input element that matches the selector.
 func (p *Page) SetInputFiles(selector string, files goja.Value, opts goja.Value) {
-	k6ext.Panic(p.ctx, "Page.textContent(selector, opts) has not been implemented yet")
-	// TODO: needs slowMo
+	p.logger.Debugf("Page:SetInputFiles", "sid:%v selector:%s", p.sessionID(), selector)
+
+	p.frameManager.MainFrame().SetInputFiles(selector, files, opts)
 }
 
 // SetViewportSize will update the viewport width and height.
+// SetViewportSize sets the viewport size of the page, and optionally the
+// deviceScaleFactor, isMobile, hasTouch and screenOrientation of the device
+// it emulates. The page is laid out again without reloading it.
 func (p *Page) SetViewportSize(viewportSize goja.Value) {
 	p.logger.Debugf("Page:SetViewportSize", "sid:%v", p.sessionID())
 
-	s := &Size{}
-	if err := s.Parse(p.ctx, viewportSize); err != nil {
+	opts := NewPageSetViewportSizeOptions(p.deviceMetrics)
+	if err := opts.Parse(p.ctx, viewportSize); err != nil {
 		k6ext.Panic(p.ctx, "parsing viewport size: %w", err)
 	}
-	if err := p.setViewportSize(s); err != nil {
+	if err := p.setDeviceMetrics(opts.DeviceMetrics); err != nil {
+		k6ext.Panic(p.ctx, "setting device metrics: %w", err)
+	}
+	if err := p.setViewportSize(&opts.Size); err != nil {
 		k6ext.Panic(p.ctx, "setting viewport size: %w", err)
 	}
 	applySlowMo(p.ctx)
@@ -824,8 +1342,21 @@ func (p *Page) Type(selector string, text string, opts goja.Value) {
 	p.MainFrame().Type(selector, text, opts)
 }
 
-func (p *Page) Unroute(url goja.Value, handler goja.Callable) {
-	k6ext.Panic(p.ctx, "Page.unroute(url, handler) has not been implemented yet")
+// Unroute removes the route handlers of the URL pattern that were added with
+// Page.Route, or only the given handler of it if handler is set. The paused
+// requests that wait for the removed handlers continue as is, and the page
+// stops intercepting the requests when it has no routes left.
+func (p *Page) Unroute(url goja.Value, handler goja.Value) {
+	p.logger.Debugf("Page:Unroute", "sid:%v url:%v", p.sessionID(), url)
+
+	removed := p.routes.remove(url, handler)
+	if len(removed) == 0 {
+		return
+	}
+	p.browserCtx.continueRemovedRoutes(removed)
+	if err := p.updateRequestInterception(); err != nil {
+		k6ext.Panic(p.ctx, "removing route: %w", err)
+	}
 }
 
 // URL returns the location of the page.
@@ -834,9 +1365,12 @@ func (p *Page) URL() string {
 	return p.Evaluate(rt.ToValue("document.location.toString()")).(string)
 }
 
-// Video returns information of recorded video.
+// Video returns the video of the page, or nil if the
+// browser context doesn't record videos.
 func (p *Page) Video() api.Video {
-	k6ext.Panic(p.ctx, "Page.video() has not been implemented yet")
+	if v := p.getVideo(); v != nil {
+		return v
+	}
 	return nil
 }
 
@@ -851,10 +1385,102 @@ func (p *Page) ViewportSize() map[string]float64 {
 	}
 }
 
-// WaitForEvent waits for the specified event to trigger.
-func (p *Page) WaitForEvent(event string, optsOrPredicate goja.Value) interface{} {
-	k6ext.Panic(p.ctx, "Page.waitForEvent(event, optsOrPredicate) has not been implemented yet")
-	return nil
+// WaitForEvent returns a promise that resolves to the data of the next event
+// of the page for which the predicate returns a truthy value. The second
+// argument is either the predicate, or options with the predicate and the
+// timeout. The events that can be waited for are in pageWaitForEvents.
+//
+// Like WaitForRequest, call this method before the action that causes the
+// event, and wait for the returned promise after it.
+func (p *Page) WaitForEvent(event string, optsOrPredicate goja.Value) *goja.Promise {
+	p.logger.Debugf("Page:WaitForEvent", "sid:%v event:%q", p.sessionID(), event)
+
+	if _, ok := pageWaitForEvents[event]; !ok {
+		k6ext.Panic(p.ctx, "unsupported page event to wait for: %q", event)
+	}
+	parsedOpts := NewPageWaitForEventOptions(p.defaultTimeout())
+	if err := parsedOpts.Parse(p.ctx, optsOrPredicate); err != nil {
+		k6ext.Panic(p.ctx, "parsing waitForEvent options: %w", err)
+	}
+
+	return p.waitForEventPromise("waitForEvent", event, parsedOpts.Timeout, nil, parsedOpts.Predicate)
+}
+
+// pageWaitForEvents are the page events that Page.waitForEvent can wait for.
+var pageWaitForEvents = map[string]struct{}{
+	EventPageClose:           {},
+	EventPageCrash:           {},
+	EventPageDownload:        {},
+	EventPageFilechooser:     {},
+	EventPagePopup:           {},
+	EventPageRequest:         {},
+	EventPageRequestFailed:   {},
+	EventPageRequestFinished: {},
+	EventPageResponse:        {},
+	EventPageWorker:          {},
+	EventPageWorkerDestroyed: {},
+}
+
+// WaitForFileChooser returns a promise that resolves to the next file
+// chooser that the page opens. The page intercepts its file choosers
+// until the promise resolves or rejects, instead of opening them.
+//
+// Like WaitForRequest, call this method before the action that opens the
+// file chooser, and wait for the returned promise after it.
+func (p *Page) WaitForFileChooser(opts goja.Value) *goja.Promise {
+	p.logger.Debugf("Page:WaitForFileChooser", "sid:%v", p.sessionID())
+
+	parsedOpts := NewPageWaitForFileChooserOptions(p.defaultTimeout())
+	if err := parsedOpts.Parse(p.ctx, opts); err != nil {
+		k6ext.Panic(p.ctx, "parsing waitForFileChooser options: %w", err)
+	}
+	if err := p.addFileChooserInterceptor(); err != nil {
+		k6ext.Panic(p.ctx, "intercepting file choosers: %w", err)
+	}
+
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+	if parsedOpts.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(p.ctx, parsedOpts.Timeout)
+	} else {
+		ctx, cancel = context.WithCancel(p.ctx)
+	}
+	ch := make(chan Event)
+	p.on(ctx, []string{EventPageFilechooser}, ch)
+
+	rt := p.vu.Runtime()
+	cb := p.vu.RegisterCallback()
+	promise, resolve, reject := rt.NewPromise()
+
+	go func() {
+		defer cancel()
+		defer func() {
+			if err := p.removeFileChooserInterceptor(); err != nil {
+				p.logger.Debugf("Page:WaitForFileChooser", "sid:%v err:%v", p.sessionID(), err)
+			}
+		}()
+
+		select {
+		case <-ctx.Done():
+			err := ctx.Err()
+			if errors.Is(err, context.DeadlineExceeded) {
+				err = fmt.Errorf("%w after %s", ErrTimedOut, parsedOpts.Timeout)
+			}
+			cb(func() error {
+				reject(fmt.Errorf("waitForFileChooser promise rejected: %w", err))
+				return nil
+			})
+		case ev := <-ch:
+			cb(func() error {
+				resolve(ev.data)
+				return nil
+			})
+		}
+	}()
+
+	return promise
 }
 
 // WaitForFunction waits for the given predicate to return a truthy value.
@@ -878,14 +1504,178 @@ func (p *Page) WaitForNavigation(opts goja.Value) api.Response {
 	return p.frameManager.MainFrame().WaitForNavigation(opts)
 }
 
-func (p *Page) WaitForRequest(urlOrPredicate, opts goja.Value) api.Request {
-	k6ext.Panic(p.ctx, "Page.waitForRequest(urlOrPredicate, opts) has not been implemented yet")
-	return nil
+// WaitForRequest returns a promise that resolves to the first request
+// whose URL matches the given string, glob or regular expression, or for
+// which the given predicate function returns a truthy value.
+//
+// Only the requests that start after this call are matched. So, to wait for
+// a request caused by an action, call this method before the action, and
+// wait for the returned promise after it.
+func (p *Page) WaitForRequest(urlOrPredicate, opts goja.Value) *goja.Promise {
+	p.logger.Debugf("Page:WaitForRequest", "sid:%v", p.sessionID())
+
+	return p.waitForNetworkEvent("waitForRequest", EventPageRequest, urlOrPredicate, opts)
 }
 
-func (p *Page) WaitForResponse(urlOrPredicate, opts goja.Value) api.Response {
-	k6ext.Panic(p.ctx, "Page.waitForResponse(urlOrPredicate, opts) has not been implemented yet")
-	return nil
+// WaitForResponse returns a promise that resolves to the first response
+// whose URL matches the given string, glob or regular expression, or for
+// which the given predicate function returns a truthy value.
+//
+// Like WaitForRequest, only the responses received after this call are matched.
+func (p *Page) WaitForResponse(urlOrPredicate, opts goja.Value) *goja.Promise {
+	p.logger.Debugf("Page:WaitForResponse", "sid:%v", p.sessionID())
+
+	return p.waitForNetworkEvent("waitForResponse", EventPageResponse, urlOrPredicate, opts)
+}
+
+// waitForNetworkEvent returns a promise that resolves to the data of the
+// first request or response event that matches urlOrPredicate.
+func (p *Page) waitForNetworkEvent(method, event string, urlOrPredicate, opts goja.Value) *goja.Promise {
+	parsedOpts := NewPageWaitForNetworkOptions(p.defaultTimeout())
+	if err := parsedOpts.Parse(p.ctx, opts); err != nil {
+		k6ext.Panic(p.ctx, "parsing %s options: %w", method, err)
+	}
+	predicate, isFunc := goja.AssertFunction(urlOrPredicate)
+	if isFunc {
+		return p.waitForEventPromise(method, event, parsedOpts.Timeout, nil, predicate)
+	}
+	matchesURL, err := newURLMatcher(p.vu.Runtime(), urlOrPredicate)
+	if err != nil {
+		k6ext.Panic(p.ctx, "parsing %s URL: %w", method, err)
+	}
+	matches := func(data interface{}) bool {
+		return matchesURL(data.(interface{ URL() string }).URL())
+	}
+
+	return p.waitForEventPromise(method, event, parsedOpts.Timeout, matches, nil)
+}
+
+// waitForEventPromise returns a promise that resolves to the data of the
+// first event that matches, and for which the predicate returns a truthy
+// value. A nil matches or predicate matches all the events, and a zero
+// timeout disables the timeout.
+//
+// It subscribes to the event before returning, so that the events caused
+// by the actions that follow the call are not missed.
+func (p *Page) waitForEventPromise(
+	method, event string, timeout time.Duration, matches func(data interface{}) bool, predicate goja.Callable,
+) *goja.Promise {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(p.ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(p.ctx)
+	}
+	ch := make(chan Event)
+	p.on(ctx, []string{event}, ch)
+
+	rt := p.vu.Runtime()
+	cb := p.vu.RegisterCallback()
+	promise, resolve, reject := rt.NewPromise()
+
+	rejectErr := func() error {
+		err := ctx.Err()
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("%w after %s", ErrTimedOut, timeout)
+		}
+		return fmt.Errorf("%s promise rejected: %w", method, err)
+	}
+	iterDone := p.vu.Context().Done()
+
+	go func() {
+		// removes the event handler too.
+		defer cancel()
+		for {
+			select {
+			case <-ctx.Done():
+				err := rejectErr()
+				cb(func() error {
+					reject(err)
+					return nil
+				})
+				return
+			case ev := <-ch:
+				if matches != nil && !matches(ev.data) {
+					continue
+				}
+				if predicate == nil {
+					cb(func() error {
+						resolve(ev.data)
+						return nil
+					})
+					return
+				}
+				// the predicate can only run in the event loop. if it doesn't
+				// match, the event loop hands over a new callback to wait for
+				// the next event with.
+				var (
+					next      = make(chan func(func() error), 1)
+					nextMu    sync.Mutex
+					abandoned bool
+				)
+				cb(func() error {
+					nextMu.Lock()
+					defer nextMu.Unlock()
+					if abandoned {
+						// the wait ended while the event was handed over.
+						reject(rejectErr())
+						next <- nil
+						return nil
+					}
+					ok, err := predicate(goja.Undefined(), rt.ToValue(ev.data))
+					switch {
+					case err != nil:
+						reject(fmt.Errorf("%s predicate: %w", method, err))
+						next <- nil
+					case ok.ToBoolean():
+						resolve(ev.data)
+						next <- nil
+					default:
+						next <- p.vu.RegisterCallback()
+					}
+					return nil
+				})
+				// abandon stops waiting for the event loop to hand over
+				// a new callback, and returns the callback if it already
+				// did, so that the promise is rejected with it.
+				abandon := func() func(func() error) {
+					nextMu.Lock()
+					abandoned = true
+					nextMu.Unlock()
+					select {
+					case cb := <-next:
+						return cb
+					default:
+						return nil
+					}
+				}
+				select {
+				case cb = <-next:
+				case <-ctx.Done():
+					cb = abandon()
+				case <-iterDone:
+					// the event loop may be gone with the iteration.
+					cancel()
+					cb = abandon()
+				}
+				if cb == nil {
+					return
+				}
+			}
+		}
+	}()
+
+	return promise
+}
+
+// WaitForURL waits for the main frame to navigate to a URL that matches the pattern.
+func (p *Page) WaitForURL(url goja.Value, opts goja.Value) {
+	p.logger.Debugf("Page:WaitForURL", "sid:%v url:%v", p.sessionID(), url)
+
+	p.frameManager.MainFrame().WaitForURL(url, opts)
 }
 
 // WaitForSelector waits for the given selector to match the waiting criteria.
@@ -906,6 +1696,9 @@ func (p *Page) WaitForTimeout(timeout int64) {
 
 // Workers returns all WebWorkers of page.
 func (p *Page) Workers() []api.Worker {
+	p.workersMu.RLock()
+	defer p.workersMu.RUnlock()
+
 	workers := make([]api.Worker, 0, len(p.workers))
 	for _, w := range p.workers {
 		workers = append(workers, w)
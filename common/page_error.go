@@ -0,0 +1,143 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/xk6-browser/api"
+
+	cdpruntime "github.com/chromedp/cdproto/runtime"
+)
+
+// onExceptionThrown records an uncaught exception of the page or of one of
+// its frames, and calls the pageerror event handlers with it, if there are any.
+func (p *Page) onExceptionThrown(details *cdpruntime.ExceptionDetails) {
+	pageErr := newPageError(details)
+
+	p.pageErrorsMu.Lock()
+	p.pageErrors = append(p.pageErrors, pageErr)
+	p.pageErrorsMu.Unlock()
+
+	if p.hasEventHandlers(EventPageError) {
+		p.callEventHandlers(EventPageError, pageErr)
+	}
+}
+
+// newPageError returns a PageError of the exception details of an uncaught
+// exception. The description of a thrown error starts with its name and
+// message, followed by its stack trace.
+func newPageError(details *cdpruntime.ExceptionDetails) api.PageError {
+	exc := details.Exception
+	if exc == nil {
+		return api.PageError{Message: details.Text, Stack: exceptionStack(details)}
+	}
+	if exc.Subtype != cdpruntime.SubtypeError {
+		msg := exc.Description
+		if v, err := parseRemoteObject(exc); err == nil && v != nil {
+			msg = fmt.Sprint(v)
+		}
+		return api.PageError{Message: msg, Stack: exceptionStack(details)}
+	}
+
+	pageErr := api.PageError{Name: exc.ClassName, Stack: exc.Description}
+	first := strings.SplitN(exc.Description, "\n", 2)[0]
+	switch {
+	case first == exc.ClassName:
+	case strings.HasPrefix(first, exc.ClassName+": "):
+		pageErr.Message = strings.TrimPrefix(first, exc.ClassName+": ")
+	default:
+		pageErr.Message = first
+	}
+
+	return pageErr
+}
+
+// exceptionStack returns the stack trace of an uncaught exception
+// formatted like the stack of a JS error.
+func exceptionStack(details *cdpruntime.ExceptionDetails) string {
+	if details.StackTrace == nil {
+		return ""
+	}
+	var b strings.Builder
+	for _, cf := range details.StackTrace.CallFrames {
+		name := cf.FunctionName
+		if name == "" {
+			name = "<anonymous>"
+		}
+		fmt.Fprintf(&b, "\n    at %s (%s:%d:%d)", name, cf.URL, cf.LineNumber+1, cf.ColumnNumber+1)
+	}
+
+	return strings.TrimPrefix(b.String(), "\n")
+}
+
+// reStackLine matches a line of the stack of a JS error, such as
+// "at f (https://example.com/app.js:3:12)" or "at <anonymous>:1:7".
+var reStackLine = regexp.MustCompile(`^at (?:(.*) \((.*):(\d+):(\d+)\)|(.*):(\d+):(\d+))$`)
+
+// newEvaluationError returns the error of the exception details of a
+// function or an expression that throws when it's evaluated in the page.
+func newEvaluationError(details *cdpruntime.ExceptionDetails) *api.EvaluationError {
+	pageErr := newPageError(details)
+	evalErr := &api.EvaluationError{
+		ClassName:    pageErr.Name,
+		Message:      pageErr.Message,
+		Stack:        pageErr.Stack,
+		URL:          details.URL,
+		LineNumber:   details.LineNumber + 1,
+		ColumnNumber: details.ColumnNumber + 1,
+	}
+	if details.StackTrace != nil && len(details.StackTrace.CallFrames) > 0 {
+		for _, cf := range details.StackTrace.CallFrames {
+			evalErr.StackFrames = append(evalErr.StackFrames, api.StackFrame{
+				FunctionName: cf.FunctionName,
+				URL:          cf.URL,
+				LineNumber:   cf.LineNumber + 1,
+				ColumnNumber: cf.ColumnNumber + 1,
+			})
+		}
+		return evalErr
+	}
+	// the exception details have no stack trace if the page doesn't
+	// capture them, but the stack of a thrown error has its frames.
+	for _, line := range strings.Split(pageErr.Stack, "\n") {
+		m := reStackLine.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		f := api.StackFrame{FunctionName: m[1], URL: m[2]}
+		lineNo, colNo := m[3], m[4]
+		if lineNo == "" {
+			f.URL, lineNo, colNo = m[5], m[6], m[7]
+		}
+		if f.URL == "<anonymous>" {
+			f.URL = ""
+		}
+		f.LineNumber, _ = strconv.ParseInt(lineNo, 10, 64)
+		f.ColumnNumber, _ = strconv.ParseInt(colNo, 10, 64)
+		evalErr.StackFrames = append(evalErr.StackFrames, f)
+	}
+
+	return evalErr
+}
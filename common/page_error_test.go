@@ -0,0 +1,155 @@
+package common
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/grafana/xk6-browser/api"
+
+	cdpruntime "github.com/chromedp/cdproto/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPageError(t *testing.T) {
+	t.Parallel()
+
+	stack := &cdpruntime.StackTrace{
+		CallFrames: []*cdpruntime.CallFrame{
+			{FunctionName: "fail", URL: "http://test/a.js", LineNumber: 1, ColumnNumber: 4},
+			{URL: "http://test/a.js", LineNumber: 9, ColumnNumber: 0},
+		},
+	}
+	testCases := []struct {
+		name    string
+		details *cdpruntime.ExceptionDetails
+		want    api.PageError
+	}{
+		{
+			name: "error",
+			details: &cdpruntime.ExceptionDetails{
+				Text: "Uncaught",
+				Exception: &cdpruntime.RemoteObject{
+					Type:        cdpruntime.TypeObject,
+					Subtype:     cdpruntime.SubtypeError,
+					ClassName:   "TypeError",
+					Description: "TypeError: boom: again\n    at fail (http://test/a.js:2:5)",
+				},
+			},
+			want: api.PageError{
+				Name:    "TypeError",
+				Message: "boom: again",
+				Stack:   "TypeError: boom: again\n    at fail (http://test/a.js:2:5)",
+			},
+		},
+		{
+			name: "error_without_message",
+			details: &cdpruntime.ExceptionDetails{
+				Exception: &cdpruntime.RemoteObject{
+					Type:        cdpruntime.TypeObject,
+					Subtype:     cdpruntime.SubtypeError,
+					ClassName:   "Error",
+					Description: "Error\n    at <anonymous>:1:7",
+				},
+			},
+			want: api.PageError{Name: "Error", Stack: "Error\n    at <anonymous>:1:7"},
+		},
+		{
+			name: "string",
+			details: &cdpruntime.ExceptionDetails{
+				Text: "Uncaught (in promise)",
+				Exception: &cdpruntime.RemoteObject{
+					Type:  cdpruntime.TypeString,
+					Value: []byte(`"oops"`),
+				},
+				StackTrace: stack,
+			},
+			want: api.PageError{
+				Message: "oops",
+				Stack:   "    at fail (http://test/a.js:2:5)\n    at <anonymous> (http://test/a.js:10:1)",
+			},
+		},
+		{
+			name:    "no_exception",
+			details: &cdpruntime.ExceptionDetails{Text: "Uncaught SyntaxError"},
+			want:    api.PageError{Message: "Uncaught SyntaxError"},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.want, newPageError(tc.details))
+		})
+	}
+}
+
+func TestNewEvaluationError(t *testing.T) {
+	t.Parallel()
+
+	t.Run("stack_trace", func(t *testing.T) {
+		t.Parallel()
+
+		err := newEvaluationError(&cdpruntime.ExceptionDetails{
+			Text:         "Uncaught",
+			LineNumber:   2,
+			ColumnNumber: 11,
+			Exception: &cdpruntime.RemoteObject{
+				Type:        cdpruntime.TypeObject,
+				Subtype:     cdpruntime.SubtypeError,
+				ClassName:   "TypeError",
+				Description: "TypeError: foo is undefined\n    at f (<anonymous>:3:12)\n    at <anonymous>:5:3",
+			},
+			StackTrace: &cdpruntime.StackTrace{
+				CallFrames: []*cdpruntime.CallFrame{
+					{FunctionName: "f", LineNumber: 2, ColumnNumber: 11},
+					{LineNumber: 4, ColumnNumber: 2},
+				},
+			},
+		})
+		assert.Equal(t, &api.EvaluationError{
+			ClassName:    "TypeError",
+			Message:      "foo is undefined",
+			Stack:        "TypeError: foo is undefined\n    at f (<anonymous>:3:12)\n    at <anonymous>:5:3",
+			LineNumber:   3,
+			ColumnNumber: 12,
+			StackFrames: []api.StackFrame{
+				{FunctionName: "f", LineNumber: 3, ColumnNumber: 12},
+				{LineNumber: 5, ColumnNumber: 3},
+			},
+		}, err)
+		assert.EqualError(t, err, "TypeError: foo is undefined at f (<anonymous>:3:12) <- <anonymous>:5:3")
+	})
+
+	t.Run("description", func(t *testing.T) {
+		t.Parallel()
+
+		err := newEvaluationError(&cdpruntime.ExceptionDetails{
+			Text: "Uncaught",
+			Exception: &cdpruntime.RemoteObject{
+				Type:        cdpruntime.TypeObject,
+				Subtype:     cdpruntime.SubtypeError,
+				ClassName:   "RangeError",
+				Description: "RangeError: out\n    at check (http://test/a.js:2:5)\n    at http://test/a.js:10:1",
+			},
+		})
+		assert.Equal(t, []api.StackFrame{
+			{FunctionName: "check", URL: "http://test/a.js", LineNumber: 2, ColumnNumber: 5},
+			{URL: "http://test/a.js", LineNumber: 10, ColumnNumber: 1},
+		}, err.StackFrames)
+		assert.EqualError(t, err, "RangeError: out at check (http://test/a.js:2:5) <- http://test/a.js:10:1")
+	})
+
+	t.Run("value", func(t *testing.T) {
+		t.Parallel()
+
+		err := newEvaluationError(&cdpruntime.ExceptionDetails{
+			Text:      "Uncaught (in promise)",
+			Exception: &cdpruntime.RemoteObject{Type: cdpruntime.TypeString, Value: []byte(`"rejected"`)},
+		})
+		assert.Empty(t, err.ClassName)
+		assert.Empty(t, err.StackFrames)
+		assert.EqualError(t, err, "rejected")
+		assert.Equal(t, api.EvaluationErrorName, api.ErrorName(fmt.Errorf("evaluating JS: %w", err)))
+	})
+}
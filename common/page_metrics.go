@@ -0,0 +1,162 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/grafana/xk6-browser/k6ext"
+
+	"github.com/dop251/goja"
+	k6modules "go.k6.io/k6/js/modules"
+	k6metrics "go.k6.io/k6/metrics"
+)
+
+// MetricURLGrouping groups the URLs of the pages in the url tag of the
+// metrics of the pages, such as the Web Vitals, so that the URLs with
+// IDs or query strings don't make too many tag values.
+type MetricURLGrouping struct {
+	// StripQuery strips the query strings and the fragments of the URLs.
+	StripQuery bool
+	// Group returns the tag of a URL. It runs on the event loop.
+	Group goja.Callable
+}
+
+// Parse parses the grouping of the URLs, which is either "stripQuery"
+// or a function that returns the tag of a URL.
+func (g *MetricURLGrouping) Parse(v goja.Value) error {
+	if fn, ok := goja.AssertFunction(v); ok {
+		g.Group = fn
+		return nil
+	}
+	if gojaValueExists(v) && v.String() == "stripQuery" {
+		g.StripQuery = true
+		return nil
+	}
+	return fmt.Errorf(`must be "stripQuery" or a function, got %q`, v)
+}
+
+// tag returns the url tag of a page URL. It must be called on
+// the event loop if the URLs are grouped with a function.
+func (g *MetricURLGrouping) tag(rt *goja.Runtime, u string) (string, error) {
+	switch {
+	case g == nil:
+		return u, nil
+	case g.StripQuery:
+		return stripURLQuery(u), nil
+	case g.Group != nil:
+		v, err := g.Group(goja.Undefined(), rt.ToValue(u))
+		if err != nil {
+			return "", fmt.Errorf("grouping URL %q: %w", u, err)
+		}
+		return v.String(), nil
+	}
+	return u, nil
+}
+
+// stripURLQuery returns the URL without its query string and fragment.
+func stripURLQuery(u string) string {
+	pu, err := url.Parse(u)
+	if err != nil {
+		if i := strings.IndexAny(u, "?#"); i >= 0 {
+			return u[:i]
+		}
+		return u
+	}
+	pu.RawQuery = ""
+	pu.ForceQuery = false
+	pu.Fragment = ""
+	pu.RawFragment = ""
+	return pu.String()
+}
+
+// pushPageMetrics pushes the values of the metrics of a page,
+// tagged with the tags of the page and the URL of the page.
+func pushPageMetrics(
+	ctx context.Context, vu k6modules.VU, tags map[string]string, url string, values map[*k6metrics.Metric]float64,
+) {
+	state := vu.State()
+	if state == nil || len(values) == 0 {
+		return
+	}
+
+	if state.Options.SystemTags.Has(k6metrics.TagURL) {
+		tags["url"] = url
+	}
+	var (
+		sampleTags = k6metrics.IntoSampleTags(&tags)
+		now        = time.Now()
+		samples    = make([]k6metrics.Sample, 0, len(values))
+	)
+	for m, v := range values {
+		samples = append(samples, k6metrics.Sample{
+			Metric: m,
+			Tags:   sampleTags,
+			Value:  v,
+			Time:   now,
+		})
+	}
+	k6metrics.PushIfNotDone(ctx, state.Samples, k6metrics.ConnectedSamples{Samples: samples})
+}
+
+// pushPageMetrics pushes the values of the metrics of a page of the browser
+// context, tagged with the tags of the page, and the URL that the
+// metricURLGrouping option returns. The metrics are pushed on the event
+// loop when the URLs are grouped with a function, unless onEventLoop is
+// set to tell that the caller runs on it.
+func (b *BrowserContext) pushPageMetrics(
+	tags map[string]string, url string, values map[*k6metrics.Metric]float64, onEventLoop bool,
+) {
+	if len(values) == 0 {
+		return
+	}
+	b.withMetricURL(url, onEventLoop, func(tag string) {
+		pushPageMetrics(b.ctx, b.vu, tags, tag, values)
+	})
+}
+
+// withMetricURL calls push with the url tag that the metricURLGrouping
+// option returns for a URL. push is called on the event loop when the
+// URLs are grouped with a function, unless onEventLoop is set to tell
+// that the caller runs on it.
+func (b *BrowserContext) withMetricURL(url string, onEventLoop bool, push func(tag string)) {
+	g := b.opts.MetricURLGrouping
+	if g == nil || g.Group == nil || onEventLoop {
+		tag, err := g.tag(b.vu.Runtime(), url)
+		if err != nil {
+			k6ext.Panic(b.ctx, "grouping metric URL: %w", err)
+		}
+		push(tag)
+		return
+	}
+	b.eventLoopQueue.push(func() error {
+		tag, err := g.tag(b.vu.Runtime(), url)
+		if err != nil {
+			return fmt.Errorf("grouping metric URL: %w", err)
+		}
+		push(tag)
+		return nil
+	})
+}
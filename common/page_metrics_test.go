@@ -0,0 +1,140 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/grafana/xk6-browser/k6ext"
+	"github.com/grafana/xk6-browser/k6ext/k6test"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	k6metrics "go.k6.io/k6/metrics"
+)
+
+func TestMetricURLGrouping(t *testing.T) {
+	t.Parallel()
+
+	const u = "https://example.com/items/42?session=1#top"
+
+	t.Run("none", func(t *testing.T) {
+		t.Parallel()
+
+		var g *MetricURLGrouping
+		tag, err := g.tag(nil, u)
+		require.NoError(t, err)
+		assert.Equal(t, u, tag)
+	})
+	t.Run("strip_query", func(t *testing.T) {
+		t.Parallel()
+
+		vu := k6test.NewVU(t)
+		var g MetricURLGrouping
+		require.NoError(t, g.Parse(vu.ToGojaValue("stripQuery")))
+		assert.True(t, g.StripQuery)
+		tag, err := g.tag(vu.Runtime(), u)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/items/42", tag)
+	})
+	t.Run("function", func(t *testing.T) {
+		t.Parallel()
+
+		vu := k6test.NewVU(t)
+		fn, err := vu.Runtime().RunString(`(url) => url.replace(/\/items\/\d+.*/, '/items/:id')`)
+		require.NoError(t, err)
+		var g MetricURLGrouping
+		require.NoError(t, g.Parse(fn))
+		require.NotNil(t, g.Group)
+		tag, err := g.tag(vu.Runtime(), u)
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/items/:id", tag)
+	})
+	t.Run("function_error", func(t *testing.T) {
+		t.Parallel()
+
+		vu := k6test.NewVU(t)
+		fn, err := vu.Runtime().RunString(`() => { throw new Error('oops'); }`)
+		require.NoError(t, err)
+		var g MetricURLGrouping
+		require.NoError(t, g.Parse(fn))
+		_, err = g.tag(vu.Runtime(), u)
+		assert.ErrorContains(t, err, "oops")
+	})
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+
+		vu := k6test.NewVU(t)
+		var g MetricURLGrouping
+		assert.Error(t, g.Parse(vu.ToGojaValue("none")))
+		assert.Error(t, g.Parse(goja.Undefined()))
+	})
+}
+
+func TestBrowserContextOptionsMetricURLGrouping(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+	opts := NewBrowserContextOptions()
+	require.NoError(t, opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{
+		"metricURLGrouping": "stripQuery",
+	})))
+	require.NotNil(t, opts.MetricURLGrouping)
+	assert.True(t, opts.MetricURLGrouping.StripQuery)
+
+	err := opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{
+		"metricURLGrouping": 1,
+	}))
+	assert.ErrorContains(t, err, "parsing metricURLGrouping")
+}
+
+func TestPushPageMetrics(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+	samples := make(chan k6metrics.SampleContainer, 10)
+	vu.State().Samples = samples
+	metrics := k6ext.RegisterCustomMetrics(k6metrics.NewRegistry())
+
+	lcp, cls := 1200.0, 0.25
+	vitals := &webVitals{URL: "https://example.com/page", LCP: &lcp, CLS: &cls}
+	pushPageMetrics(vu.Context(), vu, vuMetricTags(vu.Context(), vu.State()), vitals.URL, vitals.samples(metrics))
+
+	require.Len(t, samples, 1)
+	got := make(map[string]float64)
+	for _, s := range (<-samples).GetSamples() {
+		got[s.Metric.Name] = s.Value
+		tags := s.Tags.CloneTags()
+		assert.Equal(t, "https://example.com/page", tags["url"])
+		assert.Equal(t, vu.State().Group.Path, tags["group"])
+	}
+	assert.Equal(t, map[string]float64{
+		"browser_web_vital_lcp": 1200,
+		"browser_web_vital_cls": 0.25,
+	}, got)
+
+	// nothing is pushed without values.
+	pushPageMetrics(vu.Context(), vu, vuMetricTags(vu.Context(), vu.State()), vitals.URL, (&webVitals{}).samples(metrics))
+	assert.Empty(t, samples)
+}
+
+func TestPageMetricsDisabled(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+	samples := make(chan k6metrics.SampleContainer, 10)
+	vu.State().Samples = samples
+	metrics, err := k6ext.RegisterCustomMetricsWithOptions(k6metrics.NewRegistry(), &k6ext.MetricsOptions{
+		Disabled: map[string]bool{
+			k6ext.MetricFamilyNavigation: true,
+			k6ext.MetricFamilyWebVitals:  true,
+		},
+	})
+	require.NoError(t, err)
+
+	// the page has no browser context, so it
+	// would panic if it pushed the metrics.
+	p := &Page{ctx: vu.Context(), vu: vu, k6Metrics: metrics}
+	p.emitWebVitals(`{"url":"https://example.com/","lcp":1200}`, true)
+	p.emitNavigationTiming(&Frame{})
+	assert.Empty(t, samples)
+}
@@ -22,8 +22,10 @@ package common
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"strings"
+	"math"
+	"reflect"
 	"time"
 
 	"github.com/chromedp/cdproto/page"
@@ -32,24 +34,84 @@ import (
 	"github.com/grafana/xk6-browser/k6ext"
 )
 
+// PageCloseOptions are the options of closing a page.
+type PageCloseOptions struct {
+	// RunBeforeUnload runs the beforeunload handlers of the page
+	// before closing it, which can prevent the page from closing.
+	RunBeforeUnload bool `json:"runBeforeUnload"`
+}
+
 type PageEmulateMediaOptions struct {
 	ColorScheme   ColorScheme   `json:"colorScheme"`
 	Media         MediaType     `json:"media"`
 	ReducedMotion ReducedMotion `json:"reducedMotion"`
 }
 
+// PageSetViewportSizeOptions are the viewport size of a page,
+// and the properties of the device it emulates.
+type PageSetViewportSizeOptions struct {
+	Size
+	DeviceMetrics
+}
+
+// PageFrameOptions are the options of finding a frame of a page.
+// A frame matches if it matches all the options that are set.
+type PageFrameOptions struct {
+	// Name is the name of the frame in its name or id attribute.
+	Name string `json:"name"`
+	// URL matches the URL of the frame.
+	URL urlMatcher `json:"url"`
+}
+
 type PageReloadOptions struct {
 	WaitUntil LifecycleEvent `json:"waitUntil"`
 	Timeout   time.Duration  `json:"timeout"`
 }
 
+type PageWaitForEventOptions struct {
+	Predicate goja.Callable `json:"predicate"`
+	Timeout   time.Duration `json:"timeout"`
+}
+
+type PageWaitForFileChooserOptions struct {
+	Timeout time.Duration `json:"timeout"`
+}
+
+type PageWaitForNetworkOptions struct {
+	Timeout time.Duration `json:"timeout"`
+}
+
 type PageScreenshotOptions struct {
-	Clip           *page.Viewport `json:"clip"`
-	Path           string         `json:"path"`
-	Format         ImageFormat    `json:"format"`
-	FullPage       bool           `json:"fullPage"`
-	OmitBackground bool           `json:"omitBackground"`
-	Quality        int64          `json:"quality"`
+	Clip              *page.Viewport `json:"clip"`
+	Path              string         `json:"path"`
+	Format            ImageFormat    `json:"format"`
+	FullPage          bool           `json:"fullPage"`
+	HideFixedElements bool           `json:"hideFixedElements"`
+	Mask              []string       `json:"mask"`
+	MaskColor         string         `json:"maskColor"`
+	OmitBackground    bool           `json:"omitBackground"`
+	Quality           int64          `json:"quality"`
+}
+
+// NewPageCloseOptions returns the default options of closing a page.
+func NewPageCloseOptions() *PageCloseOptions {
+	return &PageCloseOptions{}
+}
+
+// Parse parses the page close options from a JS object.
+func (o *PageCloseOptions) Parse(ctx context.Context, opts goja.Value) error {
+	if !gojaValueExists(opts) {
+		return nil
+	}
+	rt := k6ext.Runtime(ctx)
+	obj := opts.ToObject(rt)
+	for _, k := range obj.Keys() {
+		if k == "runBeforeUnload" {
+			o.RunBeforeUnload = obj.Get(k).ToBoolean()
+		}
+	}
+
+	return nil
 }
 
 func NewPageEmulateMediaOptions(defaultMedia MediaType, defaultColorScheme ColorScheme, defaultReducedMotion ReducedMotion) *PageEmulateMediaOptions {
@@ -60,24 +122,101 @@ func NewPageEmulateMediaOptions(defaultMedia MediaType, defaultColorScheme Color
 	}
 }
 
+// Parse parses the emulateMedia options. A null option disables
+// the emulation of its media feature.
 func (o *PageEmulateMediaOptions) Parse(ctx context.Context, opts goja.Value) error {
 	rt := k6ext.Runtime(ctx)
 	if opts != nil && !goja.IsUndefined(opts) && !goja.IsNull(opts) {
 		opts := opts.ToObject(rt)
 		for _, k := range opts.Keys() {
+			v := opts.Get(k)
+			if goja.IsUndefined(v) {
+				continue
+			}
 			switch k {
 			case "colorScheme":
-				o.ColorScheme = ColorScheme(opts.Get(k).String())
+				o.ColorScheme = ""
+				if goja.IsNull(v) {
+					continue
+				}
+				cs, ok := colorSchemeToID[v.String()]
+				if !ok {
+					return fmt.Errorf("invalid colorScheme %q: must be one of: light, dark, no-preference", v)
+				}
+				o.ColorScheme = cs
 			case "media":
-				o.Media = MediaType(opts.Get(k).String())
+				o.Media = ""
+				if goja.IsNull(v) {
+					continue
+				}
+				switch m := MediaType(v.String()); m {
+				case MediaTypeScreen, MediaTypePrint:
+					o.Media = m
+				default:
+					return fmt.Errorf("invalid media %q: must be one of: screen, print", v)
+				}
 			case "reducedMotion":
-				o.ReducedMotion = ReducedMotion(opts.Get(k).String())
+				o.ReducedMotion = ""
+				if goja.IsNull(v) {
+					continue
+				}
+				rm, ok := reducedMotionToID[v.String()]
+				if !ok {
+					return fmt.Errorf("invalid reducedMotion %q: must be one of: reduce, no-preference", v)
+				}
+				o.ReducedMotion = rm
 			}
 		}
 	}
 	return nil
 }
 
+// NewPageSetViewportSizeOptions returns the setViewportSize options
+// that keep the given device metrics.
+func NewPageSetViewportSizeOptions(defaultMetrics DeviceMetrics) *PageSetViewportSizeOptions {
+	return &PageSetViewportSizeOptions{
+		DeviceMetrics: defaultMetrics,
+	}
+}
+
+// Parse parses the setViewportSize options.
+func (o *PageSetViewportSizeOptions) Parse(ctx context.Context, opts goja.Value) error {
+	if err := o.Size.Parse(ctx, opts); err != nil {
+		return err
+	}
+	if !gojaValueExists(opts) {
+		return nil
+	}
+	obj := opts.ToObject(k6ext.Runtime(ctx))
+	for _, k := range obj.Keys() {
+		v := obj.Get(k)
+		switch k {
+		case "deviceScaleFactor":
+			dsf := v.ToFloat()
+			if dsf <= 0 {
+				return fmt.Errorf("deviceScaleFactor must be greater than 0, got %v", v)
+			}
+			o.DeviceScaleFactor = dsf
+		case "hasTouch":
+			o.HasTouch = v.ToBoolean()
+		case "isMobile":
+			o.IsMobile = v.ToBoolean()
+		case "screenOrientation":
+			if !gojaValueExists(v) {
+				o.ScreenOrientation = ""
+				continue
+			}
+			so, err := parseScreenOrientation(v.String())
+			if err != nil {
+				return err
+			}
+			o.ScreenOrientation = so
+		}
+	}
+
+	return nil
+}
+
 func NewPageReloadOptions(defaultWaitUntil LifecycleEvent, defaultTimeout time.Duration) *PageReloadOptions {
 	return &PageReloadOptions{
 		WaitUntil: defaultWaitUntil,
@@ -112,6 +251,7 @@ func NewPageScreenshotOptions() *PageScreenshotOptions {
 		Path:           "",
 		Format:         ImageFormatPNG,
 		FullPage:       false,
+		MaskColor:      defaultScreenshotMaskColor,
 		OmitBackground: false,
 		Quality:        100,
 	}
@@ -120,44 +260,227 @@ func NewPageScreenshotOptions() *PageScreenshotOptions {
 func (o *PageScreenshotOptions) Parse(ctx context.Context, opts goja.Value) error {
 	rt := k6ext.Runtime(ctx)
 	if opts != nil && !goja.IsUndefined(opts) && !goja.IsNull(opts) {
-		formatSpecified := false
+		var (
+			typ        string
+			qualitySet bool
+		)
 		opts := opts.ToObject(rt)
 		for _, k := range opts.Keys() {
 			switch k {
 			case "clip":
-				var c map[string]float64
-				if rt.ExportTo(opts.Get(k), &c) != nil {
-					o.Clip = &page.Viewport{
-						X:      c["x"],
-						Y:      c["y"],
-						Width:  c["width"],
-						Height: c["height"],
-						Scale:  1,
-					}
+				clip, err := parseScreenshotClip(rt, opts.Get(k))
+				if err != nil {
+					return err
 				}
+				o.Clip = clip
 			case "fullPage":
 				o.FullPage = opts.Get(k).ToBoolean()
+			case "hideFixedElements":
+				o.HideFixedElements = opts.Get(k).ToBoolean()
+			case "mask":
+				mask, err := parseScreenshotMask(rt, opts.Get(k))
+				if err != nil {
+					return err
+				}
+				o.Mask = mask
+			case "maskColor":
+				o.MaskColor = opts.Get(k).String()
 			case "omitBackground":
 				o.OmitBackground = opts.Get(k).ToBoolean()
 			case "path":
 				o.Path = opts.Get(k).String()
+				if err := validateArtifactPath(o.Path); err != nil {
+					return err
+				}
 			case "quality":
 				o.Quality = opts.Get(k).ToInteger()
+				qualitySet = true
 			case "type":
-				if f, ok := imageFormatToID[opts.Get(k).String()]; ok {
-					o.Format = f
-					formatSpecified = true
-				}
+				typ = opts.Get(k).String()
 			}
 		}
 
 		// Infer file format by path if format not explicitly specified (default is PNG)
-		if o.Path != "" && !formatSpecified {
-			if strings.HasSuffix(o.Path, ".jpg") || strings.HasSuffix(o.Path, ".jpeg") {
-				o.Format = ImageFormatJPEG
+		format, path, err := parseScreenshotFormat(typ, o.Path, o.Format)
+		if err != nil {
+			return err
+		}
+		o.Format, o.Path = format, path
+		if qualitySet {
+			if err := validateScreenshotQuality(o.Format, o.Quality); err != nil {
+				return err
 			}
 		}
 	}
 
 	return nil
 }
+
+// parseScreenshotClip parses the clip option of a screenshot,
+// which must have a position and a non-zero area.
+func parseScreenshotClip(rt *goja.Runtime, clip goja.Value) (*page.Viewport, error) {
+	if !gojaValueExists(clip) {
+		return nil, nil
+	}
+	var (
+		c    = clip.ToObject(rt)
+		xywh [4]float64
+	)
+	for i, k := range []string{"x", "y", "width", "height"} {
+		v := c.Get(k)
+		if !gojaValueExists(v) {
+			return nil, fmt.Errorf("clip.%s is required", k)
+		}
+		f := v.ToFloat()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("clip.%s must be a number, got %q", k, v.String())
+		}
+		xywh[i] = f
+	}
+	if xywh[2] <= 0 || xywh[3] <= 0 {
+		return nil, fmt.Errorf("clip must have a non-zero area, got %gx%g", xywh[2], xywh[3])
+	}
+
+	return &page.Viewport{X: xywh[0], Y: xywh[1], Width: xywh[2], Height: xywh[3], Scale: 1}, nil
+}
+
+// parseScreenshotMask parses the mask option of a screenshot,
+// which is an array of selectors of the elements to mask.
+func parseScreenshotMask(rt *goja.Runtime, mask goja.Value) ([]string, error) {
+	if !gojaValueExists(mask) {
+		return nil, nil
+	}
+	if mask.ExportType() == nil || mask.ExportType().Kind() != reflect.Slice {
+		return nil, fmt.Errorf("mask must be an array of selectors, got %q", mask.String())
+	}
+	var selectors []string
+	obj := mask.ToObject(rt)
+	for _, k := range obj.Keys() {
+		v := obj.Get(k)
+		if _, ok := v.Export().(string); !ok {
+			return nil, fmt.Errorf("mask must be an array of selectors, got %q", v.String())
+		}
+		selectors = append(selectors, v.String())
+	}
+
+	return selectors, nil
+}
+
+// NewPageWaitForNetworkOptions returns the default options for
+// Page.waitForRequest and Page.waitForResponse.
+// NewPageFrameOptions returns the default options of finding a frame of a page.
+func NewPageFrameOptions() *PageFrameOptions {
+	return &PageFrameOptions{}
+}
+
+// Parse parses the Page.frame options, which are either a frame name, or
+// an object with the name and a URL pattern of the frame. The URL pattern
+// is a string, a glob pattern or a RegExp object.
+func (o *PageFrameOptions) Parse(ctx context.Context, nameOrOpts goja.Value) error {
+	if !gojaValueExists(nameOrOpts) {
+		return errors.New("missing frame name or options")
+	}
+	rt := k6ext.Runtime(ctx)
+	if nameOrOpts.ExportType().Kind() == reflect.String {
+		o.Name = nameOrOpts.String()
+		return nil
+	}
+	obj := nameOrOpts.ToObject(rt)
+	for _, k := range obj.Keys() {
+		switch k {
+		case "name":
+			o.Name = obj.Get(k).String()
+		case "url":
+			matches, err := newURLMatcher(rt, obj.Get(k))
+			if err != nil {
+				return fmt.Errorf("parsing frame URL: %w", err)
+			}
+			o.URL = matches
+		}
+	}
+	if o.Name == "" && o.URL == nil {
+		return errors.New("either name or url option must be set")
+	}
+
+	return nil
+}
+
+func NewPageWaitForEventOptions(defaultTimeout time.Duration) *PageWaitForEventOptions {
+	return &PageWaitForEventOptions{
+		Timeout: defaultTimeout,
+	}
+}
+
+// Parse parses the Page.waitForEvent options, which are either a predicate
+// function, or an object with the predicate and the timeout.
+// A zero timeout disables the timeout.
+func (o *PageWaitForEventOptions) Parse(ctx context.Context, optsOrPredicate goja.Value) error {
+	if !gojaValueExists(optsOrPredicate) {
+		return nil
+	}
+	if fn, ok := goja.AssertFunction(optsOrPredicate); ok {
+		o.Predicate = fn
+		return nil
+	}
+	rt := k6ext.Runtime(ctx)
+	obj := optsOrPredicate.ToObject(rt)
+	for _, k := range obj.Keys() {
+		switch k {
+		case "predicate":
+			fn, ok := goja.AssertFunction(obj.Get(k))
+			if !ok {
+				return fmt.Errorf("predicate must be a function, got %q", obj.Get(k))
+			}
+			o.Predicate = fn
+		case "timeout":
+			o.Timeout = time.Duration(obj.Get(k).ToInteger()) * time.Millisecond
+		}
+	}
+
+	return nil
+}
+
+func NewPageWaitForFileChooserOptions(defaultTimeout time.Duration) *PageWaitForFileChooserOptions {
+	return &PageWaitForFileChooserOptions{
+		Timeout: defaultTimeout,
+	}
+}
+
+// Parse parses the Page.waitForFileChooser options.
+// A zero timeout disables the timeout.
+func (o *PageWaitForFileChooserOptions) Parse(ctx context.Context, opts goja.Value) error {
+	if !gojaValueExists(opts) {
+		return nil
+	}
+	rt := k6ext.Runtime(ctx)
+	obj := opts.ToObject(rt)
+	for _, k := range obj.Keys() {
+		if k == "timeout" {
+			o.Timeout = time.Duration(obj.Get(k).ToInteger()) * time.Millisecond
+		}
+	}
+
+	return nil
+}
+
+func NewPageWaitForNetworkOptions(defaultTimeout time.Duration) *PageWaitForNetworkOptions {
+	return &PageWaitForNetworkOptions{
+		Timeout: defaultTimeout,
+	}
+}
+
+// Parse parses the Page.waitForRequest and Page.waitForResponse options.
+// A zero timeout disables the timeout.
+func (o *PageWaitForNetworkOptions) Parse(ctx context.Context, opts goja.Value) error {
+	rt := k6ext.Runtime(ctx)
+	if opts != nil && !goja.IsUndefined(opts) && !goja.IsNull(opts) {
+		opts := opts.ToObject(rt)
+		for _, k := range opts.Keys() {
+			switch k {
+			case "timeout":
+				o.Timeout = time.Duration(opts.Get(k).ToInteger()) * time.Millisecond
+			}
+		}
+	}
+	return nil
+}
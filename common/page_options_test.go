@@ -0,0 +1,254 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/xk6-browser/k6ext/k6test"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/page"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageEmulateMediaOptionsParse(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+	opts := NewPageEmulateMediaOptions(MediaTypeScreen, ColorSchemeLight, ReducedMotionNoPreference)
+	err := opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{
+		"media":         "print",
+		"colorScheme":   "dark",
+		"reducedMotion": "reduce",
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, &PageEmulateMediaOptions{
+		Media:         MediaTypePrint,
+		ColorScheme:   ColorSchemeDark,
+		ReducedMotion: ReducedMotionReduce,
+	}, opts)
+
+	// null disables the emulation, and missing options are kept.
+	err = opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{
+		"media":       nil,
+		"colorScheme": nil,
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, &PageEmulateMediaOptions{ReducedMotion: ReducedMotionReduce}, opts)
+
+	err = opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{"media": "tv"}))
+	assert.EqualError(t, err, `invalid media "tv": must be one of: screen, print`)
+	err = opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{"colorScheme": "sepia"}))
+	assert.EqualError(t, err, `invalid colorScheme "sepia": must be one of: light, dark, no-preference`)
+	err = opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{"reducedMotion": "less"}))
+	assert.EqualError(t, err, `invalid reducedMotion "less": must be one of: reduce, no-preference`)
+}
+
+func TestPageSetViewportSizeOptionsParse(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+	opts := NewPageSetViewportSizeOptions(DeviceMetrics{DeviceScaleFactor: 1, HasTouch: true})
+	err := opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{
+		"width":             400,
+		"height":            800,
+		"deviceScaleFactor": 3,
+		"isMobile":          true,
+		"screenOrientation": "landscape-primary",
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, &PageSetViewportSizeOptions{
+		Size: Size{Width: 400, Height: 800},
+		DeviceMetrics: DeviceMetrics{
+			DeviceScaleFactor: 3,
+			IsMobile:          true,
+			HasTouch:          true,
+			ScreenOrientation: ScreenOrientationLandscapePrimary,
+		},
+	}, opts)
+
+	err = opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{"deviceScaleFactor": 0}))
+	assert.EqualError(t, err, "deviceScaleFactor must be greater than 0, got 0")
+}
+
+func TestScreenOrientationToCDP(t *testing.T) {
+	t.Parallel()
+
+	portrait := &Viewport{Width: 400, Height: 800}
+	landscape := &Viewport{Width: 800, Height: 400}
+
+	so := ScreenOrientation("").toCDP(portrait)
+	assert.Equal(t, emulation.OrientationTypePortraitPrimary, so.Type)
+	so = ScreenOrientation("").toCDP(landscape)
+	assert.Equal(t, emulation.OrientationTypeLandscapePrimary, so.Type)
+	assert.Equal(t, int64(90), so.Angle)
+
+	// an explicit orientation doesn't depend on the viewport.
+	so = ScreenOrientationPortraitSecondary.toCDP(landscape)
+	assert.Equal(t, emulation.OrientationTypePortraitSecondary, so.Type)
+	assert.Equal(t, int64(180), so.Angle)
+}
+
+func TestPageScreenshotOptionsParseFormat(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name       string
+		opts       map[string]interface{}
+		wantFormat ImageFormat
+		wantPath   string
+		wantErr    string
+	}{
+		{name: "default", opts: map[string]interface{}{}, wantFormat: ImageFormatPNG},
+		{name: "type", opts: map[string]interface{}{"type": "webp", "quality": 50}, wantFormat: ImageFormatWebP},
+		{
+			name:       "path_extension",
+			opts:       map[string]interface{}{"path": "shots/a.webp"},
+			wantFormat: ImageFormatWebP, wantPath: "shots/a.webp",
+		},
+		{
+			name:       "path_jpg_extension",
+			opts:       map[string]interface{}{"path": "a.JPG"},
+			wantFormat: ImageFormatJPEG, wantPath: "a.JPG",
+		},
+		{
+			name:       "path_without_extension",
+			opts:       map[string]interface{}{"path": "shots/a", "type": "webp"},
+			wantFormat: ImageFormatWebP, wantPath: "shots/a.webp",
+		},
+		{name: "invalid_type", opts: map[string]interface{}{"type": "gif"}, wantErr: `invalid type "gif": must be one of: png, jpeg, webp`},
+		{name: "png_quality", opts: map[string]interface{}{"quality": 50}, wantErr: "quality is unsupported for png screenshots"},
+		{
+			name:    "quality_range",
+			opts:    map[string]interface{}{"type": "jpeg", "quality": 101},
+			wantErr: "quality must be between 0 and 100, got 101",
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			vu := k6test.NewVU(t)
+			opts := NewPageScreenshotOptions()
+			err := opts.Parse(vu.Context(), vu.ToGojaValue(tc.opts))
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantFormat, opts.Format)
+			assert.Equal(t, tc.wantPath, opts.Path)
+
+			// element handle screenshots share the same options.
+			ehOpts := NewElementHandleScreenshotOptions(0)
+			require.NoError(t, ehOpts.Parse(vu.Context(), vu.ToGojaValue(tc.opts)))
+			assert.Equal(t, tc.wantFormat, ehOpts.Format)
+			assert.Equal(t, tc.wantPath, ehOpts.Path)
+		})
+	}
+}
+
+func TestPageScreenshotOptionsParseClip(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+	opts := NewPageScreenshotOptions()
+	err := opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{
+		"clip": map[string]interface{}{"x": 10, "y": 20.5, "width": 100, "height": 50},
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, &page.Viewport{X: 10, Y: 20.5, Width: 100, Height: 50, Scale: 1}, opts.Clip)
+
+	for clip, wantErr := range map[string]string{
+		`{"y": 0, "width": 1, "height": 1}`:           "clip.x is required",
+		`{"x": "a", "y": 0, "width": 1, "height": 1}`: `clip.x must be a number, got "a"`,
+		`{"x": 0, "y": 0, "width": 0, "height": 1}`:   "clip must have a non-zero area, got 0x1",
+		`{"x": 0, "y": 0, "width": 1, "height": -1}`:  "clip must have a non-zero area, got 1x-1",
+	} {
+		v, err := vu.Runtime().RunString("(" + clip + ")")
+		require.NoError(t, err)
+		err = opts.Parse(vu.Context(), vu.Runtime().ToValue(map[string]interface{}{"clip": v}))
+		assert.EqualError(t, err, wantErr, clip)
+	}
+}
+
+func TestPageScreenshotOptionsParseMask(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+	opts := NewPageScreenshotOptions()
+	assert.Equal(t, "#FF00FF", opts.MaskColor)
+
+	err := opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{
+		"mask":      []string{".pii", "time"},
+		"maskColor": "rgb(0, 255, 0)",
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{".pii", "time"}, opts.Mask)
+	assert.Equal(t, "rgb(0, 255, 0)", opts.MaskColor)
+
+	err = opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{"mask": ".pii"}))
+	assert.EqualError(t, err, `mask must be an array of selectors, got ".pii"`)
+	err = opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{"mask": []interface{}{".pii", 1}}))
+	assert.EqualError(t, err, `mask must be an array of selectors, got "1"`)
+}
+
+func TestPageWaitForEventOptionsParse(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+	rt := vu.Runtime()
+
+	opts := NewPageWaitForEventOptions(time.Second)
+	fn, err := rt.RunString(`() => true`)
+	require.NoError(t, err)
+	require.NoError(t, opts.Parse(vu.Context(), fn))
+	assert.NotNil(t, opts.Predicate)
+	assert.Equal(t, time.Second, opts.Timeout)
+
+	opts = NewPageWaitForEventOptions(time.Second)
+	obj, err := rt.RunString(`({ predicate: () => true, timeout: 500 })`)
+	require.NoError(t, err)
+	require.NoError(t, opts.Parse(vu.Context(), obj))
+	assert.NotNil(t, opts.Predicate)
+	assert.Equal(t, 500*time.Millisecond, opts.Timeout)
+
+	opts = NewPageWaitForEventOptions(time.Second)
+	err = opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{"predicate": "yes"}))
+	assert.EqualError(t, err, `predicate must be a function, got "yes"`)
+}
+
+func TestPageFrameOptionsParse(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+	rt := vu.Runtime()
+
+	opts := NewPageFrameOptions()
+	require.NoError(t, opts.Parse(vu.Context(), rt.ToValue("checkout")))
+	assert.Equal(t, "checkout", opts.Name)
+	assert.Nil(t, opts.URL)
+
+	opts = NewPageFrameOptions()
+	obj, err := rt.RunString(`({ name: 'checkout', url: /\/pay\.html$/ })`)
+	require.NoError(t, err)
+	require.NoError(t, opts.Parse(vu.Context(), obj))
+	assert.Equal(t, "checkout", opts.Name)
+	require.NotNil(t, opts.URL)
+	assert.True(t, opts.URL("https://example.com/pay.html"))
+	assert.False(t, opts.URL("https://example.com/pay.html?x"))
+
+	opts = NewPageFrameOptions()
+	require.NoError(t, opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{"url": "**/pay.*"})))
+	assert.True(t, opts.URL("https://example.com/a/pay.html"))
+
+	opts = NewPageFrameOptions()
+	err = opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{}))
+	assert.EqualError(t, err, "either name or url option must be set")
+
+	opts = NewPageFrameOptions()
+	err = opts.Parse(vu.Context(), nil)
+	assert.EqualError(t, err, "missing frame name or options")
+}
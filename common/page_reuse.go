@@ -0,0 +1,317 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package common
+
+import (
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/grafana/xk6-browser/k6ext"
+
+	k6metrics "go.k6.io/k6/metrics"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/storage"
+	"github.com/chromedp/cdproto/target"
+)
+
+// pageReuse is the browser context and the page that the iterations of a
+// VU reuse with the reusePage launch option. The first context and page
+// that an iteration creates are kept, and they are reset when the next
+// iteration asks for a new context, instead of creating new ones.
+type pageReuse struct {
+	mu      sync.Mutex
+	context *BrowserContext
+	page    *Page
+	// whether the current iteration has the page already, and
+	// whether the context and the page need a reset before reuse.
+	pageTaken bool
+	dirty     bool
+}
+
+// reusesPage reports whether the browser reuses a
+// browser context and its page across the iterations.
+func (b *Browser) reusesPage() bool {
+	return b.launchOpts != nil && b.launchOpts.ReusePage
+}
+
+// keepContext keeps the browser context for
+// the next iterations, if none is kept yet.
+func (b *Browser) keepContext(bc *BrowserContext) {
+	b.reuse.mu.Lock()
+	defer b.reuse.mu.Unlock()
+
+	if b.reuse.context == nil {
+		b.reuse.context = bc
+	}
+}
+
+// isReusedContext reports whether the browser
+// context is kept for the next iterations.
+func (b *Browser) isReusedContext(bc *BrowserContext) bool {
+	b.reuse.mu.Lock()
+	defer b.reuse.mu.Unlock()
+
+	return b.reuse.context != nil && b.reuse.context == bc
+}
+
+// keepPage keeps the new page of the reused browser
+// context for the next iterations, if none is kept yet.
+func (b *Browser) keepPage(bc *BrowserContext, p *Page) {
+	b.reuse.mu.Lock()
+	defer b.reuse.mu.Unlock()
+
+	if b.reuse.context != bc || (b.reuse.page != nil && !b.reuse.page.isClosedOrCrashed()) {
+		return
+	}
+	b.reuse.page = p
+	b.reuse.pageTaken = true
+}
+
+// reusedPage returns the kept page of the browser context, or nil if the
+// context isn't reused, or the iteration already has the page.
+func (b *Browser) reusedPage(bc *BrowserContext) *Page {
+	b.reuse.mu.Lock()
+	defer b.reuse.mu.Unlock()
+
+	p := b.reuse.page
+	if b.reuse.context != bc || p == nil || p.isClosedOrCrashed() || b.reuse.pageTaken {
+		return nil
+	}
+	b.reuse.pageTaken = true
+
+	return p
+}
+
+// reusedContext returns the kept browser context after resetting it and
+// its page, or nil if there's no context to reuse. A context whose page
+// crashed, or that fails to reset, is closed instead, so that a new one
+// replaces it. It must be called on the event loop, since the reset
+// navigates the page.
+func (b *Browser) reusedContext() *BrowserContext {
+	b.reuse.mu.Lock()
+	bc, p, dirty := b.reuse.context, b.reuse.page, b.reuse.dirty
+	b.reuse.mu.Unlock()
+
+	if bc == nil || !dirty {
+		return bc
+	}
+	if p != nil && p.isClosedOrCrashed() {
+		b.reuse.mu.Lock()
+		b.reuse.context, b.reuse.page = nil, nil
+		b.reuse.mu.Unlock()
+
+		if err := bc.close(); err != nil {
+			b.logger.Errorf("Browser:reusedContext", "%v", err)
+		}
+		return nil
+	}
+
+	start := time.Now()
+	if err := bc.resetForReuse(p); err != nil {
+		// don't reuse a context that may leak the state of the last iteration.
+		b.reuse.mu.Lock()
+		b.reuse.context, b.reuse.page = nil, nil
+		b.reuse.mu.Unlock()
+
+		b.logger.Errorf("Browser:reusedContext", "resetting reused browser context: %v", err)
+		if err := bc.close(); err != nil {
+			b.logger.Errorf("Browser:reusedContext", "%v", err)
+		}
+		return nil
+	}
+	bc.pushResetDuration(time.Since(start))
+
+	b.reuse.mu.Lock()
+	b.reuse.dirty = false
+	b.reuse.mu.Unlock()
+
+	return bc
+}
+
+// releaseReusedContext closes the pages of the reused browser context other
+// than the reused page, and stops running the event handlers of the context
+// and the page, so that they no longer keep the iteration running.
+func (b *Browser) releaseReusedContext() {
+	b.reuse.mu.Lock()
+	bc, kept := b.reuse.context, b.reuse.page
+	b.reuse.mu.Unlock()
+
+	for _, p := range bc.Pages() {
+		p := p.(*Page)
+		if p == kept {
+			continue
+		}
+		action := target.CloseTarget(p.targetID)
+		if err := action.Do(cdp.WithExecutor(b.ctx, b.conn)); err != nil {
+			b.logger.Errorf("Browser:releaseReusedContext", "closing page: %v", err)
+		}
+	}
+	// don't let the requests wait for handlers that won't run.
+	bc.continuePendingRoutes()
+	bc.eventLoopQueue.stop()
+	if kept != nil {
+		kept.eventLoopQueue.stop()
+	}
+}
+
+// endIteration closes the browser contexts of the iteration, and marks the
+// reused browser context, if there's one, to be reset before its next use.
+func (b *Browser) endIteration() {
+	b.closeContexts()
+
+	b.reuse.mu.Lock()
+	defer b.reuse.mu.Unlock()
+
+	b.reuse.pageTaken = false
+	b.reuse.dirty = b.reuse.context != nil
+}
+
+// addVisitedOrigin records the origin of a URL that a frame of the
+// browser context navigated to, if the browser context is reused.
+func (b *BrowserContext) addVisitedOrigin(u string) {
+	if b.browser == nil || !b.browser.reusesPage() {
+		return
+	}
+	origin := urlOrigin(u)
+	if origin == "" {
+		return
+	}
+
+	b.visitedOriginsMu.Lock()
+	defer b.visitedOriginsMu.Unlock()
+
+	if b.visitedOrigins == nil {
+		b.visitedOrigins = make(map[string]bool)
+	}
+	b.visitedOrigins[origin] = true
+}
+
+// takeVisitedOrigins returns the visited origins and forgets them.
+func (b *BrowserContext) takeVisitedOrigins() []string {
+	b.visitedOriginsMu.Lock()
+	defer b.visitedOriginsMu.Unlock()
+
+	origins := make([]string, 0, len(b.visitedOrigins))
+	for o := range b.visitedOrigins {
+		origins = append(origins, o)
+	}
+	b.visitedOrigins = nil
+
+	return origins
+}
+
+// urlOrigin returns the origin of a URL, or an empty string if the
+// URL has an opaque origin, such as about:blank and data: URLs.
+func urlOrigin(u string) string {
+	pu, err := url.Parse(u)
+	if err != nil || (pu.Scheme != "http" && pu.Scheme != "https") || pu.Host == "" {
+		return ""
+	}
+	return pu.Scheme + "://" + pu.Host
+}
+
+// resetForReuse resets the state that the last iteration left in the
+// browser context and its page, so that the next iteration starts afresh:
+//   - The page navigates to about:blank, which releases its JS handles.
+//   - The cookies, and the storage of the visited origins are cleared,
+//     including their service worker registrations and caches.
+//   - The routes and the event handlers of the context and the page are
+//     removed.
+//
+// The settings of the context and the page, such as the viewport, the
+// extra HTTP headers, the init scripts and the exposed functions, are kept.
+func (b *BrowserContext) resetForReuse(p *Page) error {
+	b.logger.Debugf("BrowserContext:resetForReuse", "bctxid:%v", b.id)
+
+	b.routes.clear()
+	b.continuePendingRoutes()
+	b.eventHandlersMu.Lock()
+	b.eventHandlers = nil
+	b.eventHandlersMu.Unlock()
+	// drop the tasks of the removed handlers that
+	// were queued after the last iteration ended.
+	b.eventLoopQueue.stop()
+
+	if p != nil {
+		if err := p.resetForReuse(); err != nil {
+			return err
+		}
+		for _, origin := range b.takeVisitedOrigins() {
+			action := storage.ClearDataForOrigin(origin, "all")
+			if err := action.Do(cdp.WithExecutor(p.ctx, p.session)); err != nil {
+				return fmt.Errorf("clearing storage of origin %q: %w", origin, err)
+			}
+		}
+	}
+
+	action := storage.ClearCookies().WithBrowserContextID(b.id)
+	if err := action.Do(cdp.WithExecutor(b.ctx, b.browser.getConn())); err != nil {
+		return fmt.Errorf("clearing cookies: %w", err)
+	}
+
+	return nil
+}
+
+// resetForReuse removes the routes and the event handlers of the page,
+// and navigates it to about:blank. See BrowserContext.resetForReuse.
+func (p *Page) resetForReuse() error {
+	p.routes.clear()
+	if err := p.updateRequestInterception(); err != nil {
+		return fmt.Errorf("removing routes: %w", err)
+	}
+
+	p.eventHandlersMu.Lock()
+	p.eventHandlers = nil
+	p.eventHandlersMu.Unlock()
+	p.eventLoopQueue.stop()
+	p.disposeConsoleHandles()
+
+	p.pageErrorsMu.Lock()
+	p.pageErrors = nil
+	p.pageErrorsMu.Unlock()
+
+	if _, err := p.frameManager.navigateFrame(p.frameManager.MainFrame(), "about:blank", nil); err != nil {
+		return fmt.Errorf("resetting page: %w", err)
+	}
+
+	return nil
+}
+
+// pushResetDuration pushes the duration of resetting
+// the browser context for reuse as a metric.
+func (b *BrowserContext) pushResetDuration(d time.Duration) {
+	k6m := k6ext.GetCustomMetrics(b.ctx)
+	state := b.vu.State()
+	if k6m == nil || k6m.BrowserResetDuration == nil || state == nil {
+		return
+	}
+	ctx := b.vu.Context()
+	tags := vuMetricTags(ctx, state)
+	k6metrics.PushIfNotDone(ctx, state.Samples, k6metrics.Sample{
+		Metric: k6m.BrowserResetDuration,
+		Tags:   k6metrics.IntoSampleTags(&tags),
+		Value:  k6metrics.D(d),
+		Time:   time.Now(),
+	})
+}
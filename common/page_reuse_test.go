@@ -0,0 +1,38 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestURLOrigin(t *testing.T) {
+	t.Parallel()
+
+	for url, want := range map[string]string{
+		"https://example.com/path?q=1":  "https://example.com",
+		"http://127.0.0.1:8080/":        "http://127.0.0.1:8080",
+		"about:blank":                   "",
+		"data:text/html,<p>hi</p>":      "",
+		"chrome-error://chromewebdata/": "",
+		"":                              "",
+	} {
+		assert.Equal(t, want, urlOrigin(url), url)
+	}
+}
+
+func TestBrowserContextVisitedOrigins(t *testing.T) {
+	t.Parallel()
+
+	reused := &BrowserContext{browser: &Browser{launchOpts: &LaunchOptions{ReusePage: true}}}
+	reused.addVisitedOrigin("https://example.com/a")
+	reused.addVisitedOrigin("https://example.com/b")
+	reused.addVisitedOrigin("about:blank")
+	assert.Equal(t, []string{"https://example.com"}, reused.takeVisitedOrigins())
+	assert.Empty(t, reused.takeVisitedOrigins(), "should forget the taken origins")
+
+	// the origins are only tracked when the context is reused.
+	bc := &BrowserContext{browser: &Browser{launchOpts: &LaunchOptions{}}}
+	bc.addVisitedOrigin("https://example.com/")
+	assert.Empty(t, bc.takeVisitedOrigins())
+}
@@ -2,8 +2,13 @@ package common
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"testing"
 
+	"github.com/grafana/xk6-browser/log"
+
+	"github.com/chromedp/cdproto/cdp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -32,3 +37,38 @@ func TestPageLocator(t *testing.T) {
 
 	// other behavior will be tested via integration tests
 }
+
+// The sessions of the out-of-process frames are attached by the goroutines
+// that handle CDP events, while the actions of the page read them.
+func TestPageConcurrentFrameSessions(t *testing.T) {
+	t.Parallel()
+
+	main := &FrameSession{}
+	p := &Page{
+		logger:           log.NewNullLogger(),
+		mainFrameSession: main,
+		frameSessions:    map[cdp.FrameID]*FrameSession{"main": main},
+	}
+
+	const n = 50
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < n; i++ {
+			p.attachFrameSession(cdp.FrameID(fmt.Sprint(i)), &FrameSession{})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < n; i++ {
+			_ = p.getFrameSessions()
+			_ = p.getFrameSession(cdp.FrameID(fmt.Sprint(i)))
+		}
+	}()
+	wg.Wait()
+
+	sessions := p.getFrameSessions()
+	require.Len(t, sessions, n+1)
+	assert.Same(t, main, sessions[0], "the main frame session should be first")
+}
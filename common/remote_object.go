@@ -22,10 +22,12 @@ package common
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
+	"math/big"
 	"strconv"
 	"strings"
 
@@ -84,11 +86,16 @@ func parseRemoteObjectValue(t cdpruntime.Type, val string, op *cdpruntime.Object
 	case cdpruntime.TypeAccessor:
 		return "accessor", nil
 	case cdpruntime.TypeBigint:
-		n, err := strconv.ParseInt(strings.Replace(val, "n", "", -1), 10, 64)
-		if err != nil {
-			return nil, BigIntParseError{err}
+		digits := strings.TrimSuffix(val, "n")
+		n, err := strconv.ParseInt(digits, 10, 64)
+		if err == nil {
+			return n, nil
+		}
+		// BigInts that don't fit in an int64 are returned in decimal notation.
+		if _, ok := new(big.Int).SetString(digits, 10); ok {
+			return digits, nil
 		}
-		return n, nil
+		return nil, BigIntParseError{err}
 	case cdpruntime.TypeFunction:
 		return "function()", nil
 	case cdpruntime.TypeString:
@@ -116,27 +123,22 @@ func parseRemoteObjectValue(t cdpruntime.Type, val string, op *cdpruntime.Object
 	return v, nil
 }
 
-func parseExceptionDetails(exc *cdpruntime.ExceptionDetails) string {
-	if exc == nil {
-		return ""
-	}
-	var errMsg string
-	if exc.Exception != nil {
-		errMsg = exc.Exception.Description
-		if errMsg == "" {
-			if o, _ := parseRemoteObject(exc.Exception); o != nil {
-				errMsg = fmt.Sprintf("%s", o)
-			}
-		}
-	}
-	return errMsg
-}
-
 func parseRemoteObject(obj *cdpruntime.RemoteObject) (interface{}, error) {
 	if obj.UnserializableValue == "" {
 		return parseRemoteObjectValue(obj.Type, string(obj.Value), obj.Preview)
 	}
 
+	if obj.Type == cdpruntime.TypeBigint {
+		// BigInts that don't fit in an int64 are returned in decimal notation.
+		digits := strings.TrimSuffix(obj.UnserializableValue.String(), "n")
+		if n, err := strconv.ParseInt(digits, 10, 64); err == nil {
+			return n, nil
+		}
+		if _, ok := new(big.Int).SetString(digits, 10); ok {
+			return digits, nil
+		}
+	}
+
 	switch obj.UnserializableValue.String() {
 	case "-0": // To handle +0 divided by negative number
 		return math.Float64frombits(0 | (1 << 63)), nil
@@ -181,3 +183,200 @@ func handleParseRemoteObjectErr(ctx context.Context, err error, logger *logrus.E
 		}
 	}
 }
+
+// serializeValueFn serializes a JS value to JSON, keeping the types that
+// can't be represented in JSON, such as Date, Map, Set, BigInt, RegExp,
+// Error and ArrayBuffer, and the special numbers. Each value is an object
+// with one of the keys below, so that it can be converted back to a JS
+// value in another runtime by serializedValue. The binary data of the
+// ArrayBuffers, the typed arrays and the DataViews is encoded in base64,
+// up to maxBinarySize bytes in total unless it is zero.
+const serializeValueFn = `
+(value, maxDepth, maxBinarySize) => {
+	let binarySize = 0;
+	const binary = v => {
+		const bytes = v instanceof ArrayBuffer ?
+			new Uint8Array(v) : new Uint8Array(v.buffer, v.byteOffset, v.byteLength);
+		binarySize += bytes.length;
+		if (maxBinarySize > 0 && binarySize > maxBinarySize) {
+			throw new Error('value has ' + binarySize + ' bytes of binary data or more, ' +
+				'over the maximum of ' + maxBinarySize + ' bytes');
+		}
+		let s = '';
+		for (let i = 0; i < bytes.length; i += 0x8000) {
+			s += String.fromCharCode.apply(null, bytes.subarray(i, i + 0x8000));
+		}
+		return { ab: btoa(s) };
+	};
+	const serialize = (v, depth) => {
+		if (depth > maxDepth) {
+			throw new Error('value is nested deeper than the maximum depth of ' + maxDepth);
+		}
+		switch (typeof v) {
+		case 'undefined':
+		case 'function':
+		case 'symbol':
+			return { v: 'undefined' };
+		case 'boolean':
+			return { b: v };
+		case 'string':
+			return { s: v };
+		case 'bigint':
+			return { bi: v.toString() };
+		case 'number':
+			if (Object.is(v, -0)) return { v: '-0' };
+			if (!Number.isFinite(v)) return { v: String(v) };
+			return { n: v };
+		}
+		if (v === null) return { v: 'null' };
+		if (v instanceof Date) return { d: isNaN(v) ? null : v.getTime() };
+		if (v instanceof RegExp) return { r: { p: v.source, f: v.flags } };
+		if (v instanceof Error) return { e: { n: v.name, m: v.message, s: v.stack || '' } };
+		if (v instanceof Map) {
+			return { o: Array.from(v, ([k, x]) => ({ k: String(k), v: serialize(x, depth + 1) })) };
+		}
+		if (v instanceof ArrayBuffer || ArrayBuffer.isView(v)) return binary(v);
+		if (v instanceof Set || Array.isArray(v)) {
+			return { a: Array.from(v, x => serialize(x, depth + 1)) };
+		}
+		if (typeof v.toJSON === 'function') return serialize(v.toJSON(), depth);
+		return { o: Object.keys(v).map(k => ({ k, v: serialize(v[k], depth + 1) })) };
+	};
+	return serialize(value, 0);
+}
+`
+
+// serializedValue is a JS value serialized by serializeValueFn.
+// Only one of its fields is set.
+type serializedValue struct {
+	// V is one of the values undefined, null, NaN, Infinity, -Infinity and -0.
+	V *string  `json:"v"`
+	B *bool    `json:"b"`
+	S *string  `json:"s"`
+	N *float64 `json:"n"`
+	// BI is a BigInt in decimal notation.
+	BI *string `json:"bi"`
+	// D is a Date as milliseconds since the epoch, or null for invalid dates.
+	D json.RawMessage `json:"d"`
+	R *struct {
+		Pattern string `json:"p"`
+		Flags   string `json:"f"`
+	} `json:"r"`
+	E *struct {
+		Name    string `json:"n"`
+		Message string `json:"m"`
+		Stack   string `json:"s"`
+	} `json:"e"`
+	// AB is the binary data of an ArrayBuffer, a typed array or a DataView
+	// in base64.
+	AB *string `json:"ab"`
+	// A is an array or a Set.
+	A []serializedValue `json:"a"`
+	// O is an object or a Map, with its keys in order.
+	O []struct {
+		K string          `json:"k"`
+		V serializedValue `json:"v"`
+	} `json:"o"`
+}
+
+// toGojaValue converts the serialized value to a goja value.
+func (s *serializedValue) toGojaValue(rt *goja.Runtime) (goja.Value, error) { //nolint:cyclop
+	switch {
+	case s.V != nil:
+		return specialGojaValue(rt, *s.V)
+	case s.B != nil:
+		return rt.ToValue(*s.B), nil
+	case s.S != nil:
+		return rt.ToValue(*s.S), nil
+	case s.N != nil:
+		return rt.ToValue(*s.N), nil
+	case s.BI != nil:
+		// BigInts that don't fit in an int64 are returned in decimal notation.
+		if n, err := strconv.ParseInt(*s.BI, 10, 64); err == nil {
+			return rt.ToValue(n), nil
+		}
+		return rt.ToValue(*s.BI), nil
+	case s.D != nil:
+		ms := math.NaN()
+		if string(s.D) != "null" {
+			if err := json.Unmarshal(s.D, &ms); err != nil {
+				return nil, fmt.Errorf("parsing date: %w", err)
+			}
+		}
+		return newGojaObject(rt, "Date", rt.ToValue(ms))
+	case s.R != nil:
+		return newGojaObject(rt, "RegExp", rt.ToValue(s.R.Pattern), rt.ToValue(s.R.Flags))
+	case s.E != nil:
+		ctor := s.E.Name
+		if _, ok := goja.AssertFunction(rt.Get(ctor)); !ok {
+			ctor = "Error"
+		}
+		e, err := newGojaObject(rt, ctor, rt.ToValue(s.E.Message))
+		if err != nil {
+			return nil, err
+		}
+		if err := e.Set("stack", s.E.Stack); err != nil {
+			return nil, fmt.Errorf("setting error stack: %w", err)
+		}
+		return e, nil
+	case s.AB != nil:
+		data, err := base64.StdEncoding.DecodeString(*s.AB)
+		if err != nil {
+			return nil, fmt.Errorf("parsing binary data: %w", err)
+		}
+		return rt.ToValue(rt.NewArrayBuffer(data)), nil
+	case s.A != nil:
+		items := make([]interface{}, len(s.A))
+		for i := range s.A {
+			v, err := s.A[i].toGojaValue(rt)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = v
+		}
+		return rt.NewArray(items...), nil
+	case s.O != nil:
+		obj := rt.NewObject()
+		for _, p := range s.O {
+			v, err := p.V.toGojaValue(rt)
+			if err != nil {
+				return nil, err
+			}
+			if err := obj.Set(p.K, v); err != nil {
+				return nil, fmt.Errorf("setting property %q: %w", p.K, err)
+			}
+		}
+		return obj, nil
+	}
+
+	return nil, errors.New("unknown serialized value")
+}
+
+// specialGojaValue returns the goja value of a serialized value
+// that can't be represented in JSON.
+func specialGojaValue(rt *goja.Runtime, v string) (goja.Value, error) {
+	switch v {
+	case "undefined":
+		return goja.Undefined(), nil
+	case "null":
+		return goja.Null(), nil
+	}
+	n, err := parseRemoteObject(&cdpruntime.RemoteObject{
+		Type:                cdpruntime.TypeNumber,
+		UnserializableValue: cdpruntime.UnserializableValue(v),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rt.ToValue(n), nil
+}
+
+// newGojaObject creates an object with the global constructor of the
+// given name, such as Date.
+func newGojaObject(rt *goja.Runtime, ctor string, args ...goja.Value) (*goja.Object, error) {
+	obj, err := rt.New(rt.Get(ctor), args...)
+	if err != nil {
+		return nil, fmt.Errorf("creating %s: %w", ctor, err)
+	}
+	return obj, nil
+}
@@ -21,8 +21,10 @@
 package common
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"math"
+	"strings"
 	"testing"
 
 	"github.com/grafana/xk6-browser/k6ext/k6test"
@@ -64,6 +66,24 @@ func TestValueFromRemoteObject(t *testing.T) {
 		assert.ErrorIs(t, UnserializableValueError{unserializableValue}, err)
 	})
 
+	t.Run("bigint", func(t *testing.T) {
+		vu := k6test.NewVU(t)
+		for value, want := range map[string]interface{}{
+			"100n":                               int64(100),
+			"-2147483649n":                       int64(-2147483649),
+			"18446744073709551616n":              "18446744073709551616",
+			"-1" + strings.Repeat("0", 99) + "n": "-1" + strings.Repeat("0", 99),
+		} {
+			remoteObject := &runtime.RemoteObject{
+				Type:                "bigint",
+				UnserializableValue: runtime.UnserializableValue(value),
+			}
+			arg, err := valueFromRemoteObject(vu.Context(), remoteObject)
+			require.NoError(t, err)
+			assert.Equal(t, want, arg.Export())
+		}
+	})
+
 	t.Run("float64 unserializable values", func(t *testing.T) {
 		vu := k6test.NewVU(t)
 		unserializableValues := []struct {
@@ -183,6 +203,7 @@ func TestParseRemoteObject(t *testing.T) {
 				Properties: []*runtime.PropertyPreview{
 					{Name: "accessor", Type: runtime.TypeAccessor, Value: ""},
 					{Name: "bigint", Type: runtime.TypeBigint, Value: "100n"},
+					{Name: "hugeint", Type: runtime.TypeBigint, Value: strings.Repeat("9", 100) + "n"},
 					{Name: "bool", Type: runtime.TypeBoolean, Value: "true"},
 					{Name: "fn", Type: runtime.TypeFunction, Value: ""},
 					{Name: "num", Type: runtime.TypeNumber, Value: "1"},
@@ -194,6 +215,7 @@ func TestParseRemoteObject(t *testing.T) {
 			expected: map[string]interface{}{
 				"accessor": "accessor",
 				"bigint":   int64(100),
+				"hugeint":  strings.Repeat("9", 100),
 				"bool":     true,
 				"fn":       "function()",
 				"num":      float64(1),
@@ -254,3 +276,185 @@ func TestParseRemoteObject(t *testing.T) {
 		})
 	}
 }
+
+func TestSerializedValue(t *testing.T) {
+	t.Parallel()
+
+	// roundTrip serializes the value of the JS expression with the same
+	// function that is used in the browser, and converts it back.
+	roundTrip := func(t *testing.T, rt *goja.Runtime, js string, maxDepth, maxBinarySize int) (goja.Value, error) {
+		t.Helper()
+
+		serialize, err := rt.RunString("(" + serializeValueFn + ")")
+		require.NoError(t, err)
+		fn, ok := goja.AssertFunction(serialize)
+		require.True(t, ok)
+		v, err := rt.RunString("(" + js + ")")
+		require.NoError(t, err)
+
+		setBase64Globals(t, rt)
+		serialized, err := fn(goja.Undefined(), v, rt.ToValue(maxDepth), rt.ToValue(maxBinarySize))
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(serialized.Export())
+		require.NoError(t, err)
+		var sv serializedValue
+		require.NoError(t, json.Unmarshal(data, &sv))
+
+		return sv.toGojaValue(rt)
+	}
+
+	testCases := []struct {
+		name, js, check string
+	}{
+		{
+			name:  "date",
+			js:    `new Date(0)`,
+			check: `v instanceof Date && v.toISOString() === '1970-01-01T00:00:00.000Z'`,
+		},
+		{
+			name:  "invalid_date",
+			js:    `new Date('invalid')`,
+			check: `v instanceof Date && isNaN(v.getTime())`,
+		},
+		{
+			name:  "map",
+			js:    `new Map([['a', 1], [2, 'b']])`,
+			check: `v.a === 1 && v['2'] === 'b' && Object.keys(v).length === 2`,
+		},
+		{
+			name:  "set",
+			js:    `new Set([1, 'a', 1])`,
+			check: `Array.isArray(v) && v.length === 2 && v[0] === 1 && v[1] === 'a'`,
+		},
+		{
+			name:  "regexp",
+			js:    `/ab+c/gi`,
+			check: `v instanceof RegExp && v.source === 'ab+c' && v.flags === 'gi'`,
+		},
+		{
+			name: "error",
+			js:   `new TypeError('boom')`,
+			check: `v instanceof TypeError && v.message === 'boom' &&
+				v.stack.length > 0`,
+		},
+		{
+			name: "special_values",
+			js:   `[NaN, Infinity, -Infinity, -0, undefined, null]`,
+			check: `isNaN(v[0]) && v[1] === Infinity && v[2] === -Infinity &&
+				Object.is(v[3], -0) && v[4] === undefined && v[5] === null`,
+		},
+		{
+			name:  "typed_array",
+			js:    `new Uint8Array([1, 2, 255])`,
+			check: `v instanceof ArrayBuffer && new Uint8Array(v).join() === '1,2,255'`,
+		},
+		{
+			name:  "empty_array_buffer",
+			js:    `new ArrayBuffer(0)`,
+			check: `v instanceof ArrayBuffer && v.byteLength === 0`,
+		},
+		{
+			name: "views",
+			js: `[
+				new Uint8Array([1, 2, 3, 4]).subarray(1, 3),
+				new DataView(new Uint16Array([258]).buffer),
+				new Float64Array([0.5]),
+			]`,
+			check: `new Uint8Array(v[0]).join() === '2,3' && new DataView(v[1]).getUint16(0, true) === 258 &&
+				new Float64Array(v[2])[0] === 0.5`,
+		},
+		{
+			name: "nested",
+			js: `({
+				when: new Date(0),
+				tags: new Set(['a']),
+				meta: new Map([['m', { re: /x/, at: [new Date(1000)] }]]),
+				list: [[1, [2]]],
+			})`,
+			check: `v.when instanceof Date && v.tags[0] === 'a' &&
+				v.meta.m.re instanceof RegExp && v.meta.m.at[0].getTime() === 1000 &&
+				v.list[0][1][0] === 2`,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			rt := k6test.NewVU(t).Runtime()
+			v, err := roundTrip(t, rt, tc.js, 10, 0)
+			require.NoError(t, err)
+			require.NoError(t, rt.Set("v", v))
+			ok, err := rt.RunString(tc.check)
+			require.NoError(t, err)
+			assert.True(t, ok.ToBoolean(), "got %v", v)
+		})
+	}
+
+	t.Run("bigint", func(t *testing.T) {
+		t.Parallel()
+
+		rt := k6test.NewVU(t).Runtime()
+		for js, want := range map[string]interface{}{
+			`{"bi":"123"}`: int64(123),
+			`{"bi":"123456789012345678901234567890"}`: "123456789012345678901234567890",
+		} {
+			var sv serializedValue
+			require.NoError(t, json.Unmarshal([]byte(js), &sv))
+			v, err := sv.toGojaValue(rt)
+			require.NoError(t, err)
+			assert.Equal(t, want, v.Export())
+		}
+	})
+
+	t.Run("err_max_depth", func(t *testing.T) {
+		t.Parallel()
+
+		rt := k6test.NewVU(t).Runtime()
+		_, err := roundTrip(t, rt, `[[[[1]]]]`, 2, 0)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "value is nested deeper than the maximum depth of 2")
+
+		_, err = roundTrip(t, rt, `[[[1]]]`, 3, 0)
+		require.NoError(t, err)
+	})
+
+	t.Run("err_max_binary_size", func(t *testing.T) {
+		t.Parallel()
+
+		rt := k6test.NewVU(t).Runtime()
+		_, err := roundTrip(t, rt, `[new ArrayBuffer(3), new Uint8Array(2)]`, 10, 4)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "value has 5 bytes of binary data or more, over the maximum of 4 bytes")
+
+		_, err = roundTrip(t, rt, `[new ArrayBuffer(3), new Uint8Array(1)]`, 10, 4)
+		require.NoError(t, err)
+	})
+}
+
+// setBase64Globals sets the atob and btoa functions of the browsers,
+// which goja lacks, for the functions that run in the page.
+func setBase64Globals(t *testing.T, rt *goja.Runtime) {
+	t.Helper()
+
+	require.NoError(t, rt.Set("btoa", func(s string) string {
+		b := make([]byte, 0, len(s))
+		for _, r := range s {
+			b = append(b, byte(r))
+		}
+		return base64.StdEncoding.EncodeToString(b)
+	}))
+	require.NoError(t, rt.Set("atob", func(s string) (string, error) {
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return "", err
+		}
+		r := make([]rune, len(b))
+		for i, c := range b {
+			r[i] = rune(c)
+		}
+		return string(r), nil
+	}))
+}
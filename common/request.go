@@ -22,10 +22,13 @@ package common
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/grafana/xk6-browser/api"
@@ -47,20 +50,30 @@ type Request struct {
 	frame               *Frame
 	response            *Response
 	redirectChain       []*Request
+	redirectedToMu      sync.RWMutex
+	redirectedTo        *Request
 	requestID           network.RequestID
 	documentID          string
 	url                 *url.URL
 	method              string
 	headers             map[string][]string
-	postData            string
+	extraInfoMu         sync.RWMutex
+	extraHeaders        []api.HTTPHeader
+	extraInfoReady      chan struct{}
+	postDataMu          sync.RWMutex
+	postData            []byte
+	hasPostData         bool
 	resourceType        string
 	isNavigationRequest bool
 	allowInterception   bool
 	interceptionID      string
 	fromMemoryCache     bool
+	failureMu           sync.RWMutex
 	errorText           string
+	canceled            bool
 	timestamp           time.Time
 	wallTime            time.Time
+	timingMu            sync.RWMutex
 	responseEndTiming   float64
 	vu                  k6modules.VU
 }
@@ -94,7 +107,9 @@ func NewRequest(
 		url:                 u,
 		method:              event.Request.Method,
 		headers:             make(map[string][]string),
-		postData:            event.Request.PostData,
+		extraInfoReady:      make(chan struct{}),
+		postData:            requestPostData(event.Request),
+		hasPostData:         event.Request.HasPostData,
 		resourceType:        event.Type.String(),
 		isNavigationRequest: string(event.RequestID) == string(event.LoaderID) && event.Type == network.ResourceTypeDocument,
 		allowInterception:   allowInterception,
@@ -103,6 +118,7 @@ func NewRequest(
 		errorText:           "",
 		timestamp:           event.Timestamp.Time(),
 		wallTime:            event.WallTime.Time(),
+		responseEndTiming:   -1,
 		vu:                  k6ext.GetVU(ctx),
 	}
 	for n, v := range event.Request.Headers {
@@ -118,6 +134,63 @@ func NewRequest(
 	return &r, nil
 }
 
+// requestPostData returns the post data of the request, or nil if the
+// request doesn't have post data or the data is too long to be included.
+// The binary post data entries take precedence over the post data string,
+// which may be missing the bytes that aren't valid UTF-8.
+func requestPostData(req *network.Request) []byte {
+	if len(req.PostDataEntries) == 0 {
+		if req.PostData == "" {
+			return nil
+		}
+		return []byte(req.PostData)
+	}
+	var data []byte
+	for _, e := range req.PostDataEntries {
+		b, err := base64.StdEncoding.DecodeString(e.Bytes)
+		if err != nil {
+			return []byte(req.PostData)
+		}
+		data = append(data, b...)
+	}
+	return data
+}
+
+// fetchPostData fetches the post data of the request from the browser
+// if the post data was too long to be sent with the request event.
+func (r *Request) fetchPostData() error {
+	cached := func() bool {
+		r.postDataMu.RLock()
+		defer r.postDataMu.RUnlock()
+
+		return r.postData != nil || !r.hasPostData || r.frame == nil
+	}
+	if cached() {
+		return nil
+	}
+	action := network.GetRequestPostData(r.requestID)
+	postData, err := action.Do(cdp.WithExecutor(r.ctx, r.frame.manager.session))
+	if err != nil {
+		return fmt.Errorf("fetching post data: %w", err)
+	}
+	r.postDataMu.Lock()
+	r.postData = []byte(postData)
+	r.postDataMu.Unlock()
+	return nil
+}
+
+// postDataBytes returns the post data of the request,
+// or nil if the request doesn't have post data.
+func (r *Request) postDataBytes() []byte {
+	if err := r.fetchPostData(); err != nil {
+		k6ext.Panic(r.ctx, "getting request post data: %w", err)
+	}
+	r.postDataMu.RLock()
+	defer r.postDataMu.RUnlock()
+
+	return r.postData
+}
+
 func (r *Request) getFrame() *Frame {
 	return r.frame
 }
@@ -141,26 +214,78 @@ func (r *Request) headersSize() int64 {
 	return int64(size)
 }
 
-func (r *Request) setErrorText(errorText string) {
+// setFailure marks the request as failed, or canceled, with the error text.
+func (r *Request) setFailure(errorText string, canceled bool) {
+	r.failureMu.Lock()
+	defer r.failureMu.Unlock()
+
 	r.errorText = errorText
+	r.canceled = canceled
 }
 
 func (r *Request) setLoadedFromCache(fromMemoryCache bool) {
 	r.fromMemoryCache = fromMemoryCache
 }
 
-func (r *Request) AllHeaders() map[string]string {
-	// TODO: fix this data to include "ExtraInfo" header data
-	headers := make(map[string]string)
+// setExtraHeaders sets the raw headers of the request
+// from the requestWillBeSentExtraInfo event.
+func (r *Request) setExtraHeaders(headers network.Headers) {
+	r.extraInfoMu.Lock()
+	defer r.extraInfoMu.Unlock()
+
+	if r.extraHeaders != nil {
+		return
+	}
+	r.extraHeaders = headersToArray(headers)
+	close(r.extraInfoReady)
+}
+
+func (r *Request) hasExtraHeaders() bool {
+	r.extraInfoMu.RLock()
+	defer r.extraInfoMu.RUnlock()
+
+	return r.extraHeaders != nil
+}
+
+// allHeadersArray returns the raw headers of the request, which include
+// the cookie and security headers, if the browser reports them. Otherwise,
+// it returns the headers of the request event.
+func (r *Request) allHeadersArray() []api.HTTPHeader {
+	if resp := r.response; resp != nil && resp.hasExtraInfo {
+		waitForExtraInfo(r.ctx, r.extraInfoReady)
+	}
+	r.extraInfoMu.RLock()
+	defer r.extraInfoMu.RUnlock()
+
+	if r.extraHeaders != nil {
+		return append([]api.HTTPHeader(nil), r.extraHeaders...)
+	}
+	headers := make(network.Headers, len(r.headers))
 	for n, v := range r.headers {
-		headers[strings.ToLower(n)] = strings.Join(v, ",")
+		headers[n] = strings.Join(v, "\n")
 	}
-	return headers
+	return headersToArray(headers)
 }
 
+// AllHeaders returns the request headers with lowercase names, including
+// the cookie and security headers that the browser adds to the request.
+// The values of the headers with the same name are joined with commas.
+func (r *Request) AllHeaders() map[string]string {
+	return headersArrayToMap(r.allHeadersArray())
+}
+
+// Failure returns why the request failed, or null if it hasn't failed.
 func (r *Request) Failure() goja.Value {
-	k6ext.Panic(r.ctx, "Request.failure() has not been implemented yet")
-	return nil
+	r.failureMu.RLock()
+	defer r.failureMu.RUnlock()
+
+	if r.errorText == "" {
+		return goja.Null()
+	}
+	return r.vu.Runtime().ToValue(&RequestFailure{
+		ErrorText: r.errorText,
+		Canceled:  r.canceled,
+	})
 }
 
 // Frame returns the frame within which the request was made.
@@ -168,16 +293,23 @@ func (r *Request) Frame() api.Frame {
 	return r.frame
 }
 
+// HeaderValue returns the value of the header with the case-insensitive
+// name, or null if the request doesn't have the header. See AllHeaders.
 func (r *Request) HeaderValue(name string) goja.Value {
 	rt := r.vu.Runtime()
 	headers := r.AllHeaders()
-	val, ok := headers[name]
+	val, ok := headers[strings.ToLower(name)]
 	if !ok {
 		return goja.Null()
 	}
 	return rt.ToValue(val)
 }
 
+// HeaderValues returns the values of the headers with the case-insensitive name.
+func (r *Request) HeaderValues(name string) []string {
+	return headerValues(r.allHeadersArray(), name)
+}
+
 // Headers returns the request headers.
 func (r *Request) Headers() map[string]string {
 	headers := make(map[string]string)
@@ -187,14 +319,10 @@ func (r *Request) Headers() map[string]string {
 	return headers
 }
 
+// HeadersArray returns the request headers, including the ones with the
+// same name, and the cookie and security headers. See AllHeaders.
 func (r *Request) HeadersArray() []api.HTTPHeader {
-	headers := make([]api.HTTPHeader, 0)
-	for n, vals := range r.headers {
-		for _, v := range vals {
-			headers = append(headers, api.HTTPHeader{Name: n, Value: v})
-		}
-	}
-	return headers
+	return r.allHeadersArray()
 }
 
 // IsNavigationRequest returns whether this was a navigation request or not.
@@ -209,29 +337,78 @@ func (r *Request) Method() string {
 
 // PostData returns the request post data, if any.
 func (r *Request) PostData() string {
-	return r.postData
+	return string(r.postDataBytes())
 }
 
 // PostDataBuffer returns the request post data as an ArrayBuffer.
 func (r *Request) PostDataBuffer() goja.ArrayBuffer {
 	rt := r.vu.Runtime()
-	return rt.NewArrayBuffer([]byte(r.postData))
+	return rt.NewArrayBuffer(r.postDataBytes())
 }
 
-// PostDataJSON returns the request post data as a JS object.
-func (r *Request) PostDataJSON() string {
-	k6ext.Panic(r.ctx, "Request.postDataJSON() has not been implemented yet")
-	return ""
+// PostDataJSON returns the request post data parsed as JSON, or as a form
+// if the request content type is application/x-www-form-urlencoded. It
+// returns null if the request doesn't have post data.
+func (r *Request) PostDataJSON() goja.Value {
+	postData := r.postDataBytes()
+	if postData == nil {
+		return goja.Null()
+	}
+
+	rt := r.vu.Runtime()
+	contentType := r.AllHeaders()["content-type"]
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		values, err := url.ParseQuery(string(postData))
+		if err != nil {
+			k6ext.Panic(r.ctx, "parsing post data as a form: %w", err)
+		}
+		form := make(map[string]string, len(values))
+		for k, v := range values {
+			form[k] = v[len(v)-1]
+		}
+		return rt.ToValue(form)
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(postData, &v); err != nil {
+		k6ext.Panic(r.ctx, "parsing post data as JSON: %w", err)
+	}
+	return rt.ToValue(v)
 }
 
+// RedirectedFrom returns the request that the server redirected to this
+// request, or null if this request isn't the result of a redirect.
 func (r *Request) RedirectedFrom() api.Request {
-	k6ext.Panic(r.ctx, "Request.redirectedFrom() has not been implemented yet")
+	if from := r.redirectedFrom(); from != nil {
+		return from
+	}
 	return nil
 }
 
+func (r *Request) redirectedFrom() *Request {
+	if len(r.redirectChain) == 0 {
+		return nil
+	}
+	return r.redirectChain[len(r.redirectChain)-1]
+}
+
+// RedirectedTo returns the request that the server redirected this
+// request to, or null if the server didn't redirect this request.
 func (r *Request) RedirectedTo() api.Request {
-	k6ext.Panic(r.ctx, "Request.redirectedTo() has not been implemented yet")
-	return nil
+	r.redirectedToMu.RLock()
+	defer r.redirectedToMu.RUnlock()
+
+	if r.redirectedTo == nil {
+		return nil
+	}
+	return r.redirectedTo
+}
+
+func (r *Request) setRedirectedTo(req *Request) {
+	r.redirectedToMu.Lock()
+	defer r.redirectedToMu.Unlock()
+
+	r.redirectedTo = req
 }
 
 // ResourceType returns the request resource type.
@@ -245,26 +422,47 @@ func (r *Request) Response() api.Response {
 }
 
 func (r *Request) Size() api.HTTPMessageSize {
+	// the post data isn't fetched if it's too long to be sent with the
+	// request event, so that the request metrics don't wait for it.
+	r.postDataMu.RLock()
+	bodySize := len(r.postData)
+	r.postDataMu.RUnlock()
+
 	return api.HTTPMessageSize{
-		Body:    int64(len(r.postData)),
+		Body:    int64(bodySize),
 		Headers: r.headersSize(),
 	}
 }
 
+// Timing returns the timing of the request phases. See ResourceTiming.
 func (r *Request) Timing() goja.Value {
+	var timing *network.ResourceTiming
+	if r.response != nil {
+		timing = r.response.timing
+	}
+	r.timingMu.RLock()
+	responseEnd := r.responseEndTiming
+	r.timingMu.RUnlock()
+
 	rt := r.vu.Runtime()
-	timing := r.response.timing
-	return rt.ToValue(&ResourceTiming{
-		StartTime:             (timing.RequestTime - float64(r.timestamp.Unix()) + float64(r.wallTime.Unix())) * 1000,
-		DomainLookupStart:     timing.DNSStart,
-		DomainLookupEnd:       timing.DNSEnd,
-		ConnectStart:          timing.ConnectStart,
-		SecureConnectionStart: timing.SslStart,
-		ConnectEnd:            timing.ConnectEnd,
-		RequestStart:          timing.SendStart,
-		ResponseStart:         timing.ReceiveHeadersEnd,
-		ResponseEnd:           r.responseEndTiming,
-	})
+	return rt.ToValue(newResourceTiming(r.timestamp, r.wallTime, timing, responseEnd))
+}
+
+// setResponseEnd sets the end of the response, or of the request if it
+// failed, to the CDP monotonic time of the loading finished or failed event.
+func (r *Request) setResponseEnd(timestamp *cdp.MonotonicTime) {
+	if timestamp == nil {
+		return
+	}
+	start := monotonicSeconds(r.timestamp)
+	if r.response != nil && r.response.timing != nil {
+		start = r.response.timing.RequestTime
+	}
+
+	r.timingMu.Lock()
+	defer r.timingMu.Unlock()
+
+	r.responseEndTiming = (monotonicSeconds(timestamp.Time()) - start) * 1000
 }
 
 // URL returns the request URL.
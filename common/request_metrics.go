@@ -0,0 +1,68 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package common
+
+import (
+	"context"
+
+	"github.com/dop251/goja"
+
+	"github.com/grafana/xk6-browser/k6ext"
+)
+
+// RequestMetricsOptions control the metrics of the requests of the pages,
+// such as http_req_duration and browser_failed_requests.
+type RequestMetricsOptions struct {
+	// Enabled turns the metrics of the requests on or off. Pages
+	// that make a lot of requests can turn them off.
+	Enabled bool `js:"enabled"`
+	// ErrorTag tags the failed requests with their error text.
+	// It's off by default because the error texts can make too
+	// many tag values.
+	ErrorTag bool `js:"errorTag"`
+}
+
+// NewRequestMetricsOptions returns the default request metrics options,
+// where the metrics are enabled without the error tag.
+func NewRequestMetricsOptions() *RequestMetricsOptions {
+	return &RequestMetricsOptions{
+		Enabled: true,
+	}
+}
+
+// Parse parses the request metrics options from a JS object.
+func (o *RequestMetricsOptions) Parse(ctx context.Context, opts goja.Value) error {
+	if !gojaValueExists(opts) {
+		return nil
+	}
+	rt := k6ext.Runtime(ctx)
+	obj := opts.ToObject(rt)
+	for _, k := range obj.Keys() {
+		switch k {
+		case "enabled":
+			o.Enabled = obj.Get(k).ToBoolean()
+		case "errorTag":
+			o.ErrorTag = obj.Get(k).ToBoolean()
+		}
+	}
+
+	return nil
+}
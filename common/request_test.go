@@ -21,6 +21,9 @@
 package common
 
 import (
+	"context"
+	"encoding/base64"
+	"strings"
 	"testing"
 	"time"
 
@@ -29,6 +32,7 @@ import (
 
 	"github.com/chromedp/cdproto/cdp"
 	"github.com/chromedp/cdproto/network"
+	"github.com/mailru/easyjson"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -95,4 +99,256 @@ func TestRequest(t *testing.T) {
 			api.HTTPMessageSize{Headers: int64(33), Body: int64(5)},
 			req.Size())
 	})
+
+	t.Run("Failure()", func(t *testing.T) {
+		t.Parallel()
+		vu := k6test.NewVU(t)
+		req, err := NewRequest(vu.Context(), evt, nil, nil, "intercept", false)
+		require.NoError(t, err)
+		assert.Nil(t, req.Failure().Export())
+
+		req.setFailure("net::ERR_ABORTED", true)
+		assert.Equal(t, &RequestFailure{
+			ErrorText: "net::ERR_ABORTED",
+			Canceled:  true,
+		}, req.Failure().Export())
+	})
+}
+
+// postDataSession returns the post data of
+// the requests from Network.getRequestPostData.
+type postDataSession struct {
+	session
+	postData string
+}
+
+func (s *postDataSession) Execute(
+	_ context.Context, method string, _ easyjson.Marshaler, res easyjson.Unmarshaler,
+) error {
+	if r, ok := res.(*network.GetRequestPostDataReturns); ok && method == network.CommandGetRequestPostData {
+		r.PostData = s.postData
+	}
+	return nil
+}
+
+func TestRequestPostData(t *testing.T) {
+	t.Parallel()
+
+	newRequest := func(t *testing.T, req *network.Request) *Request {
+		t.Helper()
+
+		vu := k6test.NewVU(t)
+		ts := cdp.MonotonicTime(time.Now())
+		wt := cdp.TimeSinceEpoch(time.Now())
+		req.URL = "https://test/post"
+		req.Method = "POST"
+		r, err := NewRequest(vu.Context(), &network.EventRequestWillBeSent{
+			RequestID: "1234",
+			Request:   req,
+			Timestamp: &ts,
+			WallTime:  &wt,
+		}, nil, nil, "", false)
+		require.NoError(t, err)
+		return r
+	}
+
+	t.Run("json", func(t *testing.T) {
+		t.Parallel()
+
+		req := newRequest(t, &network.Request{
+			Headers:     network.Headers{"Content-Type": "application/json"},
+			PostData:    `{"name":"k6","tags":["a","b"]}`,
+			HasPostData: true,
+		})
+		assert.Equal(t, `{"name":"k6","tags":["a","b"]}`, req.PostData())
+		assert.Equal(t, map[string]interface{}{
+			"name": "k6",
+			"tags": []interface{}{"a", "b"},
+		}, req.PostDataJSON().Export())
+	})
+
+	t.Run("form", func(t *testing.T) {
+		t.Parallel()
+
+		req := newRequest(t, &network.Request{
+			Headers:     network.Headers{"Content-Type": "application/x-www-form-urlencoded"},
+			PostData:    "name=k6&tag=a&tag=b&q=a%20b",
+			HasPostData: true,
+		})
+		assert.Equal(t, map[string]string{
+			"name": "k6",
+			"tag":  "b",
+			"q":    "a b",
+		}, req.PostDataJSON().Export())
+	})
+
+	t.Run("binary", func(t *testing.T) {
+		t.Parallel()
+
+		req := newRequest(t, &network.Request{
+			PostData:    "\ufffd\ufffd",
+			HasPostData: true,
+			PostDataEntries: []*network.PostDataEntry{
+				{Bytes: base64.StdEncoding.EncodeToString([]byte{0xff})},
+				{Bytes: base64.StdEncoding.EncodeToString([]byte{0xfe})},
+			},
+		})
+		assert.Equal(t, []byte{0xff, 0xfe}, req.PostDataBuffer().Bytes())
+	})
+
+	t.Run("none", func(t *testing.T) {
+		t.Parallel()
+
+		req := newRequest(t, &network.Request{})
+		assert.Equal(t, "", req.PostData())
+		assert.Nil(t, req.PostDataJSON().Export())
+	})
+
+	t.Run("fetch", func(t *testing.T) {
+		t.Parallel()
+
+		// the post data is omitted from the request event if it's too long.
+		req := newRequest(t, &network.Request{HasPostData: true})
+		s := &postDataSession{postData: strings.Repeat("a", 70*1024)}
+		req.frame = &Frame{manager: &FrameManager{session: s}}
+		assert.Equal(t, int64(0), req.Size().Body)
+		assert.Equal(t, s.postData, req.PostData())
+		assert.Equal(t, int64(70*1024), req.Size().Body)
+	})
+}
+
+func TestRequestTiming(t *testing.T) {
+	t.Parallel()
+
+	const requestWillBeSent = `{
+		"requestId": "1", "loaderId": "1", "documentURL": "https://example.com/",
+		"request": {"url": "https://example.com/", "method": "GET", "headers": {}},
+		"timestamp": 1000.5, "wallTime": 1650000000.25, "type": "Document"
+	}`
+
+	testCases := []struct {
+		name, responseReceived, loadingFinished string
+		want                                    ResourceTiming
+	}{
+		{
+			name: "network",
+			responseReceived: `{
+				"requestId": "1", "loaderId": "1", "timestamp": 1000.63, "type": "Document",
+				"response": {
+					"url": "https://example.com/", "status": 200, "statusText": "OK",
+					"headers": {}, "mimeType": "text/html", "connectionReused": false,
+					"connectionId": 1, "encodedDataLength": 100, "securityState": "secure",
+					"timing": {
+						"requestTime": 1000.501, "proxyStart": -1, "proxyEnd": -1,
+						"dnsStart": 0.2, "dnsEnd": 10.5, "connectStart": 10.5, "connectEnd": 45.1,
+						"sslStart": 20.3, "sslEnd": 45, "workerStart": -1, "workerReady": -1,
+						"workerFetchStart": -1, "workerRespondWithSettled": -1,
+						"sendStart": 45.3, "sendEnd": 45.6, "pushStart": 0, "pushEnd": 0,
+						"receiveHeadersEnd": 120.8
+					}
+				}
+			}`,
+			loadingFinished: `{"requestId": "1", "timestamp": 1000.701, "encodedDataLength": 1000}`,
+			want: ResourceTiming{
+				StartTime:             1650000000251,
+				DomainLookupStart:     0.2,
+				DomainLookupEnd:       10.5,
+				ConnectStart:          10.5,
+				SecureConnectionStart: 20.3,
+				ConnectEnd:            45.1,
+				RequestStart:          45.3,
+				ResponseStart:         120.8,
+				ResponseEnd:           200,
+			},
+		},
+		{
+			name: "cache",
+			responseReceived: `{
+				"requestId": "1", "loaderId": "1", "timestamp": 1000.502, "type": "Document",
+				"response": {
+					"url": "https://example.com/", "status": 200, "statusText": "OK",
+					"headers": {}, "mimeType": "text/html", "connectionReused": false,
+					"connectionId": 0, "encodedDataLength": 0, "securityState": "secure",
+					"fromDiskCache": true
+				}
+			}`,
+			loadingFinished: `{"requestId": "1", "timestamp": 1000.505, "encodedDataLength": 0}`,
+			want: ResourceTiming{
+				StartTime:             1650000000250,
+				DomainLookupStart:     -1,
+				DomainLookupEnd:       -1,
+				ConnectStart:          -1,
+				SecureConnectionStart: -1,
+				ConnectEnd:            -1,
+				RequestStart:          -1,
+				ResponseStart:         -1,
+				ResponseEnd:           5,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				sent     network.EventRequestWillBeSent
+				received network.EventResponseReceived
+				finished network.EventLoadingFinished
+			)
+			require.NoError(t, easyjson.Unmarshal([]byte(requestWillBeSent), &sent))
+			require.NoError(t, easyjson.Unmarshal([]byte(tc.responseReceived), &received))
+			require.NoError(t, easyjson.Unmarshal([]byte(tc.loadingFinished), &finished))
+
+			vu := k6test.NewVU(t)
+			req, err := NewRequest(vu.Context(), &sent, nil, nil, "", false)
+			require.NoError(t, err)
+			req.response = NewHTTPResponse(vu.Context(), req, received.Response, received.Timestamp)
+			req.setResponseEnd(finished.Timestamp)
+
+			got, ok := req.Timing().Export().(*ResourceTiming)
+			require.True(t, ok)
+			const delta = 0.01
+			assert.InDelta(t, tc.want.StartTime, got.StartTime, delta)
+			assert.InDelta(t, tc.want.DomainLookupStart, got.DomainLookupStart, delta)
+			assert.InDelta(t, tc.want.DomainLookupEnd, got.DomainLookupEnd, delta)
+			assert.InDelta(t, tc.want.ConnectStart, got.ConnectStart, delta)
+			assert.InDelta(t, tc.want.SecureConnectionStart, got.SecureConnectionStart, delta)
+			assert.InDelta(t, tc.want.ConnectEnd, got.ConnectEnd, delta)
+			assert.InDelta(t, tc.want.RequestStart, got.RequestStart, delta)
+			assert.InDelta(t, tc.want.ResponseStart, got.ResponseStart, delta)
+			assert.InDelta(t, tc.want.ResponseEnd, got.ResponseEnd, delta)
+		})
+	}
+}
+
+func TestRequestRedirects(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+	ts := cdp.MonotonicTime(time.Now())
+	wt := cdp.TimeSinceEpoch(time.Now())
+	newRequest := func(url string, redirectChain []*Request) *Request {
+		req, err := NewRequest(vu.Context(), &network.EventRequestWillBeSent{
+			RequestID: "1234",
+			Request:   &network.Request{URL: url, Method: "GET"},
+			Timestamp: &ts,
+			WallTime:  &wt,
+		}, nil, redirectChain, "", false)
+		require.NoError(t, err)
+		return req
+	}
+
+	first := newRequest("https://test/first", nil)
+	second := newRequest("https://test/second", []*Request{first})
+	first.setRedirectedTo(second)
+	last := newRequest("https://test/last", []*Request{first, second})
+	second.setRedirectedTo(last)
+
+	assert.Nil(t, first.RedirectedFrom())
+	assert.Same(t, second, first.RedirectedTo())
+	assert.Same(t, first, second.RedirectedFrom())
+	assert.Same(t, last, second.RedirectedTo())
+	assert.Same(t, second, last.RedirectedFrom())
+	assert.Nil(t, last.RedirectedTo())
 }
@@ -23,6 +23,7 @@ package common
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -34,6 +35,7 @@ import (
 
 	k6modules "go.k6.io/k6/js/modules"
 
+	"github.com/chromedp/cdproto"
 	"github.com/chromedp/cdproto/cdp"
 	"github.com/chromedp/cdproto/network"
 	"github.com/dop251/goja"
@@ -42,20 +44,27 @@ import (
 // Ensure Response implements the api.Response interface.
 var _ api.Response = &Response{}
 
+// errResponseBodyUnavailable is returned when the browser
+// no longer has the body of a response, for example after
+// the page of the response navigated away.
+var errResponseBodyUnavailable = errors.New("response body is no longer available")
+
 // RemoteAddress contains informationa about a remote target.
 type RemoteAddress struct {
-	IPAddress string `json:"ipAddress"`
-	Port      int64  `json:"port"`
+	IPAddress string `json:"ipAddress" js:"ipAddress"`
+	Port      int64  `json:"port" js:"port"`
 }
 
 // SecurityDetails contains informationa about the security details of a TLS connection.
+// ValidFrom and ValidTo are in seconds since the epoch.
 type SecurityDetails struct {
-	SubjectName string   `json:"subjectName"`
-	Issuer      string   `json:"issuer"`
-	ValidFrom   int64    `json:"validFrom"`
-	ValidTo     int64    `json:"validTo"`
-	Protocol    string   `json:"protocol"`
-	SANList     []string `json:"sanList"`
+	SubjectName string   `json:"subjectName" js:"subjectName"`
+	Issuer      string   `json:"issuer" js:"issuer"`
+	ValidFrom   int64    `json:"validFrom" js:"validFrom"`
+	ValidTo     int64    `json:"validTo" js:"validTo"`
+	Protocol    string   `json:"protocol" js:"protocol"`
+	Cipher      string   `json:"cipher" js:"cipher"`
+	SANList     []string `json:"sanList" js:"sanList"`
 }
 
 // Response represents a browser HTTP response.
@@ -72,6 +81,10 @@ type Response struct {
 	bodyMu            sync.RWMutex
 	body              []byte
 	headers           map[string][]string
+	hasExtraInfo      bool
+	extraInfoMu       sync.RWMutex
+	extraHeaders      []api.HTTPHeader
+	extraInfoReady    chan struct{}
 	fromDiskCache     bool
 	fromServiceWorker bool
 	fromPrefetchCache bool
@@ -101,6 +114,7 @@ func NewHTTPResponse(ctx context.Context, req *Request, resp *network.Response,
 		statusText:        resp.StatusText,
 		body:              nil,
 		headers:           make(map[string][]string),
+		extraInfoReady:    make(chan struct{}),
 		fromDiskCache:     resp.FromDiskCache,
 		fromServiceWorker: resp.FromServiceWorker,
 		fromPrefetchCache: resp.FromPrefetchCache,
@@ -125,14 +139,19 @@ func NewHTTPResponse(ctx context.Context, req *Request, resp *network.Response,
 		r.responseTime = resp.ResponseTime.Time()
 	}
 
-	if resp.SecurityDetails != nil {
+	if sd := resp.SecurityDetails; sd != nil {
 		r.securityDetails = &SecurityDetails{
-			SubjectName: resp.SecurityDetails.SubjectName,
-			Issuer:      resp.SecurityDetails.Issuer,
-			ValidFrom:   resp.SecurityDetails.ValidFrom.Time().Unix(),
-			ValidTo:     resp.SecurityDetails.ValidTo.Time().Unix(),
-			Protocol:    resp.SecurityDetails.Protocol,
-			SANList:     resp.SecurityDetails.SanList,
+			SubjectName: sd.SubjectName,
+			Issuer:      sd.Issuer,
+			Protocol:    sd.Protocol,
+			Cipher:      sd.Cipher,
+			SANList:     sd.SanList,
+		}
+		if sd.ValidFrom != nil {
+			r.securityDetails.ValidFrom = sd.ValidFrom.Time().Unix()
+		}
+		if sd.ValidTo != nil {
+			r.securityDetails.ValidTo = sd.ValidTo.Time().Unix()
 		}
 	}
 
@@ -152,6 +171,10 @@ func (r *Response) fetchBody() error {
 	action := network.GetResponseBody(r.request.requestID)
 	body, err := action.Do(cdp.WithExecutor(r.ctx, r.request.frame.manager.session))
 	if err != nil {
+		var cdpe *cdproto.Error
+		if errors.As(err, &cdpe) && cdpe.Code == -32000 {
+			return errResponseBodyUnavailable
+		}
 		return fmt.Errorf("fetching response body: %w", err)
 	}
 	r.bodyMu.Lock()
@@ -172,13 +195,56 @@ func (r *Response) headersSize() int64 {
 	return int64(size)
 }
 
-func (r *Response) AllHeaders() map[string]string {
-	// TODO: fix this data to include "ExtraInfo" header data
-	headers := make(map[string]string)
+// setExtraHeaders sets the raw headers of the response from the
+// responseReceivedExtraInfo event. The raw text of the headers
+// is preferred, since it keeps the order of the headers.
+func (r *Response) setExtraHeaders(headers network.Headers, headersText string) {
+	r.extraInfoMu.Lock()
+	defer r.extraInfoMu.Unlock()
+
+	if r.extraHeaders != nil {
+		return
+	}
+	r.extraHeaders = parseHeadersText(headersText)
+	if r.extraHeaders == nil {
+		r.extraHeaders = headersToArray(headers)
+	}
+	close(r.extraInfoReady)
+}
+
+func (r *Response) hasExtraHeaders() bool {
+	r.extraInfoMu.RLock()
+	defer r.extraInfoMu.RUnlock()
+
+	return r.extraHeaders != nil
+}
+
+// allHeadersArray returns the raw headers of the response, which include
+// the cookie and security headers, if the browser reports them. Otherwise,
+// it returns the headers of the response event.
+func (r *Response) allHeadersArray() []api.HTTPHeader {
+	if r.hasExtraInfo {
+		waitForExtraInfo(r.ctx, r.extraInfoReady)
+	}
+	r.extraInfoMu.RLock()
+	defer r.extraInfoMu.RUnlock()
+
+	if r.extraHeaders != nil {
+		return append([]api.HTTPHeader(nil), r.extraHeaders...)
+	}
+	headers := make(network.Headers, len(r.headers))
 	for n, v := range r.headers {
-		headers[strings.ToLower(n)] = strings.Join(v, ",")
+		headers[n] = strings.Join(v, "\n")
 	}
-	return headers
+	return headersToArray(headers)
+}
+
+// AllHeaders returns the response headers with lowercase names, including
+// the Set-Cookie and security headers. The values of the headers with the
+// same name are joined with commas, except for Set-Cookie which uses new
+// lines.
+func (r *Response) AllHeaders() map[string]string {
+	return headersArrayToMap(r.allHeadersArray())
 }
 
 // Body returns the response body as a binary buffer.
@@ -224,9 +290,11 @@ func (r *Response) Frame() api.Frame {
 	return r.request.frame
 }
 
+// HeaderValue returns the value of the header with the case-insensitive
+// name, or null if the response doesn't have the header. See AllHeaders.
 func (r *Response) HeaderValue(name string) goja.Value {
 	headers := r.AllHeaders()
-	val, ok := headers[name]
+	val, ok := headers[strings.ToLower(name)]
 	if !ok {
 		return goja.Null()
 	}
@@ -234,9 +302,9 @@ func (r *Response) HeaderValue(name string) goja.Value {
 	return rt.ToValue(val)
 }
 
+// HeaderValues returns the values of the headers with the case-insensitive name.
 func (r *Response) HeaderValues(name string) []string {
-	headers := r.AllHeaders()
-	return strings.Split(headers[name], ",")
+	return headerValues(r.allHeadersArray(), name)
 }
 
 // FromCache returns whether this response was served from disk cache.
@@ -263,14 +331,10 @@ func (r *Response) Headers() map[string]string {
 	return headers
 }
 
+// HeadersArray returns the response headers, including the ones with the
+// same name, and the Set-Cookie and security headers. See AllHeaders.
 func (r *Response) HeadersArray() []api.HTTPHeader {
-	headers := make([]api.HTTPHeader, 0)
-	for n, vals := range r.headers {
-		for _, v := range vals {
-			headers = append(headers, api.HTTPHeader{Name: n, Value: v})
-		}
-	}
-	return headers
+	return r.allHeadersArray()
 }
 
 // JSON returns the response body as JSON data.
@@ -305,13 +369,23 @@ func (r *Response) Request() api.Request {
 	return r.request
 }
 
+// SecurityDetails returns the TLS details of the connection of the
+// response, or null if the response wasn't received over TLS.
 func (r *Response) SecurityDetails() goja.Value {
+	if r.securityDetails == nil {
+		return goja.Null()
+	}
 	rt := r.vu.Runtime()
 	return rt.ToValue(r.securityDetails)
 }
 
-// ServerAddr returns the remote address of the server.
+// ServerAddr returns the IP address and port of the server that the
+// browser connected to, or null if the browser didn't connect to a
+// server, like for the responses from the cache.
 func (r *Response) ServerAddr() goja.Value {
+	if r.remoteAddress == nil || r.remoteAddress.IPAddress == "" {
+		return goja.Null()
+	}
 	rt := r.vu.Runtime()
 	return rt.ToValue(r.remoteAddress)
 }
@@ -343,6 +417,12 @@ func (r *Response) Text() string {
 	return string(r.body)
 }
 
+// Timing returns the timing of the phases of the request
+// of the response. See ResourceTiming.
+func (r *Response) Timing() goja.Value {
+	return r.request.Timing()
+}
+
 // URL returns the request URL.
 func (r *Response) URL() string {
 	return r.url
@@ -0,0 +1,163 @@
+package common
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/grafana/xk6-browser/k6ext/k6test"
+
+	"github.com/chromedp/cdproto"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/dop251/goja"
+	"github.com/mailru/easyjson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// responseBodySession returns the body of the responses from
+// Network.getResponseBody, or err if it's set.
+type responseBodySession struct {
+	session
+	body          string
+	base64Encoded bool
+	err           error
+	calls         int
+}
+
+func (s *responseBodySession) Execute(
+	_ context.Context, method string, _ easyjson.Marshaler, res easyjson.Unmarshaler,
+) error {
+	if method != network.CommandGetResponseBody {
+		return nil
+	}
+	s.calls++
+	if s.err != nil {
+		return s.err
+	}
+	if r, ok := res.(*network.GetResponseBodyReturns); ok {
+		r.Body = s.body
+		r.Base64encoded = s.base64Encoded
+	}
+	return nil
+}
+
+func newTestResponse(t *testing.T, s session) *Response {
+	t.Helper()
+
+	vu := k6test.NewVU(t)
+	ts := cdp.MonotonicTime(time.Now())
+	wt := cdp.TimeSinceEpoch(time.Now())
+	req, err := NewRequest(vu.Context(), &network.EventRequestWillBeSent{
+		RequestID: "1234",
+		Request:   &network.Request{URL: "https://test/api", Method: "GET"},
+		Timestamp: &ts,
+		WallTime:  &wt,
+	}, &Frame{manager: &FrameManager{session: s}}, nil, "", false)
+	require.NoError(t, err)
+
+	return NewHTTPResponse(vu.Context(), req, &network.Response{
+		URL:    "https://test/api",
+		Status: 200,
+	}, &ts)
+}
+
+func TestResponseBody(t *testing.T) {
+	t.Parallel()
+
+	t.Run("text", func(t *testing.T) {
+		t.Parallel()
+
+		s := &responseBodySession{body: "hello"}
+		resp := newTestResponse(t, s)
+		assert.Equal(t, "hello", resp.Text())
+		assert.Equal(t, []byte("hello"), resp.Body().Bytes())
+		assert.Equal(t, 1, s.calls, "the body should be fetched once")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		t.Parallel()
+
+		s := &responseBodySession{body: `{"name":"k6","tags":["a"]}`}
+		resp := newTestResponse(t, s)
+		assert.Equal(t, map[string]interface{}{
+			"name": "k6",
+			"tags": []interface{}{"a"},
+		}, resp.JSON().Export())
+	})
+
+	t.Run("binary", func(t *testing.T) {
+		t.Parallel()
+
+		png := []byte{0x89, 'P', 'N', 'G', 0x0d, 0x0a, 0x1a, 0x0a}
+		s := &responseBodySession{
+			body:          base64.StdEncoding.EncodeToString(png),
+			base64Encoded: true,
+		}
+		resp := newTestResponse(t, s)
+		assert.Equal(t, png, resp.Body().Bytes())
+	})
+
+	t.Run("evicted", func(t *testing.T) {
+		t.Parallel()
+
+		s := &responseBodySession{
+			err: &cdproto.Error{Code: -32000, Message: "No resource with given identifier found"},
+		}
+		resp := newTestResponse(t, s)
+		assert.ErrorIs(t, resp.fetchBody(), errResponseBodyUnavailable)
+	})
+}
+
+func TestResponseSecurityDetails(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+	ts := cdp.MonotonicTime(time.Now())
+	validFrom := cdp.TimeSinceEpoch(time.Unix(1600000000, 0))
+	validTo := cdp.TimeSinceEpoch(time.Unix(1700000000, 0))
+	req, err := NewRequest(vu.Context(), &network.EventRequestWillBeSent{
+		RequestID: "1234",
+		Request:   &network.Request{URL: "https://test/", Method: "GET"},
+		Timestamp: &ts,
+		WallTime:  &validFrom,
+	}, nil, nil, "", false)
+	require.NoError(t, err)
+
+	resp := NewHTTPResponse(vu.Context(), req, &network.Response{
+		URL:             "https://test/",
+		Status:          200,
+		RemoteIPAddress: "127.0.0.1",
+		RemotePort:      443,
+		SecurityDetails: &network.SecurityDetails{
+			Protocol:    "TLS 1.3",
+			Cipher:      "AES_128_GCM",
+			SubjectName: "test",
+			Issuer:      "Test CA",
+			SanList:     []string{"test", "127.0.0.1"},
+			ValidFrom:   &validFrom,
+			ValidTo:     &validTo,
+		},
+	}, &ts)
+
+	rt := vu.Runtime()
+	require.NoError(t, rt.Set("resp", resp))
+	got, err := rt.RunString(`
+		const sd = resp.securityDetails();
+		const addr = resp.serverAddr();
+		[sd.protocol, sd.cipher, sd.subjectName, sd.issuer, sd.sanList.join(','),
+			sd.validFrom, sd.validTo, addr.ipAddress, addr.port].join(' ')`)
+	require.NoError(t, err)
+	assert.Equal(t, "TLS 1.3 AES_128_GCM test Test CA test,127.0.0.1 1600000000 1700000000 127.0.0.1 443", got.String())
+
+	// plain HTTP responses and the responses from the cache.
+	resp = NewHTTPResponse(vu.Context(), req, &network.Response{
+		URL:           "http://test/",
+		Status:        200,
+		FromDiskCache: true,
+	}, &ts)
+	assert.True(t, goja.IsNull(resp.SecurityDetails()))
+	assert.True(t, goja.IsNull(resp.ServerAddr()))
+}
@@ -0,0 +1,414 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package common
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/grafana/xk6-browser/api"
+	"github.com/grafana/xk6-browser/k6ext"
+	"github.com/grafana/xk6-browser/log"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/dop251/goja"
+	k6common "go.k6.io/k6/js/common"
+)
+
+// Ensure Route implements the api.Route interface.
+var _ api.Route = &Route{}
+
+// errRouteHandled is returned when resuming a request
+// that has already been resumed.
+var errRouteHandled = errors.New("route is already handled")
+
+// routeAbortErrorCodes maps the error codes of Route.abort
+// to the network errors that fail the requests.
+var routeAbortErrorCodes = map[string]network.ErrorReason{
+	"aborted":              network.ErrorReasonAborted,
+	"accessdenied":         network.ErrorReasonAccessDenied,
+	"addressunreachable":   network.ErrorReasonAddressUnreachable,
+	"blockedbyclient":      network.ErrorReasonBlockedByClient,
+	"blockedbyresponse":    network.ErrorReasonBlockedByResponse,
+	"connectionaborted":    network.ErrorReasonConnectionAborted,
+	"connectionclosed":     network.ErrorReasonConnectionClosed,
+	"connectionfailed":     network.ErrorReasonConnectionFailed,
+	"connectionrefused":    network.ErrorReasonConnectionRefused,
+	"connectionreset":      network.ErrorReasonConnectionReset,
+	"internetdisconnected": network.ErrorReasonInternetDisconnected,
+	"namenotresolved":      network.ErrorReasonNameNotResolved,
+	"timedout":             network.ErrorReasonTimedOut,
+	"failed":               network.ErrorReasonFailed,
+}
+
+// Route is a request that a route handler intercepted. The request is
+// paused until the handler aborts, continues or fulfills it.
+type Route struct {
+	ctx       context.Context
+	session   session
+	logger    *log.Logger
+	request   *Request
+	requestID fetch.RequestID
+
+	mu      sync.Mutex
+	handled bool
+}
+
+func newRoute(ctx context.Context, s session, logger *log.Logger, req *Request, id fetch.RequestID) *Route {
+	return &Route{
+		ctx:       ctx,
+		session:   s,
+		logger:    logger,
+		request:   req,
+		requestID: id,
+	}
+}
+
+// Abort aborts the request with the error code, which is failed by default.
+func (r *Route) Abort(errorCode string) {
+	if errorCode == "" {
+		errorCode = "failed"
+	}
+	reason, ok := routeAbortErrorCodes[strings.ToLower(errorCode)]
+	if !ok {
+		k6ext.Panic(r.ctx, "aborting request: invalid error code %q", errorCode)
+	}
+	if err := r.resume(fetch.FailRequest(r.requestID, reason)); err != nil {
+		k6ext.Panic(r.ctx, "aborting request: %w", err)
+	}
+}
+
+// Continue sends the request to the network, with the overrides of the
+// URL, method, headers and post data in opts.
+func (r *Route) Continue(opts goja.Value) {
+	parsedOpts := NewRouteContinueOptions()
+	if err := parsedOpts.Parse(r.ctx, opts); err != nil {
+		k6ext.Panic(r.ctx, "parsing continue options: %w", err)
+	}
+
+	action := fetch.ContinueRequest(r.requestID)
+	if parsedOpts.URL != "" {
+		action = action.WithURL(parsedOpts.URL)
+	}
+	if parsedOpts.Method != "" {
+		action = action.WithMethod(parsedOpts.Method)
+	}
+	if parsedOpts.Headers != nil {
+		action = action.WithHeaders(toHeaderEntries(parsedOpts.Headers))
+	}
+	if parsedOpts.PostData != nil {
+		action = action.WithPostData(base64.StdEncoding.EncodeToString(parsedOpts.PostData))
+	}
+	if err := r.resume(action); err != nil {
+		k6ext.Panic(r.ctx, "continuing request: %w", err)
+	}
+}
+
+// Fulfill responds to the request with the status, headers
+// and body in opts, without sending it to the network.
+func (r *Route) Fulfill(opts goja.Value) {
+	parsedOpts := NewRouteFulfillOptions()
+	if err := parsedOpts.Parse(r.ctx, opts); err != nil {
+		k6ext.Panic(r.ctx, "parsing fulfill options: %w", err)
+	}
+
+	headers := make(map[string]string)
+	for k, v := range parsedOpts.Headers {
+		headers[strings.ToLower(k)] = v
+	}
+	if parsedOpts.ContentType != "" {
+		headers["content-type"] = parsedOpts.ContentType
+	}
+	if _, ok := headers["content-length"]; !ok {
+		headers["content-length"] = strconv.Itoa(len(parsedOpts.Body))
+	}
+	action := fetch.FulfillRequest(r.requestID, parsedOpts.Status).
+		WithResponseHeaders(toHeaderEntries(headers)).
+		WithBody(base64.StdEncoding.EncodeToString(parsedOpts.Body))
+	if phrase := http.StatusText(int(parsedOpts.Status)); phrase != "" {
+		action = action.WithResponsePhrase(phrase)
+	}
+	if err := r.resume(action); err != nil {
+		k6ext.Panic(r.ctx, "fulfilling request: %w", err)
+	}
+}
+
+// Request returns the intercepted request.
+func (r *Route) Request() api.Request {
+	return r.request
+}
+
+// resume resumes the paused request with the action,
+// unless the request has already been resumed.
+func (r *Route) resume(action Action) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.handled {
+		return errRouteHandled
+	}
+	r.handled = true
+	if err := action.Do(cdp.WithExecutor(r.ctx, r.session)); err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	return nil
+}
+
+// continueUnhandled sends the request to the network as is,
+// unless the request has already been resumed.
+func (r *Route) continueUnhandled() {
+	err := r.resume(fetch.ContinueRequest(r.requestID))
+	if err != nil && !errors.Is(err, errRouteHandled) {
+		// the page of the request may be gone.
+		r.logger.Debugf("Route:continueUnhandled", "url:%s err:%v", r.request.URL(), err)
+	}
+}
+
+func (r *Route) isHandled() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.handled
+}
+
+func toHeaderEntries(headers map[string]string) []*fetch.HeaderEntry {
+	entries := make([]*fetch.HeaderEntry, 0, len(headers))
+	for k, v := range headers {
+		entries = append(entries, &fetch.HeaderEntry{Name: k, Value: v})
+	}
+	return entries
+}
+
+// routeHandler handles the requests to the URLs that match its pattern.
+type routeHandler struct {
+	url     goja.Value
+	matches urlMatcher
+	fn      goja.Value
+	handler goja.Callable
+}
+
+func newRouteHandler(rt *goja.Runtime, url, handler goja.Value) (*routeHandler, error) {
+	matches, err := newURLMatcher(rt, url)
+	if err != nil {
+		return nil, err
+	}
+	fn, ok := goja.AssertFunction(handler)
+	if !ok {
+		return nil, errors.New("handler must be a function")
+	}
+
+	return &routeHandler{url: url, matches: matches, fn: handler, handler: fn}, nil
+}
+
+// handle calls the handler with the route, and continues the request
+// if the handler returns without aborting, continuing or fulfilling it.
+// It must be called on the event loop.
+func (h *routeHandler) handle(rt *goja.Runtime, route *Route) error {
+	defer route.continueUnhandled()
+
+	if _, err := h.handler(goja.Undefined(), rt.ToValue(route)); err != nil {
+		return fmt.Errorf("calling route handler of %s: %w", route.request.URL(), err)
+	}
+
+	return nil
+}
+
+// routeHandlers are the route handlers of a page or a browser context.
+type routeHandlers struct {
+	mu       sync.RWMutex
+	handlers []*routeHandler
+}
+
+func (r *routeHandlers) add(h *routeHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.handlers = append(r.handlers, h)
+}
+
+// match returns the handler that handles the requests to the URL. The
+// handlers that are added later take precedence. It returns nil if no
+// handler matches the URL.
+func (r *routeHandlers) match(url string) *routeHandler {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for i := len(r.handlers) - 1; i >= 0; i-- {
+		if r.handlers[i].matches(url) {
+			return r.handlers[i]
+		}
+	}
+
+	return nil
+}
+
+// remove removes the handlers of the URL pattern, or only the given
+// handler of it if handler is set, and returns the removed handlers.
+// The pattern is compared with the patterns of the handlers by its
+// kind and string value.
+func (r *routeHandlers) remove(url, handler goja.Value) []*routeHandler {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var (
+		handlers []*routeHandler
+		removed  []*routeHandler
+	)
+	for _, h := range r.handlers {
+		if sameURLPattern(h.url, url) && (!gojaValueExists(handler) || h.fn.StrictEquals(handler)) {
+			removed = append(removed, h)
+			continue
+		}
+		handlers = append(handlers, h)
+	}
+	r.handlers = handlers
+
+	return removed
+}
+
+// clear removes all the handlers and returns them.
+func (r *routeHandlers) clear() []*routeHandler {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	removed := r.handlers
+	r.handlers = nil
+
+	return removed
+}
+
+func (r *routeHandlers) len() int {
+	if r == nil {
+		return 0
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.handlers)
+}
+
+// sameURLPattern reports whether the URL patterns a and b are the
+// same string, or regular expressions with the same source and flags.
+func sameURLPattern(a, b goja.Value) bool {
+	if !gojaValueExists(a) || !gojaValueExists(b) {
+		return false
+	}
+	isRegExp := func(v goja.Value) bool {
+		obj, ok := v.(*goja.Object)
+		return ok && obj.ClassName() == "RegExp"
+	}
+
+	return isRegExp(a) == isRegExp(b) && a.String() == b.String()
+}
+
+// routeQueue runs the route handlers of the requests that are paused while
+// a synchronous call blocks the event loop. The calls that wait for
+// navigations, like page.goto, run the queued handlers while they wait,
+// otherwise they would wait for requests that only the handlers resume.
+type routeQueue struct {
+	mu    sync.Mutex
+	tasks []func() error
+	ready chan struct{}
+}
+
+func newRouteQueue() *routeQueue {
+	return &routeQueue{ready: make(chan struct{}, 1)}
+}
+
+// push queues a task to run on the event loop.
+// It doesn't wait for the task to run.
+func (q *routeQueue) push(task func() error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.tasks = append(q.tasks, task)
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// pending returns a channel that receives when there are queued tasks.
+// It returns a nil channel, which never receives, if q is nil.
+func (q *routeQueue) pending() <-chan struct{} {
+	if q == nil {
+		return nil
+	}
+	return q.ready
+}
+
+// run runs the queued tasks. It must be called on the event
+// loop, and throws the error of the first task that fails.
+func (q *routeQueue) run(rt *goja.Runtime) {
+	q.mu.Lock()
+	tasks := q.tasks
+	q.tasks = nil
+	q.mu.Unlock()
+
+	for i, task := range tasks {
+		if err := task(); err != nil {
+			// keep the tasks that didn't run.
+			q.mu.Lock()
+			q.tasks = append(tasks[i+1:], q.tasks...)
+			if len(q.tasks) > 0 {
+				select {
+				case q.ready <- struct{}{}:
+				default:
+				}
+			}
+			q.mu.Unlock()
+			k6common.Throw(rt, err)
+		}
+	}
+}
+
+// runRouting calls fn in a new goroutine, and runs the queued route handlers
+// until fn returns. It must be called on the event loop, and fn must not use
+// the JS runtime.
+func runRouting(ctx context.Context, fn func()) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+
+	routes := getRouteQueue(ctx)
+	for {
+		select {
+		case <-done:
+			return
+		case <-routes.pending():
+			routes.run(k6ext.Runtime(ctx))
+		}
+	}
+}
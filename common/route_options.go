@@ -0,0 +1,146 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/dop251/goja"
+
+	"github.com/grafana/xk6-browser/k6ext"
+)
+
+// RouteContinueOptions are the options of continuing a routed request.
+// The zero values keep the original request as is.
+type RouteContinueOptions struct {
+	URL      string            `json:"url"`
+	Method   string            `json:"method"`
+	Headers  map[string]string `json:"headers"`
+	PostData []byte            `json:"postData"`
+}
+
+// RouteFulfillOptions are the options of fulfilling a routed request.
+type RouteFulfillOptions struct {
+	Status      int64             `json:"status"`
+	Headers     map[string]string `json:"headers"`
+	ContentType string            `json:"contentType"`
+	Body        []byte            `json:"body"`
+}
+
+// NewRouteContinueOptions returns the default options of continuing a request.
+func NewRouteContinueOptions() *RouteContinueOptions {
+	return &RouteContinueOptions{}
+}
+
+// Parse parses the route.continue options.
+func (o *RouteContinueOptions) Parse(ctx context.Context, opts goja.Value) error {
+	if !gojaValueExists(opts) {
+		return nil
+	}
+	rt := k6ext.Runtime(ctx)
+	obj := opts.ToObject(rt)
+	for _, k := range obj.Keys() {
+		var err error
+		switch k {
+		case "url":
+			o.URL = obj.Get(k).String()
+		case "method":
+			o.Method = obj.Get(k).String()
+		case "headers":
+			o.Headers = parseRouteHeaders(rt, obj.Get(k))
+		case "postData":
+			if o.PostData, err = parseRouteBody(obj.Get(k)); err != nil {
+				return fmt.Errorf("parsing postData: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// NewRouteFulfillOptions returns the default options of fulfilling a request,
+// which respond with an empty 200 OK response.
+func NewRouteFulfillOptions() *RouteFulfillOptions {
+	return &RouteFulfillOptions{
+		Status: http.StatusOK,
+	}
+}
+
+// Parse parses the route.fulfill options.
+func (o *RouteFulfillOptions) Parse(ctx context.Context, opts goja.Value) error {
+	if !gojaValueExists(opts) {
+		return nil
+	}
+	rt := k6ext.Runtime(ctx)
+	obj := opts.ToObject(rt)
+	for _, k := range obj.Keys() {
+		var err error
+		switch k {
+		case "status":
+			o.Status = obj.Get(k).ToInteger()
+			if o.Status < 100 || o.Status > 999 {
+				return fmt.Errorf("invalid status code %d", o.Status)
+			}
+		case "headers":
+			o.Headers = parseRouteHeaders(rt, obj.Get(k))
+		case "contentType":
+			o.ContentType = obj.Get(k).String()
+		case "body":
+			if o.Body, err = parseRouteBody(obj.Get(k)); err != nil {
+				return fmt.Errorf("parsing body: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func parseRouteHeaders(rt *goja.Runtime, v goja.Value) map[string]string {
+	headers := make(map[string]string)
+	if !gojaValueExists(v) {
+		return headers
+	}
+	obj := v.ToObject(rt)
+	for _, k := range obj.Keys() {
+		headers[k] = obj.Get(k).String()
+	}
+	return headers
+}
+
+// parseRouteBody parses a request or response body,
+// which is either a string or an ArrayBuffer.
+func parseRouteBody(v goja.Value) ([]byte, error) {
+	if !gojaValueExists(v) {
+		return nil, nil
+	}
+	switch b := v.Export().(type) {
+	case goja.ArrayBuffer:
+		return b.Bytes(), nil
+	case []byte:
+		return b, nil
+	case string:
+		return []byte(b), nil
+	default:
+		return nil, fmt.Errorf("must be an ArrayBuffer or a string, got %T", b)
+	}
+}
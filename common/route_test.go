@@ -0,0 +1,144 @@
+package common
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/grafana/xk6-browser/k6ext/k6test"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouteOptionsParse(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+	rt := vu.Runtime()
+
+	continueOpts := NewRouteContinueOptions()
+	opts, err := rt.RunString(`({
+		url: "https://example.com/api",
+		method: "POST",
+		headers: { "X-Test": "1" },
+		postData: "a=1",
+	})`)
+	require.NoError(t, err)
+	require.NoError(t, continueOpts.Parse(vu.Context(), opts))
+	assert.Equal(t, &RouteContinueOptions{
+		URL:      "https://example.com/api",
+		Method:   "POST",
+		Headers:  map[string]string{"X-Test": "1"},
+		PostData: []byte("a=1"),
+	}, continueOpts)
+
+	fulfillOpts := NewRouteFulfillOptions()
+	assert.Equal(t, int64(200), fulfillOpts.Status)
+	opts, err = rt.RunString(`({
+		status: 404,
+		contentType: "application/json",
+		body: new Uint8Array([123, 125]).buffer,
+	})`)
+	require.NoError(t, err)
+	require.NoError(t, fulfillOpts.Parse(vu.Context(), opts))
+	assert.Equal(t, int64(404), fulfillOpts.Status)
+	assert.Equal(t, "application/json", fulfillOpts.ContentType)
+	assert.Equal(t, []byte("{}"), fulfillOpts.Body)
+
+	err = NewRouteFulfillOptions().Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{"status": 42}))
+	assert.EqualError(t, err, "invalid status code 42")
+	err = NewRouteFulfillOptions().Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{"body": 42}))
+	assert.EqualError(t, err, "parsing body: must be an ArrayBuffer or a string, got int64")
+}
+
+func TestRouteHandlers(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+	rt := vu.Runtime()
+
+	newHandler := func(url goja.Value) (*routeHandler, goja.Value) {
+		t.Helper()
+		fn, err := rt.RunString(`(() => {})`)
+		require.NoError(t, err)
+		h, err := newRouteHandler(rt, url, fn)
+		require.NoError(t, err)
+		return h, fn
+	}
+	regExp := func(src string) goja.Value {
+		t.Helper()
+		v, err := rt.RunString(src)
+		require.NoError(t, err)
+		return v
+	}
+
+	var routes routeHandlers
+	all, _ := newHandler(rt.ToValue("**"))
+	api, apiFn := newHandler(regExp(`/\/api\//`))
+	api2, _ := newHandler(regExp(`/\/api\//`))
+	routes.add(all)
+	routes.add(api)
+	routes.add(api2)
+	assert.Equal(t, 3, routes.len())
+
+	// the last added handler takes precedence.
+	assert.Same(t, api2, routes.match("https://example.com/api/users"))
+	assert.Same(t, all, routes.match("https://example.com/"))
+
+	// the string of a regular expression is a different pattern.
+	assert.Empty(t, routes.remove(rt.ToValue(`/\/api\//`), nil))
+	assert.Equal(t, []*routeHandler{api}, routes.remove(regExp(`/\/api\//`), apiFn))
+	assert.Same(t, api2, routes.match("https://example.com/api/users"))
+	assert.Equal(t, []*routeHandler{api2}, routes.remove(regExp(`/\/api\//`), nil))
+	assert.Same(t, all, routes.match("https://example.com/api/users"))
+	assert.Equal(t, 1, routes.len())
+
+	// a removed handler can be added again.
+	routes.add(api)
+	assert.Same(t, api, routes.match("https://example.com/api/users"))
+	assert.Equal(t, []*routeHandler{all}, routes.remove(rt.ToValue("**"), nil))
+	assert.Equal(t, []*routeHandler{api}, routes.remove(regExp(`/\/api\//`), nil))
+	assert.Equal(t, 0, routes.len())
+
+	routes.add(all)
+	routes.add(api)
+	assert.Equal(t, []*routeHandler{all, api}, routes.clear())
+	assert.Nil(t, routes.match("https://example.com/"))
+	assert.Equal(t, 0, routes.len())
+
+	var nilRoutes *routeHandlers
+	assert.Nil(t, nilRoutes.match("https://example.com/"))
+	assert.Equal(t, 0, nilRoutes.len())
+
+	_, err := newRouteHandler(rt, rt.ToValue("**"), rt.ToValue("not a function"))
+	assert.EqualError(t, err, "handler must be a function")
+}
+
+func TestRouteQueue(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+
+	var nilQueue *routeQueue
+	assert.Nil(t, nilQueue.pending())
+
+	var ran []int
+	q := newRouteQueue()
+	q.push(func() error { ran = append(ran, 1); return nil })
+	q.push(func() error { return errors.New("handler failed") })
+	q.push(func() error { ran = append(ran, 3); return nil })
+	<-q.pending()
+
+	assert.Panics(t, func() { q.run(vu.Runtime()) }, "should throw the error of the failed task")
+	assert.Equal(t, []int{1}, ran)
+
+	// the tasks after the failed one are kept.
+	select {
+	case <-q.pending():
+	default:
+		t.Fatal("expected pending tasks")
+	}
+	q.run(vu.Runtime())
+	assert.Equal(t, []int{1, 3}, ran)
+}
@@ -21,14 +21,18 @@
 package common
 
 import (
+	"bytes"
 	"context"
-	"errors"
 	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
 	"io/ioutil"
 	"math"
 	"os"
 	"path/filepath"
-	"strings"
+	"time"
 
 	"github.com/chromedp/cdproto/cdp"
 	"github.com/chromedp/cdproto/emulation"
@@ -36,6 +40,24 @@ import (
 	"github.com/dop251/goja"
 )
 
+const (
+	// maxScreenshotHeight is the height in device pixels above which
+	// a single capture may exceed the texture size limits of Chrome.
+	maxScreenshotHeight = 16384
+	// fullPageSliceHeight is the height in device pixels of the slices of
+	// full page screenshots that are taller than maxScreenshotHeight.
+	fullPageSliceHeight = 4096
+)
+
+const (
+	// screenshotMaskAttribute marks the overlays that mask
+	// elements in screenshots.
+	screenshotMaskAttribute = "data-xk6-browser-screenshot-mask"
+	// defaultScreenshotMaskColor is the default color of the boxes
+	// that mask elements in screenshots.
+	defaultScreenshotMaskColor = "#FF00FF"
+)
+
 type screenshotter struct {
 	ctx context.Context
 }
@@ -128,7 +150,7 @@ func (s *screenshotter) screenshot(
 	)
 	capture := cdppage.CaptureScreenshot()
 
-	shouldSetDefaultBackground := omitBackground && format == "png"
+	shouldSetDefaultBackground := omitBackground && format != ImageFormatJPEG
 	if shouldSetDefaultBackground {
 		action := emulation.SetDefaultBackgroundColorOverride().
 			WithColor(&cdp.RGBA{R: 0, G: 0, B: 0, A: 0})
@@ -138,11 +160,14 @@ func (s *screenshotter) screenshot(
 	}
 
 	// Add common options
-	capture.WithQuality(quality)
 	// nolint:exhaustive
 	switch format {
 	case ImageFormatJPEG:
 		capture.WithFormat(cdppage.CaptureScreenshotFormatJpeg)
+		capture.WithQuality(quality)
+	case ImageFormatWebP:
+		capture.WithFormat(cdppage.CaptureScreenshotFormatWebp)
+		capture.WithQuality(quality)
 	default:
 		capture.WithFormat(cdppage.CaptureScreenshotFormatPng)
 	}
@@ -195,100 +220,147 @@ func (s *screenshotter) screenshot(
 		}
 	}
 
-	// Save screenshot capture to file
-	// TODO: we should not write to disk here but put it on some queue for async disk writes
-	if path != "" {
-		dir := filepath.Dir(path)
-		if err := os.MkdirAll(dir, 0o755); err != nil {
-			return nil, fmt.Errorf("creating screenshot directory %q: %w", dir, err)
-		}
-		if err := ioutil.WriteFile(path, buf, 0o644); err != nil {
-			return nil, fmt.Errorf("saving screenshot to %q: %w", path, err)
-		}
+	if err := saveScreenshot(path, buf); err != nil {
+		return nil, err
 	}
 
 	return &buf, nil
 }
 
-func (s *screenshotter) screenshotElement(h *ElementHandle, opts *ElementHandleScreenshotOptions) (*[]byte, error) {
+// saveScreenshot saves the screenshot capture to a file at path.
+// It doesn't save the capture if path is empty.
+func saveScreenshot(path string, buf []byte) error {
+	// TODO: we should not write to disk here but put it on some queue for async disk writes
+	if path == "" {
+		return nil
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating screenshot directory %q: %w", dir, err)
+	}
+	if err := ioutil.WriteFile(path, buf, 0o644); err != nil {
+		return fmt.Errorf("saving screenshot to %q: %w", path, err)
+	}
+	return nil
+}
+
+func (s *screenshotter) screenshotElement(h *ElementHandle, opts *ElementHandleScreenshotOptions) (_ *[]byte, err error) {
 	format := opts.Format
-	viewportSize, originalViewportSize, err := s.originalViewportSize(h.frame.page)
-	if err != nil {
-		return nil, fmt.Errorf("getting original viewport size: %w", err)
+	p := h.frame.page
+
+	if len(opts.Mask) > 0 {
+		unmask, err := s.mask(p, opts.Mask, opts.MaskColor)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			if uerr := unmask(); uerr != nil && err == nil {
+				err = uerr
+			}
+		}()
 	}
 
-	err = h.waitAndScrollIntoViewIfNeeded(h.ctx, false, true, opts.Timeout)
+	viewportSize, originalViewportSize, err := s.originalViewportSize(p)
 	if err != nil {
-		return nil, fmt.Errorf("scrolling element into view: %w", err)
+		return nil, fmt.Errorf("getting original viewport size: %w", err)
 	}
 
-	bbox, err := h.boundingBox()
+	bbox, err := s.elementBoundingBox(h, opts.Timeout)
 	if err != nil {
-		return nil, fmt.Errorf("node is either not visible or not an HTMLElement: %w", err)
-	}
-	if bbox.Width <= 0 {
-		return nil, fmt.Errorf("node has 0 width")
-	}
-	if bbox.Height <= 0 {
-		return nil, fmt.Errorf("node has 0 height")
+		return nil, err
 	}
 
-	var overriddenViewportSize *Size
 	fitsViewport := bbox.Width <= viewportSize.Width && bbox.Height <= viewportSize.Height
 	if !fitsViewport {
-		overriddenViewportSize = Size{
+		overriddenViewportSize := Size{
 			Width:  math.Max(viewportSize.Width, bbox.Width),
 			Height: math.Max(viewportSize.Height, bbox.Height),
 		}.enclosingIntSize()
-		if err := h.frame.page.setViewportSize(overriddenViewportSize); err != nil {
+		if err := p.setViewportSize(overriddenViewportSize); err != nil {
 			return nil, fmt.Errorf("setting viewport size to %s: %w",
 				overriddenViewportSize, err)
 		}
-		err = h.waitAndScrollIntoViewIfNeeded(h.ctx, false, true, opts.Timeout)
-		if err != nil {
-			return nil, fmt.Errorf("scrolling element into view: %w", err)
-		}
-		bbox, err = h.boundingBox()
-		if err != nil {
-			return nil, fmt.Errorf("node is either not visible or not an HTMLElement: %w", err)
-		}
-		if bbox.Width <= 0 {
-			return nil, fmt.Errorf("node has 0 width")
-		}
-		if bbox.Height <= 0 {
-			return nil, fmt.Errorf("node has 0 height")
+		defer func() {
+			if rerr := s.restoreViewport(p, originalViewportSize); rerr != nil && err == nil {
+				err = fmt.Errorf("restoring viewport: %w", rerr)
+			}
+		}()
+		if bbox, err = s.elementBoundingBox(h, opts.Timeout); err != nil {
+			return nil, err
 		}
 	}
 
-	documentRect := bbox
-	rt := h.execCtx.vu.Runtime()
-	scrollOffset := h.Evaluate(rt.ToValue(`() => { return {x: window.scrollX, y: window.scrollY};}`))
-	switch s := scrollOffset.(type) {
-	case goja.Value:
-		documentRect.X += s.ToObject(rt).Get("x").ToFloat()
-		documentRect.Y += s.ToObject(rt).Get("y").ToFloat()
+	// the bounding box is relative to the viewport of the main frame,
+	// while the screenshot clip is relative to its document.
+	scroll, err := s.evaluate(p, `() => ({ x: window.scrollX, y: window.scrollY })`)
+	if err != nil {
+		return nil, fmt.Errorf("getting scroll position: %w", err)
 	}
+	rt := p.vu.Runtime()
+	documentRect := bbox
+	documentRect.X += scroll.ToObject(rt).Get("x").ToFloat()
+	documentRect.Y += scroll.ToObject(rt).Get("y").ToFloat()
 
-	buf, err := s.screenshot(h.frame.page.session, documentRect.enclosingIntRect(), nil, format, opts.OmitBackground, opts.Quality, opts.Path)
+	return s.screenshot(p.session, documentRect.enclosingIntRect(), nil, format, opts.OmitBackground, opts.Quality, opts.Path)
+}
+
+// elementBoundingBox scrolls the element into view, once it is visible and
+// stable, and returns its bounding box relative to the viewport.
+func (s *screenshotter) elementBoundingBox(h *ElementHandle, timeout time.Duration) (*Rect, error) {
+	if err := h.waitAndScrollIntoViewIfNeeded(h.ctx, false, true, timeout); err != nil {
+		return nil, fmt.Errorf("scrolling element into view: %w", s.elementNotCapturableError(h, err))
+	}
+	bbox, err := h.boundingBox()
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("element is either not visible or not an HTMLElement: %w", err)
 	}
-	if overriddenViewportSize != nil {
-		if err := s.restoreViewport(h.frame.page, originalViewportSize); err != nil {
-			return nil, fmt.Errorf("restoring viewport: %w", err)
-		}
+	if bbox.Width <= 0 || bbox.Height <= 0 {
+		return nil, fmt.Errorf("element has zero size: %gx%g", bbox.Width, bbox.Height)
 	}
-	return buf, nil
+
+	return bbox, nil
 }
 
-func (s *screenshotter) screenshotPage(p *Page, opts *PageScreenshotOptions) (*[]byte, error) {
+// elementNotCapturableError explains why the element can't be captured,
+// when it doesn't become visible, if it is detached or has zero size.
+// Otherwise, it returns err as is.
+func (s *screenshotter) elementNotCapturableError(h *ElementHandle, err error) error {
+	opts := evalOptions{
+		forceCallable: true,
+		returnByValue: true,
+	}
+	result, eerr := h.eval(h.ctx, opts, `(element) => {
+		const { width, height } = element.getBoundingClientRect();
+		return { connected: element.isConnected, width, height };
+	}`)
+	v, ok := result.(goja.Value)
+	if eerr != nil || !ok {
+		return errorFromDOMErr(err)
+	}
+	o := v.ToObject(h.execCtx.vu.Runtime())
+	if !o.Get("connected").ToBoolean() {
+		return errorFromDOMError("error:notconnected")
+	}
+	if width, height := o.Get("width").ToFloat(), o.Get("height").ToFloat(); width <= 0 || height <= 0 {
+		return fmt.Errorf("element has zero size: %gx%g", width, height)
+	}
+
+	return errorFromDOMErr(err)
+}
+
+func (s *screenshotter) screenshotPage(p *Page, opts *PageScreenshotOptions) (_ *[]byte, err error) {
 	format := opts.Format
 
-	// Infer file format by path
-	if opts.Path != "" && opts.Format != "png" && opts.Format != "jpeg" {
-		if strings.HasSuffix(opts.Path, ".jpg") || strings.HasSuffix(opts.Path, ".jpeg") {
-			format = "jpeg"
+	if len(opts.Mask) > 0 {
+		unmask, err := s.mask(p, opts.Mask, opts.MaskColor)
+		if err != nil {
+			return nil, err
 		}
+		defer func() {
+			if uerr := unmask(); uerr != nil && err == nil {
+				err = uerr
+			}
+		}()
 	}
 
 	viewportSize, originalViewportSize, err := s.originalViewportSize(p)
@@ -307,37 +379,50 @@ func (s *screenshotter) screenshotPage(p *Page, opts *PageScreenshotOptions) (*[
 			Width:  fullPageSize.Width,
 			Height: fullPageSize.Height,
 		}
-		var overriddenViewportSize *Size
-		fitsViewport := fullPageSize.Width <= viewportSize.Width && fullPageSize.Height <= viewportSize.Height
-		if !fitsViewport {
-			overriddenViewportSize = fullPageSize
-			if err := p.setViewportSize(overriddenViewportSize); err != nil {
-				return nil, fmt.Errorf("setting viewport size to %s: %w",
-					overriddenViewportSize, err)
+		if opts.Clip == nil {
+			dpr, err := s.devicePixelRatio(p)
+			if err != nil {
+				return nil, fmt.Errorf("getting device pixel ratio: %w", err)
+			}
+			if fullPageSize.Height*dpr > maxScreenshotHeight {
+				if format == ImageFormatWebP {
+					return nil, fmt.Errorf("webp full page screenshots taller than %d device pixels are not supported", maxScreenshotHeight)
+				}
+				return s.screenshotFullPageSlices(p, fullPageSize, originalViewportSize, dpr, format, opts)
 			}
 		}
 		if opts.Clip != nil {
+			// the clip is relative to the full page when capturing it.
 			documentRect, err = s.trimClipToSize(&Rect{
 				X:      opts.Clip.X,
 				Y:      opts.Clip.Y,
 				Width:  opts.Clip.Width,
 				Height: opts.Clip.Height,
-			}, &Size{Width: documentRect.Width, Height: documentRect.Height})
+			}, fullPageSize, "page")
 			if err != nil {
 				return nil, fmt.Errorf("trimming clip to size: %w", err)
 			}
 		}
+		var overriddenViewportSize *Size
+		fitsViewport := fullPageSize.Width <= viewportSize.Width && fullPageSize.Height <= viewportSize.Height
+		if !fitsViewport {
+			overriddenViewportSize = fullPageSize
+			if err := p.setViewportSize(overriddenViewportSize); err != nil {
+				return nil, fmt.Errorf("setting viewport size to %s: %w",
+					overriddenViewportSize, err)
+			}
+		}
 
 		buf, err := s.screenshot(p.session, documentRect, nil, format, opts.OmitBackground, opts.Quality, opts.Path)
-		if err != nil {
-			return nil, err
-		}
 		if overriddenViewportSize != nil {
-			if err := s.restoreViewport(p, originalViewportSize); err != nil {
-				return nil, fmt.Errorf("restoring viewport to %s: %w",
-					originalViewportSize, err)
+			if rerr := s.restoreViewport(p, originalViewportSize); rerr != nil && err == nil {
+				err = fmt.Errorf("restoring viewport to %s: %w",
+					originalViewportSize, rerr)
 			}
 		}
+		if err != nil {
+			return nil, err
+		}
 		return buf, nil
 	}
 
@@ -353,7 +438,7 @@ func (s *screenshotter) screenshotPage(p *Page, opts *PageScreenshotOptions) (*[
 			Y:      opts.Clip.Y,
 			Width:  opts.Clip.Width,
 			Height: opts.Clip.Height,
-		}, viewportSize)
+		}, viewportSize, "viewport")
 		if err != nil {
 			return nil, fmt.Errorf("trimming clip to size: %w", err)
 		}
@@ -361,7 +446,274 @@ func (s *screenshotter) screenshotPage(p *Page, opts *PageScreenshotOptions) (*[
 	return s.screenshot(p.session, nil, viewportRect, format, opts.OmitBackground, opts.Quality, opts.Path)
 }
 
-func (s *screenshotter) trimClipToSize(clip *Rect, size *Size) (*Rect, error) {
+// screenshotFullPageSlices captures a full page screenshot of a page that is
+// too tall to capture at once. It resizes the viewport to the height of a
+// slice, scrolls the page to capture it slice by slice, and stitches the
+// slices into a single image.
+//
+// Fixed and sticky elements would repeat in every slice, so with the
+// hideFixedElements option they are only captured in the first one.
+func (s *screenshotter) screenshotFullPageSlices(
+	p *Page, fullPageSize, originalViewportSize *Size, dpr float64, format ImageFormat, opts *PageScreenshotOptions,
+) (_ *[]byte, err error) {
+	sliceHeight := math.Floor(fullPageSliceHeight / dpr)
+	if err := p.setViewportSize(&Size{Width: fullPageSize.Width, Height: sliceHeight}); err != nil {
+		return nil, fmt.Errorf("setting viewport size to slice size: %w", err)
+	}
+	defer func() {
+		if rerr := s.restoreViewport(p, originalViewportSize); rerr != nil && err == nil {
+			err = fmt.Errorf("restoring viewport to %s: %w", originalViewportSize, rerr)
+		}
+	}()
+
+	scroll, err := s.evaluate(p, `() => ({ x: window.scrollX, y: window.scrollY })`)
+	if err != nil {
+		return nil, fmt.Errorf("getting scroll position: %w", err)
+	}
+	defer func() {
+		rt := p.vu.Runtime()
+		o := scroll.ToObject(rt)
+		if _, serr := s.evaluate(p, `(x, y) => window.scrollTo(x, y)`, o.Get("x"), o.Get("y")); serr != nil && err == nil {
+			err = fmt.Errorf("restoring scroll position: %w", serr)
+		}
+	}()
+
+	var slices [][]byte
+	for y := 0.0; y < fullPageSize.Height; y += sliceHeight {
+		if opts.HideFixedElements && len(slices) == 1 {
+			if err := s.hideFixedElements(p); err != nil {
+				return nil, err
+			}
+			defer func() {
+				if rerr := s.restoreFixedElements(p); rerr != nil && err == nil {
+					err = rerr
+				}
+			}()
+		}
+		if _, err := s.evaluate(p, `y => new Promise(resolve => {
+			window.scrollTo(0, y);
+			requestAnimationFrame(() => requestAnimationFrame(resolve));
+		})`, p.vu.Runtime().ToValue(y)); err != nil {
+			return nil, fmt.Errorf("scrolling to slice at %.0fpx: %w", y, err)
+		}
+		slice := &Rect{
+			X:      0,
+			Y:      y,
+			Width:  fullPageSize.Width,
+			Height: math.Min(sliceHeight, fullPageSize.Height-y),
+		}
+		buf, err := s.screenshot(p.session, slice, nil, format, opts.OmitBackground, opts.Quality, "")
+		if err != nil {
+			return nil, fmt.Errorf("capturing slice at %.0fpx: %w", y, err)
+		}
+		slices = append(slices, *buf)
+	}
+
+	buf, err := stitchScreenshots(slices, format, opts.Quality)
+	if err != nil {
+		return nil, fmt.Errorf("stitching screenshot slices: %w", err)
+	}
+	if err := saveScreenshot(opts.Path, buf); err != nil {
+		return nil, err
+	}
+
+	return &buf, nil
+}
+
+// mask overlays the elements that match the selectors with opaque boxes of
+// the given color, and returns a function that removes the overlays.
+// The selectors match elements in all the frames of the page, and each
+// frame gets its own overlays, so that they follow the frame's layout.
+func (s *screenshotter) mask(p *Page, selectors []string, color string) (func() error, error) {
+	var masked []*ElementHandle
+	unmask := func() error {
+		var err error
+		for _, document := range masked {
+			_, uerr := document.eval(s.ctx, evalOptions{forceCallable: true, returnByValue: true}, `
+				(document, attr) => {
+					for (const el of document.querySelectorAll('[' + attr + ']')) {
+						el.remove();
+					}
+				}`, screenshotMaskAttribute)
+			if uerr != nil && err == nil {
+				err = fmt.Errorf("removing screenshot mask: %w", uerr)
+			}
+		}
+		return err
+	}
+
+	for _, frame := range p.frameManager.Frames() {
+		f, ok := frame.(*Frame)
+		if !ok || f.IsDetached() {
+			continue
+		}
+		document, err := f.document()
+		if err != nil {
+			_ = unmask()
+			return nil, fmt.Errorf("getting document of frame %q: %w", f.URL(), err)
+		}
+		args := []interface{}{screenshotMaskAttribute, color}
+		for _, selector := range selectors {
+			handles, err := document.queryAll(selector, document.evalWithScript)
+			if err != nil {
+				_ = unmask()
+				return nil, fmt.Errorf("masking %q: %w", selector, err)
+			}
+			for _, h := range handles {
+				args = append(args, h)
+			}
+		}
+		if len(args) == 2 {
+			continue
+		}
+		masked = append(masked, document)
+		_, err = document.eval(s.ctx, evalOptions{forceCallable: true, returnByValue: true}, `
+			(document, attr, color, ...elements) => {
+				const root = document.createElement('div');
+				root.setAttribute(attr, '');
+				root.style.cssText = 'position: absolute; top: 0; left: 0; width: 0; height: 0; ' +
+					'z-index: 2147483647; pointer-events: none';
+				for (const el of elements) {
+					const rect = el.getBoundingClientRect();
+					if (rect.width === 0 || rect.height === 0) {
+						continue;
+					}
+					const box = document.createElement('div');
+					box.style.position = 'absolute';
+					box.style.left = (rect.left + window.scrollX) + 'px';
+					box.style.top = (rect.top + window.scrollY) + 'px';
+					box.style.width = rect.width + 'px';
+					box.style.height = rect.height + 'px';
+					box.style.background = color;
+					root.appendChild(box);
+				}
+				document.documentElement.appendChild(root);
+			}`, args...)
+		for _, h := range args[2:] {
+			_ = h.(*ElementHandle).dispose() //nolint:forcetypeassert
+		}
+		if err != nil {
+			_ = unmask()
+			return nil, fmt.Errorf("masking elements in frame %q: %w", f.URL(), err)
+		}
+	}
+
+	return unmask, nil
+}
+
+// devicePixelRatio returns the ratio of device pixels to CSS pixels of the page.
+func (s *screenshotter) devicePixelRatio(p *Page) (float64, error) {
+	v, err := s.evaluate(p, `() => window.devicePixelRatio`)
+	if err != nil {
+		return 0, err
+	}
+	if dpr := v.ToFloat(); dpr > 0 {
+		return dpr, nil
+	}
+	return 1, nil
+}
+
+// hideFixedElements hides the fixed and sticky elements of the page
+// until restoreFixedElements restores them.
+func (s *screenshotter) hideFixedElements(p *Page) error {
+	_, err := s.evaluate(p, `() => {
+		const hidden = [];
+		for (const el of document.querySelectorAll('*')) {
+			const { position } = getComputedStyle(el);
+			if (position !== 'fixed' && position !== 'sticky') {
+				continue;
+			}
+			hidden.push({
+				el,
+				value: el.style.getPropertyValue('visibility'),
+				priority: el.style.getPropertyPriority('visibility'),
+			});
+			el.style.setProperty('visibility', 'hidden', 'important');
+		}
+		window.__xk6BrowserHiddenFixedElements = hidden;
+	}`)
+	if err != nil {
+		return fmt.Errorf("hiding fixed elements: %w", err)
+	}
+	return nil
+}
+
+func (s *screenshotter) restoreFixedElements(p *Page) error {
+	_, err := s.evaluate(p, `() => {
+		for (const { el, value, priority } of window.__xk6BrowserHiddenFixedElements || []) {
+			el.style.setProperty('visibility', value, priority);
+		}
+		delete window.__xk6BrowserHiddenFixedElements;
+	}`)
+	if err != nil {
+		return fmt.Errorf("restoring fixed elements: %w", err)
+	}
+	return nil
+}
+
+// evaluate evaluates js with args in the main frame of the page.
+func (s *screenshotter) evaluate(p *Page, js string, args ...goja.Value) (goja.Value, error) {
+	opts := evalOptions{
+		forceCallable: true,
+		returnByValue: true,
+	}
+	rt := p.vu.Runtime()
+	result, err := p.frameManager.MainFrame().evaluate(s.ctx, mainWorld, opts, rt.ToValue(js), args...)
+	if err != nil {
+		return nil, err
+	}
+	if v, ok := result.(goja.Value); ok {
+		return v, nil
+	}
+	return goja.Undefined(), nil
+}
+
+// stitchScreenshots stitches the screenshot slices on top of each other
+// into a single image in the given format.
+func stitchScreenshots(slices [][]byte, format ImageFormat, quality int64) ([]byte, error) {
+	var (
+		imgs          = make([]image.Image, 0, len(slices))
+		width, height int
+	)
+	for i, slice := range slices {
+		img, _, err := image.Decode(bytes.NewReader(slice))
+		if err != nil {
+			return nil, fmt.Errorf("decoding slice %d: %w", i, err)
+		}
+		imgs = append(imgs, img)
+		if w := img.Bounds().Dx(); w > width {
+			width = w
+		}
+		height += img.Bounds().Dy()
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	y := 0
+	for _, img := range imgs {
+		b := img.Bounds()
+		draw.Draw(dst, image.Rect(0, y, b.Dx(), y+b.Dy()), img, b.Min, draw.Src)
+		y += b.Dy()
+	}
+
+	var buf bytes.Buffer
+	var err error
+	//nolint:exhaustive
+	switch format {
+	case ImageFormatJPEG:
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: int(quality)})
+	default:
+		err = png.Encode(&buf, dst)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("encoding stitched screenshot: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// trimClipToSize trims the clip to the size of the area it is relative to,
+// the viewport or the full page. It returns an error if the clip is
+// entirely outside of the area.
+func (s *screenshotter) trimClipToSize(clip *Rect, size *Size, area string) (*Rect, error) {
 	p1 := Position{
 		X: math.Max(0, math.Min(clip.X, size.Width)),
 		Y: math.Max(0, math.Min(clip.Y, size.Height)),
@@ -377,7 +729,10 @@ func (s *screenshotter) trimClipToSize(clip *Rect, size *Size) (*Rect, error) {
 		Height: p2.Y - p1.Y,
 	}
 	if result.Width == 0 || result.Height == 0 {
-		return nil, errors.New("clip area is either empty or outside the viewport")
+		return nil, fmt.Errorf(
+			"clip at (%g, %g) with size %gx%g is outside of the %s of size %gx%g",
+			clip.X, clip.Y, clip.Width, clip.Height, area, size.Width, size.Height,
+		)
 	}
 	return &result, nil
 }
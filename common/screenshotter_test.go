@@ -0,0 +1,63 @@
+package common
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStitchScreenshots(t *testing.T) {
+	t.Parallel()
+
+	newSlice := func(t *testing.T, height int, c color.NRGBA) []byte {
+		t.Helper()
+
+		img := image.NewNRGBA(image.Rect(0, 0, 4, height))
+		for y := 0; y < height; y++ {
+			for x := 0; x < 4; x++ {
+				img.SetNRGBA(x, y, c)
+			}
+		}
+		var buf bytes.Buffer
+		require.NoError(t, png.Encode(&buf, img))
+		return buf.Bytes()
+	}
+	red := color.NRGBA{R: 255, A: 255}
+	blue := color.NRGBA{B: 255, A: 255}
+
+	buf, err := stitchScreenshots([][]byte{
+		newSlice(t, 3, red),
+		newSlice(t, 2, blue),
+	}, ImageFormatPNG, 100)
+	require.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(buf))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 4, 5), img.Bounds())
+	for y, want := range []color.NRGBA{red, red, red, blue, blue} {
+		got := color.NRGBAModel.Convert(img.At(0, y))
+		assert.Equal(t, want, got, "row %d", y)
+	}
+
+	_, err = stitchScreenshots([][]byte{[]byte("not an image")}, ImageFormatPNG, 100)
+	assert.ErrorContains(t, err, "decoding slice 0")
+}
+
+func TestScreenshotterTrimClipToSize(t *testing.T) {
+	t.Parallel()
+
+	s := &screenshotter{}
+	size := &Size{Width: 800, Height: 600}
+
+	clip, err := s.trimClipToSize(&Rect{X: -10, Y: 500, Width: 100, Height: 200}, size, "viewport")
+	require.NoError(t, err)
+	assert.Equal(t, &Rect{X: 0, Y: 500, Width: 90, Height: 100}, clip)
+
+	_, err = s.trimClipToSize(&Rect{X: 900, Y: 0, Width: 100, Height: 100}, size, "page")
+	assert.EqualError(t, err, "clip at (900, 0) with size 100x100 is outside of the page of size 800x600")
+}
@@ -0,0 +1,123 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package common
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/dop251/goja"
+)
+
+// builtinSelectorEngines are the names of the selector engines
+// that the injected script provides.
+var builtinSelectorEngines = map[string]bool{
+	"css":               true,
+	"css:light":         true,
+	"data-testid":       true,
+	"data-testid:light": true,
+	"nth":               true,
+	"testid":            true,
+	"testid:light":      true,
+	"text":              true,
+	"text:light":        true,
+	"visible":           true,
+	"xpath":             true,
+}
+
+// Matches the names of custom selector engines.
+var reSelectorEngineName = regexp.MustCompile(`^[a-zA-Z_0-9-]+$`)
+
+// selectorEngine is a custom selector engine registered by the user.
+type selectorEngine struct {
+	name string
+	// source is a JS expression that evaluates to the engine object.
+	source string
+}
+
+// selectorEngines is the registry of the custom selector engines of a browser.
+// Engines can only be added to it, so that execution contexts can keep track
+// of the engines they have already registered by their count.
+type selectorEngines struct {
+	mu      sync.RWMutex
+	engines []selectorEngine
+}
+
+// register adds a custom selector engine with the given name and source.
+func (r *selectorEngines) register(name, source string) error {
+	if !reSelectorEngineName.MatchString(name) {
+		return errors.New("name must only contain letters, digits, hyphens and underscores")
+	}
+	if builtinSelectorEngines[name] {
+		return errors.New("cannot override a built-in selector engine")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, e := range r.engines {
+		if e.name == name {
+			return errors.New("already registered")
+		}
+	}
+	r.engines = append(r.engines, selectorEngine{name: name, source: source})
+
+	return nil
+}
+
+// from returns the engines registered after the first n ones.
+func (r *selectorEngines) from(n int) []selectorEngine {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if n >= len(r.engines) {
+		return nil
+	}
+	engines := make([]selectorEngine, len(r.engines)-n)
+	copy(engines, r.engines[n:])
+
+	return engines
+}
+
+// selectorEngineSource returns the JS expression that evaluates to the
+// selector engine object for the given JS value that can be:
+//   - a function that returns the engine object when called.
+//   - a string with a JS expression that evaluates to the engine object.
+func selectorEngineSource(source goja.Value) (string, error) {
+	if !gojaValueExists(source) {
+		return "", errors.New("missing selector engine source")
+	}
+	if _, ok := goja.AssertFunction(source); ok {
+		return "(" + source.String() + ")()", nil
+	}
+	s, ok := source.Export().(string)
+	if !ok {
+		return "", fmt.Errorf("selector engine source must be a function or a string, got %T", source.Export())
+	}
+	if strings.TrimSpace(s) == "" {
+		return "", errors.New("missing selector engine source")
+	}
+
+	return s, nil
+}
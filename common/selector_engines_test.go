@@ -0,0 +1,50 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/grafana/xk6-browser/k6ext/k6test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectorEnginesRegister(t *testing.T) {
+	t.Parallel()
+
+	var r selectorEngines
+	require.NoError(t, r.register("comp", "{}"))
+	require.NoError(t, r.register("data_qa-2", "{}"))
+
+	assert.EqualError(t, r.register("comp", "{}"), "already registered")
+	assert.EqualError(t, r.register("css", "{}"), "cannot override a built-in selector engine")
+	assert.EqualError(t, r.register("my engine", "{}"),
+		"name must only contain letters, digits, hyphens and underscores")
+
+	assert.Len(t, r.from(0), 2)
+	assert.Equal(t, []selectorEngine{{name: "data_qa-2", source: "{}"}}, r.from(1))
+	assert.Empty(t, r.from(2))
+}
+
+func TestSelectorEngineSource(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+	rt := vu.Runtime()
+
+	fn, err := rt.RunString(`(() => ({ queryAll: (root) => [root] }))`)
+	require.NoError(t, err)
+	src, err := selectorEngineSource(fn)
+	require.NoError(t, err)
+	assert.Equal(t, `(() => ({ queryAll: (root) => [root] })()`, src)
+
+	src, err = selectorEngineSource(rt.ToValue(`({ query: (root) => root })`))
+	require.NoError(t, err)
+	assert.Equal(t, `({ query: (root) => root })`, src)
+
+	_, err = selectorEngineSource(rt.ToValue(" "))
+	assert.EqualError(t, err, "missing selector engine source")
+
+	_, err = selectorEngineSource(rt.ToValue(42))
+	assert.EqualError(t, err, "selector engine source must be a function or a string, got int64")
+}
@@ -40,6 +40,8 @@ import (
 	"errors"
 	"regexp"
 	"strings"
+
+	"github.com/grafana/xk6-browser/api"
 )
 
 // Matches `name:body`, a query engine name and selector for that engine.
@@ -69,8 +71,10 @@ func NewSelector(selector string) (*Selector, error) {
 		Parts:    make([]*SelectorPart, 0, 1),
 		Capture:  nil,
 	}
-	err := s.parse()
-	return &s, err
+	if err := s.parse(); err != nil {
+		return &s, &api.SelectorResolutionError{Selector: selector, Err: err}
+	}
+	return &s, nil
 }
 
 func (s *Selector) appendPart(p *SelectorPart, capture bool) error {
@@ -48,7 +48,7 @@ type Session struct {
 	readCh   chan *cdproto.Message
 	done     chan struct{}
 	closed   bool
-	crashed  bool
+	crashed  chan struct{}
 
 	logger *log.Logger
 }
@@ -64,6 +64,7 @@ func NewSession(
 		targetID:         tid,
 		readCh:           make(chan *cdproto.Message),
 		done:             make(chan struct{}),
+		crashed:          make(chan struct{}),
 
 		logger: logger,
 	}
@@ -96,9 +97,35 @@ func (s *Session) close() {
 	s.emit(EventSessionClosed, nil)
 }
 
+// markAsCrashed fails the messages that are being sent, and the messages
+// that will be sent, with ErrPageCrashed.
 func (s *Session) markAsCrashed() {
 	s.logger.Debugf("Session:markAsCrashed", "sid:%v tid:%v", s.id, s.targetID)
-	s.crashed = true
+	select {
+	case <-s.crashed:
+	default:
+		close(s.crashed)
+	}
+}
+
+func (s *Session) isCrashed() bool {
+	select {
+	case <-s.crashed:
+		return true
+	default:
+		return false
+	}
+}
+
+// isClosed reports whether the session is closed, such as when its page
+// is closed.
+func (s *Session) isClosed() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
 }
 
 // Wraps conn.ReadMessage in a channel.
@@ -137,9 +164,13 @@ func (s *Session) Execute(ctx context.Context, method string, params easyjson.Ma
 	if method == target.CommandCloseTarget {
 		return errors.New("to close the target, cancel its context")
 	}
-	if s.crashed {
+	if s.isCrashed() {
 		s.logger.Debugf("Session:Execute:return", "sid:%v tid:%v method:%q crashed", s.id, s.targetID, method)
-		return ErrTargetCrashed
+		return ErrPageCrashed
+	}
+	if s.isClosed() {
+		s.logger.Debugf("Session:Execute:return", "sid:%v tid:%v method:%q closed", s.id, s.targetID, method)
+		return ErrTargetClosed
 	}
 
 	id := atomic.AddInt64(&s.msgID, 1)
@@ -188,7 +219,20 @@ func (s *Session) Execute(ctx context.Context, method string, params easyjson.Ma
 		Method:    cdproto.MethodType(method),
 		Params:    buf,
 	}
-	return s.conn.send(contextWithDoneChan(ctx, s.done), msg, ch, res)
+	// the message fails as soon as the session closes or the page crashes.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ctx = contextWithDoneChan(contextWithDoneChan(ctx, s.done), s.crashed)
+	if err := s.conn.send(ctx, msg, ch, res); err != nil {
+		if s.isCrashed() {
+			return ErrPageCrashed
+		}
+		if s.isClosed() {
+			return ErrTargetClosed
+		}
+		return err
+	}
+	return nil
 }
 
 func (s *Session) ExecuteWithoutExpectationOnReply(ctx context.Context, method string, params easyjson.Marshaler, res easyjson.Unmarshaler) error {
@@ -197,9 +241,9 @@ func (s *Session) ExecuteWithoutExpectationOnReply(ctx context.Context, method s
 	if method == target.CommandCloseTarget {
 		return errors.New("to close the target, cancel its context")
 	}
-	if s.crashed {
-		s.logger.Debugf("Session:ExecuteWithoutExpectationOnReply", "sid:%v tid:%v method:%q, ErrTargetCrashed", s.id, s.targetID, method)
-		return ErrTargetCrashed
+	if s.isCrashed() {
+		s.logger.Debugf("Session:ExecuteWithoutExpectationOnReply", "sid:%v tid:%v method:%q, ErrPageCrashed", s.id, s.targetID, method)
+		return ErrPageCrashed
 	}
 
 	s.logger.Debugf("Session:Execute:s.conn.send", "sid:%v tid:%v method:%q", s.id, s.targetID, method)
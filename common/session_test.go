@@ -25,6 +25,7 @@ import (
 	"fmt"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/grafana/xk6-browser/log"
 	"github.com/grafana/xk6-browser/tests/ws"
@@ -128,3 +129,61 @@ func TestSessionCreateSession(t *testing.T) {
 		}
 	})
 }
+
+func TestSessionCrashed(t *testing.T) {
+	handler := func(conn *websocket.Conn, msg *cdproto.Message, writeCh chan cdproto.Message, done chan struct{}) {
+		// the crashed target doesn't reply to the session commands.
+		if msg.SessionID != "" || msg.Method != cdproto.MethodType(cdproto.CommandTargetAttachToTarget) {
+			return
+		}
+		writeCh <- cdproto.Message{
+			Method: cdproto.EventTargetAttachedToTarget,
+			Params: easyjson.RawMessage([]byte(`{
+				"sessionId": "session_id_0123456789",
+				"targetInfo": {
+					"targetId": "target_id_0123456789", "type": "page", "title": "",
+					"url": "about:blank", "attached": true, "browserContextId": "browser_context_id_0123456789"
+				},
+				"waitingForDebugger": false
+			}`)),
+		}
+		writeCh <- cdproto.Message{
+			ID:     msg.ID,
+			Result: easyjson.RawMessage([]byte(`{"sessionId":"session_id_0123456789"}`)),
+		}
+	}
+	server := ws.NewServer(t, ws.WithCDPHandler("/cdp", handler, nil))
+
+	ctx := context.Background()
+	url, _ := url.Parse(server.ServerHTTP.URL)
+	wsURL := fmt.Sprintf("ws://%s/cdp", url.Host)
+	conn, err := NewConnection(ctx, wsURL, log.NewNullLogger())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	session, err := conn.createSession(&target.Info{
+		Type:             "page",
+		TargetID:         "target_id_0123456789",
+		BrowserContextID: "browser_context_id_0123456789",
+	})
+	require.NoError(t, err)
+
+	// the pending message fails when the page crashes.
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- cdppage.Enable().Do(cdp.WithExecutor(ctx, session))
+	}()
+	time.Sleep(50 * time.Millisecond)
+	session.markAsCrashed()
+	select {
+	case err := <-errCh:
+		require.ErrorIs(t, err, ErrPageCrashed)
+	case <-time.After(time.Second):
+		t.Fatal("the pending message didn't fail")
+	}
+
+	// the next messages fail without being sent.
+	err = cdppage.Enable().Do(cdp.WithExecutor(ctx, session))
+	require.ErrorIs(t, err, ErrPageCrashed)
+	session.markAsCrashed() // marking it again doesn't panic
+}
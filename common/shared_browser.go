@@ -0,0 +1,168 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package common
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/grafana/xk6-browser/api"
+
+	k6modules "go.k6.io/k6/js/modules"
+)
+
+// BrowserLauncher launches a browser that outlives the iterations of a VU,
+// and returns it with a function that cancels the context of the browser.
+type BrowserLauncher func() (api.Browser, context.CancelFunc)
+
+// SharedBrowser keeps a browser launched with the reuseBrowser option
+// running across the iterations of a VU, so that the browser process isn't
+// launched for every iteration. The browser contexts are closed at the end
+// of each iteration, and the browser is closed when the run of the VU ends,
+// or replaced after it disconnects, such as when it crashes.
+//
+// k6 doesn't tell the extension when the run of a VU ends, but the run
+// context of the VU ends at its deadline at the latest, which the contexts
+// of the iterations inherit. The browser is closed by the teardown of the
+// iteration that's running at the deadline, or when the deadline passes if
+// no iteration is running. Otherwise, the browser process is terminated
+// when k6 exits.
+type SharedBrowser struct {
+	vu k6modules.VU
+
+	mu        sync.Mutex
+	browser   *Browser
+	cancel    context.CancelFunc
+	iterating bool
+	runEnd    time.Time
+	runTimer  *time.Timer
+}
+
+// NewSharedBrowser returns a new shared browser for the VU.
+func NewSharedBrowser(vu k6modules.VU) *SharedBrowser {
+	return &SharedBrowser{vu: vu}
+}
+
+// Browser returns the shared browser, or launches it with launch if it's
+// not launched yet, or if it disconnected. It must be called on the event
+// loop.
+func (s *SharedBrowser) Browser(launch BrowserLauncher) api.Browser {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.iterating = true
+	if s.browser != nil && s.browser.IsConnected() {
+		s.browser.logger.Debugf("SharedBrowser:Browser", "reusing the browser")
+		return s.browser
+	}
+	if s.browser != nil {
+		// clean up after the disconnected browser.
+		s.closeLocked()
+	}
+
+	b, cancel := launch()
+	browser, ok := b.(*Browser)
+	if !ok {
+		cancel()
+		return b
+	}
+	s.browser, s.cancel = browser, cancel
+	if deadline, ok := s.vu.Context().Deadline(); ok {
+		s.runEnd = deadline
+		s.runTimer = time.AfterFunc(time.Until(deadline), s.closeOnRunEnd)
+	}
+
+	return browser
+}
+
+// Pid returns the process ID of the shared browser, or zero
+// if there's no shared browser or it's connected to.
+func (s *SharedBrowser) Pid() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.browser == nil {
+		return 0
+	}
+	return s.browser.getBrowserProc().Pid()
+}
+
+// EndIteration closes the browser contexts that the iteration created,
+// except a reused one, and keeps the browser running. It closes the
+// browser instead if the run of the VU ended.
+func (s *SharedBrowser) EndIteration() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.iterating = false
+	if s.browser == nil {
+		return
+	}
+	if s.runEnded() {
+		s.closeLocked()
+		return
+	}
+	if s.browser.IsConnected() {
+		s.browser.endIteration()
+	}
+}
+
+// Close closes the shared browser and terminates its process.
+func (s *SharedBrowser) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closeLocked()
+}
+
+func (s *SharedBrowser) closeLocked() {
+	if s.browser == nil {
+		return
+	}
+	b, cancel := s.browser, s.cancel
+	s.browser, s.cancel = nil, nil
+	if s.runTimer != nil {
+		s.runTimer.Stop()
+		s.runTimer = nil
+	}
+
+	defer cancel()
+	if err := b.shutdown(); err != nil {
+		b.logger.Errorf("SharedBrowser:close", "%v", err)
+	}
+}
+
+// closeOnRunEnd closes the shared browser when the run of the VU ends,
+// unless an iteration is still running, which closes it when it ends.
+func (s *SharedBrowser) closeOnRunEnd() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.iterating {
+		return
+	}
+	s.closeLocked()
+}
+
+func (s *SharedBrowser) runEnded() bool {
+	return !s.runEnd.IsZero() && !time.Now().Before(s.runEnd)
+}
@@ -0,0 +1,71 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package common
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/chromedp/cdproto/input"
+	cdppage "github.com/chromedp/cdproto/page"
+	"github.com/mailru/easyjson"
+)
+
+// slowMoCommands are the input and navigation commands that are slowed
+// down by the slowMo launch option.
+var slowMoCommands = map[string]bool{
+	input.CommandDispatchKeyEvent:         true,
+	input.CommandDispatchMouseEvent:       true,
+	input.CommandDispatchTouchEvent:       true,
+	input.CommandInsertText:               true,
+	cdppage.CommandNavigate:               true,
+	cdppage.CommandNavigateToHistoryEntry: true,
+	cdppage.CommandReload:                 true,
+}
+
+// slowMoSession is a session that applies slow motion before executing
+// the input and navigation commands, so that every keyboard, mouse and
+// touch event, and every navigation is slowed down in a single place.
+type slowMoSession struct {
+	session
+}
+
+// newSlowMoSession returns a session that slows down the input and
+// navigation commands it executes with s.
+func newSlowMoSession(s session) session {
+	return &slowMoSession{session: s}
+}
+
+// Execute applies slow motion if the method is an input or navigation
+// command, and then executes it. It returns an error without executing
+// the command if ctx is done while waiting.
+func (s *slowMoSession) Execute(
+	ctx context.Context, method string, params easyjson.Marshaler, res easyjson.Unmarshaler,
+) error {
+	if slowMoCommands[method] {
+		applySlowMo(ctx)
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("applying slow motion to %s: %w", method, err)
+		}
+	}
+
+	return s.session.Execute(ctx, method, params, res) //nolint:wrapcheck
+}
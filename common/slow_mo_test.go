@@ -0,0 +1,105 @@
+package common
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/xk6-browser/k6ext/k6test"
+
+	"github.com/chromedp/cdproto/input"
+	cdpruntime "github.com/chromedp/cdproto/runtime"
+	"github.com/mailru/easyjson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingSession records the commands executed with it,
+// and the time they were executed at.
+type recordingSession struct {
+	session
+	methods []string
+	times   []time.Time
+}
+
+func (s *recordingSession) Execute(_ context.Context, method string, _ easyjson.Marshaler, _ easyjson.Unmarshaler) error {
+	s.methods = append(s.methods, method)
+	s.times = append(s.times, time.Now())
+	return nil
+}
+
+func newSlowMoContext(t *testing.T, slowMo time.Duration) context.Context {
+	t.Helper()
+
+	ctx := k6test.NewVU(t).Context()
+	ctx = WithHooks(ctx, NewHooks())
+	return WithLaunchOptions(ctx, &LaunchOptions{SlowMo: slowMo})
+}
+
+func TestSlowMoSession(t *testing.T) {
+	t.Parallel()
+
+	const slowMo = 50 * time.Millisecond
+
+	t.Run("input", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := newSlowMoContext(t, slowMo)
+		rec := &recordingSession{}
+		s := newSlowMoSession(rec)
+
+		start := time.Now()
+		for _, m := range []string{
+			input.CommandDispatchMouseEvent,
+			input.CommandDispatchMouseEvent,
+			input.CommandDispatchKeyEvent,
+		} {
+			require.NoError(t, s.Execute(ctx, m, nil, nil))
+		}
+		require.Len(t, rec.times, 3)
+		prev := start
+		for i, at := range rec.times {
+			assert.GreaterOrEqual(t, at.Sub(prev), slowMo, "gap before command %d", i)
+			prev = at
+		}
+	})
+
+	t.Run("other", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := newSlowMoContext(t, time.Hour)
+		rec := &recordingSession{}
+		s := newSlowMoSession(rec)
+
+		require.NoError(t, s.Execute(ctx, cdpruntime.CommandEvaluate, nil, nil))
+		assert.Equal(t, []string{cdpruntime.CommandEvaluate}, rec.methods)
+	})
+
+	t.Run("unset", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := newSlowMoContext(t, 0)
+		rec := &recordingSession{}
+		s := newSlowMoSession(rec)
+
+		start := time.Now()
+		for i := 0; i < 10; i++ {
+			require.NoError(t, s.Execute(ctx, input.CommandDispatchMouseEvent, nil, nil))
+		}
+		assert.Len(t, rec.times, 10)
+		assert.Less(t, time.Since(start), slowMo)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(newSlowMoContext(t, time.Hour))
+		rec := &recordingSession{}
+		s := newSlowMoSession(rec)
+
+		time.AfterFunc(slowMo, cancel)
+		err := s.Execute(ctx, input.CommandDispatchMouseEvent, nil, nil)
+		require.ErrorIs(t, err, context.Canceled)
+		assert.Empty(t, rec.methods, "canceled commands should not be executed")
+	})
+}
@@ -0,0 +1,153 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/grafana/xk6-browser/api"
+	"github.com/grafana/xk6-browser/k6ext"
+
+	"github.com/dop251/goja"
+)
+
+// storageStateRestoredKey is the local storage item that marks the origins
+// whose local storage was restored from a storage state, so that it's only
+// restored once. It isn't a part of the storage state.
+const storageStateRestoredKey = "__xk6_browser_storage_state_restored"
+
+// localStorageScript returns the origin and the local
+// storage of the frame that it runs in as JSON.
+var localStorageScript = fmt.Sprintf(`() => {
+	const localStorage = [];
+	try {
+		for (let i = 0; i < window.localStorage.length; i++) {
+			const name = window.localStorage.key(i);
+			if (name !== %[1]q) {
+				localStorage.push({ name, value: window.localStorage.getItem(name) });
+			}
+		}
+	} catch (e) {
+		// the frames of opaque origins can't access the local storage.
+	}
+	return JSON.stringify({ origin: location.origin, localStorage });
+}`, storageStateRestoredKey)
+
+// StorageStateOptions are the options of BrowserContext.storageState.
+type StorageStateOptions struct {
+	// Path is the file that the storage state is written to, if it's set.
+	Path string `js:"path"`
+}
+
+// NewStorageStateOptions returns the default storage state options.
+func NewStorageStateOptions() *StorageStateOptions {
+	return &StorageStateOptions{}
+}
+
+// Parse parses the storage state options.
+func (o *StorageStateOptions) Parse(ctx context.Context, opts goja.Value) error {
+	if !gojaValueExists(opts) {
+		return nil
+	}
+	obj := opts.ToObject(k6ext.Runtime(ctx))
+	for _, k := range obj.Keys() {
+		if k == "path" {
+			o.Path = obj.Get(k).String()
+		}
+	}
+	return nil
+}
+
+// parseStorageState parses a storage state, or reads it from
+// the file at the path if the storage state is a string.
+func parseStorageState(state goja.Value) (*api.StorageState, error) {
+	var (
+		data []byte
+		err  error
+	)
+	switch s := state.Export().(type) {
+	case *api.StorageState:
+		return s, nil
+	case string:
+		if data, err = os.ReadFile(s); err != nil {
+			return nil, fmt.Errorf("reading storage state: %w", err)
+		}
+	default:
+		if data, err = json.Marshal(s); err != nil {
+			return nil, fmt.Errorf("serializing storage state: %w", err)
+		}
+	}
+
+	var s api.StorageState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parsing storage state: %w", err)
+	}
+
+	return &s, nil
+}
+
+// writeStorageState writes the storage state as JSON to the file at the path.
+func writeStorageState(path string, state *api.StorageState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("serializing storage state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("creating storage state directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("writing storage state: %w", err)
+	}
+
+	return nil
+}
+
+// restoreLocalStorageScript returns an init script that restores the local
+// storage of the origins the first time that a frame of an origin loads,
+// including the origins that the browser context visits later.
+func restoreLocalStorageScript(origins []*api.OriginState) (string, error) {
+	data, err := json.Marshal(origins)
+	if err != nil {
+		return "", fmt.Errorf("serializing local storage: %w", err)
+	}
+
+	return fmt.Sprintf(`(origins => {
+	const state = origins.find(o => o.origin === location.origin);
+	if (!state) {
+		return;
+	}
+	try {
+		if (localStorage.getItem(%[2]q) !== null) {
+			return;
+		}
+		for (const { name, value } of state.localStorage || []) {
+			localStorage.setItem(name, value);
+		}
+		localStorage.setItem(%[2]q, '1');
+	} catch (e) {
+		// the frames of opaque origins can't access the local storage.
+	}
+})(%[1]s);`, data, storageStateRestoredKey), nil
+}
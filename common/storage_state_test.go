@@ -0,0 +1,114 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana/xk6-browser/api"
+	"github.com/grafana/xk6-browser/k6ext/k6test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStorageState(t *testing.T) {
+	t.Parallel()
+
+	want := &api.StorageState{
+		Cookies: []*api.Cookie{
+			{Name: "session", Value: "1", Domain: "example.com", Path: "/", Expires: -1, SameSite: "Lax"},
+		},
+		Origins: []*api.OriginState{
+			{Origin: "https://example.com", LocalStorage: []api.NameValue{{Name: "theme", Value: "dark"}}},
+		},
+	}
+
+	t.Run("object", func(t *testing.T) {
+		t.Parallel()
+
+		vu := k6test.NewVU(t)
+		state, err := vu.Runtime().RunString(`({
+			cookies: [{
+				name: 'session', value: '1', domain: 'example.com', path: '/',
+				expires: -1, sameSite: 'Lax',
+			}],
+			origins: [{
+				origin: 'https://example.com',
+				localStorage: [{ name: 'theme', value: 'dark' }],
+			}],
+		})`)
+		require.NoError(t, err)
+
+		got, err := parseStorageState(state)
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	})
+	t.Run("struct", func(t *testing.T) {
+		t.Parallel()
+
+		vu := k6test.NewVU(t)
+		got, err := parseStorageState(vu.Runtime().ToValue(want))
+		require.NoError(t, err)
+		assert.Same(t, want, got)
+	})
+	t.Run("path", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "state", "state.json")
+		require.NoError(t, writeStorageState(path, want))
+
+		vu := k6test.NewVU(t)
+		got, err := parseStorageState(vu.Runtime().ToValue(path))
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	})
+	t.Run("missing_path", func(t *testing.T) {
+		t.Parallel()
+
+		vu := k6test.NewVU(t)
+		path := filepath.Join(t.TempDir(), "missing.json")
+		_, err := parseStorageState(vu.Runtime().ToValue(path))
+		assert.ErrorContains(t, err, "reading storage state")
+	})
+	t.Run("invalid_json", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "state.json")
+		require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+
+		vu := k6test.NewVU(t)
+		_, err := parseStorageState(vu.Runtime().ToValue(path))
+		assert.ErrorContains(t, err, "parsing storage state")
+	})
+}
+
+func TestStorageStateOptionsParse(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+	opts := NewStorageStateOptions()
+	require.NoError(t, opts.Parse(vu.Context(), nil))
+	assert.Empty(t, opts.Path)
+
+	v, err := vu.Runtime().RunString(`({ path: 'state.json' })`)
+	require.NoError(t, err)
+	require.NoError(t, opts.Parse(vu.Context(), v))
+	assert.Equal(t, "state.json", opts.Path)
+}
+
+func TestRestoreLocalStorageScript(t *testing.T) {
+	t.Parallel()
+
+	script, err := restoreLocalStorageScript([]*api.OriginState{
+		{Origin: "https://example.com", LocalStorage: []api.NameValue{{Name: "theme", Value: "dark"}}},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, script, `{"origin":"https://example.com","localStorage":[{"name":"theme","value":"dark"}]}`)
+	assert.Contains(t, script, storageStateRestoredKey)
+
+	// the script must be valid JavaScript.
+	vu := k6test.NewVU(t)
+	_, err = vu.Runtime().RunString(`var location = { origin: 'https://other.com' };` + script)
+	require.NoError(t, err)
+}
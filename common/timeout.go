@@ -20,7 +20,10 @@
 
 package common
 
+import "time"
+
 // TimeoutSettings holds information on timeout settings.
+// The timeouts are in milliseconds, and a zero timeout means no timeout.
 type TimeoutSettings struct {
 	parent                   *TimeoutSettings
 	defaultTimeout           *int64
@@ -45,25 +48,30 @@ func (t *TimeoutSettings) setDefaultNavigationTimeout(timeout int64) {
 	t.defaultNavigationTimeout = &timeout
 }
 
-func (t *TimeoutSettings) navigationTimeout() int64 {
+// navigationTimeout returns the timeout of navigations, like goto and
+// waitForNavigation. It falls back to the default timeout, and then
+// to the parent settings.
+func (t *TimeoutSettings) navigationTimeout() time.Duration {
 	if t.defaultNavigationTimeout != nil {
-		return *t.defaultNavigationTimeout
+		return time.Duration(*t.defaultNavigationTimeout) * time.Millisecond
 	}
 	if t.defaultTimeout != nil {
-		return *t.defaultTimeout
+		return time.Duration(*t.defaultTimeout) * time.Millisecond
 	}
 	if t.parent != nil {
 		return t.parent.navigationTimeout()
 	}
-	return int64(DefaultTimeout.Seconds())
+	return DefaultTimeout
 }
 
-func (t *TimeoutSettings) timeout() int64 {
+// timeout returns the timeout of actions, like click and waitForSelector.
+// It falls back to the parent settings.
+func (t *TimeoutSettings) timeout() time.Duration {
 	if t.defaultTimeout != nil {
-		return *t.defaultTimeout
+		return time.Duration(*t.defaultTimeout) * time.Millisecond
 	}
 	if t.parent != nil {
 		return t.parent.timeout()
 	}
-	return int64(DefaultTimeout.Seconds())
+	return DefaultTimeout
 }
@@ -22,6 +22,7 @@ package common
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -44,6 +45,7 @@ func TestTimeoutSettings(t *testing.T) {
 	t.Run("TimeoutSettings.timeout", func(t *testing.T) {
 		t.Run("should work", testTimeoutSettingsTimeout)
 		t.Run("should work with parent", testTimeoutSettingsTimeoutWithParent)
+		t.Run("should treat zero as no timeout", testTimeoutSettingsTimeoutZero)
 	})
 }
 
@@ -78,11 +80,11 @@ func testTimeoutSettingsNavigationTimeout(t *testing.T) {
 	ts := NewTimeoutSettings(nil)
 
 	// Assert default timeout value is used
-	assert.Equal(t, int64(DefaultTimeout.Seconds()), ts.navigationTimeout())
+	assert.Equal(t, DefaultTimeout, ts.navigationTimeout())
 
 	// Assert custom default timeout is used
 	ts.setDefaultNavigationTimeout(100)
-	assert.Equal(t, int64(100), ts.navigationTimeout())
+	assert.Equal(t, 100*time.Millisecond, ts.navigationTimeout())
 }
 
 func testTimeoutSettingsNavigationTimeoutWithParent(t *testing.T) {
@@ -90,26 +92,26 @@ func testTimeoutSettingsNavigationTimeoutWithParent(t *testing.T) {
 	tsWithParent := NewTimeoutSettings(ts)
 
 	// Assert default timeout value is used
-	assert.Equal(t, int64(DefaultTimeout.Seconds()), tsWithParent.navigationTimeout())
+	assert.Equal(t, DefaultTimeout, tsWithParent.navigationTimeout())
 
 	// Assert custom default timeout from parent is used
 	ts.setDefaultNavigationTimeout(1000)
-	assert.Equal(t, int64(1000), tsWithParent.navigationTimeout())
+	assert.Equal(t, 1000*time.Millisecond, tsWithParent.navigationTimeout())
 
 	// Assert custom default timeout is used (over parent)
 	tsWithParent.setDefaultNavigationTimeout(100)
-	assert.Equal(t, int64(100), tsWithParent.navigationTimeout())
+	assert.Equal(t, 100*time.Millisecond, tsWithParent.navigationTimeout())
 }
 
 func testTimeoutSettingsTimeout(t *testing.T) {
 	ts := NewTimeoutSettings(nil)
 
 	// Assert default timeout value is used
-	assert.Equal(t, int64(DefaultTimeout.Seconds()), ts.timeout())
+	assert.Equal(t, DefaultTimeout, ts.timeout())
 
 	// Assert custom default timeout is used
 	ts.setDefaultTimeout(100)
-	assert.Equal(t, int64(100), ts.timeout())
+	assert.Equal(t, 100*time.Millisecond, ts.timeout())
 }
 
 func testTimeoutSettingsTimeoutWithParent(t *testing.T) {
@@ -117,13 +119,28 @@ func testTimeoutSettingsTimeoutWithParent(t *testing.T) {
 	tsWithParent := NewTimeoutSettings(ts)
 
 	// Assert default timeout value is used
-	assert.Equal(t, int64(DefaultTimeout.Seconds()), tsWithParent.timeout())
+	assert.Equal(t, DefaultTimeout, tsWithParent.timeout())
 
 	// Assert custom default timeout from parent is used
 	ts.setDefaultTimeout(1000)
-	assert.Equal(t, int64(1000), tsWithParent.timeout())
+	assert.Equal(t, 1000*time.Millisecond, tsWithParent.timeout())
 
 	// Assert custom default timeout is used (over parent)
 	tsWithParent.setDefaultTimeout(100)
-	assert.Equal(t, int64(100), tsWithParent.timeout())
+	assert.Equal(t, 100*time.Millisecond, tsWithParent.timeout())
+}
+
+func testTimeoutSettingsTimeoutZero(t *testing.T) {
+	ts := NewTimeoutSettings(nil)
+	tsWithParent := NewTimeoutSettings(ts)
+
+	// Assert zero from parent overrides the default timeout
+	ts.setDefaultTimeout(0)
+	assert.Equal(t, time.Duration(0), tsWithParent.timeout())
+	assert.Equal(t, time.Duration(0), tsWithParent.navigationTimeout())
+
+	// Assert navigation timeout can still be set over a zero timeout
+	tsWithParent.setDefaultNavigationTimeout(100)
+	assert.Equal(t, time.Duration(0), tsWithParent.timeout())
+	assert.Equal(t, 100*time.Millisecond, tsWithParent.navigationTimeout())
 }
@@ -41,25 +41,31 @@ type Touchscreen struct {
 	ctx      context.Context
 	session  session
 	keyboard *Keyboard
+	hasTouch bool
 }
 
 // NewTouchscreen returns a new TouchScreen.
-func NewTouchscreen(ctx context.Context, s session, k *Keyboard) *Touchscreen {
+// hasTouch tells whether the browser context supports touch events.
+func NewTouchscreen(ctx context.Context, s session, k *Keyboard, hasTouch bool) *Touchscreen {
 	return &Touchscreen{
 		ctx:      ctx,
 		session:  s,
 		keyboard: k,
+		hasTouch: hasTouch,
 	}
 }
 
 func (t *Touchscreen) tap(x float64, y float64) error {
+	if !t.hasTouch {
+		return ErrTouchNotSupported
+	}
 	action := input.DispatchTouchEvent(input.TouchStart, []*input.TouchPoint{{X: x, Y: y}}).
-		WithModifiers(input.Modifier(t.keyboard.modifiers))
+		WithModifiers(input.Modifier(t.keyboard.getModifiers()))
 	if err := action.Do(cdp.WithExecutor(t.ctx, t.session)); err != nil {
 		return err
 	}
 	action = input.DispatchTouchEvent(input.TouchEnd, []*input.TouchPoint{}).
-		WithModifiers(input.Modifier(t.keyboard.modifiers))
+		WithModifiers(input.Modifier(t.keyboard.getModifiers()))
 	if err := action.Do(cdp.WithExecutor(t.ctx, t.session)); err != nil {
 		return err
 	}
@@ -0,0 +1,719 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package common
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/xk6-browser/api"
+	"github.com/grafana/xk6-browser/k6ext"
+	"github.com/grafana/xk6-browser/log"
+
+	"github.com/chromedp/cdproto"
+	"github.com/chromedp/cdproto/cdp"
+	cdpio "github.com/chromedp/cdproto/io"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/cdproto/tracing"
+	"github.com/dop251/goja"
+)
+
+// Ensure Tracing implements the api.Tracing interface.
+var _ api.Tracing = &Tracing{}
+
+// The names of the files in a trace archive.
+const (
+	traceEventsFile  = "trace.json"
+	traceActionsFile = "actions.json"
+)
+
+// traceCategories are the categories of the browser trace events that
+// are always recorded. They're the categories of the DevTools Performance
+// panel.
+var traceCategories = []string{
+	"devtools.timeline",
+	"v8.execute",
+	"disabled-by-default-devtools.timeline",
+	"disabled-by-default-devtools.timeline.frame",
+	"disabled-by-default-devtools.timeline.stack",
+	"toplevel",
+	"blink.console",
+	"blink.user_timing",
+	"latencyInfo",
+}
+
+// TracingStartOptions are the options of starting a trace.
+type TracingStartOptions struct {
+	// Name is the name of the trace that is saved to the trace archive.
+	Name string `js:"name"`
+	// Screenshots records screenshots of the pages in the trace.
+	Screenshots bool `js:"screenshots"`
+	// Snapshots records the layers and paints of the pages in the trace.
+	Snapshots bool `js:"snapshots"`
+}
+
+// NewTracingStartOptions returns the default options of starting a trace.
+func NewTracingStartOptions() *TracingStartOptions {
+	return &TracingStartOptions{}
+}
+
+// Parse parses the options of starting a trace from a JS object.
+func (o *TracingStartOptions) Parse(ctx context.Context, opts goja.Value) error {
+	if !gojaValueExists(opts) {
+		return nil
+	}
+	rt := k6ext.Runtime(ctx)
+	obj := opts.ToObject(rt)
+	for _, k := range obj.Keys() {
+		switch k {
+		case "name":
+			o.Name = obj.Get(k).String()
+		case "screenshots":
+			o.Screenshots = obj.Get(k).ToBoolean()
+		case "snapshots":
+			o.Snapshots = obj.Get(k).ToBoolean()
+		}
+	}
+
+	return nil
+}
+
+// categories returns the categories of the browser trace events to record.
+func (o *TracingStartOptions) categories() []string {
+	categories := append([]string{}, traceCategories...)
+	if o.Screenshots {
+		categories = append(categories, "disabled-by-default-devtools.screenshot")
+	}
+	if o.Snapshots {
+		categories = append(categories,
+			"disabled-by-default-devtools.timeline.layers",
+			"disabled-by-default-devtools.timeline.picture",
+		)
+	}
+	return categories
+}
+
+// TracingStopOptions are the options of stopping a trace.
+type TracingStopOptions struct {
+	// Path is the path of the trace archive. The trace is
+	// discarded if it's not set.
+	Path string `js:"path"`
+}
+
+// NewTracingStopOptions returns the default options of stopping a trace.
+func NewTracingStopOptions() *TracingStopOptions {
+	return &TracingStopOptions{}
+}
+
+// Parse parses the options of stopping a trace from a JS object.
+func (o *TracingStopOptions) Parse(ctx context.Context, opts goja.Value) error {
+	if !gojaValueExists(opts) {
+		return nil
+	}
+	rt := k6ext.Runtime(ctx)
+	obj := opts.ToObject(rt)
+	for _, k := range obj.Keys() {
+		if k == "path" {
+			o.Path = obj.Get(k).String()
+		}
+	}
+
+	return nil
+}
+
+// traceAction is an action of a page that is recorded in a trace.
+// The times are in milliseconds since the Unix epoch.
+type traceAction struct {
+	Name      string  `json:"name"`
+	Selector  string  `json:"selector,omitempty"`
+	URL       string  `json:"url,omitempty"`
+	StartTime float64 `json:"startTime"`
+	EndTime   float64 `json:"endTime"`
+}
+
+// traceRecording is a trace of a browser context that is being recorded.
+type traceRecording struct {
+	opts *TracingStartOptions
+
+	mu      sync.Mutex
+	pages   map[string]bool // target IDs of the pages of the browser context
+	events  []json.RawMessage
+	actions []*traceAction
+}
+
+func newTraceRecording(opts *TracingStartOptions) *traceRecording {
+	return &traceRecording{
+		opts:  opts,
+		pages: make(map[string]bool),
+	}
+}
+
+// addPage adds a page of the browser context to the trace, so that the
+// browser trace events of the page are added to the trace.
+func (r *traceRecording) addPage(id target.ID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pages[string(id)] = true
+}
+
+// pageIDs returns the target IDs of the pages of the trace. They're
+// also the IDs of the main frames of the pages.
+func (r *traceRecording) pageIDs() map[string]bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := make(map[string]bool, len(r.pages))
+	for id := range r.pages {
+		ids[id] = true
+	}
+	return ids
+}
+
+// addEvents adds the browser trace events to the trace.
+func (r *traceRecording) addEvents(events []json.RawMessage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, events...)
+}
+
+// startAction records the start of an action, and returns
+// a function that records the end of the action.
+func (r *traceRecording) startAction(name, selector, url string) func() {
+	a := &traceAction{
+		Name:      name,
+		Selector:  selector,
+		URL:       url,
+		StartTime: traceTime(time.Now()),
+	}
+	return func() {
+		a.EndTime = traceTime(time.Now())
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.actions = append(r.actions, a)
+	}
+}
+
+// save writes the trace to a zip archive at the given path. The archive
+// has the browser trace events in the Chrome trace event format, which the
+// DevTools Performance panel can open, and the actions of the pages.
+func (r *traceRecording) save(path string) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("creating trace directory: %w", err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating trace archive: %w", err)
+	}
+	defer func() {
+		if cerr := f.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("closing trace archive: %w", cerr)
+		}
+	}()
+
+	events := r.events
+	if events == nil {
+		events = []json.RawMessage{}
+	}
+	actions := r.actions
+	if actions == nil {
+		actions = []*traceAction{}
+	}
+	zw := zip.NewWriter(f)
+	if err := writeTraceFile(zw, traceEventsFile, map[string]interface{}{
+		"traceEvents": events,
+		"metadata":    map[string]string{"name": r.opts.Name},
+	}); err != nil {
+		return err
+	}
+	if err := writeTraceFile(zw, traceActionsFile, actions); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("writing trace archive: %w", err)
+	}
+
+	return nil
+}
+
+func writeTraceFile(zw *zip.Writer, name string, v interface{}) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("writing %s to trace archive: %w", name, err)
+	}
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		return fmt.Errorf("writing %s to trace archive: %w", name, err)
+	}
+	return nil
+}
+
+// traceTime returns t in milliseconds since the Unix epoch.
+func traceTime(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Millisecond)
+}
+
+// Tracing records the traces of a browser context. A trace has the browser
+// trace events and the actions of the pages of the browser context, such as
+// navigations and clicks, with their start and end times.
+type Tracing struct {
+	ctx        context.Context
+	browserCtx *BrowserContext
+	logger     *log.Logger
+
+	mu        sync.Mutex
+	recording *traceRecording
+}
+
+// NewTracing returns the tracing of a browser context.
+func NewTracing(ctx context.Context, browserCtx *BrowserContext, logger *log.Logger) *Tracing {
+	return &Tracing{
+		ctx:        ctx,
+		browserCtx: browserCtx,
+		logger:     logger,
+	}
+}
+
+// Start starts recording a trace.
+func (t *Tracing) Start(opts goja.Value) {
+	t.logger.Debugf("Tracing:Start", "bctxid:%v", t.browserCtx.id)
+
+	popts := NewTracingStartOptions()
+	if err := popts.Parse(t.ctx, opts); err != nil {
+		k6ext.Panic(t.ctx, "parsing tracing start options: %w", err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.recording != nil {
+		k6ext.Panic(t.ctx, "starting tracing: tracing has already been started")
+	}
+	r := newTraceRecording(popts)
+	for _, p := range t.browserCtx.browser.getPages() {
+		if p.browserCtx == t.browserCtx {
+			r.addPage(p.targetID)
+		}
+	}
+	if err := t.browserCtx.browser.tracer.add(t.ctx, r, t.browserCtx.timeoutSettings.timeout()); err != nil {
+		k6ext.Panic(t.ctx, "starting tracing: %w", err)
+	}
+	t.recording = r
+}
+
+// Stop stops recording the trace, and saves it to a zip archive
+// if the path option is set.
+func (t *Tracing) Stop(opts goja.Value) {
+	t.logger.Debugf("Tracing:Stop", "bctxid:%v", t.browserCtx.id)
+
+	popts := NewTracingStopOptions()
+	if err := popts.Parse(t.ctx, opts); err != nil {
+		k6ext.Panic(t.ctx, "parsing tracing stop options: %w", err)
+	}
+
+	r, err := t.stop()
+	if err != nil {
+		k6ext.Panic(t.ctx, "stopping tracing: %w", err)
+	}
+	if popts.Path == "" {
+		return
+	}
+	if err := r.save(popts.Path); err != nil {
+		k6ext.Panic(t.ctx, "saving trace: %w", err)
+	}
+}
+
+// stop stops recording the trace after the browser trace
+// events are collected, and returns the trace.
+func (t *Tracing) stop() (*traceRecording, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	r := t.recording
+	if r == nil {
+		return nil, errors.New("tracing must be started")
+	}
+	t.recording = nil
+	if err := t.browserCtx.browser.tracer.remove(t.ctx, r, t.browserCtx.timeoutSettings.timeout()); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// discard stops recording the trace without saving it, if it's
+// being recorded, such as when the browser context closes.
+func (t *Tracing) discard() {
+	t.mu.Lock()
+	recording := t.recording != nil
+	t.mu.Unlock()
+	if !recording {
+		return
+	}
+	if _, err := t.stop(); err != nil {
+		t.logger.Debugf("Tracing:discard", "bctxid:%v err:%v", t.browserCtx.id, err)
+	}
+}
+
+// onPage adds a new page of the browser context to the
+// trace if it's being recorded.
+func (t *Tracing) onPage(p *Page) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.recording != nil {
+		t.recording.addPage(p.targetID)
+	}
+}
+
+// action records the start of an action in the trace if it's being
+// recorded, and returns a function that records the end of the action.
+func (t *Tracing) action(name, selector, url string) func() {
+	t.mu.Lock()
+	r := t.recording
+	t.mu.Unlock()
+
+	if r == nil {
+		return func() {}
+	}
+	return r.startAction(name, selector, url)
+}
+
+// browserTracer records the browser trace events for the traces of the
+// browser contexts. The browser records a single trace at a time, so the
+// tracer restarts the browser trace whenever a trace starts or stops, and
+// adds the collected events to the traces that were being recorded. Each
+// trace gets only the events of the pages of its browser context, and the
+// events that don't belong to any page. This way, the traces of the
+// browser contexts are independent of each other.
+type browserTracer struct {
+	browser *Browser
+
+	mu         sync.Mutex
+	recordings map[*traceRecording]bool
+	running    bool
+}
+
+func newBrowserTracer(b *Browser) *browserTracer {
+	return &browserTracer{
+		browser:    b,
+		recordings: make(map[*traceRecording]bool),
+	}
+}
+
+// add starts recording the browser trace events for r.
+func (t *browserTracer) add(ctx context.Context, r *traceRecording, timeout time.Duration) error {
+	return t.restart(ctx, timeout, func() { t.recordings[r] = true })
+}
+
+// remove stops recording the browser trace events for r after
+// adding the events that are recorded so far to r.
+func (t *browserTracer) remove(ctx context.Context, r *traceRecording, timeout time.Duration) error {
+	return t.restart(ctx, timeout, func() { delete(t.recordings, r) })
+}
+
+// resume starts the browser trace again for the traces that are being
+// recorded, after the browser restarts and loses its trace.
+func (t *browserTracer) resume(ctx context.Context, timeout time.Duration) error {
+	t.mu.Lock()
+	t.running = false
+	t.mu.Unlock()
+
+	return t.restart(ctx, timeout, func() {})
+}
+
+// restart ends the browser trace if it's running, and adds its events to
+// the traces. It then calls update to change the traces, and starts the
+// browser trace again if there are traces to record.
+func (t *browserTracer) restart(ctx context.Context, timeout time.Duration, update func()) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var endErr error
+	if t.running {
+		t.running = false
+		var events []json.RawMessage
+		events, endErr = t.end(tctx)
+		trace := parseBrowserTrace(events)
+		for r := range t.recordings {
+			r.addEvents(trace.eventsOf(r.pageIDs()))
+		}
+	}
+	update()
+	if endErr != nil {
+		return endErr
+	}
+	if len(t.recordings) == 0 {
+		return nil
+	}
+	if err := t.start(tctx); err != nil {
+		return err
+	}
+	t.running = true
+
+	return nil
+}
+
+// start starts the browser trace with the categories of the traces.
+func (t *browserTracer) start(ctx context.Context) error {
+	var (
+		seen       = make(map[string]bool)
+		categories []string
+	)
+	for r := range t.recordings {
+		for _, c := range r.opts.categories() {
+			if !seen[c] {
+				seen[c] = true
+				categories = append(categories, c)
+			}
+		}
+	}
+	action := tracing.Start().
+		WithTransferMode(tracing.TransferModeReturnAsStream).
+		WithStreamFormat(tracing.StreamFormatJSON).
+		WithTraceConfig(&tracing.TraceConfig{
+			IncludedCategories: categories,
+			ExcludedCategories: []string{"*"},
+		})
+	if err := action.Do(cdp.WithExecutor(ctx, t.browser.getConn())); err != nil {
+		return fmt.Errorf("starting browser trace: %w", err)
+	}
+
+	return nil
+}
+
+// end ends the browser trace and returns its events. The events are
+// returned as a stream instead of dataCollected events, because the
+// events are handled concurrently, and the stream guarantees that all
+// the events are collected when the trace completes.
+func (t *browserTracer) end(ctx context.Context) ([]json.RawMessage, error) {
+	evCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ch := make(chan Event)
+	t.browser.getConn().on(evCtx, []string{cdproto.EventTracingTracingComplete}, ch)
+
+	if err := tracing.End().Do(cdp.WithExecutor(ctx, t.browser.getConn())); err != nil {
+		return nil, fmt.Errorf("ending browser trace: %w", err)
+	}
+	var complete *tracing.EventTracingComplete
+	select {
+	case ev := <-ch:
+		complete, _ = ev.data.(*tracing.EventTracingComplete)
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for browser trace: %w", ctx.Err())
+	}
+	if complete == nil || complete.Stream == "" {
+		return nil, nil
+	}
+
+	data, err := readStream(ctx, t.browser.getConn(), complete.Stream)
+	if err != nil {
+		return nil, fmt.Errorf("reading browser trace: %w", err)
+	}
+	var trace struct {
+		TraceEvents []json.RawMessage `json:"traceEvents"`
+	}
+	if err := json.Unmarshal([]byte(data), &trace); err != nil {
+		return nil, fmt.Errorf("parsing browser trace: %w", err)
+	}
+
+	return trace.TraceEvents, nil
+}
+
+// traceFrame is a frame that a browser trace event refers to.
+type traceFrame struct {
+	Frame     string       `json:"frame"`
+	Parent    string       `json:"parent"`
+	ProcessID int          `json:"processId"`
+	Frames    []traceFrame `json:"frames"`
+}
+
+// traceEvent has the fields of a browser trace event that tell
+// which process and frame the event belongs to.
+type traceEvent struct {
+	Name string `json:"name"`
+	Pid  int    `json:"pid"`
+	Args struct {
+		Data json.RawMessage `json:"data"`
+	} `json:"args"`
+
+	data traceFrame
+}
+
+// browserTrace is a browser trace that is split into the events of the
+// browser contexts by the frames of their pages.
+type browserTrace struct {
+	raw    []json.RawMessage
+	events []traceEvent
+
+	frameParents   map[string]string
+	frameProcesses map[string]int
+	renderers      map[int]bool
+}
+
+// parseBrowserTrace maps the frames of a browser trace to their renderer
+// processes, which the browser reports at the start of the trace and
+// whenever a frame commits a navigation.
+func parseBrowserTrace(raw []json.RawMessage) *browserTrace {
+	t := &browserTrace{
+		raw:            raw,
+		events:         make([]traceEvent, len(raw)),
+		frameParents:   make(map[string]string),
+		frameProcesses: make(map[string]int),
+		renderers:      make(map[int]bool),
+	}
+	addFrame := func(f traceFrame) {
+		if f.Frame == "" || f.ProcessID == 0 {
+			return
+		}
+		t.frameProcesses[f.Frame] = f.ProcessID
+		t.renderers[f.ProcessID] = true
+		if f.Parent != "" {
+			t.frameParents[f.Frame] = f.Parent
+		}
+	}
+	for i, r := range raw {
+		ev := &t.events[i]
+		// the events that can't be parsed don't belong to any frame.
+		_ = json.Unmarshal(r, ev)
+		_ = json.Unmarshal(ev.Args.Data, &ev.data)
+		switch ev.Name {
+		case "TracingStartedInBrowser":
+			for _, f := range ev.data.Frames {
+				addFrame(f)
+			}
+		case "FrameCommittedInBrowser":
+			addFrame(ev.data)
+		}
+	}
+
+	return t
+}
+
+// eventsOf returns the events of the pages with the given main frame IDs,
+// and the events that don't belong to any page, such as the ones of the
+// GPU process.
+func (t *browserTrace) eventsOf(pages map[string]bool) []json.RawMessage {
+	frames := make(map[string]bool, len(pages))
+	for id := range pages {
+		frames[id] = true
+	}
+	// add the subframes of the pages.
+	for added := true; added; {
+		added = false
+		for f, parent := range t.frameParents {
+			if !frames[f] && frames[parent] {
+				frames[f] = true
+				added = true
+			}
+		}
+	}
+	processes := make(map[int]bool)
+	for f := range frames {
+		if pid, ok := t.frameProcesses[f]; ok {
+			processes[pid] = true
+		}
+	}
+
+	var events []json.RawMessage
+	for i, ev := range t.events {
+		switch {
+		case ev.Name == "TracingStartedInBrowser":
+			events = append(events, withTraceFrames(t.raw[i], frames))
+		case processes[ev.Pid]:
+			events = append(events, t.raw[i])
+		case t.renderers[ev.Pid]:
+			// the renderer process of the pages of another browser context.
+		case ev.data.Frame != "" && !frames[ev.data.Frame]:
+			// the browser process event of a frame of another browser context.
+		default:
+			events = append(events, t.raw[i])
+		}
+	}
+
+	return events
+}
+
+// withTraceFrames returns the TracingStartedInBrowser event with only the
+// given frames, so that it doesn't reveal the frames of the pages of the
+// other browser contexts.
+func withTraceFrames(raw json.RawMessage, frames map[string]bool) json.RawMessage {
+	var ev map[string]interface{}
+	if err := json.Unmarshal(raw, &ev); err != nil {
+		return raw
+	}
+	args, _ := ev["args"].(map[string]interface{})
+	data, _ := args["data"].(map[string]interface{})
+	all, _ := data["frames"].([]interface{})
+	if all == nil {
+		return raw
+	}
+	kept := []interface{}{}
+	for _, f := range all {
+		if m, ok := f.(map[string]interface{}); ok && frames[fmt.Sprint(m["frame"])] {
+			kept = append(kept, f)
+		}
+	}
+	data["frames"] = kept
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return raw
+	}
+
+	return b
+}
+
+// readStream reads a stream of the browser to the end, and closes it.
+func readStream(ctx context.Context, exec cdp.Executor, handle cdpio.StreamHandle) (string, error) {
+	ctx = cdp.WithExecutor(ctx, exec)
+
+	var sb strings.Builder
+	for {
+		data, eof, err := cdpio.Read(handle).Do(ctx)
+		if err != nil {
+			return "", fmt.Errorf("reading stream: %w", err)
+		}
+		sb.WriteString(data)
+		if eof {
+			break
+		}
+	}
+	if err := cdpio.Close(handle).Do(ctx); err != nil {
+		return "", fmt.Errorf("closing stream: %w", err)
+	}
+
+	return sb.String(), nil
+}
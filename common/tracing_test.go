@@ -0,0 +1,143 @@
+package common
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana/xk6-browser/k6ext/k6test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracingOptionsParse(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+
+	start := NewTracingStartOptions()
+	require.NoError(t, start.Parse(vu.Context(), nil))
+	assert.Equal(t, traceCategories, start.categories())
+
+	v, err := vu.Runtime().RunString(`({ name: 'trace', screenshots: true, snapshots: true })`)
+	require.NoError(t, err)
+	require.NoError(t, start.Parse(vu.Context(), v))
+	assert.Equal(t, &TracingStartOptions{Name: "trace", Screenshots: true, Snapshots: true}, start)
+	assert.Contains(t, start.categories(), "disabled-by-default-devtools.screenshot")
+	assert.Contains(t, start.categories(), "disabled-by-default-devtools.timeline.picture")
+
+	stop := NewTracingStopOptions()
+	v, err = vu.Runtime().RunString(`({ path: 'trace.zip' })`)
+	require.NoError(t, err)
+	require.NoError(t, stop.Parse(vu.Context(), v))
+	assert.Equal(t, "trace.zip", stop.Path)
+}
+
+// readTraceFile decodes a file of a trace archive into v.
+func readTraceFile(t *testing.T, path, name string, v interface{}) {
+	t.Helper()
+
+	zr, err := zip.OpenReader(path)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, zr.Close()) }()
+
+	f, err := zr.Open(name)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, f.Close()) }()
+	require.NoError(t, json.NewDecoder(f).Decode(v))
+}
+
+func TestTraceRecordingSave(t *testing.T) {
+	t.Parallel()
+
+	r := newTraceRecording(&TracingStartOptions{Name: "trace"})
+	r.addEvents([]json.RawMessage{json.RawMessage(`{"name":"event1"}`)})
+	endGoto := r.startAction("goto", "", "https://example.com")
+	endGoto()
+	endClick := r.startAction("click", "#button", "https://example.com")
+	r.addEvents([]json.RawMessage{json.RawMessage(`{"name":"event2"}`)})
+	endClick()
+
+	path := filepath.Join(t.TempDir(), "traces", "trace.zip")
+	require.NoError(t, r.save(path))
+
+	var trace struct {
+		TraceEvents []map[string]interface{} `json:"traceEvents"`
+		Metadata    map[string]string        `json:"metadata"`
+	}
+	readTraceFile(t, path, traceEventsFile, &trace)
+	assert.Equal(t, []map[string]interface{}{
+		{"name": "event1"}, {"name": "event2"},
+	}, trace.TraceEvents)
+	assert.Equal(t, "trace", trace.Metadata["name"])
+
+	var actions []*traceAction
+	readTraceFile(t, path, traceActionsFile, &actions)
+	require.Len(t, actions, 2)
+	assert.Equal(t, "goto", actions[0].Name)
+	assert.Equal(t, "https://example.com", actions[0].URL)
+	assert.Equal(t, "click", actions[1].Name)
+	assert.Equal(t, "#button", actions[1].Selector)
+	for _, a := range actions {
+		assert.LessOrEqual(t, a.StartTime, a.EndTime)
+	}
+	assert.LessOrEqual(t, actions[0].EndTime, actions[1].StartTime)
+}
+
+func TestTraceRecordingSaveEmpty(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "trace.zip")
+	require.NoError(t, newTraceRecording(NewTracingStartOptions()).save(path))
+
+	var trace map[string]json.RawMessage
+	readTraceFile(t, path, traceEventsFile, &trace)
+	assert.JSONEq(t, `[]`, string(trace["traceEvents"]))
+
+	var actions []*traceAction
+	readTraceFile(t, path, traceActionsFile, &actions)
+	assert.NotNil(t, actions)
+	assert.Empty(t, actions)
+}
+
+func TestBrowserTraceEventsOf(t *testing.T) {
+	t.Parallel()
+
+	trace := parseBrowserTrace([]json.RawMessage{
+		json.RawMessage(`{"name":"TracingStartedInBrowser","pid":1,"args":{"data":{"frames":[` +
+			`{"frame":"page1","processId":10,"url":"https://one.test"},` +
+			`{"frame":"page2","processId":20,"url":"https://two.test"}]}}}`),
+		json.RawMessage(`{"name":"FrameCommittedInBrowser","pid":1,"args":{"data":{"frame":"sub1","parent":"page1","processId":30}}}`),
+		json.RawMessage(`{"name":"FrameCommittedInBrowser","pid":1,"args":{"data":{"frame":"page2","processId":20}}}`),
+		json.RawMessage(`{"name":"Paint","pid":10,"args":{"data":{"frame":"page1"}}}`),
+		json.RawMessage(`{"name":"Layout","pid":30,"args":{}}`),
+		json.RawMessage(`{"name":"Paint","pid":20,"args":{"data":{"frame":"page2"}}}`),
+		json.RawMessage(`{"name":"GPUTask","pid":2,"args":{"data":"gpu"}}`),
+	})
+	names := func(events []json.RawMessage) []string {
+		var names []string
+		for _, e := range events {
+			var ev traceEvent
+			require.NoError(t, json.Unmarshal(e, &ev))
+			names = append(names, fmt.Sprintf("%s:%d", ev.Name, ev.Pid))
+		}
+		return names
+	}
+
+	events := trace.eventsOf(map[string]bool{"page1": true})
+	assert.Equal(t, []string{
+		"TracingStartedInBrowser:1", "FrameCommittedInBrowser:1", "Paint:10", "Layout:30", "GPUTask:2",
+	}, names(events))
+	assert.Contains(t, string(events[0]), "https://one.test")
+	assert.NotContains(t, string(events[0]), "https://two.test")
+
+	events = trace.eventsOf(map[string]bool{"page2": true})
+	assert.Equal(t, []string{
+		"TracingStartedInBrowser:1", "FrameCommittedInBrowser:1", "Paint:20", "GPUTask:2",
+	}, names(events))
+	assert.NotContains(t, string(events[0]), "https://one.test")
+	assert.Contains(t, string(events[1]), "page2")
+}
@@ -24,14 +24,21 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
+	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/grafana/xk6-browser/api"
 	"github.com/grafana/xk6-browser/k6ext"
 
+	cdpbrowser "github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/network"
 	"github.com/dop251/goja"
 )
 
@@ -148,21 +155,34 @@ func NewEmulatedSize(viewport *Viewport, screen *Screen) *EmulatedSize {
 	}
 }
 
+// DeviceMetrics are the properties of the device that a page emulates.
+type DeviceMetrics struct {
+	DeviceScaleFactor float64
+	IsMobile          bool
+	HasTouch          bool
+	ScreenOrientation ScreenOrientation
+}
+
+// Geolocation is the emulated geolocation of the pages. The latitude and
+// the longitude are in degrees, and the accuracy is in meters.
 type Geolocation struct {
 	Latitude  float64 `js:"latitude"`
 	Longitude float64 `js:"longitude"`
-	Accurracy float64 `js:"accurracy"`
+	Accuracy  float64 `js:"accuracy"`
 }
 
+// NewGeolocation returns a new geolocation.
 func NewGeolocation() *Geolocation {
 	return &Geolocation{}
 }
 
+// Parse parses the geolocation. The latitude and the longitude are required.
 func (g *Geolocation) Parse(ctx context.Context, opts goja.Value) error {
 	rt := k6ext.Runtime(ctx)
 	longitude := 0.0
 	latitude := 0.0
 	accuracy := 0.0
+	var hasLatitude, hasLongitude bool
 
 	if opts != nil && !goja.IsUndefined(opts) && !goja.IsNull(opts) {
 		opts := opts.ToObject(rt)
@@ -172,12 +192,17 @@ func (g *Geolocation) Parse(ctx context.Context, opts goja.Value) error {
 				accuracy = opts.Get(k).ToFloat()
 			case "latitude":
 				latitude = opts.Get(k).ToFloat()
+				hasLatitude = true
 			case "longitude":
 				longitude = opts.Get(k).ToFloat()
+				hasLongitude = true
 			}
 		}
 	}
 
+	if !hasLatitude || !hasLongitude {
+		return errors.New("latitude and longitude are required")
+	}
 	if longitude < -180 || longitude > 180 {
 		return fmt.Errorf(`invalid longitude "%.2f": precondition -180 <= LONGITUDE <= 180 failed`, longitude)
 	}
@@ -188,12 +213,97 @@ func (g *Geolocation) Parse(ctx context.Context, opts goja.Value) error {
 		return fmt.Errorf(`invalid accuracy "%.2f": precondition 0 <= ACCURACY failed`, accuracy)
 	}
 
-	g.Accurracy = accuracy
+	g.Accuracy = accuracy
 	g.Latitude = latitude
 	g.Longitude = longitude
 	return nil
 }
 
+// NetworkConditions are the network conditions to emulate. The latency is
+// in milliseconds and the throughputs are in bytes per second, where -1
+// disables the throttling.
+type NetworkConditions struct {
+	Offline            bool    `js:"offline"`
+	Latency            float64 `js:"latency"`
+	DownloadThroughput float64 `js:"downloadThroughput"`
+	UploadThroughput   float64 `js:"uploadThroughput"`
+}
+
+// networkConditionsPresets are the named network conditions, with the
+// same values as the presets of the Chrome DevTools.
+var networkConditionsPresets = map[string]NetworkConditions{
+	"Slow 3G": {Latency: 2000, DownloadThroughput: 50000, UploadThroughput: 50000},
+	"Fast 3G": {Latency: 562.5, DownloadThroughput: 180000, UploadThroughput: 84375},
+	"Slow 4G": {Latency: 562.5, DownloadThroughput: 180000, UploadThroughput: 84375},
+	"Fast 4G": {Latency: 165, DownloadThroughput: 1012500, UploadThroughput: 168750},
+}
+
+// NewNetworkConditions returns network conditions without any throttling.
+func NewNetworkConditions() *NetworkConditions {
+	return &NetworkConditions{DownloadThroughput: -1, UploadThroughput: -1}
+}
+
+// Parse parses the network conditions from an object, or from the name
+// of a preset such as 'Slow 3G'.
+func (c *NetworkConditions) Parse(ctx context.Context, opts goja.Value) error {
+	if opts == nil || goja.IsUndefined(opts) || goja.IsNull(opts) {
+		return nil
+	}
+	if name, ok := opts.Export().(string); ok {
+		preset, ok := networkConditionsPresets[name]
+		if !ok {
+			names := make([]string, 0, len(networkConditionsPresets))
+			for n := range networkConditionsPresets {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+			return fmt.Errorf("unknown network conditions preset %q: must be one of: %s",
+				name, strings.Join(names, ", "))
+		}
+		*c = preset
+		return nil
+	}
+
+	rt := k6ext.Runtime(ctx)
+	obj := opts.ToObject(rt)
+	for _, k := range obj.Keys() {
+		switch k {
+		case "offline":
+			c.Offline = obj.Get(k).ToBoolean()
+		case "latency":
+			c.Latency = obj.Get(k).ToFloat()
+		case "downloadThroughput":
+			c.DownloadThroughput = obj.Get(k).ToFloat()
+		case "uploadThroughput":
+			c.UploadThroughput = obj.Get(k).ToFloat()
+		}
+	}
+
+	if c.Latency < 0 {
+		return fmt.Errorf("invalid latency %v: must be a positive number of milliseconds", c.Latency)
+	}
+	if c.DownloadThroughput < 0 && c.DownloadThroughput != -1 {
+		return fmt.Errorf("invalid downloadThroughput %v: must be a positive number of bytes per second, "+
+			"or -1 to disable the throttling", c.DownloadThroughput)
+	}
+	if c.UploadThroughput < 0 && c.UploadThroughput != -1 {
+		return fmt.Errorf("invalid uploadThroughput %v: must be a positive number of bytes per second, "+
+			"or -1 to disable the throttling", c.UploadThroughput)
+	}
+	return nil
+}
+
+// emulateAction returns the action that emulates the network conditions,
+// or restores the default conditions if there are no conditions. The
+// network is offline if either offline or the conditions are offline.
+func (c *NetworkConditions) emulateAction(offline bool) *network.EmulateNetworkConditionsParams {
+	if c == nil {
+		return network.EmulateNetworkConditions(offline, 0, -1, -1)
+	}
+	return network.EmulateNetworkConditions(
+		offline || c.Offline, c.Latency, c.DownloadThroughput, c.UploadThroughput)
+}
+
 // ImageFormat represents an image file format.
 type ImageFormat string
 
@@ -201,6 +311,7 @@ type ImageFormat string
 const (
 	ImageFormatJPEG ImageFormat = "jpeg"
 	ImageFormatPNG  ImageFormat = "png"
+	ImageFormatWebP ImageFormat = "webp"
 )
 
 func (f ImageFormat) String() string {
@@ -210,11 +321,54 @@ func (f ImageFormat) String() string {
 var imageFormatToString = map[ImageFormat]string{
 	ImageFormatJPEG: "jpeg",
 	ImageFormatPNG:  "png",
+	ImageFormatWebP: "webp",
 }
 
 var imageFormatToID = map[string]ImageFormat{
 	"jpeg": ImageFormatJPEG,
 	"png":  ImageFormatPNG,
+	"webp": ImageFormatWebP,
+}
+
+// parseScreenshotFormat returns the image format of a screenshot with the
+// given type option, or with the file extension of path if typ is empty,
+// falling back to def. It also returns the path with the file extension
+// of the format, if the path doesn't have an extension.
+func parseScreenshotFormat(typ, path string, def ImageFormat) (ImageFormat, string, error) {
+	format := def
+	ext := strings.ToLower(filepath.Ext(path))
+	switch {
+	case typ != "":
+		f, ok := imageFormatToID[typ]
+		if !ok {
+			return "", "", fmt.Errorf("invalid type %q: must be one of: png, jpeg, webp", typ)
+		}
+		format = f
+	case ext == ".jpg" || ext == ".jpeg":
+		format = ImageFormatJPEG
+	case ext == ".png":
+		format = ImageFormatPNG
+	case ext == ".webp":
+		format = ImageFormatWebP
+	}
+	if path != "" && ext == "" {
+		path += "." + format.String()
+	}
+
+	return format, path, nil
+}
+
+// validateScreenshotQuality returns an error if the quality option
+// is not supported by the image format or is out of range.
+func validateScreenshotQuality(format ImageFormat, quality int64) error {
+	if format == ImageFormatPNG {
+		return fmt.Errorf("quality is unsupported for %s screenshots", format)
+	}
+	if quality < 0 || quality > 100 {
+		return fmt.Errorf("quality must be between 0 and 100, got %d", quality)
+	}
+
+	return nil
 }
 
 // MarshalJSON marshals the enum as a quoted JSON string.
@@ -440,6 +594,11 @@ func (r *ReducedMotion) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// ResourceTiming is the timing of the phases of a request. StartTime is
+// the wall time of the start of the request in milliseconds since the
+// epoch. The other times are in milliseconds relative to StartTime, and
+// are -1 if the request doesn't have the phase, like the requests that
+// are served from the cache or data URLs.
 type ResourceTiming struct {
 	StartTime             float64 `js:"startTime"`
 	DomainLookupStart     float64 `js:"domainLookupStart"`
@@ -452,6 +611,53 @@ type ResourceTiming struct {
 	ResponseEnd           float64 `js:"responseEnd"`
 }
 
+// newResourceTiming returns the timing of a request that started at the
+// CDP monotonic timestamp and wall time. The timing of its response is
+// nil if the response doesn't have one, and responseEnd is relative to
+// the request time of the timing, or to the timestamp if it's nil.
+func newResourceTiming(
+	timestamp, wallTime time.Time, timing *network.ResourceTiming, responseEnd float64,
+) *ResourceTiming {
+	wallMillis := float64(wallTime.UnixNano()) / float64(time.Millisecond)
+	if timing == nil {
+		return &ResourceTiming{
+			StartTime:             wallMillis,
+			DomainLookupStart:     -1,
+			DomainLookupEnd:       -1,
+			ConnectStart:          -1,
+			SecureConnectionStart: -1,
+			ConnectEnd:            -1,
+			RequestStart:          -1,
+			ResponseStart:         -1,
+			ResponseEnd:           responseEnd,
+		}
+	}
+
+	return &ResourceTiming{
+		StartTime:             wallMillis + (timing.RequestTime-monotonicSeconds(timestamp))*1000,
+		DomainLookupStart:     timing.DNSStart,
+		DomainLookupEnd:       timing.DNSEnd,
+		ConnectStart:          timing.ConnectStart,
+		SecureConnectionStart: timing.SslStart,
+		ConnectEnd:            timing.ConnectEnd,
+		RequestStart:          timing.SendStart,
+		ResponseStart:         timing.ReceiveHeadersEnd,
+		ResponseEnd:           responseEnd,
+	}
+}
+
+// monotonicSeconds returns the seconds of a CDP monotonic time, which
+// the timings of the CDP network events are based on.
+func monotonicSeconds(t time.Time) float64 {
+	return t.Sub(*cdp.MonotonicTimeEpoch).Seconds()
+}
+
+// RequestFailure represents why a request failed.
+type RequestFailure struct {
+	ErrorText string `js:"errorText"`
+	Canceled  bool   `js:"canceled"`
+}
+
 // Screen represents a device screen.
 type Screen struct {
 	Width  int64 `js:"width"`
@@ -474,6 +680,127 @@ func (s *Screen) Parse(ctx context.Context, screen goja.Value) error {
 	return nil
 }
 
+// ScreenOrientation represents the orientation of a device screen.
+type ScreenOrientation string
+
+// Valid screen orientations. An empty orientation follows the viewport:
+// landscape if the viewport is wider than it's tall, portrait otherwise.
+const (
+	ScreenOrientationPortraitPrimary    ScreenOrientation = "portrait-primary"
+	ScreenOrientationPortraitSecondary  ScreenOrientation = "portrait-secondary"
+	ScreenOrientationLandscapePrimary   ScreenOrientation = "landscape-primary"
+	ScreenOrientationLandscapeSecondary ScreenOrientation = "landscape-secondary"
+)
+
+var screenOrientationToCDP = map[ScreenOrientation]emulation.ScreenOrientation{
+	ScreenOrientationPortraitPrimary:    {Type: emulation.OrientationTypePortraitPrimary, Angle: 0},
+	ScreenOrientationPortraitSecondary:  {Type: emulation.OrientationTypePortraitSecondary, Angle: 180},
+	ScreenOrientationLandscapePrimary:   {Type: emulation.OrientationTypeLandscapePrimary, Angle: 90},
+	ScreenOrientationLandscapeSecondary: {Type: emulation.OrientationTypeLandscapeSecondary, Angle: 270},
+}
+
+// parseScreenOrientation returns the screen orientation with the given name.
+func parseScreenOrientation(s string) (ScreenOrientation, error) {
+	o := ScreenOrientation(s)
+	if _, ok := screenOrientationToCDP[o]; !ok {
+		return "", fmt.Errorf("invalid screenOrientation %q: must be one of: "+
+			"portrait-primary, portrait-secondary, landscape-primary, landscape-secondary", s)
+	}
+	return o, nil
+}
+
+// resourceTypes are the CDP resource types by their lowercase names.
+var resourceTypes = map[string]network.ResourceType{
+	"document":           network.ResourceTypeDocument,
+	"stylesheet":         network.ResourceTypeStylesheet,
+	"image":              network.ResourceTypeImage,
+	"media":              network.ResourceTypeMedia,
+	"font":               network.ResourceTypeFont,
+	"script":             network.ResourceTypeScript,
+	"texttrack":          network.ResourceTypeTextTrack,
+	"xhr":                network.ResourceTypeXHR,
+	"fetch":              network.ResourceTypeFetch,
+	"eventsource":        network.ResourceTypeEventSource,
+	"websocket":          network.ResourceTypeWebSocket,
+	"manifest":           network.ResourceTypeManifest,
+	"signedexchange":     network.ResourceTypeSignedExchange,
+	"ping":               network.ResourceTypePing,
+	"cspviolationreport": network.ResourceTypeCSPViolationReport,
+	"preflight":          network.ResourceTypePreflight,
+	"other":              network.ResourceTypeOther,
+}
+
+// parseResourceType returns the CDP resource type with the given name.
+// The name is case-insensitive.
+func parseResourceType(s string) (network.ResourceType, error) {
+	t, ok := resourceTypes[strings.ToLower(s)]
+	if !ok {
+		names := make([]string, 0, len(resourceTypes))
+		for n := range resourceTypes {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return "", fmt.Errorf("invalid resource type %q: must be one of: %s", s, strings.Join(names, ", "))
+	}
+	return t, nil
+}
+
+// permissionTypes are the CDP permission types by their permission names.
+var permissionTypes = map[string]cdpbrowser.PermissionType{
+	"geolocation":          cdpbrowser.PermissionTypeGeolocation,
+	"midi":                 cdpbrowser.PermissionTypeMidi,
+	"midi-sysex":           cdpbrowser.PermissionTypeMidiSysex,
+	"notifications":        cdpbrowser.PermissionTypeNotifications,
+	"camera":               cdpbrowser.PermissionTypeVideoCapture,
+	"microphone":           cdpbrowser.PermissionTypeAudioCapture,
+	"background-sync":      cdpbrowser.PermissionTypeBackgroundSync,
+	"ambient-light-sensor": cdpbrowser.PermissionTypeSensors,
+	"accelerometer":        cdpbrowser.PermissionTypeSensors,
+	"gyroscope":            cdpbrowser.PermissionTypeSensors,
+	"magnetometer":         cdpbrowser.PermissionTypeSensors,
+	"accessibility-events": cdpbrowser.PermissionTypeAccessibilityEvents,
+	"clipboard-read":       cdpbrowser.PermissionTypeClipboardReadWrite,
+	"clipboard-write":      cdpbrowser.PermissionTypeClipboardSanitizedWrite,
+	"payment-handler":      cdpbrowser.PermissionTypePaymentHandler,
+}
+
+// parsePermissions returns the CDP permission types of the permission names.
+// Several names, such as the sensors, can share the same permission type.
+func parsePermissions(permissions []string) ([]cdpbrowser.PermissionType, error) {
+	var (
+		types = make([]cdpbrowser.PermissionType, 0, len(permissions))
+		seen  = make(map[cdpbrowser.PermissionType]bool)
+	)
+	for _, p := range permissions {
+		t, ok := permissionTypes[p]
+		if !ok {
+			names := make([]string, 0, len(permissionTypes))
+			for n := range permissionTypes {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("invalid permission %q: must be one of: %s", p, strings.Join(names, ", "))
+		}
+		if !seen[t] {
+			seen[t] = true
+			types = append(types, t)
+		}
+	}
+	return types, nil
+}
+
+// toCDP returns the CDP screen orientation for a page with the given viewport.
+func (o ScreenOrientation) toCDP(viewport *Viewport) *emulation.ScreenOrientation {
+	if o == "" {
+		o = ScreenOrientationPortraitPrimary
+		if viewport.Width > viewport.Height {
+			o = ScreenOrientationLandscapePrimary
+		}
+	}
+	so := screenOrientationToCDP[o]
+	return &so
+}
+
 type SelectOption struct {
 	Value *string `json:"value"`
 	Label *string `json:"label"`
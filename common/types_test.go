@@ -3,6 +3,10 @@ package common
 import (
 	"testing"
 
+	"github.com/grafana/xk6-browser/k6ext/k6test"
+
+	cdpbrowser "github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/network"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -129,3 +133,122 @@ func TestLifecycleEventUnmarshalText(t *testing.T) {
 				`must be one of: load, domcontentloaded, networkidle`)
 	})
 }
+
+func TestNetworkConditionsParse(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+
+	c := NewNetworkConditions()
+	require.NoError(t, c.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{
+		"latency": 500,
+	})))
+	assert.Equal(t, &NetworkConditions{Latency: 500, DownloadThroughput: -1, UploadThroughput: -1}, c)
+	assert.Equal(t, &network.EmulateNetworkConditionsParams{
+		Latency: 500, DownloadThroughput: -1, UploadThroughput: -1,
+	}, c.emulateAction(false))
+	assert.True(t, c.emulateAction(true).Offline, "offline mode should take precedence")
+
+	c = NewNetworkConditions()
+	require.NoError(t, c.Parse(vu.Context(), vu.ToGojaValue("Slow 3G")))
+	assert.Equal(t, &NetworkConditions{Latency: 2000, DownloadThroughput: 50000, UploadThroughput: 50000}, c)
+
+	err := NewNetworkConditions().Parse(vu.Context(), vu.ToGojaValue("Dial-up"))
+	assert.EqualError(t, err, `unknown network conditions preset "Dial-up": `+
+		`must be one of: Fast 3G, Fast 4G, Slow 3G, Slow 4G`)
+	err = NewNetworkConditions().Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{
+		"latency": -1,
+	}))
+	assert.EqualError(t, err, "invalid latency -1: must be a positive number of milliseconds")
+	err = NewNetworkConditions().Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{
+		"downloadThroughput": -2,
+	}))
+	assert.EqualError(t, err, "invalid downloadThroughput -2: must be a positive number of bytes per second, "+
+		"or -1 to disable the throttling")
+
+	var nilConditions *NetworkConditions
+	assert.Equal(t, &network.EmulateNetworkConditionsParams{
+		DownloadThroughput: -1, UploadThroughput: -1,
+	}, nilConditions.emulateAction(false))
+}
+
+func TestParsePermissions(t *testing.T) {
+	t.Parallel()
+
+	perms, err := parsePermissions([]string{"geolocation", "accelerometer", "gyroscope", "clipboard-read"})
+	require.NoError(t, err)
+	assert.Equal(t, []cdpbrowser.PermissionType{
+		cdpbrowser.PermissionTypeGeolocation,
+		cdpbrowser.PermissionTypeSensors,
+		cdpbrowser.PermissionTypeClipboardReadWrite,
+	}, perms)
+
+	perms, err = parsePermissions(nil)
+	require.NoError(t, err)
+	assert.Empty(t, perms)
+
+	_, err = parsePermissions([]string{"geolocation", "Geolocation"})
+	assert.EqualError(t, err, `invalid permission "Geolocation": must be one of: `+
+		"accelerometer, accessibility-events, ambient-light-sensor, background-sync, camera, "+
+		"clipboard-read, clipboard-write, geolocation, gyroscope, magnetometer, microphone, "+
+		"midi, midi-sysex, notifications, payment-handler")
+}
+
+func TestGeolocationParse(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name, geolocation, wantErr string
+		want                       *Geolocation
+	}{
+		{
+			name:        "valid",
+			geolocation: `({ latitude: 59.95, longitude: 30.31667, accuracy: 10 })`,
+			want:        &Geolocation{Latitude: 59.95, Longitude: 30.31667, Accuracy: 10},
+		},
+		{
+			name:        "no_accuracy",
+			geolocation: `({ latitude: -33.86, longitude: 151.2 })`,
+			want:        &Geolocation{Latitude: -33.86, Longitude: 151.2},
+		},
+		{
+			name:        "no_longitude",
+			geolocation: `({ latitude: 10 })`,
+			wantErr:     "latitude and longitude are required",
+		},
+		{
+			name:        "invalid_latitude",
+			geolocation: `({ latitude: 91, longitude: 0 })`,
+			wantErr:     `invalid latitude "91.00"`,
+		},
+		{
+			name:        "invalid_longitude",
+			geolocation: `({ latitude: 0, longitude: -181 })`,
+			wantErr:     `invalid longitude "-181.00"`,
+		},
+		{
+			name:        "invalid_accuracy",
+			geolocation: `({ latitude: 0, longitude: 0, accuracy: -1 })`,
+			wantErr:     `invalid accuracy "-1.00"`,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			vu := k6test.NewVU(t)
+			v, err := vu.Runtime().RunString(tc.geolocation)
+			require.NoError(t, err)
+
+			g := NewGeolocation()
+			err = g.Parse(vu.Context(), v)
+			if tc.wantErr != "" {
+				assert.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, g)
+		})
+	}
+}
@@ -0,0 +1,160 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package common
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/dop251/goja"
+)
+
+// urlMatcher reports whether a URL matches a URL pattern.
+type urlMatcher func(url string) bool
+
+// newURLMatcher returns a urlMatcher for the given JS value that can be:
+//   - a string that matches URLs equal to it. If it contains the glob
+//     wildcards *, ** or ?, or {a,b} alternatives, it matches URLs that
+//     match the glob pattern instead (see globToRegexp).
+//   - a RegExp object that matches URLs it matches.
+//
+// String patterns also match URLs with percent-encoded characters that
+// are equal to the pattern once decoded, and a pattern of an origin, like
+// https://example.com, matches the URL of its root path too.
+func newURLMatcher(rt *goja.Runtime, pattern goja.Value) (urlMatcher, error) {
+	if !gojaValueExists(pattern) {
+		return nil, errors.New("missing URL pattern")
+	}
+	if obj, ok := pattern.(*goja.Object); ok && obj.ClassName() == "RegExp" {
+		re, err := jsRegExpToGo(obj)
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString, nil
+	}
+
+	s := pattern.String()
+	if !strings.ContainsAny(s, "*?{") {
+		s = normalizeURLPattern(s)
+		return matchDecoded(func(url string) bool { return url == s }), nil
+	}
+	re, err := regexp.Compile(globToRegexp(s))
+	if err != nil {
+		return nil, fmt.Errorf("compiling glob pattern %q: %w", s, err)
+	}
+
+	return matchDecoded(re.MatchString), nil
+}
+
+// matchDecoded returns a urlMatcher that reports whether a URL, or the URL
+// with its percent-encoded characters decoded, matches.
+func matchDecoded(matches urlMatcher) urlMatcher {
+	return func(u string) bool {
+		if matches(u) {
+			return true
+		}
+		decoded, err := url.PathUnescape(u)
+		return err == nil && decoded != u && matches(decoded)
+	}
+}
+
+// normalizeURLPattern adds the root path to a pattern of an origin, like
+// browsers do with URLs. It returns other patterns as is.
+func normalizeURLPattern(pattern string) string {
+	u, err := url.Parse(pattern)
+	if err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" || u.Opaque != "" {
+		return pattern
+	}
+	u.Path = "/"
+
+	return u.String()
+}
+
+// globToRegexp converts a glob pattern to a regular expression that matches
+// whole strings, where:
+//   - * matches any characters except a slash.
+//   - ** matches any characters including slashes.
+//   - ? matches a single character.
+//   - {a,b} matches any of the comma separated alternatives.
+//
+// Other characters match themselves, and \ escapes the next character.
+func globToRegexp(glob string) string {
+	var (
+		b       strings.Builder
+		inGroup bool
+		chars   = []rune(glob)
+	)
+	b.WriteString("^")
+	for i := 0; i < len(chars); i++ {
+		c := chars[i]
+		switch {
+		case c == '\\' && i+1 < len(chars):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(chars[i])))
+		case c == '*' && i+1 < len(chars) && chars[i+1] == '*':
+			i++
+			b.WriteString(".*")
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString(".")
+		case c == '{' && !inGroup:
+			inGroup = true
+			b.WriteString("(?:")
+		case c == '}' && inGroup:
+			inGroup = false
+			b.WriteString(")")
+		case c == ',' && inGroup:
+			b.WriteString("|")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+
+	return b.String()
+}
+
+// jsRegExpToGo converts a JS RegExp object to a Go regular expression.
+// The i, m and s flags are supported. The others don't change whether
+// a string matches, so they are ignored.
+func jsRegExpToGo(obj *goja.Object) (*regexp.Regexp, error) {
+	source := obj.Get("source").String()
+	var flags string
+	for _, f := range obj.Get("flags").String() {
+		if strings.ContainsRune("ims", f) {
+			flags += string(f)
+		}
+	}
+	expr := source
+	if flags != "" {
+		expr = "(?" + flags + ")" + source
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("compiling regular expression /%s/: %w", source, err)
+	}
+
+	return re, nil
+}
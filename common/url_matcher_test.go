@@ -0,0 +1,156 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/grafana/xk6-browser/k6ext/k6test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestURLMatcher(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		pattern  string // JS expression
+		match    []string
+		mismatch []string
+	}{
+		{
+			name:     "exact",
+			pattern:  `"https://example.com/a?b=1"`,
+			match:    []string{"https://example.com/a?b=1"},
+			mismatch: []string{"https://example.com/a", "https://example.com/a?b=12"},
+		},
+		{
+			name:     "glob_star",
+			pattern:  `"https://example.com/*.html"`,
+			match:    []string{"https://example.com/index.html"},
+			mismatch: []string{"https://example.com/a/index.html", "https://example.com/index.htm"},
+		},
+		{
+			name:     "glob_double_star",
+			pattern:  `"**/checkout/**"`,
+			match:    []string{"https://example.com/checkout/done", "http://x/y/checkout/a/b"},
+			mismatch: []string{"https://example.com/checkout"},
+		},
+		{
+			name:     "glob_question_mark",
+			pattern:  `"**/page?"`,
+			match:    []string{"https://example.com/page1", "https://example.com/pageA"},
+			mismatch: []string{"https://example.com/page", "https://example.com/page12"},
+		},
+		{
+			name:     "glob_alternatives",
+			pattern:  `"**/*.{png,jpg}"`,
+			match:    []string{"https://example.com/a.png", "https://example.com/b/c.jpg"},
+			mismatch: []string{"https://example.com/a.gif", "https://example.com/a.png.txt"},
+		},
+		{
+			name:     "glob_escape",
+			pattern:  `"**/a\\*b"`,
+			match:    []string{"https://example.com/a*b"},
+			mismatch: []string{"https://example.com/axb"},
+		},
+		{
+			name:     "exact_origin",
+			pattern:  `"https://example.com"`,
+			match:    []string{"https://example.com/"},
+			mismatch: []string{"https://example.com/a", "https://example.com/?a=1"},
+		},
+		{
+			name:     "exact_trailing_slash",
+			pattern:  `"https://example.com/docs/"`,
+			match:    []string{"https://example.com/docs/"},
+			mismatch: []string{"https://example.com/docs"},
+		},
+		{
+			name:     "exact_encoded",
+			pattern:  `"https://example.com/a b"`,
+			match:    []string{"https://example.com/a%20b", "https://example.com/a b"},
+			mismatch: []string{"https://example.com/a%2520b"},
+		},
+		{
+			name:     "glob_query",
+			pattern:  `"**/api/*.json"`,
+			match:    []string{"https://example.com/api/items.json"},
+			mismatch: []string{"https://example.com/api/items.json?page=2", "https://example.com/api/v1/items.json"},
+		},
+		{
+			name:     "glob_query_wildcard",
+			pattern:  `"**/api/*.json*"`,
+			match:    []string{"https://example.com/api/items.json", "https://example.com/api/items.json?page=2"},
+			mismatch: []string{"https://example.com/api/items.xml", "https://example.com/api/v1/items.json?page=2"},
+		},
+		{
+			name:     "glob_trailing_slash",
+			pattern:  `"**/docs/"`,
+			match:    []string{"https://example.com/docs/"},
+			mismatch: []string{"https://example.com/docs", "https://example.com/docs/a"},
+		},
+		{
+			name:     "glob_encoded",
+			pattern:  `"**/search/café au lait"`,
+			match:    []string{"https://example.com/search/caf%C3%A9%20au%20lait"},
+			mismatch: []string{"https://example.com/search/cafe%20au%20lait"},
+		},
+		{
+			name:     "regexp",
+			pattern:  `/\/items\/\d+$/`,
+			match:    []string{"https://example.com/items/42"},
+			mismatch: []string{"https://example.com/items/abc", "https://example.com/ITEMS/42"},
+		},
+		{
+			name:     "regexp_flags",
+			pattern:  `/\/items\/\d+$/i`,
+			match:    []string{"https://example.com/ITEMS/42"},
+			mismatch: []string{"https://example.com/items/"},
+		},
+		{
+			name:     "regexp_query",
+			pattern:  `/\?q=\w+&page=\d$/`,
+			match:    []string{"https://example.com/search?q=go&page=2"},
+			mismatch: []string{"https://example.com/search?q=go&page=", "https://example.com/search#q=go&page=2"},
+		},
+		{
+			name:     "regexp_flags_combined",
+			pattern:  `/^HTTPS:\/\/example\.com\/$/im`,
+			match:    []string{"https://example.com/"},
+			mismatch: []string{"http://example.com/", "https://example.com/a"},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			vu := k6test.NewVU(t)
+			pattern, err := vu.Runtime().RunString(tc.pattern)
+			require.NoError(t, err)
+			matches, err := newURLMatcher(vu.Runtime(), pattern)
+			require.NoError(t, err)
+
+			for _, u := range tc.match {
+				assert.True(t, matches(u), "%s should match %s", tc.pattern, u)
+			}
+			for _, u := range tc.mismatch {
+				assert.False(t, matches(u), "%s should not match %s", tc.pattern, u)
+			}
+		})
+	}
+}
+
+func TestURLMatcherErrors(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+	_, err := newURLMatcher(vu.Runtime(), nil)
+	assert.EqualError(t, err, "missing URL pattern")
+
+	pattern, err := vu.Runtime().RunString(`/(?<=a)b/`)
+	require.NoError(t, err)
+	_, err = newURLMatcher(vu.Runtime(), pattern)
+	assert.ErrorContains(t, err, "compiling regular expression")
+}
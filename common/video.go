@@ -0,0 +1,307 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package common
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/grafana/xk6-browser/api"
+	"github.com/grafana/xk6-browser/k6ext"
+	"github.com/grafana/xk6-browser/log"
+
+	"github.com/chromedp/cdproto/cdp"
+	cdppage "github.com/chromedp/cdproto/page"
+	"github.com/dop251/goja"
+)
+
+const (
+	// videoFPS is the frame rate of the recorded videos.
+	videoFPS = 25
+	// videoMaxSize is the maximum width and height of the recorded
+	// videos, when their size is not set.
+	videoMaxSize = 800
+	// videoQuality is the JPEG quality of the frames of the recorded videos.
+	videoQuality = 90
+)
+
+// Ensure Video implements the api.Video interface.
+var _ api.Video = &Video{}
+
+// VideoOptions are the options of recording videos of the pages
+// of a browser context.
+type VideoOptions struct {
+	// Dir is the directory to save the videos to.
+	Dir string `js:"dir"`
+	// Size is the maximum size of the videos. It defaults to the
+	// viewport size scaled down to fit in 800x800.
+	Size *Size `js:"size"`
+}
+
+// NewVideoOptions returns the default video recording options.
+func NewVideoOptions() *VideoOptions {
+	return &VideoOptions{}
+}
+
+// Parse parses the video recording options from a JS object.
+func (o *VideoOptions) Parse(ctx context.Context, opts goja.Value) error {
+	if !gojaValueExists(opts) {
+		return nil
+	}
+	rt := k6ext.Runtime(ctx)
+	obj := opts.ToObject(rt)
+	for _, k := range obj.Keys() {
+		switch k {
+		case "dir":
+			o.Dir = obj.Get(k).String()
+			if err := validateArtifactPath(o.Dir); err != nil {
+				return err
+			}
+		case "size":
+			var s Size
+			if err := s.Parse(ctx, obj.Get(k)); err != nil {
+				return fmt.Errorf("parsing size: %w", err)
+			}
+			if s.Width <= 0 || s.Height <= 0 {
+				return fmt.Errorf("size must be positive, got %s", s)
+			}
+			o.Size = &s
+		}
+	}
+	if o.Dir == "" {
+		return errors.New("dir option must be set")
+	}
+
+	return nil
+}
+
+// videoSize returns the maximum size of the videos of a page
+// with the given viewport.
+func (o *VideoOptions) videoSize(viewport *Viewport) Size {
+	if o.Size != nil {
+		return *o.Size
+	}
+	size := Size{Width: videoMaxSize, Height: videoMaxSize}
+	if viewport == nil || viewport.Width <= 0 || viewport.Height <= 0 {
+		return size
+	}
+	scale := math.Min(1, math.Min(
+		videoMaxSize/float64(viewport.Width),
+		videoMaxSize/float64(viewport.Height),
+	))
+	size.Width = math.Floor(float64(viewport.Width) * scale)
+	size.Height = math.Floor(float64(viewport.Height) * scale)
+
+	return size
+}
+
+// Video is a video of a page that is recorded from the screencast frames of
+// the page. The video is a Motion JPEG AVI file with a constant frame rate,
+// which is finished when the page or its browser context closes.
+type Video struct {
+	path   string
+	logger *log.Logger
+
+	mu     sync.Mutex
+	writer *aviWriter
+	// the timestamp of the first frame, and the last frame that is
+	// repeated until the next frame arrives, with its timestamp and
+	// the time it arrived at.
+	start         float64
+	lastFrame     []byte
+	lastTimestamp float64
+	lastArrival   time.Time
+	err           error
+	finished      bool
+}
+
+// newVideo creates the video file of a page in the given directory.
+func newVideo(dir string, name string, size Size, logger *log.Logger) (*Video, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating videos directory: %w", err)
+	}
+	path := filepath.Join(dir, name+".avi")
+	w, err := newAVIWriter(path, videoFPS, int(size.Width), int(size.Height))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Video{
+		path:   path,
+		logger: logger,
+		writer: w,
+	}, nil
+}
+
+// Path returns the path of the video file.
+func (v *Video) Path() string {
+	return v.path
+}
+
+// addFrame adds a JPEG frame that the page rendered at the given time in
+// seconds. The previous frame is repeated until the time of the new frame.
+func (v *Video) addFrame(frame []byte, timestamp float64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.finished || v.err != nil {
+		return
+	}
+	if v.lastFrame == nil {
+		v.start = timestamp
+	} else {
+		v.err = v.repeatLastFrame(timestamp)
+	}
+	v.lastFrame = frame
+	v.lastTimestamp = timestamp
+	v.lastArrival = time.Now()
+}
+
+// repeatLastFrame writes the last frame, and repeats it until the frame at
+// the given time. The repeats only add to the index of the video, so that
+// idle pages don't grow the video file, or block the other event handlers
+// of the page while its frames are written.
+func (v *Video) repeatLastFrame(until float64) error {
+	n := int(math.Round((until - v.start) * videoFPS))
+	// a frame is written at least once.
+	if n <= v.writer.frames() {
+		n = v.writer.frames() + 1
+	}
+	if err := v.writer.writeFrame(v.lastFrame); err != nil {
+		return err
+	}
+	for v.writer.frames() < n {
+		if err := v.writer.repeatFrame(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// finish writes the last frame until now and closes the video file.
+// It can be called multiple times.
+func (v *Video) finish() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.finished {
+		return
+	}
+	v.finished = true
+	if v.lastFrame != nil && v.err == nil {
+		// the timestamps of the frames come from the clock of the browser.
+		until := v.lastTimestamp + time.Since(v.lastArrival).Seconds()
+		v.err = v.repeatLastFrame(until)
+	}
+	if err := v.writer.close(); err != nil && v.err == nil {
+		v.err = err
+	}
+	if v.err != nil {
+		v.logger.Errorf("Video:finish", "path:%q err:%v", v.path, v.err)
+	}
+}
+
+// startVideoRecording starts recording a video of the page if the
+// browser context records videos.
+func (fs *FrameSession) startVideoRecording() error {
+	opts := fs.page.browserCtx.opts.RecordVideo
+	if opts == nil {
+		return nil
+	}
+	size := opts.videoSize(fs.page.browserCtx.opts.Viewport)
+	video, err := newVideo(fs.page.artifactPath(opts.Dir), string(fs.targetID), size, fs.logger)
+	if err != nil {
+		return fmt.Errorf("recording video: %w", err)
+	}
+	fs.page.setVideo(video)
+
+	action := cdppage.StartScreencast().
+		WithFormat(cdppage.ScreencastFormatJpeg).
+		WithQuality(videoQuality).
+		WithMaxWidth(int64(size.Width)).
+		WithMaxHeight(int64(size.Height)).
+		WithEveryNthFrame(1)
+	if err := action.Do(cdp.WithExecutor(fs.ctx, fs.session)); err != nil {
+		return fmt.Errorf("starting screencast: %w", err)
+	}
+
+	return nil
+}
+
+// onScreencastFrame acknowledges a screencast frame right away, so that the
+// browser keeps sending frames, and adds it to the video of the page.
+func (fs *FrameSession) onScreencastFrame(event *cdppage.EventScreencastFrame) {
+	go func() {
+		action := cdppage.ScreencastFrameAck(event.SessionID)
+		if err := action.Do(cdp.WithExecutor(fs.ctx, fs.session)); err != nil {
+			fs.logger.Debugf("FrameSession:onScreencastFrame",
+				"sid:%v tid:%v err:%v", fs.session.ID(), fs.targetID, err)
+		}
+	}()
+
+	video := fs.page.getVideo()
+	if video == nil {
+		return
+	}
+	frame, err := base64.StdEncoding.DecodeString(event.Data)
+	if err != nil {
+		fs.logger.Debugf("FrameSession:onScreencastFrame",
+			"sid:%v tid:%v decoding frame: %v", fs.session.ID(), fs.targetID, err)
+		return
+	}
+	var timestamp float64
+	if ts := event.Metadata.Timestamp; ts != nil {
+		timestamp = float64(ts.Time().UnixNano()) / float64(time.Second)
+	} else {
+		timestamp = float64(time.Now().UnixNano()) / float64(time.Second)
+	}
+	video.addFrame(frame, timestamp)
+}
+
+// setVideo sets the video of the page.
+func (p *Page) setVideo(v *Video) {
+	p.videoMu.Lock()
+	defer p.videoMu.Unlock()
+
+	p.video = v
+}
+
+// getVideo returns the video of the page, or nil if it isn't recorded.
+func (p *Page) getVideo() *Video {
+	p.videoMu.RLock()
+	defer p.videoMu.RUnlock()
+
+	return p.video
+}
+
+// finishVideo finishes the video of the page, if it is recorded.
+func (p *Page) finishVideo() {
+	if v := p.getVideo(); v != nil {
+		v.finish()
+	}
+}
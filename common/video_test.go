@@ -0,0 +1,195 @@
+package common
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana/xk6-browser/k6ext/k6test"
+	"github.com/grafana/xk6-browser/log"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestJPEG(t *testing.T, width, height int, c color.Color) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, c)
+		}
+	}
+	var b bytes.Buffer
+	require.NoError(t, jpeg.Encode(&b, img, nil))
+
+	return b.Bytes()
+}
+
+// readAVIFrames returns the frames of an AVI file that aviWriter wrote.
+func readAVIFrames(t *testing.T, path string) (width, height uint32, frames [][]byte) {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Greater(t, len(data), aviHeaderSize)
+	assert.Equal(t, "RIFF", string(data[0:4]))
+	assert.Equal(t, uint32(len(data)-8), binary.LittleEndian.Uint32(data[aviRIFFSizeOffset:]))
+	assert.Equal(t, "AVI ", string(data[8:12]))
+	assert.Equal(t, "movi", string(data[220:224]))
+
+	total := binary.LittleEndian.Uint32(data[aviTotalFramesOffset:])
+	assert.Equal(t, total, binary.LittleEndian.Uint32(data[aviStreamLenOffset:]))
+	width = binary.LittleEndian.Uint32(data[64:])
+	height = binary.LittleEndian.Uint32(data[68:])
+
+	// the frames are read through the index, which can point at
+	// the same chunk more than once for the repeated frames.
+	moviEnd := aviMoviSizeOffset + 4 + int(binary.LittleEndian.Uint32(data[aviMoviSizeOffset:]))
+	require.Equal(t, "idx1", string(data[moviEnd:moviEnd+4]))
+	require.Equal(t, int(total)*16, int(binary.LittleEndian.Uint32(data[moviEnd+4:])))
+	for i := 0; i < int(total); i++ {
+		e := data[moviEnd+8+i*16:]
+		require.Equal(t, "00dc", string(e[0:4]))
+		off := aviHeaderSize - 4 + int(binary.LittleEndian.Uint32(e[8:]))
+		size := int(binary.LittleEndian.Uint32(e[12:]))
+		require.Less(t, off+8+size, moviEnd+1)
+		require.Equal(t, "00dc", string(data[off:off+4]))
+		require.Equal(t, size, int(binary.LittleEndian.Uint32(data[off+4:])))
+		frames = append(frames, data[off+8:off+8+size])
+	}
+
+	return width, height, frames
+}
+
+// aviChunks returns the number of frame chunks in an AVI file.
+func aviChunks(t *testing.T, path string) int {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var n int
+	moviEnd := aviMoviSizeOffset + 4 + int(binary.LittleEndian.Uint32(data[aviMoviSizeOffset:]))
+	for off := aviHeaderSize; off < moviEnd; n++ {
+		require.Equal(t, "00dc", string(data[off:off+4]))
+		size := int(binary.LittleEndian.Uint32(data[off+4:]))
+		off += 8 + size + size%2
+	}
+
+	return n
+}
+
+func TestAVIWriter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("frames", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "video.avi")
+		w, err := newAVIWriter(path, videoFPS, 800, 600)
+		require.NoError(t, err)
+
+		red := newTestJPEG(t, 40, 30, color.RGBA{R: 255, A: 255})
+		blue := newTestJPEG(t, 40, 30, color.RGBA{B: 255, A: 255})
+		require.NoError(t, w.writeFrame(red))
+		require.NoError(t, w.writeFrame(blue))
+		require.NoError(t, w.writeFrame(blue))
+		require.NoError(t, w.repeatFrame())
+		require.NoError(t, w.close())
+
+		width, height, frames := readAVIFrames(t, path)
+		assert.Equal(t, uint32(40), width, "the size of the video is the size of its frames")
+		assert.Equal(t, uint32(30), height)
+		assert.Equal(t, [][]byte{red, blue, blue, blue}, frames)
+		assert.Equal(t, 3, aviChunks(t, path), "the repeated frame should not be written again")
+	})
+
+	t.Run("repeat_no_frames", func(t *testing.T) {
+		t.Parallel()
+
+		w, err := newAVIWriter(filepath.Join(t.TempDir(), "video.avi"), videoFPS, 800, 600)
+		require.NoError(t, err)
+		assert.Error(t, w.repeatFrame())
+		require.NoError(t, w.close())
+	})
+
+	t.Run("no_frames", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "video.avi")
+		w, err := newAVIWriter(path, videoFPS, 800, 600)
+		require.NoError(t, err)
+		require.NoError(t, w.close())
+
+		width, height, frames := readAVIFrames(t, path)
+		assert.Equal(t, uint32(800), width)
+		assert.Equal(t, uint32(600), height)
+		assert.Empty(t, frames)
+	})
+}
+
+func TestVideoFrameTiming(t *testing.T) {
+	t.Parallel()
+
+	v, err := newVideo(t.TempDir(), "page", Size{Width: 800, Height: 600}, log.NewNullLogger())
+	require.NoError(t, err)
+
+	red := newTestJPEG(t, 8, 8, color.RGBA{R: 255, A: 255})
+	blue := newTestJPEG(t, 8, 8, color.RGBA{B: 255, A: 255})
+	// the red frame is shown for 200ms, which is 5 frames at 25 FPS.
+	v.addFrame(red, 100)
+	v.addFrame(blue, 100.2)
+	v.mu.Lock()
+	assert.Equal(t, 5, v.writer.frames())
+	v.mu.Unlock()
+
+	v.finish()
+	v.finish()
+	v.addFrame(red, 100.4) // ignored after finishing
+	require.NoError(t, v.err)
+
+	_, _, frames := readAVIFrames(t, v.Path())
+	require.Greater(t, len(frames), 5, "the last frame is written until the video finishes")
+	assert.Equal(t, 2, aviChunks(t, v.Path()), "each frame should be written once")
+	for i, f := range frames {
+		if i < 5 {
+			assert.Equal(t, red, f, "frame %d", i)
+		} else {
+			assert.Equal(t, blue, f, "frame %d", i)
+		}
+	}
+}
+
+func TestVideoOptionsParse(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+
+	opts := NewVideoOptions()
+	err := opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{
+		"dir":  "videos",
+		"size": map[string]interface{}{"width": 640, "height": 480},
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, "videos", opts.Dir)
+	assert.Equal(t, Size{Width: 640, Height: 480}, opts.videoSize(&Viewport{Width: 1280, Height: 720}))
+
+	opts = NewVideoOptions()
+	err = opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{"dir": "videos"}))
+	require.NoError(t, err)
+	assert.Nil(t, opts.Size)
+	assert.Equal(t, Size{Width: 800, Height: 450}, opts.videoSize(&Viewport{Width: 1280, Height: 720}),
+		"the viewport should be scaled down to fit in 800x800")
+	assert.Equal(t, Size{Width: 400, Height: 300}, opts.videoSize(&Viewport{Width: 400, Height: 300}))
+
+	opts = NewVideoOptions()
+	err = opts.Parse(vu.Context(), vu.ToGojaValue(map[string]interface{}{}))
+	assert.EqualError(t, err, "dir option must be set")
+}
@@ -0,0 +1,192 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/grafana/xk6-browser/k6ext"
+
+	"github.com/chromedp/cdproto/cdp"
+	cdpruntime "github.com/chromedp/cdproto/runtime"
+	k6metrics "go.k6.io/k6/metrics"
+)
+
+const (
+	// webVitalsBindingName is the name of the CDP binding that the pages
+	// call to report their Web Vitals.
+	webVitalsBindingName = "__xk6BrowserWebVitals__"
+	// webVitalsReportName is the name of the function that reports
+	// the Web Vitals of a document.
+	webVitalsReportName = "__xk6BrowserReportWebVitals__"
+)
+
+// webVitalsScript observes the Web Vitals of the top-level documents, and
+// reports them once per document with the binding when the document is
+// unloaded or hidden. The interaction to next paint falls back to the
+// duration of the first input in the browsers without event timing.
+var webVitalsScript = fmt.Sprintf(`(() => {
+	const binding = globalThis[%[1]q];
+	if (typeof binding !== "function" || window !== window.top || globalThis[%[2]q]) {
+		return;
+	}
+	const vitals = {};
+	const observe = (type, callback, opts) => {
+		try {
+			const observer = new PerformanceObserver((list) => list.getEntries().forEach(callback));
+			observer.observe({ type, buffered: true, ...opts });
+			return true;
+		} catch (e) {
+			// the entry type isn't supported.
+			return false;
+		}
+	};
+	observe("largest-contentful-paint", (e) => {
+		vitals.lcp = e.renderTime || e.loadTime || e.startTime;
+	});
+	// the largest session window of layout shifts, as in the CLS definition.
+	let session = { value: 0, first: 0, last: 0 };
+	const layoutShifts = observe("layout-shift", (e) => {
+		if (e.hadRecentInput) {
+			return;
+		}
+		if (session.value > 0 && e.startTime - session.last < 1000 && e.startTime - session.first < 5000) {
+			session.value += e.value;
+		} else {
+			session = { value: e.value, first: e.startTime };
+		}
+		session.last = e.startTime;
+		vitals.cls = Math.max(vitals.cls || 0, session.value);
+	});
+	if (layoutShifts) {
+		vitals.cls = vitals.cls || 0;
+	}
+	observe("first-input", (e) => {
+		vitals.inp = Math.max(vitals.inp || 0, e.duration);
+	});
+	observe("event", (e) => {
+		if (e.interactionId) {
+			vitals.inp = Math.max(vitals.inp || 0, e.duration);
+		}
+	}, { durationThreshold: 16 });
+
+	let reported = false;
+	const report = (send) => {
+		if (reported) {
+			return "";
+		}
+		reported = true;
+		const payload = JSON.stringify({ url: location.href, ...vitals });
+		if (send) {
+			binding(payload);
+		}
+		return payload;
+	};
+	Object.defineProperty(globalThis, %[2]q, { value: report });
+	addEventListener("pagehide", () => report(true), { capture: true });
+	addEventListener("visibilitychange", () => {
+		if (document.visibilityState === "hidden") {
+			report(true);
+		}
+	}, { capture: true });
+})();`, webVitalsBindingName, webVitalsReportName)
+
+// webVitalsReportScript returns the Web Vitals of the document
+// if they haven't been reported yet.
+var webVitalsReportScript = fmt.Sprintf(`() => {
+	const report = globalThis[%q];
+	return typeof report === "function" ? report(false) : "";
+}`, webVitalsReportName)
+
+// webVitals are the Web Vitals of a document. The largest contentful
+// paint and the interaction to next paint are in milliseconds.
+type webVitals struct {
+	URL string   `json:"url"`
+	LCP *float64 `json:"lcp"`
+	CLS *float64 `json:"cls"`
+	INP *float64 `json:"inp"`
+}
+
+// samples returns the values of the Web Vitals of the
+// document by their metrics, skipping the missing ones.
+func (v *webVitals) samples(m *k6ext.CustomMetrics) map[*k6metrics.Metric]float64 {
+	samples := make(map[*k6metrics.Metric]float64)
+	if v.LCP != nil {
+		samples[m.BrowserWebVitalLCP] = *v.LCP
+	}
+	if v.CLS != nil {
+		samples[m.BrowserWebVitalCLS] = *v.CLS
+	}
+	if v.INP != nil {
+		samples[m.BrowserWebVitalINP] = *v.INP
+	}
+	return samples
+}
+
+// initWebVitals makes the new top-level documents of the frame session
+// report their Web Vitals, unless the Web Vitals metrics are disabled.
+func (fs *FrameSession) initWebVitals() error {
+	if !fs.k6Metrics.Enabled(k6ext.MetricFamilyWebVitals) {
+		return nil
+	}
+	if err := cdpruntime.AddBinding(webVitalsBindingName).Do(cdp.WithExecutor(fs.ctx, fs.session)); err != nil {
+		return fmt.Errorf("adding Web Vitals binding: %w", err)
+	}
+	return fs.addScriptToEvaluateOnNewDocument(webVitalsScript)
+}
+
+// emitWebVitals emits the Web Vitals that a document of the page reported.
+// It must be called on the event loop if onEventLoop is set.
+func (p *Page) emitWebVitals(payload string, onEventLoop bool) {
+	if payload == "" || p.k6Metrics == nil || !p.k6Metrics.Enabled(k6ext.MetricFamilyWebVitals) {
+		return
+	}
+	var v webVitals
+	if err := json.Unmarshal([]byte(payload), &v); err != nil {
+		p.logger.Debugf("Page:emitWebVitals", "sid:%v err:%v", p.sessionID(), err)
+		return
+	}
+	p.logger.Debugf("Page:emitWebVitals", "sid:%v url:%q", p.sessionID(), v.URL)
+
+	p.browserCtx.pushPageMetrics(p.metricTags(), v.URL, v.samples(p.k6Metrics), onEventLoop)
+}
+
+// reportWebVitals emits the Web Vitals of the current document of the page,
+// as the document doesn't report them when the page closes without
+// unloading it, such as when its browser context closes.
+func (p *Page) reportWebVitals() {
+	f := p.frameManager.MainFrame()
+	if f == nil || f.IsDetached() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(p.ctx, p.timeoutSettings.timeout())
+	defer cancel()
+	opts := evalOptions{forceCallable: true, returnByValue: true}
+	result, err := f.evaluate(ctx, mainWorld, opts, p.vu.Runtime().ToValue(webVitalsReportScript))
+	if err != nil {
+		// the page may be crashed or navigating.
+		p.logger.Debugf("Page:reportWebVitals", "sid:%v err:%v", p.sessionID(), err)
+		return
+	}
+	p.emitWebVitals(asGojaValue(p.ctx, result).String(), true)
+}
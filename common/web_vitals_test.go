@@ -0,0 +1,24 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/grafana/xk6-browser/k6ext/k6test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebVitalsScripts(t *testing.T) {
+	t.Parallel()
+
+	// the scripts must be valid JavaScript, and the observer script
+	// must not run without the binding.
+	vu := k6test.NewVU(t)
+	_, err := vu.Runtime().RunString(webVitalsScript)
+	require.NoError(t, err)
+	v, err := vu.Runtime().RunString(`(` + webVitalsReportScript + `)()`)
+	require.NoError(t, err)
+	assert.Equal(t, "", v.String())
+	assert.Nil(t, vu.Runtime().Get(webVitalsReportName))
+}
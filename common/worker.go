@@ -22,11 +22,16 @@ package common
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/grafana/xk6-browser/api"
+	"github.com/grafana/xk6-browser/k6ext"
 
+	"github.com/chromedp/cdproto"
 	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/emulation"
 	"github.com/chromedp/cdproto/log"
 	"github.com/chromedp/cdproto/network"
 	"github.com/chromedp/cdproto/runtime"
@@ -43,19 +48,28 @@ type Worker struct {
 
 	ctx     context.Context
 	session session
+	page    *Page
 
 	targetID target.ID
 	url      string
+
+	// execCtx is the execution context of the worker, which is
+	// created after the worker starts. execCtxReady is closed then.
+	execCtxMu    sync.RWMutex
+	execCtx      *ExecutionContext
+	execCtxReady chan struct{}
 }
 
-// NewWorker creates a new page viewport.
-func NewWorker(ctx context.Context, s session, id target.ID, url string) (*Worker, error) {
+// NewWorker creates a new web worker of the page.
+func NewWorker(ctx context.Context, s session, p *Page, id target.ID, url string) (*Worker, error) {
 	w := Worker{
 		BaseEventEmitter: NewBaseEventEmitter(ctx),
 		ctx:              ctx,
 		session:          s,
+		page:             p,
 		targetID:         id,
 		url:              url,
+		execCtxReady:     make(chan struct{}),
 	}
 	if err := w.initEvents(); err != nil {
 		return nil, err
@@ -69,11 +83,54 @@ func (w *Worker) didClose() {
 }
 
 func (w *Worker) initEvents() error {
+	events := make(chan Event)
+	w.session.on(w.ctx, []string{
+		cdproto.EventRuntimeConsoleAPICalled,
+		cdproto.EventRuntimeExecutionContextCreated,
+	}, events)
+	go func() {
+		for {
+			select {
+			case <-w.ctx.Done():
+				return
+			case <-w.session.Done():
+				return
+			case event := <-events:
+				switch ev := event.data.(type) {
+				case *runtime.EventConsoleAPICalled:
+					w.onConsoleAPICalled(ev)
+				case *runtime.EventExecutionContextCreated:
+					w.onExecutionContextCreated(ev)
+				}
+			}
+		}
+	}()
+
 	actions := []Action{
 		log.Enable(),
 		network.Enable(),
-		runtime.RunIfWaitingForDebugger(),
 	}
+	// throttle the requests of the worker as the requests of its page,
+	// and put it offline with the page.
+	if w.page != nil {
+		if action, ok := w.page.workerNetworkConditionsAction(); ok {
+			actions = append(actions, action)
+		}
+	}
+	// the workers don't have the emulation domain, so
+	// their user agent is overridden with the network domain.
+	if w.page != nil && w.page.browserCtx.opts.hasUserAgentOverride() {
+		opts := w.page.browserCtx.opts
+		actions = append(actions, networkUserAgentOverride{
+			emulation.SetUserAgentOverride(opts.UserAgent).
+				WithAcceptLanguage(opts.Locale).
+				WithPlatform(opts.Platform),
+		})
+	}
+	actions = append(actions,
+		runtime.Enable(),
+		runtime.RunIfWaitingForDebugger(),
+	)
 	for _, action := range actions {
 		if err := action.Do(cdp.WithExecutor(w.ctx, w.session)); err != nil {
 			return fmt.Errorf("protocol error while initializing worker %T: %w", action, err)
@@ -82,16 +139,91 @@ func (w *Worker) initEvents() error {
 	return nil
 }
 
+// onConsoleAPICalled passes the console API calls of the worker to its page.
+// networkUserAgentOverride overrides the user agent with
+// Network.setUserAgentOverride, which has the same parameters as
+// Emulation.setUserAgentOverride, but that the workers support.
+type networkUserAgentOverride struct {
+	*emulation.SetUserAgentOverrideParams
+}
+
+// Do executes Network.setUserAgentOverride against the provided context.
+func (a networkUserAgentOverride) Do(ctx context.Context) error {
+	return cdp.Execute(ctx, "Network.setUserAgentOverride", a.SetUserAgentOverrideParams, nil) //nolint:wrapcheck
+}
+
+func (w *Worker) onConsoleAPICalled(event *runtime.EventConsoleAPICalled) {
+	if w.page == nil {
+		return
+	}
+	w.execCtxMu.RLock()
+	ec := w.execCtx
+	w.execCtxMu.RUnlock()
+	if ec == nil || ec.ID() != event.ExecutionContextID {
+		ec = NewExecutionContext(w.ctx, w.session, nil, event.ExecutionContextID, w.page.logger)
+	}
+	w.page.onConsoleAPICalled(ec, w, event)
+}
+
+// onExecutionContextCreated sets the execution context of the worker.
+// A worker has only one execution context.
+func (w *Worker) onExecutionContextCreated(event *runtime.EventExecutionContextCreated) {
+	w.execCtxMu.Lock()
+	defer w.execCtxMu.Unlock()
+
+	if w.execCtx != nil {
+		return
+	}
+	w.execCtx = NewExecutionContext(w.ctx, w.session, nil, event.Context.ID, w.page.logger)
+	close(w.execCtxReady)
+}
+
+// executionContext waits for the execution context of the worker
+// until the default timeout of its page.
+func (w *Worker) executionContext() (*ExecutionContext, error) {
+	timeout := w.page.defaultTimeout()
+	select {
+	case <-w.execCtxReady:
+	case <-w.ctx.Done():
+		return nil, fmt.Errorf("waiting for the worker to start: %w", w.ctx.Err())
+	case <-w.session.Done():
+		return nil, errors.New("worker has been closed")
+	case <-timeoutAfter(timeout):
+		return nil, fmt.Errorf("%w after %s waiting for the worker to start", ErrTimedOut, timeout)
+	}
+
+	w.execCtxMu.RLock()
+	defer w.execCtxMu.RUnlock()
+
+	return w.execCtx, nil
+}
+
 // Evaluate evaluates a page function in the context of the web worker.
 func (w *Worker) Evaluate(pageFunc goja.Value, args ...goja.Value) interface{} {
-	// TODO: implement
-	return nil
+	ec, err := w.executionContext()
+	if err != nil {
+		k6ext.Panic(w.ctx, "evaluating in worker: %w", err)
+	}
+	res, err := ec.Eval(w.ctx, pageFunc, args...)
+	if err != nil {
+		k6ext.Panic(w.ctx, "evaluating in worker: %w", err)
+	}
+
+	return res
 }
 
 // EvaluateHandle evaluates a page function in the context of the web worker and returns a JS handle.
 func (w *Worker) EvaluateHandle(pageFunc goja.Value, args ...goja.Value) api.JSHandle {
-	// TODO: implement
-	return nil
+	ec, err := w.executionContext()
+	if err != nil {
+		k6ext.Panic(w.ctx, "evaluating handle in worker: %w", err)
+	}
+	h, err := ec.EvalHandle(w.ctx, pageFunc, args...)
+	if err != nil {
+		k6ext.Panic(w.ctx, "evaluating handle in worker: %w", err)
+	}
+
+	return h
 }
 
 // URL returns the URL of the web worker.
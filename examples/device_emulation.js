@@ -6,7 +6,8 @@ export default function() {
     headless: __ENV.XK6_HEADLESS ? true : false,
   });
 
-  const device = k6b.devices['iPhone X'];
+  // devices.get throws for unknown device names, and suggests similar ones.
+  const device = k6b.devices.get('iPhone X');
   // The spread operator is currently unsupported by k6's Babel, so use
   // Object.assign instead to merge browser context and device options.
   // See https://github.com/grafana/k6/issues/2296
@@ -0,0 +1,18 @@
+import launcher from 'k6/x/browser';
+
+export default function() {
+  const browser = launcher.launch('chromium', {
+    headless: __ENV.XK6_HEADLESS ? true : false,
+  });
+  const context = browser.newContext();
+  const page = context.newPage();
+
+  // Runs when the iteration ends, even if it throws or the test is aborted.
+  launcher.onIterationEnd(() => {
+    page.screenshot({ path: 'teardown.png' });
+    page.close();
+    browser.close();
+  }, { timeout: 5000 });
+
+  page.goto('https://test.k6.io/');
+}
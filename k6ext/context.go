@@ -2,6 +2,7 @@ package k6ext
 
 import (
 	"context"
+	"sync/atomic"
 
 	k6modules "go.k6.io/k6/js/modules"
 
@@ -14,6 +15,8 @@ const (
 	ctxKeyVU ctxKey = iota
 	ctxKeyPid
 	ctxKeyCustomK6Metrics
+	ctxKeyKillOnError
+	ctxKeySharedBrowser
 )
 
 // WithVU returns a new context based on ctx with the k6 VU instance attached.
@@ -35,13 +38,53 @@ func GetVU(ctx context.Context) k6modules.VU {
 
 // WithProcessID saves the browser process ID to the context.
 func WithProcessID(ctx context.Context, pid int) context.Context {
-	return context.WithValue(ctx, ctxKeyPid, pid)
+	v := new(int64)
+	*v = int64(pid)
+	return context.WithValue(ctx, ctxKeyPid, v)
+}
+
+// SetProcessID replaces the browser process ID in the context, such as
+// when the browser is relaunched. It does nothing if the context has no
+// process ID.
+func SetProcessID(ctx context.Context, pid int) {
+	if v, ok := ctx.Value(ctxKeyPid).(*int64); ok {
+		atomic.StoreInt64(v, int64(pid))
+	}
 }
 
 // GetProcessID returns the browser process ID from the context.
 func GetProcessID(ctx context.Context) int {
-	v, _ := ctx.Value(ctxKeyPid).(int)
-	return v // it will return zero on error
+	v, ok := ctx.Value(ctxKeyPid).(*int64)
+	if !ok {
+		return 0
+	}
+	return int(atomic.LoadInt64(v))
+}
+
+// WithKillOnError returns a new context based on ctx in which Panic kills
+// the browser process on every error, instead of only on the fatal errors.
+func WithKillOnError(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ctxKeyKillOnError, true)
+}
+
+// killOnError reports whether ctx is derived from WithKillOnError.
+func killOnError(ctx context.Context) bool {
+	kill, _ := ctx.Value(ctxKeyKillOnError).(bool)
+	return kill
+}
+
+// WithSharedBrowser returns a new context based on ctx in which Panic
+// doesn't kill the browser process on fatal errors, since the browser is
+// shared across the iterations of a VU, which replace it once it
+// disconnects.
+func WithSharedBrowser(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ctxKeySharedBrowser, true)
+}
+
+// sharedBrowser reports whether ctx is derived from WithSharedBrowser.
+func sharedBrowser(ctx context.Context) bool {
+	shared, _ := ctx.Value(ctxKeySharedBrowser).(bool)
+	return shared
 }
 
 // WithCustomMetrics attaches the CustomK6Metrics object to the context.
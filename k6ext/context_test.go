@@ -0,0 +1,27 @@
+package k6ext_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/xk6-browser/k6ext"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessID(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	assert.Zero(t, k6ext.GetProcessID(ctx))
+	k6ext.SetProcessID(ctx, 42)
+	assert.Zero(t, k6ext.GetProcessID(ctx), "should not set a missing process ID")
+
+	ctx = k6ext.WithProcessID(ctx, 42)
+	child, cancel := context.WithCancel(ctx)
+	defer cancel()
+	assert.Equal(t, 42, k6ext.GetProcessID(child))
+
+	k6ext.SetProcessID(child, 43)
+	assert.Equal(t, 43, k6ext.GetProcessID(ctx), "should replace the process ID of all the derived contexts")
+}
@@ -1,29 +1,178 @@
 package k6ext
 
-import k6metrics "go.k6.io/k6/metrics"
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	k6metrics "go.k6.io/k6/metrics"
+)
+
+// The families of the custom metrics that can be disabled.
+const (
+	// MetricFamilyNavigation are the metrics of the page loads,
+	// such as browser_loaded and browser_navigation_ttfb.
+	MetricFamilyNavigation = "navigation"
+	// MetricFamilyRequests are the metrics of the requests of the
+	// pages, such as http_req_duration and browser_failed_requests.
+	MetricFamilyRequests = "requests"
+	// MetricFamilyWebVitals are the Web Vitals of the pages,
+	// such as browser_web_vital_lcp.
+	MetricFamilyWebVitals = "webvitals"
+)
+
+// The environment variables of the metrics options.
+const (
+	metricsPrefixEnv  = "XK6_BROWSER_METRICS_PREFIX"
+	metricsDisableEnv = "XK6_BROWSER_METRICS_DISABLE"
+)
+
+// MetricsOptions control the names of the custom metrics,
+// and which of their families are registered and emitted.
+type MetricsOptions struct {
+	// Prefix is prepended to the names of the custom metrics.
+	Prefix string
+	// Disabled are the metric families that aren't emitted.
+	Disabled map[string]bool
+}
+
+// ParseMetricsOptions parses the metrics options from the environment
+// variables that lookupEnv returns:
+//   - XK6_BROWSER_METRICS_PREFIX is prepended to the metric names.
+//   - XK6_BROWSER_METRICS_DISABLE is a comma-separated list of the
+//     metric families to disable: navigation, requests and webvitals.
+func ParseMetricsOptions(lookupEnv func(string) (string, bool)) (*MetricsOptions, error) {
+	opts := &MetricsOptions{
+		Disabled: make(map[string]bool),
+	}
+	if v, ok := lookupEnv(metricsPrefixEnv); ok {
+		opts.Prefix = strings.TrimSpace(v)
+	}
+	v, ok := lookupEnv(metricsDisableEnv)
+	if !ok {
+		return opts, nil
+	}
+	for _, family := range strings.Split(v, ",") {
+		family = strings.ToLower(strings.TrimSpace(family))
+		switch family {
+		case "":
+		case MetricFamilyNavigation, MetricFamilyRequests, MetricFamilyWebVitals:
+			opts.Disabled[family] = true
+		default:
+			return nil, fmt.Errorf(
+				"invalid metric family %q in %s: must be one of: %s, %s, %s", family, metricsDisableEnv,
+				MetricFamilyNavigation, MetricFamilyRequests, MetricFamilyWebVitals)
+		}
+	}
+	return opts, nil
+}
+
+// MetricsOptionsFromEnv returns the metrics options
+// of the environment variables of the process.
+func MetricsOptionsFromEnv() (*MetricsOptions, error) {
+	return ParseMetricsOptions(os.LookupEnv)
+}
 
 // CustomMetrics are the custom k6 metrics used by xk6-browser.
+// The metrics of the disabled families are nil.
 type CustomMetrics struct {
 	BrowserDOMContentLoaded     *k6metrics.Metric
 	BrowserFirstPaint           *k6metrics.Metric
 	BrowserFirstContentfulPaint *k6metrics.Metric
 	BrowserFirstMeaningfulPaint *k6metrics.Metric
 	BrowserLoaded               *k6metrics.Metric
+	BrowserBlockedRequests      *k6metrics.Metric
+	BrowserFailedRequests       *k6metrics.Metric
+	BrowserWebVitalLCP          *k6metrics.Metric
+	BrowserWebVitalCLS          *k6metrics.Metric
+	BrowserWebVitalINP          *k6metrics.Metric
+
+	BrowserNavigationTTFB             *k6metrics.Metric
+	BrowserNavigationFCP              *k6metrics.Metric
+	BrowserNavigationDOMContentLoaded *k6metrics.Metric
+	BrowserNavigationLoadTime         *k6metrics.Metric
+
+	// BrowserResetDuration is the duration of resetting the page
+	// that's reused across iterations with the reusePage option.
+	BrowserResetDuration *k6metrics.Metric
+
+	disabled map[string]bool
 }
 
 // RegisterCustomMetrics creates and registers our custom metrics with the k6
 // VU Registry and returns our internal struct pointer.
 func RegisterCustomMetrics(registry *k6metrics.Registry) *CustomMetrics {
-	return &CustomMetrics{
-		BrowserDOMContentLoaded: registry.MustNewMetric(
-			"browser_dom_content_loaded", k6metrics.Trend, k6metrics.Time),
-		BrowserFirstPaint: registry.MustNewMetric(
-			"browser_first_paint", k6metrics.Trend, k6metrics.Time),
-		BrowserFirstContentfulPaint: registry.MustNewMetric(
-			"browser_first_contentful_paint", k6metrics.Trend, k6metrics.Time),
-		BrowserFirstMeaningfulPaint: registry.MustNewMetric(
-			"browser_first_meaningful_paint", k6metrics.Trend, k6metrics.Time),
-		BrowserLoaded: registry.MustNewMetric(
-			"browser_loaded", k6metrics.Trend, k6metrics.Time),
+	m, err := RegisterCustomMetricsWithOptions(registry, &MetricsOptions{})
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// RegisterCustomMetricsWithOptions registers the custom metrics of the
+// enabled families with the k6 VU Registry, with the prefix of the options.
+// The metrics of the disabled families are never registered.
+func RegisterCustomMetricsWithOptions(registry *k6metrics.Registry, opts *MetricsOptions) (*CustomMetrics, error) {
+	var (
+		m   = &CustomMetrics{disabled: opts.Disabled}
+		err error
+	)
+	register := func(name string, typ k6metrics.MetricType, t ...k6metrics.ValueType) *k6metrics.Metric {
+		if err != nil {
+			return nil
+		}
+		var metric *k6metrics.Metric
+		if metric, err = registry.NewMetric(opts.Prefix+name, typ, t...); err != nil {
+			err = fmt.Errorf("registering metric %q: %w", opts.Prefix+name, err)
+		}
+		return metric
+	}
+
+	if m.Enabled(MetricFamilyNavigation) {
+		m.BrowserDOMContentLoaded = register(
+			"browser_dom_content_loaded", k6metrics.Trend, k6metrics.Time)
+		m.BrowserFirstPaint = register(
+			"browser_first_paint", k6metrics.Trend, k6metrics.Time)
+		m.BrowserFirstContentfulPaint = register(
+			"browser_first_contentful_paint", k6metrics.Trend, k6metrics.Time)
+		m.BrowserFirstMeaningfulPaint = register(
+			"browser_first_meaningful_paint", k6metrics.Trend, k6metrics.Time)
+		m.BrowserLoaded = register(
+			"browser_loaded", k6metrics.Trend, k6metrics.Time)
+		m.BrowserNavigationTTFB = register(
+			"browser_navigation_ttfb", k6metrics.Trend, k6metrics.Time)
+		m.BrowserNavigationFCP = register(
+			"browser_navigation_fcp", k6metrics.Trend, k6metrics.Time)
+		m.BrowserNavigationDOMContentLoaded = register(
+			"browser_navigation_dom_content_loaded", k6metrics.Trend, k6metrics.Time)
+		m.BrowserNavigationLoadTime = register(
+			"browser_navigation_load_time", k6metrics.Trend, k6metrics.Time)
 	}
+	if m.Enabled(MetricFamilyRequests) {
+		m.BrowserBlockedRequests = register(
+			"browser_blocked_requests", k6metrics.Counter)
+		m.BrowserFailedRequests = register(
+			"browser_failed_requests", k6metrics.Counter)
+	}
+	if m.Enabled(MetricFamilyWebVitals) {
+		m.BrowserWebVitalLCP = register(
+			"browser_web_vital_lcp", k6metrics.Trend, k6metrics.Time)
+		m.BrowserWebVitalCLS = register(
+			"browser_web_vital_cls", k6metrics.Trend)
+		m.BrowserWebVitalINP = register(
+			"browser_web_vital_inp", k6metrics.Trend, k6metrics.Time)
+	}
+	m.BrowserResetDuration = register(
+		"browser_reset_duration", k6metrics.Trend, k6metrics.Time)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Enabled reports whether the metrics of a family are emitted.
+// All the families are enabled if there are no custom metrics.
+func (m *CustomMetrics) Enabled(family string) bool {
+	return m == nil || !m.disabled[family]
 }
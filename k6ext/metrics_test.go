@@ -0,0 +1,79 @@
+package k6ext_test
+
+import (
+	"testing"
+
+	"github.com/grafana/xk6-browser/k6ext"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	k6metrics "go.k6.io/k6/metrics"
+)
+
+func TestParseMetricsOptions(t *testing.T) {
+	t.Parallel()
+
+	env := func(vars map[string]string) func(string) (string, bool) {
+		return func(k string) (string, bool) {
+			v, ok := vars[k]
+			return v, ok
+		}
+	}
+
+	opts, err := k6ext.ParseMetricsOptions(env(nil))
+	require.NoError(t, err)
+	assert.Empty(t, opts.Prefix)
+	assert.Empty(t, opts.Disabled)
+
+	opts, err = k6ext.ParseMetricsOptions(env(map[string]string{
+		"XK6_BROWSER_METRICS_PREFIX":  "shop_",
+		"XK6_BROWSER_METRICS_DISABLE": "webvitals, Requests,",
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, "shop_", opts.Prefix)
+	assert.Equal(t, map[string]bool{"webvitals": true, "requests": true}, opts.Disabled)
+
+	_, err = k6ext.ParseMetricsOptions(env(map[string]string{
+		"XK6_BROWSER_METRICS_DISABLE": "vitals",
+	}))
+	assert.ErrorContains(t, err, `invalid metric family "vitals"`)
+}
+
+func TestRegisterCustomMetricsWithOptions(t *testing.T) {
+	t.Parallel()
+
+	t.Run("prefix", func(t *testing.T) {
+		t.Parallel()
+
+		registry := k6metrics.NewRegistry()
+		m, err := k6ext.RegisterCustomMetricsWithOptions(registry, &k6ext.MetricsOptions{Prefix: "shop_"})
+		require.NoError(t, err)
+		require.NotNil(t, m.BrowserWebVitalLCP)
+		assert.Equal(t, "shop_browser_web_vital_lcp", m.BrowserWebVitalLCP.Name)
+		assert.Same(t, m.BrowserWebVitalLCP, registry.Get("shop_browser_web_vital_lcp"))
+		assert.Nil(t, registry.Get("browser_web_vital_lcp"))
+	})
+	t.Run("invalid_prefix", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := k6ext.RegisterCustomMetricsWithOptions(
+			k6metrics.NewRegistry(), &k6ext.MetricsOptions{Prefix: "shop*"})
+		assert.ErrorContains(t, err, `registering metric "shop*browser_dom_content_loaded"`)
+	})
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+
+		registry := k6metrics.NewRegistry()
+		m, err := k6ext.RegisterCustomMetricsWithOptions(registry, &k6ext.MetricsOptions{
+			Disabled: map[string]bool{k6ext.MetricFamilyWebVitals: true},
+		})
+		require.NoError(t, err)
+		assert.False(t, m.Enabled(k6ext.MetricFamilyWebVitals))
+		assert.True(t, m.Enabled(k6ext.MetricFamilyNavigation))
+		assert.True(t, m.Enabled(k6ext.MetricFamilyRequests))
+		assert.Nil(t, m.BrowserWebVitalLCP)
+		assert.Nil(t, registry.Get("browser_web_vital_lcp"))
+		assert.NotNil(t, registry.Get("browser_navigation_ttfb"))
+		assert.NotNil(t, registry.Get("browser_failed_requests"))
+	})
+}
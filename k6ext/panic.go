@@ -2,37 +2,110 @@ package k6ext
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"strconv"
+
+	"github.com/grafana/xk6-browser/api"
 
 	k6common "go.k6.io/k6/js/common"
+
+	"github.com/dop251/goja"
 )
 
-// Panic will cause a panic with the given error which will shut
-// the application down. Before panicking, it will find the
-// browser process from the context and kill it if it still exists.
-// TODO: test.
+// killOnErrorEnv is the environment variable that restores killing the
+// browser process on every error that Panic throws.
+const killOnErrorEnv = "XK6_BROWSER_KILL_ON_ERROR"
+
+// fatalError is implemented by the errors after which the browser can't
+// be used anymore, such as a lost connection.
+type fatalError interface {
+	Fatal() bool
+}
+
+// Panic will cause a panic with the given error, which the script can
+// catch. If the error is fatal, such as when the connection to the
+// browser is lost, it will find the browser process from the context
+// and kill it before panicking, if it still exists, unless the browser
+// is connected to instead of launched, or the context is derived from
+// WithSharedBrowser. The process is killed on every error if the context
+// is derived from WithKillOnError.
 func Panic(ctx context.Context, format string, a ...interface{}) {
 	rt := Runtime(ctx)
 	if rt == nil {
 		// this should never happen unless a programmer error
 		panic("no k6 JS runtime in context")
 	}
-	defer k6common.Throw(rt, fmt.Errorf(format, a...))
+	err := fmt.Errorf(format, a...)
+	defer throw(rt, err)
 
+	if !killOnError(ctx) && (!IsFatal(err) || sharedBrowser(ctx)) {
+		// leave the browser running for the rest of the iteration,
+		// such as for taking a screenshot of the failure. The shared
+		// browser is replaced in the next iteration if it disconnected.
+		return
+	}
 	pid := GetProcessID(ctx)
 	if pid == 0 {
-		// this should never happen unless a programmer error
-		panic("no browser process ID in context")
+		// the browser process isn't launched by the extension, such as
+		// when it's connected with connect(), so leave it running.
+		return
 	}
 	p, err := os.FindProcess(pid)
 	if err != nil {
 		// optimistically return and don't kill the process
 		return
 	}
-	// no need to check the error for waiting the process to release
-	// its resources or whether we could kill it as we're already
-	// dying.
-	_ = p.Release()
+	// no need to check whether we could kill the process or the error
+	// for releasing its resources as we're already dying. The process
+	// must be killed before it's released, which invalidates it.
 	_ = p.Kill()
+	_ = p.Release()
+}
+
+// throw throws err as a JS exception. The errors of the browser API are
+// thrown as JS errors with their names, such as TimeoutError, which scripts
+// can tell apart with e.name, and with their fields, such as the stack
+// frames of an EvaluationError, in e.details. Like other Go errors, the
+// error that they wrap is in e.value.
+func throw(rt *goja.Runtime, err error) {
+	apiErr := api.AsError(err)
+	if apiErr == nil {
+		k6common.Throw(rt, err)
+	}
+	e := rt.NewGoError(err)
+	_ = e.Set("name", apiErr.Name())
+	_ = e.Set("details", apiErr)
+	panic(e)
+}
+
+// IsFatal reports whether the browser can't be used anymore after the
+// error, which is the case if an error in its chain reports it as fatal
+// with a Fatal method.
+func IsFatal(err error) bool {
+	var fe fatalError
+	return errors.As(err, &fe) && fe.Fatal()
+}
+
+// ParseKillOnError parses whether Panic kills the browser process on
+// every error, instead of only on the fatal errors, from the
+// XK6_BROWSER_KILL_ON_ERROR environment variable that lookupEnv returns.
+// It enables the killOnError launch option for every launched browser.
+func ParseKillOnError(lookupEnv func(string) (string, bool)) (bool, error) {
+	v, ok := lookupEnv(killOnErrorEnv)
+	if !ok || v == "" {
+		return false, nil
+	}
+	kill, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s: %q must be a boolean", killOnErrorEnv, v)
+	}
+	return kill, nil
+}
+
+// KillOnErrorFromEnv returns whether Panic kills the browser process on
+// every error, from the environment variables of the process.
+func KillOnErrorFromEnv() (bool, error) {
+	return ParseKillOnError(os.LookupEnv)
 }
@@ -0,0 +1,209 @@
+package k6ext_test
+
+import (
+	"errors"
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/grafana/xk6-browser/api"
+	"github.com/grafana/xk6-browser/k6ext"
+	"github.com/grafana/xk6-browser/k6ext/k6test"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fatalErr struct{ fatal bool }
+
+func (e fatalErr) Error() string { return "fatal error" }
+func (e fatalErr) Fatal() bool   { return e.fatal }
+
+func TestPanic(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("the test process is a sleep command")
+	}
+
+	testCases := []struct {
+		name        string
+		err         error
+		killOnError bool
+		shared      bool
+		wantKill    bool
+	}{
+		{name: "recoverable", err: errors.New("timed out")},
+		{name: "fatal", err: fatalErr{fatal: true}, wantKill: true},
+		{name: "not_fatal", err: fatalErr{fatal: false}},
+		{name: "kill_on_error", err: errors.New("timed out"), killOnError: true, wantKill: true},
+		{name: "shared_fatal", err: fatalErr{fatal: true}, shared: true},
+		{name: "shared_kill_on_error", err: errors.New("timed out"), shared: true, killOnError: true, wantKill: true},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			cmd := exec.Command("sleep", "30")
+			require.NoError(t, cmd.Start())
+			exited := make(chan struct{})
+			go func() {
+				_ = cmd.Wait()
+				close(exited)
+			}()
+			t.Cleanup(func() {
+				_ = cmd.Process.Kill()
+				<-exited
+			})
+
+			vu := k6test.NewVU(t)
+			ctx := k6ext.WithProcessID(vu.Context(), cmd.Process.Pid)
+			if tc.killOnError {
+				ctx = k6ext.WithKillOnError(ctx)
+			}
+			if tc.shared {
+				ctx = k6ext.WithSharedBrowser(ctx)
+			}
+			thrown := func() (thrown interface{}) {
+				defer func() { thrown = recover() }()
+				k6ext.Panic(ctx, "doing something: %w", tc.err)
+				return nil
+			}()
+			require.IsType(t, &goja.Object{}, thrown, "should throw a JS exception")
+			assert.Contains(t, thrown.(*goja.Object).String(), "doing something: ")
+
+			select {
+			case <-exited:
+				assert.True(t, tc.wantKill, "should not kill the browser process")
+			case <-time.After(500 * time.Millisecond):
+				assert.False(t, tc.wantKill, "should kill the browser process")
+			}
+		})
+	}
+}
+
+func TestPanicErrorName(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name, wantName string
+		err            error
+	}{
+		{name: "timeout", err: &api.TimeoutError{Err: errors.New("timed out")}, wantName: "TimeoutError"},
+		{name: "target_closed", err: &api.TargetClosedError{}, wantName: "TargetClosedError"},
+		{name: "other", err: errors.New("something failed"), wantName: "undefined"},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			vu := k6test.NewVU(t)
+			rt := vu.Runtime()
+			require.NoError(t, rt.Set("fail", func() {
+				k6ext.Panic(vu.Context(), "doing something: %w", tc.err)
+			}))
+			v, err := rt.RunString(`
+				let name;
+				try { fail(); } catch (e) { name = String(e.name); }
+				name;
+			`)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantName, v.String())
+			if tc.wantName != "undefined" {
+				// the errors of the browser API are JS errors.
+				v, err = rt.RunString(`
+					let got;
+					try { fail(); } catch (e) {
+						got = [e instanceof Error, e.stack.split('\n')[0], String(e)];
+					}
+					got;
+				`)
+				require.NoError(t, err)
+				assert.Equal(t, []interface{}{
+					true, tc.wantName, tc.wantName + ": doing something: " + tc.err.Error(),
+				}, v.Export())
+			}
+
+			// the error keeps its chain for the Go code that recovers it.
+			thrown := func() (thrown interface{}) {
+				defer func() { thrown = recover() }()
+				k6ext.Panic(vu.Context(), "doing something: %w", tc.err)
+				return nil
+			}()
+			require.IsType(t, &goja.Object{}, thrown)
+			obj := thrown.(*goja.Object)
+			if v := obj.Get("value"); v != nil {
+				obj = v.ToObject(rt)
+			}
+			gerr, ok := obj.Export().(error)
+			require.True(t, ok, "should export an error")
+			assert.ErrorIs(t, gerr, tc.err)
+		})
+	}
+}
+
+func TestPanicErrorDetails(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+	rt := vu.Runtime()
+	require.NoError(t, rt.Set("fail", func() {
+		k6ext.Panic(vu.Context(), "evaluating JS: %w", &api.EvaluationError{
+			ClassName:   "TypeError",
+			Message:     "foo is undefined",
+			LineNumber:  3,
+			StackFrames: []api.StackFrame{{FunctionName: "f", LineNumber: 3, ColumnNumber: 12}},
+		})
+	}))
+	v, err := rt.RunString(`
+		let got;
+		try { fail(); } catch (e) {
+			got = [e.name, e.details.className, e.details.lineNumber, e.details.stackFrames[0].functionName, e.message];
+		}
+		got.join('|');
+	`)
+	require.NoError(t, err)
+	assert.Equal(t, "EvaluationError|TypeError|3|f|evaluating JS: TypeError: foo is undefined at f (<anonymous>:3:12)", v.String())
+}
+
+func TestIsFatal(t *testing.T) {
+	t.Parallel()
+
+	assert.False(t, k6ext.IsFatal(nil))
+	assert.False(t, k6ext.IsFatal(errors.New("timed out")))
+	assert.False(t, k6ext.IsFatal(fatalErr{fatal: false}))
+	assert.True(t, k6ext.IsFatal(fatalErr{fatal: true}))
+	assert.True(t, k6ext.IsFatal(wrapErr{fatalErr{fatal: true}}), "should find a fatal error in the chain")
+}
+
+type wrapErr struct{ err error }
+
+func (e wrapErr) Error() string { return "wrapped: " + e.err.Error() }
+func (e wrapErr) Unwrap() error { return e.err }
+
+func TestParseKillOnError(t *testing.T) {
+	t.Parallel()
+
+	lookup := func(env map[string]string) func(string) (string, bool) {
+		return func(k string) (string, bool) {
+			v, ok := env[k]
+			return v, ok
+		}
+	}
+
+	kill, err := k6ext.ParseKillOnError(lookup(nil))
+	require.NoError(t, err)
+	assert.False(t, kill)
+
+	kill, err = k6ext.ParseKillOnError(lookup(map[string]string{"XK6_BROWSER_KILL_ON_ERROR": "true"}))
+	require.NoError(t, err)
+	assert.True(t, kill)
+
+	_, err = k6ext.ParseKillOnError(lookup(map[string]string{"XK6_BROWSER_KILL_ON_ERROR": "sometimes"}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid XK6_BROWSER_KILL_ON_ERROR: "sometimes" must be a boolean`)
+}
@@ -0,0 +1,341 @@
+package k6ext
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	k6modules "go.k6.io/k6/js/modules"
+
+	"github.com/dop251/goja"
+)
+
+// DefaultTeardownTimeout is the default execution budget of a single
+// iteration teardown callback.
+const DefaultTeardownTimeout = 10 * time.Second
+
+// errTeardownTimeout is used for interrupting a teardown callback
+// that exceeds its execution budget.
+var errTeardownTimeout = errors.New("iteration teardown callback exceeded its execution budget")
+
+type teardownCallback struct {
+	fn      goja.Callable
+	goFn    func()
+	timeout time.Duration
+}
+
+// iterationState holds the teardown state of a single VU iteration.
+type iterationState struct {
+	iterCtx context.Context // VU context of the iteration
+	ctx     context.Context // canceled once the callbacks have run
+	cancel  context.CancelFunc
+	done    chan struct{}
+	// hold keeps the event loop of the iteration
+	// running until the callbacks have run.
+	hold func(func() error)
+
+	mu        sync.Mutex
+	callbacks []teardownCallback
+	pending   int  // callbacks registered with the VU of the teardown, but not run yet
+	returned  bool // whether the function that started tracking the iteration returned
+	ran       bool
+}
+
+// IterationTeardown runs the callbacks registered during a VU iteration
+// once that iteration ends for any reason: normal completion, a thrown
+// error or a test abort.
+//
+// The callbacks run on the event loop of the VU once the iteration has no
+// more work to do: the function that started the iteration has returned
+// and the callbacks registered with the VU returned by VU have run. If the
+// event loop stops before that, such as when the iteration throws, they run
+// once k6 ends the iteration, and before it starts the next one.
+//
+// The browser lifetime is bound to the context returned by Context, which
+// outlives the VU iteration context until all callbacks have returned.
+// This keeps the pages and browser contexts alive while the callbacks run,
+// and the VU returned by VU reports that context as its own meanwhile, so
+// that waiting in the callbacks isn't cut short by the ended iteration.
+// The callbacks also run when k6 interrupted the runtime for an abort.
+type IterationTeardown struct {
+	vu k6modules.VU
+
+	mu   sync.Mutex
+	iter *iterationState
+}
+
+// NewIterationTeardown returns a new iteration teardown for the given VU.
+func NewIterationTeardown(vu k6modules.VU) *IterationTeardown {
+	return &IterationTeardown{vu: vu}
+}
+
+// Register adds fn to the callbacks to run when the current iteration ends.
+// A zero or negative timeout means DefaultTeardownTimeout.
+func (t *IterationTeardown) Register(fn goja.Callable, timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = DefaultTeardownTimeout
+	}
+	t.register(teardownCallback{fn: fn, timeout: timeout})
+}
+
+// RegisterFunc adds a Go function to the callbacks to run when the current
+// iteration ends. It runs in the order of the callbacks, but without an
+// execution budget, as it doesn't run JavaScript.
+func (t *IterationTeardown) RegisterFunc(fn func()) {
+	t.register(teardownCallback{goFn: fn})
+}
+
+func (t *IterationTeardown) register(cb teardownCallback) {
+	it := t.current()
+	it.mu.Lock()
+	defer it.mu.Unlock()
+
+	if it.ran {
+		// the iteration has already ended, there is nothing to tear down.
+		return
+	}
+	it.callbacks = append(it.callbacks, cb)
+}
+
+// Context returns a context that carries the current VU iteration values
+// and is canceled only after the iteration teardown callbacks have run.
+// The VU that it carries is the one returned by VU.
+func (t *IterationTeardown) Context() context.Context {
+	return t.current().ctx
+}
+
+// VU returns the VU of the teardown. The iteration teardown waits for the
+// callbacks that are registered with its RegisterCallback method to run,
+// so that, for example, a pending promise is settled before the browser
+// is closed.
+func (t *IterationTeardown) VU() k6modules.VU {
+	return &teardownVU{VU: t.vu, t: t}
+}
+
+// current returns the state of the current VU iteration, and starts
+// tracking it if it's not tracked yet. It must be called on the event loop.
+func (t *IterationTeardown) current() *iterationState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	iterCtx := t.vu.Context()
+	if t.iter != nil {
+		if t.iter.iterCtx == iterCtx {
+			return t.iter
+		}
+		// the teardown of the previous iteration may still be using the runtime.
+		<-t.iter.done
+	}
+
+	it := &iterationState{
+		iterCtx: iterCtx,
+		done:    make(chan struct{}),
+		hold:    t.vu.RegisterCallback(),
+	}
+	it.ctx, it.cancel = context.WithCancel(WithVU(context.Background(), t.VU()))
+	t.iter = it
+
+	// the queued function runs after the function
+	// that is running on the event loop returns.
+	returned := t.vu.RegisterCallback()
+	returned(func() error {
+		it.mu.Lock()
+		it.returned = true
+		idle := it.pending == 0
+		it.mu.Unlock()
+
+		if idle {
+			t.end(it)
+		}
+		return nil
+	})
+
+	go func() {
+		select {
+		case <-it.done:
+		case <-iterCtx.Done():
+			// the event loop stopped without running the callbacks.
+			// k6 cancels the iteration context only after that, and
+			// then waits for the teardown to release the event loop
+			// before it starts the next iteration, so the runtime is
+			// not in use while the callbacks run.
+			t.end(it)
+		}
+	}()
+
+	return it
+}
+
+// end invokes the callbacks of the iteration only once, and then releases
+// the event loop.
+func (t *IterationTeardown) end(it *iterationState) {
+	it.mu.Lock()
+	if it.ran {
+		it.mu.Unlock()
+		return
+	}
+	callbacks := it.callbacks
+	it.callbacks = nil
+	it.ran = true
+	it.mu.Unlock()
+
+	// callbacks run in the reverse order of their registration,
+	// like deferred function calls.
+	for i := len(callbacks) - 1; i >= 0; i-- {
+		if err := t.call(callbacks[i]); err != nil {
+			// the iteration error, if any, is already reported by k6.
+			// so we only log the error here so as not to mask it.
+			t.vu.State().Logger.Errorf("running iteration teardown callback: %v", err)
+		}
+	}
+
+	it.cancel()
+	close(it.done)
+	it.hold(func() error { return nil })
+}
+
+func (t *IterationTeardown) call(cb teardownCallback) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = panicToError(r)
+		}
+	}()
+
+	if cb.goFn != nil {
+		cb.goFn()
+		return nil
+	}
+
+	rt := t.vu.Runtime()
+	// k6 interrupts the runtime when it aborts the iteration,
+	// and the callbacks must run regardless.
+	rt.ClearInterrupt()
+	timer := time.AfterFunc(cb.timeout, func() {
+		rt.Interrupt(errTeardownTimeout)
+	})
+	defer func() {
+		if !timer.Stop() {
+			// the interrupt may not have been consumed by the callback.
+			rt.ClearInterrupt()
+		}
+	}()
+	_, err = cb.fn(goja.Undefined())
+
+	return err
+}
+
+// pendingDone marks a callback that is registered with the VU of the
+// teardown as run, and ends the iteration if it was the last one.
+func (t *IterationTeardown) pendingDone(it *iterationState) {
+	it.mu.Lock()
+	it.pending--
+	idle := it.pending == 0 && it.returned
+	it.mu.Unlock()
+
+	if idle {
+		t.end(it)
+	}
+}
+
+func panicToError(r interface{}) error {
+	switch v := r.(type) {
+	case error:
+		return v
+	case goja.Value:
+		return errors.New(v.String())
+	case string:
+		return errors.New(v)
+	default:
+		return fmt.Errorf("iteration teardown callback panicked: %v", v)
+	}
+}
+
+// teardownVU is the VU of an iteration teardown.
+type teardownVU struct {
+	k6modules.VU
+	t *IterationTeardown
+}
+
+// Context returns the context of the current VU iteration. While the
+// iteration teardown callbacks run, it returns the teardown context
+// instead, as the iteration context is already done by then.
+func (v *teardownVU) Context() context.Context {
+	v.t.mu.Lock()
+	it := v.t.iter
+	v.t.mu.Unlock()
+
+	if it != nil {
+		it.mu.Lock()
+		tearingDown := it.ran && it.ctx.Err() == nil
+		it.mu.Unlock()
+		if tearingDown {
+			return it.ctx
+		}
+	}
+	return v.VU.Context()
+}
+
+// RegisterCallback registers a callback on the event loop like the one of
+// the underlying VU, and the iteration teardown waits for it to run.
+func (v *teardownVU) RegisterCallback() func(func() error) {
+	it := v.t.current()
+	it.mu.Lock()
+	it.pending++
+	it.mu.Unlock()
+
+	cb := v.VU.RegisterCallback()
+	return func(f func() error) {
+		cb(func() error {
+			err := f()
+			if err == nil {
+				v.t.pendingDone(it)
+			}
+			return err
+		})
+	}
+}
+
+// RegisterListener registers a callback on the event loop like the one of
+// the underlying VU for waiting for events, such as the ones of a page.
+// Unlike RegisterCallback, the iteration teardown doesn't wait for the
+// callback while it waits for an event, only while it runs the function
+// that it's called with. The function is dropped if the iteration has
+// already ended.
+func (v *teardownVU) RegisterListener() func(func() error) {
+	it := v.t.current()
+	cb := v.VU.RegisterCallback()
+	return func(f func() error) {
+		it.mu.Lock()
+		// the iteration ends once it returned and has nothing pending.
+		ended := it.ran || (it.returned && it.pending == 0)
+		if !ended {
+			it.pending++
+		}
+		it.mu.Unlock()
+
+		if ended {
+			cb(func() error { return nil })
+			return
+		}
+		cb(func() error {
+			err := f()
+			if err == nil {
+				v.t.pendingDone(it)
+			}
+			return err
+		})
+	}
+}
+
+// RegisterListener registers a callback on the event loop of the VU for
+// waiting for events. If the VU is the one of an iteration teardown, the
+// teardown doesn't wait for the callback while it waits for an event, so
+// that listening to events doesn't keep the iteration running.
+func RegisterListener(vu k6modules.VU) func(func() error) {
+	if v, ok := vu.(*teardownVU); ok {
+		return v.RegisterListener()
+	}
+	return vu.RegisterCallback()
+}
@@ -0,0 +1,213 @@
+package k6ext_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/xk6-browser/k6ext"
+	"github.com/grafana/xk6-browser/k6ext/k6test"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIterationTeardown(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+	iterCtx, endIteration := context.WithCancel(vu.CtxField)
+	vu.CtxField = iterCtx
+
+	td := k6ext.NewIterationTeardown(vu)
+	browserCtx := td.Context()
+
+	var calls []string
+	register := func(name string) {
+		td.Register(func(goja.Value, ...goja.Value) (goja.Value, error) {
+			// the browser must still be alive while the callbacks run.
+			assert.NoError(t, browserCtx.Err())
+			calls = append(calls, name)
+			return nil, nil
+		}, 0)
+	}
+	register("first")
+	td.RegisterFunc(func() {
+		assert.NoError(t, browserCtx.Err())
+		calls = append(calls, "go")
+	})
+	td.Register(func(goja.Value, ...goja.Value) (goja.Value, error) {
+		calls = append(calls, "failing")
+		_, err := vu.Runtime().RunString(`throw new Error("oops")`)
+		return nil, err
+	}, 0)
+	register("last")
+
+	endIteration()
+
+	select {
+	case <-browserCtx.Done():
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "browser context was not canceled after the iteration teardown")
+	}
+	// a failing callback must not prevent the others from running,
+	// and callbacks must run only once.
+	assert.Equal(t, []string{"last", "failing", "go", "first"}, calls)
+}
+
+func TestIterationTeardownTimeout(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+	iterCtx, endIteration := context.WithCancel(vu.CtxField)
+	vu.CtxField = iterCtx
+
+	td := k6ext.NewIterationTeardown(vu)
+	browserCtx := td.Context()
+
+	cb, err := vu.Runtime().RunString(`(function() { for (;;) {} })`)
+	require.NoError(t, err)
+	fn, ok := goja.AssertFunction(cb)
+	require.True(t, ok)
+	td.Register(fn, 100*time.Millisecond)
+
+	endIteration()
+
+	select {
+	case <-browserCtx.Done():
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "teardown callback was not interrupted")
+	}
+}
+
+func TestIterationTeardownOnEventLoop(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+	td := k6ext.NewIterationTeardown(vu)
+
+	var calls []string
+	err := vu.Loop.Start(func() error {
+		browserCtx := td.Context()
+		td.RegisterFunc(func() {
+			assert.NoError(t, browserCtx.Err())
+			calls = append(calls, "teardown")
+		})
+		td.RegisterFunc(func() {
+			panic("oops")
+		})
+		// the teardown waits for the callbacks
+		// registered with the VU of the teardown.
+		cb := td.VU().RegisterCallback()
+		time.AfterFunc(50*time.Millisecond, func() {
+			cb(func() error {
+				calls = append(calls, "pending")
+				return nil
+			})
+		})
+		return nil
+	})
+	require.NoError(t, err)
+
+	// the event loop must return only after the callbacks ran,
+	// and a panicking callback must not prevent the others from running.
+	assert.Equal(t, []string{"pending", "teardown"}, calls)
+	assert.Error(t, td.Context().Err(), "browser context must be canceled after the teardown")
+}
+
+func TestIterationTeardownListener(t *testing.T) {
+	t.Parallel()
+
+	// iterate runs an iteration that listens to an event until the browser
+	// context is canceled, and emits the event while the iteration runs
+	// if emit is true.
+	iterate := func(t *testing.T, emit bool) []string {
+		t.Helper()
+
+		vu := k6test.NewVU(t)
+		td := k6ext.NewIterationTeardown(vu)
+
+		var calls []string
+		err := vu.Loop.Start(func() error {
+			browserCtx := td.Context()
+			td.Register(func(goja.Value, ...goja.Value) (goja.Value, error) {
+				calls = append(calls, "teardown")
+				return nil, nil
+			}, 0)
+
+			listen := k6ext.RegisterListener(td.VU())
+			event := func() error {
+				calls = append(calls, "event")
+				return nil
+			}
+			if emit {
+				listen(event)
+				return nil
+			}
+			go func() {
+				<-browserCtx.Done()
+				listen(event)
+			}()
+			return nil
+		})
+		require.NoError(t, err)
+
+		return calls
+	}
+
+	// the teardown waits for the events that happened during the iteration,
+	assert.Equal(t, []string{"event", "teardown"}, iterate(t, true))
+	// but the listener doesn't keep the iteration running,
+	// and the events after the iteration ended are dropped.
+	assert.Equal(t, []string{"teardown"}, iterate(t, false))
+}
+
+func TestIterationTeardownAbort(t *testing.T) {
+	t.Parallel()
+
+	vu := k6test.NewVU(t)
+	runCtx, abort := context.WithCancel(vu.CtxField)
+	iterCtx, endIteration := context.WithCancel(runCtx)
+	vu.CtxField = iterCtx
+
+	td := k6ext.NewIterationTeardown(vu)
+
+	var calls []string
+	err := vu.Loop.Start(func() error {
+		browserCtx := td.Context()
+		td.Register(func(goja.Value, ...goja.Value) (goja.Value, error) {
+			// the abort must not interrupt the callback,
+			_, err := vu.Runtime().RunString(`1 + 1`)
+			assert.NoError(t, err)
+			// nor cut short its waits on the context of the VU.
+			assert.NoError(t, td.VU().Context().Err())
+			assert.NoError(t, browserCtx.Err())
+			calls = append(calls, "teardown")
+			return nil, nil
+		}, 0)
+
+		// the iteration waits for a pending callback when k6 aborts
+		// the test: it cancels the run context, and interrupts the
+		// runtime of the VU.
+		pending := td.VU().RegisterCallback()
+		time.AfterFunc(50*time.Millisecond, func() {
+			abort()
+			vu.Runtime().Interrupt(context.Canceled)
+		})
+		go func() {
+			<-browserCtx.Done()
+			pending(func() error {
+				calls = append(calls, "pending")
+				return nil
+			})
+		}()
+		return nil
+	})
+	require.NoError(t, err)
+	endIteration()
+	vu.Loop.WaitOnRegistered()
+
+	assert.Equal(t, []string{"teardown", "pending"}, calls)
+	assert.ErrorIs(t, td.VU().Context().Err(), context.Canceled)
+}
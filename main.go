@@ -21,7 +21,9 @@
 package browser
 
 import (
+	"context"
 	"errors"
+	"time"
 
 	"github.com/grafana/xk6-browser/api"
 	"github.com/grafana/xk6-browser/chromium"
@@ -43,10 +45,13 @@ type (
 
 	// JSModule is the entrypoint into the browser JS module.
 	JSModule struct {
-		vu        k6modules.VU
-		k6Metrics *k6ext.CustomMetrics
-		Devices   map[string]common.Device
-		Version   string
+		vu          k6modules.VU
+		k6Metrics   *k6ext.CustomMetrics
+		killOnError bool
+		teardown    *k6ext.IterationTeardown
+		shared      *common.SharedBrowser
+		Devices     common.Devices
+		Version     string
 	}
 
 	// ModuleInstance represents an instance of the JS module.
@@ -68,13 +73,27 @@ func New() *RootModule {
 // NewModuleInstance implements the k6modules.Module interface to return
 // a new instance for each VU.
 func (*RootModule) NewModuleInstance(vu k6modules.VU) k6modules.Instance {
-	k6m := k6ext.RegisterCustomMetrics(vu.InitEnv().Registry)
+	opts, err := k6ext.MetricsOptionsFromEnv()
+	if err != nil {
+		k6common.Throw(vu.Runtime(), err)
+	}
+	k6m, err := k6ext.RegisterCustomMetricsWithOptions(vu.InitEnv().Registry, opts)
+	if err != nil {
+		k6common.Throw(vu.Runtime(), err)
+	}
+	killOnError, err := k6ext.KillOnErrorFromEnv()
+	if err != nil {
+		k6common.Throw(vu.Runtime(), err)
+	}
 	return &ModuleInstance{
 		mod: &JSModule{
-			vu:        vu,
-			k6Metrics: k6m,
-			Devices:   common.GetDevices(),
-			Version:   version,
+			vu:          vu,
+			k6Metrics:   k6m,
+			killOnError: killOnError,
+			teardown:    k6ext.NewIterationTeardown(vu),
+			shared:      common.NewSharedBrowser(vu),
+			Devices:     common.GetDevices(),
+			Version:     version,
 		},
 	}
 }
@@ -96,8 +115,17 @@ func (m *JSModule) Launch(browserName string, opts goja.Value) api.Browser {
 		<-ctx.Done()
 	}()*/
 
-	ctx := k6ext.WithVU(m.vu.Context(), m.vu)
+	if browserName == "chromium" && m.reuseBrowser(opts) {
+		return m.launchShared(opts)
+	}
+
+	// the browser outlives the iteration until the teardown callbacks
+	// registered with onIterationEnd return.
+	ctx := m.teardown.Context()
 	ctx = k6ext.WithCustomMetrics(ctx, m.k6Metrics)
+	if m.killOnError {
+		ctx = k6ext.WithKillOnError(ctx)
+	}
 
 	if browserName == "chromium" {
 		bt := chromium.NewBrowserType(ctx)
@@ -109,6 +137,91 @@ func (m *JSModule) Launch(browserName string, opts goja.Value) api.Browser {
 	return nil
 }
 
+// reuseBrowser reports whether the launch options enable reusing
+// the browser across iterations, which reusing the page implies.
+func (m *JSModule) reuseBrowser(opts goja.Value) bool {
+	if opts == nil || goja.IsUndefined(opts) || goja.IsNull(opts) {
+		return false
+	}
+	obj := opts.ToObject(m.vu.Runtime())
+	for _, k := range []string{"reuseBrowser", "reusePage"} {
+		if v := obj.Get(k); v != nil && v.ToBoolean() {
+			return true
+		}
+	}
+	return false
+}
+
+// launchShared returns the browser that's shared across the iterations of
+// the VU, and launches it if it isn't running. The browser contexts are
+// closed when the iteration ends, and the browser when the run of the VU
+// ends.
+func (m *JSModule) launchShared(opts goja.Value) api.Browser {
+	b := m.shared.Browser(func() (api.Browser, context.CancelFunc) {
+		// the browser outlives the iterations, so its context
+		// isn't derived from the context of an iteration.
+		ctx, cancel := context.WithCancel(k6ext.WithVU(context.Background(), m.vu))
+		ctx = k6ext.WithCustomMetrics(ctx, m.k6Metrics)
+		if m.killOnError {
+			ctx = k6ext.WithKillOnError(ctx)
+		}
+
+		bt := chromium.NewBrowserType(ctx)
+		return bt.Launch(opts), cancel
+	})
+	m.teardown.RegisterFunc(m.shared.EndIteration)
+
+	return b
+}
+
+// Connect connects to a running browser instead of launching one. The
+// wsEndpoint is the DevTools WebSocket URL of the browser, or its HTTP
+// endpoint, such as http://localhost:9222. The XK6_BROWSER_WS_URL
+// environment variable is used if it's empty.
+func (m *JSModule) Connect(wsEndpoint string, opts goja.Value) api.Browser {
+	ctx := m.teardown.Context()
+	ctx = k6ext.WithCustomMetrics(ctx, m.k6Metrics)
+
+	bt := chromium.NewBrowserType(ctx)
+	return bt.Connect(wsEndpoint, opts)
+}
+
+// OnIterationEnd registers fn to be called when the current iteration ends
+// for any reason, before the browser launched in the iteration is closed.
+// Callbacks run in the reverse order of their registration, and each one
+// has an execution budget that can be set with the timeout option in
+// milliseconds.
+func (m *JSModule) OnIterationEnd(fn goja.Value, opts goja.Value) {
+	rt := m.vu.Runtime()
+
+	cb, ok := goja.AssertFunction(fn)
+	if !ok {
+		k6common.Throw(rt, errors.New("onIterationEnd requires a function argument"))
+	}
+
+	var timeout time.Duration
+	if opts != nil && !goja.IsUndefined(opts) && !goja.IsNull(opts) {
+		if v := opts.ToObject(rt).Get("timeout"); v != nil && !goja.IsUndefined(v) {
+			timeout = time.Duration(v.ToInteger()) * time.Millisecond
+		}
+	}
+
+	m.teardown.Register(cb, timeout)
+}
+
+// Expect returns the assertions of the elements that a locator matches.
+// The assertions retry until they pass or their timeout expires, and
+// record their result as a k6 check. A failed assertion doesn't abort
+// the iteration unless the hard option is set.
+func (m *JSModule) Expect(locator api.Locator) api.Expectation {
+	l, ok := locator.(*common.Locator)
+	if !ok {
+		k6common.Throw(m.vu.Runtime(), errors.New("expect requires a locator argument"))
+	}
+
+	return l.Expect()
+}
+
 func init() {
 	k6modules.Register("k6/x/browser", New())
 }
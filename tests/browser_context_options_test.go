@@ -23,7 +23,11 @@ package tests
 import (
 	_ "embed"
 	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/grafana/xk6-browser/common"
 
@@ -36,6 +40,7 @@ func TestBrowserContextOptionsDefaultValues(t *testing.T) {
 
 	opts := common.NewBrowserContextOptions()
 	assert.False(t, opts.AcceptDownloads)
+	assert.False(t, opts.BackgroundThrottling)
 	assert.False(t, opts.BypassCSP)
 	assert.Equal(t, common.ColorSchemeLight, opts.ColorScheme)
 	assert.Equal(t, 1.0, opts.DeviceScaleFactor)
@@ -47,6 +52,7 @@ func TestBrowserContextOptionsDefaultValues(t *testing.T) {
 	assert.False(t, opts.IsMobile)
 	assert.True(t, opts.JavaScriptEnabled)
 	assert.Equal(t, common.DefaultLocale, opts.Locale)
+	assert.Equal(t, common.NewNetworkIdleOptions(), opts.NetworkIdle)
 	assert.False(t, opts.Offline)
 	assert.Empty(t, opts.Permissions)
 	assert.Equal(t, common.ReducedMotionNoPreference, opts.ReducedMotion)
@@ -104,3 +110,305 @@ func TestBrowserContextOptionsExtraHTTPHeaders(t *testing.T) {
 	require.NotEmpty(t, h)
 	assert.Equal(t, "Some-Value", h[0])
 }
+
+func TestBrowserContextOptionsBackgroundThrottling(t *testing.T) {
+	t.Parallel()
+
+	const measure = 1 * time.Second
+
+	// backgroundTicks sends a page to the background by bringing another
+	// page to the front, and returns how many times an interval of 10ms
+	// fired in it during the measurement, and whether it had focus.
+	backgroundTicks := func(t *testing.T, throttling bool) (int64, bool) {
+		t.Helper()
+
+		tb := newTestBrowser(t)
+		bctx := tb.NewContext(tb.toGojaValue(struct {
+			BackgroundThrottling bool `js:"backgroundThrottling"`
+		}{
+			BackgroundThrottling: throttling,
+		}))
+		t.Cleanup(bctx.Close)
+
+		bg := bctx.NewPage()
+		fg := bctx.NewPage()
+		fg.BringToFront()
+		bg.Evaluate(tb.toGojaValue(`() => {
+			window.ticks = 0;
+			setInterval(() => window.ticks++, 10);
+		}`))
+		time.Sleep(measure)
+
+		ticks := bg.Evaluate(tb.toGojaValue(`() => window.ticks`))
+		focus := bg.Evaluate(tb.toGojaValue(`() => document.hasFocus()`))
+
+		return tb.asGojaValue(ticks).ToInteger(), tb.asGojaValue(focus).ToBoolean()
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+
+		ticks, focus := backgroundTicks(t, false)
+		t.Logf("interval fired %d times in %s", ticks, measure)
+		assert.True(t, focus, "background page should behave as the page in front")
+		// 100 ticks without any drift.
+		assert.Greater(t, ticks, int64(50), "interval should not be throttled")
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		t.Parallel()
+
+		ticks, focus := backgroundTicks(t, true)
+		t.Logf("interval fired %d times in %s", ticks, measure)
+		assert.False(t, focus, "background page should not have focus")
+	})
+}
+
+func TestBrowserContextOptionsDevice(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t)
+	rt := tb.runtime()
+	require.NoError(t, rt.Set("browser", tb.Browser))
+	require.NoError(t, rt.Set("devices", common.GetDevices()))
+
+	v, err := rt.RunString(`
+		const context = browser.newContext({ ...devices.get('iPhone 12') });
+		const page = context.newPage();
+		page.setContent('<meta name="viewport" content="width=device-width"><p>mobile</p>');
+		page.evaluate(() => JSON.stringify({
+			userAgent: navigator.userAgent,
+			maxTouchPoints: navigator.maxTouchPoints,
+			width: window.innerWidth,
+			devicePixelRatio: window.devicePixelRatio,
+		}));
+	`)
+	require.NoError(t, err)
+
+	var got struct {
+		UserAgent        string
+		MaxTouchPoints   int
+		Width            int
+		DevicePixelRatio float64
+	}
+	require.NoError(t, json.Unmarshal([]byte(v.String()), &got))
+	iphone := common.GetDevices()["iPhone 12"]
+	assert.Equal(t, iphone.UserAgent, got.UserAgent)
+	assert.Positive(t, got.MaxTouchPoints, "touch should be enabled")
+	assert.Equal(t, int(iphone.Viewport.Width), got.Width)
+	assert.Equal(t, iphone.DeviceScaleFactor, got.DevicePixelRatio)
+
+	_, err = rt.RunString(`devices.get('iPhone 99')`)
+	assert.ErrorContains(t, err, `unknown device "iPhone 99", did you mean`)
+}
+
+func TestBrowserContextOptionsBlockResourceTypes(t *testing.T) {
+	t.Parallel()
+
+	var imageHits int64
+	tb := newTestBrowser(t, withHTTPServer())
+	tb.withHandler("/page.html", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `<html><body><img src="/image.png"></body></html>`)
+	})
+	tb.withHandler("/image.png", func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt64(&imageHits, 1)
+		w.Header().Set("Content-Type", "image/png")
+	})
+	tb.withHandler("/api", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, "ok")
+	})
+
+	rt := tb.runtime()
+	require.NoError(t, rt.Set("browser", tb.Browser))
+	require.NoError(t, rt.Set("url", tb.URL("/page.html")))
+	var log []string
+	require.NoError(t, rt.Set("log", func(s string) { log = append(log, s) }))
+
+	err := tb.vu.Loop.Start(func() error {
+		_, err := rt.RunString(`
+			const context = browser.newContext({ blockResourceTypes: ['image', 'font', 'media'] });
+			const page = context.newPage();
+			page.on('requestfailed', req => {
+				log(req.resourceType() + ' ' + req.failure().errorText);
+				page.close();
+			});
+			log(String(page.goto(url).ok()));
+			log(page.evaluate(() => fetch('/api').then(r => r.text())));`)
+		if err != nil {
+			return fmt.Errorf("%w", err)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"true", "ok", "Image net::ERR_BLOCKED_BY_CLIENT"}, log)
+	assert.Zero(t, atomic.LoadInt64(&imageHits), "blocked image should not reach the server")
+}
+
+func TestBrowserContextOptionsTimezoneID(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t, withHTTPServer())
+	tb.withHandler("/page", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `<html><body></body></html>`)
+	})
+
+	rt := tb.runtime()
+	require.NoError(t, rt.Set("browser", tb.Browser))
+	require.NoError(t, rt.Set("url", tb.URL("/page")))
+	var log []string
+	require.NoError(t, rt.Set("log", func(s string) { log = append(log, s) }))
+
+	err := tb.vu.Loop.Start(func() error {
+		_, err := rt.RunString(`
+			const timezone = page => page.evaluate(() => Intl.DateTimeFormat().resolvedOptions().timeZone +
+				' ' + new Date(2022, 0, 1).getTimezoneOffset());
+
+			for (const timezoneID of ['America/New_York', 'Asia/Tokyo']) {
+				const page = browser.newContext({ timezoneID }).newPage();
+				page.goto(url);
+				log(timezone(page));
+				page.goto(url + '?again');
+				log(timezone(page));
+
+				const popup = page.waitForEvent('popup');
+				page.evaluate(() => { window.open('/page'); });
+				popup.then(p => {
+					p.waitForURL('**/page');
+					log('popup ' + timezone(p));
+				}, err => log('err: ' + err));
+			}
+		`)
+		if err != nil {
+			return fmt.Errorf("%w", err)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		"America/New_York 300",
+		"America/New_York 300",
+		"popup America/New_York 300",
+		"Asia/Tokyo -540",
+		"Asia/Tokyo -540",
+		"popup Asia/Tokyo -540",
+	}, log)
+
+	_, err = rt.RunString(`browser.newContext({ timezoneID: 'Mars/Olympus_Mons' })`)
+	assert.ErrorContains(t, err, `invalid timezoneID "Mars/Olympus_Mons"`)
+}
+
+func TestBrowserContextOptionsLocale(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t, withHTTPServer())
+	tb.withHandler("/page", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `<html><body><iframe src="/frame"></iframe></body></html>`)
+	})
+	tb.withHandler("/frame", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `<html><body></body></html>`)
+	})
+	tb.withHandler("/echo", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Header.Get("Accept-Language"))
+	})
+
+	rt := tb.runtime()
+	require.NoError(t, rt.Set("browser", tb.Browser))
+	require.NoError(t, rt.Set("url", tb.URL("/page")))
+	var log []string
+	require.NoError(t, rt.Set("log", func(s string) { log = append(log, s) }))
+
+	err := tb.vu.Loop.Start(func() error {
+		_, err := rt.RunString(`
+			const locale = f => f.evaluate(() => fetch('/echo').then(r => r.text()).then(h => [
+				navigator.language,
+				h.split(',')[0],
+				(1234.5).toLocaleString(),
+				new Intl.DateTimeFormat(undefined, { month: 'long' }).format(new Date(2022, 2, 1)),
+			].join(' ')));
+
+			const page = browser.newContext({ locale: 'de-DE' }).newPage();
+			page.goto(url, { waitUntil: 'load' });
+			log('page ' + locale(page));
+			log('frame ' + locale(page.frames()[1]));
+
+			const popup = page.waitForEvent('popup');
+			page.evaluate(() => { window.open('/frame'); });
+			popup.then(p => {
+				p.waitForURL('**/frame');
+				log('popup ' + locale(p));
+			}, err => log('err: ' + err));
+		`)
+		if err != nil {
+			return fmt.Errorf("%w", err)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"page de-DE de-DE 1.234,5 März",
+		"frame de-DE de-DE 1.234,5 März",
+		"popup de-DE de-DE 1.234,5 März",
+	}, log)
+
+	_, err = rt.RunString(`browser.newContext({ locale: 'de_DE' })`)
+	assert.ErrorContains(t, err, `invalid locale "de_DE"`)
+}
+
+func TestBrowserContextOptionsUserAgent(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t, withHTTPServer())
+	tb.withHandler("/page", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `<html><body></body></html>`)
+	})
+	tb.withHandler("/echo", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Header.Get("User-Agent"))
+	})
+	tb.withHandler("/worker.js", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/javascript")
+		fmt.Fprint(w, `self.userAgent = () => fetch('/echo').then(r => r.text())
+			.then(h => navigator.userAgent + ' ' + h);`)
+	})
+
+	rt := tb.runtime()
+	require.NoError(t, rt.Set("browser", tb.Browser))
+	require.NoError(t, rt.Set("devices", common.GetDevices()))
+	require.NoError(t, rt.Set("url", tb.URL("/page")))
+	var log []string
+	require.NoError(t, rt.Set("log", func(s string) { log = append(log, s) }))
+
+	err := tb.vu.Loop.Start(func() error {
+		_, err := rt.RunString(`
+			const userAgent = p => p.evaluate(() => fetch('/echo').then(r => r.text())
+				.then(h => navigator.userAgent + ' ' + h));
+
+			const page = browser.newContext({ userAgent: 'k6-bot/1.0', platform: 'k6OS' }).newPage();
+			page.goto(url);
+			log(userAgent(page));
+			log(page.evaluate(() => navigator.platform));
+
+			const device = browser.newContext({ ...devices.get('iPhone 12') }).newPage();
+			device.goto(url);
+			log(String(userAgent(device).includes('iPhone')));
+			const overridden = browser.newContext({ ...devices.get('iPhone 12'), userAgent: 'k6-mobile/1.0' }).newPage();
+			overridden.goto(url);
+			log(userAgent(overridden));
+
+			const worker = page.waitForEvent('worker');
+			page.evaluate(() => { window.worker = new Worker('/worker.js'); });
+			worker.then(w => log('worker ' + w.evaluate(() => self.userAgent())), err => log('err: ' + err));
+		`)
+		if err != nil {
+			return fmt.Errorf("%w", err)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"k6-bot/1.0 k6-bot/1.0",
+		"k6OS",
+		"true",
+		"k6-mobile/1.0 k6-mobile/1.0",
+		"worker k6-bot/1.0 k6-bot/1.0",
+	}, log)
+}
@@ -0,0 +1,430 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBrowserContextSetOffline(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t, withHTTPServer())
+	tb.withHandler("/api", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, "ok")
+	})
+
+	rt := tb.runtime()
+	require.NoError(t, rt.Set("browser", tb.Browser))
+	require.NoError(t, rt.Set("url", tb.URL("/api")))
+
+	v, err := rt.RunString(`
+		const status = page => page.evaluate(() => fetch('/api', { cache: 'no-store' })
+			.then(r => r.text())
+			.catch(() => 'failed')
+			.then(s => s + ' ' + (navigator.onLine ? 'online' : 'offline')));
+
+		const context = browser.newContext();
+		const page = context.newPage();
+		page.goto(url);
+		const other = browser.newContext().newPage();
+		other.goto(url);
+
+		const got = [status(page)];
+		context.setOffline(true);
+		got.push(status(page), status(other));
+		const later = context.newPage();
+		later.goto('about:blank');
+		got.push(later.evaluate(() => navigator.onLine ? 'online' : 'offline'));
+		context.setOffline(false);
+		got.push(status(page));
+		got;
+	`)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		"ok online",
+		"failed offline",
+		"ok online",
+		"offline",
+		"ok online",
+	}, v.Export())
+}
+
+func TestBrowserContextCookies(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t, withHTTPServer())
+	tb.withHandler("/set", func(w http.ResponseWriter, _ *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "app", Value: "3", Path: "/"})
+		fmt.Fprint(w, `<html><body></body></html>`)
+	})
+	tb.withHandler("/echo", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Header.Get("Cookie"))
+	})
+
+	rt := tb.runtime()
+	require.NoError(t, rt.Set("browser", tb.Browser))
+	require.NoError(t, rt.Set("baseURL", tb.URL("")))
+
+	v, err := rt.RunString(`
+		const context = browser.newContext();
+		context.addCookies([
+			{ name: 'session', value: '1', url: baseURL },
+			{
+				name: 'tracking', value: '2', domain: '127.0.0.1', path: '/',
+				expires: 2000000000, httpOnly: true, secure: true, sameSite: 'None',
+			},
+		]);
+		const byName = cs => cs.sort((a, b) => a.name.localeCompare(b.name));
+		const got = { added: byName(context.cookies()) };
+
+		const page = context.newPage();
+		page.goto(baseURL + '/set');
+		got.sent = page.evaluate(() => fetch('/echo').then(r => r.text()));
+		got.forURL = byName(context.cookies(baseURL + '/')).map(c => c.name);
+		got.forOtherURL = context.cookies('http://example.com/').length;
+
+		context.clearCookies();
+		got.cleared = context.cookies().length;
+		JSON.stringify(got);
+	`)
+	require.NoError(t, err)
+
+	type cookie struct {
+		Name     string  `json:"name"`
+		Value    string  `json:"value"`
+		Domain   string  `json:"domain"`
+		Path     string  `json:"path"`
+		Expires  float64 `json:"expires"`
+		HTTPOnly bool    `json:"httpOnly"`
+		Secure   bool    `json:"secure"`
+		SameSite string  `json:"sameSite"`
+	}
+	var got struct {
+		Added       []cookie `json:"added"`
+		Sent        string   `json:"sent"`
+		ForURL      []string `json:"forURL"`
+		ForOtherURL int      `json:"forOtherURL"`
+		Cleared     int      `json:"cleared"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(v.String()), &got))
+
+	assert.Equal(t, []cookie{
+		{Name: "session", Value: "1", Domain: "127.0.0.1", Path: "/", Expires: -1, SameSite: "Lax"},
+		{
+			Name: "tracking", Value: "2", Domain: "127.0.0.1", Path: "/",
+			Expires: 2000000000, HTTPOnly: true, Secure: true, SameSite: "None",
+		},
+	}, got.Added)
+	assert.Contains(t, got.Sent, "session=1")
+	assert.Contains(t, got.Sent, "app=3")
+	assert.Equal(t, []string{"app", "session", "tracking"}, got.ForURL)
+	assert.Zero(t, got.ForOtherURL)
+	assert.Zero(t, got.Cleared)
+}
+
+func TestBrowserContextStorageState(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t, withHTTPServer())
+	tb.withHandler("/page", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `<html><body></body></html>`)
+	})
+	tb.withHandler("/echo", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Header.Get("Cookie"))
+	})
+
+	rt := tb.runtime()
+	require.NoError(t, rt.Set("browser", tb.Browser))
+	require.NoError(t, rt.Set("baseURL", tb.URL("")))
+	require.NoError(t, rt.Set("path", filepath.Join(t.TempDir(), "state.json")))
+
+	v, err := rt.RunString(`
+		const saved = browser.newContext();
+		saved.addCookies([{ name: 'session', value: '1', url: baseURL }]);
+		const page = saved.newPage();
+		page.goto(baseURL + '/page');
+		page.evaluate(() => localStorage.setItem('theme', 'dark'));
+		const state = saved.storageState({ path: path });
+
+		const restored = storageState => {
+			const context = browser.newContext({ storageState });
+			const page = context.newPage();
+			page.goto(baseURL + '/page');
+			return {
+				sent: page.evaluate(() => fetch('/echo').then(r => r.text())),
+				theme: page.evaluate(() => localStorage.getItem('theme')),
+			};
+		};
+		const notVisited = browser.newContext({ storageState: path }).storageState();
+
+		JSON.stringify({
+			state,
+			fromObject: restored(state),
+			fromPath: restored(path),
+			notVisited,
+		});
+	`)
+	require.NoError(t, err)
+
+	type restored struct {
+		Sent  string `json:"sent"`
+		Theme string `json:"theme"`
+	}
+	var got struct {
+		State struct {
+			Cookies []struct {
+				Name  string `json:"name"`
+				Value string `json:"value"`
+			} `json:"cookies"`
+			Origins []struct {
+				Origin       string `json:"origin"`
+				LocalStorage []struct {
+					Name  string `json:"name"`
+					Value string `json:"value"`
+				} `json:"localStorage"`
+			} `json:"origins"`
+		} `json:"state"`
+		FromObject restored        `json:"fromObject"`
+		FromPath   restored        `json:"fromPath"`
+		NotVisited json.RawMessage `json:"notVisited"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(v.String()), &got))
+
+	require.Len(t, got.State.Cookies, 1)
+	assert.Equal(t, "session", got.State.Cookies[0].Name)
+	assert.Equal(t, "1", got.State.Cookies[0].Value)
+	require.Len(t, got.State.Origins, 1)
+	assert.Equal(t, strings.TrimSuffix(tb.URL(""), "/"), got.State.Origins[0].Origin)
+	require.Len(t, got.State.Origins[0].LocalStorage, 1)
+	assert.Equal(t, "theme", got.State.Origins[0].LocalStorage[0].Name)
+	assert.Equal(t, "dark", got.State.Origins[0].LocalStorage[0].Value)
+
+	for _, r := range []restored{got.FromObject, got.FromPath} {
+		assert.Equal(t, "session=1", r.Sent)
+		assert.Equal(t, "dark", r.Theme)
+	}
+	assert.Contains(t, string(got.NotVisited), `"theme"`,
+		"the restored origins must be exported before they are visited")
+}
+
+func TestBrowserContextPermissions(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t, withHTTPServer())
+	tb.withHandler("/page", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `<html><body></body></html>`)
+	})
+
+	rt := tb.runtime()
+	require.NoError(t, rt.Set("browser", tb.Browser))
+	require.NoError(t, rt.Set("url", tb.URL("/page")))
+
+	v, err := rt.RunString(`
+		const context = browser.newContext();
+		const page = context.newPage();
+		page.goto(url);
+		const state = () => page.evaluate(() => navigator.permissions.query({ name: 'geolocation' })
+			.then(s => s.state));
+
+		const got = [state()];
+		context.grantPermissions(['geolocation']);
+		got.push(state());
+		context.clearPermissions();
+		got.push(state());
+		context.grantPermissions(['geolocation'], { origin: 'https://example.com' });
+		got.push(state());
+		context.grantPermissions(['geolocation'], { origin: new URL(url).origin });
+		got.push(state());
+		got;
+	`)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"prompt", "granted", "prompt", "prompt", "granted"}, v.Export())
+
+	_, err = rt.RunString(`browser.newContext().grantPermissions(['webcam'])`)
+	assert.ErrorContains(t, err, `invalid permission "webcam": must be one of: `)
+}
+
+func TestBrowserContextSetGeolocation(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t, withHTTPServer())
+	tb.withHandler("/page", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `<html><body></body></html>`)
+	})
+
+	rt := tb.runtime()
+	require.NoError(t, rt.Set("browser", tb.Browser))
+	require.NoError(t, rt.Set("url", tb.URL("/page")))
+
+	v, err := rt.RunString(`
+		const position = page => page.evaluate(() => new Promise(resolve => {
+			navigator.geolocation.getCurrentPosition(
+				p => resolve(p.coords.latitude + ',' + p.coords.longitude + ',' + p.coords.accuracy),
+				e => resolve('error ' + e.code),
+				{ timeout: 1000 },
+			);
+		}));
+
+		const context = browser.newContext({
+			geolocation: { latitude: 59.95, longitude: 30.31667, accuracy: 10 },
+		});
+		const page = context.newPage();
+		page.goto(url);
+		const got = [position(page)];
+
+		context.setGeolocation({ latitude: -33.86, longitude: 151.2 });
+		const later = context.newPage();
+		later.goto(url);
+		got.push(position(page), position(later));
+
+		context.setGeolocation(null);
+		got.push(page.evaluate(() => navigator.permissions.query({ name: 'geolocation' })
+			.then(s => s.state)));
+		got;
+	`)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		"59.95,30.31667,10",
+		"-33.86,151.2,0",
+		"-33.86,151.2,0",
+		"granted",
+	}, v.Export())
+
+	_, err = rt.RunString(`browser.newContext().setGeolocation({ latitude: 91, longitude: 0 })`)
+	assert.ErrorContains(t, err, `parsing geolocation: invalid latitude "91.00"`)
+}
+
+func TestBrowserContextNewCDPSession(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t, withHTTPServer())
+	tb.withHandler("/page", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `<html><body></body></html>`)
+	})
+
+	rt := tb.runtime()
+	require.NoError(t, rt.Set("browser", tb.Browser))
+	require.NoError(t, rt.Set("url", tb.URL("/page")))
+	var log []string
+	require.NoError(t, rt.Set("log", func(s string) { log = append(log, s) }))
+
+	err := tb.vu.Loop.Start(func() error {
+		_, err := rt.RunString(`
+			const context = browser.newContext();
+			const page = context.newPage();
+			const session = context.newCDPSession(page);
+
+			log(String(session.send('Browser.getVersion').product.includes('Chrome')));
+			try {
+				session.send('Nope.nope');
+			} catch (e) {
+				log(String(e).includes("'Nope.nope' wasn't found (-32601)"));
+			}
+
+			session.on('Network.requestWillBeSent', e => {
+				if (e.request.url.endsWith('/page')) {
+					log(e.request.method + ' ' + e.type);
+					session.detach();
+				}
+			});
+			session.send('Network.enable');
+			page.goto(url);
+			log(String(context.pages().length));
+		`)
+		if err != nil {
+			return fmt.Errorf("%w", err)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"true", "true", "1", "GET Document"}, log)
+
+	_, err = rt.RunString(`
+		const closed = browser.newContext();
+		const closedPage = closed.newPage();
+		const closedSession = closed.newCDPSession(closedPage);
+		closedPage.close();
+		closedSession.send('Browser.getVersion');
+	`)
+	assert.ErrorContains(t, err, "session is detached")
+}
+
+func TestBrowserContextsAndPages(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t, withHTTPServer())
+	tb.withHandler("/page", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `<html><body></body></html>`)
+	})
+
+	rt := tb.runtime()
+	require.NoError(t, rt.Set("browser", tb.Browser))
+	require.NoError(t, rt.Set("url", tb.URL("/page")))
+	var log []string
+	require.NoError(t, rt.Set("log", func(s string) { log = append(log, s) }))
+
+	err := tb.vu.Loop.Start(func() error {
+		_, err := rt.RunString(`
+			const shape = () => browser.contexts().map(c => c.pages().length).join(',');
+
+			const first = browser.newContext();
+			const second = browser.newContext();
+			const pages = [first.newPage(), first.newPage(), second.newPage()];
+			log(shape());
+			log(String(browser.contexts()[0] === first && browser.contexts()[1] === second));
+			log(String(pages.every((p, i) => p.context() === (i < 2 ? first : second))));
+			log(String(first.browser() === browser && second.browser() === browser));
+
+			pages[1].close();
+			log(shape());
+			log(String(first.pages()[0] === pages[0]));
+
+			pages[0].goto(url);
+			const popup = pages[0].waitForEvent('popup');
+			pages[0].evaluate(() => { window.open('/page'); });
+			popup.then(p => {
+				log(shape());
+				log(String(first.pages()[1] === p && p.context() === first));
+				second.close();
+				log(shape());
+			}, err => log('err: ' + err));
+		`)
+		if err != nil {
+			return fmt.Errorf("%w", err)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"2,1", "true", "true", "true",
+		"1,1", "true",
+		"2,1", "true", "2",
+	}, log)
+}
@@ -21,8 +21,12 @@
 package tests
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"image/png"
+	"net"
+	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -30,6 +34,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/grafana/xk6-browser/api"
+	"github.com/grafana/xk6-browser/chromium"
+	"github.com/grafana/xk6-browser/common"
+	"github.com/grafana/xk6-browser/k6ext"
+
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -147,6 +156,280 @@ func TestBrowserOn(t *testing.T) {
 	})
 }
 
+func TestBrowserDisconnected(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t, withSkipClose())
+	rt := tb.runtime()
+	require.NoError(t, rt.Set("browser", tb.Browser))
+	var log []string
+	require.NoError(t, rt.Set("log", func(s string) { log = append(log, s) }))
+
+	pid := k6ext.GetProcessID(tb.ctx)
+	require.NotZero(t, pid)
+	p, err := os.FindProcess(pid)
+	require.NoError(t, err)
+
+	err = tb.vu.Loop.Start(func() error {
+		_, err := rt.RunString(`
+			browser.on('disconnected', (b) => {
+				log('handler: ' + (b === browser) + ' ' + b.isConnected());
+			}).then((val) => {
+				log('ok: ' + val);
+				try {
+					browser.newContext();
+				} catch (e) {
+					log('newContext: ' + e);
+				}
+			});
+		`)
+		if err != nil {
+			return fmt.Errorf("%w", err)
+		}
+		return p.Kill() //nolint:wrapcheck
+	})
+	require.NoError(t, err)
+
+	assert.False(t, tb.IsConnected())
+	require.Len(t, log, 3)
+	assert.Equal(t, "handler: true false", log[0])
+	assert.Equal(t, "ok: true", log[1])
+	assert.Contains(t, log[2], "browser has been closed or disconnected")
+}
+
+func TestBrowserCrashed(t *testing.T) {
+	t.Parallel()
+
+	opts := defaultLaunchOpts()
+	opts.AutoRestart = true
+	tb := newTestBrowser(t, withHTTPServer(), opts)
+	tb.withHandler("/restarted", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprint(w, `<html><body>restarted</body></html>`)
+	})
+	rt := tb.runtime()
+	require.NoError(t, rt.Set("browser", tb.Browser))
+	require.NoError(t, rt.Set("crashedPage", tb.NewPage(nil)))
+	require.NoError(t, rt.Set("url", tb.URL("/restarted")))
+	var log []string
+	require.NoError(t, rt.Set("log", func(s string) { log = append(log, s) }))
+
+	pid := k6ext.GetProcessID(tb.ctx)
+	p, err := os.FindProcess(pid)
+	require.NoError(t, err)
+
+	// the browser is used from another goroutine while it restarts,
+	// so that the race detector catches unguarded restarts.
+	stopPolling := make(chan struct{})
+	polled := make(chan struct{})
+	go func() {
+		defer close(polled)
+		for {
+			select {
+			case <-stopPolling:
+				return
+			case <-time.After(time.Millisecond):
+				_ = tb.IsConnected()
+			}
+		}
+	}()
+	defer func() {
+		close(stopPolling)
+		<-polled
+	}()
+
+	err = tb.vu.Loop.Start(func() error {
+		_, err := rt.RunString(`
+			browser.on('crashed', (b) => {
+				log('handler: ' + (b === browser));
+			}).then(() => {
+				try {
+					crashedPage.evaluate(() => 1);
+				} catch (e) {
+					log('crashedPage: ' + e);
+				}
+				const page = browser.newPage();
+				page.goto(url);
+				log('newPage: ' + page.textContent('body'));
+				log('connected: ' + browser.isConnected());
+			});
+		`)
+		if err != nil {
+			return fmt.Errorf("%w", err)
+		}
+		return p.Kill() //nolint:wrapcheck
+	})
+	require.NoError(t, err)
+
+	require.Len(t, log, 4)
+	assert.Equal(t, "handler: true", log[0])
+	assert.Contains(t, log[1], "page crashed")
+	assert.Equal(t, "newPage: restarted", log[2])
+	assert.Equal(t, "connected: true", log[3])
+	assert.NotEqual(t, pid, k6ext.GetProcessID(tb.ctx), "should relaunch the browser")
+}
+
+func TestBrowserSurvivesRecoverableError(t *testing.T) {
+	t.Parallel()
+
+	t.Run("recoverable", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		p := tb.NewPage(nil)
+		require.Panics(t, func() {
+			p.WaitForSelector("#missing", tb.toGojaValue(map[string]interface{}{"timeout": 100}))
+		})
+
+		assert.True(t, tb.IsConnected(), "should not kill the browser on a selector timeout")
+		assert.Equal(t, int64(2), tb.asGojaValue(p.Evaluate(tb.toGojaValue("() => 1 + 1"))).Export())
+		assert.NotEmpty(t, p.Screenshot(nil).Bytes(), "should take a screenshot after the failure")
+	})
+
+	tests := []struct {
+		name   string
+		shared bool
+	}{
+		{name: "fatal"},
+		{name: "fatal_shared", shared: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			port := freePort(t)
+			opts := defaultLaunchOpts()
+			opts.Args = []string{fmt.Sprintf("remote-debugging-port=%d", port)}
+			opts.ReuseBrowser = tt.shared
+			tb := newTestBrowser(t, withSkipClose(), opts)
+			pid := k6ext.GetProcessID(tb.ctx)
+			require.NotZero(t, pid)
+
+			var (
+				shared   = common.NewSharedBrowser(tb.vu)
+				launches int
+			)
+			t.Cleanup(shared.Close)
+			launch := func() (api.Browser, context.CancelFunc) {
+				launches++
+				return tb.Browser, func() {}
+			}
+			if tt.shared {
+				require.Same(t, tb.Browser, shared.Browser(launch))
+			}
+
+			// lose the connection to the browser, and use the browser
+			// as a script would after it.
+			if !cutConnection(t, port) {
+				t.Skip("cutting the connection isn't supported on this platform")
+			}
+			require.Eventually(t, func() bool { return !tb.IsConnected() }, 5*time.Second, 50*time.Millisecond)
+			thrown := func() (thrown interface{}) {
+				defer func() { thrown = recover() }()
+				tb.NewContext(nil)
+				return nil
+			}()
+			require.NotNil(t, thrown, "should throw on a lost connection")
+			err := thrownError(t, thrown)
+			assert.True(t, k6ext.IsFatal(err), "should classify %q as fatal", err)
+			assert.ErrorIs(t, err, &api.TargetClosedError{})
+
+			// the browser process can't be used without its connection,
+			// whether or not it's shared, and a shared browser is
+			// replaced in the next iteration.
+			require.Eventually(t, func() bool { return !processRunning(pid) }, 5*time.Second, 50*time.Millisecond,
+				"should not leave the browser process running")
+			if !tt.shared {
+				return
+			}
+			shared.EndIteration()
+			shared.Browser(launch)
+			assert.Equal(t, 2, launches, "should replace the disconnected shared browser")
+		})
+	}
+}
+
+// freePort returns a local port that nothing listens on.
+func freePort(t *testing.T) int {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+	require.NoError(t, l.Close())
+
+	return port
+}
+
+func TestBrowserConnect(t *testing.T) {
+	t.Parallel()
+
+	// launch a browser that listens on a known port to connect to.
+	launchWithPort := func(t *testing.T) (*testBrowser, string) {
+		t.Helper()
+
+		port := freePort(t)
+		opts := defaultLaunchOpts()
+		opts.Args = []string{fmt.Sprintf("remote-debugging-port=%d", port)}
+		tb := newTestBrowser(t, withHTTPServer(), withSkipClose(), opts)
+		t.Cleanup(func() {
+			if tb.IsConnected() {
+				tb.Close()
+			}
+		})
+
+		return tb, fmt.Sprintf("http://127.0.0.1:%d", port)
+	}
+
+	t.Run("goto_screenshot", func(t *testing.T) {
+		t.Parallel()
+
+		tb, endpoint := launchWithPort(t)
+		tb.withHandler("/remote", func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = fmt.Fprint(w, `<html><body style="background: blue">remote</body></html>`)
+		})
+
+		remote := chromium.NewBrowserType(tb.vu.Context()).Connect(endpoint, nil)
+		require.True(t, remote.IsConnected())
+
+		p := remote.NewPage(nil)
+		require.NotNil(t, p.Goto(tb.URL("/remote"), nil))
+		assert.Equal(t, "remote", p.InnerText("body", nil))
+
+		img, err := png.Decode(bytes.NewReader(p.Screenshot(nil).Bytes()))
+		require.NoError(t, err)
+		assert.NotZero(t, img.Bounds().Dx())
+
+		// disconnecting leaves the remote browser running.
+		remote.Close()
+		assert.Eventually(t, func() bool { return !remote.IsConnected() }, 5*time.Second, 50*time.Millisecond)
+		assert.True(t, tb.IsConnected())
+		assert.NotNil(t, tb.NewPage(nil))
+	})
+
+	t.Run("close_on_disconnect", func(t *testing.T) {
+		t.Parallel()
+
+		tb, endpoint := launchWithPort(t)
+
+		remote := chromium.NewBrowserType(tb.vu.Context()).Connect(endpoint,
+			tb.toGojaValue(map[string]interface{}{"closeOnDisconnect": true}))
+		remote.Close()
+
+		assert.Eventually(t, func() bool { return !tb.IsConnected() }, 5*time.Second, 50*time.Millisecond)
+	})
+
+	t.Run("err_endpoint", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		rt := tb.runtime()
+		require.NoError(t, rt.Set("bt", chromium.NewBrowserType(tb.vu.Context())))
+		_, err := rt.RunString(`bt.connect('ftp://localhost')`)
+		assert.ErrorContains(t, err, "must be a ws, wss, http, or https URL")
+	})
+}
+
 // This only works for Chrome!
 func TestBrowserVersion(t *testing.T) {
 	const re = `^\d+\.\d+\.\d+\.\d+$`
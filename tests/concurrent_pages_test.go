@@ -0,0 +1,93 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A page navigates in the background while the script drives another
+// page of the same context.
+func TestConcurrentPagesOfContext(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t, withHTTPServer())
+	tb.withHandler("/nav", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<html><body>%s</body></html>`, r.URL.Query().Get("i"))
+	})
+	tb.withHandler("/counter", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `<html><body>
+			<button id="inc" onclick="window.count++">inc</button>
+			<input id="text">
+			<script>window.count = 0;</script>
+		</body></html>`)
+	})
+
+	bctx := tb.NewContext(nil)
+	a := bctx.NewPage()
+	b := bctx.NewPage()
+	b.Goto(tb.URL("/counter"), nil)
+	b.Focus("#text", nil)
+
+	rt := tb.runtime()
+	require.NoError(t, rt.Set("a", a))
+	require.NoError(t, rt.Set("b", b))
+	require.NoError(t, rt.Set("navURL", tb.URL("/nav")))
+	var (
+		count int64
+		url   string
+	)
+	require.NoError(t, rt.Set("done", func(c int64, u string) {
+		count, url = c, u
+	}))
+
+	err := tb.vu.Loop.Start(func() error {
+		_, err := rt.RunString(`
+			const rounds = 20;
+			const round = i => {
+				if (i === rounds) {
+					a.waitForURL(navURL + '?i=' + (rounds - 1));
+					done(b.evaluate(() => window.count), a.url());
+					return;
+				}
+				const u = navURL + '?i=' + i;
+				const navigated = a.waitForResponse(u);
+				a.evaluate(u => setTimeout(() => location.href = u, 0), u);
+				b.click('#inc');
+				b.keyboard.press('KeyA');
+				navigated.then(() => round(i + 1));
+			};
+			round(0);`)
+		if err != nil {
+			return fmt.Errorf("%w", err)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.EqualValues(t, 20, count)
+	assert.Equal(t, tb.URL("/nav?i=19"), url)
+	assert.Equal(t, "aaaaaaaaaaaaaaaaaaaa", b.InputValue("#text", nil))
+}
@@ -0,0 +1,51 @@
+//go:build linux
+// +build linux
+
+package tests
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+)
+
+// cutConnection shuts down the connections of the test process to the
+// given local port, such as the websocket connection to a browser that
+// listens on it, and leaves their ends open as a lost network would.
+// It reports whether it's supported on the platform.
+func cutConnection(tb testing.TB, port int) bool {
+	tb.Helper()
+
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		tb.Fatalf("listing the file descriptors: %v", err)
+	}
+	var cut int
+	for _, f := range fds {
+		fd, err := strconv.Atoi(f.Name())
+		if err != nil {
+			continue
+		}
+		sa, err := syscall.Getpeername(fd)
+		if err != nil {
+			continue
+		}
+		if sa, ok := sa.(*syscall.SockaddrInet4); !ok || sa.Port != port {
+			continue
+		}
+		if err := syscall.Shutdown(fd, syscall.SHUT_RDWR); err == nil {
+			cut++
+		}
+	}
+	if cut == 0 {
+		tb.Fatalf("no connection to port %d", port)
+	}
+
+	return true
+}
+
+// processRunning reports whether the process with the given ID is running.
+func processRunning(pid int) bool {
+	return syscall.Kill(pid, 0) == nil
+}
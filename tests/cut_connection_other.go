@@ -0,0 +1,18 @@
+//go:build !linux
+// +build !linux
+
+package tests
+
+import "testing"
+
+// cutConnection shuts down the connections of the test process to the
+// given local port. It reports whether it's supported on the platform.
+func cutConnection(tb testing.TB, port int) bool {
+	return false
+}
+
+// processRunning reports whether the process with the given ID is running.
+// It's used only after cutConnection, so it's not implemented.
+func processRunning(pid int) bool {
+	return false
+}
@@ -0,0 +1,138 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageOnDownload(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name            string
+		acceptDownloads bool
+		url             string
+		want            []string
+	}{
+		{
+			name:            "success",
+			acceptDownloads: true,
+			url:             "/export",
+			want:            []string{"report.csv", "true", ""},
+		},
+		{
+			name:            "server_aborted",
+			acceptDownloads: true,
+			url:             "/aborted",
+			want:            []string{"broken.csv", "true", "canceled"},
+		},
+		{
+			name: "not_accepted",
+			url:  "/export",
+			want: []string{
+				"report.csv", "true",
+				"canceled: set the acceptDownloads option of the browser context to accept downloads",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tb := newTestBrowser(t, withHTTPServer())
+			tb.withHandler("/downloads.html", func(w http.ResponseWriter, _ *http.Request) {
+				fmt.Fprint(w, `<html><body>
+					<a href="/export">export</a>
+					<a href="/aborted">aborted</a>
+				</body></html>`)
+			})
+			tb.withHandler("/export", func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Disposition", `attachment; filename="report.csv"`)
+				fmt.Fprint(w, "a,b\n1,2\n")
+			})
+			tb.withHandler("/aborted", func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Disposition", `attachment; filename="broken.csv"`)
+				w.Header().Set("Content-Length", "1000")
+				fmt.Fprint(w, "a,b\n")
+				w.(http.Flusher).Flush()
+				conn, _, err := w.(http.Hijacker).Hijack()
+				require.NoError(t, err)
+				require.NoError(t, conn.Close())
+			})
+			bctx := tb.NewContext(tb.toGojaValue(map[string]interface{}{
+				"acceptDownloads": tc.acceptDownloads,
+			}))
+			p := bctx.NewPage()
+			p.Goto(tb.URL("/downloads.html"), nil)
+
+			rt := tb.runtime()
+			require.NoError(t, rt.Set("page", p))
+			var (
+				log  []string
+				path string
+			)
+			require.NoError(t, rt.Set("log", func(s string) { log = append(log, s) }))
+			require.NoError(t, rt.Set("savePath", filepath.Join(t.TempDir(), "saved.csv")))
+			require.NoError(t, rt.Set("setPath", func(s string) { path = s }))
+
+			err := tb.vu.Loop.Start(func() error {
+				_, err := rt.RunString(fmt.Sprintf(`
+					page.on('download', d => {
+						log(d.suggestedFilename());
+						log(String(d.url().endsWith('%s')));
+						log(d.failure());
+						if (d.failure() === '') {
+							setPath(d.path());
+							d.saveAs(savePath);
+						}
+						page.close();
+					});
+					page.click('a[href="%s"]');`, tc.url, tc.url))
+				if err != nil {
+					return fmt.Errorf("%w", err)
+				}
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, log)
+
+			if tc.name != "success" {
+				return
+			}
+			saved, err := os.ReadFile(rt.Get("savePath").String())
+			require.NoError(t, err)
+			assert.Equal(t, "a,b\n1,2\n", string(saved))
+			// the downloaded file is removed with the browser context.
+			require.NotEmpty(t, path)
+			_, err = os.Stat(path)
+			assert.ErrorIs(t, err, os.ErrNotExist)
+		})
+	}
+}
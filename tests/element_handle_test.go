@@ -338,6 +338,72 @@ func TestElementHandleScreenshot(t *testing.T) {
 	assert.Equal(t, uint32(0), b)
 }
 
+func TestElementHandleScreenshotScrolledContainer(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t)
+	p := tb.NewPage(nil)
+	p.SetViewportSize(tb.toGojaValue(struct {
+		Width  float64 `js:"width"`
+		Height float64 `js:"height"`
+	}{Width: 800, Height: 600}))
+	p.SetContent(`
+		<body style="margin: 0; height: 3000px">
+			<div id="container" style="margin-top: 1000px; width: 300px; height: 200px; overflow: auto">
+				<div style="height: 1000px"></div>
+				<div id="target" style="margin-left: 20px; width: 50px; height: 40px; background: rgb(0, 0, 255)"></div>
+				<div style="height: 1000px"></div>
+			</div>
+			<div id="empty"></div>
+		</body>`, nil)
+	p.Evaluate(tb.toGojaValue(`() => window.scrollTo(0, 500)`))
+
+	assertBlue := func(t *testing.T, buf goja.ArrayBuffer) {
+		t.Helper()
+
+		img, err := png.Decode(bytes.NewReader(buf.Bytes()))
+		require.NoError(t, err)
+		require.Equal(t, 50, img.Bounds().Dx())
+		require.Equal(t, 40, img.Bounds().Dy())
+		for _, pt := range [][2]int{{0, 0}, {49, 0}, {0, 39}, {49, 39}, {25, 20}} {
+			r, g, b, _ := img.At(pt[0], pt[1]).RGBA()
+			assert.Equal(t, [3]uint32{0, 0, 255}, [3]uint32{r >> 8, g >> 8, b >> 8}, "pixel at %v", pt)
+		}
+	}
+
+	t.Run("element_handle", func(t *testing.T) {
+		assertBlue(t, p.Query("#target").Screenshot(nil))
+	})
+	t.Run("locator", func(t *testing.T) {
+		p.Evaluate(tb.toGojaValue(`() => {
+			window.scrollTo(0, 0);
+			document.querySelector('#container').scrollTop = 0;
+		}`))
+		assertBlue(t, p.Locator("#target", nil).Screenshot(nil))
+	})
+	screenshotErr := func(elem api.ElementHandle) (msg string) {
+		defer func() {
+			if err := recover(); err != nil {
+				errMsg, ok := err.(*goja.Object)
+				require.True(t, ok)
+				msg = errMsg.String()
+			}
+		}()
+		elem.Screenshot(tb.toGojaValue(struct {
+			Timeout int64 `js:"timeout"`
+		}{Timeout: 500}))
+		return ""
+	}
+	t.Run("zero_size", func(t *testing.T) {
+		assert.Contains(t, screenshotErr(p.Query("#empty")), "element has zero size")
+	})
+	t.Run("detached", func(t *testing.T) {
+		elem := p.Query("#empty")
+		p.Evaluate(tb.toGojaValue(`() => document.querySelector('#empty').remove()`))
+		assert.Contains(t, screenshotErr(elem), "element is not attached to the DOM")
+	})
+}
+
 func TestElementHandleWaitForSelector(t *testing.T) {
 	tb := newTestBrowser(t)
 	p := tb.NewPage(nil)
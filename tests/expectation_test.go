@@ -0,0 +1,117 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/xk6-browser/common"
+
+	k6metrics "go.k6.io/k6/metrics"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpectation(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t)
+	samples := make(chan k6metrics.SampleContainer, 1000)
+	tb.vu.StateField.Samples = samples
+
+	p := tb.NewPage(nil)
+	p.SetContent(`
+		<div id="msg"></div>
+		<a id="link" href="/home">home</a>
+		<ul><li>1</li><li>2</li></ul>
+		<script>
+			setTimeout(() => document.getElementById('msg').textContent = 'loaded', 500);
+		</script>
+	`, nil)
+
+	expect := func(selector string) *common.Expectation {
+		l, ok := p.Locator(selector, nil).(*common.Locator)
+		require.True(t, ok)
+		return l.Expect()
+	}
+	timeout := func(ms int64) goja.Value {
+		return tb.toGojaValue(map[string]interface{}{"timeout": ms})
+	}
+
+	start := time.Now()
+	assert.True(t, expect("#msg").ToHaveText("loaded", timeout(5000)),
+		"the assertion must retry until the text appears")
+	assert.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+	assert.True(t, expect("#link").ToHaveAttribute("href", "/home", nil))
+	assert.True(t, expect("#link").ToBeVisible(nil))
+	assert.True(t, expect("li").ToHaveCount(2, nil))
+
+	assert.False(t, expect("#msg").ToHaveText("missing", timeout(300)))
+
+	checks := make(map[string]float64)
+	for len(samples) > 0 {
+		for _, s := range (<-samples).GetSamples() {
+			if s.Metric.Name != k6metrics.ChecksName {
+				continue
+			}
+			name, _ := s.Tags.Get("check")
+			checks[name] = s.Value
+		}
+	}
+	assert.Equal(t, map[string]float64{
+		`expect("#msg").toHaveText("loaded")`:              1,
+		`expect("#link").toHaveAttribute("href", "/home")`: 1,
+		`expect("#link").toBeVisible()`:                    1,
+		`expect("li").toHaveCount(2)`:                      1,
+		`expect("#msg").toHaveText("missing")`:             0,
+	}, checks)
+
+	check, err := tb.vu.StateField.Group.Check(`expect("#msg").toHaveText("missing")`)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, check.Fails)
+}
+
+func TestExpectationHard(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t)
+	p := tb.NewPage(nil)
+	p.SetContent(`<div id="msg">loaded</div>`, nil)
+	require.NoError(t, tb.runtime().Set("page", p))
+	require.NoError(t, tb.runtime().Set("expect", func(l *common.Locator) *common.Expectation {
+		return l.Expect()
+	}))
+
+	_, err := tb.runtime().RunString(`
+		expect(page.locator('#msg')).toHaveText('missing', { timeout: 300 });
+		'soft';
+	`)
+	require.NoError(t, err, "a soft assertion must not abort the iteration")
+
+	_, err = tb.runtime().RunString(`
+		expect(page.locator('#msg')).toHaveText('missing', { timeout: 300, hard: true });
+	`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `expect("#msg").toHaveText("missing"): expected "missing", got "loaded"`)
+}
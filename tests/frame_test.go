@@ -0,0 +1,866 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/xk6-browser/api"
+	"github.com/grafana/xk6-browser/common"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const htmlHoverMenu = `
+<!DOCTYPE html>
+<html>
+  <head>
+	<style>
+	  #menu { width: 100px; height: 40px; }
+	  #menu .item { display: none; }
+	  #menu:hover .item { display: block; }
+	</style>
+  </head>
+  <body>
+	<div id="menu">Menu<a class="item" href="#">Item</a></div>
+	<script>
+	  window.entered = false;
+	  window.offsetX = undefined;
+	  window.offsetY = undefined;
+	  window.shiftKey = undefined;
+	  window.buttons = undefined;
+	  const menu = document.querySelector('#menu');
+	  menu.addEventListener('mouseenter', () => { entered = true; });
+	  menu.addEventListener('mousemove', e => {
+		offsetX = e.offsetX;
+		offsetY = e.offsetY;
+		shiftKey = e.shiftKey;
+		buttons = e.buttons;
+	  });
+	</script>
+  </body>
+</html>
+`
+
+func TestFrameHover(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t)
+	p := tb.NewPage(nil)
+	p.SetContent(htmlHoverMenu, nil)
+
+	require.False(t, p.IsVisible("#menu .item", nil))
+
+	p.MainFrame().Hover("#menu", tb.toGojaValue(map[string]interface{}{
+		"position":  map[string]float64{"x": 10, "y": 5},
+		"modifiers": []string{"Shift"},
+	}))
+
+	assert.True(t, p.IsVisible("#menu .item", nil), "hover should reveal the menu item")
+
+	result := p.Evaluate(tb.toGojaValue(
+		"() => ({ entered, offsetX, offsetY, shiftKey, buttons })",
+	))
+	res, ok := result.(goja.Value)
+	require.True(t, ok)
+	assert.Equal(t, map[string]interface{}{
+		"entered":  true,
+		"offsetX":  int64(10),
+		"offsetY":  int64(5),
+		"shiftKey": true,
+		"buttons":  int64(0), // hover must not press any button
+	}, res.Export())
+}
+
+func TestElementHandleHover(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t)
+	p := tb.NewPage(nil)
+	p.SetContent(htmlHoverMenu, nil)
+
+	p.Query("#menu").Hover(nil)
+
+	assert.True(t, p.IsVisible("#menu .item", nil), "hover should reveal the menu item")
+
+	result := p.Evaluate(tb.toGojaValue("() => entered"))
+	res, ok := result.(goja.Value)
+	require.True(t, ok)
+	assert.True(t, res.ToBoolean(), "expected mouseenter to be dispatched")
+}
+
+func TestFrameClickTripleClick(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t)
+	p := tb.NewPage(nil)
+	p.SetContent(`<textarea rows="3" cols="20">first line
+second line
+third line</textarea>`, nil)
+
+	p.MainFrame().Click("textarea", tb.toGojaValue(map[string]interface{}{
+		"clickCount":  3,
+		"position":    map[string]float64{"x": 5, "y": 5},
+		"noWaitAfter": true,
+	}))
+	cp, ok := p.(*common.Page)
+	require.True(t, ok)
+	cp.Keyboard.Type("new", nil)
+
+	// the triple click selects the whole first line, which is
+	// replaced by the typed text.
+	got := p.InputValue("textarea", nil)
+	assert.True(t, strings.HasPrefix(got, "new"), "got %q", got)
+	assert.NotContains(t, got, "first line")
+	assert.Contains(t, got, "second line\nthird line")
+}
+
+func TestFrameDragAndDrop(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t)
+	p := tb.NewPage(nil)
+	p.SetContent(`
+		<ul>
+		  <li id="apple" draggable="true">Apple</li>
+		  <li id="banana" draggable="true">Banana</li>
+		</ul>
+		<div id="basket" style="width: 100px; height: 100px;">Basket</div>
+		<script>
+		  window.dropped = undefined;
+		  for (const li of document.querySelectorAll('li')) {
+			li.addEventListener('dragstart', e => e.dataTransfer.setData('text/plain', e.target.id));
+		  }
+		  const basket = document.querySelector('#basket');
+		  basket.addEventListener('dragover', e => e.preventDefault());
+		  basket.addEventListener('drop', e => {
+			e.preventDefault();
+			dropped = e.dataTransfer.getData('text/plain');
+		  });
+		</script>
+	`, nil)
+
+	p.MainFrame().DragAndDrop("#banana", "#basket", nil)
+
+	result := p.Evaluate(tb.toGojaValue("() => window.dropped"))
+	res, ok := result.(goja.Value)
+	require.True(t, ok)
+	assert.Equal(t, "banana", res.String())
+}
+
+func TestFrameTap(t *testing.T) {
+	t.Parallel()
+
+	const html = `
+		<button style="width: 100px; height: 50px;">Tap me</button>
+		<script>
+		  window.tapped = undefined;
+		  document.querySelector('button').addEventListener('touchend', e => {
+			tapped = { x: e.changedTouches[0].clientX, shiftKey: e.shiftKey };
+		  });
+		</script>
+	`
+
+	t.Run("ok", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		bctx := tb.NewContext(tb.toGojaValue(struct {
+			HasTouch bool `js:"hasTouch"`
+		}{
+			HasTouch: true,
+		}))
+		t.Cleanup(bctx.Close)
+		p := bctx.NewPage()
+		p.SetContent(html, nil)
+
+		p.MainFrame().Tap("button", tb.toGojaValue(map[string]interface{}{
+			"position":  map[string]float64{"x": 10, "y": 10},
+			"modifiers": []string{"Shift"},
+		}))
+
+		result := p.Evaluate(tb.toGojaValue("() => tapped"))
+		res, ok := result.(goja.Value)
+		require.True(t, ok)
+		assert.Equal(t, map[string]interface{}{
+			"x":        int64(18), // 8px of default body margin
+			"shiftKey": true,
+		}, res.Export())
+	})
+
+	t.Run("err/no_touch", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		p := tb.NewPage(nil)
+		p.SetContent(html, nil)
+
+		var errorMsg string
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					errMsg, ok := err.(*goja.Object)
+					require.True(t, ok)
+					errorMsg = errMsg.String()
+				}
+			}()
+			p.MainFrame().Tap("button", nil)
+		}()
+		assert.Contains(t, errorMsg, common.ErrTouchNotSupported.Error())
+	})
+}
+
+func TestFrameWaitForURL(t *testing.T) {
+	t.Parallel()
+
+	// redirectPage returns a handler for a page that redirects the
+	// browser to the given path once it is loaded.
+	redirectPage := func(to string) http.HandlerFunc {
+		return func(w http.ResponseWriter, _ *http.Request) {
+			fmt.Fprintf(w, `<script>
+			  window.addEventListener('load', () => setTimeout(() => location.href = '%s', 100));
+			</script>`, to)
+		}
+	}
+	newBrowser := func(t *testing.T) *testBrowser {
+		t.Helper()
+
+		tb := newTestBrowser(t, withHTTPServer())
+		tb.withHandler("/first", redirectPage("/second"))
+		tb.withHandler("/second", redirectPage("/third"))
+		tb.withHandler("/third", func(w http.ResponseWriter, _ *http.Request) {
+			fmt.Fprint(w, `<a href="#done">done</a>`)
+		})
+		return tb
+	}
+
+	t.Run("intermediate_redirect", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newBrowser(t)
+		p := tb.NewPage(nil)
+		p.Goto(tb.URL("/first"), nil)
+
+		// second is only visited on the way to third.
+		p.MainFrame().WaitForURL(tb.toGojaValue("**/sec?nd"), nil)
+		assert.Contains(t, []string{tb.URL("/second"), tb.URL("/third")}, p.URL())
+	})
+
+	t.Run("regexp", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newBrowser(t)
+		p := tb.NewPage(nil)
+		p.Goto(tb.URL("/first"), nil)
+
+		re, err := tb.runtime().RunString(`/\/THIRD$/i`)
+		require.NoError(t, err)
+		p.WaitForURL(re, nil)
+		assert.Equal(t, tb.URL("/third"), p.URL())
+	})
+
+	t.Run("already_matches", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newBrowser(t)
+		p := tb.NewPage(nil)
+		p.Goto(tb.URL("/third"), nil)
+
+		p.WaitForURL(tb.toGojaValue(tb.URL("/third")), tb.toGojaValue(map[string]interface{}{
+			"timeout": 100,
+		}))
+	})
+
+	t.Run("same_document", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newBrowser(t)
+		p := tb.NewPage(nil)
+		p.Goto(tb.URL("/third"), nil)
+
+		p.Evaluate(tb.toGojaValue(
+			"() => setTimeout(() => history.pushState({}, '', '/fourth'), 100)",
+		))
+		p.WaitForURL(tb.toGojaValue("**/fourth"), nil)
+		assert.Equal(t, tb.URL("/fourth"), p.URL())
+
+		p.Click("a", nil)
+		p.WaitForURL(tb.toGojaValue("**/fourth#done"), nil)
+	})
+
+	t.Run("err/timeout", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newBrowser(t)
+		p := tb.NewPage(nil)
+		p.Goto(tb.URL("/third"), nil)
+
+		var errorMsg string
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					errMsg, ok := err.(*goja.Object)
+					require.True(t, ok)
+					errorMsg = errMsg.String()
+				}
+			}()
+			p.WaitForURL(tb.toGojaValue("**/never"), tb.toGojaValue(map[string]interface{}{
+				"timeout": 500,
+			}))
+		}()
+		assert.Contains(t, errorMsg, "timed out after 500ms")
+	})
+}
+
+func TestFrameWaitForSelectorStates(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name, state, change string
+	}{
+		{name: "detached/removed", state: "detached", change: "spinner.remove()"},
+		{name: "hidden/removed", state: "hidden", change: "spinner.remove()"},
+		{name: "hidden/display_none", state: "hidden", change: "spinner.style.display = 'none'"},
+		{name: "hidden/visibility_hidden", state: "hidden", change: "spinner.style.visibility = 'hidden'"},
+		{name: "hidden/zero_size", state: "hidden", change: "spinner.style.width = '0'"},
+		{name: "hidden/parent_hidden", state: "hidden", change: "document.body.style.display = 'none'"},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tb := newTestBrowser(t)
+			p := tb.NewPage(nil)
+			p.SetContent(`<div id="spinner" style="width: 20px; height: 20px;"></div>`, nil)
+
+			p.Evaluate(tb.toGojaValue(fmt.Sprintf(`() => {
+				const spinner = document.querySelector('#spinner');
+				setTimeout(() => { %s }, 100);
+			}`, tc.change)))
+
+			h := p.MainFrame().WaitForSelector("#spinner", tb.toGojaValue(map[string]interface{}{
+				"state":   tc.state,
+				"timeout": 1000,
+			}))
+			assert.Nil(t, h)
+		})
+	}
+
+	t.Run("err/detached_only_hidden", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		p := tb.NewPage(nil)
+		p.SetContent(`<div id="spinner" style="display: none;"></div>`, nil)
+
+		var errorMsg string
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					errMsg, ok := err.(*goja.Object)
+					require.True(t, ok)
+					errorMsg = errMsg.String()
+				}
+			}()
+			p.WaitForSelector("#spinner", tb.toGojaValue(map[string]interface{}{
+				"state":   "detached",
+				"timeout": 500,
+			}))
+		}()
+		assert.Contains(t, errorMsg, `waiting for selector "#spinner" to be detached`)
+		assert.Contains(t, errorMsg, "timed out after 500ms")
+	})
+}
+
+func TestFrameSetInputFiles(t *testing.T) {
+	t.Parallel()
+
+	newPage := func(t *testing.T) (*testBrowser, api.Page) {
+		t.Helper()
+
+		tb := newTestBrowser(t, withHTTPServer())
+		tb.withHandler("/upload", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				fmt.Fprint(w, `
+					<input type="file" name="file" multiple>
+					<script>
+					  window.changes = 0;
+					  const input = document.querySelector('input');
+					  input.addEventListener('change', () => changes++);
+					  window.upload = async () => {
+						const body = new FormData();
+						for (const f of input.files) body.append('file', f);
+						const resp = await fetch('/upload', { method: 'POST', body });
+						return resp.json();
+					  };
+					</script>`)
+				return
+			}
+			assert.NoError(t, r.ParseMultipartForm(1<<20))
+			type file struct {
+				Name string `json:"name"`
+				Type string `json:"type"`
+				Size int64  `json:"size"`
+			}
+			files := []file{}
+			for _, fh := range r.MultipartForm.File["file"] {
+				files = append(files, file{Name: fh.Filename, Type: fh.Header.Get("Content-Type"), Size: fh.Size})
+			}
+			assert.NoError(t, json.NewEncoder(w).Encode(files))
+		})
+		p := tb.NewPage(nil)
+		p.Goto(tb.URL("/upload"), nil)
+
+		return tb, p
+	}
+	upload := func(t *testing.T, tb *testBrowser, p api.Page) string {
+		t.Helper()
+
+		res := tb.asGojaValue(p.Evaluate(tb.toGojaValue(
+			"async () => JSON.stringify({ changes, files: await upload() })",
+		)))
+		return res.String()
+	}
+
+	t.Run("paths", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "report.txt")
+		require.NoError(t, os.WriteFile(path, []byte("hello world"), 0o600))
+
+		tb, p := newPage(t)
+		p.SetInputFiles("input", tb.toGojaValue(path), nil)
+
+		assert.JSONEq(t,
+			`{"changes": 1, "files": [{"name": "report.txt", "type": "text/plain", "size": 11}]}`,
+			upload(t, tb, p))
+	})
+
+	t.Run("payloads", func(t *testing.T) {
+		t.Parallel()
+
+		tb, p := newPage(t)
+		files, err := tb.runtime().RunString(`[
+			{ name: 'a.json', mimeType: 'application/json', buffer: new Uint8Array([123, 125]).buffer },
+			{ name: 'b.bin', mimeType: 'application/octet-stream', buffer: new ArrayBuffer(5) },
+		]`)
+		require.NoError(t, err)
+		p.Query("input").SetInputFiles(files, nil)
+
+		assert.JSONEq(t,
+			`{"changes": 1, "files": [
+				{"name": "a.json", "type": "application/json", "size": 2},
+				{"name": "b.bin", "type": "application/octet-stream", "size": 5}
+			]}`,
+			upload(t, tb, p))
+	})
+
+	t.Run("clear", func(t *testing.T) {
+		t.Parallel()
+
+		tb, p := newPage(t)
+		p.SetInputFiles("input", tb.toGojaValue(map[string]interface{}{
+			"name":   "a.txt",
+			"buffer": "a",
+		}), nil)
+		p.SetInputFiles("input", tb.toGojaValue([]string{}), nil)
+
+		assert.JSONEq(t, `{"changes": 2, "files": []}`, upload(t, tb, p))
+	})
+
+	t.Run("err/not_file_input", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		p := tb.NewPage(nil)
+		p.SetContent(`<input type="text">`, nil)
+
+		var errorMsg string
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					errMsg, ok := err.(*goja.Object)
+					require.True(t, ok)
+					errorMsg = errMsg.String()
+				}
+			}()
+			p.SetInputFiles("input", tb.toGojaValue(map[string]interface{}{
+				"name":   "a.txt",
+				"buffer": "a",
+			}), nil)
+		}()
+		assert.Contains(t, errorMsg, "node is not an <input type=file> element")
+	})
+}
+
+func TestFrameCheck(t *testing.T) {
+	t.Parallel()
+
+	const html = `
+		<input id="agree" type="checkbox">
+		<input id="checked-hidden" type="checkbox" checked style="visibility: hidden;">
+		<input id="locked" type="checkbox" onclick="event.preventDefault()">
+		<input id="red" name="color" type="radio">
+		<input id="blue" name="color" type="radio">
+		<label id="fancy" style="position: relative; display: inline-block; width: 40px; height: 20px;">
+		  <input id="fancy-input" type="checkbox" style="position: absolute; opacity: 0; left: 0; top: 0;">
+		  <span style="position: absolute; inset: 0; background: gray;"></span>
+		</label>
+		<script>
+		  window.clicks = {};
+		  for (const input of document.querySelectorAll('input')) {
+			input.addEventListener('click', () => clicks[input.id] = (clicks[input.id] || 0) + 1);
+		  }
+		</script>
+	`
+	newPage := func(t *testing.T) (*testBrowser, api.Page) {
+		t.Helper()
+
+		tb := newTestBrowser(t)
+		p := tb.NewPage(nil)
+		p.SetContent(html, nil)
+
+		return tb, p
+	}
+	clicks := func(tb *testBrowser, p api.Page, id string) int64 {
+		return tb.asGojaValue(p.Evaluate(tb.toGojaValue(
+			fmt.Sprintf("() => clicks[%q] || 0", id),
+		))).ToInteger()
+	}
+	panicMsg := func(t *testing.T, fn func()) (msg string) {
+		t.Helper()
+
+		defer func() {
+			if err := recover(); err != nil {
+				errMsg, ok := err.(*goja.Object)
+				require.True(t, ok)
+				msg = errMsg.String()
+			}
+		}()
+		fn()
+		return ""
+	}
+
+	t.Run("checkbox", func(t *testing.T) {
+		t.Parallel()
+
+		tb, p := newPage(t)
+		f := p.MainFrame()
+
+		f.Check("#agree", nil)
+		assert.True(t, f.IsChecked("#agree", nil))
+		f.Check("#agree", nil)
+		assert.Equal(t, int64(1), clicks(tb, p, "agree"), "should not click a checked checkbox")
+
+		f.Uncheck("#agree", nil)
+		assert.False(t, f.IsChecked("#agree", nil))
+		f.Uncheck("#agree", nil)
+		assert.Equal(t, int64(2), clicks(tb, p, "agree"), "should not click an unchecked checkbox")
+
+		h := p.Query("#agree")
+		h.SetChecked(true, nil)
+		assert.True(t, h.IsChecked())
+		h.SetChecked(false, nil)
+		assert.False(t, h.IsChecked())
+	})
+
+	t.Run("already_checked_and_hidden", func(t *testing.T) {
+		t.Parallel()
+
+		tb, p := newPage(t)
+		p.Check("#checked-hidden", nil)
+		assert.Equal(t, int64(0), clicks(tb, p, "checked-hidden"))
+	})
+
+	t.Run("radio", func(t *testing.T) {
+		t.Parallel()
+
+		_, p := newPage(t)
+		p.Check("#red", nil)
+		p.Query("#blue").Check(nil)
+		assert.False(t, p.IsChecked("#red", nil))
+		assert.True(t, p.IsChecked("#blue", nil))
+
+		msg := panicMsg(t, func() { p.Uncheck("#blue", nil) })
+		assert.Contains(t, msg, "cannot uncheck a radio button")
+	})
+
+	t.Run("position", func(t *testing.T) {
+		t.Parallel()
+
+		tb, p := newPage(t)
+		p.Check("#fancy", tb.toGojaValue(map[string]interface{}{
+			"position": map[string]float64{"x": 30, "y": 10},
+		}))
+		assert.True(t, p.IsChecked("#fancy-input", nil))
+	})
+
+	t.Run("force", func(t *testing.T) {
+		t.Parallel()
+
+		tb, p := newPage(t)
+		// the input is covered by the span, so it doesn't receive the events.
+		msg := panicMsg(t, func() {
+			p.Query("#fancy-input").Check(tb.toGojaValue(map[string]interface{}{"timeout": 500}))
+		})
+		assert.NotEmpty(t, msg)
+
+		p.Query("#fancy-input").Check(tb.toGojaValue(map[string]interface{}{"force": true}))
+		assert.True(t, p.IsChecked("#fancy-input", nil))
+	})
+
+	t.Run("err/state_not_changed", func(t *testing.T) {
+		t.Parallel()
+
+		_, p := newPage(t)
+		msg := panicMsg(t, func() { p.Check("#locked", nil) })
+		assert.Contains(t, msg, "clicking the checkbox did not change its state")
+	})
+}
+
+func TestFrameDragAndDropKanban(t *testing.T) {
+	t.Parallel()
+
+	newPage := func(t *testing.T) (*testBrowser, api.Page) {
+		t.Helper()
+
+		tb := newTestBrowser(t, withFileServer())
+		p := tb.NewPage(nil)
+		p.Goto(tb.staticURL("/kanban.html"), nil)
+
+		return tb, p
+	}
+	columnOf := func(tb *testBrowser, p api.Page, card string) string {
+		return tb.asGojaValue(p.Evaluate(tb.toGojaValue(
+			fmt.Sprintf("() => columnOf(%q)", card),
+		))).String()
+	}
+
+	t.Run("native", func(t *testing.T) {
+		t.Parallel()
+
+		tb, p := newPage(t)
+		p.MainFrame().DragAndDrop("#card-2", "#done", nil)
+		assert.Equal(t, "done", columnOf(tb, p, "card-2"))
+		assert.Equal(t, "todo", columnOf(tb, p, "card-1"))
+
+		p.MainFrame().DragAndDrop("#card-2", "#doing", tb.toGojaValue(map[string]interface{}{
+			"sourcePosition": map[string]float64{"x": 5, "y": 5},
+			"targetPosition": map[string]float64{"x": 100, "y": 250},
+			"steps":          5,
+		}))
+		assert.Equal(t, "doing", columnOf(tb, p, "card-2"))
+	})
+
+	t.Run("steps", func(t *testing.T) {
+		t.Parallel()
+
+		tb, p := newPage(t)
+		p.MainFrame().DragAndDrop("#card-1", "#doing", tb.toGojaValue(map[string]interface{}{
+			"native": false,
+			"steps":  10,
+		}))
+		moves := tb.asGojaValue(p.Evaluate(tb.toGojaValue("() => mouseMoves"))).ToInteger()
+		assert.GreaterOrEqual(t, moves, int64(11), "a move to the source and ten moves to the target")
+	})
+
+	t.Run("trial", func(t *testing.T) {
+		t.Parallel()
+
+		tb, p := newPage(t)
+		p.MainFrame().DragAndDrop("#card-1", "#done", tb.toGojaValue(map[string]interface{}{
+			"trial": true,
+		}))
+		assert.Equal(t, "todo", columnOf(tb, p, "card-1"))
+	})
+}
+
+func TestFrameStrictSelectors(t *testing.T) {
+	t.Parallel()
+
+	const html = `
+	<button id="first" onclick="window.clicked = this.id">One</button>
+	<button id="second" onclick="window.clicked = this.id">Two</button>
+	<input class="name"><input class="name">`
+
+	strict := map[string]interface{}{"strict": true}
+	testCases := []struct {
+		name, selector string
+		call           func(tb *testBrowser, f api.Frame, selector string)
+	}{
+		{
+			name: "click", selector: "button",
+			call: func(tb *testBrowser, f api.Frame, sel string) { f.Click(sel, tb.toGojaValue(strict)) },
+		},
+		{
+			name: "fill", selector: ".name",
+			call: func(tb *testBrowser, f api.Frame, sel string) { f.Fill(sel, "a", tb.toGojaValue(strict)) },
+		},
+		{
+			name: "textContent", selector: "button",
+			call: func(tb *testBrowser, f api.Frame, sel string) { f.TextContent(sel, tb.toGojaValue(strict)) },
+		},
+		{
+			name: "type", selector: ".name",
+			call: func(tb *testBrowser, f api.Frame, sel string) { f.Type(sel, "a", tb.toGojaValue(strict)) },
+		},
+		{
+			name: "press", selector: ".name",
+			call: func(tb *testBrowser, f api.Frame, sel string) { f.Press(sel, "a", tb.toGojaValue(strict)) },
+		},
+		{
+			name: "waitForSelector", selector: "button",
+			call: func(tb *testBrowser, f api.Frame, sel string) { f.WaitForSelector(sel, tb.toGojaValue(strict)) },
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tb := newTestBrowser(t)
+			p := tb.NewPage(nil)
+			p.SetContent(html, nil)
+
+			var errorMsg string
+			func() {
+				defer func() {
+					if err := recover(); err != nil {
+						errMsg, ok := err.(*goja.Object)
+						require.True(t, ok)
+						errorMsg = errMsg.String()
+					}
+				}()
+				tc.call(tb, p.MainFrame(), tc.selector)
+			}()
+			assert.Contains(t, errorMsg,
+				fmt.Sprintf("strict mode violation: selector %q resolved to 2 elements", tc.selector))
+		})
+	}
+
+	t.Run("previews", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		p := tb.NewPage(nil)
+		p.SetContent(html, nil)
+
+		var errorMsg string
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					errMsg, ok := err.(*goja.Object)
+					require.True(t, ok)
+					errorMsg = errMsg.String()
+				}
+			}()
+			p.Click("button >> nth=0 >> xpath=.. >> button", tb.toGojaValue(strict))
+		}()
+		assert.Contains(t, errorMsg, `selector "button >> nth=0 >> xpath=.. >> button" resolved to 2 elements`)
+		assert.Contains(t, errorMsg, `1) <button id="first"`)
+		assert.Contains(t, errorMsg, `2) <button id="second"`)
+		assert.False(t, tb.asGojaBool(p.Evaluate(tb.toGojaValue(`() => "clicked" in window`))))
+	})
+
+	t.Run("default", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		p := tb.NewPage(nil)
+		p.SetContent(html, nil)
+
+		p.Click("button", nil)
+		assert.Equal(t, "first", tb.asGojaValue(p.Evaluate(tb.toGojaValue(`() => window.clicked`))).String())
+		assert.Equal(t, "One", p.TextContent("button", nil))
+	})
+
+	t.Run("context", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		bctx := tb.NewContext(tb.toGojaValue(map[string]interface{}{
+			"strictSelectors": true,
+		}))
+		p := bctx.NewPage()
+		p.SetContent(html, nil)
+
+		var errorMsg string
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					errMsg, ok := err.(*goja.Object)
+					require.True(t, ok)
+					errorMsg = errMsg.String()
+				}
+			}()
+			p.Click("button", nil)
+		}()
+		assert.Contains(t, errorMsg, `strict mode violation: selector "button" resolved to 2 elements`)
+
+		// selectors that resolve to a single element keep working.
+		p.Click("#second", nil)
+		assert.Equal(t, "second", tb.asGojaValue(p.Evaluate(tb.toGojaValue(`() => window.clicked`))).String())
+
+		// the strict option of an action overrides the one of the context.
+		nonStrict := tb.toGojaValue(map[string]interface{}{"strict": false})
+		p.Click("button", nonStrict)
+		assert.Equal(t, "first", tb.asGojaValue(p.Evaluate(tb.toGojaValue(`() => window.clicked`))).String())
+		assert.Equal(t, "One", p.TextContent("button", nonStrict))
+		assert.NotNil(t, p.WaitForSelector("button", nonStrict))
+	})
+}
+
+func TestFrameFrameElement(t *testing.T) {
+	t.Parallel()
+
+	const html = `
+		<iframe id="editor" style="width: 300px; height: 200px; border: 0"
+			srcdoc="<textarea style='width: 100%; height: 100%; box-sizing: border-box'></textarea>">
+		</iframe>`
+
+	t.Run("focus_and_type", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		p := tb.NewPage(nil)
+		p.SetContent(html, nil)
+
+		child := p.Query("#editor").ContentFrame()
+		require.NotNil(t, child)
+		el := child.FrameElement()
+		require.NotNil(t, el)
+		assert.Equal(t, "editor", el.GetAttribute("id").String())
+
+		// clicking the iframe element focuses the textarea in the child frame.
+		el.Click(nil)
+		cp, ok := p.(*common.Page)
+		require.True(t, ok)
+		cp.Keyboard.Type("hello", nil)
+		assert.Equal(t, "hello", child.InputValue("textarea", nil))
+
+		assert.Panics(t, func() { p.MainFrame().FrameElement() }, "main frame has no frame element")
+	})
+
+	t.Run("detached", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		p := tb.NewPage(nil)
+		p.SetContent(html, nil)
+
+		child := p.Query("#editor").ContentFrame()
+		require.NotNil(t, child)
+		p.Evaluate(tb.toGojaValue(`() => document.querySelector('#editor').remove()`))
+		assert.Eventually(t, child.IsDetached, 5*time.Second, 50*time.Millisecond)
+
+		assert.Panics(t, func() { child.FrameElement() })
+	})
+}
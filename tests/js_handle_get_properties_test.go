@@ -22,9 +22,11 @@ package tests
 
 import (
 	_ "embed"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestJSHandleGetProperties(t *testing.T) {
@@ -45,3 +47,40 @@ func TestJSHandleGetProperties(t *testing.T) {
 	value := props["prop1"].JSONValue().String()
 	assert.Equal(t, value, "one", `expected property value of "one", got %q`, value)
 }
+
+func TestJSHandleAsArray(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t)
+	p := tb.NewPage(nil)
+	p.SetContent(`<ul></ul>
+		<script>
+			const ul = document.querySelector('ul');
+			for (let i = 0; i < 100; i++) {
+				const li = document.createElement('li');
+				li.textContent = 'item ' + i;
+				ul.appendChild(li);
+			}
+		</script>`, nil)
+
+	rt := tb.runtime()
+	require.NoError(t, rt.Set("page", p))
+	got, err := rt.RunString(`
+		const items = page.evaluateHandle(() => document.querySelectorAll('li')).asArray();
+		items.map(item => item.textContent());
+	`)
+	require.NoError(t, err)
+	want := make([]interface{}, 100)
+	for i := range want {
+		want[i] = fmt.Sprintf("item %d", i)
+	}
+	assert.Equal(t, want, got.Export())
+
+	// the items of arrays that aren't DOM nodes are JS handles.
+	items := p.EvaluateHandle(tb.toGojaValue(`() => [1, 'two', { three: 3 }]`)).AsArray()
+	require.Len(t, items, 3)
+	assert.Nil(t, items[0].AsElement())
+	assert.Equal(t, int64(1), items[0].JSONValue().Export())
+	assert.Equal(t, "two", items[1].JSONValue().Export())
+	assert.Equal(t, map[string]interface{}{"three": float64(3)}, items[2].JSONValue().Export())
+}
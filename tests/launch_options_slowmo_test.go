@@ -234,7 +234,12 @@ func testSlowMoImpl(t *testing.T, tb *testBrowser, fn func(*testBrowser)) {
 	defer hooks.Register(common.HookApplySlowMo, currentHook)
 	hooks.Register(common.HookApplySlowMo, func(ctx context.Context) {
 		currentHook(ctx)
-		chCalled <- true
+		// actions may be slowed down more than once, e.g. a click
+		// dispatches multiple mouse events.
+		select {
+		case chCalled <- true:
+		default:
+		}
 	})
 
 	didSlowMo := false
@@ -179,6 +179,7 @@ func TestLocator(t *testing.T) {
 		},
 	}
 	for _, tt := range tests {
+		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
@@ -254,6 +255,7 @@ func TestLocator(t *testing.T) {
 		},
 	}
 	for _, tt := range sanityTests {
+		tt := tt
 		t.Run("timeout/"+tt.name, func(t *testing.T) {
 			t.Parallel()
 
@@ -309,6 +311,7 @@ func TestLocatorElementState(t *testing.T) {
 	}
 
 	for _, tt := range tests {
+		tt := tt
 		t.Run(tt.state, func(t *testing.T) {
 			t.Parallel()
 
@@ -352,6 +355,7 @@ func TestLocatorElementState(t *testing.T) {
 		},
 	}
 	for _, tt := range sanityTests {
+		tt := tt
 		t.Run("timeout/"+tt.name, func(t *testing.T) {
 			t.Parallel()
 
@@ -371,3 +375,49 @@ func TestLocatorElementState(t *testing.T) {
 		})
 	}
 }
+
+func TestLocatorReResolvesReplacedElements(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t)
+	p := tb.NewPage(nil)
+	p.SetContent(`
+		<div id="app"></div>
+		<script>
+		  window.render = (version) => {
+			document.querySelector('#app').innerHTML = '<button id="save">Save v' + version + '</button>' +
+			  '<input id="name">';
+			document.querySelector('#save').addEventListener('click', () => window.clicked = version);
+		  };
+		  render(1);
+		</script>
+	`, nil)
+
+	save := p.Locator("#save", nil)
+	name := p.Locator("#name", nil)
+	stale := p.Query("#save")
+
+	// re-render the app, which replaces the matched elements.
+	p.Evaluate(tb.toGojaValue("() => render(2)"))
+
+	assert.Equal(t, "Save v2", save.TextContent(nil))
+	save.Click(nil)
+	assert.Equal(t, int64(2), tb.asGojaValue(p.Evaluate(tb.toGojaValue("() => window.clicked"))).ToInteger())
+
+	name.Fill("k6", nil)
+	assert.Equal(t, "k6", name.InputValue(nil))
+
+	// unlike the locator, the element handle still refers to the removed element.
+	connected := stale.Evaluate(tb.toGojaValue("el => el.isConnected"))
+	assert.False(t, tb.asGojaBool(connected))
+
+	// the locator waits for the element that replaces the current one.
+	p.Evaluate(tb.toGojaValue(`() => {
+		document.querySelector('#app').innerHTML = '';
+		setTimeout(() => render(3), 100);
+	}`))
+	save.WaitFor(nil)
+	name.Type("k6", nil)
+	assert.Equal(t, "k6", name.InputValue(nil))
+	assert.Equal(t, "Save v3", save.TextContent(nil))
+}
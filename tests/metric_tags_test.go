@@ -0,0 +1,86 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	k6lib "go.k6.io/k6/lib"
+	k6metrics "go.k6.io/k6/metrics"
+)
+
+func TestMetricGroupTags(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t, withHTTPServer())
+	tb.withHandler("/page", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `<link rel="stylesheet" href="/style.css"><h1>Groups</h1>`)
+	})
+	tb.withHandler("/style.css", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/css")
+		fmt.Fprint(w, `h1 { color: red; }`)
+	})
+	samples := make(chan k6metrics.SampleContainer, 1000)
+	tb.vu.StateField.Samples = samples
+
+	// enter sets the group of the VU, like the group function of k6 does.
+	state := tb.vu.StateField
+	enter := func(g *k6lib.Group) {
+		state.Group = g
+		state.Tags.Set("group", g.Path)
+	}
+	root := state.Group
+	outer, err := root.Group("outer")
+	require.NoError(t, err)
+	inner, err := outer.Group("inner")
+	require.NoError(t, err)
+
+	p := tb.NewPage(nil)
+	enter(outer)
+	enter(inner)
+	require.NotNil(t, p.Goto(tb.URL("/page"), nil))
+	// the script leaves the groups while the page is still loading.
+	enter(outer)
+	enter(root)
+
+	got := make(map[string]string)
+	require.Eventually(t, func() bool {
+		for len(samples) > 0 {
+			for _, s := range (<-samples).GetSamples() {
+				switch s.Metric.Name {
+				case "http_req_duration", "data_sent", "browser_navigation_load_time":
+					tags := s.Tags.CloneTags()
+					got[s.Metric.Name+" "+tags["url"]] = tags["group"]
+				}
+			}
+		}
+		return len(got) == 5
+	}, 5*time.Second, 50*time.Millisecond)
+
+	for name, group := range got {
+		assert.Equal(t, "::outer::inner", group, name)
+	}
+}
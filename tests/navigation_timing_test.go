@@ -0,0 +1,73 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	k6metrics "go.k6.io/k6/metrics"
+)
+
+func TestNavigationTiming(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t, withHTTPServer())
+	tb.withHandler("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/page?id=1", http.StatusFound)
+	})
+	tb.withHandler("/page", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `<h1>Navigation timing</h1>`)
+	})
+	samples := make(chan k6metrics.SampleContainer, 1000)
+	tb.vu.StateField.Samples = samples
+
+	bctx := tb.NewContext(tb.toGojaValue(map[string]interface{}{
+		"metricURLGrouping": "stripQuery",
+	}))
+	p := bctx.NewPage()
+	p.Goto(tb.URL("/redirect"), nil)
+
+	// the timing is emitted after the load event handlers run.
+	got := make(map[string]map[string]string)
+	require.Eventually(t, func() bool {
+		for len(samples) > 0 {
+			for _, s := range (<-samples).GetSamples() {
+				switch s.Metric.Name {
+				case "browser_navigation_ttfb", "browser_navigation_fcp",
+					"browser_navigation_dom_content_loaded", "browser_navigation_load_time":
+					assert.Greater(t, s.Value, 0.0)
+					got[s.Metric.Name] = s.Tags.CloneTags()
+				}
+			}
+		}
+		return len(got) == 4
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// the timing is attributed to the final URL without its query string.
+	for name, tags := range got {
+		assert.Equal(t, tb.URL("/page"), tags["url"], name)
+	}
+}
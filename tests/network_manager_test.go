@@ -22,7 +22,9 @@ package tests
 
 import (
 	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/grafana/xk6-browser/api"
@@ -127,3 +129,102 @@ func TestBasicAuth(t *testing.T) {
 		assert.Equal(t, http.StatusUnauthorized, int(resp.Status()))
 	})
 }
+
+func TestHTTPCredentialsSubresources(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t, withHTTPServer())
+	bctx := tb.NewContext(tb.toGojaValue(map[string]interface{}{
+		"httpCredentials": map[string]string{"username": "user", "password": "pass"},
+	}))
+	t.Cleanup(bctx.Close)
+	p := bctx.NewPage()
+	require.NotNil(t, p.Goto(tb.URL("/get"), nil))
+
+	status := func(path string) int64 {
+		t.Helper()
+
+		v := p.Evaluate(tb.toGojaValue(fmt.Sprintf(`() => fetch(%q).then(r => r.status)`, path)))
+		return tb.asGojaValue(v).ToInteger()
+	}
+	assert.Equal(t, int64(http.StatusOK), status("/basic-auth/user/pass"), "basic auth")
+	assert.Equal(t, int64(http.StatusOK), status("/digest-auth/auth/user/pass"), "digest auth")
+	assert.Equal(t, int64(http.StatusUnauthorized), status("/basic-auth/user/other"),
+		"wrong credentials should get the 401 response")
+}
+
+// newTestProxy returns a forward proxy that tags the requests that it
+// forwards with the tag. It authenticates the clients if user is set.
+func newTestProxy(t *testing.T, tag, user, pass string) *httptest.Server {
+	t.Helper()
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user != "" {
+			auth := &http.Request{Header: http.Header{"Authorization": r.Header.Values("Proxy-Authorization")}}
+			if u, p, ok := auth.BasicAuth(); !ok || u != user || p != pass {
+				w.Header().Set("Proxy-Authenticate", `Basic realm="proxy"`)
+				w.WriteHeader(http.StatusProxyAuthRequired)
+				return
+			}
+		}
+		r.RequestURI = ""
+		r.Header.Del("Proxy-Authorization")
+		r.Header.Set("X-Proxy-Tag", tag)
+		resp, err := http.DefaultTransport.RoundTrip(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close() //nolint:errcheck
+		for k, vs := range resp.Header {
+			w.Header()[k] = vs
+		}
+		w.WriteHeader(resp.StatusCode)
+		_, _ = io.Copy(w, resp.Body)
+	}))
+	t.Cleanup(proxy.Close)
+
+	return proxy
+}
+
+func TestBrowserContextProxy(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t, withHTTPServer())
+	tb.withHandler("/tag", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "tag:%s", r.Header.Get("X-Proxy-Tag"))
+	})
+	proxyA := newTestProxy(t, "a", "", "")
+	proxyB := newTestProxy(t, "b", "user", "pass")
+
+	// visit returns the status and the body of the tag page
+	// in a new browser context with the proxy options.
+	visit := func(proxy map[string]interface{}) (int64, string) {
+		t.Helper()
+
+		opts := map[string]interface{}{}
+		if proxy != nil {
+			opts["proxy"] = proxy
+		}
+		bctx := tb.NewContext(tb.toGojaValue(opts))
+		t.Cleanup(bctx.Close)
+
+		resp := bctx.NewPage().Goto(tb.URL("/tag"), nil)
+		require.NotNil(t, resp)
+		return resp.Status(), string(resp.Body().Bytes())
+	}
+
+	status, body := visit(map[string]interface{}{"server": proxyA.URL})
+	assert.Equal(t, int64(http.StatusOK), status)
+	assert.Equal(t, "tag:a", body, "should use the proxy of the context")
+
+	status, body = visit(map[string]interface{}{
+		"server": proxyB.URL, "username": "user", "password": "pass",
+	})
+	assert.Equal(t, int64(http.StatusOK), status)
+	assert.Equal(t, "tag:b", body, "should authenticate to the proxy")
+
+	status, body = visit(nil)
+	assert.Equal(t, int64(http.StatusOK), status)
+	assert.Equal(t, "tag:", body, "context without a proxy should not use one")
+}
@@ -0,0 +1,184 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/grafana/xk6-browser/api"
+	"github.com/grafana/xk6-browser/common"
+
+	k6metrics "go.k6.io/k6/metrics"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageReuseAcrossIterations(t *testing.T) {
+	t.Parallel()
+
+	opts := defaultLaunchOpts()
+	opts.ReusePage = true
+	tb := newTestBrowser(t, withLaunchOptions(opts), withHTTPServer(), withSkipClose())
+	tb.withHandler("/storage", func(w http.ResponseWriter, _ *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "1", Path: "/"})
+		fmt.Fprint(w, `<html><body></body></html>`)
+	})
+	samples := make(chan k6metrics.SampleContainer, 1000)
+	tb.vu.StateField.Samples = samples
+
+	shared := common.NewSharedBrowser(tb.vu)
+	t.Cleanup(shared.Close)
+	launch := func() (api.Browser, context.CancelFunc) {
+		return tb.Browser, func() {}
+	}
+
+	rt := tb.runtime()
+	require.NoError(t, rt.Set("baseURL", tb.URL("")))
+
+	// iterate runs a single iteration with the shared browser,
+	// and returns what the script returns as JSON.
+	iterate := func(script string) string {
+		t.Helper()
+
+		require.NoError(t, rt.Set("browser", shared.Browser(launch)))
+		v, err := rt.RunString(`(() => {
+			const page = browser.newPage();
+			` + script + `
+		})()`)
+		require.NoError(t, err)
+		_, err = rt.RunString(`browser.close()`)
+		require.NoError(t, err)
+		shared.EndIteration()
+
+		return v.String()
+	}
+
+	got := iterate(`
+		page.route('**/blocked', route => route.abort());
+		page.goto(baseURL + '/storage');
+		page.evaluate(() => {
+			localStorage.setItem('written', '1');
+			sessionStorage.setItem('written', '1');
+		});
+		return JSON.stringify({
+			cookies: page.context().cookies().length,
+			storage: page.evaluate(() => localStorage.getItem('written')),
+		});
+	`)
+	assert.JSONEq(t, `{"cookies":1,"storage":"1"}`, got)
+	require.Len(t, tb.Contexts(), 1, "the reused context must be kept")
+
+	got = iterate(`
+		const blank = page.url();
+		page.goto(baseURL + '/storage');
+		return JSON.stringify({
+			blank: blank,
+			storage: page.evaluate(() => localStorage.getItem('written')),
+			session: page.evaluate(() => sessionStorage.getItem('written')),
+			routed: page.evaluate(() => fetch('/blocked').then(r => r.status, () => 'aborted')),
+		});
+	`)
+	assert.JSONEq(t, `{
+		"blank": "about:blank",
+		"storage": null,
+		"session": null,
+		"routed": 404
+	}`, got, "the state of the last iteration must be reset")
+
+	var resets int
+	for len(samples) > 0 {
+		for _, s := range (<-samples).GetSamples() {
+			if s.Metric.Name == "browser_reset_duration" {
+				resets++
+			}
+		}
+	}
+	assert.Equal(t, 1, resets)
+}
+
+func TestPageReuseCookiesCleared(t *testing.T) {
+	t.Parallel()
+
+	opts := defaultLaunchOpts()
+	opts.ReusePage = true
+	tb := newTestBrowser(t, withLaunchOptions(opts), withHTTPServer(), withSkipClose())
+
+	shared := common.NewSharedBrowser(tb.vu)
+	t.Cleanup(shared.Close)
+	launch := func() (api.Browser, context.CancelFunc) {
+		return tb.Browser, func() {}
+	}
+
+	b := shared.Browser(launch)
+	bctx := b.NewContext(nil)
+	bctx.AddCookies(tb.toGojaValue([]map[string]interface{}{
+		{"name": "session", "value": "1", "url": tb.URL("")},
+	}))
+	p := bctx.NewPage()
+	require.Len(t, bctx.Cookies(), 1)
+	shared.EndIteration()
+
+	b = shared.Browser(launch)
+	reused := b.NewContext(nil)
+	assert.Same(t, bctx, reused)
+	assert.Same(t, p, reused.NewPage())
+	assert.NotSame(t, p, reused.NewPage(), "the page is reused once per iteration")
+	assert.Empty(t, reused.Cookies())
+}
+
+func TestPageReuseResetFails(t *testing.T) {
+	t.Parallel()
+
+	opts := defaultLaunchOpts()
+	opts.ReusePage = true
+	tb := newTestBrowser(t, withLaunchOptions(opts), withSkipClose())
+
+	shared := common.NewSharedBrowser(tb.vu)
+	t.Cleanup(shared.Close)
+	launch := func() (api.Browser, context.CancelFunc) {
+		return tb.Browser, func() {}
+	}
+
+	b := shared.Browser(launch)
+	bctx := b.NewContext(nil)
+	p := bctx.NewPage()
+	// the navigation to about:blank of the reset times out
+	// while the page runs its unload handler.
+	p.SetDefaultNavigationTimeout(1)
+	require.NoError(t, tb.runtime().Set("page", p))
+	_, err := tb.runtime().RunString(`page.evaluate(() => {
+		window.addEventListener('unload', () => {
+			const end = Date.now() + 1000;
+			while (Date.now() < end) {}
+		});
+	})`)
+	require.NoError(t, err)
+	shared.EndIteration()
+
+	b = shared.Browser(launch)
+	var fresh api.BrowserContext
+	require.NotPanics(t, func() { fresh = b.NewContext(nil) })
+	assert.NotSame(t, bctx, fresh, "the context that fails to reset must be replaced")
+}
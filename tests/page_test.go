@@ -27,8 +27,19 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"image"
+	"image/color"
 	"image/png"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/grafana/xk6-browser/api"
 
 	"github.com/dop251/goja"
 	"github.com/stretchr/testify/assert"
@@ -74,6 +85,151 @@ func TestPageEmulateMedia(t *testing.T) {
 	assert.True(t, res.ToBoolean(), "expected reduced motion setting to be 'reduce'")
 }
 
+func TestPageAddInitScript(t *testing.T) {
+	t.Parallel()
+
+	const script = `Object.defineProperty(navigator, 'webdriver', { get: () => 'overridden' })`
+
+	newBrowser := func(t *testing.T) *testBrowser {
+		t.Helper()
+
+		tb := newTestBrowser(t, withHTTPServer())
+		tb.withHandler("/page", func(w http.ResponseWriter, _ *http.Request) {
+			fmt.Fprint(w, `<script>window.seen = navigator.webdriver</script><iframe src="/frame"></iframe>`)
+		})
+		tb.withHandler("/frame", func(w http.ResponseWriter, _ *http.Request) {
+			fmt.Fprint(w, `<script>window.seen = navigator.webdriver</script>`)
+		})
+		return tb
+	}
+	assertOverridden := func(t *testing.T, tb *testBrowser, p api.Page) {
+		t.Helper()
+
+		p.Goto(tb.URL("/page"), nil)
+		frames := p.Frames()
+		require.Len(t, frames, 2)
+		for _, f := range frames {
+			seen := tb.asGojaValue(f.Evaluate(tb.toGojaValue("() => window.seen")))
+			assert.Equal(t, "overridden", seen.String(), "frame %q", f.URL())
+		}
+	}
+
+	t.Run("page", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newBrowser(t)
+		p := tb.NewPage(nil)
+		p.AddInitScript(tb.toGojaValue(script), nil)
+		assertOverridden(t, tb, p)
+	})
+
+	t.Run("function_arg", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newBrowser(t)
+		p := tb.NewPage(nil)
+		fn, err := tb.runtime().RunString(`(v => Object.defineProperty(navigator, 'webdriver', { get: () => v }))`)
+		require.NoError(t, err)
+		p.AddInitScript(fn, tb.toGojaValue("overridden"))
+		assertOverridden(t, tb, p)
+	})
+
+	t.Run("browser_context", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newBrowser(t)
+		bctx := tb.NewContext(nil)
+		current := bctx.NewPage()
+		bctx.AddInitScript(tb.toGojaValue(struct {
+			Content string `js:"content"`
+		}{Content: script}), nil)
+		assertOverridden(t, tb, current)
+		assertOverridden(t, tb, bctx.NewPage())
+
+		// the pages of other contexts are not affected.
+		p := tb.NewContext(nil).NewPage()
+		p.Goto(tb.URL("/page"), nil)
+		assert.True(t, tb.asGojaBool(p.Evaluate(tb.toGojaValue("() => window.seen"))))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		p := tb.NewPage(nil)
+		assert.Panics(t, func() { p.AddInitScript(tb.toGojaValue(42), nil) })
+	})
+}
+
+func TestPageEmulateMediaPersistsAndResets(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t, withHTTPServer())
+	tb.withHandler("/media", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `<style>@media print { p { display: none; } }</style><p>screen only</p>`)
+	})
+	p := tb.NewPage(nil)
+	matches := func(query string) bool {
+		return tb.asGojaBool(p.Evaluate(tb.toGojaValue(fmt.Sprintf("() => matchMedia(%q).matches", query))))
+	}
+
+	p.EmulateMedia(tb.toGojaValue(map[string]interface{}{
+		"media":       "print",
+		"colorScheme": "dark",
+	}))
+	p.Goto(tb.URL("/media"), nil)
+	assert.True(t, matches("(prefers-color-scheme: dark)"), "color scheme should persist across navigations")
+	assert.False(t, p.IsVisible("p", nil), "print media rule should apply")
+
+	p.EmulateMedia(tb.toGojaValue(map[string]interface{}{
+		"media":       nil,
+		"colorScheme": "light",
+	}))
+	assert.True(t, matches("(prefers-color-scheme: light)"))
+	assert.True(t, p.IsVisible("p", nil), "print media rule should not apply after reset")
+
+	assert.Panics(t, func() {
+		p.EmulateMedia(tb.toGojaValue(map[string]interface{}{"colorScheme": "sepia"}))
+	})
+}
+
+func TestPageEmulateNetworkConditions(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t, withHTTPServer())
+	tb.withHandler("/api", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, "ok")
+	})
+	p := tb.NewPage(nil)
+	p.Goto(tb.URL("/api"), nil)
+
+	// fetchDuration returns how long it takes to fetch the API in the page.
+	fetchDuration := func() time.Duration {
+		t.Helper()
+
+		v := p.Evaluate(tb.toGojaValue(`async () => {
+			const start = performance.now();
+			await fetch('/api', { cache: 'no-store' }).then(r => r.text());
+			return performance.now() - start;
+		}`))
+		return time.Duration(tb.asGojaValue(v).ToFloat() * float64(time.Millisecond))
+	}
+
+	const latency = 500 * time.Millisecond
+	assert.Less(t, fetchDuration(), latency, "fetch should not be throttled by default")
+
+	p.EmulateNetworkConditions(tb.toGojaValue(map[string]interface{}{
+		"latency": latency.Milliseconds(),
+	}))
+	assert.GreaterOrEqual(t, fetchDuration(), latency, "fetch should be throttled")
+
+	p.EmulateNetworkConditions(goja.Null())
+	assert.Less(t, fetchDuration(), latency, "fetch should not be throttled after the reset")
+
+	p.EmulateNetworkConditions(tb.toGojaValue("Slow 3G"))
+	assert.GreaterOrEqual(t, fetchDuration(), 2*time.Second, "fetch should be throttled by the preset")
+}
+
 func TestPageContent(t *testing.T) {
 	t.Parallel()
 
@@ -106,6 +262,187 @@ func TestPageEvaluate(t *testing.T) {
 		assert.Equal(t, "test", gotVal.Export())
 	})
 
+	t.Run("ok/rich_values", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		rt := tb.runtime()
+		require.NoError(t, rt.Set("page", tb.NewPage(nil)))
+
+		got, err := rt.RunString(`
+			const v = page.evaluate(() => ({
+				when: new Date(0),
+				tags: new Set(['a', 'b']),
+				meta: new Map([['k', 1]]),
+				big: BigInt(10),
+				re: /a+/g,
+				err: new RangeError('out'),
+				nested: [{ at: new Date(1000), ids: new Set([new Map([['id', BigInt(1)]])]) }],
+			}));
+			[
+				v.when instanceof Date && v.when.getTime() === 0,
+				v.tags.join() === 'a,b',
+				v.meta.k === 1,
+				v.big === 10,
+				v.re instanceof RegExp && v.re.source === 'a+' && v.re.flags === 'g',
+				v.err instanceof RangeError && v.err.message === 'out' && v.err.stack.includes('RangeError'),
+				v.nested[0].at.getTime() === 1000 && v.nested[0].ids[0].id === 1,
+				page.evaluate(() => BigInt('18446744073709551616')) === '18446744073709551616',
+				page.evaluate(() => new Date(0)).toISOString() === '1970-01-01T00:00:00.000Z',
+			];
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, []interface{}{true, true, true, true, true, true, true, true, true}, got.Export())
+	})
+
+	t.Run("ok/nested_handles", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		p := tb.NewPage(nil)
+		p.SetContent(`<div id="a">A</div><div id="b">B</div>`, nil)
+		rt := tb.runtime()
+		require.NoError(t, rt.Set("page", p))
+
+		got, err := rt.RunString(`
+			const a = page.$('#a'), b = page.$('#b');
+			[
+				page.evaluate(([el, n]) => el.textContent + n, [a, 42]),
+				page.evaluate(o => o.el.id + o.n, { el: b, n: 1 }),
+				page.evaluate(o => o.list[0].el.textContent + o.list[1].id, {
+					list: [{ el: a }, page.evaluateHandle(() => ({ id: 'b' }))],
+				}),
+				page.evaluate((el, [other]) => el.id + other.id, a, [b]),
+			];
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, []interface{}{"A42", "b1", "Ab", "ab"}, got.Export())
+	})
+
+	t.Run("ok/date_args", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		rt := tb.runtime()
+		require.NoError(t, rt.Set("page", tb.NewPage(nil)))
+
+		got, err := rt.RunString(`
+			const when = new Date(1500);
+			[
+				page.evaluate(d => d instanceof Date && d.getTime(), when),
+				page.evaluate(o => o.at instanceof Date && o.at.getTime(), { at: when }),
+				page.evaluate(([d]) => d instanceof Date && d.getTime() + 1, [when]),
+				page.evaluate(d => d, when).getTime(),
+			];
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, []interface{}{int64(1500), int64(1500), int64(1501), int64(1500)}, got.Export())
+	})
+
+	t.Run("ok/bigint_args", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		rt := tb.runtime()
+		require.NoError(t, rt.Set("page", tb.NewPage(nil)))
+		huge, ok := new(big.Int).SetString("-1"+strings.Repeat("0", 99), 10)
+		require.True(t, ok)
+		require.NoError(t, rt.Set("huge", huge))
+
+		got, err := rt.RunString(`
+			const isBig = v => typeof v === 'bigint' && v.toString();
+			[
+				page.evaluate(isBig, 2147483648),
+				page.evaluate(isBig, -2147483649),
+				page.evaluate(isBig, huge),
+				page.evaluate(([v]) => typeof v === 'bigint' && v.toString(), [huge]),
+				page.evaluate(v => v, huge),
+			];
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, []interface{}{
+			"2147483648", "-2147483649", huge.String(), huge.String(), huge.String(),
+		}, got.Export())
+	})
+
+	t.Run("ok/binary_args", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		rt := tb.runtime()
+		require.NoError(t, rt.Set("page", tb.NewPage(nil)))
+
+		got, err := rt.RunString(`
+			const bytes = v => v instanceof ArrayBuffer ? Array.from(new Uint8Array(v)) :
+				v.constructor.name + ':' + Array.from(new Uint8Array(v.buffer, v.byteOffset, v.byteLength));
+			[
+				page.evaluate(bytes, new Uint8Array([1, 2, 255]).buffer),
+				page.evaluate(bytes, new ArrayBuffer(0)),
+				page.evaluate(bytes, new Uint8Array([1, 2, 3, 4]).subarray(1, 3)),
+				page.evaluate(({ list }) => list.map(bytes), { list: [new Uint16Array([258])] }),
+				Array.from(new Uint8Array(page.evaluate(() => new Uint8Array([1, 2, 3])))),
+				page.evaluate(() => new ArrayBuffer(0)).byteLength,
+				Array.from(new Uint8Array(page.evaluate(v => v, new Uint8Array([9]).buffer))),
+			];
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, []interface{}{
+			[]interface{}{int64(1), int64(2), int64(255)},
+			[]interface{}{},
+			"Uint8Array:2,3",
+			[]interface{}{"Uint16Array:2,1"},
+			[]interface{}{int64(1), int64(2), int64(3)},
+			int64(0),
+			[]interface{}{int64(9)},
+		}, got.Export())
+	})
+
+	t.Run("ok/map_set_args", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		rt := tb.runtime()
+		require.NoError(t, rt.Set("page", tb.NewPage(nil)))
+
+		got, err := rt.RunString(`
+			page.evaluate((m, { nested }) => [
+				m instanceof Map, Array.from(m.keys()), m.get('a'), m.get(2) instanceof Set && Array.from(m.get(2)),
+				nested.s instanceof Set, nested.s.size, nested.m instanceof Map && nested.m.get('when') instanceof Date,
+			], new Map([['a', 1], [2, new Set(['x', 'y'])]]), {
+				nested: { s: new Set([1, 1, 2]), m: new Map([['when', new Date(0)]]) },
+			});
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, []interface{}{
+			true, []interface{}{"a", int64(2)}, int64(1), []interface{}{"x", "y"},
+			true, int64(2), true,
+		}, got.Export())
+	})
+
+	t.Run("err/max_binary_size", func(t *testing.T) {
+		t.Parallel()
+
+		opts := defaultLaunchOpts()
+		opts.EvaluateMaxBinarySize = 4
+		tb := newTestBrowser(t, withLaunchOptions(opts))
+		p := tb.NewPage(nil)
+
+		rt := tb.runtime()
+		evalErr := func(js string, args ...goja.Value) {
+			t.Helper()
+			defer func() {
+				assertPanicErrorContains(t, recover(), "over the maximum of 4 bytes")
+			}()
+			p.Evaluate(tb.toGojaValue(js), args...)
+			t.Error("did not panic")
+		}
+		evalErr(`v => v.byteLength`, rt.ToValue(rt.NewArrayBuffer(make([]byte, 5))))
+		evalErr(`() => new Uint8Array(5)`)
+
+		got := p.Evaluate(tb.toGojaValue(`v => v.byteLength`), rt.ToValue(rt.NewArrayBuffer(make([]byte, 4))))
+		assert.Equal(t, int64(4), tb.asGojaValue(got).Export())
+	})
+
 	t.Run("err", func(t *testing.T) {
 		t.Parallel()
 
@@ -122,6 +459,11 @@ func TestPageEvaluate(t *testing.T) {
 				"evaluating JS: SyntaxError: Unexpected token ')'",
 			},
 			{"undef", "undef", "evaluating JS: ReferenceError: undef is not defined"},
+			{
+				"max_depth",
+				`() => { let a = []; for (let i = 0; i < 100; i++) a = [a]; return a; }`,
+				"value is nested deeper than the maximum depth of 64",
+			},
 		}
 
 		for _, tc := range testCases {
@@ -144,6 +486,392 @@ func TestPageEvaluate(t *testing.T) {
 	})
 }
 
+func TestPageExposeFunction(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name, script string
+		want         []string
+	}{
+		{
+			name: "round_trip",
+			script: `
+				page.exposeFunction('concat', (s, o) => ({ s: s + o.suffix, n: o.n * 2 }));
+				page.evaluate(() => {
+					window.concat('foo', { suffix: 'bar', n: 21 }).then(r => window.result = r.s + r.n);
+				});`,
+			want: []string{"result: foobar42"},
+		},
+		{
+			name: "callback_throws",
+			script: `
+				page.exposeFunction('fail', () => { throw new Error('boom'); });
+				page.evaluate(() => {
+					window.fail().catch(err => window.result = err.message);
+				});`,
+			want: []string{"result: Error: boom"},
+		},
+		{
+			name: "navigation",
+			script: `
+				page.exposeFunction('add', (a, b) => a + b);
+				page.goto('data:text/html,<p>navigated</p>');
+				page.evaluate(() => {
+					window.add(1, 2).then(r => window.result = r);
+				});`,
+			want: []string{"result: 3"},
+		},
+		{
+			name: "iframe",
+			script: `
+				page.setContent('<iframe srcdoc="<p>frame</p>"></iframe>');
+				page.exposeFunction('add', (a, b) => a + b);
+				page.frames()[1].evaluate(() => {
+					window.add(2, 3).then(r => window.parent.result = r);
+				});`,
+			want: []string{"result: 5"},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tb := newTestBrowser(t)
+			p := tb.NewPage(nil)
+			rt := tb.runtime()
+			require.NoError(t, rt.Set("page", p))
+			var log []string
+			require.NoError(t, rt.Set("log", func(s string) { log = append(log, s) }))
+
+			err := tb.vu.Loop.Start(func() error {
+				script := tc.script + `
+					page.waitForFunction(() => window.result !== undefined).then(() => {
+						log('result: ' + page.evaluate(() => window.result));
+						page.close();
+					});`
+				if _, err := rt.RunString(script); err != nil {
+					return fmt.Errorf("%w", err)
+				}
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, log)
+		})
+	}
+}
+
+func TestPageOnConsole(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name, script string
+		want         []string
+	}{
+		{
+			name: "arg_types",
+			script: `
+				page.on('console', msg => {
+					log(msg.type() + ': ' + msg.text());
+					log(JSON.stringify(msg.args().map(arg => arg.jsonValue())));
+					const loc = msg.location();
+					log(loc.url.endsWith('/console.js') + ':' + loc.lineNumber);
+					page.close();
+				});
+				page.evaluate(() => logIt('hi', 1, true, { a: 1, b: 'x' }, null));`,
+			want: []string{
+				`error: hi 1 true {"a":1,"b":"x"} null`,
+				`["hi",1,true,{"a":1,"b":"x"},null]`,
+				"true:1",
+			},
+		},
+		{
+			name: "iframe",
+			script: `
+				page.on('console', msg => {
+					log(msg.type() + ': ' + msg.text());
+					page.close();
+				});
+				page.evaluate(() => {
+					const iframe = document.createElement('iframe');
+					iframe.srcdoc = '<script>console.info("from iframe")<\/script>';
+					document.body.appendChild(iframe);
+				});`,
+			want: []string{"info: from iframe"},
+		},
+		{
+			name: "worker",
+			script: `
+				page.on('console', msg => {
+					log(msg.type() + ': ' + msg.text());
+					log(String(msg.args()[1].jsonValue()));
+					page.close();
+				});
+				page.evaluate(() => { window.worker = new Worker('/worker.js'); });`,
+			want: []string{"log: from worker 7", "7"},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tb := newTestBrowser(t, withHTTPServer())
+			tb.withHandler("/console.html", func(w http.ResponseWriter, _ *http.Request) {
+				fmt.Fprint(w, `<html><body><script src="/console.js"></script></body></html>`)
+			})
+			tb.withHandler("/console.js", func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", "text/javascript")
+				fmt.Fprint(w, "function logIt(...args) {\n  console.error(...args);\n}\n")
+			})
+			tb.withHandler("/worker.js", func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", "text/javascript")
+				fmt.Fprint(w, "console.log('from worker', 7);")
+			})
+			p := tb.NewPage(nil)
+			p.Goto(tb.URL("/console.html"), nil)
+
+			rt := tb.runtime()
+			require.NoError(t, rt.Set("page", p))
+			var log []string
+			require.NoError(t, rt.Set("log", func(s string) { log = append(log, s) }))
+
+			err := tb.vu.Loop.Start(func() error {
+				if _, err := rt.RunString(tc.script); err != nil {
+					return fmt.Errorf("%w", err)
+				}
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, log)
+		})
+	}
+}
+
+func TestPageOnIterationEnd(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t)
+	p := tb.NewPage(nil)
+
+	rt := tb.runtime()
+	require.NoError(t, rt.Set("page", p))
+	var log []string
+	require.NoError(t, rt.Set("log", func(s string) { log = append(log, s) }))
+
+	// iterate runs the script in an iteration, which ends after
+	// a while if endEarly is true, even if the page isn't closed.
+	vuCtx := tb.vu.Context()
+	iterate := func(script string, endEarly bool) {
+		t.Helper()
+
+		iterCtx, endIteration := context.WithCancel(vuCtx)
+		defer endIteration()
+		tb.vu.CtxField = iterCtx
+		defer func() { tb.vu.CtxField = vuCtx }()
+
+		err := tb.vu.Loop.Start(func() error {
+			if endEarly {
+				time.AfterFunc(100*time.Millisecond, endIteration)
+			}
+			_, err := rt.RunString(script)
+			return err
+		})
+		require.NoError(t, err)
+	}
+
+	iterate(`page.on('console', msg => log('first: ' + msg.text()));`, true)
+	assert.False(t, p.IsClosed())
+
+	// the next iteration gets the events of the page again.
+	iterate(`
+		page.on('console', msg => {
+			log(msg.text());
+			page.close();
+		});
+		page.evaluate(() => console.log('hello'));`, false)
+	assert.Equal(t, []string{"first: hello", "hello"}, log)
+}
+
+func TestPageOnPageError(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t, withHTTPServer())
+	tb.withHandler("/errors.html", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `<html><body></body></html>`)
+	})
+	p := tb.NewPage(nil)
+	p.Goto(tb.URL("/errors.html"), nil)
+
+	rt := tb.runtime()
+	require.NoError(t, rt.Set("page", p))
+	var log []string
+	require.NoError(t, rt.Set("log", func(s string) { log = append(log, s) }))
+
+	err := tb.vu.Loop.Start(func() error {
+		_, err := rt.RunString(`
+			let count = 0;
+			page.on('pageerror', err => {
+				log(err.name + ': ' + err.message + ' ' + err.stack.includes('at '));
+				if (++count === 3) {
+					log(String(page.pageErrors().length));
+					page.close();
+				}
+			});
+			page.evaluate(() => {
+				setTimeout(() => { throw new TypeError('boom'); }, 0);
+				setTimeout(() => { Promise.reject(new Error('rejected')); }, 10);
+				setTimeout(() => {
+					const iframe = document.createElement('iframe');
+					iframe.srcdoc = '<script>throw new RangeError("from iframe")<\/script>';
+					document.body.appendChild(iframe);
+				}, 20);
+			});`)
+		if err != nil {
+			return fmt.Errorf("%w", err)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"TypeError: boom true",
+		"Error: rejected true",
+		"RangeError: from iframe true",
+		"3",
+	}, log)
+}
+
+func TestPageOnDialog(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name, open, answer string
+		want               []string
+	}{
+		{
+			name:   "alert",
+			open:   `alert('hi'); window.result = 'closed';`,
+			answer: `d.accept()`,
+			want:   []string{"alert: hi ", "closed"},
+		},
+		{
+			name:   "confirm_accept",
+			open:   `window.result = confirm('sure?');`,
+			answer: `d.accept()`,
+			want:   []string{"confirm: sure? ", "true"},
+		},
+		{
+			name:   "confirm_dismiss",
+			open:   `window.result = confirm('sure?');`,
+			answer: `d.dismiss()`,
+			want:   []string{"confirm: sure? ", "false"},
+		},
+		{
+			name:   "prompt",
+			open:   `window.result = prompt('name?', 'nobody');`,
+			answer: `d.accept('somebody')`,
+			want:   []string{"prompt: name? nobody", "somebody"},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tb := newTestBrowser(t)
+			p := tb.NewPage(nil)
+
+			rt := tb.runtime()
+			require.NoError(t, rt.Set("page", p))
+			var log []string
+			require.NoError(t, rt.Set("log", func(s string) { log = append(log, s) }))
+
+			err := tb.vu.Loop.Start(func() error {
+				_, err := rt.RunString(fmt.Sprintf(`
+					page.on('dialog', d => {
+						log(d.type() + ': ' + d.message() + ' ' + d.defaultValue());
+						%s;
+						log(String(page.evaluate(() => window.result)));
+						page.close();
+					});
+					page.evaluate(() => { setTimeout(() => { %s }, 0); });`,
+					tc.answer, tc.open))
+				if err != nil {
+					return fmt.Errorf("%w", err)
+				}
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, log)
+		})
+	}
+
+	t.Run("beforeunload", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		p := tb.NewPage(nil)
+		p.SetContent(`<body onbeforeunload="return 'leave?'">content</body>`, nil)
+		// beforeunload dialogs are only opened after a user interaction.
+		p.Click("body", nil)
+
+		rt := tb.runtime()
+		require.NoError(t, rt.Set("page", p))
+		var log []string
+		require.NoError(t, rt.Set("log", func(s string) { log = append(log, s) }))
+
+		err := tb.vu.Loop.Start(func() error {
+			_, err := rt.RunString(`
+				page.on('dialog', d => {
+					log(d.type());
+					d.accept();
+				});
+				page.close({ runBeforeUnload: true });`)
+			if err != nil {
+				return fmt.Errorf("%w", err)
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"beforeunload"}, log)
+		assert.True(t, p.IsClosed())
+	})
+}
+
+func TestPageDialogWithoutHandlers(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t, withHTTPServer())
+	tb.withHandler("/dialogs.html", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `<html><body onbeforeunload="return 'leave?'"><script>
+			window.results = [confirm('sure?'), prompt('name?', 'nobody'), alert('hi')];
+		</script></body></html>`)
+	})
+	p := tb.NewPage(nil)
+
+	// the dialogs are dismissed, so they don't block the navigation.
+	require.NotNil(t, p.Goto(tb.URL("/dialogs.html"), nil))
+	got := p.Evaluate(tb.toGojaValue(`() => JSON.stringify(window.results)`))
+	assert.Equal(t, "[false,null,null]", tb.asGojaValue(got).String())
+
+	// beforeunload dialogs are accepted, so the page closes.
+	p.Click("body", nil)
+	p.Close(tb.toGojaValue(map[string]interface{}{"runBeforeUnload": true}))
+	assert.Eventually(t, p.IsClosed, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestPageOnUnknownEvent(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t)
+	p := tb.NewPage(nil)
+	rt := tb.runtime()
+	require.NoError(t, rt.Set("page", p))
+
+	_, err := rt.RunString(`page.on('nope', () => {})`)
+	assert.ErrorContains(t, err, `unknown page event: "nope"`)
+}
+
 func TestPageGoto(t *testing.T) {
 	b := newTestBrowser(t, withFileServer())
 
@@ -199,6 +927,120 @@ func TestPageGotoWaitUntilDOMContentLoaded(t *testing.T) {
 	assert.EqualValues(t, "DOMContentLoaded", actual[0], `expected "DOMContentLoaded" event to have fired`)
 }
 
+func TestPageGotoWaitUntilNetworkIdlePersistentConnections(t *testing.T) {
+	t.Parallel()
+
+	// newPersistentTestBrowser returns a test browser that serves a page
+	// that keeps an EventSource connection and a long poll open forever.
+	newPersistentTestBrowser := func(t *testing.T, opts ...interface{}) *testBrowser {
+		t.Helper()
+
+		tb := newTestBrowser(t, append([]interface{}{withHTTPServer()}, opts...)...)
+		done := make(chan struct{})
+		t.Cleanup(func() { close(done) })
+		hold := func(r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-done:
+			}
+		}
+		tb.withHandler("/persistent.html", func(w http.ResponseWriter, r *http.Request) {
+			poll := r.URL.Query().Get("poll") != ""
+			fmt.Fprintf(w, `<html><body><script>
+				new EventSource('/events');
+				if (%t) fetch('/poll');
+			</script></body></html>`, poll)
+		})
+		tb.withHandler("/events", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: hello\n\n")
+			w.(http.Flusher).Flush()
+			hold(r)
+		})
+		tb.withHandler("/poll", func(w http.ResponseWriter, r *http.Request) {
+			hold(r)
+		})
+
+		return tb
+	}
+
+	t.Run("event_source", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newPersistentTestBrowser(t)
+		p := tb.NewPage(nil)
+		assert.NotPanics(t, func() {
+			p.Goto(tb.URL("/persistent.html"), tb.toGojaValue(map[string]interface{}{
+				"waitUntil": "networkidle",
+				"timeout":   5000,
+			}))
+		})
+	})
+
+	t.Run("waitUntil_connections", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newPersistentTestBrowser(t)
+		p := tb.NewPage(nil)
+		assert.NotPanics(t, func() {
+			p.Goto(tb.URL("/persistent.html?poll=1"), tb.toGojaValue(map[string]interface{}{
+				"waitUntil": map[string]interface{}{
+					"networkidle": map[string]interface{}{"connections": 1, "idleTime": "200ms"},
+				},
+				"timeout": 5000,
+			}))
+		})
+	})
+
+	t.Run("context_connections", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newPersistentTestBrowser(t)
+		bctx := tb.NewContext(tb.toGojaValue(map[string]interface{}{
+			"networkIdle": map[string]interface{}{"connections": 1, "idleTime": 200},
+		}))
+		t.Cleanup(bctx.Close)
+		p := bctx.NewPage()
+		assert.NotPanics(t, func() {
+			p.Goto(tb.URL("/persistent.html?poll=1"), tb.toGojaValue(map[string]interface{}{
+				"waitUntil": "networkidle",
+				"timeout":   5000,
+			}))
+			p.WaitForLoadState("networkidle", tb.toGojaValue(map[string]interface{}{"timeout": 5000}))
+		})
+	})
+
+	t.Run("waitForLoadState_connections", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newPersistentTestBrowser(t)
+		p := tb.NewPage(nil)
+		p.Goto(tb.URL("/persistent.html?poll=1"), nil)
+		assert.NotPanics(t, func() {
+			p.WaitForLoadState("networkidle", tb.toGojaValue(map[string]interface{}{
+				"networkIdle": map[string]interface{}{"connections": 1},
+				"timeout":     5000,
+			}))
+		})
+	})
+
+	t.Run("err_timeout", func(t *testing.T) {
+		t.Parallel()
+
+		defer func() {
+			assertPanicErrorContains(t, recover(), "timed out after 1s")
+		}()
+
+		tb := newPersistentTestBrowser(t)
+		p := tb.NewPage(nil)
+		p.Goto(tb.URL("/persistent.html?poll=1"), tb.toGojaValue(map[string]interface{}{
+			"waitUntil": "networkidle",
+			"timeout":   1000,
+		}))
+		t.Error("did not panic")
+	})
+}
+
 func TestPageInnerHTML(t *testing.T) {
 	t.Parallel()
 
@@ -425,23 +1267,405 @@ func TestPageScreenshotFullpage(t *testing.T) {
 	assert.Greater(t, b, uint32(128))
 }
 
-func TestPageTitle(t *testing.T) {
-	p := newTestBrowser(t).NewPage(nil)
-	p.SetContent(`<html><head><title>Some title</title></head></html>`, nil)
-	assert.Equal(t, "Some title", p.Title())
-}
-
-func TestPageSetExtraHTTPHeaders(t *testing.T) {
-	b := newTestBrowser(t, withHTTPServer())
-
-	p := b.NewPage(nil)
+func TestPageScreenshotWebP(t *testing.T) {
+	t.Parallel()
 
-	headers := map[string]string{
-		"Some-Header": "Some-Value",
+	tb := newTestBrowser(t)
+	p := tb.NewPage(nil)
+	p.SetContent(`<div style="width: 100px; height: 100px; background: red"></div>`, nil)
+
+	opts := tb.toGojaValue(struct {
+		Type    string `js:"type"`
+		Quality int64  `js:"quality"`
+	}{Type: "webp", Quality: 50})
+	assertWebP := func(t *testing.T, buf []byte) {
+		t.Helper()
+
+		require.Greater(t, len(buf), 12)
+		assert.Equal(t, "RIFF", string(buf[0:4]))
+		assert.Equal(t, "WEBP", string(buf[8:12]))
 	}
-	p.SetExtraHTTPHeaders(headers)
 
-	resp := p.Goto(b.URL("/get"), nil)
+	t.Run("page", func(t *testing.T) {
+		assertWebP(t, p.Screenshot(opts).Bytes())
+	})
+	t.Run("element_handle", func(t *testing.T) {
+		assertWebP(t, p.Query("div").Screenshot(opts).Bytes())
+	})
+	t.Run("full_page", func(t *testing.T) {
+		// a short page is captured at once, so webp is supported.
+		assertWebP(t, p.Screenshot(tb.toGojaValue(map[string]interface{}{
+			"type":     "webp",
+			"fullPage": true,
+		})).Bytes())
+	})
+}
+
+func TestPageScreenshotPathTemplate(t *testing.T) {
+	t.Parallel()
+
+	opts := defaultLaunchOpts()
+	opts.ArtifactsDir = t.TempDir()
+	tb := newTestBrowser(t, withHTTPServer(), withLaunchOptions(opts))
+	tb.vu.StateField.VUID = 7
+	p := tb.NewPage(nil)
+	require.NotNil(t, p.Goto(tb.URL("/get"), nil))
+
+	p.Screenshot(tb.toGojaValue(map[string]interface{}{
+		"path": "vu-{{vu}}/{{url_host}}",
+	}))
+
+	u, err := url.Parse(tb.URL("/get"))
+	require.NoError(t, err)
+	host := strings.ReplaceAll(u.Host, ":", "_")
+	_, err = os.Stat(filepath.Join(opts.ArtifactsDir, "vu-7", host+".png"))
+	assert.NoError(t, err)
+}
+
+func TestPageScreenshotClip(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t)
+	p := tb.NewPage(nil)
+	p.SetViewportSize(tb.toGojaValue(struct {
+		Width  float64 `js:"width"`
+		Height float64 `js:"height"`
+	}{Width: 800, Height: 600}))
+	p.SetContent(`
+		<body style="margin: 0">
+			<div style="height: 2000px; background: rgb(255, 0, 0)"></div>
+			<div style="height: 1000px; background: rgb(0, 0, 255)"></div>
+		</body>`, nil)
+
+	type clip struct {
+		X      float64 `js:"x"`
+		Y      float64 `js:"y"`
+		Width  float64 `js:"width"`
+		Height float64 `js:"height"`
+	}
+	type options struct {
+		Clip     clip `js:"clip"`
+		FullPage bool `js:"fullPage"`
+	}
+	screenshot := func(t *testing.T, opts options) (img image.Image, errMsg string) {
+		t.Helper()
+
+		defer func() {
+			if err := recover(); err != nil {
+				gerr, ok := err.(*goja.Object)
+				require.True(t, ok)
+				errMsg = gerr.String()
+			}
+		}()
+		img, err := png.Decode(bytes.NewReader(p.Screenshot(tb.toGojaValue(opts)).Bytes()))
+		require.NoError(t, err)
+		return img, ""
+	}
+
+	t.Run("viewport", func(t *testing.T) {
+		img, errMsg := screenshot(t, options{Clip: clip{X: 10, Y: 20, Width: 100, Height: 50}})
+		require.Empty(t, errMsg)
+		assert.Equal(t, 100, img.Bounds().Dx())
+		assert.Equal(t, 50, img.Bounds().Dy())
+	})
+	t.Run("viewport_trimmed", func(t *testing.T) {
+		img, errMsg := screenshot(t, options{Clip: clip{X: 700, Y: 550, Width: 200, Height: 200}})
+		require.Empty(t, errMsg)
+		assert.Equal(t, 100, img.Bounds().Dx())
+		assert.Equal(t, 50, img.Bounds().Dy())
+	})
+	t.Run("full_page", func(t *testing.T) {
+		// the clip is below the viewport, in the blue part of the page.
+		img, errMsg := screenshot(t, options{Clip: clip{X: 0, Y: 2500, Width: 120, Height: 80}, FullPage: true})
+		require.Empty(t, errMsg)
+		assert.Equal(t, 120, img.Bounds().Dx())
+		assert.Equal(t, 80, img.Bounds().Dy())
+		r, _, b, _ := img.At(60, 40).RGBA()
+		assert.Equal(t, uint32(0), r>>8)
+		assert.Equal(t, uint32(255), b>>8)
+	})
+	t.Run("out_of_bounds", func(t *testing.T) {
+		_, errMsg := screenshot(t, options{Clip: clip{X: 0, Y: 700, Width: 100, Height: 100}})
+		assert.Contains(t, errMsg, "clip at (0, 700) with size 100x100 is outside of the viewport of size 800x600")
+	})
+	t.Run("zero_area", func(t *testing.T) {
+		_, errMsg := screenshot(t, options{Clip: clip{X: 0, Y: 0, Width: 0, Height: 100}})
+		assert.Contains(t, errMsg, "clip must have a non-zero area, got 0x100")
+	})
+}
+
+func TestPageScreenshotMask(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t)
+	p := tb.NewPage(nil)
+	p.SetViewportSize(tb.toGojaValue(struct {
+		Width  float64 `js:"width"`
+		Height float64 `js:"height"`
+	}{Width: 800, Height: 600}))
+	p.SetContent(`
+		<body style="margin: 0; background: rgb(255, 255, 255)">
+			<div id="pii" style="position: absolute; left: 100px; top: 50px; width: 200px; height: 40px">
+				john.doe@example.com
+			</div>
+			<iframe style="position: absolute; left: 0; top: 300px; width: 400px; height: 200px; border: 0"
+				srcdoc="<body style='margin: 0; background: rgb(255, 255, 255)'>
+					<div id='pii' style='position: absolute; left: 50px; top: 20px; width: 100px; height: 30px'>secret</div>
+				</body>"></iframe>
+		</body>`, nil)
+
+	maskColor := [3]uint32{0, 255, 0}
+	white := [3]uint32{255, 255, 255}
+	rgb := func(img image.Image, x, y int) [3]uint32 {
+		r, g, b, _ := img.At(x, y).RGBA()
+		return [3]uint32{r >> 8, g >> 8, b >> 8}
+	}
+	masks := func() int64 {
+		v := p.Evaluate(tb.toGojaValue(`() => document.querySelectorAll('[data-xk6-browser-screenshot-mask]').length`))
+		return tb.asGojaValue(v).ToInteger()
+	}
+
+	t.Run("page", func(t *testing.T) {
+		buf := p.Screenshot(tb.toGojaValue(struct {
+			Mask      []string `js:"mask"`
+			MaskColor string   `js:"maskColor"`
+		}{Mask: []string{"#pii"}, MaskColor: "rgb(0, 255, 0)"}))
+		img, err := png.Decode(bytes.NewReader(buf.Bytes()))
+		require.NoError(t, err)
+
+		// the masked elements are solid boxes of the mask color.
+		for y := 50; y < 90; y += 3 {
+			for x := 100; x < 300; x += 3 {
+				require.Equal(t, maskColor, rgb(img, x, y), "pixel at (%d, %d)", x, y)
+			}
+		}
+		for y := 320; y < 350; y += 3 {
+			for x := 50; x < 150; x += 3 {
+				require.Equal(t, maskColor, rgb(img, x, y), "pixel at (%d, %d) in the iframe", x, y)
+			}
+		}
+		// the surrounding pixels are unchanged.
+		for _, pt := range [][2]int{{98, 70}, {302, 70}, {200, 47}, {200, 92}, {48, 335}, {152, 335}} {
+			assert.Equal(t, white, rgb(img, pt[0], pt[1]), "pixel at %v", pt)
+		}
+		assert.Equal(t, int64(0), masks(), "masks should be removed after the capture")
+	})
+	t.Run("element_handle", func(t *testing.T) {
+		buf := p.Query("body").Screenshot(tb.toGojaValue(struct {
+			Mask []string `js:"mask"`
+		}{Mask: []string{"#pii"}}))
+		img, err := png.Decode(bytes.NewReader(buf.Bytes()))
+		require.NoError(t, err)
+		assert.Equal(t, [3]uint32{255, 0, 255}, rgb(img, 200, 70), "default mask color")
+		assert.Equal(t, int64(0), masks())
+	})
+	t.Run("failed_capture", func(t *testing.T) {
+		func() {
+			defer func() { assert.NotNil(t, recover()) }()
+			// the clip is outside of the viewport.
+			p.Screenshot(tb.toGojaValue(map[string]interface{}{
+				"mask": []string{"#pii"},
+				"clip": map[string]float64{"x": 1000, "y": 1000, "width": 10, "height": 10},
+			}))
+		}()
+		assert.Equal(t, int64(0), masks(), "masks should be removed after a failed capture")
+	})
+}
+
+func TestPageScreenshotFullpageStitched(t *testing.T) {
+	t.Parallel()
+
+	const (
+		bands      = 400
+		bandHeight = 100
+		width      = 200
+		height     = bands * bandHeight
+		header     = 50
+	)
+	bandColor := func(i int) color.NRGBA {
+		return color.NRGBA{R: uint8(i % 256), G: uint8(i * 7 % 256), B: uint8(i * 13 % 256), A: 255}
+	}
+
+	tb := newTestBrowser(t)
+	p := tb.NewPage(nil)
+	p.SetViewportSize(tb.toGojaValue(struct {
+		Width  float64 `js:"width"`
+		Height float64 `js:"height"`
+	}{Width: width, Height: 800}))
+	p.Evaluate(tb.toGojaValue(fmt.Sprintf(`
+	() => {
+		document.documentElement.style.margin = '0';
+		document.body.style.margin = '0';
+
+		const header = document.createElement('div');
+		header.style = 'position: fixed; top: 0; left: 0; width: 100%%; height: %dpx; background: rgb(255, 255, 255)';
+		document.body.appendChild(header);
+
+		for (let i = 0; i < %d; i++) {
+			const div = document.createElement('div');
+			div.style.width = '%dpx';
+			div.style.height = '%dpx';
+			div.style.background = `+"`rgb(${i %% 256}, ${i * 7 %% 256}, ${i * 13 %% 256})`"+`;
+			document.body.appendChild(div);
+		}
+	}`, header, bands, width, bandHeight)))
+
+	buf := p.Screenshot(tb.toGojaValue(struct {
+		FullPage          bool `js:"fullPage"`
+		HideFixedElements bool `js:"hideFixedElements"`
+	}{FullPage: true, HideFixedElements: true}))
+
+	img, err := png.Decode(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, width, img.Bounds().Dx())
+	require.Equal(t, height, img.Bounds().Dy())
+
+	// every row, including the rows at the seams of the slices, should
+	// have the color of its band, and the fixed header should only be
+	// captured once at the top.
+	for y := 0; y < height; y++ {
+		want := bandColor(y / bandHeight)
+		if y < header {
+			want = color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+		}
+		got := color.NRGBAModel.Convert(img.At(10, y)).(color.NRGBA) //nolint:forcetypeassert
+		if !assert.Equal(t, want, got, "row %d", y) {
+			break
+		}
+	}
+
+	// the viewport should be restored after stitching.
+	assert.Equal(t, int64(800), tb.asGojaValue(p.Evaluate(tb.toGojaValue(`() => window.innerHeight`))).ToInteger())
+}
+
+func TestPageTitle(t *testing.T) {
+	p := newTestBrowser(t).NewPage(nil)
+	p.SetContent(`<html><head><title>Some title</title></head></html>`, nil)
+	assert.Equal(t, "Some title", p.Title())
+}
+
+func TestPageSetContent(t *testing.T) {
+	t.Parallel()
+
+	newBrowser := func(t *testing.T, delay time.Duration) *testBrowser {
+		t.Helper()
+
+		tb := newTestBrowser(t, withHTTPServer())
+		tb.withHandler("/slow.css", func(w http.ResponseWriter, _ *http.Request) {
+			time.Sleep(delay)
+			w.Header().Set("Content-Type", "text/css")
+			fmt.Fprint(w, `p { color: rgb(255, 0, 0); }`)
+		})
+		tb.withHandler("/slow", func(w http.ResponseWriter, _ *http.Request) {
+			time.Sleep(delay)
+			fmt.Fprint(w, `<p>slow page</p>`)
+		})
+		return tb
+	}
+
+	t.Run("wait_until_load", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newBrowser(t, 500*time.Millisecond)
+		p := tb.NewPage(nil)
+		html := fmt.Sprintf(`<link rel="stylesheet" href="%s"><p>styled</p>`, tb.URL("/slow.css"))
+		p.SetContent(html, tb.toGojaValue(map[string]interface{}{"waitUntil": "load"}))
+
+		color := p.Evaluate(tb.toGojaValue(`() => getComputedStyle(document.querySelector('p')).color`))
+		assert.Equal(t, "rgb(255, 0, 0)", tb.asGojaValue(color).String())
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newBrowser(t, 2*time.Second)
+		p := tb.NewPage(nil)
+		html := fmt.Sprintf(`<link rel="stylesheet" href="%s"><p>styled</p>`, tb.URL("/slow.css"))
+
+		var errorMsg string
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					errMsg, ok := err.(*goja.Object)
+					require.True(t, ok)
+					errorMsg = errMsg.String()
+				}
+			}()
+			p.SetContent(html, tb.toGojaValue(map[string]interface{}{"timeout": 300}))
+		}()
+		assert.Contains(t, errorMsg, `waiting for "load": timed out after 300ms`)
+	})
+
+	t.Run("stops_navigation", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newBrowser(t, time.Second)
+		p := tb.NewPage(nil)
+		p.Evaluate(tb.toGojaValue(fmt.Sprintf(`() => { location.href = %q }`, tb.URL("/slow"))))
+		p.SetContent(`<p>content</p>`, nil)
+
+		// the navigation would have replaced the content by now.
+		time.Sleep(1500 * time.Millisecond)
+		assert.Equal(t, "content", p.InnerText("p", nil))
+	})
+}
+
+func TestPageSetViewportSizeDeviceMetrics(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t)
+	p := tb.NewContext(tb.toGojaValue(map[string]interface{}{
+		"viewport":          map[string]interface{}{"width": 300, "height": 200},
+		"deviceScaleFactor": 3,
+		"screenOrientation": "landscape-secondary",
+	})).NewPage()
+	p.SetContent(`<p>device</p>`, nil)
+
+	eval := func(js string) goja.Value {
+		return tb.asGojaValue(p.Evaluate(tb.toGojaValue(js)))
+	}
+	screenshotSize := func() (int, int) {
+		img, err := png.Decode(bytes.NewReader(p.Screenshot(nil).Bytes()))
+		require.NoError(t, err)
+		return img.Bounds().Dx(), img.Bounds().Dy()
+	}
+
+	assert.Equal(t, int64(3), eval("() => window.devicePixelRatio").ToInteger())
+	assert.Equal(t, "landscape-secondary", eval("() => screen.orientation.type").String())
+	w, h := screenshotSize()
+	assert.Equal(t, 900, w)
+	assert.Equal(t, 600, h)
+
+	// the page is laid out again without a reload.
+	p.Evaluate(tb.toGojaValue("() => window.notReloaded = true"))
+	p.SetViewportSize(tb.toGojaValue(map[string]interface{}{
+		"width":             200,
+		"height":            400,
+		"deviceScaleFactor": 2,
+		"hasTouch":          true,
+		"screenOrientation": "portrait-primary",
+	}))
+	assert.True(t, eval("() => window.notReloaded").ToBoolean())
+	assert.Equal(t, int64(200), eval("() => window.innerWidth").ToInteger())
+	assert.Equal(t, int64(2), eval("() => window.devicePixelRatio").ToInteger())
+	assert.Equal(t, "portrait-primary", eval("() => screen.orientation.type").String())
+	w, h = screenshotSize()
+	assert.Equal(t, 400, w)
+	assert.Equal(t, 800, h)
+	assert.NotPanics(t, func() { p.Tap("p", nil) }, "hasTouch should enable tapping")
+}
+
+func TestPageSetExtraHTTPHeaders(t *testing.T) {
+	b := newTestBrowser(t, withHTTPServer())
+
+	p := b.NewPage(nil)
+
+	headers := map[string]string{
+		"Some-Header": "Some-Value",
+	}
+	p.SetExtraHTTPHeaders(headers)
+
+	resp := p.Goto(b.URL("/get"), nil)
 
 	require.NotNil(t, resp)
 	var body struct{ Headers map[string][]string }
@@ -452,6 +1676,36 @@ func TestPageSetExtraHTTPHeaders(t *testing.T) {
 	assert.Equal(t, "Some-Value", h[0])
 }
 
+func TestPageSetExtraHTTPHeadersMerge(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t, withHTTPServer())
+	tb.withHandler("/echo", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", r.Header.Get("X-Context"), r.Header.Get("X-Tenant"))
+	})
+	bctx := tb.NewContext(tb.toGojaValue(map[string]interface{}{
+		"extraHTTPHeaders": map[string]string{"X-Context": "c", "X-Tenant": "context"},
+	}))
+	t.Cleanup(bctx.Close)
+	p := bctx.NewPage()
+
+	// echo returns the headers that the document and an XHR were sent with.
+	echo := func() []string {
+		t.Helper()
+
+		resp := p.Goto(tb.URL("/echo"), nil)
+		require.NotNil(t, resp)
+		xhr := p.Evaluate(tb.toGojaValue(`() => fetch('/echo').then(r => r.text())`))
+		return []string{string(resp.Body().Bytes()), tb.asGojaValue(xhr).String()}
+	}
+
+	p.SetExtraHTTPHeaders(map[string]string{"x-tenant": "page"})
+	assert.Equal(t, []string{"c page", "c page"}, echo(), "page headers should take precedence")
+
+	p.SetExtraHTTPHeaders(map[string]string{})
+	assert.Equal(t, []string{"c context", "c context"}, echo(), "page headers should be cleared")
+}
+
 func TestPageWaitForFunction(t *testing.T) {
 	t.Parallel()
 
@@ -673,6 +1927,70 @@ func TestPageWaitForFunction(t *testing.T) {
 		require.NoError(t, err)
 		assert.Contains(t, log, "ok: null")
 	})
+
+	t.Run("ok_func_poll_mutation_static_page", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		p := tb.NewPage(nil)
+		require.NoError(t, tb.runtime().Set("page", p))
+		var log []string
+		require.NoError(t, tb.runtime().Set("log", func(s string) { log = append(log, s) }))
+
+		// the predicate counts its calls, and is only satisfied by the
+		// single DOM change that happens after a second.
+		_, err := tb.runtime().RunString(`fn = selector => {
+			window._calls = (window._calls || 0) + 1;
+			return document.querySelector(selector) !== null;
+		}`)
+		require.NoError(t, err)
+		p.Evaluate(tb.toGojaValue(`() => {
+			setTimeout(() => document.body.appendChild(document.createElement('h1')), 1000);
+		}`))
+
+		err = tb.vu.Loop.Start(func() error {
+			if _, err := tb.runtime().RunString(fmt.Sprintf(script, "fn",
+				"{ polling: 'mutation', timeout: 2000, }", `"h1"`)); err != nil {
+				return fmt.Errorf("%w", err)
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Contains(t, log, "ok: null")
+
+		calls := p.Evaluate(tb.toGojaValue("() => window._calls"))
+		assert.Equal(t, int64(2), tb.asGojaValue(calls).ToInteger(),
+			"the predicate should only run initially and after the DOM change")
+	})
+
+	t.Run("ok_func_poll_interval_period", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		p := tb.NewPage(nil)
+		require.NoError(t, tb.runtime().Set("page", p))
+		var log []string
+		require.NoError(t, tb.runtime().Set("log", func(s string) { log = append(log, s) }))
+
+		_, err := tb.runtime().RunString(`fn = () => {
+			window._calls = (window._calls || 0) + 1;
+			return window._calls === 5;
+		}`)
+		require.NoError(t, err)
+
+		start := time.Now()
+		err = tb.vu.Loop.Start(func() error {
+			if _, err := tb.runtime().RunString(fmt.Sprintf(script, "fn",
+				"{ polling: 200, timeout: 3000, }", "null")); err != nil {
+				return fmt.Errorf("%w", err)
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Contains(t, log, "ok: null")
+		// four intervals between five calls.
+		assert.GreaterOrEqual(t, time.Since(start), 800*time.Millisecond)
+	})
 }
 
 func TestPageWaitForLoadState(t *testing.T) {
@@ -708,11 +2026,792 @@ func assertPanicErrorContains(t *testing.T, err interface{}, expErrMsg string) {
 	t.Helper()
 
 	require.NotNil(t, err)
-	require.IsType(t, &goja.Object{}, err)
-	gotObj, _ := err.(*goja.Object)
-	got := gotObj.Export()
-	expErr := errors.New(expErrMsg)
-	gotErr, ok := got.(error)
-	require.True(t, ok)
-	assert.Contains(t, gotErr.Error(), expErr.Error())
+	assert.Contains(t, thrownError(t, err).Error(), expErrMsg)
+}
+
+// thrownError returns the Go error of a JS exception that the browser
+// API throws. The errors of the browser API are thrown as JS errors,
+// which have the Go error in their value property.
+func thrownError(t *testing.T, thrown interface{}) error {
+	t.Helper()
+
+	require.IsType(t, &goja.Object{}, thrown)
+	obj, _ := thrown.(*goja.Object)
+	if v := obj.Get("value"); v != nil {
+		obj, _ = v.(*goja.Object)
+		require.NotNil(t, obj, "should throw an error")
+	}
+	err, ok := obj.Export().(error)
+	require.True(t, ok, "should throw an error")
+	return err
+}
+
+func TestPageWaitForRequestAndResponse(t *testing.T) {
+	t.Parallel()
+
+	newBrowser := func(t *testing.T) *testBrowser {
+		t.Helper()
+
+		tb := newTestBrowser(t, withHTTPServer())
+		tb.withHandler("/shop", func(w http.ResponseWriter, _ *http.Request) {
+			fmt.Fprint(w, `<button onclick="fetch('/api/cart', { method: 'POST' })">Add</button>`)
+		})
+		tb.withHandler("/api/cart", func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("X-Cart", "1")
+			w.WriteHeader(http.StatusCreated)
+		})
+		p := tb.NewPage(nil)
+		p.Goto(tb.URL("/shop"), nil)
+
+		require.NoError(t, tb.runtime().Set("page", p))
+
+		return tb
+	}
+	run := func(t *testing.T, tb *testBrowser, script string) []string {
+		t.Helper()
+
+		var log []string
+		require.NoError(t, tb.runtime().Set("log", func(s string) { log = append(log, s) }))
+		err := tb.vu.Loop.Start(func() error {
+			if _, err := tb.runtime().RunString(script); err != nil {
+				return fmt.Errorf("%w", err)
+			}
+			return nil
+		})
+		require.NoError(t, err)
+
+		return log
+	}
+
+	testCases := []struct {
+		name, script, want string
+	}{
+		{
+			name: "response_regexp",
+			script: `
+				const resp = page.waitForResponse(/\/api\/cart$/);
+				page.click('button');
+				resp.then(r => log(r.status() + ' ' + r.headerValue('x-cart')), err => log('err: ' + err));`,
+			want: "201 1",
+		},
+		{
+			name: "response_glob",
+			script: `
+				const resp = page.waitForResponse('**/api/*');
+				page.click('button');
+				resp.then(r => log(r.status()), err => log('err: ' + err));`,
+			want: "201",
+		},
+		{
+			name: "request_predicate",
+			script: `
+				const req = page.waitForRequest(r => r.method() === 'POST');
+				page.click('button');
+				req.then(r => log(r.method() + ' ' + r.url().endsWith('/api/cart')), err => log('err: ' + err));`,
+			want: "POST true",
+		},
+		{
+			name: "request_string",
+			script: `
+				const req = page.waitForRequest(page.url().replace('/shop', '/api/cart'));
+				page.click('button');
+				req.then(r => log(r.method()), err => log('err: ' + err));`,
+			want: "POST",
+		},
+		{
+			name: "err/timeout",
+			script: `
+				page.waitForResponse('**/never', { timeout: 500 })
+					.then(r => log('ok'), err => log('err: ' + err));`,
+			want: "err: waitForResponse promise rejected: timed out after 500ms",
+		},
+		{
+			name: "err/predicate",
+			script: `
+				const req = page.waitForRequest(() => { throw new Error('boom'); });
+				page.click('button');
+				req.then(r => log('ok'), err => log('err: ' + err));`,
+			want: "err: waitForRequest predicate: Error: boom",
+		},
+		{
+			name: "err/predicate_timeout",
+			script: `
+				const req = page.waitForRequest(() => {
+					const end = Date.now() + 700;
+					while (Date.now() < end) {}
+					return false;
+				}, { timeout: 500 });
+				page.click('button');
+				req.then(r => log('ok'), err => log('err: ' + err));`,
+			want: "err: waitForRequest promise rejected: timed out after 500ms",
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tb := newBrowser(t)
+			log := run(t, tb, tc.script)
+			require.Len(t, log, 1)
+			assert.Contains(t, log[0], tc.want)
+		})
+	}
+}
+
+func TestPageFileChooser(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name, script string
+		want         []string
+	}{
+		{
+			name: "wait",
+			script: `
+				const chooser = page.waitForFileChooser();
+				page.click('button');
+				chooser.then(fc => {
+					log(fc.isMultiple() + ' ' + fc.element().getAttribute('id'));
+					fc.setFiles({ name: 'a.txt', mimeType: 'text/plain', buffer: new ArrayBuffer(3) });
+					log(page.evaluate(() => changes + ' ' + upload.files[0].name));
+				}, err => log('err: ' + err));`,
+			want: []string{"true upload", "1 a.txt"},
+		},
+		{
+			name: "on",
+			script: `
+				page.on('filechooser', fc => {
+					log(fc.isMultiple() + ' ' + fc.element().getAttribute('id'));
+					fc.setFiles([]);
+					page.close();
+				});
+				page.click('button');`,
+			want: []string{"true upload"},
+		},
+		{
+			name: "err/timeout",
+			script: `
+				page.waitForFileChooser({ timeout: 500 })
+					.then(() => log('ok'), err => log('err: ' + err));`,
+			want: []string{"err: waitForFileChooser promise rejected: timed out after 500ms"},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tb := newTestBrowser(t)
+			p := tb.NewPage(nil)
+			// the input is hidden, so it can only be reached through
+			// the file chooser that the button opens.
+			p.SetContent(`
+				<input type="file" id="upload" multiple style="display: none">
+				<button onclick="upload.click()">Upload</button>
+				<script>
+					window.changes = 0;
+					upload.addEventListener('change', () => changes++);
+				</script>`, nil)
+
+			rt := tb.runtime()
+			require.NoError(t, rt.Set("page", p))
+			var log []string
+			require.NoError(t, rt.Set("log", func(s string) { log = append(log, s) }))
+
+			err := tb.vu.Loop.Start(func() error {
+				if _, err := rt.RunString(tc.script); err != nil {
+					return fmt.Errorf("%w", err)
+				}
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, log)
+		})
+	}
+}
+
+func TestPagePopup(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name, script string
+		want         []string
+	}{
+		{
+			name: "window_open_blank",
+			script: `
+				const popup = page.waitForEvent('popup');
+				page.evaluate(() => { window.open(); });
+				popup.then(p => {
+					log(p.url());
+					log(String(p.opener().url().endsWith('/opener.html')));
+					log(String(page.context().pages().length));
+				}, err => log('err: ' + err));`,
+			want: []string{"about:blank", "true", "2"},
+		},
+		{
+			name: "window_open_url",
+			script: `
+				const popup = page.waitForEvent('popup', { timeout: 5000 });
+				page.evaluate(() => { window.open('/popup.html'); });
+				popup.then(p => {
+					p.waitForURL('**/popup.html');
+					log(p.title());
+				}, err => log('err: ' + err));`,
+			want: []string{"Popup"},
+		},
+		{
+			name: "anchor",
+			script: `
+				page.on('popup', p => {
+					p.waitForURL('**/popup.html');
+					log(p.title());
+					page.close();
+				});
+				page.click('a');`,
+			want: []string{"Popup"},
+		},
+		{
+			name: "context_page",
+			script: `
+				page.context().on('page', p => {
+					p.waitForURL('**/popup.html');
+					log(p.title());
+					page.close();
+				});
+				page.click('a');`,
+			want: []string{"Popup"},
+		},
+		{
+			name: "predicate",
+			script: `
+				let n = 0;
+				const popup = page.waitForEvent('popup', () => ++n === 2);
+				page.evaluate(() => { window.open(); window.open(); });
+				popup.then(() => log(String(n)), err => log('err: ' + err));`,
+			want: []string{"2"},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tb := newTestBrowser(t, withHTTPServer())
+			tb.withHandler("/opener.html", func(w http.ResponseWriter, _ *http.Request) {
+				fmt.Fprint(w, `<html><body><a href="/popup.html" target="_blank">open</a></body></html>`)
+			})
+			tb.withHandler("/popup.html", func(w http.ResponseWriter, _ *http.Request) {
+				fmt.Fprint(w, `<html><head><title>Popup</title></head><body></body></html>`)
+			})
+			p := tb.NewPage(nil)
+			p.Goto(tb.URL("/opener.html"), nil)
+
+			rt := tb.runtime()
+			require.NoError(t, rt.Set("page", p))
+			var log []string
+			require.NoError(t, rt.Set("log", func(s string) { log = append(log, s) }))
+
+			err := tb.vu.Loop.Start(func() error {
+				if _, err := rt.RunString(tc.script); err != nil {
+					return fmt.Errorf("%w", err)
+				}
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, log)
+		})
+	}
+
+	t.Run("without_listeners", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		p := tb.NewPage(nil)
+		p.Evaluate(tb.toGojaValue(`() => { window.open(); }`))
+
+		// the popup is still reachable from the browser context.
+		assert.Eventually(t, func() bool {
+			return len(p.Context().Pages()) == 2
+		}, 5*time.Second, 50*time.Millisecond)
+	})
+}
+
+func TestPageWaitForEventUnsupported(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t)
+	p := tb.NewPage(nil)
+	rt := tb.runtime()
+	require.NoError(t, rt.Set("page", p))
+
+	_, err := rt.RunString(`page.waitForEvent('nope')`)
+	assert.ErrorContains(t, err, `unsupported page event to wait for: "nope"`)
+}
+
+func TestPageWorkers(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name, script string
+		want         []string
+	}{
+		{
+			name: "evaluate",
+			script: `
+				const worker = page.waitForEvent('worker');
+				page.evaluate(() => { window.worker = new Worker('/worker.js'); });
+				worker.then(w => {
+					log(String(w.url().endsWith('/worker.js')));
+					log(String(w.evaluate(n => {
+						let sum = 0;
+						for (let i = 1; i <= n; i++) sum += i;
+						return sum;
+					}, 100)));
+					log(String(w.evaluate(() => self.double(21))));
+					log(String(w.evaluateHandle(() => ({ a: 1 })).jsonValue().a));
+					log(String(page.workers().length));
+				}, err => log('err: ' + err));`,
+			want: []string{"true", "5050", "42", "1", "1"},
+		},
+		{
+			name: "destroyed",
+			script: `
+				page.on('workerdestroyed', w => {
+					log(String(w.url().endsWith('/worker.js')));
+					log(String(page.workers().length));
+					page.close();
+				});
+				page.on('worker', () => {
+					page.evaluate(() => window.worker.terminate());
+				});
+				page.evaluate(() => { window.worker = new Worker('/worker.js'); });`,
+			want: []string{"true", "0"},
+		},
+		{
+			name: "console",
+			script: `
+				page.on('console', msg => {
+					log(msg.text());
+					log(String(msg.location().url.endsWith('/worker.js')));
+					log(String(msg.worker().url().endsWith('/worker.js')));
+					page.close();
+				});
+				page.evaluate(() => {
+					const w = new Worker('/worker.js');
+					w.postMessage('hello');
+				});`,
+			want: []string{"got hello", "true", "true"},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tb := newTestBrowser(t, withHTTPServer())
+			tb.withHandler("/worker.html", func(w http.ResponseWriter, _ *http.Request) {
+				fmt.Fprint(w, `<html><body></body></html>`)
+			})
+			tb.withHandler("/worker.js", func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", "text/javascript")
+				fmt.Fprint(w, `
+					self.double = n => n * 2;
+					self.onmessage = e => console.log('got ' + e.data);`)
+			})
+			p := tb.NewPage(nil)
+			p.Goto(tb.URL("/worker.html"), nil)
+
+			rt := tb.runtime()
+			require.NoError(t, rt.Set("page", p))
+			var log []string
+			require.NoError(t, rt.Set("log", func(s string) { log = append(log, s) }))
+
+			err := tb.vu.Loop.Start(func() error {
+				if _, err := rt.RunString(tc.script); err != nil {
+					return fmt.Errorf("%w", err)
+				}
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, log)
+		})
+	}
+}
+
+func TestPageFrame(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t, withHTTPServer())
+	tb.withHandler("/frames.html", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `<html><body>
+			<iframe name="checkout" src="/checkout.html"></iframe>
+			<iframe src="/ads/banner.html"></iframe>
+		</body></html>`)
+	})
+	tb.withHandler("/checkout.html", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `<html><head><title>Checkout</title></head></html>`)
+	})
+	tb.withHandler("/ads/banner.html", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `<html><head><title>Banner</title></head></html>`)
+	})
+	p := tb.NewPage(nil)
+	p.Goto(tb.URL("/frames.html"), tb.toGojaValue(map[string]string{"waitUntil": "load"}))
+
+	t.Run("name", func(t *testing.T) {
+		f := p.Frame(tb.toGojaValue("checkout"))
+		require.NotNil(t, f)
+		assert.Equal(t, "checkout", f.Name())
+		assert.Equal(t, "Checkout", f.Title())
+
+		f = p.Frame(tb.toGojaValue(map[string]string{"name": "checkout"}))
+		require.NotNil(t, f)
+		assert.Equal(t, "Checkout", f.Title())
+
+		assert.Nil(t, p.Frame(tb.toGojaValue("nope")))
+	})
+
+	t.Run("url_glob", func(t *testing.T) {
+		f := p.Frame(tb.toGojaValue(map[string]string{"url": "**/ads/*.html"}))
+		require.NotNil(t, f)
+		assert.Equal(t, "Banner", f.Title())
+
+		assert.Nil(t, p.Frame(tb.toGojaValue(map[string]string{
+			"name": "checkout",
+			"url":  "**/ads/*.html",
+		})))
+	})
+
+	t.Run("url_regex", func(t *testing.T) {
+		re, err := tb.runtime().RunString(`/checkout\.html$/`)
+		require.NoError(t, err)
+		f := p.Frame(tb.toGojaValue(map[string]interface{}{"url": re}))
+		require.NotNil(t, f)
+		assert.Equal(t, "checkout", f.Name())
+	})
+
+	t.Run("detached", func(t *testing.T) {
+		f := p.Frame(tb.toGojaValue("checkout"))
+		require.NotNil(t, f)
+
+		p.Evaluate(tb.toGojaValue(`() => document.querySelector('iframe[name=checkout]').remove()`))
+		assert.Eventually(t, func() bool {
+			return p.Frame(tb.toGojaValue("checkout")) == nil
+		}, 5*time.Second, 50*time.Millisecond)
+
+		assert.True(t, f.IsDetached())
+		for _, fr := range p.Frames() {
+			assert.NotEqual(t, "checkout", fr.Name())
+		}
+		assert.Panics(t, func() { f.Title() }, "using a detached frame should not hang")
+	})
+}
+
+func TestPageSetDefaultTimeout(t *testing.T) {
+	t.Parallel()
+
+	waitForMissing := func(p api.Page, opts goja.Value) time.Duration {
+		start := time.Now()
+		assert.Panics(t, func() { p.WaitForSelector("#missing", opts) })
+		return time.Since(start)
+	}
+
+	t.Run("page", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		p := tb.NewPage(nil)
+		p.SetDefaultTimeout(100)
+
+		elapsed := waitForMissing(p, nil)
+		assert.GreaterOrEqual(t, elapsed, 100*time.Millisecond)
+		assert.Less(t, elapsed, 2*time.Second)
+	})
+
+	t.Run("override", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		p := tb.NewPage(nil)
+		p.SetDefaultTimeout(100)
+
+		elapsed := waitForMissing(p, tb.toGojaValue(map[string]interface{}{"timeout": 1000}))
+		assert.GreaterOrEqual(t, elapsed, time.Second)
+	})
+
+	t.Run("context", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		bctx := tb.NewContext(nil)
+		bctx.SetDefaultTimeout(100)
+		p := bctx.NewPage()
+
+		elapsed := waitForMissing(p, nil)
+		assert.GreaterOrEqual(t, elapsed, 100*time.Millisecond)
+		assert.Less(t, elapsed, 2*time.Second)
+	})
+
+	t.Run("navigation", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		tb.withHandler("/slow", func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+		})
+		p := tb.NewPage(nil)
+		p.SetDefaultNavigationTimeout(100)
+
+		start := time.Now()
+		assert.Panics(t, func() { p.Goto(tb.URL("/slow"), nil) })
+		assert.Less(t, time.Since(start), 2*time.Second)
+	})
+}
+
+func TestPageOnRequestFailed(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name, script string
+		want         []string
+		wantFailure  string
+		wantCanceled string
+	}{
+		{
+			name: "blocked",
+			script: `
+				page.evaluate(() => {
+					const img = document.createElement('img');
+					img.src = 'http://127.0.0.1:1/blocked.png';
+					document.body.appendChild(img);
+				});`,
+			want:         []string{"GET", "Image", "http://127.0.0.1:1/blocked.png"},
+			wantCanceled: "false",
+		},
+		{
+			name: "aborted",
+			script: `
+				page.evaluate(() => {
+					const c = new AbortController();
+					fetch('/slow', { signal: c.signal }).catch(() => {});
+					setTimeout(() => c.abort(), 100);
+				});`,
+			want:         []string{"GET", "Fetch", "/slow"},
+			wantFailure:  "net::ERR_ABORTED",
+			wantCanceled: "true",
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tb := newTestBrowser(t, withHTTPServer())
+			tb.withHandler("/empty.html", func(w http.ResponseWriter, _ *http.Request) {
+				fmt.Fprint(w, `<html><body></body></html>`)
+			})
+			tb.withHandler("/slow", func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-r.Context().Done():
+				case <-time.After(5 * time.Second):
+				}
+			})
+			p := tb.NewPage(nil)
+			resp := p.Goto(tb.URL("/empty.html"), nil)
+			require.NotNil(t, resp)
+			assert.True(t, goja.IsNull(resp.Request().Failure()), "succeeded request should have no failure")
+
+			rt := tb.runtime()
+			require.NoError(t, rt.Set("page", p))
+			var log []string
+			require.NoError(t, rt.Set("log", func(s string) { log = append(log, s) }))
+
+			err := tb.vu.Loop.Start(func() error {
+				script := `
+					page.on('requestfailed', req => {
+						const f = req.failure();
+						log(req.method());
+						log(req.resourceType());
+						log(req.url());
+						log(f.errorText);
+						log(String(f.canceled));
+						page.close();
+					});` + tc.script
+				if _, err := rt.RunString(script); err != nil {
+					return fmt.Errorf("%w", err)
+				}
+				return nil
+			})
+			require.NoError(t, err)
+			require.Len(t, log, 5)
+			assert.Equal(t, tc.want[:2], log[:2])
+			assert.True(t, strings.HasSuffix(log[2], tc.want[2]), "unexpected URL %q", log[2])
+			assert.True(t, strings.HasPrefix(log[3], "net::ERR_"), "unexpected error text %q", log[3])
+			if tc.wantFailure != "" {
+				assert.Equal(t, tc.wantFailure, log[3])
+			}
+			assert.Equal(t, tc.wantCanceled, log[4])
+		})
+	}
+}
+
+func TestPageCrashed(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t)
+	p := tb.NewPage(nil)
+	rt := tb.runtime()
+	require.NoError(t, rt.Set("page", p))
+	var log []string
+	require.NoError(t, rt.Set("log", func(s string) { log = append(log, s) }))
+
+	err := tb.vu.Loop.Start(func() error {
+		_, err := rt.RunString(`
+			page.on('crashed', (p) => log('crashed: ' + (p === page)));
+			try {
+				page.goto('chrome://crash');
+			} catch (e) {
+				log('goto: ' + e);
+			}
+			try {
+				page.evaluate(() => 1);
+			} catch (e) {
+				log('evaluate: ' + e);
+			}
+		`)
+		if err != nil {
+			return fmt.Errorf("%w", err)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, log, 3)
+	assert.Contains(t, log, "crashed: true")
+	for _, s := range log {
+		if !strings.HasPrefix(s, "crashed:") {
+			assert.Contains(t, s, "page crashed")
+		}
+	}
+	assert.Empty(t, p.Context().Pages(), "should not list the crashed page")
+}
+
+func TestPageErrorNames(t *testing.T) {
+	t.Parallel()
+
+	t.Run("timeout", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		p := tb.NewPage(nil)
+		rt := tb.runtime()
+		require.NoError(t, rt.Set("page", p))
+
+		got, err := rt.RunString(`
+			let name;
+			try {
+				page.waitForSelector('#missing', { timeout: 100 });
+			} catch (e) {
+				name = e.name;
+			}
+			name;
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "TimeoutError", got.String())
+
+		thrown := func() (thrown interface{}) {
+			defer func() { thrown = recover() }()
+			p.WaitForSelector("#missing", tb.toGojaValue(map[string]interface{}{"timeout": 100}))
+			return nil
+		}()
+		assert.ErrorIs(t, thrownError(t, thrown), &api.TimeoutError{})
+	})
+
+	t.Run("selector_resolution", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		rt := tb.runtime()
+		require.NoError(t, rt.Set("page", tb.NewPage(nil)))
+
+		got, err := rt.RunString(`
+			page.setContent('<button>a</button><button>b</button>');
+			let name;
+			try {
+				page.click('button', { strict: true, timeout: 500 });
+			} catch (e) {
+				name = e.name;
+			}
+			name;
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "SelectorResolutionError", got.String())
+	})
+
+	t.Run("target_closed", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		rt := tb.runtime()
+		require.NoError(t, rt.Set("page", tb.NewPage(nil)))
+
+		got, err := rt.RunString(`
+			page.setContent('<button>a</button>');
+			page.close();
+			let name;
+			try {
+				page.click('button', { timeout: 500 });
+			} catch (e) {
+				name = e.name;
+			}
+			name;
+		`)
+		require.NoError(t, err)
+		assert.Equal(t, "TargetClosedError", got.String())
+	})
+}
+
+func TestPageEvaluateErrorDetails(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t)
+	p := tb.NewPage(nil)
+	rt := tb.runtime()
+	require.NoError(t, rt.Set("page", p))
+
+	fn := `() => {
+		const f = (o) => o.foo.bar;
+		return f({});
+	}`
+	got, err := rt.RunString(`
+		let got;
+		try {
+			page.evaluate(` + "`" + fn + "`" + `);
+		} catch (e) {
+			got = e;
+		}
+		[got.name, got.details.className, got.details.lineNumber, got.details.stackFrames.length > 1, got.message];
+	`)
+	require.NoError(t, err)
+	v := got.Export().([]interface{})
+	assert.Equal(t, "EvaluationError", v[0])
+	assert.Equal(t, "TypeError", v[1])
+	assert.Greater(t, v[2], int64(0), "should report the line of the throw")
+	assert.Equal(t, true, v[3], "should have the stack frames")
+	msg, _ := v[4].(string)
+	assert.Contains(t, msg, "TypeError: Cannot read properties of undefined (reading 'bar') at f (")
+	assert.NotContains(t, msg, "\n", "should be on a single line")
+
+	thrown := func() (thrown interface{}) {
+		defer func() { thrown = recover() }()
+		p.Evaluate(tb.toGojaValue(fn))
+		return nil
+	}()
+	gerr := thrownError(t, thrown)
+	var evalErr *api.EvaluationError
+	require.True(t, errors.As(gerr, &evalErr))
+	assert.Contains(t, evalErr.Stack, "at f (")
 }
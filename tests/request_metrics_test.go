@@ -0,0 +1,134 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	k6metrics "go.k6.io/k6/metrics"
+)
+
+func TestRequestMetrics(t *testing.T) {
+	t.Parallel()
+
+	// the fixture page loads a stylesheet, a script,
+	// and an image from a port that refuses connections.
+	fixture := func(tb *testBrowser) {
+		tb.withHandler("/fixture", func(w http.ResponseWriter, _ *http.Request) {
+			fmt.Fprint(w, `<html><head>
+				<link rel="stylesheet" href="/style.css?v=1">
+				<script src="/script.js?v=1"></script>
+				</head><body><img src="http://127.0.0.1:1/image.png"></body></html>`)
+		})
+		tb.withHandler("/style.css", func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "text/css")
+			fmt.Fprint(w, `body { color: red; }`)
+		})
+		tb.withHandler("/script.js", func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "text/javascript")
+			fmt.Fprint(w, `var loaded = true;`)
+		})
+	}
+	// load loads the fixture page, and returns the tags
+	// of the samples of the fixture by their metric names.
+	load := func(t *testing.T, opts map[string]interface{}) (*testBrowser, map[string][]map[string]string) {
+		t.Helper()
+
+		tb := newTestBrowser(t, withHTTPServer())
+		fixture(tb)
+		samples := make(chan k6metrics.SampleContainer, 1000)
+		tb.vu.StateField.Samples = samples
+
+		bctx := tb.NewContext(tb.toGojaValue(opts))
+		t.Cleanup(bctx.Close)
+		p := bctx.NewPage()
+		require.NotNil(t, p.Goto(tb.URL("/fixture"), tb.toGojaValue(map[string]interface{}{
+			"waitUntil": "load",
+		})))
+
+		urls := map[string]bool{
+			tb.URL("/fixture"):             true,
+			tb.URL("/style.css"):           true,
+			tb.URL("/script.js"):           true,
+			"http://127.0.0.1:1/image.png": true,
+		}
+		got := make(map[string][]map[string]string)
+		for len(samples) > 0 {
+			for _, s := range (<-samples).GetSamples() {
+				tags := s.Tags.CloneTags()
+				if urls[tags["url"]] {
+					got[s.Metric.Name] = append(got[s.Metric.Name], tags)
+				}
+			}
+		}
+		return tb, got
+	}
+
+	t.Run("enabled", func(t *testing.T) {
+		t.Parallel()
+
+		tb, got := load(t, map[string]interface{}{
+			"metricURLGrouping": "stripQuery",
+		})
+
+		for _, name := range []string{"data_sent", "http_reqs", "http_req_duration", "data_received"} {
+			assert.Len(t, got[name], 4, name)
+		}
+		types := make(map[string]string)
+		for _, tags := range got["http_req_duration"] {
+			types[tags["url"]] = tags["resource_type"]
+			assert.Equal(t, "GET", tags["method"])
+			assert.Equal(t, "false", tags["from_cache"])
+		}
+		assert.Equal(t, map[string]string{
+			tb.URL("/fixture"):             "document",
+			tb.URL("/style.css"):           "stylesheet",
+			tb.URL("/script.js"):           "script",
+			"http://127.0.0.1:1/image.png": "image",
+		}, types)
+
+		require.Len(t, got["browser_failed_requests"], 1)
+		failed := got["browser_failed_requests"][0]
+		assert.Equal(t, "image", failed["resource_type"])
+		assert.NotContains(t, failed, "error")
+	})
+	t.Run("error_tag", func(t *testing.T) {
+		t.Parallel()
+
+		_, got := load(t, map[string]interface{}{
+			"requestMetrics": map[string]interface{}{"errorTag": true},
+		})
+		require.Len(t, got["browser_failed_requests"], 1)
+		assert.Equal(t, "net::ERR_CONNECTION_REFUSED", got["browser_failed_requests"][0]["error"])
+	})
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+
+		_, got := load(t, map[string]interface{}{
+			"requestMetrics": map[string]interface{}{"enabled": false},
+		})
+		assert.Empty(t, got)
+	})
+}
@@ -0,0 +1,179 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestPostData(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name, send string
+		want       []string
+	}{
+		{
+			name: "json",
+			send: `fetch('/api', {
+				method: 'POST',
+				headers: { 'Content-Type': 'application/json' },
+				body: JSON.stringify({ name: 'k6', tags: ['a', 'b'] }),
+			});`,
+			want: []string{`{"name":"k6","tags":["a","b"]}`, "k6", "a,b"},
+		},
+		{
+			name: "form",
+			send: `
+				document.body.innerHTML = '<form method="POST" action="/api">' +
+					'<input name="name" value="k6"><input name="tags" value="b"></form>';
+				document.querySelector('form').submit();`,
+			want: []string{"name=k6&tags=b", "k6", "b"},
+		},
+		{
+			name: "long",
+			send: `fetch('/api', {
+				method: 'POST',
+				headers: { 'Content-Type': 'application/json' },
+				body: JSON.stringify({ name: 'k6', tags: ['b'], padding: 'a'.repeat(70 * 1024) }),
+			});`,
+			want: []string{"71719", "k6", "b"},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tb := newTestBrowser(t, withHTTPServer())
+			tb.withHandler("/empty.html", func(w http.ResponseWriter, _ *http.Request) {
+				fmt.Fprint(w, `<html><body></body></html>`)
+			})
+			p := tb.NewPage(nil)
+			p.Goto(tb.URL("/empty.html"), nil)
+
+			rt := tb.runtime()
+			require.NoError(t, rt.Set("page", p))
+			var log []string
+			require.NoError(t, rt.Set("log", func(s string) { log = append(log, s) }))
+
+			err := tb.vu.Loop.Start(func() error {
+				_, err := rt.RunString(fmt.Sprintf(`
+					page.route('**/api', route => {
+						const req = route.request();
+						const data = req.postData();
+						log(data.length > 1024 ? String(data.length) : data);
+						const json = req.postDataJSON();
+						log(json.name);
+						log(String(json.tags));
+						route.fulfill();
+						page.close();
+					});
+					page.evaluate(() => { %s });`, tc.send))
+				if err != nil {
+					return fmt.Errorf("%w", err)
+				}
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, log)
+		})
+	}
+}
+
+func TestRequestRedirects(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t, withHTTPServer())
+	for from, to := range map[string]string{"/r1": "/r2", "/r2": "/r3", "/r3": "/final"} {
+		to := to
+		tb.withHandler(from, func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, to, http.StatusFound)
+		})
+	}
+	tb.withHandler("/final", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `<html><body>final</body></html>`)
+	})
+	p := tb.NewPage(nil)
+
+	resp := p.Goto(tb.URL("/r1"), nil)
+	require.NotNil(t, resp)
+	assert.Equal(t, tb.URL("/final"), resp.URL())
+
+	var urls []string
+	for req := resp.Request(); req != nil; req = req.RedirectedFrom() {
+		urls = append(urls, strings.TrimPrefix(req.URL(), tb.URL("")))
+	}
+	assert.Equal(t, []string{"/final", "/r3", "/r2", "/r1"}, urls)
+
+	first := resp.Request().RedirectedFrom().RedirectedFrom().RedirectedFrom()
+	assert.Equal(t, tb.URL("/r2"), first.RedirectedTo().URL())
+	assert.Equal(t, int64(http.StatusFound), first.Response().Status())
+	assert.Nil(t, resp.Request().RedirectedTo())
+}
+
+func TestRequestRedirectLoop(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t, withHTTPServer())
+	tb.withHandler("/empty.html", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `<html><body></body></html>`)
+	})
+	tb.withHandler("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	p := tb.NewPage(nil)
+	p.Goto(tb.URL("/empty.html"), nil)
+
+	rt := tb.runtime()
+	require.NoError(t, rt.Set("page", p))
+	var log []string
+	require.NoError(t, rt.Set("log", func(s string) { log = append(log, s) }))
+
+	err := tb.vu.Loop.Start(func() error {
+		_, err := rt.RunString(`
+			page.on('requestfailed', req => {
+				let first = req;
+				let redirects = 0;
+				while (first.redirectedFrom()) {
+					first = first.redirectedFrom();
+					redirects++;
+				}
+				log(req.failure().errorText);
+				log(String(redirects > 1));
+				log(String(first.redirectedTo().url() === req.url()));
+				page.close();
+			});
+			page.evaluate(() => { fetch('/loop').catch(() => {}); });`)
+		if err != nil {
+			return fmt.Errorf("%w", err)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"net::ERR_TOO_MANY_REDIRECTS", "true", "true"}, log)
+}
@@ -0,0 +1,126 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseBody(t *testing.T) {
+	t.Parallel()
+
+	png := []byte{0x89, 'P', 'N', 'G', 0x0d, 0x0a, 0x1a, 0x0a}
+	tb := newTestBrowser(t, withHTTPServer())
+	tb.withHandler("/text", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, "hello")
+	})
+	tb.withHandler("/json", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"name":"k6","tags":["a"]}`)
+	})
+	tb.withHandler("/image.png", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write(png)
+	})
+	p := tb.NewPage(nil)
+
+	resp := p.Goto(tb.URL("/text"), nil)
+	require.NotNil(t, resp)
+	assert.Equal(t, "hello", resp.Text())
+	assert.Equal(t, "hello", resp.Text(), "the cached body should be returned again")
+
+	resp = p.Goto(tb.URL("/json"), nil)
+	require.NotNil(t, resp)
+	assert.Equal(t, map[string]interface{}{
+		"name": "k6",
+		"tags": []interface{}{"a"},
+	}, resp.JSON().Export())
+
+	resp = p.Goto(tb.URL("/image.png"), nil)
+	require.NotNil(t, resp)
+	assert.Equal(t, png, resp.Body().Bytes())
+}
+
+func TestResponseHeaders(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t, withHTTPServer())
+	tb.withHandler("/cookies", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Add("Set-Cookie", "a=1")
+		w.Header().Add("Set-Cookie", "b=2")
+		fmt.Fprint(w, "cookies")
+	})
+	tb.withHandler("/empty", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, "empty")
+	})
+	p := tb.NewPage(nil)
+
+	resp := p.Goto(tb.URL("/cookies"), nil)
+	require.NotNil(t, resp)
+	assert.Equal(t, []string{"a=1", "b=2"}, resp.HeaderValues("set-cookie"))
+	assert.Equal(t, "a=1\nb=2", resp.AllHeaders()["set-cookie"])
+	var setCookies int
+	for _, h := range resp.HeadersArray() {
+		if h.Name == "Set-Cookie" {
+			setCookies++
+		}
+	}
+	assert.Equal(t, 2, setCookies)
+
+	// the browser sends the cookies with the next request.
+	resp = p.Goto(tb.URL("/empty"), nil)
+	require.NotNil(t, resp)
+	assert.Equal(t, "a=1; b=2", resp.Request().HeaderValue("Cookie").Export())
+	assert.Equal(t, []string{"a=1; b=2"}, resp.Request().HeaderValues("cookie"))
+}
+
+func TestResponseSecurityDetails(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t, withHTTPServer())
+	tb.withHandler("/secure", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, "secure")
+	})
+	bctx := tb.NewContext(tb.toGojaValue(struct {
+		IgnoreHTTPSErrors bool `js:"ignoreHTTPSErrors"`
+	}{
+		IgnoreHTTPSErrors: true,
+	}))
+	p := bctx.NewPage()
+
+	resp := p.Goto(tb.http.ServerHTTPS.URL+"/secure", nil)
+	require.NotNil(t, resp)
+	sd := resp.SecurityDetails().ToObject(tb.runtime())
+	assert.Contains(t, sd.Get("protocol").String(), "TLS")
+	assert.Contains(t, sd.Get("sanList").Export(), "example.com")
+	addr := resp.ServerAddr().ToObject(tb.runtime())
+	assert.Equal(t, "127.0.0.1", addr.Get("ipAddress").String())
+
+	resp = p.Goto(tb.URL("/secure"), nil)
+	require.NotNil(t, resp)
+	assert.True(t, goja.IsNull(resp.SecurityDetails()))
+}
@@ -0,0 +1,182 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBrowserContextRoute(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name, script string
+		want         []string
+	}{
+		{
+			name: "popup",
+			script: `
+				context.route('**/api/user', route => {
+					log(route.request().url());
+					route.fulfill({ contentType: 'application/json', body: '{}' });
+					context.close();
+				});
+				page.evaluate(() => { window.open('/popup.html'); });`,
+			want: []string{"/api/user"},
+		},
+		{
+			name: "page_first",
+			script: `
+				context.route('**/api/user', route => {
+					log('context');
+					route.fulfill();
+					context.close();
+				});
+				page.route('**/api/user', route => {
+					log('page');
+					route.fulfill();
+					context.close();
+				});
+				page.evaluate(() => { fetch('/api/user'); });`,
+			want: []string{"page"},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tb := newTestBrowser(t, withHTTPServer())
+			tb.withHandler("/empty.html", func(w http.ResponseWriter, _ *http.Request) {
+				fmt.Fprint(w, `<html><body></body></html>`)
+			})
+			tb.withHandler("/popup.html", func(w http.ResponseWriter, _ *http.Request) {
+				fmt.Fprint(w, `<html><body><script>fetch('/api/user');</script></body></html>`)
+			})
+			tb.withHandler("/api/user", func(w http.ResponseWriter, _ *http.Request) {
+				fmt.Fprint(w, `{"name":"server"}`)
+			})
+			bctx := tb.NewContext(nil)
+			p := bctx.NewPage()
+			p.Goto(tb.URL("/empty.html"), nil)
+
+			rt := tb.runtime()
+			require.NoError(t, rt.Set("context", bctx))
+			require.NoError(t, rt.Set("page", p))
+			var log []string
+			require.NoError(t, rt.Set("log", func(s string) {
+				log = append(log, strings.TrimPrefix(s, tb.URL("")))
+			}))
+
+			err := tb.vu.Loop.Start(func() error {
+				if _, err := rt.RunString(tc.script); err != nil {
+					return fmt.Errorf("%w", err)
+				}
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, log)
+		})
+	}
+}
+
+func TestPageUnroute(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name, script string
+		want         []string
+	}{
+		{
+			name: "handler",
+			script: `
+				const stub = route => {
+					log('stub');
+					route.fulfill({ body: 'stub' });
+					page.unroute('**/api/user', stub);
+					page.evaluate(() => {
+						fetch('/api/user').then(r => r.text()).then(t => console.log(t));
+					});
+				};
+				page.route('**/api/user', stub);
+				page.on('console', msg => {
+					log(msg.text());
+					page.close();
+				});
+				page.evaluate(() => { fetch('/api/user'); });`,
+			want: []string{"stub", "server"},
+		},
+		{
+			name: "readd",
+			script: `
+				const second = route => {
+					log('second');
+					route.fulfill();
+					page.close();
+				};
+				page.route('**/api/user', route => {
+					log('first');
+					route.fulfill();
+					page.unroute('**/api/user');
+					page.route('**/api/user', second);
+					page.evaluate(() => { fetch('/api/user'); });
+				});
+				page.evaluate(() => { fetch('/api/user'); });`,
+			want: []string{"first", "second"},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tb := newTestBrowser(t, withHTTPServer())
+			tb.withHandler("/empty.html", func(w http.ResponseWriter, _ *http.Request) {
+				fmt.Fprint(w, `<html><body></body></html>`)
+			})
+			tb.withHandler("/api/user", func(w http.ResponseWriter, _ *http.Request) {
+				fmt.Fprint(w, `server`)
+			})
+			p := tb.NewPage(nil)
+			p.Goto(tb.URL("/empty.html"), nil)
+
+			rt := tb.runtime()
+			require.NoError(t, rt.Set("page", p))
+			var log []string
+			require.NoError(t, rt.Set("log", func(s string) { log = append(log, s) }))
+
+			err := tb.vu.Loop.Start(func() error {
+				if _, err := rt.RunString(tc.script); err != nil {
+					return fmt.Errorf("%w", err)
+				}
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, log)
+		})
+	}
+}
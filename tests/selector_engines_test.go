@@ -0,0 +1,320 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTextSelectorEngine(t *testing.T) {
+	t.Parallel()
+
+	const html = `
+	<div id="form">
+	  <button id="signin">  Sign
+	    in </button>
+	  <button id="signup">Sign up</button>
+	  <p id="nested">Welcome <b id="user">back</b>, <span id="name">Jane</span></p>
+	  <div id="hidden" style="display: none">Secret</div>
+	  <div id="partly">Visible <span style="visibility: hidden">Secret</span></div>
+	  <input id="submit" type="submit" value="Send">
+	</div>`
+
+	testCases := []struct {
+		name, selector string
+		want           []string
+	}{
+		{name: "substring", selector: "text=sign", want: []string{"signin", "signup"}},
+		{name: "case_insensitive", selector: "text=SIGN IN", want: []string{"signin"}},
+		{name: "whitespace", selector: "text=  sign   in ", want: []string{"signin"}},
+		{name: "exact", selector: `text="Sign in"`, want: []string{"signin"}},
+		{name: "exact_case_sensitive", selector: `text="sign in"`},
+		{name: "exact_shorthand", selector: `"Sign up"`, want: []string{"signup"}},
+		{name: "regexp", selector: "text=/^sign (in|up)$/i", want: []string{"signin", "signup"}},
+		{name: "deepest", selector: "text=Jane", want: []string{"name"}},
+		{name: "across_children", selector: "text=Welcome back, Jane", want: []string{"nested"}},
+		{name: "hidden", selector: "text=Secret"},
+		{name: "hidden_descendant", selector: `text="Visible"`, want: []string{"partly"}},
+		{name: "input_value", selector: "text=Send", want: []string{"submit"}},
+		{name: "chained", selector: "#nested >> text=back", want: []string{"user"}},
+		{name: "chained_no_match", selector: "#hidden >> text=Sign"},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tb := newTestBrowser(t)
+			p := tb.NewPage(nil)
+			p.SetContent(html, nil)
+
+			var got []string
+			for _, h := range p.QueryAll(tc.selector) {
+				got = append(got, h.GetAttribute("id").String())
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+
+	t.Run("click", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		p := tb.NewPage(nil)
+		p.SetContent(`
+		<button onclick="window.clicked = 'signup'">Sign up</button>
+		<button onclick="window.clicked = 'signin'">Sign in</button>`, nil)
+
+		p.Click("text=Sign in", nil)
+		require.Equal(t, "signin", tb.asGojaValue(p.Evaluate(tb.toGojaValue(`() => window.clicked`))).String())
+		assert.Equal(t, "Sign up", p.TextContent(`text="Sign up"`, nil))
+	})
+}
+
+func TestCustomSelectorEngine(t *testing.T) {
+	t.Parallel()
+
+	const html = `
+	<button data-comp="CartButton">Cart</button>
+	<div data-comp="ProductList">
+	  <button data-comp="CartButton">Add to cart</button>
+	</div>`
+
+	// compEngine finds the elements by their component name.
+	const compEngine = `({
+	  queryAll(root, name) {
+	    return root.querySelectorAll('[data-comp="' + name + '"]');
+	  }
+	})`
+
+	panicMessage := func(t *testing.T, fn func()) string {
+		t.Helper()
+
+		var msg string
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					errObj, ok := err.(*goja.Object)
+					require.True(t, ok)
+					msg = errObj.String()
+				}
+			}()
+			fn()
+		}()
+		return msg
+	}
+
+	t.Run("query", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		tb.RegisterSelectorEngine("comp", tb.toGojaValue(compEngine))
+		p := tb.NewPage(nil)
+		p.SetContent(html, nil)
+
+		assert.Len(t, p.QueryAll("comp=CartButton"), 2)
+		assert.Equal(t, "Add to cart", p.TextContent("comp=ProductList >> comp=CartButton", nil))
+		assert.Nil(t, p.Query("comp=Checkout"))
+	})
+
+	t.Run("query_only", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		fn, err := tb.runtime().RunString(`(() => ({
+		  query: (root, name) => root.querySelector('[data-comp="' + name + '"]')
+		}))`)
+		require.NoError(t, err)
+		tb.RegisterSelectorEngine("comp", fn)
+		p := tb.NewPage(nil)
+		p.SetContent(html, nil)
+
+		assert.Len(t, p.QueryAll("comp=CartButton"), 1)
+	})
+
+	t.Run("existing_page", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		p := tb.NewPage(nil)
+		p.SetContent(html, nil)
+		// make sure that the injected script is already created.
+		require.NotNil(t, p.Query("button"))
+
+		tb.RegisterSelectorEngine("comp", tb.toGojaValue(compEngine))
+		assert.Equal(t, "Cart", p.TextContent("comp=CartButton", nil))
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		tb.RegisterSelectorEngine("comp", tb.toGojaValue(compEngine))
+		msg := panicMessage(t, func() {
+			tb.RegisterSelectorEngine("comp", tb.toGojaValue(compEngine))
+		})
+		assert.Contains(t, msg, `registering selector engine "comp": already registered`)
+	})
+
+	t.Run("syntax_error", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		tb.RegisterSelectorEngine("broken", tb.toGojaValue(`({ queryAll(root, s) { return [ } })`))
+		p := tb.NewPage(nil)
+		p.SetContent(html, nil)
+
+		msg := panicMessage(t, func() { p.Query("broken=x") })
+		assert.Contains(t, msg, `selector engine "broken" failed`)
+		assert.Contains(t, msg, "SyntaxError")
+		// the other selectors keep working.
+		assert.NotNil(t, p.Query("button"))
+	})
+
+	t.Run("exception", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		tb.RegisterSelectorEngine("throws", tb.toGojaValue(`({
+		  queryAll() { throw new Error("boom"); }
+		})`))
+		p := tb.NewPage(nil)
+		p.SetContent(html, nil)
+
+		msg := panicMessage(t, func() { p.Click("throws=x", nil) })
+		assert.Contains(t, msg, `selector engine "throws" failed: boom`)
+	})
+}
+
+func TestTestIDSelectorEngine(t *testing.T) {
+	t.Parallel()
+
+	const html = `
+	<button data-testid="submit-order" data-qa="place-order">Order</button>
+	<button data-testid="cancel" data-qa="cancel-order">Cancel</button>
+	<div id="later"></div>
+	<script>
+	  setTimeout(() => {
+	    document.querySelector('#later').innerHTML =
+	      '<span data-testid="done" data-qa="order-done">Done</span>';
+	  }, 100);
+	</script>`
+
+	t.Run("default", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		p := tb.NewPage(nil)
+		p.SetContent(html, nil)
+
+		p.Click("testid=submit-order", nil)
+		assert.Equal(t, "Order", p.TextContent("data-testid=submit-order", nil))
+		assert.Equal(t, "Cancel", p.TextContent(`testid="cancel"`, nil))
+		assert.Nil(t, p.Query("testid=submit"), "should match the whole attribute value")
+
+		el := p.WaitForSelector("testid=done", nil)
+		require.NotNil(t, el)
+		assert.Equal(t, "Done", el.TextContent())
+	})
+
+	t.Run("attribute", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t)
+		bctx := tb.NewContext(tb.toGojaValue(map[string]interface{}{
+			"testIdAttribute": "data-qa",
+		}))
+		p := bctx.NewPage()
+		p.SetContent(html, nil)
+
+		assert.Equal(t, "Order", p.TextContent("testid=place-order", nil))
+		assert.Nil(t, p.Query("testid=submit-order"))
+		// data-testid always matches the data-testid attribute.
+		assert.Equal(t, "Order", p.TextContent("data-testid=submit-order", nil))
+
+		el := p.WaitForSelector("testid=order-done", nil)
+		require.NotNil(t, el)
+		assert.Equal(t, "Done", el.TextContent())
+	})
+}
+
+func TestSelectorsPierceShadowRoots(t *testing.T) {
+	t.Parallel()
+
+	panicMessage := func(t *testing.T, fn func()) string {
+		t.Helper()
+
+		var msg string
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					errObj, ok := err.(*goja.Object)
+					require.True(t, ok)
+					msg = errObj.String()
+				}
+			}()
+			fn()
+		}()
+		return msg
+	}
+
+	t.Run("fill_and_click", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t, withFileServer())
+		p := tb.NewPage(nil)
+		require.NotNil(t, p.Goto(tb.staticURL("/shadow_dom.html"), nil))
+
+		p.Fill("login-field[name=username] input", "jane", nil)
+		p.Fill("testid=password", "secret", nil)
+		p.Click("text=Sign in", nil)
+
+		assert.Equal(t, "jane", p.InputValue("testid=username", nil))
+		assert.Equal(t, "secret", p.InputValue("[data-testid=password]", nil))
+		assert.True(t, tb.asGojaBool(p.Evaluate(tb.toGojaValue(`() => window.submitted`))))
+		assert.Equal(t, "Password", p.TextContent("login-field[name=password] >> label", nil))
+	})
+
+	t.Run("wait_for_selector", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t, withFileServer())
+		p := tb.NewPage(nil)
+		require.NotNil(t, p.Goto(tb.staticURL("/shadow_dom.html"), nil))
+
+		p.Evaluate(tb.toGojaValue(`() => addField("email", 100)`))
+		el := p.WaitForSelector("testid=email", nil)
+		require.NotNil(t, el)
+		assert.Equal(t, "email", el.GetAttribute("data-testid").String())
+	})
+
+	t.Run("light", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t, withFileServer())
+		p := tb.NewPage(nil)
+		require.NotNil(t, p.Goto(tb.staticURL("/shadow_dom.html"), nil))
+
+		assert.Len(t, p.QueryAll("input"), 2)
+		assert.Empty(t, p.QueryAll("css:light=input"))
+		assert.Empty(t, p.QueryAll("text:light=Sign in"))
+		assert.Empty(t, p.QueryAll("testid:light=username"))
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		t.Parallel()
+
+		tb := newTestBrowser(t, withFileServer())
+		p := tb.NewPage(nil)
+		require.NotNil(t, p.Goto(tb.staticURL("/shadow_dom.html"), nil))
+
+		assert.Nil(t, p.Query(".secret"))
+		msg := panicMessage(t, func() {
+			p.WaitForSelector(".secret", tb.toGojaValue(map[string]interface{}{"timeout": 500}))
+		})
+		assert.Contains(t, msg, `cannot pierce closed shadow root of <closed-box id="closed">`)
+		assert.Contains(t, msg, `selector ".secret" matches elements inside it`)
+	})
+}
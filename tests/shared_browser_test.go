@@ -0,0 +1,175 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/xk6-browser/api"
+	"github.com/grafana/xk6-browser/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSharedBrowserAcrossIterations(t *testing.T) {
+	t.Parallel()
+
+	opts := defaultLaunchOpts()
+	opts.ReuseBrowser = true
+	tb := newTestBrowser(t, withLaunchOptions(opts), withHTTPServer(), withSkipClose())
+
+	shared := common.NewSharedBrowser(tb.vu)
+	t.Cleanup(shared.Close)
+
+	var launches int
+	launch := func() (api.Browser, context.CancelFunc) {
+		launches++
+		return tb.Browser, func() {}
+	}
+
+	rt := tb.runtime()
+	require.NoError(t, rt.Set("baseURL", tb.URL("")))
+
+	// iterate runs a single iteration with the shared
+	// browser and returns the cookies of its context.
+	iterate := func(script string) (pid int, cookies int64) {
+		t.Helper()
+
+		require.NoError(t, rt.Set("browser", shared.Browser(launch)))
+		v, err := rt.RunString(`(() => {
+			const context = browser.newContext();
+			` + script + `
+			return context.cookies().length;
+		})()`)
+		require.NoError(t, err)
+		// the script closes the browser as usual,
+		// which keeps the shared browser running.
+		_, err = rt.RunString(`browser.close()`)
+		require.NoError(t, err)
+
+		pid = shared.Pid()
+		shared.EndIteration()
+
+		return pid, v.ToInteger()
+	}
+
+	pid1, cookies := iterate(`context.addCookies([{ name: 'session', value: '1', url: baseURL }]);`)
+	assert.Equal(t, int64(1), cookies)
+	assert.Empty(t, tb.Contexts(), "the contexts of the iteration must be closed")
+	assert.True(t, tb.IsConnected(), "the browser must keep running")
+
+	pid2, cookies := iterate(``)
+	assert.Equal(t, int64(0), cookies, "the cookies must not leak to the next iteration")
+	assert.NotZero(t, pid1)
+	assert.Equal(t, pid1, pid2, "the browser process must be reused")
+	assert.Equal(t, 1, launches)
+}
+
+func TestSharedBrowserIterationError(t *testing.T) {
+	t.Parallel()
+
+	opts := defaultLaunchOpts()
+	opts.ReuseBrowser = true
+	tb := newTestBrowser(t, withLaunchOptions(opts), withSkipClose())
+
+	shared := common.NewSharedBrowser(tb.vu)
+	t.Cleanup(shared.Close)
+
+	launch := func() (api.Browser, context.CancelFunc) {
+		return tb.Browser, func() {}
+	}
+	rt := tb.runtime()
+
+	require.NoError(t, rt.Set("browser", shared.Browser(launch)))
+	_, err := rt.RunString(`
+		const page = browser.newContext().newPage();
+		page.click('#missing', { timeout: 100 });
+	`)
+	require.Error(t, err)
+	shared.EndIteration()
+
+	require.True(t, tb.IsConnected(), "an iteration error must not terminate the browser")
+	assert.Same(t, tb.Browser, shared.Browser(launch))
+}
+
+func TestSharedBrowserRunEnd(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		// iterate runs an iteration with the shared browser,
+		// and returns after the run of the VU ends.
+		iterate func(*common.SharedBrowser, common.BrowserLauncher, context.Context)
+	}{
+		{
+			name: "idle",
+			iterate: func(s *common.SharedBrowser, launch common.BrowserLauncher, runCtx context.Context) {
+				s.Browser(launch)
+				s.EndIteration()
+				<-runCtx.Done()
+			},
+		},
+		{
+			name: "iterating",
+			iterate: func(s *common.SharedBrowser, launch common.BrowserLauncher, runCtx context.Context) {
+				s.Browser(launch)
+				<-runCtx.Done()
+				s.EndIteration()
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			opts := defaultLaunchOpts()
+			opts.ReuseBrowser = true
+			tb := newTestBrowser(t, withLaunchOptions(opts), withSkipClose())
+
+			// the contexts of the iterations inherit
+			// the deadline of the run of the VU.
+			runCtx, cancelRun := context.WithTimeout(tb.vu.Context(), time.Second)
+			defer cancelRun()
+			tb.vu.CtxField = runCtx
+
+			shared := common.NewSharedBrowser(tb.vu)
+			t.Cleanup(shared.Close)
+
+			canceled := make(chan struct{})
+			launch := func() (api.Browser, context.CancelFunc) {
+				return tb.Browser, func() { close(canceled) }
+			}
+			tt.iterate(shared, launch, runCtx)
+
+			select {
+			case <-canceled:
+			case <-time.After(5 * time.Second):
+				t.Fatal("the browser must be closed when the run of the VU ends")
+			}
+			assert.False(t, tb.IsConnected())
+			assert.Zero(t, shared.Pid())
+		})
+	}
+}
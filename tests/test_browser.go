@@ -30,6 +30,7 @@ import (
 
 	"github.com/grafana/xk6-browser/api"
 	"github.com/grafana/xk6-browser/chromium"
+	"github.com/grafana/xk6-browser/common"
 	"github.com/grafana/xk6-browser/k6ext"
 	"github.com/grafana/xk6-browser/k6ext/k6test"
 
@@ -252,10 +253,16 @@ func (b *testBrowser) asGojaBool(v interface{}) bool {
 // launchOptions provides a way to customize browser type
 // launch options in tests.
 type launchOptions struct {
-	Debug    bool   `js:"debug"`
-	Headless bool   `js:"headless"`
-	SlowMo   string `js:"slowMo"`
-	Timeout  string `js:"timeout"`
+	Args                  []string `js:"args"`
+	ArtifactsDir          string   `js:"artifactsDir"`
+	AutoRestart           bool     `js:"autoRestart"`
+	Debug                 bool     `js:"debug"`
+	EvaluateMaxBinarySize int64    `js:"evaluateMaxBinarySize"`
+	Headless              bool     `js:"headless"`
+	ReuseBrowser          bool     `js:"reuseBrowser"`
+	ReusePage             bool     `js:"reusePage"`
+	SlowMo                string   `js:"slowMo"`
+	Timeout               string   `js:"timeout"`
 }
 
 // withLaunchOptions is a helper for increasing readability
@@ -278,9 +285,10 @@ func defaultLaunchOpts() launchOptions {
 	}
 
 	return launchOptions{
-		Headless: headless,
-		SlowMo:   "0s",
-		Timeout:  "30s",
+		EvaluateMaxBinarySize: common.DefaultEvaluateMaxBinarySize,
+		Headless:              headless,
+		SlowMo:                "0s",
+		Timeout:               "30s",
 	}
 }
 
@@ -0,0 +1,133 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package tests
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana/xk6-browser/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type traceAction struct {
+	Name      string  `json:"name"`
+	Selector  string  `json:"selector"`
+	URL       string  `json:"url"`
+	StartTime float64 `json:"startTime"`
+	EndTime   float64 `json:"endTime"`
+}
+
+// readTraceArchive returns the browser trace events
+// and the actions in a trace archive.
+func readTraceArchive(t *testing.T, path string) (events string, actions []traceAction) {
+	t.Helper()
+
+	zr, err := zip.OpenReader(path)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, zr.Close()) }()
+
+	decode := func(name string, v interface{}) {
+		f, err := zr.Open(name)
+		require.NoError(t, err)
+		defer func() { require.NoError(t, f.Close()) }()
+		require.NoError(t, json.NewDecoder(f).Decode(v))
+	}
+	var trace struct {
+		TraceEvents json.RawMessage `json:"traceEvents"`
+	}
+	decode("trace.json", &trace)
+	decode("actions.json", &actions)
+
+	return string(trace.TraceEvents), actions
+}
+
+func TestBrowserContextTracing(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t, withHTTPServer())
+	for _, path := range []string{"/page1", "/page2"} {
+		tb.withHandler(path, func(w http.ResponseWriter, _ *http.Request) {
+			fmt.Fprint(w, `<button onclick="this.textContent = 'clicked'">click</button>`)
+		})
+	}
+
+	dir := t.TempDir()
+	bctx1 := tb.NewContext(nil)
+	bctx2 := tb.NewContext(nil)
+	tracing1 := bctx1.(*common.BrowserContext).Tracing
+	tracing2 := bctx2.(*common.BrowserContext).Tracing
+	tracing1.Start(tb.toGojaValue(map[string]interface{}{"name": "trace1", "screenshots": true}))
+	tracing2.Start(nil)
+
+	// both contexts navigate while both traces are recorded.
+	p1 := bctx1.NewPage()
+	p2 := bctx2.NewPage()
+	p1.Goto(tb.URL("/page1"), nil)
+	p2.Goto(tb.URL("/page2"), nil)
+	p1.Click("button", nil)
+	assert.Equal(t, "clicked", p1.TextContent("button", nil))
+
+	// stopping a trace doesn't stop the traces of the other contexts.
+	path1 := filepath.Join(dir, "trace1.zip")
+	tracing1.Stop(tb.toGojaValue(map[string]interface{}{"path": path1}))
+
+	p2.Click("button", nil)
+	path2 := filepath.Join(dir, "trace2.zip")
+	tracing2.Stop(tb.toGojaValue(map[string]interface{}{"path": path2}))
+
+	events, actions := readTraceArchive(t, path1)
+	assert.Contains(t, events, tb.URL("/page1"))
+	assert.NotContains(t, events, tb.URL("/page2"), "trace has the events of another context")
+	require.Len(t, actions, 2)
+	assert.Equal(t, "goto", actions[0].Name)
+	assert.Equal(t, tb.URL("/page1"), actions[0].URL)
+	assert.Equal(t, "click", actions[1].Name)
+	assert.Equal(t, "button", actions[1].Selector)
+	assert.LessOrEqual(t, actions[0].StartTime, actions[0].EndTime)
+	assert.LessOrEqual(t, actions[0].EndTime, actions[1].StartTime)
+	assert.LessOrEqual(t, actions[1].StartTime, actions[1].EndTime)
+
+	events, actions = readTraceArchive(t, path2)
+	assert.Contains(t, events, tb.URL("/page2"))
+	assert.NotContains(t, events, tb.URL("/page1"), "trace has the events of another context")
+	require.Len(t, actions, 2)
+	assert.Equal(t, "goto", actions[0].Name)
+	assert.Equal(t, "click", actions[1].Name)
+}
+
+func TestBrowserContextTracingNotStarted(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t)
+	tracing := tb.NewContext(nil).(*common.BrowserContext).Tracing
+	assert.Panics(t, func() { tracing.Stop(nil) })
+
+	tracing.Start(nil)
+	assert.Panics(t, func() { tracing.Start(nil) })
+	tracing.Stop(nil)
+}
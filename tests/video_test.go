@@ -0,0 +1,73 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageVideo(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t, withHTTPServer())
+	tb.withHandler("/color", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<html><body style="background: %s"></body></html>`, r.URL.Query().Get("c"))
+	})
+
+	dir := t.TempDir()
+	bctx := tb.NewContext(tb.toGojaValue(map[string]interface{}{
+		"recordVideo": map[string]interface{}{"dir": dir},
+	}))
+	p := bctx.NewPage()
+	require.NotNil(t, p.Video())
+	path := p.Video().Path()
+	assert.Equal(t, dir, filepath.Dir(path))
+	assert.Equal(t, ".avi", filepath.Ext(path))
+
+	for _, c := range []string{"red", "green", "blue"} {
+		p.Goto(tb.URL("/color?c="+c), nil)
+		time.Sleep(200 * time.Millisecond)
+	}
+	bctx.Close()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Greater(t, len(data), 12)
+	assert.Equal(t, "RIFF", string(data[0:4]))
+	assert.Equal(t, "AVI ", string(data[8:12]))
+	assert.Greater(t, bytes.Count(data, []byte("00dc")), 1, "expected more than one frame")
+}
+
+func TestPageVideoNotRecorded(t *testing.T) {
+	t.Parallel()
+
+	p := newTestBrowser(t).NewPage(nil)
+	assert.Nil(t, p.Video())
+}
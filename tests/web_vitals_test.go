@@ -0,0 +1,75 @@
+/*
+ *
+ * xk6-browser - a browser automation extension for k6
+ * Copyright (C) 2021 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	k6metrics "go.k6.io/k6/metrics"
+)
+
+// webVitalSamples returns the tags of the Web Vitals samples by their metrics.
+func webVitalSamples(samples chan k6metrics.SampleContainer) map[string][]map[string]string {
+	got := make(map[string][]map[string]string)
+	for len(samples) > 0 {
+		for _, s := range (<-samples).GetSamples() {
+			switch s.Metric.Name {
+			case "browser_web_vital_lcp", "browser_web_vital_cls", "browser_web_vital_inp":
+				got[s.Metric.Name] = append(got[s.Metric.Name], s.Tags.CloneTags())
+			}
+		}
+	}
+	return got
+}
+
+func TestWebVitals(t *testing.T) {
+	t.Parallel()
+
+	tb := newTestBrowser(t, withHTTPServer())
+	tb.withHandler("/page", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `<h1>Web Vitals</h1><button onclick="this.textContent = 'clicked'">click</button>`)
+	})
+	samples := make(chan k6metrics.SampleContainer, 1000)
+	tb.vu.StateField.Samples = samples
+
+	bctx := tb.NewContext(nil)
+	p := bctx.NewPage()
+	p.Goto(tb.URL("/page"), nil)
+	p.Click("button", nil)
+	// the first document reports its Web Vitals when it's unloaded.
+	p.Goto(tb.URL("/page?second"), nil)
+	// and the second one when its browser context closes.
+	bctx.Close()
+
+	got := webVitalSamples(samples)
+	require.Len(t, got["browser_web_vital_lcp"], 2)
+	require.Len(t, got["browser_web_vital_cls"], 2)
+	assert.Len(t, got["browser_web_vital_inp"], 1)
+	assert.Equal(t, tb.URL("/page"), got["browser_web_vital_lcp"][0]["url"])
+	assert.Equal(t, tb.URL("/page?second"), got["browser_web_vital_lcp"][1]["url"])
+	for _, tags := range got["browser_web_vital_lcp"] {
+		assert.Equal(t, tb.vu.StateField.Group.Path, tags["group"])
+	}
+}